@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/database"
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+)
+
+var (
+	refsFile  = flag.String("refs", "", "File listing the referenced paths, one per line, or - for stdin (for gc)")
+	refsQuery = flag.String("refs-query", "", "SQL query selecting the referenced paths from the DB_* database (for gc)")
+	grace     = flag.Duration("grace", filesystem.DefaultGCGracePeriod, "Keep files modified more recently than this (for gc)")
+	deleteGC  = flag.Bool("delete", false, "Delete the orphans instead of only listing them (for gc)")
+)
+
+// collectGarbage lists, or deletes with -delete, the files under -dir that
+// are not referenced
+func collectGarbage(ctx context.Context, provider *filesystem.Provider, dir string) {
+	refs, err := loadReferences(ctx)
+	if err != nil {
+		log.Fatalf("Error loading references: %v", err)
+	}
+
+	report, err := provider.CollectGarbage(ctx, filesystem.GCConfig{
+		Dir:         dir,
+		References:  refs,
+		GracePeriod: *grace,
+		DryRun:      !*deleteGC,
+	})
+	if err != nil {
+		log.Fatalf("Error collecting garbage: %v", err)
+	}
+
+	for _, orphan := range report.Orphans {
+		state := "orphan "
+		switch {
+		case orphan.Error != "":
+			state = "failed "
+		case orphan.Deleted:
+			state = "deleted"
+		}
+		fmt.Printf("%s %s (%d bytes, modified: %s)", state, orphan.Path, orphan.Size,
+			orphan.LastModified.Format("2006-01-02 15:04:05"))
+		if orphan.Error != "" {
+			fmt.Printf(": %s", orphan.Error)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("\nScanned %d files older than %s, found %d orphans (%d bytes)\n",
+		report.Scanned, *grace, len(report.Orphans), report.Bytes)
+	if report.DryRun {
+		fmt.Println("Dry run: nothing was deleted, run again with -delete to delete the orphans")
+	} else {
+		fmt.Printf("Deleted %d orphans\n", report.Deleted)
+	}
+}
+
+// loadReferences reads the referenced paths from -refs or -refs-query
+func loadReferences(ctx context.Context) (filesystem.ReferenceSet, error) {
+	refs := filesystem.ReferenceSet{}
+
+	switch {
+	case *refsFile != "":
+		var r io.Reader = os.Stdin
+		if *refsFile != "-" {
+			file, err := os.Open(*refsFile)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			r = file
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				refs[line] = true
+			}
+		}
+		return refs, scanner.Err()
+
+	case *refsQuery != "":
+		db, err := database.OpenFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		defer database.Close(db)
+
+		var paths []string
+		if err := db.WithContext(ctx).Raw(*refsQuery).Scan(&paths).Error; err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			refs[p] = true
+		}
+		return refs, nil
+
+	default:
+		return nil, fmt.Errorf("-refs or -refs-query is required, or every file would be an orphan")
+	}
+}
@@ -15,7 +15,7 @@ import (
 )
 
 var (
-	operation   = flag.String("op", "", "Operation: upload, get, exists, list, delete, info")
+	operation   = flag.String("op", "", "Operation: upload, get, exists, list, delete, info, gc, usage, migrate-db")
 	src         = flag.String("src", "", "Source file path (for upload)")
 	dest        = flag.String("dest", "", "Destination path in storage")
 	dir         = flag.String("dir", "", "Directory to list files from")
@@ -30,6 +30,11 @@ var (
 func main() {
 	flag.Parse()
 
+	if *operation == "migrate-db" {
+		runMigrations(context.Background())
+		return
+	}
+
 	// Create configuration
 	config := filesystem.DefaultConfig()
 	config.StorageType = *storageType
@@ -103,6 +108,12 @@ func main() {
 		}
 		getFileInfo(ctx, provider.Provider, *dest)
 
+	case "gc":
+		collectGarbage(ctx, provider.Provider, *dir)
+
+	case "usage":
+		reportUsage(ctx, provider.Provider, *dir)
+
 	default:
 		fmt.Println("GoKit CLI Tool")
 		fmt.Println("====================")
@@ -115,6 +126,19 @@ func main() {
 		fmt.Println("  List:    gokit -op list -dir uploads")
 		fmt.Println("  Delete:  gokit -op delete -dest uploads/file.txt")
 		fmt.Println("  Info:    gokit -op info -dest uploads/file.txt")
+		fmt.Println("\nGarbage collection (files not referenced, older than -grace):")
+		fmt.Println("  Report:  gokit -op gc -dir uploads -refs referenced.txt")
+		fmt.Println("  Query:   gokit -op gc -dir uploads -refs-query \"SELECT file_path FROM documents\"")
+		fmt.Println("  Delete:  gokit -op gc -dir uploads -refs referenced.txt -grace 72h -delete")
+		fmt.Println("\nStorage usage (files and bytes by extension, top-level folder, and age):")
+		fmt.Println("  Report:  gokit -op usage -dir uploads")
+		fmt.Println("  Groups:  gokit -op usage -dir uploads -group-by extension,age")
+		fmt.Println("\nMigrations (database from DB_* environment variables):")
+		fmt.Println("  Status:  gokit -op migrate-db -cmd status -migrations ./migrations")
+		fmt.Println("  Up:      gokit -op migrate-db -cmd up")
+		fmt.Println("  Down:    gokit -op migrate-db -cmd down -steps 1")
+		fmt.Println("  Force:   gokit -op migrate-db -cmd force -version 20240101120000")
+		fmt.Println("  Create:  gokit -op migrate-db -cmd create -name create_users")
 		fmt.Println("\nStorage Types:")
 		fmt.Println("  Local:   gokit -storage local -local-path ./storage")
 		fmt.Println("  S3:      gokit -storage s3 -s3-bucket my-bucket -s3-region us-east-1")
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/anaknegeri/gokit/pkg/database"
+	"github.com/anaknegeri/gokit/pkg/migrate"
+)
+
+var (
+	migrationsDir = flag.String("migrations", "./migrations", "Migrations directory (for migrate-db)")
+	migrateCmd    = flag.String("cmd", "status", "Migration command: up, down, status, version, force, create")
+	steps         = flag.Int("steps", 0, "Number of migrations to apply or revert; up applies all and down reverts 1 by default")
+	version       = flag.String("version", "", "Target version (for force and up)")
+	name          = flag.String("name", "", "Migration name (for create)")
+)
+
+// runMigrations runs a migration command against the database configured by
+// the DB_* environment variables
+func runMigrations(ctx context.Context) {
+	if *migrateCmd == "create" {
+		up, down, err := migrate.Create(*migrationsDir, *name)
+		if err != nil {
+			log.Fatalf("Error creating migration: %v", err)
+		}
+		fmt.Printf("Created %s\nCreated %s\n", up, down)
+		return
+	}
+
+	db, err := database.OpenFromEnv()
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer database.Close(db)
+
+	migrator, err := migrate.New(db, os.DirFS(*migrationsDir))
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+
+	switch *migrateCmd {
+	case "up":
+		target := parseVersion(*version)
+		if *steps > 0 {
+			target = stepTarget(ctx, migrator, *steps)
+		}
+		applied, err := migrator.UpTo(ctx, target)
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+
+	case "down":
+		n := *steps
+		if n == 0 {
+			n = 1
+		}
+		reverted, err := migrator.Down(ctx, n)
+		if err != nil {
+			log.Fatalf("Error reverting migrations: %v", err)
+		}
+		fmt.Printf("Reverted %d migrations\n", reverted)
+
+	case "force":
+		if *version == "" {
+			log.Fatal("Version is required for force")
+		}
+		if err := migrator.Force(ctx, parseVersion(*version)); err != nil {
+			log.Fatalf("Error forcing version: %v", err)
+		}
+		fmt.Printf("Forced version %s\n", *version)
+
+	case "version":
+		v, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			log.Fatalf("Error reading version: %v", err)
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", v)
+		} else {
+			fmt.Println(v)
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Error reading migration status: %v", err)
+		}
+		if len(statuses) == 0 {
+			fmt.Printf("No migrations found in %s\n", *migrationsDir)
+			return
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Dirty {
+				state = "dirty"
+			} else if s.Applied {
+				state = "applied"
+			}
+			current := ""
+			if s.Current {
+				current = " (current)"
+			}
+			fmt.Printf("%-8s %d_%s%s\n", state, s.Version, s.Name, current)
+		}
+
+	default:
+		log.Fatalf("Unknown migration command: %s", *migrateCmd)
+	}
+}
+
+// parseVersion parses a version flag, 0 when empty
+func parseVersion(value string) uint64 {
+	if value == "" {
+		return 0
+	}
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid version %q: %v", value, err)
+	}
+	return v
+}
+
+// stepTarget returns the version reached by applying the next n migrations
+func stepTarget(ctx context.Context, migrator *migrate.Migrator, n int) uint64 {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		log.Fatalf("Error reading migration status: %v", err)
+	}
+	var target uint64
+	for _, s := range statuses {
+		if s.Applied {
+			continue
+		}
+		if n == 0 {
+			break
+		}
+		target = s.Version
+		n--
+	}
+	return target
+}
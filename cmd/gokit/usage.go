@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+)
+
+var groupBy = flag.String("group-by", "", "Comma-separated groups: extension, folder, age; all by default (for usage)")
+
+// reportUsage prints the files under -dir and their bytes, by group
+func reportUsage(ctx context.Context, provider *filesystem.Provider, dir string) {
+	var groups []filesystem.UsageGroup
+	for _, group := range strings.Split(*groupBy, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, filesystem.UsageGroup(group))
+		}
+	}
+	if len(groups) == 0 {
+		groups = filesystem.UsageGroups
+	}
+
+	report, err := provider.UsageReport(ctx, dir, groups...)
+	if err != nil {
+		log.Fatalf("Error reporting usage: %v", err)
+	}
+
+	for _, group := range groups {
+		fmt.Printf("By %s:\n", group)
+		for _, bucket := range report.Groups[group] {
+			key := bucket.Key
+			if key == "" {
+				key = "(none)"
+			}
+			fmt.Printf("  %-20s %8d files %14d bytes\n", key, bucket.Count, bucket.Bytes)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Total: %d files, %d bytes\n", report.Count, report.Bytes)
+}
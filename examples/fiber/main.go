@@ -2,14 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
+	"time"
 
 	"github.com/anaknegeri/gokit"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -57,11 +58,21 @@ func main() {
 	// Create sample users
 	createSampleUsers(db)
 
-	// Initialize validator
-	validate := gokit.NewValidator()
+	// Initialize the validator used by BindAndValidate
+	gokit.SetBindingValidator(gokit.NewValidator(gokit.WithValidatorDefaults()))
 
 	// Initialize paginator
-	paginator := gokit.NewPaginator(db)
+	paginator := gokit.NewPaginator(db.Model(&User{})).SetSortableFields(map[string]string{
+		"firstName": "first_name",
+		"lastName":  "last_name",
+		"age":       "age",
+	}).SetFilterSchema(gokit.FilterSchema{
+		Fields: map[string]gokit.FilterField{
+			"age":   {Column: "age", Type: gokit.FilterInt},
+			"email": {Column: "email", Operators: []gokit.FilterOperator{gokit.OpEq, gokit.OpLike}},
+		},
+		SearchColumns: []string{"first_name", "last_name"},
+	})
 
 	// Create fiber app
 	app := fiber.New(fiber.Config{
@@ -69,13 +80,22 @@ func main() {
 	})
 
 	// Add middleware
-	app.Use(logger.New())
-	app.Use(recover.New())
+	app.Use(requestid.New())
+	app.Use(gokit.AccessLogMiddleware(customLogger, gokit.AccessLogConfig{
+		SkipPaths:     []string{"/health"},
+		SlowThreshold: 500 * time.Millisecond,
+	}))
+	app.Use(gokit.RecoverMiddleware(customLogger))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowHeaders: "Origin, Content-Type, Accept",
 	}))
 
+	// Health check, skipped by the access log
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.SendString("OK")
+	})
+
 	// API routes
 	api := app.Group("/api")
 
@@ -83,6 +103,7 @@ func main() {
 	fileAPI := api.Group("/files")
 	fileAPI.Post("/upload", fs.GetUploadHandler()("files").(fiber.Handler))
 	fileAPI.Get("/info/*", fs.GetFileInfoHandler()("files").(fiber.Handler))
+	fileAPI.Get("/list/*", fs.GetListFilesPagedHandler()("files").(fiber.Handler))
 	fileAPI.Get("/*", fs.GetFileHandler()("files").(fiber.Handler))
 	fileAPI.Delete("/*", fs.GetDeleteFileHandler()("files").(fiber.Handler))
 	fileAPI.Get("/", fs.GetListFilesHandler()("files").(fiber.Handler))
@@ -96,11 +117,15 @@ func main() {
 		params := gokit.PaginationParams{
 			Page:     c.QueryInt("page", 1),
 			PageSize: c.QueryInt("pageSize", 10),
+			Sort:     gokit.ParseSort(c.Query("sort")), // e.g. ?sort=-age,lastName
+			Filter:   gokit.ParseFilters(c.Queries()),  // e.g. ?filter[age][gte]=18&q=smith
 		}
 
 		// Get users with pagination
-		var users []User
-		result, err := paginator.Paginate(params, &users)
+		result, err := gokit.PaginateWith[User](paginator, params)
+		if errors.Is(err, gokit.ErrInvalidSort) || errors.Is(err, gokit.ErrInvalidFilter) {
+			return gokit.ErrorResponseWithErr(c, err)
+		}
 		if err != nil {
 			customLogger.Errorf("Failed to get users: %v", err)
 			return gokit.ErrorResponseWithErr(c, gokit.WrapError(
@@ -133,14 +158,9 @@ func main() {
 	userAPI.Post("/", func(c *fiber.Ctx) error {
 		var user User
 
-		// Parse request body
-		if err := c.BodyParser(&user); err != nil {
-			return gokit.BadRequestResponse(c, "Invalid request body", nil)
-		}
-
-		// Validate user
-		if err := validate.Struct(user); err != nil {
-			return gokit.ErrorResponseWithErr(c, gokit.ValidatorError(err))
+		// Parse and validate the request body
+		if ok, err := gokit.BindAndValidate(c, &user); !ok {
+			return err
 		}
 
 		// Save user
@@ -86,7 +86,17 @@ func main() {
 	dbLogger.Info("Database connection established")
 	cacheLogger.Info("Cache initialized")
 
-	// Example 9: Error handling with logger
+	// Example 9: Structured fields shared by every entry
+	requestLogger := logger.With("request_id", "req-123").WithFields(gokit.LogFields{
+		"user_id": 12345,
+		"tenant":  "acme",
+	})
+	requestLogger.Info("Processing request")
+	requestLogger.Infoj(map[string]interface{}{
+		"action": "upload",
+	})
+
+	// Example 10: Error handling with logger
 	if err := simulateError(); err != nil {
 		appErr := gokit.NewError(500, "An error occurred in the application")
 		logger.Errorf("Application error: %v", appErr)
@@ -54,8 +54,10 @@ func main() {
 		createSampleRoles(db)
 	}
 
-	// Create paginator
-	paginator := gokit.NewPaginator(db)
+	// Create paginators, loading each page's associations in one query
+	// instead of one query per row
+	paginator := gokit.NewPaginator(db.Model(&Role{})).Preload("Permissions")
+	userPaginator := gokit.NewPaginator(db.Model(&User{})).Preload("Roles")
 
 	// Initialize Fiber app
 	app := fiber.New()
@@ -79,6 +81,16 @@ func main() {
 		return gokit.SuccessWithPagination(c, "Roles retrieved successfully", result)
 	})
 
+	app.Get("/api/users", func(c *fiber.Ctx) error {
+		// Get users with their roles
+		result, err := gokit.PaginateWith[User](userPaginator, gokit.GetParams(c))
+		if err != nil {
+			return gokit.ErrorResponseWithErr(c, err)
+		}
+
+		return gokit.SuccessWithPagination(c, "Users retrieved successfully", result)
+	})
+
 	// Start server
 	log.Println("Server started on http://localhost:3000")
 	log.Fatal(app.Listen(":3000"))
@@ -4,14 +4,44 @@ package gokit
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"log/slog"
 	"reflect"
+	"time"
 
+	"github.com/anaknegeri/gokit/pkg/auth"
+	"github.com/anaknegeri/gokit/pkg/authz"
+	"github.com/anaknegeri/gokit/pkg/binding"
+	"github.com/anaknegeri/gokit/pkg/cache"
+	"github.com/anaknegeri/gokit/pkg/crud"
+	"github.com/anaknegeri/gokit/pkg/database"
 	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/events"
+	"github.com/anaknegeri/gokit/pkg/export"
 	"github.com/anaknegeri/gokit/pkg/filesystem"
+	"github.com/anaknegeri/gokit/pkg/flags"
+	"github.com/anaknegeri/gokit/pkg/health"
+	"github.com/anaknegeri/gokit/pkg/httpclient"
+	"github.com/anaknegeri/gokit/pkg/i18n"
+	"github.com/anaknegeri/gokit/pkg/jobs"
+	"github.com/anaknegeri/gokit/pkg/lifecycle"
 	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/mailer"
+	"github.com/anaknegeri/gokit/pkg/metrics"
+	"github.com/anaknegeri/gokit/pkg/middleware"
+	"github.com/anaknegeri/gokit/pkg/migrate"
 	"github.com/anaknegeri/gokit/pkg/pagination"
+	"github.com/anaknegeri/gokit/pkg/repository"
 	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/anaknegeri/gokit/pkg/scheduler"
+	"github.com/anaknegeri/gokit/pkg/secrets"
+	"github.com/anaknegeri/gokit/pkg/server"
+	"github.com/anaknegeri/gokit/pkg/tenant"
+	"github.com/anaknegeri/gokit/pkg/tracing"
 	"github.com/anaknegeri/gokit/pkg/validator"
+	"github.com/anaknegeri/gokit/pkg/ws"
+	"github.com/go-playground/locales"
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
@@ -19,32 +49,253 @@ import (
 // Re-export types for filesystem
 type (
 	// Filesystem types
-	FileStorage       = filesystem.Storage
-	FileSystemConfig  = filesystem.Config
-	FileSystemInfo    = filesystem.FileInfo
-	FilesystemHandler = filesystem.FilesystemProvider
+	FileStorage          = filesystem.Storage
+	FileSystemConfig     = filesystem.Config
+	FileSystemInfo       = filesystem.FileInfo
+	FilesystemHandler    = filesystem.FilesystemProvider
+	DownloadGrant        = filesystem.DownloadGrant
+	DownloadTokensConfig = filesystem.DownloadTokensConfig
+	AssetsConfig         = filesystem.AssetsConfig
+	CDN                  = filesystem.CDN
+	CloudFrontConfig     = filesystem.CloudFrontConfig
+	GenericCDNConfig     = filesystem.GenericCDNConfig
+	GCConfig             = filesystem.GCConfig
+	Processor            = filesystem.Processor
+	ProcessFile          = filesystem.ProcessFile
+	PipelineConfig       = filesystem.PipelineConfig
+	UploadPolicy         = filesystem.UploadPolicy
+	MetadataStore        = filesystem.MetadataStore
+	PreviewConverter     = filesystem.PreviewConverter
+	MediaRunner          = filesystem.MediaRunner
+	Rendition            = filesystem.Rendition
+	ProcessingStatus     = filesystem.ProcessingStatus
+	Indexer              = filesystem.Indexer
+	TextExtractor        = filesystem.TextExtractor
+	SearchHit            = filesystem.SearchHit
+	ExifInfo             = filesystem.ExifInfo
+	WatermarkConfig      = filesystem.WatermarkConfig
+	NamingStrategy       = filesystem.NamingStrategy
+	UploadRequest        = filesystem.UploadRequest
+	IngestConfig         = filesystem.IngestConfig
+	IngestRequest        = filesystem.IngestRequest
+	DirectUploadConfig   = filesystem.DirectUploadConfig
+	DirectUploadOptions  = filesystem.DirectUploadOptions
+	DirectUpload         = filesystem.DirectUpload
+	DirectUploadRequest  = filesystem.DirectUploadRequest
+	BatchConfig          = filesystem.BatchConfig
+	BatchOperation       = filesystem.BatchOperation
+	BatchStatus          = filesystem.BatchStatus
+	DuplicateConfig      = filesystem.DuplicateConfig
+	DuplicateReport      = filesystem.DuplicateReport
+	DuplicatesConfig     = filesystem.DuplicatesConfig
+	UsageGroup           = filesystem.UsageGroup
+	UsageReport          = filesystem.UsageReport
+	SymlinkPolicy        = filesystem.SymlinkPolicy
 
 	// Pagination types
 	PaginationParams = pagination.PaginationParams
 	PaginationMeta   = pagination.PaginationMeta
 	PaginationResult = pagination.PaginationResult
 	Paginator        = pagination.Paginator
+	PaginationScope  = pagination.Scope
+	CountMode        = pagination.CountMode
+	PageLinks        = pagination.Links
+	SQLQueryer       = pagination.Queryer
+	PageParamConfig  = pagination.ParamConfig
+	RawPageConfig    = pagination.RawConfig
+	SortField        = pagination.SortField
+	SortDirection    = pagination.SortDirection
+	FilterSchema     = pagination.FilterSchema
+	FilterField      = pagination.FilterField
+	FilterParams     = pagination.FilterParams
+	Filter           = pagination.Filter
+	FilterOperator   = pagination.FilterOperator
+	FilterType       = pagination.FilterType
+	CursorParams     = pagination.CursorParams
+	CursorMeta       = pagination.CursorMeta
+	CursorResult     = pagination.CursorResult
 
 	// Error types
-	AppError        = errors.AppError
-	ValidationError = errors.ValidationError
+	AppError          = errors.AppError
+	ValidationError   = errors.ValidationError
+	ValidationOptions = errors.ValidationOptions
 
 	// Validator types
-	Validator = validator.Validator
+	Validator       = validator.Validator
+	ValidatorOption = validator.Option
+	PasswordPolicy  = validator.PasswordPolicy
+	FileValidator   = validator.FileValidator
+	Sanitizer       = validator.Sanitizer
+	Modifier        = validator.Modifier
+
+	// Auth types
+	AuthManager          = auth.Manager
+	AuthConfig           = auth.Config
+	AuthClaims           = auth.Claims
+	AuthMiddlewareConfig = auth.MiddlewareConfig
+	TokenPair            = auth.TokenPair
+	APIKey               = auth.APIKey
+	APIKeys              = auth.APIKeys
+	APIKeyStore          = auth.APIKeyStore
+	APIKeyConfig         = auth.APIKeyConfig
+
+	// Authorization types
+	Authorizer   = authz.Authorizer
+	AuthzConfig  = authz.Config
+	AuthzSubject = authz.Subject
+	AuthzStore   = authz.Store
+
+	// Cache types
+	Cache         = cache.Cache
+	MemoryCache   = cache.Memory
+	RedisCache    = cache.Redis
+	RedisClient   = cache.RedisClient
+	RedisConfig   = cache.RedisConfig
+	CachedStorage = filesystem.CachedStorage
+
+	// Job types
+	Job        = jobs.Job
+	JobQueue   = jobs.Queue
+	JobConfig  = jobs.Config
+	JobBackend = jobs.Backend
+	JobHandler = jobs.Handler
+
+	// Scheduler types
+	Scheduler       = scheduler.Scheduler
+	SchedulerConfig = scheduler.Config
+	TaskConfig      = scheduler.TaskConfig
+	TaskRun         = scheduler.Run
+
+	// Event types
+	Event        = events.Event
+	EventBus     = events.Bus
+	EventConfig  = events.Config
+	EventBackend = events.Backend
+	EventHandler = events.Handler
+	NATSConfig   = events.NATSConfig
+	EventStorage = filesystem.EventStorage
+	FileEvent    = filesystem.FileEvent
+
+	// Mailer types
+	Mailer         = mailer.Mailer
+	MailService    = mailer.Service
+	MailConfig     = mailer.Config
+	MailMessage    = mailer.Message
+	MailAttachment = mailer.Attachment
+	MailTemplates  = mailer.Templates
+	SMTPConfig     = mailer.SMTPConfig
+
+	// HTTP client types
+	HTTPClient        = httpclient.Client
+	HTTPClientConfig  = httpclient.Config
+	HTTPClientRequest = httpclient.Request
+	BreakerConfig     = httpclient.BreakerConfig
+
+	// Database types
+	DatabaseConfig = database.Config
+	HealthCheck    = health.Check
+	HealthRegistry = health.Registry
+	HealthReport   = health.Report
+
+	// Migration types
+	Migrator        = migrate.Migrator
+	MigrateConfig   = migrate.Config
+	MigrationStatus = migrate.Status
+
+	// Repository types
+	RepositoryConfig = repository.Config
+
+	// I18n types
+	I18nArgs     = i18n.Args
+	I18nMessages = i18n.Messages
+	I18nConfig   = i18n.MiddlewareConfig
+
+	// Metrics types
+	MetricsRegistry = metrics.Registry
+	MetricsConfig   = metrics.MiddlewareConfig
+
+	// Tracing types
+	TracingConfig           = tracing.Config
+	TracingMiddlewareConfig = tracing.MiddlewareConfig
+
+	// Secrets types
+	Secrets       = secrets.Secrets
+	VaultConfig   = secrets.VaultConfig
+	CachedSecrets = secrets.Cached
+
+	// Feature flag types
+	Flag       = flags.Flag
+	FlagTarget = flags.Target
+
+	// Tenant types
+	TenantConfig   = tenant.Config
+	TenantResolver = tenant.Resolver
+
+	// Server types
+	App       = server.App
+	AppOption = server.Option
+	AppModule = server.Module
+
+	// Lifecycle types
+	Shutdowner = lifecycle.Shutdowner
+
+	// WebSocket types
+	WSHub     = ws.Hub
+	WSConfig  = ws.Config
+	WSConn    = ws.Conn
+	WSMessage = ws.Message
+
+	// Export types
+	Exporter     = export.Exporter
+	ExportColumn = export.Column
+	ExportFormat = export.Format
 
 	// Logger types
-	Logger   = logger.Logger
-	LogLevel = logger.LogLevel
+	Logger    = logger.Logger
+	LogLevel  = logger.LogLevel
+	LogFields = logger.Fields
+	LogEntry  = logger.Entry
+	LogHook   = logger.Hook
+	LogOutput = logger.Output
+
+	LogAsyncConfig = logger.AsyncConfig
+	LogLevelLoader = logger.LevelLoader
+
+	// Middleware types
+	AccessLogConfig = middleware.AccessLogConfig
+	BodyGuardConfig = middleware.BodyGuardConfig
+	RecoverConfig   = middleware.RecoverConfig
+	PanicError      = middleware.PanicError
+
+	// Log formatters
+	LogFormatter        = logger.Formatter
+	TextLogFormatter    = logger.TextFormatter
+	JSONLogFormatter    = logger.JSONFormatter
+	LogfmtLogFormatter  = logger.LogfmtFormatter
+	ConsoleLogFormatter = logger.ConsoleFormatter
+	SyslogLogFormatter  = logger.SyslogFormatter
+	JournalLogFormatter = logger.JournalFormatter
 
 	// Response types
-	ApiResponse = response.Response
+	ApiResponse      = response.Response
+	ResponseConfig   = response.Config
+	ResponseFields   = response.Fields
+	ResponseMetadata = response.Metadata
+	KeyCase          = response.KeyCase
+	ResponseEncoder  = response.Encoder
+	ResponseLinks    = response.Links
+	Deprecation      = response.Deprecation
 )
 
+// PaginatedResult is a page of results of type T
+type PaginatedResult[T any] = pagination.Result[T]
+
+// Repository reads and writes the records of the model T
+type Repository[T any] = repository.Repository[T]
+
+// CRUDOptions configures the routes RegisterCRUD mounts for the model T
+type CRUDOptions[T any] = crud.Options[T]
+
 // Export error codes
 const (
 	// Generic error codes
@@ -58,12 +309,55 @@ const (
 	ErrCodeServiceUnavailable = errors.ErrCodeServiceUnavailable
 
 	// Filesystem specific error codes
-	ErrCodeFileNotFound       = errors.ErrCodeFileNotFound
-	ErrCodeFileAlreadyExists  = errors.ErrCodeFileAlreadyExists
-	ErrCodeFileTooLarge       = errors.ErrCodeFileTooLarge
-	ErrCodeInvalidFileType    = errors.ErrCodeInvalidFileType
-	ErrCodeStorageUnavailable = errors.ErrCodeStorageUnavailable
-	ErrCodePermissionDenied   = errors.ErrCodePermissionDenied
+	ErrCodeFileNotFound        = errors.ErrCodeFileNotFound
+	ErrCodeFileAlreadyExists   = errors.ErrCodeFileAlreadyExists
+	ErrCodeFileTooLarge        = errors.ErrCodeFileTooLarge
+	ErrCodeInvalidFileType     = errors.ErrCodeInvalidFileType
+	ErrCodeStorageUnavailable  = errors.ErrCodeStorageUnavailable
+	ErrCodePermissionDenied    = errors.ErrCodePermissionDenied
+	ErrCodeInsufficientStorage = errors.ErrCodeInsufficientStorage
+	ErrCodeFileRejected        = errors.ErrCodeFileRejected
+	ErrCodeDownloadFailed      = errors.ErrCodeDownloadFailed
+
+	// Sort directions
+	SortAsc  = pagination.SortAsc
+	SortDesc = pagination.SortDesc
+
+	// Page sizes
+	DefaultPageSize    = pagination.DefaultPageSize
+	DefaultMaxPageSize = pagination.DefaultMaxPageSize
+
+	// Count modes
+	CountExact     = pagination.CountExact
+	CountEstimated = pagination.CountEstimated
+	CountCached    = pagination.CountCached
+	CountNone      = pagination.CountNone
+
+	// Filter operators
+	OpEq   = pagination.OpEq
+	OpNe   = pagination.OpNe
+	OpGt   = pagination.OpGt
+	OpGte  = pagination.OpGte
+	OpLt   = pagination.OpLt
+	OpLte  = pagination.OpLte
+	OpIn   = pagination.OpIn
+	OpLike = pagination.OpLike
+	OpNull = pagination.OpNull
+
+	// Filter types
+	FilterString = pagination.FilterString
+	FilterInt    = pagination.FilterInt
+	FilterFloat  = pagination.FilterFloat
+	FilterBool   = pagination.FilterBool
+	FilterTime   = pagination.FilterTime
+
+	// Locales
+	LocaleEnglish    = errors.LocaleEnglish
+	LocaleIndonesian = errors.LocaleIndonesian
+
+	// Validation error formats
+	ValidationFormatList = errors.ValidationFormatList
+	ValidationFormatMap  = errors.ValidationFormatMap
 
 	// Log levels
 	LogLevelDebug = logger.DEBUG
@@ -71,8 +365,92 @@ const (
 	LogLevelWarn  = logger.WARN
 	LogLevelError = logger.ERROR
 	LogLevelFatal = logger.FATAL
+
+	// Export formats
+	ExportCSV  = export.CSV
+	ExportXLSX = export.XLSX
+
+	// Response key cases
+	KeyCasePassThrough = response.KeyCasePassThrough
+	KeyCaseSnake       = response.KeyCaseSnake
+	KeyCaseCamel       = response.KeyCaseCamel
+
+	// Storage usage groups
+	UsageByExtension = filesystem.UsageByExtension
+	UsageByFolder    = filesystem.UsageByFolder
+	UsageByAge       = filesystem.UsageByAge
+
+	// Local storage symlink policies
+	SymlinkFollow = filesystem.SymlinkFollow
+	SymlinkReject = filesystem.SymlinkReject
+	SymlinkSkip   = filesystem.SymlinkSkip
+)
+
+// Export sentinel errors, matched by code with errors.Is
+var (
+	// Generic errors
+	ErrBadRequest         = errors.ErrBadRequest
+	ErrUnauthorized       = errors.ErrUnauthorized
+	ErrForbidden          = errors.ErrForbidden
+	ErrNotFound           = errors.ErrNotFound
+	ErrConflict           = errors.ErrConflict
+	ErrValidation         = errors.ErrValidation
+	ErrInternal           = errors.ErrInternal
+	ErrServiceUnavailable = errors.ErrServiceUnavailable
+	ErrMethodNotAllowed   = errors.ErrMethodNotAllowed
+
+	// Filesystem errors
+	ErrFileNotFound        = errors.ErrFileNotFound
+	ErrFileAlreadyExists   = errors.ErrFileAlreadyExists
+	ErrFileTooLarge        = errors.ErrFileTooLarge
+	ErrInvalidFileType     = errors.ErrInvalidFileType
+	ErrStorageUnavailable  = errors.ErrStorageUnavailable
+	ErrPermissionDenied    = errors.ErrPermissionDenied
+	ErrQuotaExceeded       = errors.ErrQuotaExceeded
+	ErrInvalidPath         = errors.ErrInvalidPath
+	ErrInsufficientStorage = errors.ErrInsufficientStorage
+	ErrFileRejected        = errors.ErrFileRejected
+	ErrDownloadFailed      = errors.ErrDownloadFailed
+
+	// Database errors
+	ErrDatabase            = errors.ErrDatabase
+	ErrDuplicateEntry      = errors.ErrDuplicateEntry
+	ErrForeignKeyViolation = errors.ErrForeignKeyViolation
+	ErrRecordNotFound      = errors.ErrRecordNotFound
+
+	// Authentication errors
+	ErrInvalidCredentials = errors.ErrInvalidCredentials
+	ErrTokenExpired       = errors.ErrTokenExpired
+	ErrInvalidToken       = errors.ErrInvalidToken
+	ErrAccountLocked      = errors.ErrAccountLocked
+	ErrInvalidAPIKey      = errors.ErrInvalidAPIKey
+	ErrInsufficientScope  = errors.ErrInsufficientScope
+
+	// Query errors
+	ErrInvalidSort   = errors.ErrInvalidSort
+	ErrInvalidFilter = errors.ErrInvalidFilter
+	ErrInvalidCursor = errors.ErrInvalidCursor
+	ErrInvalidPage   = errors.ErrInvalidPage
+
+	// Request errors
+	ErrInvalidRequest       = errors.ErrInvalidRequest
+	ErrUnsupportedMediaType = errors.ErrUnsupportedMediaType
 )
 
+// Export pagination parameter configurations
+var (
+	PageParams        = pagination.PageParams
+	PerPageParams     = pagination.PerPageParams
+	LimitOffsetParams = pagination.LimitOffsetParams
+)
+
+// ErrCacheMiss is returned by Cache.Get when a key is missing or expired
+var ErrCacheMiss = cache.ErrMiss
+
+// HTTPResponse sends the standard responses with net/http, e.g.
+// gokit.HTTPResponse.Success(w, r, "OK", data)
+var HTTPResponse = response.HTTP
+
 // Filesystem functions
 
 // NewFilesystem creates a new filesystem provider from environment variables
@@ -95,8 +473,257 @@ func NewS3Storage(config filesystem.S3Config) (filesystem.Storage, error) {
 	return filesystem.NewS3Storage(config)
 }
 
+// NewS3StorageContext creates a new S3 storage, connecting within a context
+func NewS3StorageContext(ctx context.Context, config filesystem.S3Config) (filesystem.Storage, error) {
+	return filesystem.NewS3StorageContext(ctx, config)
+}
+
+// NewCachedStorage wraps a storage with a cache of file metadata
+func NewCachedStorage(storage filesystem.Storage, c cache.Cache, ttl time.Duration) *filesystem.CachedStorage {
+	return filesystem.NewCachedStorage(storage, c, ttl)
+}
+
+// NewEventStorage wraps a storage to publish file events on a bus
+func NewEventStorage(storage filesystem.Storage, bus *events.Bus) *filesystem.EventStorage {
+	return filesystem.NewEventStorage(storage, bus)
+}
+
+// NewDownloadTokens creates a service issuing signed download grants, checked
+// by the file handler when set in its config
+func NewDownloadTokens(config filesystem.DownloadTokensConfig) (*filesystem.DownloadTokens, error) {
+	return filesystem.NewDownloadTokens(config)
+}
+
+// NewAssets creates a server of the files of a storage directory under
+// content-hashed names
+func NewAssets(ctx context.Context, config filesystem.AssetsConfig) (*filesystem.Assets, error) {
+	return filesystem.NewAssets(ctx, config)
+}
+
+// NewCloudFront creates a CDN invalidating files on a CloudFront distribution
+func NewCloudFront(ctx context.Context, config filesystem.CloudFrontConfig) (*filesystem.CloudFront, error) {
+	return filesystem.NewCloudFront(ctx, config)
+}
+
+// NewGenericCDN creates a CDN serving files from a base URL and purging them
+// through a webhook
+func NewGenericCDN(config filesystem.GenericCDNConfig) *filesystem.GenericCDN {
+	return filesystem.NewGenericCDN(config)
+}
+
+// NewPipeline creates a pipeline running processors on the files uploaded
+// to a provider
+func NewPipeline(provider *filesystem.Provider, config filesystem.PipelineConfig, processors ...filesystem.Processor) *filesystem.Pipeline {
+	return filesystem.NewPipeline(provider, config, processors...)
+}
+
+// NewBatcher creates a batcher running moves, copies, and deletes on the
+// files of a provider
+func NewBatcher(provider *filesystem.Provider, config filesystem.BatchConfig) *filesystem.Batcher {
+	return filesystem.NewBatcher(provider, config)
+}
+
+// NewTenantStorage wraps a storage to keep the files of each tenant under a
+// directory named after it
+func NewTenantStorage(storage filesystem.Storage) *filesystem.TenantStorage {
+	return filesystem.NewTenantStorage(storage)
+}
+
+// Auth functions
+
+// NewAuth creates a token manager that issues and verifies JWTs
+func NewAuth(config auth.Config) (*auth.Manager, error) {
+	return auth.New(config)
+}
+
+// CurrentClaims returns the token claims of a request authenticated by the auth middleware
+func CurrentClaims(c *fiber.Ctx) (*auth.Claims, bool) {
+	return auth.CurrentClaims(c)
+}
+
+// NewAPIKeys creates an API key manager storing keys in store
+func NewAPIKeys(store auth.APIKeyStore, prefix string) *auth.APIKeys {
+	return auth.NewAPIKeys(store, prefix)
+}
+
+// NewGormAPIKeyStore creates an API key store backed by the api_keys table
+func NewGormAPIKeyStore(db *gorm.DB) *auth.GormAPIKeyStore {
+	return auth.NewGormAPIKeyStore(db)
+}
+
+// NewAuthorizer creates a role-based authorizer
+func NewAuthorizer(config ...authz.Config) *authz.Authorizer {
+	return authz.New(config...)
+}
+
+// NewGormAuthzStore creates an authorizer store reading the roles, permissions,
+// role_permissions, and user_roles tables
+func NewGormAuthzStore(db *gorm.DB) *authz.GormStore {
+	return authz.NewGormStore(db)
+}
+
+// Cache functions
+
+// NewMemoryCache creates an in-process LRU cache holding up to maxEntries values
+func NewMemoryCache(maxEntries int) *cache.Memory {
+	return cache.NewMemory(maxEntries)
+}
+
+// NewRedisCache creates a cache stored in Redis whose keys start with prefix
+func NewRedisCache(client cache.RedisClient, prefix string) *cache.Redis {
+	return cache.NewRedis(client, prefix)
+}
+
+// DialRedis creates a Redis client for NewRedisCache
+func DialRedis(config cache.RedisConfig) *cache.RedisConn {
+	return cache.DialRedis(config)
+}
+
+// Remember returns a cached value decoded from JSON, or loads and caches it
+func Remember[T any](ctx context.Context, c cache.Cache, key string, ttl time.Duration, fn func(ctx context.Context) (T, error), tags ...string) (T, error) {
+	return cache.Remember(ctx, c, key, ttl, fn, tags...)
+}
+
+// Job functions
+
+// NewJobQueue creates a background job queue storing jobs in backend
+func NewJobQueue(backend jobs.Backend, config ...jobs.Config) *jobs.Queue {
+	return jobs.New(backend, config...)
+}
+
+// NewMemoryJobBackend creates a job backend kept in process
+func NewMemoryJobBackend() *jobs.Memory {
+	return jobs.NewMemory()
+}
+
+// NewRedisJobBackend creates a job backend stored in Redis whose keys start with prefix
+func NewRedisJobBackend(client cache.RedisClient, prefix string) *jobs.Redis {
+	return jobs.NewRedis(client, prefix)
+}
+
+// Event functions
+
+// NewEventBus creates an event bus delivering events through backend
+func NewEventBus(backend events.Backend, config ...events.Config) *events.Bus {
+	return events.New(backend, config...)
+}
+
+// NewMemoryEventBackend creates an event backend delivering events in process
+func NewMemoryEventBackend() *events.Memory {
+	return events.NewMemory()
+}
+
+// NewRedisEventBackend creates an event backend using Redis Streams whose keys start with prefix
+func NewRedisEventBackend(client cache.RedisClient, prefix string) *events.Redis {
+	return events.NewRedis(client, prefix)
+}
+
+// ConnectNATS creates an event backend connected to a NATS server
+func ConnectNATS(config events.NATSConfig) (*events.NATS, error) {
+	return events.ConnectNATS(config)
+}
+
+// Mailer functions
+
+// NewMailService creates a mail service sending messages with m
+func NewMailService(m mailer.Mailer, config ...mailer.Config) *mailer.Service {
+	return mailer.New(m, config...)
+}
+
+// NewSMTPMailer creates a mailer sending through an SMTP server
+func NewSMTPMailer(config mailer.SMTPConfig) *mailer.SMTP {
+	return mailer.NewSMTP(config)
+}
+
+// NewMailTemplates parses the .html and .txt email templates of a file system
+func NewMailTemplates(fsys fs.FS) (*mailer.Templates, error) {
+	return mailer.NewTemplates(fsys)
+}
+
+// HTTP client functions
+
+// NewHTTPClient creates a client calling HTTP services with retries and a circuit breaker
+func NewHTTPClient(config ...httpclient.Config) *httpclient.Client {
+	return httpclient.New(config...)
+}
+
+// Database functions
+
+// OpenDatabase connects to a database with pool settings and the gokit logger
+func OpenDatabase(config database.Config, gormConfig ...*gorm.Config) (*gorm.DB, error) {
+	return database.Open(config, gormConfig...)
+}
+
+// OpenDatabaseFromEnv connects to the database configured by DB_* environment variables
+func OpenDatabaseFromEnv(gormConfig ...*gorm.Config) (*gorm.DB, error) {
+	return database.OpenFromEnv(gormConfig...)
+}
+
+// WithTx runs fn in a transaction, rolling back on errors and panics
+func WithTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return database.WithTx(ctx, db, fn)
+}
+
+// NewRepository creates a repository for the model T
+func NewRepository[T any](db *gorm.DB, config ...repository.Config) *repository.Repository[T] {
+	return repository.New[T](db, config...)
+}
+
+// RegisterCRUD mounts the list, get, create, update, and delete routes of a
+// repository on a router
+func RegisterCRUD[T any](router fiber.Router, repo *repository.Repository[T], options ...crud.Options[T]) {
+	crud.Register(router, repo, options...)
+}
+
+// NewMigrator creates a migrator for the SQL migrations of a file system
+func NewMigrator(db *gorm.DB, fsys fs.FS, config ...migrate.Config) (*migrate.Migrator, error) {
+	return migrate.New(db, fsys, config...)
+}
+
+// RegisterHealthCheck adds a check to the default health registry
+func RegisterHealthCheck(name string, check health.Check) {
+	health.Register(name, check)
+}
+
+// HealthHandler serves the report of the default health registry
+func HealthHandler() fiber.Handler {
+	return health.Handler()
+}
+
+// Scheduler functions
+
+// NewScheduler creates a scheduler running tasks on cron expressions or intervals
+func NewScheduler(config ...scheduler.Config) *scheduler.Scheduler {
+	return scheduler.New(config...)
+}
+
 // Pagination functions
 
+// ParseSort parses a sort query value such as "-createdAt,name"
+func ParseSort(value string) []SortField {
+	return pagination.ParseSort(value)
+}
+
+// ParseFilters parses filter[field], filter[field][operator], and q query parameters
+func ParseFilters(query map[string]string) FilterParams {
+	return pagination.ParseFilters(query)
+}
+
+// Paginate paginates a query and returns the page as a []T
+func Paginate[T any](db *gorm.DB, params PaginationParams, scopes ...PaginationScope) (*PaginatedResult[T], error) {
+	return pagination.Paginate[T](db, params, scopes...)
+}
+
+// PaginateWith paginates with a configured paginator and returns the page as a []T
+func PaginateWith[T any](p *Paginator, params PaginationParams, scopes ...PaginationScope) (*PaginatedResult[T], error) {
+	return pagination.PaginateWith[T](p, params, scopes...)
+}
+
+// PaginateRaw paginates a raw SQL query run with database/sql or sqlx
+func PaginateRaw(ctx context.Context, db SQLQueryer, querySQL, countSQL string, args []interface{}, params PaginationParams, dest interface{}, config ...RawPageConfig) (*PaginationResult, error) {
+	return pagination.PaginateRaw(ctx, db, querySQL, countSQL, args, params, dest, config...)
+}
+
 // NewPaginator creates a new paginator
 func NewPaginator(db *gorm.DB) *pagination.Paginator {
 	return pagination.NewPaginator(db)
@@ -105,15 +732,92 @@ func NewPaginator(db *gorm.DB) *pagination.Paginator {
 // GetParams extracts pagination parameters from a request
 func GetParams(c interface {
 	QueryInt(string, ...int) int
+	Query(string, ...string) string
+	Queries() map[string]string
 }) pagination.PaginationParams {
 	return pagination.GetParams(c)
 }
 
+// GetPaginationFromRequest extracts and validates pagination parameters from a request
+func GetPaginationFromRequest(c interface {
+	Query(string, ...string) string
+	Queries() map[string]string
+}, config ...PageParamConfig) (PaginationParams, error) {
+	return pagination.GetPaginationFromRequest(c, config...)
+}
+
+// GetCursorParams extracts keyset pagination parameters from a request
+func GetCursorParams(c interface {
+	QueryInt(string, ...int) int
+	Query(string, ...string) string
+	Queries() map[string]string
+}) pagination.CursorParams {
+	return pagination.GetCursorParams(c)
+}
+
+// Export functions
+
+// NewExporter creates an exporter writing CSV or XLSX to w
+func NewExporter(w io.Writer, format ExportFormat, columns []ExportColumn) (*Exporter, error) {
+	return export.New(w, format, columns)
+}
+
+// ExportQuery writes every row of a query in batches of []T
+func ExportQuery[T any](e *Exporter, query *gorm.DB, batchSize int) error {
+	return export.Query[T](e, query, batchSize)
+}
+
+// SendExport streams an export as a file download
+func SendExport(c *fiber.Ctx, format ExportFormat, filename string, columns []ExportColumn, write func(e *Exporter) error) error {
+	return export.Send(c, format, filename, columns, write)
+}
+
 // Validator functions
 
 // NewValidator creates a new validator
-func NewValidator() validator.Validator {
-	return validator.NewValidator()
+func NewValidator(opts ...validator.Option) validator.Validator {
+	return validator.NewValidator(opts...)
+}
+
+// ContextWithRoles returns a context carrying the user's roles for StructCtx validations
+func ContextWithRoles(ctx context.Context, roles ...string) context.Context {
+	return validator.ContextWithRoles(ctx, roles...)
+}
+
+// ContextWithFlags returns a context carrying enabled feature flags for StructCtx validations
+func ContextWithFlags(ctx context.Context, flags ...string) context.Context {
+	return validator.ContextWithFlags(ctx, flags...)
+}
+
+// Sanitize normalizes a struct with the modifiers in its mod tags, e.g. mod:"trim,lcase"
+func Sanitize(v interface{}) error {
+	return validator.Sanitize(v)
+}
+
+// NewSanitizer creates a sanitizer with the built-in modifiers
+func NewSanitizer() *validator.Sanitizer {
+	return validator.NewSanitizer()
+}
+
+// RegisterModifier adds or replaces a modifier used by Sanitize and BindAndValidate
+func RegisterModifier(name string, fn validator.Modifier) {
+	validator.RegisterModifier(name, fn)
+}
+
+// NewFileValidator creates a validator for uploaded files with upload tags
+func NewFileValidator() *validator.FileValidator {
+	return validator.NewFileValidator()
+}
+
+// WithValidatorDefaults registers the built-in validations such as
+// strong_password, nik, npwp, and slug
+func WithValidatorDefaults() validator.Option {
+	return validator.WithDefaults()
+}
+
+// WithPasswordPolicy registers strong_password with a custom policy
+func WithPasswordPolicy(policy validator.PasswordPolicy) validator.Option {
+	return validator.WithPasswordPolicy(policy)
 }
 
 // Error functions
@@ -148,9 +852,59 @@ func WrapError(err error, httpCode int, message string) *errors.AppError {
 	return errors.WrapError(err, httpCode, message)
 }
 
+// Wrapf wraps an existing error with a formatted message, which may wrap more errors with %w
+func Wrapf(err error, httpCode int, format string, args ...interface{}) *errors.AppError {
+	return errors.Wrapf(err, httpCode, format, args...)
+}
+
+// WithDetails returns a copy of the error's AppError with details added
+func WithDetails(err error, details interface{}) *errors.AppError {
+	return errors.WithDetails(err, details)
+}
+
 // ValidatorError creates an error from validation errors
-func ValidatorError(err error) *errors.AppError {
-	return errors.ValidatorError(err)
+func ValidatorError(err error, opts ...ValidationOptions) *errors.AppError {
+	return errors.ValidatorError(err, opts...)
+}
+
+// ValidatorErrorLocalized creates an error from validation errors with messages in the given locale
+func ValidatorErrorLocalized(err error, locale string, opts ...ValidationOptions) *errors.AppError {
+	return errors.ValidatorErrorLocalized(err, locale, opts...)
+}
+
+// SetValidationOptions sets how the details of validation errors are built
+// for the app, e.g. only the first error of each field, by field
+func SetValidationOptions(opts ValidationOptions) {
+	errors.SetValidationOptions(opts)
+}
+
+// LocalizeError renders an error's message in the given locale
+func LocalizeError(err error, locale string) error {
+	return errors.Localize(err, locale)
+}
+
+// RegisterErrorMessages adds or overrides error message templates for a locale
+func RegisterErrorMessages(locale string, messages map[string]string) {
+	catalog := make(i18n.Messages, len(messages))
+	for key, text := range messages {
+		catalog[key] = text
+	}
+	_ = i18n.Add(locale, catalog)
+}
+
+// RegisterValidatorLocale adds the validator's own messages for another locale
+func RegisterValidatorLocale(translator locales.Translator, register errors.TranslationRegistrar) {
+	errors.RegisterValidatorLocale(translator, register)
+}
+
+// SetDefaultLocale sets the locale used for error messages when none is requested
+func SetDefaultLocale(locale string) {
+	i18n.SetDefaultLocale(locale)
+}
+
+// FromGormError translates GORM and database driver errors into AppErrors
+func FromGormError(err error) *errors.AppError {
+	return errors.FromGormError(err)
 }
 
 // ValidatorFieldLevel is an alias for validator.FieldLevel
@@ -165,6 +919,118 @@ func FormatErrorResponse(err error) *errors.ErrorResponse {
 	return errors.FormatErrorResponse(err)
 }
 
+// Server functions
+
+// NewApp creates a Fiber app with the gokit logger, error responses, request
+// IDs, metrics, and health routes, configured by the APP_* environment
+// variables and the options:
+//
+//	if err := gokit.NewApp(server.WithName("uploads")).Mount(fs).Run(); err != nil {
+//		log.Fatal(err)
+//	}
+func NewApp(opts ...server.Option) *server.App {
+	return server.New(opts...)
+}
+
+// Lifecycle functions
+
+// RegisterShutdown adds a component to the default lifecycle manager, which
+// apps built with NewApp shut down in reverse order of registration
+func RegisterShutdown(name string, closer lifecycle.Shutdowner, timeout ...time.Duration) {
+	lifecycle.Register(name, closer, timeout...)
+}
+
+// Secrets functions
+
+// GetSecret reads a secret of the default secrets, the environment unless
+// secrets.SetDefault changed them
+func GetSecret(ctx context.Context, name string) (string, error) {
+	return secrets.Get(ctx, name)
+}
+
+// SetSecrets replaces the default secrets, which gokit reads credentials from
+func SetSecrets(s secrets.Secrets) {
+	secrets.SetDefault(s)
+}
+
+// WebSocket functions
+
+// NewWSHub creates a hub of WebSocket connections, served with its Handler
+func NewWSHub(config ...ws.Config) *ws.Hub {
+	return ws.NewHub(config...)
+}
+
+// Feature flag functions
+
+// FlagEnabled reports whether a feature flag is on for the user of a request
+func FlagEnabled(c *fiber.Ctx, name string) bool {
+	return flags.Enabled(c, name)
+}
+
+// RequireFlag hides routes behind a feature flag, responding 404 while it
+// is off
+func RequireFlag(name string) fiber.Handler {
+	return flags.Require(name)
+}
+
+// Tenant functions
+
+// TenantMiddleware resolves the tenant of each request, from the token claim
+// by default, or the X-Tenant-ID header with TenantConfig.TrustHeader
+func TenantMiddleware(config ...tenant.Config) fiber.Handler {
+	return tenant.Middleware(config...)
+}
+
+// CurrentTenant returns the tenant of a request resolved by the tenant middleware
+func CurrentTenant(c *fiber.Ctx) (string, bool) {
+	return tenant.Current(c)
+}
+
+// Tracing functions
+
+// InitTracing sets up OpenTelemetry tracing, configured by the OTEL_*
+// environment variables when no config is given. Call the returned function
+// on shutdown to flush pending spans.
+func InitTracing(ctx context.Context, config ...tracing.Config) (func(context.Context) error, error) {
+	return tracing.Init(ctx, config...)
+}
+
+// TracingMiddleware starts a server span for each request
+func TracingMiddleware(config ...tracing.MiddlewareConfig) fiber.Handler {
+	return tracing.Middleware(config...)
+}
+
+// Metrics functions
+
+// MetricsMiddleware records the count, latency, and size of requests by
+// route and status
+func MetricsMiddleware(config ...metrics.MiddlewareConfig) fiber.Handler {
+	return metrics.Middleware(config...)
+}
+
+// MetricsHandler serves the default metrics registry to Prometheus
+func MetricsHandler() fiber.Handler {
+	return metrics.Handler()
+}
+
+// I18n functions
+
+// LoadMessages adds the JSON message catalogs of a directory of a file
+// system, one file per locale such as "en.json"
+func LoadMessages(fsys fs.FS, dir string) error {
+	return i18n.LoadFS(fsys, dir)
+}
+
+// T renders a message in the locale of ctx
+func T(ctx context.Context, key string, args ...i18n.Args) string {
+	return i18n.T(ctx, key, args...)
+}
+
+// I18nMiddleware negotiates the locale of each request
+func I18nMiddleware(config ...i18n.MiddlewareConfig) fiber.Handler {
+	return i18n.Middleware(config...)
+}
+
 // Logger functions
 
 // NewLogger creates a new logger
@@ -172,13 +1038,87 @@ func NewLogger() *logger.Logger {
 	return logger.NewLogger()
 }
 
+// NewLoggerFromSlog creates a logger that writes through a slog.Logger
+func NewLoggerFromSlog(sl *slog.Logger) *logger.Logger {
+	return logger.NewLoggerFromSlog(sl)
+}
+
+// LoggerFromContext returns the logger stored in ctx, enriched with request and trace IDs
+func LoggerFromContext(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx)
+}
+
+// ContextWithLogger returns a copy of ctx that carries the logger
+func ContextWithLogger(ctx context.Context, l *logger.Logger) context.Context {
+	return logger.WithContext(ctx, l)
+}
+
+// NamedLogger returns the logger for a subsystem such as "filesystem.s3"
+func NamedLogger(name string) *logger.Logger {
+	return logger.Named(name)
+}
+
+// SetNamedLogLevel sets the level for a logger name and the names below it
+func SetNamedLogLevel(name string, level LogLevel) {
+	logger.SetNamedLevel(name, level)
+}
+
 // InitLogger initializes a logger from environment variables
 func InitLogger() *logger.Logger {
 	return logger.InitLogger()
 }
 
+// Middleware functions
+
+// AccessLogMiddleware logs one structured entry per request with the logger
+func AccessLogMiddleware(l *logger.Logger, config ...AccessLogConfig) fiber.Handler {
+	return middleware.AccessLog(l, config...)
+}
+
+// RecoverMiddleware turns panics into 500 error responses, logging them with
+// their stack
+func RecoverMiddleware(l *logger.Logger, config ...RecoverConfig) fiber.Handler {
+	return middleware.Recover(l, config...)
+}
+
+// BodyGuardMiddleware rejects request bodies that are too large or of other
+// content types before they are parsed
+func BodyGuardMiddleware(config BodyGuardConfig) fiber.Handler {
+	return middleware.BodyGuard(config)
+}
+
+// NewSyslogOutput creates a log output that sends RFC 5424 messages to a syslog server
+func NewSyslogOutput(network, addr string, formatter SyslogLogFormatter) LogOutput {
+	return logger.NewSyslogOutput(network, addr, formatter)
+}
+
+// NewJournalOutput creates a log output that sends entries to the systemd journal
+func NewJournalOutput(formatter JournalLogFormatter) LogOutput {
+	return logger.NewJournalOutput(formatter)
+}
+
+// LogLevelFromFile returns a loader that reads a level name from a file
+func LogLevelFromFile(path string) LogLevelLoader {
+	return logger.LevelFromFile(path)
+}
+
+// LogLevelHandler reports the logger level on GET and changes it on PUT
+func LogLevelHandler(l *logger.Logger) fiber.Handler {
+	return middleware.LogLevelHandler(l)
+}
+
 // Response functions
 
+// ConfigureResponses sets the key case, envelope field names, and metadata of responses
+func ConfigureResponses(cfg ResponseConfig) {
+	response.Configure(cfg)
+}
+
+// RegisterResponseEncoder adds or replaces the response encoder of a content type
+func RegisterResponseEncoder(contentType string, enc ResponseEncoder) {
+	response.RegisterEncoder(contentType, enc)
+}
+
 // SuccessResponse sends a success response
 func SuccessResponse(c *fiber.Ctx, message string, data interface{}, statusCode ...int) error {
 	return response.Success(c, message, data, statusCode...)
@@ -189,11 +1129,59 @@ func SuccessWithPagination(c *fiber.Ctx, message string, paginationResult interf
 	return response.SuccessWithPagination(c, message, paginationResult, statusCode...)
 }
 
+// SuccessWithLinks sends a success response with links to related resources
+func SuccessWithLinks(c *fiber.Ctx, message string, data interface{}, links ResponseLinks, statusCode ...int) error {
+	return response.SuccessWithLinks(c, message, data, links, statusCode...)
+}
+
+// PaginationLinks builds the first, prev, next, and last page links for a request
+func PaginationLinks(c *fiber.Ctx, meta interface{}) *PageLinks {
+	return response.PaginationLinks(c, meta)
+}
+
+// Deprecated adds the Deprecation, Sunset, Link, and Warning headers to the response
+func Deprecated(c *fiber.Ctx, d Deprecation) {
+	response.Deprecated(c, d)
+}
+
+// DeprecatedRoute returns a middleware adding the deprecation headers to every response
+func DeprecatedRoute(d Deprecation) fiber.Handler {
+	return response.DeprecatedRoute(d)
+}
+
 // ErrorResponse sends an error response
 func ErrorResponseWithErr(c *fiber.Ctx, err error) error {
 	return response.Error(c, err)
 }
 
+// ValidationErrorResponse sends a 422 response for validator errors in the request locale
+func ValidationErrorResponse(c *fiber.Ctx, err error) error {
+	return response.ValidationError(c, err)
+}
+
+// Binding functions
+
+// BindAndValidate parses the request body into dto and validates it, sending
+// the error response and returning false on failure
+func BindAndValidate(c *fiber.Ctx, dto interface{}) (bool, error) {
+	return binding.BindAndValidate(c, dto)
+}
+
+// BindQuery parses the query string into dto and validates it
+func BindQuery(c *fiber.Ctx, dto interface{}) (bool, error) {
+	return binding.BindQuery(c, dto)
+}
+
+// BindParams parses the route parameters into dto and validates it
+func BindParams(c *fiber.Ctx, dto interface{}) (bool, error) {
+	return binding.BindParams(c, dto)
+}
+
+// SetBindingValidator sets the validator used by BindAndValidate, BindQuery, and BindParams
+func SetBindingValidator(v validator.Validator) {
+	binding.SetValidator(v)
+}
+
 // CreatedResponse sends a created response
 func CreatedResponse(c *fiber.Ctx, message string, data interface{}) error {
 	return response.Created(c, message, data)
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// MemoryAPIKeyStore keeps API keys in memory, for tests and keys configured
+// at startup
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey // By hash
+}
+
+// NewMemoryAPIKeyStore creates an empty memory store
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: make(map[string]*APIKey)}
+}
+
+// Create stores a new key
+func (s *MemoryAPIKeyStore) Create(_ context.Context, key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *key
+	s.keys[key.Hash] = &stored
+	return nil
+}
+
+// FindByHash returns the key with a hash, or nil when there is none
+func (s *MemoryAPIKeyStore) FindByHash(_ context.Context, hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, nil
+	}
+	found := *key
+	return &found, nil
+}
+
+// Revoke marks a key revoked
+func (s *MemoryAPIKeyStore) Revoke(_ context.Context, id string, at time.Time) error {
+	return s.update(id, func(key *APIKey) { key.RevokedAt = &at })
+}
+
+// Touch records when a key was last used
+func (s *MemoryAPIKeyStore) Touch(_ context.Context, id string, at time.Time) error {
+	return s.update(id, func(key *APIKey) { key.LastUsedAt = &at })
+}
+
+// update changes the key with an ID
+func (s *MemoryAPIKeyStore) update(id string, fn func(key *APIKey)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.keys {
+		if key.ID == id {
+			fn(key)
+			return nil
+		}
+	}
+	return errors.RecordNotFoundError("API key", id)
+}
+
+// GormAPIKeyStore keeps API keys in the api_keys table. Create the table
+// with db.AutoMigrate(&auth.APIKey{}).
+type GormAPIKeyStore struct {
+	db *gorm.DB
+}
+
+// NewGormAPIKeyStore creates a store backed by a database
+func NewGormAPIKeyStore(db *gorm.DB) *GormAPIKeyStore {
+	return &GormAPIKeyStore{db: db}
+}
+
+// Create stores a new key
+func (s *GormAPIKeyStore) Create(ctx context.Context, key *APIKey) error {
+	if err := s.db.WithContext(ctx).Create(key).Error; err != nil {
+		return errors.FromGormError(err)
+	}
+	return nil
+}
+
+// FindByHash returns the key with a hash, or nil when there is none
+func (s *GormAPIKeyStore) FindByHash(ctx context.Context, hash string) (*APIKey, error) {
+	var keys []APIKey
+	if err := s.db.WithContext(ctx).Where("hash = ?", hash).Limit(1).Find(&keys).Error; err != nil {
+		return nil, errors.FromGormError(err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
+
+// Revoke marks a key revoked
+func (s *GormAPIKeyStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	return s.update(ctx, id, "revoked_at", at)
+}
+
+// Touch records when a key was last used
+func (s *GormAPIKeyStore) Touch(ctx context.Context, id string, at time.Time) error {
+	return s.update(ctx, id, "last_used_at", at)
+}
+
+// update sets a column of the key with an ID
+func (s *GormAPIKeyStore) update(ctx context.Context, id string, column string, value interface{}) error {
+	result := s.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update(column, value)
+	if result.Error != nil {
+		return errors.FromGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.RecordNotFoundError("API key", id)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	keys := NewAPIKeys(NewMemoryAPIKeyStore(), "")
+	keys.now = func() time.Time { return now }
+
+	create := func(name string, scopes []string, ttl time.Duration) string {
+		t.Helper()
+		key, _, err := keys.Create(ctx, name, scopes, ttl)
+		if err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		return key
+	}
+	valid := create("valid", []string{"files:*"}, 0)
+	uploader := create("uploader", []string{"files:upload"}, 0)
+	reader := create("reader", []string{"files:read"}, 0)
+	admin := create("admin", []string{"*"}, 0)
+	expiring := create("expiring", []string{"files:*"}, time.Hour)
+	revoked, revokedKey, err := keys.Create(ctx, "revoked", []string{"files:*"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := keys.Revoke(ctx, revokedKey.ID); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+
+	app := fiber.New()
+	api := app.Group("/api", keys.Middleware(APIKeyConfig{Scopes: []string{"files:upload"}}))
+	api.Get("/files", func(c *fiber.Ctx) error {
+		key, _ := CurrentAPIKey(c)
+		return c.SendString(key.Name)
+	})
+	api.Delete("/files", RequireScopes("files:delete"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	unknown, _ := GenerateAPIKey(DefaultAPIKeyPrefix)
+	otherPrefix, _ := GenerateAPIKey("other")
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		status int
+		code   string
+	}{
+		{"valid", http.MethodGet, valid, http.StatusOK, ""},
+		{"exact scope", http.MethodGet, uploader, http.StatusOK, ""},
+		{"wildcard scope", http.MethodGet, admin, http.StatusOK, ""},
+		{"missing key", http.MethodGet, "", http.StatusUnauthorized, errors.ErrCodeInvalidAPIKey},
+		{"unknown key", http.MethodGet, unknown, http.StatusUnauthorized, errors.ErrCodeInvalidAPIKey},
+		{"other prefix", http.MethodGet, otherPrefix, http.StatusUnauthorized, errors.ErrCodeInvalidAPIKey},
+		{"malformed key", http.MethodGet, "not a key", http.StatusUnauthorized, errors.ErrCodeInvalidAPIKey},
+		{"revoked key", http.MethodGet, revoked, http.StatusUnauthorized, errors.ErrCodeInvalidAPIKey},
+		{"expired key", http.MethodGet, expiring, http.StatusUnauthorized, errors.ErrCodeInvalidAPIKey},
+		{"missing scope", http.MethodGet, reader, http.StatusForbidden, errors.ErrCodeInsufficientScope},
+		{"route scope granted", http.MethodDelete, valid, http.StatusNoContent, ""},
+		{"route scope missing", http.MethodDelete, uploader, http.StatusForbidden, errors.ErrCodeInsufficientScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/files", nil)
+			if tt.key != "" {
+				req.Header.Set(DefaultAPIKeyHeader, tt.key)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.code == "" {
+				return
+			}
+			var body struct {
+				Code string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body.Code != tt.code {
+				t.Errorf("Expected error %s, got %s", tt.code, body.Code)
+			}
+		})
+	}
+}
+
+func TestAPIKeysValidate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryAPIKeyStore()
+	keys := NewAPIKeys(store, "svc")
+	keys.now = func() time.Time { return now }
+
+	key, record, err := keys.Create(ctx, "worker", []string{"jobs:run"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if record.Hash == key || record.Prefix != key[:len("svc_")+apiKeyDisplayLength] {
+		t.Errorf("Expected only the hash and prefix of the key to be stored: %+v", record)
+	}
+
+	validated, err := keys.Validate(ctx, key)
+	if err != nil {
+		t.Fatalf("Failed to validate key: %v", err)
+	}
+	if validated.LastUsedAt == nil || !validated.LastUsedAt.Equal(now) {
+		t.Errorf("Expected the use of the key to be recorded, got %v", validated.LastUsedAt)
+	}
+
+	// The key expires at the end of its TTL
+	now = now.Add(time.Hour)
+	if _, err := keys.Validate(ctx, key); !errors.Is(err, errors.ErrInvalidAPIKey) {
+		t.Errorf("Expected an expired key to be invalid, got %v", err)
+	}
+}
+
+func TestAPIKeyHasScope(t *testing.T) {
+	key := &APIKey{Scopes: []string{"files:*", "jobs:run"}}
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{"files:upload", true},
+		{"files:", true},
+		{"jobs:run", true},
+		{"jobs:cancel", false},
+		{"filesystem:read", false},
+		{"admin", false},
+	}
+	for _, tt := range tests {
+		if got := key.HasScope(tt.scope); got != tt.want {
+			t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+	if !(&APIKey{Scopes: []string{"*"}}).HasScope("anything") {
+		t.Error("Expected * to grant every scope")
+	}
+}
@@ -0,0 +1,325 @@
+// Package auth authenticates Fiber requests with JSON Web Tokens, which it
+// issues and verifies, and with API keys
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+	"github.com/anaknegeri/gokit/pkg/errors"
+)
+
+const (
+	// DefaultAccessTTL is how long access tokens are valid by default
+	DefaultAccessTTL = 15 * time.Minute
+	// DefaultRefreshTTL is how long refresh tokens are valid by default
+	DefaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+// Config configures token signing and verification
+type Config struct {
+	// Algorithm is HS256, RS256, or EdDSA, defaulting to HS256
+	Algorithm Algorithm
+
+	// Secret is the HS256 key of at least 32 bytes
+	Secret []byte
+
+	// PrivateKey signs RS256 and EdDSA tokens, e.g. from ParsePrivateKeyPEM
+	PrivateKey crypto.Signer
+
+	// PublicKey verifies RS256 and EdDSA tokens, defaulting to the public key
+	// of PrivateKey. Services that only verify tokens set just this.
+	PublicKey crypto.PublicKey
+
+	// KeyID is written to the kid header, for verifiers that rotate keys
+	KeyID string
+
+	// Issuer is written to issued tokens and required in verified ones when set
+	Issuer string
+
+	// Audience is written to issued tokens and required in verified ones when set
+	Audience string
+
+	// AccessTTL and RefreshTTL default to DefaultAccessTTL and DefaultRefreshTTL
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+
+	// Leeway allows for clock skew when checking expiry and not-before times
+	Leeway time.Duration
+
+	// Revoked reports whether a verified token has been revoked, e.g. by
+	// looking its ID up in a cache filled on logout
+	Revoked func(ctx context.Context, claims *Claims) (bool, error)
+
+	// RefreshTokens keeps the IDs of the refresh tokens exchanged by Refresh
+	// until they expire, so each is exchanged once. It must not drop IDs
+	// before they expire: it defaults to a memory cache without a size
+	// limit, and services refreshing tokens on several instances share one,
+	// e.g. cache.NewRedis.
+	RefreshTokens cache.Adder
+}
+
+// TokenPair is an access token with the refresh token that renews it
+type TokenPair struct {
+	AccessToken      string    `json:"accessToken"`
+	RefreshToken     string    `json:"refreshToken"`
+	TokenType        string    `json:"tokenType"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// header is the JOSE header of a token
+type header struct {
+	Algorithm Algorithm `json:"alg"`
+	Type      string    `json:"typ,omitempty"`
+	KeyID     string    `json:"kid,omitempty"`
+}
+
+// Manager issues and verifies tokens
+type Manager struct {
+	config Config
+	keys   *keys
+	now    func() time.Time
+}
+
+// New creates a token manager, checking that the keys suit the algorithm
+func New(config Config) (*Manager, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = HS256
+	}
+	if config.AccessTTL <= 0 {
+		config.AccessTTL = DefaultAccessTTL
+	}
+	if config.RefreshTTL <= 0 {
+		config.RefreshTTL = DefaultRefreshTTL
+	}
+	if config.RefreshTokens == nil {
+		config.RefreshTokens = cache.NewMemory(-1)
+	}
+
+	k, err := newKeys(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{config: config, keys: k, now: time.Now}, nil
+}
+
+// Issue signs an access token for the claims. Their type, times, and ID are
+// set, and the issuer and audience default to the configured ones.
+func (m *Manager) Issue(claims Claims) (string, error) {
+	token, _, err := m.issue(claims, AccessToken, m.config.AccessTTL)
+	return token, err
+}
+
+// IssuePair signs an access token and a refresh token for the claims
+func (m *Manager) IssuePair(claims Claims) (*TokenPair, error) {
+	access, accessExpires, err := m.issue(claims, AccessToken, m.config.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, refreshExpires, err := m.issue(claims, RefreshToken, m.config.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresAt:        accessExpires,
+		RefreshExpiresAt: refreshExpires,
+	}, nil
+}
+
+// issue signs a token of a type
+func (m *Manager) issue(claims Claims, tokenType TokenType, ttl time.Duration) (string, time.Time, error) {
+	now := m.now()
+	claims.Type = tokenType
+	claims.IssuedAt = now
+	claims.NotBefore = time.Time{}
+	claims.ExpiresAt = now.Add(ttl)
+	claims.ID = newID()
+	if claims.Issuer == "" {
+		claims.Issuer = m.config.Issuer
+	}
+	if len(claims.Audience) == 0 && m.config.Audience != "" {
+		claims.Audience = []string{m.config.Audience}
+	}
+
+	headerJSON, err := json.Marshal(header{Algorithm: m.keys.algorithm, Type: "JWT", KeyID: m.config.KeyID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	input := encodeSegment(headerJSON) + "." + encodeSegment(claimsJSON)
+	signature, err := m.keys.sign([]byte(input))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return input + "." + encodeSegment(signature), claims.ExpiresAt, nil
+}
+
+// Verify checks an access token and returns its claims. Invalid tokens fail
+// with an INVALID_TOKEN error and expired ones with a TOKEN_EXPIRED error.
+func (m *Manager) Verify(ctx context.Context, token string) (*Claims, error) {
+	return m.verify(ctx, token, AccessToken)
+}
+
+// VerifyRefresh checks a refresh token and returns its claims
+func (m *Manager) VerifyRefresh(ctx context.Context, token string) (*Claims, error) {
+	return m.verify(ctx, token, RefreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair with the same
+// subject, roles, and custom claims. The refresh token is rotated: it is
+// used up, and exchanging it again fails with INVALID_TOKEN. Use
+// VerifyRefresh, Consume, and IssuePair instead to reload the claims, e.g.
+// after the user's roles changed.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := m.VerifyRefresh(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Consume(ctx, claims); err != nil {
+		return nil, err
+	}
+	return m.IssuePair(*claims)
+}
+
+// Consume uses up a verified refresh token, failing with INVALID_TOKEN when
+// it was used before, see Config.RefreshTokens
+func (m *Manager) Consume(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" {
+		return invalidToken("refresh token without ID")
+	}
+	ttl := claims.ExpiresAt.Add(m.config.Leeway).Sub(m.now())
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+
+	added, err := m.config.RefreshTokens.Add(ctx, "auth:refresh:"+claims.ID, []byte{1}, ttl)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return invalidToken("refresh token reused")
+	}
+	return nil
+}
+
+// verify checks a token of a type
+func (m *Manager) verify(ctx context.Context, token string, tokenType TokenType) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+
+	headerJSON, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	var h header
+	if err := json.Unmarshal(headerJSON, &h); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	// Only the configured algorithm is accepted, which rules out "none" and
+	// tokens signed with a public key as an HMAC secret
+	if h.Algorithm != m.keys.algorithm {
+		return nil, invalidToken(fmt.Sprintf("unexpected algorithm %q", h.Algorithm))
+	}
+
+	signature, err := decodeSegment(parts[2])
+	if err != nil || !m.keys.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, invalidToken("invalid signature")
+	}
+
+	claimsJSON, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+
+	// Tokens without an expiry would be valid forever
+	if claims.ExpiresAt.IsZero() {
+		return nil, invalidToken("missing expiry")
+	}
+	now := m.now()
+	if !now.Before(claims.ExpiresAt.Add(m.config.Leeway)) {
+		appErr := errors.TokenExpiredError()
+		appErr.Internal = fmt.Errorf("auth: token expired at %s", claims.ExpiresAt.Format(time.RFC3339))
+		return nil, appErr
+	}
+	if !claims.NotBefore.IsZero() && now.Add(m.config.Leeway).Before(claims.NotBefore) {
+		return nil, invalidToken("token not valid yet")
+	}
+	if m.config.Issuer != "" && claims.Issuer != m.config.Issuer {
+		return nil, invalidToken(fmt.Sprintf("unexpected issuer %q", claims.Issuer))
+	}
+	if m.config.Audience != "" && !contains(claims.Audience, m.config.Audience) {
+		return nil, invalidToken("unexpected audience")
+	}
+	// Tokens from other issuers carry no type or another one, and are
+	// accepted as access tokens
+	if (tokenType == RefreshToken) != (claims.Type == RefreshToken) {
+		return nil, invalidToken(fmt.Sprintf("expected %s token", tokenType))
+	}
+
+	if m.config.Revoked != nil {
+		revoked, err := m.config.Revoked(ctx, &claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, invalidToken("token revoked")
+		}
+	}
+	return &claims, nil
+}
+
+// invalidToken returns an INVALID_TOKEN error with the reason kept internal
+func invalidToken(reason string) *errors.AppError {
+	err := errors.InvalidTokenError()
+	err.Internal = fmt.Errorf("auth: %s", reason)
+	return err
+}
+
+// newID returns a random token ID
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// encodeSegment encodes a token segment as unpadded base64url
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSegment decodes an unpadded base64url token segment
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
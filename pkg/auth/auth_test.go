@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+	"github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// testSecret is an HS256 secret of the minimum length
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// newTestManager returns an HS256 manager whose clock is set to now
+func newTestManager(t *testing.T, config Config, now time.Time) *Manager {
+	t.Helper()
+	if config.Secret == nil && config.PrivateKey == nil {
+		config.Secret = testSecret
+	}
+	m, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	m.now = func() time.Time { return now }
+	return m
+}
+
+// signRaw signs a token with any header and claims
+func signRaw(t *testing.T, m *Manager, h map[string]interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(h)
+	claimsJSON, _ := json.Marshal(claims)
+	input := encodeSegment(headerJSON) + "." + encodeSegment(claimsJSON)
+	signature, err := m.keys.sign([]byte(input))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return input + "." + encodeSegment(signature)
+}
+
+// errorCode returns the code of an AppError, or ""
+func errorCode(err error) string {
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestManager(t, Config{Issuer: "gokit", Audience: "api", Leeway: 10 * time.Second}, now)
+	pair, err := m.IssuePair(Claims{Subject: "42", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	parts := strings.Split(pair.AccessToken, ".")
+
+	// Other managers and keys
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaManager := newTestManager(t, Config{Algorithm: RS256, PrivateKey: rsaKey}, now)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edManager := newTestManager(t, Config{Algorithm: EdDSA, PrivateKey: edKey}, now)
+	otherIssuer := newTestManager(t, Config{Issuer: "other", Audience: "api"}, now)
+	otherAudience := newTestManager(t, Config{Issuer: "gokit", Audience: "web"}, now)
+	otherSecret := newTestManager(t, Config{Secret: []byte("fedcba9876543210fedcba9876543210"), Issuer: "gokit", Audience: "api"}, now)
+
+	claims := func(exp time.Time) map[string]interface{} {
+		c := map[string]interface{}{"sub": "42", "iss": "gokit", "aud": "api"}
+		if !exp.IsZero() {
+			c["exp"] = exp.Unix()
+		}
+		return c
+	}
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	rsaToken, _ := rsaManager.Issue(Claims{Subject: "42"})
+	edToken, _ := edManager.Issue(Claims{Subject: "42"})
+
+	// Payload claiming another subject, with the original signature
+	tamperedClaims, _ := json.Marshal(claims(now.Add(time.Hour)))
+	tamperedClaims = []byte(strings.Replace(string(tamperedClaims), `"42"`, `"1"`, 1))
+
+	tests := []struct {
+		name    string
+		manager *Manager
+		token   string
+		code    string // "" for a valid token
+	}{
+		{"valid", m, pair.AccessToken, ""},
+		{"RS256", rsaManager, rsaToken, ""},
+		{"EdDSA", edManager, edToken, ""},
+		{"malformed", m, "not.a-token", errors.ErrCodeInvalidToken},
+		{"alg none", m, encodeSegment([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + ".", errors.ErrCodeInvalidToken},
+		{"alg none uppercase", m, encodeSegment([]byte(`{"alg":"NONE"}`)) + "." + parts[1] + ".", errors.ErrCodeInvalidToken},
+		{"HS256 token for RS256", rsaManager, pair.AccessToken, errors.ErrCodeInvalidToken},
+		{"RS256 token for HS256", m, rsaToken, errors.ErrCodeInvalidToken},
+		{"EdDSA token for RS256", rsaManager, edToken, errors.ErrCodeInvalidToken},
+		{"other secret", otherSecret, pair.AccessToken, errors.ErrCodeInvalidToken},
+		{"tampered signature", m, parts[0] + "." + parts[1] + "." + encodeSegment([]byte("forged")), errors.ErrCodeInvalidToken},
+		{"tampered payload", m, parts[0] + "." + encodeSegment(tamperedClaims) + "." + parts[2], errors.ErrCodeInvalidToken},
+		{"missing expiry", m, signRaw(t, m, hs256, claims(time.Time{})), errors.ErrCodeInvalidToken},
+		{"expired within leeway", m, signRaw(t, m, hs256, claims(now.Add(-5*time.Second))), ""},
+		{"expired beyond leeway", m, signRaw(t, m, hs256, claims(now.Add(-11*time.Second))), errors.ErrCodeTokenExpired},
+		{"not valid yet", m, signRaw(t, m, hs256, map[string]interface{}{"iss": "gokit", "aud": "api", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()}), errors.ErrCodeInvalidToken},
+		{"other issuer", otherIssuer, pair.AccessToken, errors.ErrCodeInvalidToken},
+		{"other audience", otherAudience, pair.AccessToken, errors.ErrCodeInvalidToken},
+		{"refresh as access", m, pair.RefreshToken, errors.ErrCodeInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.manager.Verify(context.Background(), tt.token)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("Expected a valid token, got %v", err)
+				}
+				if got.Subject != "42" {
+					t.Errorf("Expected subject 42, got %q", got.Subject)
+				}
+				return
+			}
+			if code := errorCode(err); code != tt.code {
+				t.Errorf("Expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+
+	if _, err := m.VerifyRefresh(context.Background(), pair.AccessToken); errorCode(err) != errors.ErrCodeInvalidToken {
+		t.Errorf("Expected an access token to be refused as a refresh token, got %v", err)
+	}
+}
+
+func TestRefreshRotates(t *testing.T) {
+	now := time.Now()
+	m := newTestManager(t, Config{}, now)
+	ctx := context.Background()
+	pair, err := m.IssuePair(Claims{Subject: "42", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+
+	renewed, err := m.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	claims, err := m.Verify(ctx, renewed.AccessToken)
+	if err != nil || claims.Subject != "42" || !claims.HasRole("admin") {
+		t.Fatalf("Expected the claims to be kept, got %+v and %v", claims, err)
+	}
+
+	if _, err := m.Refresh(ctx, pair.RefreshToken); errorCode(err) != errors.ErrCodeInvalidToken {
+		t.Errorf("Expected a reused refresh token to be refused, got %v", err)
+	}
+	if _, err := m.Refresh(ctx, renewed.RefreshToken); err != nil {
+		t.Errorf("Expected the rotated refresh token to be exchanged, got %v", err)
+	}
+	if _, err := m.Refresh(ctx, renewed.AccessToken); errorCode(err) != errors.ErrCodeInvalidToken {
+		t.Errorf("Expected an access token to be refused by Refresh, got %v", err)
+	}
+}
+
+func TestRefreshReuseAfterCachePressure(t *testing.T) {
+	now := time.Now()
+	m := newTestManager(t, Config{}, now)
+	ctx := context.Background()
+	pair, err := m.IssuePair(Claims{Subject: "42"})
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	if _, err := m.Refresh(ctx, pair.RefreshToken); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+
+	// More exchanges than an LRU cache of the default size holds
+	for i := 0; i < 2*cache.DefaultMaxEntries; i++ {
+		claims := &Claims{ID: fmt.Sprintf("other-%d", i), ExpiresAt: now.Add(time.Hour)}
+		if err := m.Consume(ctx, claims); err != nil {
+			t.Fatalf("Failed to consume token %d: %v", i, err)
+		}
+	}
+	if _, err := m.Refresh(ctx, pair.RefreshToken); errorCode(err) != errors.ErrCodeInvalidToken {
+		t.Errorf("Expected a reused refresh token to be refused, got %v", err)
+	}
+}
+
+func TestRefreshConcurrentExchange(t *testing.T) {
+	stores := []struct {
+		name  string
+		store cache.Adder
+	}{
+		{"default", nil},
+		{"redis", cache.NewRedis(newFakeRedis(), "test:")},
+	}
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, Config{RefreshTokens: tt.store}, time.Now())
+			pair, err := m.IssuePair(Claims{Subject: "42"})
+			if err != nil {
+				t.Fatalf("Failed to issue tokens: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			exchanged := 0
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := m.Refresh(context.Background(), pair.RefreshToken); err == nil {
+						mu.Lock()
+						exchanged++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			if exchanged != 1 {
+				t.Errorf("Expected the refresh token to be exchanged once, got %d", exchanged)
+			}
+		})
+	}
+}
+
+// newFakeRedis returns a Redis client answering SET with and without NX
+// from a map, like a Redis server running one command at a time
+func newFakeRedis() cache.RedisFunc {
+	var mu sync.Mutex
+	values := make(map[string][]byte)
+	return func(_ context.Context, args ...interface{}) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if args[0] != "SET" {
+			return nil, fmt.Errorf("unexpected command %v", args[0])
+		}
+		key := args[1].(string)
+		for _, arg := range args[3:] {
+			if _, ok := values[key]; ok && arg == "NX" {
+				return nil, nil
+			}
+		}
+		values[key] = args[2].([]byte)
+		return "OK", nil
+	}
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// TokenType tells access tokens from refresh tokens
+type TokenType string
+
+const (
+	// AccessToken authenticates requests
+	AccessToken TokenType = "access"
+	// RefreshToken is exchanged for a new token pair
+	RefreshToken TokenType = "refresh"
+)
+
+// Claims are the contents of a token. Extra holds custom claims, which are
+// written next to the registered ones, e.g. {"sub":"42","tenant":"acme"}.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	Type      TokenType
+	Roles     []string
+	Extra     map[string]interface{}
+}
+
+// registeredClaims is the JSON form of the claims gokit understands
+type registeredClaims struct {
+	Subject   string          `json:"sub,omitempty"`
+	Issuer    string          `json:"iss,omitempty"`
+	Audience  json.RawMessage `json:"aud,omitempty"`
+	ExpiresAt float64         `json:"exp,omitempty"`
+	NotBefore float64         `json:"nbf,omitempty"`
+	IssuedAt  float64         `json:"iat,omitempty"`
+	ID        string          `json:"jti,omitempty"`
+	Type      TokenType       `json:"typ,omitempty"`
+	Roles     []string        `json:"roles,omitempty"`
+}
+
+// registeredNames are the JSON names of registeredClaims
+var registeredNames = []string{"sub", "iss", "aud", "exp", "nbf", "iat", "jti", "typ", "roles"}
+
+// Get decodes a custom claim into v, reporting whether it is present
+func (c *Claims) Get(name string, v interface{}) (bool, error) {
+	value, ok := c.Extra[name]
+	if !ok {
+		return false, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return true, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// Set sets a custom claim
+func (c *Claims) Set(name string, value interface{}) {
+	if c.Extra == nil {
+		c.Extra = make(map[string]interface{})
+	}
+	c.Extra[name] = value
+}
+
+// HasRole reports whether the claims carry one of the roles
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, have := range c.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MarshalJSON writes the registered claims and the custom claims as one object
+func (c Claims) MarshalJSON() ([]byte, error) {
+	registered := registeredClaims{
+		Subject:   c.Subject,
+		Issuer:    c.Issuer,
+		ExpiresAt: unix(c.ExpiresAt),
+		NotBefore: unix(c.NotBefore),
+		IssuedAt:  unix(c.IssuedAt),
+		ID:        c.ID,
+		Type:      c.Type,
+		Roles:     c.Roles,
+	}
+	// A single audience is written as a string, as most verifiers expect
+	switch len(c.Audience) {
+	case 0:
+	case 1:
+		registered.Audience, _ = json.Marshal(c.Audience[0])
+	default:
+		registered.Audience, _ = json.Marshal(c.Audience)
+	}
+
+	if len(c.Extra) == 0 {
+		return json.Marshal(registered)
+	}
+
+	data, err := json.Marshal(registered)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]json.RawMessage, len(c.Extra)+len(registeredNames))
+	for name, value := range c.Extra {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		merged[name] = raw
+	}
+	// Registered claims win over custom claims of the same name
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON reads the registered claims and keeps the rest in Extra
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var registered registeredClaims
+	if err := json.Unmarshal(data, &registered); err != nil {
+		return err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, name := range registeredNames {
+		delete(all, name)
+	}
+
+	*c = Claims{
+		Subject:   registered.Subject,
+		Issuer:    registered.Issuer,
+		ExpiresAt: fromUnix(registered.ExpiresAt),
+		NotBefore: fromUnix(registered.NotBefore),
+		IssuedAt:  fromUnix(registered.IssuedAt),
+		ID:        registered.ID,
+		Type:      registered.Type,
+		Roles:     registered.Roles,
+	}
+	if len(all) > 0 {
+		c.Extra = all
+	}
+
+	// The audience is a string or an array of strings
+	if len(registered.Audience) > 0 {
+		var audience string
+		if err := json.Unmarshal(registered.Audience, &audience); err == nil {
+			c.Audience = []string{audience}
+		} else if err := json.Unmarshal(registered.Audience, &c.Audience); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unix returns a time in whole seconds since the epoch, or 0 for the zero time
+func unix(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
+
+// fromUnix returns the time of seconds since the epoch, which other issuers
+// may write with a fraction, or the zero time for 0
+func fromUnix(seconds float64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	whole := math.Floor(seconds)
+	return time.Unix(int64(whole), int64((seconds-whole)*1e9))
+}
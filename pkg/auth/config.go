@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// NewConfigFromEnv loads configuration from environment variables:
+// JWT_ALGORITHM, JWT_SECRET, JWT_PRIVATE_KEY_FILE, JWT_PUBLIC_KEY_FILE,
+// JWT_KEY_ID, JWT_ISSUER, JWT_AUDIENCE, JWT_ACCESS_TTL, JWT_REFRESH_TTL, and
+// JWT_LEEWAY, with durations such as "15m"
+func NewConfigFromEnv() (Config, error) {
+	config := Config{
+		Algorithm: Algorithm(os.Getenv("JWT_ALGORITHM")),
+		Secret:    []byte(os.Getenv("JWT_SECRET")),
+		KeyID:     os.Getenv("JWT_KEY_ID"),
+		Issuer:    os.Getenv("JWT_ISSUER"),
+		Audience:  os.Getenv("JWT_AUDIENCE"),
+	}
+
+	if path := os.Getenv("JWT_PRIVATE_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("auth: read private key: %w", err)
+		}
+		if config.PrivateKey, err = ParsePrivateKeyPEM(data); err != nil {
+			return config, err
+		}
+	}
+	if path := os.Getenv("JWT_PUBLIC_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, fmt.Errorf("auth: read public key: %w", err)
+		}
+		if config.PublicKey, err = ParsePublicKeyPEM(data); err != nil {
+			return config, err
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"JWT_ACCESS_TTL":  &config.AccessTTL,
+		"JWT_REFRESH_TTL": &config.RefreshTTL,
+		"JWT_LEEWAY":      &config.Leeway,
+	}
+	for name, target := range durations {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("auth: invalid %s: %w", name, err)
+		}
+		*target = d
+	}
+	return config, nil
+}
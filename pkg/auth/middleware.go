@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/anaknegeri/gokit/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+)
+
+// claimsLocalsKey is the Fiber locals key of the claims of a request
+const claimsLocalsKey = "gokit.auth.claims"
+
+// claimsContextKey is the context key of the claims of a request
+type claimsContextKey struct{}
+
+// MiddlewareConfig configures the authentication middleware
+type MiddlewareConfig struct {
+	// Cookie is a cookie read for the token when there is no Authorization
+	// header, e.g. for browser sessions
+	Cookie string
+
+	// Query is a query parameter read for the token when there is no
+	// Authorization header, e.g. "access_token" for WebSocket handshakes
+	Query string
+
+	// Optional lets requests without a token through unauthenticated.
+	// Requests with an invalid token are still rejected.
+	Optional bool
+
+	// Skip reports whether a request is not authenticated, e.g. health checks
+	Skip func(c *fiber.Ctx) bool
+}
+
+// Middleware returns a middleware that authenticates requests with an access
+// token in the Authorization header ("Bearer <token>"). The claims are
+// available to later handlers with CurrentClaims, and from the user context
+// with ClaimsFromContext, which also carries the roles for StructCtx role
+// validations. Requests without a token are rejected with 401 UNAUTHORIZED,
+// and invalid or expired tokens with 401 INVALID_TOKEN or TOKEN_EXPIRED.
+func (m *Manager) Middleware(config ...MiddlewareConfig) fiber.Handler {
+	cfg := MiddlewareConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			return c.Next()
+		}
+
+		token := bearerToken(c, cfg)
+		if token == "" {
+			if cfg.Optional {
+				return c.Next()
+			}
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return response.Error(c, errors.UnauthorizedError(""))
+		}
+
+		claims, err := m.Verify(c.UserContext(), token)
+		if err != nil {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return response.Error(c, err)
+		}
+
+		c.Locals(claimsLocalsKey, claims)
+		ctx := ContextWithClaims(c.UserContext(), claims)
+		c.SetUserContext(validator.ContextWithRoles(ctx, claims.Roles...))
+		return c.Next()
+	}
+}
+
+// refreshRequest is the body accepted by RefreshHandler
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" form:"refreshToken"`
+}
+
+// RefreshHandler returns a handler that exchanges the refresh token of a
+// {"refreshToken": "..."} body for a new token pair:
+//
+//	app.Post("/auth/refresh", tokens.RefreshHandler())
+func (m *Manager) RefreshHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req refreshRequest
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+		if req.RefreshToken == "" {
+			return response.BadRequest(c, "Refresh token is required", nil)
+		}
+
+		pair, err := m.Refresh(c.UserContext(), req.RefreshToken)
+		if err != nil {
+			return response.Error(c, err)
+		}
+		return response.Success(c, "Token refreshed", pair)
+	}
+}
+
+// bearerToken returns the token of a request, or "" when there is none
+func bearerToken(c *fiber.Ctx, cfg MiddlewareConfig) string {
+	if authorization := c.Get(fiber.HeaderAuthorization); authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if cfg.Cookie != "" {
+		if token := c.Cookies(cfg.Cookie); token != "" {
+			return token
+		}
+	}
+	if cfg.Query != "" {
+		return c.Query(cfg.Query)
+	}
+	return ""
+}
+
+// ContextWithClaims returns a copy of ctx that carries the claims
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored in ctx, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// CurrentClaims returns the claims of an authenticated request
+func CurrentClaims(c *fiber.Ctx) (*Claims, bool) {
+	claims, ok := c.Locals(claimsLocalsKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// LogFields adds the subject of the claims in a context to log entries as
+// user_id. Register it with logger.AddContextExtractor(auth.LogFields).
+func LogFields(ctx context.Context) logger.Fields {
+	if claims, ok := ClaimsFromContext(ctx); ok && claims.Subject != "" {
+		return logger.Fields{"user_id": claims.Subject}
+	}
+	return nil
+}
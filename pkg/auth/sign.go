@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Algorithm is a token signing algorithm
+type Algorithm string
+
+const (
+	// HS256 signs with HMAC SHA-256 and a shared secret
+	HS256 Algorithm = "HS256"
+	// RS256 signs with RSA PKCS #1 v1.5 and SHA-256
+	RS256 Algorithm = "RS256"
+	// EdDSA signs with Ed25519
+	EdDSA Algorithm = "EdDSA"
+)
+
+// minSecretLength is the shortest HS256 secret accepted, as long as the hash
+const minSecretLength = 32
+
+// keys signs and verifies tokens with one algorithm
+type keys struct {
+	algorithm  Algorithm
+	secret     []byte
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// newKeys checks that the keys of a config suit its algorithm
+func newKeys(config Config) (*keys, error) {
+	k := &keys{algorithm: config.Algorithm, secret: config.Secret, privateKey: config.PrivateKey, publicKey: config.PublicKey}
+	if k.publicKey == nil && k.privateKey != nil {
+		k.publicKey = k.privateKey.Public()
+	}
+
+	switch k.algorithm {
+	case HS256:
+		if len(k.secret) < minSecretLength {
+			return nil, fmt.Errorf("auth: HS256 needs a secret of at least %d bytes", minSecretLength)
+		}
+	case RS256:
+		if _, ok := k.publicKey.(*rsa.PublicKey); !ok {
+			return nil, errors.New("auth: RS256 needs an RSA key")
+		}
+	case EdDSA:
+		if _, ok := k.publicKey.(ed25519.PublicKey); !ok {
+			return nil, errors.New("auth: EdDSA needs an Ed25519 key")
+		}
+	default:
+		return nil, fmt.Errorf("auth: unsupported algorithm %q", k.algorithm)
+	}
+	return k, nil
+}
+
+// sign signs the signing input of a token
+func (k *keys) sign(input []byte) ([]byte, error) {
+	switch k.algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case RS256:
+		if k.privateKey == nil {
+			return nil, errors.New("auth: no private key to sign with")
+		}
+		digest := sha256.Sum256(input)
+		return k.privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		if k.privateKey == nil {
+			return nil, errors.New("auth: no private key to sign with")
+		}
+		return k.privateKey.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+}
+
+// verify reports whether a signature of the signing input is valid
+func (k *keys) verify(input, signature []byte) bool {
+	switch k.algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case RS256:
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(k.publicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	default:
+		return ed25519.Verify(k.publicKey.(ed25519.PublicKey), input, signature)
+	}
+}
+
+// ParsePrivateKeyPEM parses an RSA or Ed25519 private key in PKCS #8 or, for
+// RSA, PKCS #1 PEM form
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("auth: no PEM block in private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("auth: unsupported private key %T", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM parses an RSA or Ed25519 public key in PKIX or, for RSA,
+// PKCS #1 PEM form
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("auth: no PEM block in public key")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parse public key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,192 @@
+// Package authz checks what users may do from the permissions of their roles
+package authz
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultCacheTTL is how long the permissions of a role are cached by default
+	DefaultCacheTTL = time.Minute
+
+	// OwnSuffix marks a permission limited to the user's own resources, e.g.
+	// "files:delete:own"
+	OwnSuffix = ":own"
+
+	// cacheTag tags every cached permission list
+	cacheTag = "authz"
+)
+
+// Subject is a user whose permissions are checked
+type Subject struct {
+	ID    string
+	Roles []string
+}
+
+// Owned is a resource with an owner, e.g. an uploaded file
+type Owned interface {
+	OwnerID() string
+}
+
+// Config configures an authorizer
+type Config struct {
+	// Store loads role permissions and user roles, e.g. NewGormStore(db).
+	// Roles granted with Grant are used with or without a store.
+	Store Store
+
+	// Cache holds the permissions loaded from the store, defaulting to an
+	// in-memory cache
+	Cache cache.Cache
+
+	// CacheTTL defaults to DefaultCacheTTL
+	CacheTTL time.Duration
+
+	// Subject returns the user of a request, defaulting to the subject and
+	// roles of the token claims set by the auth middleware
+	Subject func(c *fiber.Ctx) (Subject, bool)
+}
+
+// Authorizer grants permissions to roles and checks them. Permissions are
+// names such as "files:delete"; "files:*" grants every permission starting
+// with "files:" and "*" grants every permission.
+type Authorizer struct {
+	config Config
+
+	mu     sync.RWMutex
+	grants map[string][]string
+}
+
+// New creates an authorizer
+func New(config ...Config) *Authorizer {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Cache == nil {
+		cfg.Cache = cache.NewMemory(0)
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	if cfg.Subject == nil {
+		cfg.Subject = claimsSubject
+	}
+	return &Authorizer{config: cfg, grants: make(map[string][]string)}
+}
+
+// Grant gives a role permissions in addition to those in the store
+func (a *Authorizer) Grant(role string, permissions ...string) *Authorizer {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.grants[role] = append(a.grants[role], permissions...)
+	return a
+}
+
+// Invalidate drops the cached permissions, e.g. after roles or permissions
+// were changed in the store
+func (a *Authorizer) Invalidate(ctx context.Context) error {
+	return a.config.Cache.DeleteTags(ctx, cacheTag)
+}
+
+// Permissions returns the permissions of roles
+func (a *Authorizer) Permissions(ctx context.Context, roles ...string) ([]string, error) {
+	seen := make(map[string]bool)
+	var permissions []string
+	add := func(values []string) {
+		for _, p := range values {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+
+	a.mu.RLock()
+	for _, role := range roles {
+		add(a.grants[role])
+	}
+	a.mu.RUnlock()
+
+	if a.config.Store != nil && len(roles) > 0 {
+		stored, err := a.storedPermissions(ctx, roles)
+		if err != nil {
+			return nil, err
+		}
+		add(stored)
+	}
+	return permissions, nil
+}
+
+// storedPermissions returns the permissions of roles from the store through
+// the cache
+func (a *Authorizer) storedPermissions(ctx context.Context, roles []string) ([]string, error) {
+	sorted := append([]string(nil), roles...)
+	sort.Strings(sorted)
+	key := "authz:roles:" + strings.Join(sorted, ",")
+	return cache.Remember(ctx, a.config.Cache, key, a.config.CacheTTL, func(ctx context.Context) ([]string, error) {
+		return a.config.Store.RolePermissions(ctx, sorted)
+	}, cacheTag)
+}
+
+// roles returns the roles of a subject, loading them from the store when the
+// subject carries none
+func (a *Authorizer) roles(ctx context.Context, subject Subject) ([]string, error) {
+	if len(subject.Roles) > 0 || subject.ID == "" || a.config.Store == nil {
+		return subject.Roles, nil
+	}
+	return cache.Remember(ctx, a.config.Cache, "authz:user:"+subject.ID, a.config.CacheTTL, func(ctx context.Context) ([]string, error) {
+		return a.config.Store.UserRoles(ctx, subject.ID)
+	}, cacheTag)
+}
+
+// Can reports whether a subject has a permission
+func (a *Authorizer) Can(ctx context.Context, subject Subject, permission string) (bool, error) {
+	roles, err := a.roles(ctx, subject)
+	if err != nil {
+		return false, err
+	}
+	permissions, err := a.Permissions(ctx, roles...)
+	if err != nil {
+		return false, err
+	}
+	return Match(permissions, permission), nil
+}
+
+// CanAccess reports whether a subject has a permission on a resource: either
+// the permission itself, or the permission with OwnSuffix when the subject
+// owns the resource
+func (a *Authorizer) CanAccess(ctx context.Context, subject Subject, permission string, resource Owned) (bool, error) {
+	roles, err := a.roles(ctx, subject)
+	if err != nil {
+		return false, err
+	}
+	permissions, err := a.Permissions(ctx, roles...)
+	if err != nil {
+		return false, err
+	}
+	if Match(permissions, permission) {
+		return true, nil
+	}
+	owner := resource != nil && subject.ID != "" && resource.OwnerID() == subject.ID
+	return owner && Match(permissions, permission+OwnSuffix), nil
+}
+
+// Match reports whether granted permissions include a permission
+func Match(granted []string, permission string) bool {
+	for _, g := range granted {
+		if g == "*" || g == permission {
+			return true
+		}
+		if strings.HasSuffix(g, ":*") && strings.HasPrefix(permission, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+	return false
+}
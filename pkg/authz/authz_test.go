@@ -0,0 +1,231 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// testStore is a store of fixed roles and permissions that counts its loads
+type testStore struct {
+	permissions map[string][]string
+	users       map[string][]string
+	loads       int
+}
+
+func (s *testStore) RolePermissions(_ context.Context, roles []string) ([]string, error) {
+	s.loads++
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, s.permissions[role]...)
+	}
+	return permissions, nil
+}
+
+func (s *testStore) UserRoles(_ context.Context, userID string) ([]string, error) {
+	s.loads++
+	return s.users[userID], nil
+}
+
+// file is an owned resource
+type file struct {
+	owner string
+}
+
+func (f file) OwnerID() string { return f.owner }
+
+// headerSubject reads the user of a request from the X-User and X-Roles
+// headers
+func headerSubject(c *fiber.Ctx) (Subject, bool) {
+	id := c.Get("X-User")
+	if id == "" {
+		return Subject{}, false
+	}
+	var roles []string
+	if value := c.Get("X-Roles"); value != "" {
+		roles = strings.Split(value, ",")
+	}
+	return Subject{ID: id, Roles: roles}, true
+}
+
+// newTestAuthorizer returns an authorizer of admins, editors, viewers, and
+// guests, whose editors and viewers come from a store
+func newTestAuthorizer() (*Authorizer, *testStore) {
+	store := &testStore{
+		permissions: map[string][]string{
+			"editor": {"files:read", "files:upload", "files:delete:own"},
+			"viewer": {"files:read"},
+		},
+		users: map[string][]string{"7": {"editor"}},
+	}
+	rbac := New(Config{Store: store, Subject: headerSubject}).
+		Grant("admin", "*").
+		Grant("guest")
+	return rbac, store
+}
+
+// errorCode returns the code of an AppError, or ""
+func errorCode(err error) string {
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
+
+func TestRequirePermission(t *testing.T) {
+	rbac, _ := newTestAuthorizer()
+	app := fiber.New()
+	app.Get("/files", rbac.RequirePermission("files:read"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Post("/files", rbac.RequirePermission("files:read", "files:upload"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Delete("/files", rbac.RequirePermission("files:delete"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		user   string
+		roles  string
+		status int
+		code   string
+	}{
+		{"allowed", http.MethodGet, "1", "viewer", http.StatusNoContent, ""},
+		{"every permission", http.MethodPost, "1", "editor", http.StatusNoContent, ""},
+		{"wildcard", http.MethodDelete, "1", "admin", http.StatusNoContent, ""},
+		{"one of several roles", http.MethodPost, "1", "viewer,editor", http.StatusNoContent, ""},
+		{"roles from the store", http.MethodPost, "7", "", http.StatusNoContent, ""},
+		{"no user", http.MethodGet, "", "", http.StatusUnauthorized, errors.ErrCodeUnauthorized},
+		{"denied", http.MethodDelete, "1", "viewer", http.StatusForbidden, errors.ErrCodePermissionDenied},
+		{"one permission missing", http.MethodPost, "1", "viewer", http.StatusForbidden, errors.ErrCodePermissionDenied},
+		{"own permission only", http.MethodDelete, "1", "editor", http.StatusForbidden, errors.ErrCodePermissionDenied},
+		{"role without permissions", http.MethodGet, "1", "guest", http.StatusForbidden, errors.ErrCodePermissionDenied},
+		{"unknown role", http.MethodGet, "1", "owner", http.StatusForbidden, errors.ErrCodePermissionDenied},
+		{"user without roles", http.MethodGet, "8", "", http.StatusForbidden, errors.ErrCodePermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/files", nil)
+			req.Header.Set("X-User", tt.user)
+			req.Header.Set("X-Roles", tt.roles)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.code == "" {
+				return
+			}
+			var body struct {
+				Code string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body.Code != tt.code {
+				t.Errorf("Expected error %s, got %s", tt.code, body.Code)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	rbac, _ := newTestAuthorizer()
+
+	tests := []struct {
+		name       string
+		user       string
+		roles      string
+		permission string
+		resource   Owned
+		code       string // "" when allowed
+	}{
+		{"owner", "1", "editor", "files:delete", file{owner: "1"}, ""},
+		{"not owner", "1", "editor", "files:delete", file{owner: "2"}, errors.ErrCodePermissionDenied},
+		{"no owner", "1", "editor", "files:delete", file{}, errors.ErrCodePermissionDenied},
+		{"no resource", "1", "editor", "files:delete", nil, errors.ErrCodePermissionDenied},
+		{"owner without the own permission", "1", "viewer", "files:delete", file{owner: "1"}, errors.ErrCodePermissionDenied},
+		{"permission on any resource", "1", "admin", "files:delete", file{owner: "2"}, ""},
+		{"permission without ownership", "1", "viewer", "files:read", file{owner: "2"}, ""},
+		{"owner with roles from the store", "7", "", "files:delete", file{owner: "7"}, ""},
+		{"no user", "", "", "files:read", file{owner: "1"}, errors.ErrCodeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			var err error
+			app.Get("/", func(c *fiber.Ctx) error {
+				err = rbac.Authorize(c, tt.permission, tt.resource)
+				return nil
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-User", tt.user)
+			req.Header.Set("X-Roles", tt.roles)
+			if _, testErr := app.Test(req); testErr != nil {
+				t.Fatalf("Request failed: %v", testErr)
+			}
+			if code := errorCode(err); code != tt.code {
+				t.Errorf("Expected %q, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestPermissionsCache(t *testing.T) {
+	rbac, store := newTestAuthorizer()
+	ctx := context.Background()
+	editor := Subject{ID: "7"}
+
+	for i := 0; i < 3; i++ {
+		if ok, err := rbac.Can(ctx, editor, "files:upload"); err != nil || !ok {
+			t.Fatalf("Expected the editor to upload, got %v and %v", ok, err)
+		}
+	}
+	if store.loads != 2 {
+		t.Errorf("Expected the roles and permissions to be loaded once, got %d loads", store.loads)
+	}
+
+	// Changes in the store apply once the cache is invalidated
+	store.permissions["editor"] = []string{"files:read"}
+	if err := rbac.Invalidate(ctx); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	if ok, _ := rbac.Can(ctx, editor, "files:upload"); ok {
+		t.Error("Expected the removed permission to be denied")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		granted    []string
+		permission string
+		want       bool
+	}{
+		{[]string{"files:read"}, "files:read", true},
+		{[]string{"files:read"}, "files:delete", false},
+		{[]string{"files:*"}, "files:delete", true},
+		{[]string{"files:*"}, "files:delete:own", true},
+		{[]string{"files:*"}, "filesystem:read", false},
+		{[]string{"files"}, "files:read", false},
+		{[]string{"*"}, "anything", true},
+		{nil, "files:read", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.granted, tt.permission); got != tt.want {
+			t.Errorf("Match(%v, %q) = %v, want %v", tt.granted, tt.permission, got, tt.want)
+		}
+	}
+}
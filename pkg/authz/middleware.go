@@ -0,0 +1,62 @@
+package authz
+
+import (
+	"github.com/anaknegeri/gokit/pkg/auth"
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission returns a middleware that lets requests through when
+// their user has every permission, e.g. RequirePermission("files:delete").
+// Requests without a user are rejected with 401 UNAUTHORIZED and users
+// lacking a permission with 403 PERMISSION_DENIED.
+func (a *Authorizer) RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		subject, ok := a.config.Subject(c)
+		if !ok {
+			return response.Error(c, errors.UnauthorizedError(""))
+		}
+		for _, permission := range permissions {
+			allowed, err := a.Can(c.UserContext(), subject, permission)
+			if err != nil {
+				return response.Error(c, err)
+			}
+			if !allowed {
+				return response.Error(c, errors.PermissionDeniedError(permission))
+			}
+		}
+		return c.Next()
+	}
+}
+
+// Authorize checks that the user of a request has a permission on a
+// resource, returning an UNAUTHORIZED or PERMISSION_DENIED error otherwise.
+// Handlers call it after loading the resource:
+//
+//	if err := rbac.Authorize(c, "files:delete", file); err != nil {
+//		return response.Error(c, err)
+//	}
+func (a *Authorizer) Authorize(c *fiber.Ctx, permission string, resource Owned) error {
+	subject, ok := a.config.Subject(c)
+	if !ok {
+		return errors.UnauthorizedError("")
+	}
+	allowed, err := a.CanAccess(c.UserContext(), subject, permission, resource)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.PermissionDeniedError(permission)
+	}
+	return nil
+}
+
+// claimsSubject returns the subject of the token claims of a request
+func claimsSubject(c *fiber.Ctx) (Subject, bool) {
+	claims, ok := auth.CurrentClaims(c)
+	if !ok {
+		return Subject{}, false
+	}
+	return Subject{ID: claims.Subject, Roles: claims.Roles}, true
+}
@@ -0,0 +1,100 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Store loads roles and permissions, e.g. from a database
+type Store interface {
+	// RolePermissions returns the permissions of roles, by role name
+	RolePermissions(ctx context.Context, roles []string) ([]string, error)
+
+	// UserRoles returns the role names of a user
+	UserRoles(ctx context.Context, userID string) ([]string, error)
+}
+
+// GormTables names the tables of GormStore. The defaults match GORM models
+// such as:
+//
+//	type Role struct {
+//		ID          string
+//		Name        string       `gorm:"uniqueIndex"`
+//		Permissions []Permission `gorm:"many2many:role_permissions;"`
+//	}
+//
+//	type Permission struct {
+//		ID   string
+//		Name string `gorm:"uniqueIndex"`
+//	}
+//
+//	type User struct {
+//		ID    string
+//		Roles []Role `gorm:"many2many:user_roles;"`
+//	}
+type GormTables struct {
+	Roles           string // id and name columns, defaulting to "roles"
+	Permissions     string // id and name columns, defaulting to "permissions"
+	RolePermissions string // role_id and permission_id columns, defaulting to "role_permissions"
+	UserRoles       string // user_id and role_id columns, defaulting to "user_roles"
+}
+
+// GormStore loads roles and permissions with GORM
+type GormStore struct {
+	db     *gorm.DB
+	tables GormTables
+}
+
+// NewGormStore creates a store reading the tables of the Role, Permission,
+// and User models with many-to-many associations
+func NewGormStore(db *gorm.DB, tables ...GormTables) *GormStore {
+	t := GormTables{}
+	if len(tables) > 0 {
+		t = tables[0]
+	}
+	if t.Roles == "" {
+		t.Roles = "roles"
+	}
+	if t.Permissions == "" {
+		t.Permissions = "permissions"
+	}
+	if t.RolePermissions == "" {
+		t.RolePermissions = "role_permissions"
+	}
+	if t.UserRoles == "" {
+		t.UserRoles = "user_roles"
+	}
+	return &GormStore{db: db, tables: t}
+}
+
+// RolePermissions returns the permissions of roles, by role name
+func (s *GormStore) RolePermissions(ctx context.Context, roles []string) ([]string, error) {
+	var permissions []string
+	err := s.db.WithContext(ctx).
+		Table(s.tables.Permissions+" AS p").
+		Distinct("p.name").
+		Joins("JOIN "+s.tables.RolePermissions+" AS rp ON rp.permission_id = p.id").
+		Joins("JOIN "+s.tables.Roles+" AS r ON r.id = rp.role_id").
+		Where("r.name IN ?", roles).
+		Pluck("p.name", &permissions).Error
+	if err != nil {
+		return nil, errors.FromGormError(err)
+	}
+	return permissions, nil
+}
+
+// UserRoles returns the role names of a user
+func (s *GormStore) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	var roles []string
+	err := s.db.WithContext(ctx).
+		Table(s.tables.Roles+" AS r").
+		Joins("JOIN "+s.tables.UserRoles+" AS ur ON ur.role_id = r.id").
+		Where("ur.user_id = ?", userID).
+		Pluck("r.name", &roles).Error
+	if err != nil {
+		return nil, errors.FromGormError(err)
+	}
+	return roles, nil
+}
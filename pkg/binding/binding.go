@@ -0,0 +1,162 @@
+// Package binding parses and validates Fiber requests into structs
+package binding
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/anaknegeri/gokit/pkg/validator"
+	playground "github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	validatorMu      sync.RWMutex
+	defaultValidator validator.Validator
+)
+
+// SetValidator sets the validator used to validate bound structs. It
+// defaults to a validator with the built-in validations of validator.WithDefaults.
+func SetValidator(v validator.Validator) {
+	validatorMu.Lock()
+	defer validatorMu.Unlock()
+	defaultValidator = v
+}
+
+// getValidator returns the validator, creating the default one on first use
+func getValidator() validator.Validator {
+	validatorMu.RLock()
+	v := defaultValidator
+	validatorMu.RUnlock()
+	if v != nil {
+		return v
+	}
+
+	validatorMu.Lock()
+	defer validatorMu.Unlock()
+	if defaultValidator == nil {
+		defaultValidator = validator.NewValidator(validator.WithDefaults())
+	}
+	return defaultValidator
+}
+
+// BindAndValidate parses the request body into dto according to its content
+// type (JSON, XML, URL-encoded or multipart form) and validates it. Uploaded
+// files of multipart forms are bound to *multipart.FileHeader and
+// []*multipart.FileHeader fields by their form tag, and checked against their
+// upload tags with validator.FileValidator. Fields are normalized with their
+// mod tags, such as mod:"trim,lcase", before validation. On failure it sends
+// the error response and returns false with the result of sending it:
+//
+//	var req CreateUserRequest
+//	if ok, err := binding.BindAndValidate(c, &req); !ok {
+//		return err
+//	}
+//
+// Malformed bodies are answered with 400, unsupported content types with 415,
+// and validation failures with 422 in the request locale.
+// An empty body is not parsed, so required fields are reported as missing.
+func BindAndValidate(c *fiber.Ctx, dto interface{}) (bool, error) {
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(dto); err != nil {
+			if err == fiber.ErrUnprocessableEntity {
+				return false, response.Error(c, errors.UnsupportedMediaTypeError(string(c.Request().Header.ContentType())))
+			}
+			return false, response.Error(c, errors.InvalidRequestError(err.Error()))
+		}
+		if err := bindFiles(c, dto); err != nil {
+			return false, response.Error(c, errors.InvalidRequestError(err.Error()))
+		}
+	}
+	return validate(c, dto)
+}
+
+// bindFiles sets the file fields of dto from a multipart form
+func bindFiles(c *fiber.Ctx, dto interface{}) error {
+	if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEMultipartForm) {
+		return nil
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return err
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(dto))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		files := form.File[name]
+		if len(files) == 0 {
+			continue
+		}
+
+		switch field.Type {
+		case reflect.TypeOf((*multipart.FileHeader)(nil)):
+			value.Field(i).Set(reflect.ValueOf(files[0]))
+		case reflect.TypeOf([]*multipart.FileHeader(nil)):
+			value.Field(i).Set(reflect.ValueOf(files))
+		}
+	}
+	return nil
+}
+
+// BindQuery parses the query string into dto using its query tags and
+// validates it, sending the error response on failure like BindAndValidate
+func BindQuery(c *fiber.Ctx, dto interface{}) (bool, error) {
+	if err := c.QueryParser(dto); err != nil {
+		return false, response.Error(c, errors.InvalidRequestError(err.Error()))
+	}
+	return validate(c, dto)
+}
+
+// BindParams parses the route parameters into dto using its params tags and
+// validates it, sending the error response on failure like BindAndValidate
+func BindParams(c *fiber.Ctx, dto interface{}) (bool, error) {
+	if err := c.ParamsParser(dto); err != nil {
+		return false, response.Error(c, errors.InvalidRequestError(err.Error()))
+	}
+	return validate(c, dto)
+}
+
+// validate normalizes a bound struct with its mod tags and validates it and
+// its uploaded files, sending the validation errors on failure
+func validate(c *fiber.Ctx, dto interface{}) (bool, error) {
+	if err := validator.Sanitize(dto); err != nil {
+		return false, response.Error(c, err)
+	}
+
+	var failures playground.ValidationErrors
+	for _, err := range []error{
+		getValidator().StructCtx(c.UserContext(), dto),
+		validator.NewFileValidator().Struct(dto),
+	} {
+		if err == nil {
+			continue
+		}
+		var validationErrs playground.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return false, response.Error(c, err)
+		}
+		failures = append(failures, validationErrs...)
+	}
+
+	if len(failures) > 0 {
+		errors.SortValidationErrors(failures, dto)
+		return false, response.ValidationError(c, failures)
+	}
+	return true, nil
+}
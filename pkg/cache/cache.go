@@ -0,0 +1,146 @@
+// Package cache stores values with expiry times in memory or in Redis
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrMiss is returned by Get when a key is missing or expired
+var ErrMiss = errors.New("cache: miss")
+
+// ErrLoadPanicked is returned by GetOrSet to the callers waiting for a load
+// whose function panicked. The caller that ran it panics again.
+var ErrLoadPanicked = errors.New("cache: load panicked")
+
+// Cache stores byte values by key with an optional time to live. Tags group
+// keys so related values can be removed together, e.g. every value computed
+// from one user's records.
+type Cache interface {
+	// Get returns the value of a key, or ErrMiss when it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores a value for ttl, or without expiry when ttl is 0, tagged
+	// with the tags
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+
+	// Delete removes keys
+	Delete(ctx context.Context, keys ...string) error
+
+	// DeleteTags removes the keys stored with any of the tags
+	DeleteTags(ctx context.Context, tags ...string) error
+
+	// GetOrSet returns the value of a key, or loads it with fn and stores it.
+	// Concurrent calls for the same missing key wait for a single call of fn.
+	// Values are not stored when fn fails.
+	GetOrSet(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) ([]byte, error), tags ...string) ([]byte, error)
+}
+
+// Adder stores values only when their key is free, e.g. to use up one-time
+// tokens. Memory and Redis implement it.
+type Adder interface {
+	// Add stores a value for ttl, or without expiry when ttl is 0, unless
+	// the key holds an unexpired value, reporting whether it was stored.
+	// The check and the store are atomic.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+}
+
+// Remember returns the value of a key decoded from JSON, or loads it with fn
+// and stores it as JSON, like GetOrSet:
+//
+//	user, err := cache.Remember(ctx, c, "user:42", time.Minute, func(ctx context.Context) (*User, error) {
+//		return users.Find(ctx, 42)
+//	}, "users")
+func Remember[T any](ctx context.Context, c Cache, key string, ttl time.Duration, fn func(ctx context.Context) (T, error), tags ...string) (T, error) {
+	var value T
+	data, err := c.GetOrSet(ctx, key, ttl, func(ctx context.Context) ([]byte, error) {
+		v, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}, tags...)
+	if err != nil {
+		return value, err
+	}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// getOrSet implements GetOrSet for a cache, loading missing values once
+// through the group
+func getOrSet(ctx context.Context, c Cache, g *group, key string, ttl time.Duration, fn func(ctx context.Context) ([]byte, error), tags []string) ([]byte, error) {
+	value, err := c.Get(ctx, key)
+	if err == nil || !errors.Is(err, ErrMiss) {
+		return value, err
+	}
+
+	return g.do(key, func() ([]byte, error) {
+		// Another caller may have stored the value while this one waited
+		if value, err := c.Get(ctx, key); err == nil {
+			return value, nil
+		}
+
+		value, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(ctx, key, value, ttl, tags...); err != nil {
+			return nil, err
+		}
+		return value, nil
+	})
+}
+
+// call is a load in progress
+type call struct {
+	wg    sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// group runs one load per key at a time, sharing its result with the callers
+// that ask for the same key meanwhile
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do runs fn for the key, or waits for the load already running
+func (g *group) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.value, c.err
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// The deferred calls also run when fn panics, so the waiters are released
+	// with an error and the next caller loads the key again
+	defer c.wg.Done()
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			c.value, c.err = nil, fmt.Errorf("%w: %v", ErrLoadPanicked, r)
+			panic(r)
+		}
+	}()
+
+	c.value, c.err = fn()
+	return c.value, c.err
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrSet(t *testing.T) {
+	caches := []struct {
+		name  string
+		cache Cache
+	}{
+		{"memory", NewMemory(0)},
+		{"redis", NewRedis(newFakeRedis(), "app:")},
+	}
+	for _, tt := range caches {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var loads int32
+			load := func(context.Context) ([]byte, error) {
+				atomic.AddInt32(&loads, 1)
+				time.Sleep(10 * time.Millisecond)
+				return []byte("value"), nil
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if value, err := tt.cache.GetOrSet(ctx, "key", time.Minute, load); err != nil || string(value) != "value" {
+						t.Errorf("Expected the loaded value, got %q and %v", value, err)
+					}
+				}()
+			}
+			wg.Wait()
+			if loads != 1 {
+				t.Errorf("Expected one load, got %d", loads)
+			}
+
+			failed := errors.New("failed")
+			if _, err := tt.cache.GetOrSet(ctx, "failing", time.Minute, func(context.Context) ([]byte, error) {
+				return nil, failed
+			}); !errors.Is(err, failed) {
+				t.Errorf("Expected the load error, got %v", err)
+			}
+			if _, err := tt.cache.Get(ctx, "failing"); !errors.Is(err, ErrMiss) {
+				t.Errorf("Expected a failed load not to be stored, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	var g group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	recovered := make(chan interface{})
+	go func() {
+		defer func() { recovered <- recover() }()
+		g.do("key", func() ([]byte, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-started
+	go func() {
+		// Give the waiter below time to join the load
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if _, err := g.do("key", func() ([]byte, error) { return []byte("second"), nil }); !errors.Is(err, ErrLoadPanicked) {
+		t.Errorf("Expected the waiter to get ErrLoadPanicked, got %v", err)
+	}
+	if r := <-recovered; r != "boom" {
+		t.Errorf("Expected the loader to panic again, got %v", r)
+	}
+
+	if value, err := g.do("key", func() ([]byte, error) { return []byte("again"), nil }); err != nil || string(value) != "again" {
+		t.Errorf("Expected the key to be loaded again, got %q and %v", value, err)
+	}
+}
+
+func TestRemember(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	c := NewMemory(0)
+	ctx := context.Background()
+	loads := 0
+	load := func(context.Context) (*user, error) {
+		loads++
+		return &user{Name: "Ann"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		u, err := Remember(ctx, c, "user:1", time.Minute, load)
+		if err != nil || u.Name != "Ann" {
+			t.Fatalf("Expected the user, got %+v and %v", u, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected one load, got %d", loads)
+	}
+	if data, _ := c.Get(ctx, "user:1"); string(data) != `{"name":"Ann"}` {
+		t.Errorf("Expected the user to be stored as JSON, got %s", data)
+	}
+}
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is the capacity of a memory cache created without one
+const DefaultMaxEntries = 10000
+
+// sweepInterval is how often a memory cache removes its expired values
+const sweepInterval = time.Minute
+
+// memoryEntry is a value of a memory cache
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+// expired reports whether the entry has expired
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// Memory is an in-process cache that evicts the least recently used values
+// when it is full. Expired values are removed when they are read or evicted,
+// and by a sweep once a minute.
+type Memory struct {
+	mu         sync.Mutex
+	maxEntries int // Negative for no limit
+	nextSweep  time.Time
+	order      *list.List // Front is the most recently used
+	entries    map[string]*list.Element
+	tags       map[string]map[string]struct{}
+	loads      group
+	now        func() time.Time
+}
+
+// NewMemory creates a memory cache holding up to maxEntries values, or
+// DefaultMaxEntries when maxEntries is 0. A negative maxEntries removes the
+// limit, so values are only removed when they expire or are deleted.
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries == 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Memory{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+// Get returns the value of a key, or ErrMiss when it is missing or expired
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if entry.expired(m.now()) {
+		m.remove(elem)
+		return nil, ErrMiss
+	}
+	m.order.MoveToFront(elem)
+	return append([]byte(nil), entry.value...), nil
+}
+
+// Set stores a value for ttl, or without expiry when ttl is 0
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	entry := &memoryEntry{
+		key:   key,
+		value: append([]byte(nil), value...),
+		tags:  tags,
+	}
+	if ttl > 0 {
+		entry.expires = m.now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(entry)
+	return nil
+}
+
+// Add stores a value for ttl unless the key holds an unexpired value,
+// reporting whether it was stored
+func (m *Memory) Add(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	now := m.now()
+	entry := &memoryEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[key]; ok && !elem.Value.(*memoryEntry).expired(now) {
+		return false, nil
+	}
+	m.store(entry)
+	return true, nil
+}
+
+// Delete removes keys
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := m.entries[key]; ok {
+			m.remove(elem)
+		}
+	}
+	return nil
+}
+
+// DeleteTags removes the keys stored with any of the tags
+func (m *Memory) DeleteTags(_ context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			if elem, ok := m.entries[key]; ok {
+				m.remove(elem)
+			}
+		}
+		delete(m.tags, tag)
+	}
+	return nil
+}
+
+// GetOrSet returns the value of a key, or loads it once with fn and stores it
+func (m *Memory) GetOrSet(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) ([]byte, error), tags ...string) ([]byte, error) {
+	return getOrSet(ctx, m, &m.loads, key, ttl, fn, tags)
+}
+
+// Len returns the number of values, including expired ones not yet removed
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// store replaces the entry of its key, evicting the least recently used
+// values over the limit; the caller holds the lock
+func (m *Memory) store(entry *memoryEntry) {
+	if elem, ok := m.entries[entry.key]; ok {
+		m.remove(elem)
+	}
+	m.entries[entry.key] = m.order.PushFront(entry)
+	for _, tag := range entry.tags {
+		if m.tags[tag] == nil {
+			m.tags[tag] = make(map[string]struct{})
+		}
+		m.tags[tag][entry.key] = struct{}{}
+	}
+
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	m.sweep(m.now())
+}
+
+// sweep removes the expired entries at most once per sweepInterval; the
+// caller holds the lock
+func (m *Memory) sweep(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	m.nextSweep = now.Add(sweepInterval)
+	for elem := m.order.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*memoryEntry).expired(now) {
+			m.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// remove removes an entry and its tags; the caller holds the lock
+func (m *Memory) remove(elem *list.Element) {
+	entry := elem.Value.(*memoryEntry)
+	m.order.Remove(elem)
+	delete(m.entries, entry.key)
+	for _, tag := range entry.tags {
+		if keys := m.tags[tag]; keys != nil {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(m.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keys returns the sorted keys of the unexpired values of a memory cache
+func (m *Memory) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for key, elem := range m.entries {
+		if !elem.Value.(*memoryEntry).expired(m.now()) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestMemoryEviction(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		ops        []string // "set <key>" or "get <key>"
+		want       string
+	}{
+		{"least recently set first", 3, []string{"set a", "set b", "set c", "set d"}, "b c d"},
+		{"reads keep values", 3, []string{"set a", "set b", "set c", "get a", "set d"}, "a c d"},
+		{"writes keep values", 3, []string{"set a", "set b", "set c", "set a", "set d"}, "a c d"},
+		{"misses keep nothing", 2, []string{"set a", "set b", "get c", "set d"}, "b d"},
+		{"default capacity", 0, []string{"set a", "set b", "set c", "set d"}, "a b c d"},
+		{"no limit", -1, []string{"set a", "set b", "set c", "set d"}, "a b c d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(tt.maxEntries)
+			ctx := context.Background()
+			for _, op := range tt.ops {
+				action, key, _ := strings.Cut(op, " ")
+				if action == "set" {
+					m.Set(ctx, key, []byte(key), 0)
+				} else {
+					m.Get(ctx, key)
+				}
+			}
+			if got := strings.Join(m.keys(), " "); got != tt.want {
+				t.Errorf("Expected %q to be kept, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		elapsed time.Duration
+		found   bool
+	}{
+		{"no expiry", 0, 365 * 24 * time.Hour, true},
+		{"before expiry", time.Minute, time.Minute - time.Millisecond, true},
+		{"at expiry", time.Minute, time.Minute, false},
+		{"after expiry", time.Minute, time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			m := NewMemory(0)
+			m.now = func() time.Time { return now }
+			ctx := context.Background()
+			m.Set(ctx, "key", []byte("value"), tt.ttl)
+
+			now = now.Add(tt.elapsed)
+			value, err := m.Get(ctx, "key")
+			if tt.found && (err != nil || string(value) != "value") {
+				t.Errorf("Expected the value, got %q and %v", value, err)
+			}
+			if !tt.found && !errors.Is(err, ErrMiss) {
+				t.Errorf("Expected ErrMiss, got %q and %v", value, err)
+			}
+			if added, _ := m.Add(ctx, "key", []byte("added"), 0); added == tt.found {
+				t.Errorf("Expected Add to store the value only over an expired one, got %v", added)
+			}
+		})
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	now := time.Now()
+	m := NewMemory(-1)
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	m.Set(ctx, "short", []byte("1"), time.Second, "tag")
+	m.Set(ctx, "long", []byte("2"), time.Hour, "tag")
+	now = now.Add(time.Second)
+	m.Set(ctx, "other", []byte("3"), 0)
+	if m.Len() != 3 {
+		t.Fatalf("Expected expired values to be kept until the next sweep, got %d", m.Len())
+	}
+
+	now = now.Add(sweepInterval)
+	m.Set(ctx, "other", []byte("3"), 0)
+	if got := strings.Join(m.keys(), " "); m.Len() != 2 || got != "long other" {
+		t.Errorf("Expected the expired value to be swept, got %d values %q", m.Len(), got)
+	}
+	if len(m.tags["tag"]) != 1 {
+		t.Errorf("Expected the swept value to leave its tags, got %v", m.tags)
+	}
+}
+
+func TestMemoryTags(t *testing.T) {
+	m := NewMemory(0)
+	ctx := context.Background()
+	m.Set(ctx, "a", nil, 0, "x")
+	m.Set(ctx, "b", nil, 0, "x", "y")
+	m.Set(ctx, "c", nil, 0, "y")
+	m.Set(ctx, "d", nil, 0)
+
+	if err := m.DeleteTags(ctx, "x"); err != nil {
+		t.Fatalf("DeleteTags failed: %v", err)
+	}
+	if got := strings.Join(m.keys(), " "); got != "c d" {
+		t.Errorf("Expected the tagged values to be removed, got %q", got)
+	}
+	if _, ok := m.tags["x"]; ok || len(m.tags["y"]) != 1 {
+		t.Errorf("Expected the tags of removed values to be dropped, got %v", m.tags)
+	}
+
+	// Values replaced without their tags leave them
+	m.Set(ctx, "c", nil, 0)
+	if err := m.DeleteTags(ctx, "y"); err != nil {
+		t.Fatalf("DeleteTags failed: %v", err)
+	}
+	if got := strings.Join(m.keys(), " "); got != "c d" || len(m.tags) != 0 {
+		t.Errorf("Expected the untagged value to be kept, got %q and %v", got, m.tags)
+	}
+}
+
+func TestMemoryCopiesValues(t *testing.T) {
+	m := NewMemory(0)
+	ctx := context.Background()
+	value := []byte("value")
+	m.Set(ctx, "key", value, 0)
+	value[0] = 'V'
+
+	got, _ := m.Get(ctx, "key")
+	got[1] = 'A'
+	if got, _ := m.Get(ctx, "key"); string(got) != "value" {
+		t.Errorf("Expected the stored value to be unchanged, got %q", got)
+	}
+}
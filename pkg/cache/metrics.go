@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/metrics"
+)
+
+// MetricsCache records the use of a cache:
+//
+//	cache_requests_total{cache, result}              lookups by Get and GetOrSet, with result "hit" or "miss"
+//	cache_errors_total{cache, operation}             failed operations
+//	cache_operation_duration_seconds{cache, operation} latency, including loads by GetOrSet
+type MetricsCache struct {
+	cache    Cache
+	name     string
+	requests *metrics.Counter
+	errors   *metrics.Counter
+	duration *metrics.Timer
+}
+
+// NewMetricsCache wraps a cache to record its use in a registry, defaulting
+// to metrics.Default(), labelled with a name such as "sessions"
+func NewMetricsCache(c Cache, r *metrics.Registry, name string) *MetricsCache {
+	if r == nil {
+		r = metrics.Default()
+	}
+	return &MetricsCache{
+		cache:    c,
+		name:     name,
+		requests: r.Counter("cache_requests_total", "Cache lookups by result", "cache", "result"),
+		errors:   r.Counter("cache_errors_total", "Failed cache operations", "cache", "operation"),
+		duration: r.Timer("cache_operation_duration_seconds", "Cache operation latency in seconds", "cache", "operation"),
+	}
+}
+
+// Get returns the value of a key, or ErrMiss when it is missing or expired
+func (c *MetricsCache) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := c.cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		c.requests.Inc(c.name, "hit")
+	case errors.Is(err, ErrMiss):
+		c.requests.Inc(c.name, "miss")
+	default:
+		c.errors.Inc(c.name, "get")
+	}
+	c.duration.Since(start, c.name, "get")
+	return value, err
+}
+
+// Set stores a value for ttl, tagged with the tags
+func (c *MetricsCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	start := time.Now()
+	err := c.cache.Set(ctx, key, value, ttl, tags...)
+	c.done("set", start, err)
+	return err
+}
+
+// Delete removes keys
+func (c *MetricsCache) Delete(ctx context.Context, keys ...string) error {
+	start := time.Now()
+	err := c.cache.Delete(ctx, keys...)
+	c.done("delete", start, err)
+	return err
+}
+
+// DeleteTags removes the keys stored with any of the tags
+func (c *MetricsCache) DeleteTags(ctx context.Context, tags ...string) error {
+	start := time.Now()
+	err := c.cache.DeleteTags(ctx, tags...)
+	c.done("delete_tags", start, err)
+	return err
+}
+
+// GetOrSet returns the value of a key, or loads it with fn and stores it. It
+// counts a miss when fn is called and a hit otherwise.
+func (c *MetricsCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) ([]byte, error), tags ...string) ([]byte, error) {
+	start := time.Now()
+	loaded := false
+	value, err := c.cache.GetOrSet(ctx, key, ttl, func(ctx context.Context) ([]byte, error) {
+		loaded = true
+		return fn(ctx)
+	}, tags...)
+
+	if err == nil || loaded {
+		result := "hit"
+		if loaded {
+			result = "miss"
+		}
+		c.requests.Inc(c.name, result)
+	}
+	c.done("get_or_set", start, err)
+	return value, err
+}
+
+// done records an operation started at start
+func (c *MetricsCache) done(operation string, start time.Time, err error) {
+	if err != nil {
+		c.errors.Inc(c.name, operation)
+	}
+	c.duration.Since(start, c.name, operation)
+}
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RedisClient runs Redis commands. Replies are returned as nil, string,
+// []byte, int64, or []interface{} values, with nil for missing keys. It is
+// satisfied by RedisConn, and other clients can be adapted with RedisFunc.
+type RedisClient interface {
+	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// RedisFunc adapts a function to RedisClient, e.g. to use go-redis:
+//
+//	client := cache.RedisFunc(func(ctx context.Context, args ...interface{}) (interface{}, error) {
+//		reply, err := rdb.Do(ctx, args...).Result()
+//		if err == redis.Nil {
+//			return nil, nil
+//		}
+//		return reply, err
+//	})
+type RedisFunc func(ctx context.Context, args ...interface{}) (interface{}, error)
+
+// Do runs a command
+func (f RedisFunc) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return f(ctx, args...)
+}
+
+// Redis is a cache stored in Redis, shared by every instance of an app. Tags
+// are kept in Redis sets that expire with the longest-lived key they hold.
+type Redis struct {
+	client RedisClient
+	prefix string
+	loads  group
+}
+
+// NewRedis creates a Redis cache whose keys start with prefix, e.g. "myapp:"
+func NewRedis(client RedisClient, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// Get returns the value of a key, or ErrMiss when it is missing or expired
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.client.Do(ctx, "GET", r.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case nil:
+		return nil, ErrMiss
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("cache: unexpected reply %T to GET", reply)
+	}
+}
+
+// Set stores a value for ttl, or without expiry when ttl is 0
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	args := []interface{}{"SET", r.prefix + key, value}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+	if _, err := r.client.Do(ctx, args...); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if err := r.addToTag(ctx, r.tagKey(tag), r.prefix+key, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add stores a value for ttl unless the key holds an unexpired value,
+// reporting whether it was stored, with SET NX
+func (r *Redis) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []interface{}{"SET", r.prefix + key, value, "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+	reply, err := r.client.Do(ctx, args...)
+	if err != nil {
+		return false, err
+	}
+	// SET NX replies OK when it stored the value and nil otherwise
+	return reply != nil, nil
+}
+
+// addToTag adds a key to a tag set, making the set live at least as long as
+// the key
+func (r *Redis) addToTag(ctx context.Context, tagKey, key string, ttl time.Duration) error {
+	// PTTL is -2 for a missing set and -1 for a set without expiry
+	reply, err := r.client.Do(ctx, "PTTL", tagKey)
+	if err != nil {
+		return err
+	}
+	remaining, _ := toInt64(reply)
+
+	if _, err := r.client.Do(ctx, "SADD", tagKey, key); err != nil {
+		return err
+	}
+
+	switch {
+	case ttl <= 0:
+		if remaining != -1 {
+			_, err = r.client.Do(ctx, "PERSIST", tagKey)
+		}
+	case remaining == -1:
+		// The set holds a key without expiry
+	case remaining < ttl.Milliseconds():
+		_, err = r.client.Do(ctx, "PEXPIRE", tagKey, ttl.Milliseconds())
+	}
+	return err
+}
+
+// Delete removes keys
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []interface{}{"DEL"}
+	for _, key := range keys {
+		args = append(args, r.prefix+key)
+	}
+	_, err := r.client.Do(ctx, args...)
+	return err
+}
+
+// DeleteTags removes the keys stored with any of the tags
+func (r *Redis) DeleteTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := r.tagKey(tag)
+		reply, err := r.client.Do(ctx, "SMEMBERS", tagKey)
+		if err != nil {
+			return err
+		}
+
+		args := []interface{}{"DEL", tagKey}
+		members, _ := reply.([]interface{})
+		for _, member := range members {
+			switch m := member.(type) {
+			case []byte:
+				args = append(args, string(m))
+			case string:
+				args = append(args, m)
+			}
+		}
+		if _, err := r.client.Do(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOrSet returns the value of a key, or loads it once with fn and stores it.
+// Loads are shared within this process, not across instances.
+func (r *Redis) GetOrSet(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) ([]byte, error), tags ...string) ([]byte, error) {
+	return getOrSet(ctx, r, &r.loads, key, ttl, fn, tags)
+}
+
+// tagKey returns the key of the set holding the keys of a tag
+func (r *Redis) tagKey(tag string) string {
+	return r.prefix + "tag:" + tag
+}
+
+// toInt64 converts an integer reply
+func toInt64(reply interface{}) (int64, bool) {
+	switch v := reply.(type) {
+	case int64:
+		return v, true
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis runs the commands of the Redis cache on maps, one at a time as
+// a Redis server does. Expiry times are remaining milliseconds that do not
+// elapse.
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string][]byte
+	sets     map[string]map[string]bool
+	ttls     map[string]int64
+	commands []string
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		values: make(map[string][]byte),
+		sets:   make(map[string]map[string]bool),
+		ttls:   make(map[string]int64),
+	}
+}
+
+func (f *fakeRedis) Do(_ context.Context, args ...interface{}) (interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := make([]string, len(args))
+	for i, arg := range args {
+		if b, ok := arg.([]byte); ok {
+			arg = string(b)
+		}
+		s[i] = fmt.Sprint(arg)
+	}
+	f.commands = append(f.commands, strings.Join(s, " "))
+
+	switch s[0] {
+	case "GET":
+		if value, ok := f.values[s[1]]; ok {
+			return value, nil
+		}
+		return nil, nil
+	case "SET":
+		options := strings.Join(s[3:], " ")
+		if _, ok := f.values[s[1]]; ok && strings.Contains(options, "NX") {
+			return nil, nil
+		}
+		f.values[s[1]] = []byte(s[2])
+		delete(f.ttls, s[1])
+		if _, px, ok := strings.Cut(options, "PX "); ok {
+			f.ttls[s[1]], _ = strconv.ParseInt(px, 10, 64)
+		}
+		return "OK", nil
+	case "DEL":
+		for _, key := range s[1:] {
+			delete(f.values, key)
+			delete(f.sets, key)
+			delete(f.ttls, key)
+		}
+		return int64(len(s) - 1), nil
+	case "SADD":
+		if f.sets[s[1]] == nil {
+			f.sets[s[1]] = make(map[string]bool)
+		}
+		for _, member := range s[2:] {
+			f.sets[s[1]][member] = true
+		}
+		return int64(len(s) - 2), nil
+	case "SMEMBERS":
+		members := []interface{}{}
+		for member := range f.sets[s[1]] {
+			members = append(members, []byte(member))
+		}
+		return members, nil
+	case "PTTL":
+		if _, ok := f.sets[s[1]]; !ok {
+			return int64(-2), nil
+		}
+		if ttl, ok := f.ttls[s[1]]; ok {
+			return ttl, nil
+		}
+		return int64(-1), nil
+	case "PEXPIRE":
+		f.ttls[s[1]], _ = strconv.ParseInt(s[2], 10, 64)
+		return int64(1), nil
+	case "PERSIST":
+		delete(f.ttls, s[1])
+		return int64(1), nil
+	}
+	return nil, RedisError("ERR unknown command " + s[0])
+}
+
+func TestRedis(t *testing.T) {
+	fake := newFakeRedis()
+	r := NewRedis(fake, "app:")
+	ctx := context.Background()
+
+	if _, err := r.Get(ctx, "key"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Expected ErrMiss, got %v", err)
+	}
+	if err := r.Set(ctx, "key", []byte("value"), 1500*time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, err := r.Get(ctx, "key"); err != nil || string(value) != "value" {
+		t.Errorf("Expected the value, got %q and %v", value, err)
+	}
+	if fake.commands[1] != "SET app:key value PX 1500" {
+		t.Errorf("Unexpected command %q", fake.commands[1])
+	}
+
+	for i, want := range []bool{true, false} {
+		if added, err := r.Add(ctx, "once", []byte("1"), time.Minute); err != nil || added != want {
+			t.Errorf("Expected Add %d to report %v, got %v and %v", i+1, want, added, err)
+		}
+	}
+	if last := fake.commands[len(fake.commands)-1]; last != "SET app:once 1 NX PX 60000" {
+		t.Errorf("Unexpected command %q", last)
+	}
+
+	if err := r.Delete(ctx, "key", "once"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(fake.values) != 0 {
+		t.Errorf("Expected the keys to be deleted, got %v", fake.values)
+	}
+}
+
+func TestRedisTags(t *testing.T) {
+	tests := []struct {
+		name string
+		ttls []time.Duration // Of the keys stored with the tag, in order
+		want int64           // Remaining time of the tag set, -1 for none
+	}{
+		{"one key", []time.Duration{time.Minute}, 60000},
+		{"longer key", []time.Duration{time.Minute, time.Hour}, 3600000},
+		{"shorter key", []time.Duration{time.Hour, time.Minute}, 3600000},
+		{"key without expiry", []time.Duration{time.Minute, 0}, -1},
+		{"after key without expiry", []time.Duration{0, time.Minute}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRedis()
+			r := NewRedis(fake, "app:")
+			ctx := context.Background()
+			for i, ttl := range tt.ttls {
+				if err := r.Set(ctx, fmt.Sprint("key", i), nil, ttl, "users"); err != nil {
+					t.Fatalf("Set failed: %v", err)
+				}
+			}
+			if got, _ := fake.Do(ctx, "PTTL", "app:tag:users"); got != tt.want {
+				t.Errorf("Expected the tag to live %d ms, got %v", tt.want, got)
+			}
+		})
+	}
+
+	fake := newFakeRedis()
+	r := NewRedis(fake, "app:")
+	ctx := context.Background()
+	r.Set(ctx, "a", nil, 0, "users")
+	r.Set(ctx, "b", nil, 0, "users", "posts")
+	r.Set(ctx, "c", nil, 0, "posts")
+	if err := r.DeleteTags(ctx, "users"); err != nil {
+		t.Fatalf("DeleteTags failed: %v", err)
+	}
+	var keys []string
+	for key := range fake.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, " ") != "app:c" {
+		t.Errorf("Expected the tagged keys to be deleted, got %v", keys)
+	}
+	if _, ok := fake.sets["app:tag:users"]; ok {
+		t.Error("Expected the tag set to be deleted")
+	}
+}
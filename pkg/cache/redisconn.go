@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisConfig configures a connection to Redis
+type RedisConfig struct {
+	// Addr is the host and port of the server, defaulting to localhost:6379
+	Addr string
+
+	// Password authenticates the connection when set
+	Password string
+
+	// DB is the database number selected after connecting
+	DB int
+
+	// PoolSize is the maximum number of idle connections, defaulting to 10
+	PoolSize int
+
+	// DialTimeout limits connecting, defaulting to 5 seconds
+	DialTimeout time.Duration
+}
+
+// RedisError is an error reply of the server
+type RedisError string
+
+func (e RedisError) Error() string { return string(e) }
+
+// RedisConn is a small Redis client with a pool of connections, enough for
+// the cache. Use another client through RedisFunc for clustering or TLS.
+type RedisConn struct {
+	config RedisConfig
+	idle   chan *redisConn
+}
+
+// redisConn is a pooled connection
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// DialRedis creates a Redis client. Connections are opened when commands run.
+func DialRedis(config RedisConfig) *RedisConn {
+	if config.Addr == "" {
+		config.Addr = "localhost:6379"
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = 10
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	return &RedisConn{config: config, idle: make(chan *redisConn, config.PoolSize)}
+}
+
+// Do runs a command, e.g. Do(ctx, "SET", "key", "value", "PX", 1000). The
+// deadline of ctx applies to the round trip.
+func (r *RedisConn) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection is in an unknown state after a network error
+		conn.conn.Close()
+		return nil, err
+	}
+	r.put(conn)
+	return reply, err
+}
+
+// Close closes the idle connections
+func (r *RedisConn) Close() error {
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// get returns an idle connection or opens one
+func (r *RedisConn) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.config.DialTimeout}
+	c, err := dialer.DialContext(ctx, "tcp", r.config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: c, reader: bufio.NewReader(c)}
+
+	if r.config.Password != "" {
+		if _, err := conn.do(ctx, []interface{}{"AUTH", r.config.Password}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if r.config.DB != 0 {
+		if _, err := conn.do(ctx, []interface{}{"SELECT", r.config.DB}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (r *RedisConn) put(conn *redisConn) {
+	select {
+	case r.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// do writes a command and reads its reply
+func (c *redisConn) do(ctx context.Context, args []interface{}) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// Commands are arrays of bulk strings
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		default:
+			s = fmt.Sprint(v)
+		}
+		buf = append(buf, "$"+strconv.Itoa(len(s))+"\r\n"+s+"\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads a reply: a simple string, error, integer, bulk string, or array
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("cache: malformed redis reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RedisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			// Error replies inside arrays are returned as values
+			item, err := c.readReply()
+			var redisErr RedisError
+			if err != nil && !errors.As(err, &redisErr) {
+				return nil, err
+			}
+			if err != nil {
+				item = err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("cache: malformed redis reply %q", line)
+	}
+}
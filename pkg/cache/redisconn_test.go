@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// respServer is a Redis server answering commands with raw RESP replies
+type respServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    int
+	commands []string
+}
+
+// newRESPServer starts a server replying to each command, joined by spaces,
+// with the reply of its name
+func newRESPServer(t *testing.T, replies map[string]string) *respServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &respServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+			go s.serve(conn, replies)
+		}
+	}()
+	return s
+}
+
+// serve answers the commands of a connection
+func (s *respServer) serve(conn net.Conn, replies map[string]string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		s.mu.Unlock()
+
+		reply, ok := replies[args[0]]
+		if !ok {
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisConn(t *testing.T) {
+	server := newRESPServer(t, map[string]string{
+		"AUTH":     "+OK\r\n",
+		"SELECT":   "+OK\r\n",
+		"SET":      "+OK\r\n",
+		"GET":      "$5\r\nhello\r\n",
+		"EXISTS":   "$-1\r\n",
+		"INCR":     ":42\r\n",
+		"SMEMBERS": "*3\r\n$1\r\na\r\n-ERR inside\r\n:7\r\n",
+		"EMPTY":    "$0\r\n\r\n",
+	})
+	client := DialRedis(RedisConfig{Addr: server.listener.Addr().String(), Password: "secret", DB: 2})
+	defer client.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		args []interface{}
+		want interface{}
+		err  string
+	}{
+		{[]interface{}{"SET", "key", []byte("value"), "PX", int64(1000)}, "OK", ""},
+		{[]interface{}{"GET", "key"}, []byte("hello"), ""},
+		{[]interface{}{"EXISTS", "missing"}, nil, ""},
+		{[]interface{}{"INCR", "n"}, int64(42), ""},
+		{[]interface{}{"SMEMBERS", "set"}, []interface{}{[]byte("a"), RedisError("ERR inside"), int64(7)}, ""},
+		{[]interface{}{"EMPTY"}, []byte{}, ""},
+		{[]interface{}{"UNKNOWN"}, nil, "ERR unknown command"},
+	}
+	for _, tt := range tests {
+		reply, err := client.Do(ctx, tt.args...)
+		var redisErr RedisError
+		if tt.err != "" {
+			if !errors.As(err, &redisErr) || err.Error() != tt.err {
+				t.Errorf("%v: expected error %q, got %v", tt.args[0], tt.err, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(reply, tt.want) {
+			t.Errorf("%v: expected %#v, got %#v and %v", tt.args[0], tt.want, reply, err)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.conns != 1 {
+		t.Errorf("Expected the connection to be reused after error replies, got %d connections", server.conns)
+	}
+	if len(server.commands) < 3 || server.commands[0] != "AUTH secret" || server.commands[1] != "SELECT 2" ||
+		server.commands[2] != "SET key value PX 1000" {
+		t.Errorf("Unexpected commands %q", server.commands)
+	}
+}
+
+func TestRedisConnDialError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := DialRedis(RedisConfig{Addr: addr})
+	if _, err := client.Do(context.Background(), "PING"); err == nil {
+		t.Error("Expected an error without a server")
+	}
+}
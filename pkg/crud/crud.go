@@ -0,0 +1,356 @@
+// Package crud mounts the list, get, create, update, and delete routes of a
+// repository, answering with the standard response envelope
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/anaknegeri/gokit/pkg/binding"
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/openapi"
+	"github.com/anaknegeri/gokit/pkg/pagination"
+	"github.com/anaknegeri/gokit/pkg/repository"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/schema"
+)
+
+// Action is a route mounted by Register
+type Action string
+
+// Actions
+const (
+	List   Action = "list"   // GET /
+	Get    Action = "get"    // GET /:id
+	Create Action = "create" // POST /
+	Update Action = "update" // PUT and PATCH /:id
+	Delete Action = "delete" // DELETE /:id
+)
+
+// Options configures the routes of a repository
+type Options[T any] struct {
+	// Actions are the routes to mount, defaulting to all of them
+	Actions []Action
+
+	// Name is the name of the model in response messages, defaulting to the
+	// entity of the repository
+	Name string
+
+	// IDParam is the route parameter of the primary key, defaulting to "id"
+	IDParam string
+
+	// Params configures the pagination parameters of the list route,
+	// defaulting to ?page=2&pageSize=20
+	Params *pagination.ParamConfig
+
+	// Middleware runs before the handler of an action, e.g. a permission check
+	Middleware map[Action][]fiber.Handler
+
+	// Scope narrows the records a request can see, e.g. to those of the
+	// current user. Records outside the scope are answered with 404.
+	Scope func(c *fiber.Ctx) pagination.Scope
+
+	// BeforeCreate and BeforeUpdate run after the body is bound and
+	// validated, e.g. to set the owner of a record. A returned error is sent
+	// as the response.
+	BeforeCreate func(c *fiber.Ctx, entity *T) error
+	BeforeUpdate func(c *fiber.Ctx, entity *T) error
+
+	// OpenAPI describes the mounted routes in a spec when set, with the
+	// model as their body
+	OpenAPI *openapi.Spec
+}
+
+// handlers are the route handlers of a repository
+type handlers[T any] struct {
+	repo    *repository.Repository[T]
+	options Options[T]
+}
+
+// Register mounts the routes of a repository on a router:
+//
+//	GET    /     a page of records, sorted and filtered by the query string
+//	GET    /:id  a record
+//	POST   /     creates a record from the validated body
+//	PUT    /:id  updates the fields of a record sent in the body
+//	PATCH  /:id  the same as PUT
+//	DELETE /:id  deletes a record
+//
+// The model is bound and validated with its json and validate tags, and
+// the primary key of the body is ignored. Fields clients must not set, such
+// as an owner, are left out of JSON with json:"-" or set by BeforeCreate and
+// BeforeUpdate.
+func Register[T any](router fiber.Router, repo *repository.Repository[T], options ...Options[T]) {
+	opts := Options[T]{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Name == "" {
+		opts.Name = repo.Entity()
+	}
+	if opts.IDParam == "" {
+		opts.IDParam = "id"
+	}
+	if len(opts.Actions) == 0 {
+		opts.Actions = []Action{List, Get, Create, Update, Delete}
+	}
+
+	h := &handlers[T]{repo: repo, options: opts}
+	path := "/:" + opts.IDParam
+	for _, action := range opts.Actions {
+		chain := append([]fiber.Handler(nil), opts.Middleware[action]...)
+		switch action {
+		case List:
+			router.Get("/", append(chain, h.list)...)
+		case Get:
+			router.Get(path, append(chain, h.get)...)
+		case Create:
+			router.Post("/", append(chain, h.create)...)
+		case Update:
+			router.Put(path, append(chain, h.update)...)
+			router.Patch(path, append(chain, h.update)...)
+		case Delete:
+			router.Delete(path, append(chain, h.delete)...)
+		default:
+			panic(fmt.Sprintf("crud: unknown action %q", action))
+		}
+	}
+	if opts.OpenAPI != nil {
+		h.describe(router, opts.OpenAPI)
+	}
+}
+
+// describe adds the mounted routes to a spec
+func (h *handlers[T]) describe(router fiber.Router, spec *openapi.Spec) {
+	name := h.options.Name
+	tags := []string{name}
+	path := "/:" + h.options.IDParam
+
+	// Type the route parameter like the primary key
+	var params interface{}
+	if field, err := h.primaryField(); err == nil {
+		params = reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: "ID",
+			Type: field.FieldType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`params:"%s"`, h.options.IDParam)),
+		}})).Interface()
+	}
+
+	for _, action := range h.options.Actions {
+		switch action {
+		case List:
+			spec.Describe(router, fiber.MethodGet, "/", openapi.Route{
+				Summary:    "List " + name + " records",
+				Tags:       tags,
+				Response:   new(T),
+				Paginated:  true,
+				PageParams: h.options.Params,
+			})
+		case Get:
+			spec.Describe(router, fiber.MethodGet, path, openapi.Route{
+				Summary:  "Get " + name,
+				Tags:     tags,
+				Params:   params,
+				Response: new(T),
+				Errors:   []int{fiber.StatusNotFound},
+			})
+		case Create:
+			spec.Describe(router, fiber.MethodPost, "/", openapi.Route{
+				Summary:  "Create " + name,
+				Tags:     tags,
+				Request:  new(T),
+				Response: new(T),
+				Status:   fiber.StatusCreated,
+			})
+		case Update:
+			for _, method := range []string{fiber.MethodPut, fiber.MethodPatch} {
+				spec.Describe(router, method, path, openapi.Route{
+					Summary:  "Update " + name,
+					Tags:     tags,
+					Params:   params,
+					Request:  new(T),
+					Response: new(T),
+					Errors:   []int{fiber.StatusNotFound},
+				})
+			}
+		case Delete:
+			spec.Describe(router, fiber.MethodDelete, path, openapi.Route{
+				Summary: "Delete " + name,
+				Tags:    tags,
+				Params:  params,
+				Errors:  []int{fiber.StatusNotFound},
+			})
+		}
+	}
+}
+
+func (h *handlers[T]) list(c *fiber.Ctx) error {
+	var config []pagination.ParamConfig
+	if h.options.Params != nil {
+		config = append(config, *h.options.Params)
+	}
+	params, err := pagination.GetPaginationFromRequest(c, config...)
+	if err != nil {
+		return response.Error(c, err)
+	}
+
+	result, err := h.repo.List(c.UserContext(), params, h.scopes(c)...)
+	if err != nil {
+		return response.Error(c, err)
+	}
+	return response.SuccessWithPagination(c, h.options.Name+" list retrieved", result)
+}
+
+func (h *handlers[T]) get(c *fiber.Ctx) error {
+	entity, _, err := h.find(c)
+	if err != nil {
+		return response.Error(c, err)
+	}
+	return response.Success(c, h.options.Name+" retrieved", entity)
+}
+
+func (h *handlers[T]) create(c *fiber.Ctx) error {
+	entity := new(T)
+	if ok, err := binding.BindAndValidate(c, entity); !ok {
+		return err
+	}
+	if err := h.setID(c, entity, nil); err != nil {
+		return response.Error(c, err)
+	}
+	if h.options.BeforeCreate != nil {
+		if err := h.options.BeforeCreate(c, entity); err != nil {
+			return response.Error(c, err)
+		}
+	}
+
+	if err := h.repo.Create(c.UserContext(), entity); err != nil {
+		return response.Error(c, err)
+	}
+	return response.Created(c, h.options.Name+" created", entity)
+}
+
+// update binds the body onto a record, validates the result, and saves it
+func (h *handlers[T]) update(c *fiber.Ctx) error {
+	entity, id, err := h.find(c)
+	if err != nil {
+		return response.Error(c, err)
+	}
+	if ok, err := binding.BindAndValidate(c, entity); !ok {
+		return err
+	}
+	if err := h.setID(c, entity, id); err != nil {
+		return response.Error(c, err)
+	}
+	if h.options.BeforeUpdate != nil {
+		if err := h.options.BeforeUpdate(c, entity); err != nil {
+			return response.Error(c, err)
+		}
+	}
+
+	if err := h.repo.Update(c.UserContext(), entity); err != nil {
+		return response.Error(c, err)
+	}
+	updated, err := h.repo.GetByID(c.UserContext(), id, h.scopes(c)...)
+	if err != nil {
+		return response.Error(c, err)
+	}
+	return response.Success(c, h.options.Name+" updated", updated)
+}
+
+func (h *handlers[T]) delete(c *fiber.Ctx) error {
+	_, id, err := h.find(c)
+	if err != nil {
+		return response.Error(c, err)
+	}
+	if err := h.repo.Delete(c.UserContext(), id); err != nil {
+		return response.Error(c, err)
+	}
+	return response.Success(c, h.options.Name+" deleted", nil)
+}
+
+// find loads the record of the route parameter within the scope
+func (h *handlers[T]) find(c *fiber.Ctx) (*T, interface{}, error) {
+	id, err := h.id(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	entity, err := h.repo.GetByID(c.UserContext(), id, h.scopes(c)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entity, id, nil
+}
+
+// id parses the route parameter as the type of the primary key. Values
+// that cannot be a primary key are answered with 404.
+func (h *handlers[T]) id(c *fiber.Ctx) (interface{}, error) {
+	raw := c.Params(h.options.IDParam)
+	field, err := h.primaryField()
+	if err != nil {
+		return nil, err
+	}
+
+	switch field.FieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.RecordNotFoundError(h.repo.Entity(), raw)
+		}
+		return id, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, errors.RecordNotFoundError(h.repo.Entity(), raw)
+		}
+		return id, nil
+	default:
+		return raw, nil
+	}
+}
+
+// setID sets the primary key of a bound record, or clears it when id is nil
+func (h *handlers[T]) setID(c *fiber.Ctx, entity *T, id interface{}) error {
+	field, err := h.primaryField()
+	if err != nil {
+		return err
+	}
+	if id == nil {
+		id = reflect.Zero(field.FieldType).Interface()
+	}
+	if err := field.Set(c.UserContext(), reflect.ValueOf(entity).Elem(), id); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
+
+// primaryField returns the primary key field of the model
+func (h *handlers[T]) primaryField() (*schema.Field, error) {
+	s, err := h.repo.Schema()
+	if err != nil {
+		return nil, err
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, internalError(fmt.Errorf("crud: %s has no primary key", h.repo.Entity()))
+	}
+	return s.PrioritizedPrimaryField, nil
+}
+
+// scopes returns the scope of a request, if any
+func (h *handlers[T]) scopes(c *fiber.Ctx) []pagination.Scope {
+	if h.options.Scope == nil {
+		return nil
+	}
+	if scope := h.options.Scope(c); scope != nil {
+		return []pagination.Scope{scope}
+	}
+	return nil
+}
+
+// internalError is a 500 error caused by err
+func internalError(err error) error {
+	appErr := errors.InternalServerError("")
+	appErr.Internal = err
+	return appErr
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures a database connection. Set DSN, or the driver and the
+// parts it is built from.
+type Config struct {
+	// Driver is "postgres", "mysql", or "sqlite" (default)
+	Driver string
+
+	// DSN is passed to the driver as is when set
+	DSN string
+
+	Host     string
+	Port     int
+	User     string
+	Password string
+
+	// Name is the database name, or the file path for SQLite
+	Name string
+
+	// SSLMode is the Postgres sslmode, defaulting to "disable"
+	SSLMode string
+
+	// Params are added to the DSN, e.g. {"TimeZone": "UTC"} for Postgres
+	Params map[string]string
+
+	// Pool settings, defaulting to 25 open and 5 idle connections that are
+	// closed after 30 minutes, or 5 minutes when idle
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout limits connecting and the first ping, defaulting to
+	// 10 seconds
+	ConnectTimeout time.Duration
+
+	// LogLevel is the level of the GORM logger: "silent", "error", "warn"
+	// (default), or "info" to log every query at debug level
+	LogLevel string
+
+	// SlowThreshold is the duration from which queries are logged as slow,
+	// defaulting to 200ms
+	SlowThreshold time.Duration
+
+	// HealthCheck is the name under which Open registers a ping of the
+	// database with the default health registry, e.g. "database"
+	HealthCheck string
+}
+
+// ConfigFromEnv loads configuration from environment variables: DB_DRIVER,
+// DB_DSN, DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE,
+// DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME,
+// DB_CONN_MAX_IDLE_TIME, DB_CONNECT_TIMEOUT, DB_LOG_LEVEL, and
+// DB_SLOW_THRESHOLD, with durations such as "30m"
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Driver:   os.Getenv("DB_DRIVER"),
+		DSN:      os.Getenv("DB_DSN"),
+		Host:     os.Getenv("DB_HOST"),
+		User:     os.Getenv("DB_USER"),
+		Password: os.Getenv("DB_PASSWORD"),
+		Name:     os.Getenv("DB_NAME"),
+		SSLMode:  os.Getenv("DB_SSLMODE"),
+		LogLevel: os.Getenv("DB_LOG_LEVEL"),
+	}
+
+	ints := map[string]*int{
+		"DB_PORT":           &config.Port,
+		"DB_MAX_OPEN_CONNS": &config.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &config.MaxIdleConns,
+	}
+	for name, target := range ints {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("database: invalid %s: %w", name, err)
+		}
+		*target = n
+	}
+
+	durations := map[string]*time.Duration{
+		"DB_CONN_MAX_LIFETIME":  &config.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME": &config.ConnMaxIdleTime,
+		"DB_CONNECT_TIMEOUT":    &config.ConnectTimeout,
+		"DB_SLOW_THRESHOLD":     &config.SlowThreshold,
+	}
+	for name, target := range durations {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("database: invalid %s: %w", name, err)
+		}
+		*target = d
+	}
+	return config, nil
+}
+
+// withDefaults fills in the defaults of a config
+func (c Config) withDefaults() Config {
+	c.Driver = normalizeDriver(c.Driver)
+	if c.MaxOpenConns == 0 {
+		c.MaxOpenConns = 25
+	}
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = 5
+	}
+	if c.ConnMaxLifetime == 0 {
+		c.ConnMaxLifetime = 30 * time.Minute
+	}
+	if c.ConnMaxIdleTime == 0 {
+		c.ConnMaxIdleTime = 5 * time.Minute
+	}
+	if c.ConnectTimeout <= 0 {
+		c.ConnectTimeout = 10 * time.Second
+	}
+	if c.SlowThreshold == 0 {
+		c.SlowThreshold = 200 * time.Millisecond
+	}
+	if c.SSLMode == "" {
+		c.SSLMode = "disable"
+	}
+	return c
+}
+
+// normalizeDriver returns the registered name of a driver
+func normalizeDriver(driver string) string {
+	switch strings.ToLower(driver) {
+	case "", "sqlite3":
+		return "sqlite"
+	case "postgresql", "pg", "pgx":
+		return "postgres"
+	case "mariadb":
+		return "mysql"
+	default:
+		return strings.ToLower(driver)
+	}
+}
+
+// BuildDSN returns the DSN of a config for its driver
+func (c Config) BuildDSN() (string, error) {
+	if c.DSN != "" {
+		return c.DSN, nil
+	}
+	c = c.withDefaults()
+	timeout := int(c.ConnectTimeout.Seconds())
+
+	switch c.Driver {
+	case "postgres":
+		parts := []string{
+			"host=" + quoteDSNValue(orDefault(c.Host, "localhost")),
+			"port=" + strconv.Itoa(orDefaultInt(c.Port, 5432)),
+		}
+		if c.User != "" {
+			parts = append(parts, "user="+quoteDSNValue(c.User))
+		}
+		if c.Password != "" {
+			parts = append(parts, "password="+quoteDSNValue(c.Password))
+		}
+		if c.Name != "" {
+			parts = append(parts, "dbname="+quoteDSNValue(c.Name))
+		}
+		parts = append(parts, "sslmode="+c.SSLMode, "connect_timeout="+strconv.Itoa(timeout))
+		for _, key := range sortedKeys(c.Params) {
+			parts = append(parts, key+"="+quoteDSNValue(c.Params[key]))
+		}
+		return strings.Join(parts, " "), nil
+
+	case "mysql":
+		params := url.Values{
+			"parseTime": {"true"},
+			"charset":   {"utf8mb4"},
+			"loc":       {"UTC"},
+			"timeout":   {fmt.Sprintf("%ds", timeout)},
+		}
+		for key, value := range c.Params {
+			params.Set(key, value)
+		}
+		credentials := c.User
+		if c.Password != "" {
+			credentials += ":" + c.Password
+		}
+		host := fmt.Sprintf("%s:%d", orDefault(c.Host, "localhost"), orDefaultInt(c.Port, 3306))
+		return fmt.Sprintf("%s@tcp(%s)/%s?%s", credentials, host, c.Name, params.Encode()), nil
+
+	case "sqlite":
+		if c.Name == "" {
+			return "", fmt.Errorf("database: the SQLite file name is required")
+		}
+		if len(c.Params) == 0 {
+			return c.Name, nil
+		}
+		params := url.Values{}
+		for key, value := range c.Params {
+			params.Set(key, value)
+		}
+		return c.Name + "?" + params.Encode(), nil
+
+	default:
+		return "", fmt.Errorf("database: unsupported driver %q", c.Driver)
+	}
+}
+
+// quoteDSNValue quotes a Postgres DSN value when needed
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+func orDefaultInt(value, def int) int {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,135 @@
+// Package database opens GORM connections from configuration or the
+// environment, with pool settings, a GORM logger writing to the gokit logger,
+// transactions, and a health check
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/anaknegeri/gokit/pkg/health"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/tracing"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Dialector opens a GORM dialector for a DSN, such as postgres.Open
+type Dialector func(dsn string) gorm.Dialector
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Dialector{"sqlite": sqlite.Open}
+)
+
+// RegisterDriver makes a GORM driver available to Open. SQLite is
+// registered; register the drivers of other databases once at startup:
+//
+//	database.RegisterDriver("postgres", postgres.Open) // gorm.io/driver/postgres
+//	database.RegisterDriver("mysql", mysql.Open)       // gorm.io/driver/mysql
+func RegisterDriver(name string, open Dialector) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[normalizeDriver(name)] = open
+}
+
+// Drivers returns the names of the registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open connects to a database, configures its pool, and checks the
+// connection with a ping. Queries are traced once tracing.Init has run.
+func Open(config Config, gormConfig ...*gorm.Config) (*gorm.DB, error) {
+	cfg := config.withDefaults()
+
+	driversMu.RLock()
+	open, ok := drivers[cfg.Driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("database: driver %q is not registered, see RegisterDriver", cfg.Driver)
+	}
+	dsn, err := cfg.BuildDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	gc := &gorm.Config{}
+	if len(gormConfig) > 0 && gormConfig[0] != nil {
+		gc = gormConfig[0]
+	}
+	if gc.Logger == nil {
+		gc.Logger = NewLogger(LoggerConfig{
+			Level:         cfg.LogLevel,
+			SlowThreshold: cfg.SlowThreshold,
+		})
+	}
+
+	db, err := gorm.Open(open(dsn), gc)
+	if err != nil {
+		return nil, fmt.Errorf("database: open %s: %w", cfg.Driver, err)
+	}
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		return nil, fmt.Errorf("database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("database: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("database: connect to %s: %w", cfg.Driver, err)
+	}
+
+	if cfg.HealthCheck != "" {
+		health.Register(cfg.HealthCheck, HealthCheck(db))
+	}
+	logger.Named("database").Infof("Connected to %s database", cfg.Driver)
+	return db, nil
+}
+
+// OpenFromEnv connects to the database configured by the environment, see
+// ConfigFromEnv
+func OpenFromEnv(gormConfig ...*gorm.Config) (*gorm.DB, error) {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return Open(config, gormConfig...)
+}
+
+// Close closes the connections of a database
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("database: %w", err)
+	}
+	return sqlDB.Close()
+}
+
+// HealthCheck returns a health check pinging a database
+func HealthCheck(db *gorm.DB) health.Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
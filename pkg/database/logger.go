@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// LoggerConfig configures the GORM logger
+type LoggerConfig struct {
+	// Level is "silent", "error", "warn" (default), or "info" to log every
+	// query at debug level
+	Level string
+
+	// SlowThreshold is the duration from which queries are logged as slow,
+	// defaulting to 200ms. A negative value disables it.
+	SlowThreshold time.Duration
+
+	// LogRecordNotFound logs gorm.ErrRecordNotFound as an error
+	LogRecordNotFound bool
+
+	// Logger defaults to logger.Named("database")
+	Logger *logger.Logger
+}
+
+// gormLogger writes the logs of GORM to the gokit logger, with the request
+// ID and trace fields of the query context
+type gormLogger struct {
+	config LoggerConfig
+	level  gormlogger.LogLevel
+}
+
+// NewLogger creates a GORM logger writing to the gokit logger
+func NewLogger(config ...LoggerConfig) gormlogger.Interface {
+	cfg := LoggerConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = 200 * time.Millisecond
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("database")
+	}
+	return &gormLogger{config: cfg, level: parseLogLevel(cfg.Level)}
+}
+
+// parseLogLevel returns the GORM log level of a name
+func parseLogLevel(name string) gormlogger.LogLevel {
+	switch strings.ToLower(name) {
+	case "silent":
+		return gormlogger.Silent
+	case "error":
+		return gormlogger.Error
+	case "info", "debug":
+		return gormlogger.Info
+	default:
+		return gormlogger.Warn
+	}
+}
+
+// LogMode returns a copy of the logger with a level
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.config.Logger.Ctx(ctx).Infof(msg, args...)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.config.Logger.Ctx(ctx).Warnf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.config.Logger.Ctx(ctx).Errorf(msg, args...)
+	}
+}
+
+// Trace logs a query: failed queries at error level, slow queries at warn
+// level, and the others at debug level
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	slow := l.config.SlowThreshold > 0 && elapsed > l.config.SlowThreshold
+	failed := err != nil && (l.config.LogRecordNotFound || !errors.Is(err, gorm.ErrRecordNotFound))
+
+	switch {
+	case failed && l.level >= gormlogger.Error:
+	case slow && l.level >= gormlogger.Warn:
+	case l.level >= gormlogger.Info:
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	fields := logger.Fields{
+		"sql":         sql,
+		"duration_ms": float64(elapsed.Microseconds()) / 1000,
+	}
+	if rows >= 0 {
+		fields["rows"] = rows
+	}
+	log := l.config.Logger.Ctx(ctx).WithFields(fields)
+
+	switch {
+	case failed && l.level >= gormlogger.Error:
+		log.Errorf("Query failed: %v", err)
+	case slow && l.level >= gormlogger.Warn:
+		log.Warnf("Slow query took %s", elapsed.Round(time.Millisecond))
+	default:
+		log.Debugf("Query took %s", elapsed.Round(time.Microsecond))
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type txContextKey struct{}
+
+// WithTx runs fn in a transaction, committing when it returns nil and
+// rolling back when it returns an error or panics. A panic is returned as
+// an internal server error.
+//
+// The context of tx carries the transaction, so calls to WithTx and Conn
+// with it join the transaction; nested calls use a savepoint.
+func WithTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) (err error) {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		db = tx
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Errorf("Transaction panicked: %v\n%s", r, debug.Stack())
+			appErr := errors.InternalServerError("")
+			appErr.Internal = fmt.Errorf("panic: %v", r)
+			err = appErr
+		}
+	}()
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(tx.WithContext(context.WithValue(ctx, txContextKey{}, tx)))
+	}, opts...)
+}
+
+// Conn returns the transaction carried by ctx, or db with ctx, so functions
+// taking a context run in the transaction of their caller, if any
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
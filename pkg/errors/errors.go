@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/anaknegeri/gokit/pkg/i18n"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -25,14 +26,17 @@ const (
 	ErrCodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"
 
 	// Filesystem specific error codes
-	ErrCodeFileNotFound       = "FILE_NOT_FOUND"
-	ErrCodeFileAlreadyExists  = "FILE_ALREADY_EXISTS"
-	ErrCodeFileTooLarge       = "FILE_TOO_LARGE"
-	ErrCodeInvalidFileType    = "INVALID_FILE_TYPE"
-	ErrCodeStorageUnavailable = "STORAGE_UNAVAILABLE"
-	ErrCodePermissionDenied   = "PERMISSION_DENIED"
-	ErrCodeQuotaExceeded      = "QUOTA_EXCEEDED"
-	ErrCodeInvalidPath        = "INVALID_PATH"
+	ErrCodeFileNotFound        = "FILE_NOT_FOUND"
+	ErrCodeFileAlreadyExists   = "FILE_ALREADY_EXISTS"
+	ErrCodeFileTooLarge        = "FILE_TOO_LARGE"
+	ErrCodeInvalidFileType     = "INVALID_FILE_TYPE"
+	ErrCodeStorageUnavailable  = "STORAGE_UNAVAILABLE"
+	ErrCodePermissionDenied    = "PERMISSION_DENIED"
+	ErrCodeQuotaExceeded       = "QUOTA_EXCEEDED"
+	ErrCodeInvalidPath         = "INVALID_PATH"
+	ErrCodeInsufficientStorage = "INSUFFICIENT_STORAGE"
+	ErrCodeFileRejected        = "FILE_REJECTED"
+	ErrCodeDownloadFailed      = "DOWNLOAD_FAILED"
 
 	// Database specific error codes
 	ErrCodeDatabaseError       = "DATABASE_ERROR"
@@ -45,6 +49,18 @@ const (
 	ErrCodeTokenExpired       = "TOKEN_EXPIRED"
 	ErrCodeInvalidToken       = "INVALID_TOKEN"
 	ErrCodeAccountLocked      = "ACCOUNT_LOCKED"
+	ErrCodeInvalidAPIKey      = "INVALID_API_KEY"
+	ErrCodeInsufficientScope  = "INSUFFICIENT_SCOPE"
+
+	// Query specific error codes
+	ErrCodeInvalidSort   = "INVALID_SORT"
+	ErrCodeInvalidFilter = "INVALID_FILTER"
+	ErrCodeInvalidCursor = "INVALID_CURSOR"
+	ErrCodeInvalidPage   = "INVALID_PAGE"
+
+	// Request specific error codes
+	ErrCodeInvalidRequest       = "INVALID_REQUEST"
+	ErrCodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
 )
 
 // Map HTTP status codes to error codes
@@ -67,6 +83,16 @@ type AppError struct {
 	Details  interface{} `json:"details,omitempty"`
 	HTTPCode int         `json:"-"`
 	Internal error       `json:"-"`
+
+	// kind is the specific code of errors sent with a generic Code, such as
+	// INVALID_PATH for BAD_REQUEST, which matches its sentinel too
+	kind string
+
+	// Message catalog key and parameters used to re-render Message in another locale
+	messageKey        string
+	messageParams     map[string]string
+	validationErrs    validator.ValidationErrors
+	validationOptions ValidationOptions
 }
 
 // Error implements the error interface for AppError
@@ -140,6 +166,91 @@ func WrapErrorWithCustomCode(err error, httpCode int, code string, message strin
 	return appErr
 }
 
+// Wrapf wraps an existing error with a formatted message. Like fmt.Errorf,
+// the format may use %w, and errors wrapped that way join err in the chain
+// seen by errors.Is and errors.As:
+//
+//	errors.Wrapf(err, http.StatusBadGateway, "Failed to fetch %s", url)
+func Wrapf(err error, httpCode int, format string, args ...interface{}) *AppError {
+	formatted := fmt.Errorf(format, args...)
+	appErr := NewError(httpCode, formatted.Error())
+	appErr.Internal = err
+
+	var wrapped []error
+	switch u := formatted.(type) {
+	case interface{ Unwrap() error }:
+		wrapped = []error{u.Unwrap()}
+	case interface{ Unwrap() []error }:
+		wrapped = u.Unwrap()
+	}
+	if len(wrapped) > 0 {
+		appErr.Internal = &wrapChain{err: err, wrapped: wrapped}
+	}
+	return appErr
+}
+
+// wrapChain is the Internal error of Wrapf when the format wraps errors with
+// %w. It reads as the wrapped error and unwraps to it and the %w errors.
+type wrapChain struct {
+	err     error
+	wrapped []error
+}
+
+// Error returns the message of the wrapped error
+func (c *wrapChain) Error() string {
+	if c.err == nil {
+		return c.wrapped[0].Error()
+	}
+	return c.err.Error()
+}
+
+// Unwrap returns the wrapped error followed by the %w errors
+func (c *wrapChain) Unwrap() []error {
+	if c.err == nil {
+		return c.wrapped
+	}
+	return append([]error{c.err}, c.wrapped...)
+}
+
+// WithDetails returns a copy of the AppError in err's chain with details
+// added, keeping its code, message, and wrapped error. Map details are merged
+// into existing map details, replacing keys; other details replace them.
+// Errors without an AppError are wrapped as internal errors. It returns nil
+// when err is nil.
+func WithDetails(err error, details interface{}) *AppError {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		appErr = InternalServerError("")
+		appErr.Internal = err
+	}
+
+	enriched := *appErr
+	enriched.Details = mergeDetails(appErr.Details, details)
+	return &enriched
+}
+
+// mergeDetails merges map details into existing map details, or replaces them
+func mergeDetails(existing, added interface{}) interface{} {
+	base, ok := existing.(map[string]interface{})
+	extra, extraOK := added.(map[string]interface{})
+	if !ok || !extraOK {
+		return added
+	}
+
+	merged := make(map[string]interface{}, len(base)+len(extra))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	return merged
+}
+
 // Is checks if an error is of a specific type
 func Is(err error, target error) bool {
 	return errors.Is(err, target)
@@ -152,6 +263,8 @@ func As(err error, target interface{}) bool {
 
 // ValidationError represents a field validation error
 type ValidationError struct {
+	// Field is the path of the field below the validated struct, using the
+	// JSON names, e.g. "user.address.street" or "items[0].name"
 	Field   string      `json:"field"`
 	Message string      `json:"message"`
 	Tag     string      `json:"tag,omitempty"`
@@ -159,32 +272,50 @@ type ValidationError struct {
 	Param   string      `json:"param,omitempty"`
 }
 
-// ValidatorError processes validator.ValidationErrors into a consistent format
-func ValidatorError(err error) *AppError {
-	var validationErrors []ValidationError
+// ValidatorError processes validator.ValidationErrors into a consistent
+// format. The options default to those set with SetValidationOptions.
+func ValidatorError(err error, opts ...ValidationOptions) *AppError {
+	return ValidatorErrorLocalized(err, i18n.DefaultLocale(), opts...)
+}
 
-	if validationErrs, ok := err.(validator.ValidationErrors); ok {
-		for _, e := range validationErrs {
-			validationErrors = append(validationErrors, ValidationError{
-				Field:   formatFieldName(e.Field()),
-				Message: generateValidationMessage(e),
-				Tag:     e.Tag(),
-				Value:   e.Value(),
-				Param:   e.Param(),
-			})
-		}
+// ValidatorErrorLocalized processes validator.ValidationErrors into a consistent
+// format with messages rendered in the given locale
+func ValidatorErrorLocalized(err error, locale string, opts ...ValidationOptions) *AppError {
+	var validationErrs validator.ValidationErrors
+	errors.As(err, &validationErrs)
+	options := resolveValidationOptions(opts)
+
+	return &AppError{
+		Code:              ErrCodeValidationError,
+		Message:           translate(locale, MsgValidationFailed, nil),
+		Details:           buildValidationDetails(validationErrs, locale, options),
+		HTTPCode:          http.StatusUnprocessableEntity,
+		Internal:          err,
+		messageKey:        MsgValidationFailed,
+		validationErrs:    validationErrs,
+		validationOptions: options,
 	}
+}
 
-	return NewErrorWithDetails(
-		http.StatusUnprocessableEntity,
-		"Validation failed",
-		validationErrors,
-	)
+// buildValidationErrors converts validator errors into ValidationError details
+func buildValidationErrors(validationErrs validator.ValidationErrors, locale string) []ValidationError {
+	var validationErrors []ValidationError
+	for _, e := range validationErrs {
+		validationErrors = append(validationErrors, ValidationError{
+			Field:   fieldPath(e),
+			Message: generateValidationMessage(e, locale),
+			Tag:     e.Tag(),
+			Value:   e.Value(),
+			Param:   e.Param(),
+		})
+	}
+	return validationErrors
 }
 
 // FormatErrorResponse formats an error into a consistent API response
 func FormatErrorResponse(err error) *ErrorResponse {
-	if appErr, ok := err.(*AppError); ok {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
 		return &ErrorResponse{
 			Success: false,
 			Code:    appErr.HTTPCode,
@@ -213,7 +344,7 @@ func BadRequestError(message string) *AppError {
 // UnauthorizedError creates an unauthorized error
 func UnauthorizedError(message string) *AppError {
 	if message == "" {
-		message = "Unauthorized access"
+		return newLocalizedError(http.StatusUnauthorized, ErrCodeUnauthorized, MsgUnauthorized, nil)
 	}
 	return NewError(http.StatusUnauthorized, message)
 }
@@ -221,7 +352,7 @@ func UnauthorizedError(message string) *AppError {
 // ForbiddenError creates a forbidden error
 func ForbiddenError(message string) *AppError {
 	if message == "" {
-		message = "Access forbidden"
+		return newLocalizedError(http.StatusForbidden, ErrCodeForbidden, MsgForbidden, nil)
 	}
 	return NewError(http.StatusForbidden, message)
 }
@@ -229,7 +360,7 @@ func ForbiddenError(message string) *AppError {
 // NotFoundError creates a not found error
 func NotFoundError(message string) *AppError {
 	if message == "" {
-		message = "Resource not found"
+		return newLocalizedError(http.StatusNotFound, ErrCodeNotFound, MsgNotFound, nil)
 	}
 	return NewError(http.StatusNotFound, message)
 }
@@ -242,7 +373,7 @@ func ConflictError(message string) *AppError {
 // InternalServerError creates an internal server error
 func InternalServerError(message string) *AppError {
 	if message == "" {
-		message = "Internal server error"
+		return newLocalizedError(http.StatusInternalServerError, ErrCodeInternalError, MsgInternalError, nil)
 	}
 	return NewError(http.StatusInternalServerError, message)
 }
@@ -250,7 +381,7 @@ func InternalServerError(message string) *AppError {
 // MethodNotAllowedError creates a method not allowed error
 func MethodNotAllowedError(message string) *AppError {
 	if message == "" {
-		message = "Method not allowed"
+		return newLocalizedError(http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, MsgMethodNotAllowed, nil)
 	}
 	return NewError(http.StatusMethodNotAllowed, message)
 }
@@ -258,7 +389,7 @@ func MethodNotAllowedError(message string) *AppError {
 // ServiceUnavailableError creates a service unavailable error
 func ServiceUnavailableError(message string) *AppError {
 	if message == "" {
-		message = "Service temporarily unavailable"
+		return newLocalizedError(http.StatusServiceUnavailable, ErrCodeServiceUnavailable, MsgServiceUnavailable, nil)
 	}
 	return NewError(http.StatusServiceUnavailable, message)
 }
@@ -267,250 +398,426 @@ func ServiceUnavailableError(message string) *AppError {
 
 // FileNotFoundError creates an error for file not found situations
 func FileNotFoundError(path string) *AppError {
-	return NewCustomError(
+	return newLocalizedError(
 		http.StatusNotFound,
 		ErrCodeFileNotFound,
-		fmt.Sprintf("File not found: %s", path),
+		MsgFileNotFound,
+		map[string]string{"path": path},
 	)
 }
 
 // FileTooLargeError creates an error for files that exceed size limits
 func FileTooLargeError(size, maxSize int64) *AppError {
-	return NewCustomError(
+	return newLocalizedError(
 		http.StatusBadRequest,
 		ErrCodeFileTooLarge,
-		fmt.Sprintf("File size of %d bytes exceeds the maximum allowed size of %d bytes", size, maxSize),
+		MsgFileTooLarge,
+		map[string]string{"size": toParam(size), "maxSize": toParam(maxSize)},
 	)
 }
 
 // InvalidFileTypeError creates an error for unsupported file types
 func InvalidFileTypeError(fileType string, allowedTypes []string) *AppError {
-	return NewErrorWithDetails(
+	err := newLocalizedError(
 		http.StatusBadRequest,
-		fmt.Sprintf("File type '%s' is not allowed", fileType),
-		map[string]interface{}{
-			"fileType":     fileType,
-			"allowedTypes": allowedTypes,
-		},
+		ErrCodeBadRequest,
+		MsgInvalidFileType,
+		map[string]string{"fileType": fileType},
 	)
+	err.kind = ErrCodeInvalidFileType
+	err.Details = map[string]interface{}{
+		"fileType":     fileType,
+		"allowedTypes": allowedTypes,
+	}
+	return err
 }
 
 // FileAlreadyExistsError creates an error for when a file already exists
 func FileAlreadyExistsError(path string) *AppError {
-	return NewCustomError(
+	return newLocalizedError(
 		http.StatusConflict,
 		ErrCodeFileAlreadyExists,
-		fmt.Sprintf("File already exists: %s", path),
+		MsgFileAlreadyExists,
+		map[string]string{"path": path},
 	)
 }
 
 // StorageUnavailableError creates an error for when storage is unavailable
 func StorageUnavailableError(err error) *AppError {
-	return WrapErrorWithCustomCode(
-		err,
+	appErr := newLocalizedError(
 		http.StatusServiceUnavailable,
 		ErrCodeStorageUnavailable,
-		"Storage service is currently unavailable",
+		MsgStorageUnavailable,
+		nil,
 	)
+	appErr.Internal = err
+	return appErr
+}
+
+// InsufficientStorageError creates an error for an upload refused because
+// the storage is running out of space
+func InsufficientStorageError(size int64) *AppError {
+	return newLocalizedError(
+		http.StatusInsufficientStorage,
+		ErrCodeInsufficientStorage,
+		MsgInsufficientStorage,
+		map[string]string{"size": toParam(size)},
+	)
+}
+
+// FileRejectedError creates an error for an uploaded file refused by its
+// processing, e.g. a virus scan, with the reason in the details
+func FileRejectedError(path string, reason string) *AppError {
+	err := newLocalizedError(
+		http.StatusUnprocessableEntity,
+		ErrCodeFileRejected,
+		MsgFileRejected,
+		map[string]string{"path": path},
+	)
+	err.Details = map[string]interface{}{
+		"path":   path,
+		"reason": reason,
+	}
+	return err
+}
+
+// DownloadFailedError creates an error for a remote file that could not be
+// downloaded, with the reason in the details
+func DownloadFailedError(url string, reason string) *AppError {
+	err := newLocalizedError(
+		http.StatusBadGateway,
+		ErrCodeDownloadFailed,
+		MsgDownloadFailed,
+		map[string]string{"url": url},
+	)
+	err.Details = map[string]interface{}{
+		"url":    url,
+		"reason": reason,
+	}
+	return err
 }
 
 // InvalidPathError creates an error for invalid file paths
 func InvalidPathError(path string, reason string) *AppError {
-	return NewErrorWithDetails(
+	err := newLocalizedError(
 		http.StatusBadRequest,
-		fmt.Sprintf("Invalid path: %s", path),
-		map[string]interface{}{
-			"path":   path,
-			"reason": reason,
-		},
+		ErrCodeBadRequest,
+		MsgInvalidPath,
+		map[string]string{"path": path},
 	)
+	err.kind = ErrCodeInvalidPath
+	err.Details = map[string]interface{}{
+		"path":   path,
+		"reason": reason,
+	}
+	return err
 }
 
 // Database-specific errors
 
 // DatabaseError creates a general database error
 func DatabaseError(err error) *AppError {
-	return WrapErrorWithCustomCode(
-		err,
+	appErr := newLocalizedError(
 		http.StatusInternalServerError,
 		ErrCodeDatabaseError,
-		"Database operation failed",
+		MsgDatabaseError,
+		nil,
 	)
+	appErr.Internal = err
+	return appErr
 }
 
 // RecordNotFoundError creates a record not found error
 func RecordNotFoundError(entity string, id interface{}) *AppError {
-	return NewCustomError(
+	if id == nil {
+		return newLocalizedError(
+			http.StatusNotFound,
+			ErrCodeRecordNotFound,
+			MsgRecordNotFoundNoID,
+			map[string]string{"entity": entity},
+		)
+	}
+	return newLocalizedError(
 		http.StatusNotFound,
 		ErrCodeRecordNotFound,
-		fmt.Sprintf("%s with ID %v not found", entity, id),
+		MsgRecordNotFound,
+		map[string]string{"entity": entity, "id": toParam(id)},
 	)
 }
 
 // DuplicateEntryError creates a duplicate entry error
 func DuplicateEntryError(entity string, field string, value interface{}) *AppError {
-	return NewErrorWithDetails(
+	err := newLocalizedError(
 		http.StatusConflict,
-		fmt.Sprintf("Duplicate %s: %s already exists", entity, field),
-		map[string]interface{}{
-			"entity": entity,
-			"field":  field,
-			"value":  value,
-		},
+		ErrCodeConflict,
+		MsgDuplicateEntry,
+		map[string]string{"entity": entity, "field": field},
 	)
+	err.kind = ErrCodeDuplicateEntry
+	err.Details = map[string]interface{}{
+		"entity": entity,
+		"field":  field,
+		"value":  value,
+	}
+	return err
 }
 
 // ForeignKeyViolationError creates a foreign key violation error
 func ForeignKeyViolationError(entity string, relation string) *AppError {
-	return NewErrorWithDetails(
+	err := newLocalizedError(
 		http.StatusConflict,
-		fmt.Sprintf("Cannot modify %s due to existing %s references", entity, relation),
-		map[string]interface{}{
-			"entity":   entity,
-			"relation": relation,
-		},
+		ErrCodeConflict,
+		MsgForeignKeyViolation,
+		map[string]string{"entity": entity, "relation": relation},
 	)
+	err.kind = ErrCodeForeignKeyViolation
+	err.Details = map[string]interface{}{
+		"entity":   entity,
+		"relation": relation,
+	}
+	return err
 }
 
 // Authentication-specific errors
 
 // InvalidCredentialsError creates an invalid credentials error
 func InvalidCredentialsError() *AppError {
-	return NewCustomError(
+	return newLocalizedError(
 		http.StatusUnauthorized,
 		ErrCodeInvalidCredentials,
-		"Invalid credentials",
+		MsgInvalidCredentials,
+		nil,
 	)
 }
 
 // TokenExpiredError creates a token expired error
 func TokenExpiredError() *AppError {
-	return NewCustomError(
+	return newLocalizedError(
 		http.StatusUnauthorized,
 		ErrCodeTokenExpired,
-		"Authentication token has expired",
+		MsgTokenExpired,
+		nil,
 	)
 }
 
 // InvalidTokenError creates an invalid token error
 func InvalidTokenError() *AppError {
-	return NewCustomError(
+	return newLocalizedError(
 		http.StatusUnauthorized,
 		ErrCodeInvalidToken,
-		"Invalid authentication token",
+		MsgInvalidToken,
+		nil,
 	)
 }
 
 // AccountLockedError creates an account locked error
 func AccountLockedError() *AppError {
-	return NewCustomError(
+	return newLocalizedError(
 		http.StatusForbidden,
 		ErrCodeAccountLocked,
-		"Account is locked",
+		MsgAccountLocked,
+		nil,
+	)
+}
+
+// InvalidAPIKeyError creates an error for a missing, unknown, revoked, or expired API key
+func InvalidAPIKeyError() *AppError {
+	return newLocalizedError(
+		http.StatusUnauthorized,
+		ErrCodeInvalidAPIKey,
+		MsgInvalidAPIKey,
+		nil,
+	)
+}
+
+// InsufficientScopeError creates an error for credentials lacking the scopes a request requires
+func InsufficientScopeError(required []string) *AppError {
+	err := newLocalizedError(
+		http.StatusForbidden,
+		ErrCodeInsufficientScope,
+		MsgInsufficientScope,
+		map[string]string{"scopes": strings.Join(required, ", ")},
+	)
+	err.Details = map[string]interface{}{
+		"required": required,
+	}
+	return err
+}
+
+// PermissionDeniedError creates an error for a user lacking a permission
+func PermissionDeniedError(permission string) *AppError {
+	err := newLocalizedError(
+		http.StatusForbidden,
+		ErrCodePermissionDenied,
+		MsgPermissionDenied,
+		map[string]string{"permission": permission},
+	)
+	err.Details = map[string]interface{}{
+		"permission": permission,
+	}
+	return err
+}
+
+// Query-specific errors
+
+// InvalidSortError creates an error for a sort field that is not allowed
+func InvalidSortError(field string, allowed []string) *AppError {
+	err := newLocalizedError(
+		http.StatusBadRequest,
+		ErrCodeInvalidSort,
+		MsgInvalidSort,
+		map[string]string{"field": field},
+	)
+	err.Details = map[string]interface{}{
+		"field":   field,
+		"allowed": allowed,
+	}
+	return err
+}
+
+// InvalidFilterError creates an error for a filter that is not allowed or has an invalid value
+func InvalidFilterError(field string, reason string) *AppError {
+	err := newLocalizedError(
+		http.StatusBadRequest,
+		ErrCodeInvalidFilter,
+		MsgInvalidFilter,
+		map[string]string{"field": field},
+	)
+	err.Details = map[string]interface{}{
+		"field":  field,
+		"reason": reason,
+	}
+	return err
+}
+
+// InvalidCursorError creates an error for a pagination cursor that cannot be decoded
+func InvalidCursorError() *AppError {
+	return newLocalizedError(
+		http.StatusBadRequest,
+		ErrCodeInvalidCursor,
+		MsgInvalidCursor,
+		nil,
+	)
+}
+
+// InvalidPageError creates an error for a page number or page size parameter
+// that is not a number or is out of range
+func InvalidPageError(param string, reason string) *AppError {
+	err := newLocalizedError(
+		http.StatusBadRequest,
+		ErrCodeInvalidPage,
+		MsgInvalidPage,
+		map[string]string{"field": param},
+	)
+	err.Details = map[string]interface{}{
+		"field":  param,
+		"reason": reason,
+	}
+	return err
+}
+
+// InvalidRequestError creates an error for a request body, query string, or
+// path parameters that cannot be parsed
+func InvalidRequestError(reason string) *AppError {
+	err := newLocalizedError(
+		http.StatusBadRequest,
+		ErrCodeInvalidRequest,
+		MsgInvalidRequest,
+		nil,
+	)
+	err.Details = map[string]interface{}{
+		"reason": reason,
+	}
+	return err
+}
+
+// UnsupportedMediaTypeError creates an error for a request body in a content
+// type that cannot be parsed
+func UnsupportedMediaTypeError(contentType string) *AppError {
+	return newLocalizedError(
+		http.StatusUnsupportedMediaType,
+		ErrCodeUnsupportedMediaType,
+		MsgUnsupportedMediaType,
+		map[string]string{"contentType": contentType},
 	)
 }
 
 // formatFieldName converts field names to camelCase
 func formatFieldName(field string) string {
+	if field == "" {
+		return field
+	}
 	return strings.ToLower(field[:1]) + field[1:]
 }
 
-// generateValidationMessage generates user-friendly validation messages
-func generateValidationMessage(fe validator.FieldError) string {
-	switch fe.Tag() {
-	case "required":
-		return fmt.Sprintf("%s is required", fe.Field())
-	case "email":
-		return "Invalid email format"
-	case "min":
-		if fe.Kind() == reflect.String {
-			return fmt.Sprintf("%s must be at least %s characters long", fe.Field(), fe.Param())
+// fieldPath returns the path of a failed field below the validated struct,
+// e.g. "user.address.street" for the namespace "Order.user.address.street"
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	_, path, nested := strings.Cut(namespace, ".")
+	if !nested {
+		// Top-level fields and variables have no parent struct in the namespace
+		path = fe.Field()
+	}
+
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		segments[i] = formatFieldName(segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+// validationCondition describes the fields a conditional validation such as
+// required_if depends on, e.g. "type = business, country = ID" for the
+// parameter "Type business Country ID", or "phone, email" for required_with
+func validationCondition(fe validator.FieldError) string {
+	tag := fe.Tag()
+	if !strings.HasPrefix(tag, "required_") && !strings.HasPrefix(tag, "excluded_") {
+		return ""
+	}
+
+	words := strings.Fields(fe.Param())
+	if strings.HasSuffix(tag, "_if") || strings.HasSuffix(tag, "_unless") {
+		// The parameter holds field and value pairs
+		conditions := make([]string, 0, len(words)/2)
+		for i := 0; i+1 < len(words); i += 2 {
+			conditions = append(conditions, formatFieldName(words[i])+" = "+words[i+1])
 		}
-		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
-	case "max":
+		return strings.Join(conditions, ", ")
+	}
+
+	for i, word := range words {
+		words[i] = formatFieldName(word)
+	}
+	return strings.Join(words, ", ")
+}
+
+// generateValidationMessage generates user-friendly validation messages in the given locale
+func generateValidationMessage(fe validator.FieldError, locale string) string {
+	params := map[string]string{
+		"field":     fe.Field(),
+		"param":     fe.Param(),
+		"tag":       fe.Tag(),
+		"condition": validationCondition(fe),
+	}
+
+	key := validationMessagePrefix + fe.Tag()
+	for _, l := range i18n.Fallbacks(locale) {
 		if fe.Kind() == reflect.String {
-			return fmt.Sprintf("%s must not exceed %s characters", fe.Field(), fe.Param())
+			// Prefer a string-specific variant (e.g. "characters long") when one exists
+			if message, ok := i18n.Message(l, key+".string", params); ok {
+				return message
+			}
+		}
+		if message, ok := i18n.Message(l, key, params); ok {
+			return message
+		}
+
+		// Use the validator's own message for tags the catalog does not cover
+		if message, ok := translateValidation(fe, l); ok {
+			return message
 		}
-		return fmt.Sprintf("%s must not exceed %s", fe.Field(), fe.Param())
-	case "uuid":
-		return fmt.Sprintf("%s must be a valid UUID", fe.Field())
-	case "oneof":
-		return fmt.Sprintf("%s must be one of [%s]", fe.Field(), fe.Param())
-	case "unique":
-		return fmt.Sprintf("%s must be unique", fe.Field())
-	case "numeric":
-		return fmt.Sprintf("%s must be numeric", fe.Field())
-	case "json":
-		return fmt.Sprintf("%s must be valid JSON", fe.Field())
-	case "url":
-		return fmt.Sprintf("%s must be a valid URL", fe.Field())
-	case "gt":
-		return fmt.Sprintf("%s must be greater than %s", fe.Field(), fe.Param())
-	case "lt":
-		return fmt.Sprintf("%s must be less than %s", fe.Field(), fe.Param())
-	case "gte":
-		return fmt.Sprintf("%s must be greater than or equal to %s", fe.Field(), fe.Param())
-	case "lte":
-		return fmt.Sprintf("%s must be less than or equal to %s", fe.Field(), fe.Param())
-	case "alpha":
-		return fmt.Sprintf("%s must contain only letters", fe.Field())
-	case "alphanum":
-		return fmt.Sprintf("%s must contain only letters and numbers", fe.Field())
-	case "datetime":
-		return fmt.Sprintf("%s must be a valid datetime", fe.Field())
-	case "file":
-		return fmt.Sprintf("%s must be a valid file", fe.Field())
-	case "image":
-		return fmt.Sprintf("%s must be a valid image", fe.Field())
-	case "mime":
-		return fmt.Sprintf("%s must be of type %s", fe.Field(), fe.Param())
-	case "password":
-		return fmt.Sprintf("%s must meet password requirements", fe.Field())
-	case "eqfield":
-		return fmt.Sprintf("%s must be equal to %s", fe.Field(), fe.Param())
-	case "nefield":
-		return fmt.Sprintf("%s must not be equal to %s", fe.Field(), fe.Param())
-	case "isbn":
-		return fmt.Sprintf("%s must be a valid ISBN", fe.Field())
-	case "isbn10":
-		return fmt.Sprintf("%s must be a valid ISBN-10", fe.Field())
-	case "isbn13":
-		return fmt.Sprintf("%s must be a valid ISBN-13", fe.Field())
-	case "creditcard":
-		return fmt.Sprintf("%s must be a valid credit card number", fe.Field())
-	case "hexcolor":
-		return fmt.Sprintf("%s must be a valid hex color", fe.Field())
-	case "rgb":
-		return fmt.Sprintf("%s must be a valid RGB color", fe.Field())
-	case "rgba":
-		return fmt.Sprintf("%s must be a valid RGBA color", fe.Field())
-	case "hsv":
-		return fmt.Sprintf("%s must be a valid HSV color", fe.Field())
-	case "hsla":
-		return fmt.Sprintf("%s must be a valid HSLA color", fe.Field())
-	case "e164":
-		return fmt.Sprintf("%s must be a valid E.164 formatted phone number", fe.Field())
-	case "base64":
-		return fmt.Sprintf("%s must be a valid Base64 string", fe.Field())
-	case "base64url":
-		return fmt.Sprintf("%s must be a valid Base64URL string", fe.Field())
-	case "contains":
-		return fmt.Sprintf("%s must contain the text '%s'", fe.Field(), fe.Param())
-	case "containsany":
-		return fmt.Sprintf("%s must contain at least one of the following characters '%s'", fe.Field(), fe.Param())
-	case "excludes":
-		return fmt.Sprintf("%s may not contain the text '%s'", fe.Field(), fe.Param())
-	case "excludesall":
-		return fmt.Sprintf("%s may not contain any of the following characters '%s'", fe.Field(), fe.Param())
-	case "ip":
-		return fmt.Sprintf("%s must be a valid IP address", fe.Field())
-	case "ipv4":
-		return fmt.Sprintf("%s must be a valid IPv4 address", fe.Field())
-	case "ipv6":
-		return fmt.Sprintf("%s must be a valid IPv6 address", fe.Field())
-	case "mac":
-		return fmt.Sprintf("%s must be a valid MAC address", fe.Field())
-	default:
-		return fmt.Sprintf("%s failed validation for tag %s", fe.Field(), fe.Tag())
 	}
+
+	return translate(locale, validationMessagePrefix+"default", params)
 }
@@ -0,0 +1,166 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+func TestWrapf(t *testing.T) {
+	err := Wrapf(context.DeadlineExceeded, http.StatusBadGateway, "Failed to fetch invoice %d", 42)
+	if err.Message != "Failed to fetch invoice 42" || err.HTTPCode != http.StatusBadGateway || err.Code != ErrCodeInternalError {
+		t.Errorf("Wrapf = %+v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("errors.Is does not find the wrapped error")
+	}
+	if got, want := err.Error(), "[INTERNAL_ERROR] Failed to fetch invoice 42: context deadline exceeded"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestWrapfVerbW(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrNotExist}
+	err := Wrapf(context.Canceled, http.StatusNotFound, "Failed to read %s: %w", "a.txt", pathErr)
+
+	if err.Message != "Failed to read a.txt: open a.txt: file does not exist" {
+		t.Errorf("Message = %q", err.Message)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, fs.ErrNotExist) {
+		t.Error("errors.Is does not find the wrapped errors")
+	}
+	var target *fs.PathError
+	if !errors.As(err, &target) || target != pathErr {
+		t.Error("errors.As does not find the %w error")
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("errors.Is does not match the sentinel")
+	}
+
+	onlyW := Wrapf(nil, http.StatusNotFound, "Missing: %w", pathErr)
+	if !errors.Is(onlyW, fs.ErrNotExist) || onlyW.Internal.Error() != pathErr.Error() {
+		t.Errorf("Wrapf(nil) = %v", onlyW)
+	}
+}
+
+func TestWithDetails(t *testing.T) {
+	cause := errors.New("quota check failed")
+	base := WrapErrorWithCustomCode(cause, http.StatusForbidden, ErrCodeQuotaExceeded, "Quota exceeded")
+	base.Details = map[string]interface{}{"limit": 10, "used": 9}
+
+	err := WithDetails(fmt.Errorf("upload: %w", base), map[string]interface{}{"used": 10, "path": "a.txt"})
+	want := map[string]interface{}{"limit": 10, "used": 10, "path": "a.txt"}
+	if fmt.Sprint(err.Details) != fmt.Sprint(want) {
+		t.Errorf("Details = %v, want %v", err.Details, want)
+	}
+	if err.Code != ErrCodeQuotaExceeded || err.HTTPCode != http.StatusForbidden || !errors.Is(err, cause) {
+		t.Errorf("WithDetails = %v", err)
+	}
+	if base.Details.(map[string]interface{})["used"] != 9 {
+		t.Error("WithDetails modified the original error")
+	}
+
+	replaced := WithDetails(base, []string{"a", "b"})
+	if fmt.Sprint(replaced.Details) != "[a b]" {
+		t.Errorf("Details = %v", replaced.Details)
+	}
+
+	sentinelDetails := WithDetails(ErrNotFound, "x")
+	if sentinelDetails.Code != ErrCodeNotFound || sentinelDetails.HTTPCode != http.StatusNotFound || sentinelDetails.Details != "x" {
+		t.Errorf("WithDetails(sentinel) = %v", sentinelDetails)
+	}
+	var fromSentinel *AppError
+	if !errors.As(ErrNotFound, &fromSentinel) || fromSentinel.Details != nil {
+		t.Error("WithDetails modified a sentinel")
+	}
+
+	plain := WithDetails(cause, "x")
+	if plain.HTTPCode != http.StatusInternalServerError || !errors.Is(plain, cause) {
+		t.Errorf("WithDetails(plain error) = %v", plain)
+	}
+
+	if WithDetails(nil, "x") != nil {
+		t.Error("WithDetails(nil) is not nil")
+	}
+}
+
+func TestChainsAreUnwrappable(t *testing.T) {
+	type user struct {
+		Email string `validate:"required"`
+	}
+	validationErr := validator.New().Struct(user{})
+	appErr := ValidatorError(fmt.Errorf("binding: %w", validationErr))
+	var validationErrs validator.ValidationErrors
+	if !errors.As(appErr, &validationErrs) || len(validationErrs) != 1 {
+		t.Error("errors.As does not find the validation errors")
+	}
+	if details, _ := appErr.Details.([]ValidationError); len(details) != 1 {
+		t.Errorf("Details = %v", appErr.Details)
+	}
+
+	notFound := FromGormError(fmt.Errorf("find user: %w", gorm.ErrRecordNotFound))
+	if !errors.Is(notFound, gorm.ErrRecordNotFound) || !errors.Is(notFound, ErrRecordNotFound) {
+		t.Error("record not found errors do not wrap the GORM error")
+	}
+
+	wrapped := fmt.Errorf("handler: %w", FileNotFoundError("a.txt"))
+	if response := FormatErrorResponse(wrapped); response.Code != http.StatusNotFound || response.Error != ErrCodeFileNotFound {
+		t.Errorf("FormatErrorResponse = %+v", response)
+	}
+	if localized := Localize(wrapped, LocaleIndonesian); localized.(*AppError).Code != ErrCodeFileNotFound {
+		t.Errorf("Localize = %v", localized)
+	}
+	plain := fmt.Errorf("handler: %w", NewError(http.StatusBadRequest, "Bad"))
+	if Localize(plain, LocaleIndonesian) != plain {
+		t.Error("Localize changed an error without a message key")
+	}
+}
+
+func TestSentinels(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *AppError
+		code     string
+		matches  []error
+		excluded []error
+	}{
+		{"file not found", FileNotFoundError("a.txt"), ErrCodeFileNotFound, []error{ErrFileNotFound}, []error{ErrNotFound}},
+		{"invalid file type", InvalidFileTypeError(".exe", nil), ErrCodeBadRequest, []error{ErrInvalidFileType, ErrBadRequest}, []error{ErrInvalidPath}},
+		{"invalid path", InvalidPathError("../a", "leaves the root"), ErrCodeBadRequest, []error{ErrInvalidPath, ErrBadRequest}, []error{ErrInvalidFileType}},
+		{"duplicate entry", DuplicateEntryError("user", "email", "a@b.c"), ErrCodeConflict, []error{ErrDuplicateEntry, ErrConflict}, []error{ErrForeignKeyViolation}},
+		{"foreign key violation", ForeignKeyViolationError("order", "user"), ErrCodeConflict, []error{ErrForeignKeyViolation, ErrConflict}, []error{ErrDuplicateEntry}},
+		{"bad request", NewError(http.StatusBadRequest, "Bad"), ErrCodeBadRequest, []error{ErrBadRequest}, []error{ErrInvalidPath}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Code != tt.code {
+				t.Errorf("Code = %s, want %s", tt.err.Code, tt.code)
+			}
+			wrapped := fmt.Errorf("handler: %w", tt.err)
+			for _, sentinel := range tt.matches {
+				if !errors.Is(wrapped, sentinel) {
+					t.Errorf("errors.Is(%v, %v) = false", tt.err, sentinel)
+				}
+			}
+			for _, sentinel := range tt.excluded {
+				if errors.Is(wrapped, sentinel) {
+					t.Errorf("errors.Is(%v, %v) = true", tt.err, sentinel)
+				}
+			}
+		})
+	}
+
+	// Sentinels are sent like the errors they match
+	if response := FormatErrorResponse(fmt.Errorf("find: %w", ErrRecordNotFound)); response.Code != http.StatusNotFound || response.Error != ErrCodeRecordNotFound {
+		t.Errorf("FormatErrorResponse(sentinel) = %+v", response)
+	}
+	if errors.Is(FileNotFoundError("a.txt"), FileNotFoundError("b.txt")) {
+		t.Error("AppErrors match each other instead of only sentinels")
+	}
+}
@@ -0,0 +1,244 @@
+package errors
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Database error codes used to classify driver errors
+const (
+	// PostgreSQL SQLSTATE codes
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+
+	// MySQL error numbers
+	mysqlDuplicateEntry      = 1062
+	mysqlNoReferencedRowNoFK = 1216
+	mysqlRowIsReferencedNoFK = 1217
+	mysqlRowIsReferenced     = 1451
+	mysqlNoReferencedRow     = 1452
+
+	// SQLite extended result codes
+	sqliteConstraintFK     = 787
+	sqliteConstraintPK     = 1555
+	sqliteConstraintUnique = 2067
+)
+
+// Defaults used when the driver error does not name the entity or field
+const (
+	defaultEntityName         = "Record"
+	defaultDuplicateFieldName = "value"
+	defaultRelationName       = "related record"
+)
+
+var (
+	// Key (email)=(john@example.com) already exists.
+	pgDetailPattern = regexp.MustCompile(`Key \(([^)]+)\)=\((.*)\)`)
+	// Duplicate entry 'john@example.com' for key 'users.email'
+	mysqlDuplicatePattern = regexp.MustCompile(`Duplicate entry '(.*)' for key '([^']+)'`)
+	// UNIQUE constraint failed: users.email
+	sqliteUniquePattern = regexp.MustCompile(`constraint failed: ([\w.]+)`)
+)
+
+// dbErrorKind classifies a database error
+type dbErrorKind int
+
+const (
+	dbErrorUnknown dbErrorKind = iota
+	dbErrorDuplicate
+	dbErrorForeignKey
+)
+
+// FromGormError translates GORM and database driver errors into AppErrors.
+// Record-not-found, duplicate key, and foreign key violations from Postgres,
+// MySQL, and SQLite are mapped to RecordNotFoundError, DuplicateEntryError, and
+// ForeignKeyViolationError; anything else becomes a DatabaseError.
+// It returns nil when err is nil.
+func FromGormError(err error) *AppError {
+	return FromGormErrorFor(err, "", nil)
+}
+
+// FromGormErrorFor translates GORM errors like FromGormError, using the given
+// entity name and ID in the resulting messages
+func FromGormErrorFor(err error, entity string, id interface{}) *AppError {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		appErr = RecordNotFoundError(entityOrDefault(entity, ""), id)
+		appErr.Internal = err
+		return appErr
+	}
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		appErr = DuplicateEntryError(entityOrDefault(entity, ""), defaultDuplicateFieldName, nil)
+		appErr.Internal = err
+		return appErr
+	}
+
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		appErr = ForeignKeyViolationError(entityOrDefault(entity, ""), defaultRelationName)
+		appErr.Internal = err
+		return appErr
+	}
+
+	switch kind, table, field, value := classifyDriverError(err); kind {
+	case dbErrorDuplicate:
+		if field == "" {
+			field = defaultDuplicateFieldName
+		}
+		appErr = DuplicateEntryError(entityOrDefault(entity, table), field, value)
+		appErr.Internal = err
+		return appErr
+	case dbErrorForeignKey:
+		relation := field
+		if relation == "" {
+			relation = defaultRelationName
+		}
+		appErr = ForeignKeyViolationError(entityOrDefault(entity, table), relation)
+		appErr.Internal = err
+		return appErr
+	}
+
+	return DatabaseError(err)
+}
+
+// classifyDriverError inspects the error chain for a known driver error and
+// returns its kind along with the table, field, and value when available
+func classifyDriverError(err error) (kind dbErrorKind, table, field string, value interface{}) {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		// PostgreSQL (pgx and lib/pq) expose the SQLSTATE code
+		if sqlState, ok := e.(interface{ SQLState() string }); ok {
+			switch sqlState.SQLState() {
+			case pgUniqueViolation:
+				kind = dbErrorDuplicate
+			case pgForeignKeyViolation:
+				kind = dbErrorForeignKey
+			default:
+				continue
+			}
+			table = stringField(e, "TableName")
+			field = stringField(e, "ColumnName")
+			if m := pgDetailPattern.FindStringSubmatch(stringField(e, "Detail")); m != nil {
+				if field == "" {
+					field = m[1]
+				}
+				value = m[2]
+			}
+			if kind == dbErrorForeignKey && field == "" {
+				field = stringField(e, "ConstraintName")
+			}
+			return kind, table, field, value
+		}
+
+		// MySQL reports a numeric error code
+		if number, ok := intField(e, "Number"); ok {
+			switch number {
+			case mysqlDuplicateEntry:
+				if m := mysqlDuplicatePattern.FindStringSubmatch(e.Error()); m != nil {
+					table, field = splitQualifiedName(m[2])
+					value = m[1]
+				}
+				return dbErrorDuplicate, table, field, value
+			case mysqlRowIsReferenced, mysqlNoReferencedRow, mysqlRowIsReferencedNoFK, mysqlNoReferencedRowNoFK:
+				return dbErrorForeignKey, "", "", nil
+			}
+			continue
+		}
+
+		// SQLite drivers report an extended result code
+		code, ok := intField(e, "ExtendedCode")
+		if !ok {
+			if coder, isCoder := e.(interface{ Code() int }); isCoder {
+				code, ok = int64(coder.Code()), true
+			}
+		}
+		if ok {
+			switch code {
+			case sqliteConstraintUnique, sqliteConstraintPK:
+				if m := sqliteUniquePattern.FindStringSubmatch(e.Error()); m != nil {
+					// Composite constraints list several columns; the pattern captures the first
+					table, field = splitQualifiedName(m[1])
+				}
+				return dbErrorDuplicate, table, field, nil
+			case sqliteConstraintFK:
+				return dbErrorForeignKey, "", "", nil
+			}
+		}
+	}
+
+	return dbErrorUnknown, "", "", nil
+}
+
+// entityOrDefault returns the first non-empty entity name
+func entityOrDefault(entity, table string) string {
+	if entity != "" {
+		return entity
+	}
+	if table != "" {
+		return table
+	}
+	return defaultEntityName
+}
+
+// splitQualifiedName splits "table.column" into its parts
+func splitQualifiedName(name string) (string, string) {
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	return "", name
+}
+
+// structValue returns the struct behind err, dereferencing pointers
+func structValue(err error) (reflect.Value, bool) {
+	v := reflect.ValueOf(err)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct
+}
+
+// stringField reads an exported string field from a driver error
+func stringField(err error, name string) string {
+	v, ok := structValue(err)
+	if !ok {
+		return ""
+	}
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// intField reads an exported integer field from a driver error
+func intField(err error, name string) (int64, bool) {
+	v, ok := structValue(err)
+	if !ok {
+		return 0, false
+	}
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		return 0, false
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,386 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/i18n"
+)
+
+// Supported locales
+const (
+	LocaleEnglish    = "en"
+	LocaleIndonesian = "id"
+)
+
+// Message keys for standard error messages
+const (
+	MsgValidationFailed    = "validation_failed"
+	MsgUnauthorized        = "unauthorized"
+	MsgForbidden           = "forbidden"
+	MsgNotFound            = "not_found"
+	MsgInternalError       = "internal_error"
+	MsgMethodNotAllowed    = "method_not_allowed"
+	MsgServiceUnavailable  = "service_unavailable"
+	MsgFileNotFound        = "file_not_found"
+	MsgFileTooLarge        = "file_too_large"
+	MsgInvalidFileType     = "invalid_file_type"
+	MsgFileAlreadyExists   = "file_already_exists"
+	MsgStorageUnavailable  = "storage_unavailable"
+	MsgInvalidPath         = "invalid_path"
+	MsgInsufficientStorage = "insufficient_storage"
+	MsgFileRejected        = "file_rejected"
+	MsgDownloadFailed      = "download_failed"
+	MsgDatabaseError       = "database_error"
+	MsgRecordNotFound      = "record_not_found"
+	MsgRecordNotFoundNoID  = "record_not_found_no_id"
+	MsgDuplicateEntry      = "duplicate_entry"
+	MsgForeignKeyViolation = "foreign_key_violation"
+	MsgInvalidCredentials  = "invalid_credentials"
+	MsgTokenExpired        = "token_expired"
+	MsgInvalidToken        = "invalid_token"
+	MsgAccountLocked       = "account_locked"
+	MsgInvalidAPIKey       = "invalid_api_key"
+	MsgInsufficientScope   = "insufficient_scope"
+	MsgPermissionDenied    = "permission_denied"
+	MsgInvalidSort         = "invalid_sort"
+	MsgInvalidFilter       = "invalid_filter"
+	MsgInvalidCursor       = "invalid_cursor"
+	MsgInvalidPage         = "invalid_page"
+
+	// Request parsing messages
+	MsgInvalidRequest       = "invalid_request"
+	MsgUnsupportedMediaType = "unsupported_media_type"
+)
+
+// validationMessagePrefix is prepended to a validation tag to build its message key,
+// e.g. "validation.required" or "validation.min.string" for string-specific variants
+const validationMessagePrefix = "validation."
+
+// builtinMessages are the error and validation messages of each locale,
+// added to the catalogs of pkg/i18n. Templates use {name} placeholders
+// which are replaced with the message parameters.
+var builtinMessages = map[string]map[string]string{
+	LocaleEnglish: {
+		MsgValidationFailed:    "Validation failed",
+		MsgUnauthorized:        "Unauthorized access",
+		MsgForbidden:           "Access forbidden",
+		MsgNotFound:            "Resource not found",
+		MsgInternalError:       "Internal server error",
+		MsgMethodNotAllowed:    "Method not allowed",
+		MsgServiceUnavailable:  "Service temporarily unavailable",
+		MsgFileNotFound:        "File not found: {path}",
+		MsgFileTooLarge:        "File size of {size} bytes exceeds the maximum allowed size of {maxSize} bytes",
+		MsgInvalidFileType:     "File type '{fileType}' is not allowed",
+		MsgFileAlreadyExists:   "File already exists: {path}",
+		MsgStorageUnavailable:  "Storage service is currently unavailable",
+		MsgInvalidPath:         "Invalid path: {path}",
+		MsgInsufficientStorage: "Not enough storage space left for a file of {size} bytes",
+		MsgFileRejected:        "File was rejected: {path}",
+		MsgDownloadFailed:      "Failed to download {url}",
+		MsgDatabaseError:       "Database operation failed",
+		MsgRecordNotFound:      "{entity} with ID {id} not found",
+		MsgRecordNotFoundNoID:  "{entity} not found",
+		MsgDuplicateEntry:      "Duplicate {entity}: {field} already exists",
+		MsgForeignKeyViolation: "Cannot modify {entity} due to existing {relation} references",
+		MsgInvalidCredentials:  "Invalid credentials",
+		MsgTokenExpired:        "Authentication token has expired",
+		MsgInvalidToken:        "Invalid authentication token",
+		MsgAccountLocked:       "Account is locked",
+		MsgInvalidAPIKey:       "Invalid API key",
+		MsgInsufficientScope:   "Insufficient scope: requires {scopes}",
+		MsgPermissionDenied:    "Permission '{permission}' is required",
+		MsgInvalidSort:         "Cannot sort by '{field}'",
+		MsgInvalidFilter:       "Cannot filter by '{field}'",
+		MsgInvalidCursor:       "Invalid pagination cursor",
+		MsgInvalidPage:         "Invalid value for '{field}'",
+
+		MsgInvalidRequest:       "The request could not be parsed",
+		MsgUnsupportedMediaType: "Content type '{contentType}' is not supported",
+
+		"validation.required":    "{field} is required",
+		"validation.email":       "Invalid email format",
+		"validation.min.string":  "{field} must be at least {param} characters long",
+		"validation.min":         "{field} must be at least {param}",
+		"validation.max.string":  "{field} must not exceed {param} characters",
+		"validation.max":         "{field} must not exceed {param}",
+		"validation.uuid":        "{field} must be a valid UUID",
+		"validation.oneof":       "{field} must be one of [{param}]",
+		"validation.unique":      "{field} must be unique",
+		"validation.numeric":     "{field} must be numeric",
+		"validation.json":        "{field} must be valid JSON",
+		"validation.url":         "{field} must be a valid URL",
+		"validation.gt":          "{field} must be greater than {param}",
+		"validation.lt":          "{field} must be less than {param}",
+		"validation.gte":         "{field} must be greater than or equal to {param}",
+		"validation.lte":         "{field} must be less than or equal to {param}",
+		"validation.alpha":       "{field} must contain only letters",
+		"validation.alphanum":    "{field} must contain only letters and numbers",
+		"validation.datetime":    "{field} must be a valid datetime",
+		"validation.file":        "{field} must be a valid file",
+		"validation.image":       "{field} must be a valid image",
+		"validation.mime":        "{field} must be of type {param}",
+		"validation.password":    "{field} must meet password requirements",
+		"validation.eqfield":     "{field} must be equal to {param}",
+		"validation.nefield":     "{field} must not be equal to {param}",
+		"validation.isbn":        "{field} must be a valid ISBN",
+		"validation.isbn10":      "{field} must be a valid ISBN-10",
+		"validation.isbn13":      "{field} must be a valid ISBN-13",
+		"validation.creditcard":  "{field} must be a valid credit card number",
+		"validation.hexcolor":    "{field} must be a valid hex color",
+		"validation.rgb":         "{field} must be a valid RGB color",
+		"validation.rgba":        "{field} must be a valid RGBA color",
+		"validation.hsv":         "{field} must be a valid HSV color",
+		"validation.hsla":        "{field} must be a valid HSLA color",
+		"validation.e164":        "{field} must be a valid E.164 formatted phone number",
+		"validation.base64":      "{field} must be a valid Base64 string",
+		"validation.base64url":   "{field} must be a valid Base64URL string",
+		"validation.contains":    "{field} must contain the text '{param}'",
+		"validation.containsany": "{field} must contain at least one of the following characters '{param}'",
+		"validation.excludes":    "{field} may not contain the text '{param}'",
+		"validation.excludesall": "{field} may not contain any of the following characters '{param}'",
+		"validation.ip":          "{field} must be a valid IP address",
+		"validation.ipv4":        "{field} must be a valid IPv4 address",
+		"validation.ipv6":        "{field} must be a valid IPv6 address",
+		"validation.mac":         "{field} must be a valid MAC address",
+		"validation.default":     "{field} failed validation for tag {tag}",
+
+		// Validations registered by validator.WithDefaults
+		"validation.strong_password": "{field} is too weak; use a longer password that mixes letters, numbers, and symbols",
+		"validation.e164_id":         "{field} must be an Indonesian phone number such as +6281234567890",
+		"validation.nik":             "{field} must be a valid 16-digit NIK",
+		"validation.npwp":            "{field} must be a valid NPWP",
+		"validation.slug":            "{field} must contain only lower-case letters, numbers, and hyphens",
+		"validation.no_html":         "{field} must not contain HTML",
+		"validation.safe_filename":   "{field} must be a valid file name",
+
+		// Upload rules checked by validator.FileValidator
+		"validation.maxsize":   "{field} must not be larger than {param}",
+		"validation.minsize":   "{field} must be at least {param}",
+		"validation.ext":       "{field} must have one of the extensions [{param}]",
+		"validation.minwidth":  "{field} must be at least {param} pixels wide",
+		"validation.maxwidth":  "{field} must be at most {param} pixels wide",
+		"validation.minheight": "{field} must be at least {param} pixels high",
+		"validation.maxheight": "{field} must be at most {param} pixels high",
+
+		// Conditional validations, where {condition} lists the fields they depend on
+		"validation.required_if":          "{field} is required when {condition}",
+		"validation.required_unless":      "{field} is required unless {condition}",
+		"validation.required_with":        "{field} is required when {condition} is set",
+		"validation.required_with_all":    "{field} is required when all of {condition} are set",
+		"validation.required_without":     "{field} is required when {condition} is not set",
+		"validation.required_without_all": "{field} is required when none of {condition} are set",
+		"validation.excluded_if":          "{field} must be empty when {condition}",
+		"validation.excluded_unless":      "{field} must be empty unless {condition}",
+		"validation.excluded_with":        "{field} must be empty when {condition} is set",
+		"validation.excluded_with_all":    "{field} must be empty when all of {condition} are set",
+		"validation.excluded_without":     "{field} must be empty when {condition} is not set",
+		"validation.excluded_without_all": "{field} must be empty when none of {condition} are set",
+		"validation.required_if_role":     "{field} is required for the {param} role",
+		"validation.excluded_unless_role": "{field} can only be set by the {param} role",
+		"validation.required_if_flag":     "{field} is required when {param} is enabled",
+		"validation.excluded_unless_flag": "{field} can only be set when {param} is enabled",
+	},
+	LocaleIndonesian: {
+		MsgValidationFailed:    "Validasi gagal",
+		MsgUnauthorized:        "Akses tidak sah",
+		MsgForbidden:           "Akses ditolak",
+		MsgNotFound:            "Data tidak ditemukan",
+		MsgInternalError:       "Terjadi kesalahan pada server",
+		MsgMethodNotAllowed:    "Metode tidak diizinkan",
+		MsgServiceUnavailable:  "Layanan sedang tidak tersedia",
+		MsgFileNotFound:        "File tidak ditemukan: {path}",
+		MsgFileTooLarge:        "Ukuran file {size} byte melebihi batas maksimum {maxSize} byte",
+		MsgInvalidFileType:     "Tipe file '{fileType}' tidak diizinkan",
+		MsgFileAlreadyExists:   "File sudah ada: {path}",
+		MsgStorageUnavailable:  "Layanan penyimpanan sedang tidak tersedia",
+		MsgInvalidPath:         "Path tidak valid: {path}",
+		MsgInsufficientStorage: "Ruang penyimpanan tidak cukup untuk file berukuran {size} byte",
+		MsgFileRejected:        "File ditolak: {path}",
+		MsgDownloadFailed:      "Gagal mengunduh {url}",
+		MsgDatabaseError:       "Operasi database gagal",
+		MsgRecordNotFound:      "{entity} dengan ID {id} tidak ditemukan",
+		MsgRecordNotFoundNoID:  "{entity} tidak ditemukan",
+		MsgDuplicateEntry:      "{entity} duplikat: {field} sudah ada",
+		MsgForeignKeyViolation: "Tidak dapat mengubah {entity} karena masih direferensikan oleh {relation}",
+		MsgInvalidCredentials:  "Kredensial tidak valid",
+		MsgTokenExpired:        "Token autentikasi telah kedaluwarsa",
+		MsgInvalidToken:        "Token autentikasi tidak valid",
+		MsgAccountLocked:       "Akun terkunci",
+		MsgInvalidAPIKey:       "API key tidak valid",
+		MsgInsufficientScope:   "Cakupan akses tidak mencukupi: memerlukan {scopes}",
+		MsgPermissionDenied:    "Izin '{permission}' diperlukan",
+		MsgInvalidSort:         "Tidak dapat mengurutkan berdasarkan '{field}'",
+		MsgInvalidFilter:       "Tidak dapat memfilter berdasarkan '{field}'",
+		MsgInvalidCursor:       "Kursor paginasi tidak valid",
+		MsgInvalidPage:         "Nilai '{field}' tidak valid",
+
+		MsgInvalidRequest:       "Permintaan tidak dapat dibaca",
+		MsgUnsupportedMediaType: "Tipe konten '{contentType}' tidak didukung",
+
+		"validation.required":    "{field} wajib diisi",
+		"validation.email":       "Format email tidak valid",
+		"validation.min.string":  "{field} minimal {param} karakter",
+		"validation.min":         "{field} minimal {param}",
+		"validation.max.string":  "{field} maksimal {param} karakter",
+		"validation.max":         "{field} maksimal {param}",
+		"validation.uuid":        "{field} harus berupa UUID yang valid",
+		"validation.oneof":       "{field} harus salah satu dari [{param}]",
+		"validation.unique":      "{field} harus unik",
+		"validation.numeric":     "{field} harus berupa angka",
+		"validation.json":        "{field} harus berupa JSON yang valid",
+		"validation.url":         "{field} harus berupa URL yang valid",
+		"validation.gt":          "{field} harus lebih besar dari {param}",
+		"validation.lt":          "{field} harus lebih kecil dari {param}",
+		"validation.gte":         "{field} harus lebih besar dari atau sama dengan {param}",
+		"validation.lte":         "{field} harus lebih kecil dari atau sama dengan {param}",
+		"validation.alpha":       "{field} hanya boleh berisi huruf",
+		"validation.alphanum":    "{field} hanya boleh berisi huruf dan angka",
+		"validation.datetime":    "{field} harus berupa tanggal dan waktu yang valid",
+		"validation.file":        "{field} harus berupa file yang valid",
+		"validation.image":       "{field} harus berupa gambar yang valid",
+		"validation.mime":        "{field} harus bertipe {param}",
+		"validation.password":    "{field} harus memenuhi persyaratan kata sandi",
+		"validation.eqfield":     "{field} harus sama dengan {param}",
+		"validation.nefield":     "{field} tidak boleh sama dengan {param}",
+		"validation.isbn":        "{field} harus berupa ISBN yang valid",
+		"validation.isbn10":      "{field} harus berupa ISBN-10 yang valid",
+		"validation.isbn13":      "{field} harus berupa ISBN-13 yang valid",
+		"validation.creditcard":  "{field} harus berupa nomor kartu kredit yang valid",
+		"validation.hexcolor":    "{field} harus berupa warna hex yang valid",
+		"validation.rgb":         "{field} harus berupa warna RGB yang valid",
+		"validation.rgba":        "{field} harus berupa warna RGBA yang valid",
+		"validation.hsv":         "{field} harus berupa warna HSV yang valid",
+		"validation.hsla":        "{field} harus berupa warna HSLA yang valid",
+		"validation.e164":        "{field} harus berupa nomor telepon berformat E.164 yang valid",
+		"validation.base64":      "{field} harus berupa string Base64 yang valid",
+		"validation.base64url":   "{field} harus berupa string Base64URL yang valid",
+		"validation.contains":    "{field} harus mengandung teks '{param}'",
+		"validation.containsany": "{field} harus mengandung setidaknya salah satu karakter berikut '{param}'",
+		"validation.excludes":    "{field} tidak boleh mengandung teks '{param}'",
+		"validation.excludesall": "{field} tidak boleh mengandung karakter berikut '{param}'",
+		"validation.ip":          "{field} harus berupa alamat IP yang valid",
+		"validation.ipv4":        "{field} harus berupa alamat IPv4 yang valid",
+		"validation.ipv6":        "{field} harus berupa alamat IPv6 yang valid",
+		"validation.mac":         "{field} harus berupa alamat MAC yang valid",
+		"validation.default":     "{field} gagal validasi untuk tag {tag}",
+
+		// Validations registered by validator.WithDefaults
+		"validation.strong_password": "{field} terlalu lemah; gunakan kata sandi yang lebih panjang dengan kombinasi huruf, angka, dan simbol",
+		"validation.e164_id":         "{field} harus berupa nomor telepon Indonesia seperti +6281234567890",
+		"validation.nik":             "{field} harus berupa NIK 16 digit yang valid",
+		"validation.npwp":            "{field} harus berupa NPWP yang valid",
+		"validation.slug":            "{field} hanya boleh berisi huruf kecil, angka, dan tanda hubung",
+		"validation.no_html":         "{field} tidak boleh mengandung HTML",
+		"validation.safe_filename":   "{field} harus berupa nama file yang valid",
+
+		// Upload rules checked by validator.FileValidator
+		"validation.maxsize":   "{field} tidak boleh lebih besar dari {param}",
+		"validation.minsize":   "{field} minimal {param}",
+		"validation.ext":       "{field} harus berekstensi salah satu dari [{param}]",
+		"validation.minwidth":  "Lebar {field} minimal {param} piksel",
+		"validation.maxwidth":  "Lebar {field} maksimal {param} piksel",
+		"validation.minheight": "Tinggi {field} minimal {param} piksel",
+		"validation.maxheight": "Tinggi {field} maksimal {param} piksel",
+
+		// Conditional validations, where {condition} lists the fields they depend on
+		"validation.required_if":          "{field} wajib diisi jika {condition}",
+		"validation.required_unless":      "{field} wajib diisi kecuali jika {condition}",
+		"validation.required_with":        "{field} wajib diisi jika {condition} diisi",
+		"validation.required_with_all":    "{field} wajib diisi jika semua {condition} diisi",
+		"validation.required_without":     "{field} wajib diisi jika {condition} tidak diisi",
+		"validation.required_without_all": "{field} wajib diisi jika semua {condition} tidak diisi",
+		"validation.excluded_if":          "{field} harus kosong jika {condition}",
+		"validation.excluded_unless":      "{field} harus kosong kecuali jika {condition}",
+		"validation.excluded_with":        "{field} harus kosong jika {condition} diisi",
+		"validation.excluded_with_all":    "{field} harus kosong jika semua {condition} diisi",
+		"validation.excluded_without":     "{field} harus kosong jika {condition} tidak diisi",
+		"validation.excluded_without_all": "{field} harus kosong jika semua {condition} tidak diisi",
+		"validation.required_if_role":     "{field} wajib diisi untuk peran {param}",
+		"validation.excluded_unless_role": "{field} hanya dapat diisi oleh peran {param}",
+		"validation.required_if_flag":     "{field} wajib diisi jika {param} aktif",
+		"validation.excluded_unless_flag": "{field} hanya dapat diisi jika {param} aktif",
+	},
+}
+
+func init() {
+	for locale, messages := range builtinMessages {
+		catalog := make(i18n.Messages, len(messages))
+		for key, text := range messages {
+			catalog[key] = text
+		}
+		if err := i18n.Add(locale, catalog); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Localize returns a copy of the AppError in err's chain with its message
+// (and validation details) rendered in the given locale. Errors without an
+// AppError or a known message key are returned unchanged.
+func Localize(err error, locale string) error {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+	if localized := appErr.Localize(locale); localized != appErr {
+		return localized
+	}
+	return err
+}
+
+// Localize returns a copy of the error rendered in the given locale
+func (e *AppError) Localize(locale string) *AppError {
+	if e.messageKey == "" && e.validationErrs == nil {
+		return e
+	}
+
+	localized := *e
+	if e.messageKey != "" {
+		localized.Message = translate(locale, e.messageKey, e.messageParams)
+	}
+	if e.validationErrs != nil {
+		localized.Details = buildValidationDetails(e.validationErrs, locale, e.validationOptions)
+	}
+	return &localized
+}
+
+// NewLocalizedError creates an AppError whose message is the catalog message
+// for key, rendered in the request locale when sent by the response package:
+//
+//	i18n.Add("en", i18n.Messages{"order_closed": "Order {id} is closed"})
+//	errors.NewLocalizedError(http.StatusConflict, "ORDER_CLOSED", "order_closed", map[string]string{"id": "42"})
+func NewLocalizedError(httpCode int, code string, key string, params map[string]string) *AppError {
+	return newLocalizedError(httpCode, code, key, params)
+}
+
+// newLocalizedError creates an AppError whose message comes from the catalog
+func newLocalizedError(httpCode int, code string, key string, params map[string]string) *AppError {
+	return &AppError{
+		Code:          code,
+		Message:       translate(i18n.DefaultLocale(), key, params),
+		HTTPCode:      httpCode,
+		messageKey:    key,
+		messageParams: params,
+	}
+}
+
+// translate renders the message for key in a locale, see i18n.Translate
+func translate(locale string, key string, params map[string]string) string {
+	args := make(i18n.Args, len(params))
+	for name, value := range params {
+		args[name] = value
+	}
+	return i18n.Translate(locale, key, args)
+}
+
+// normalizeLocale lowercases a language tag and uses "-" as separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// toParam converts a message parameter to its string form
+func toParam(v interface{}) string {
+	return fmt.Sprint(v)
+}
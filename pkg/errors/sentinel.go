@@ -0,0 +1,101 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for every error code. AppErrors match a sentinel with
+// errors.Is when their codes are equal, regardless of message or details.
+// InvalidFileTypeError, InvalidPathError, DuplicateEntryError, and
+// ForeignKeyViolationError are sent as BAD_REQUEST or CONFLICT and match both
+// that sentinel and their own:
+//
+//	if errors.Is(err, errors.ErrFileNotFound) { ... }
+var (
+	// Generic errors
+	ErrBadRequest         = sentinel(http.StatusBadRequest, ErrCodeBadRequest, "Bad request")
+	ErrUnauthorized       = sentinel(http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized access")
+	ErrForbidden          = sentinel(http.StatusForbidden, ErrCodeForbidden, "Access forbidden")
+	ErrNotFound           = sentinel(http.StatusNotFound, ErrCodeNotFound, "Resource not found")
+	ErrConflict           = sentinel(http.StatusConflict, ErrCodeConflict, "Conflict")
+	ErrValidation         = sentinel(http.StatusUnprocessableEntity, ErrCodeValidationError, "Validation failed")
+	ErrInternal           = sentinel(http.StatusInternalServerError, ErrCodeInternalError, "Internal server error")
+	ErrServiceUnavailable = sentinel(http.StatusServiceUnavailable, ErrCodeServiceUnavailable, "Service temporarily unavailable")
+	ErrMethodNotAllowed   = sentinel(http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed")
+
+	// Filesystem errors
+	ErrFileNotFound        = sentinel(http.StatusNotFound, ErrCodeFileNotFound, "File not found")
+	ErrFileAlreadyExists   = sentinel(http.StatusConflict, ErrCodeFileAlreadyExists, "File already exists")
+	ErrFileTooLarge        = sentinel(http.StatusBadRequest, ErrCodeFileTooLarge, "File too large")
+	ErrInvalidFileType     = sentinel(http.StatusBadRequest, ErrCodeInvalidFileType, "File type is not allowed")
+	ErrStorageUnavailable  = sentinel(http.StatusServiceUnavailable, ErrCodeStorageUnavailable, "Storage service is currently unavailable")
+	ErrPermissionDenied    = sentinel(http.StatusForbidden, ErrCodePermissionDenied, "Permission denied")
+	ErrQuotaExceeded       = sentinel(http.StatusForbidden, ErrCodeQuotaExceeded, "Quota exceeded")
+	ErrInvalidPath         = sentinel(http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+	ErrInsufficientStorage = sentinel(http.StatusInsufficientStorage, ErrCodeInsufficientStorage, "Insufficient storage")
+	ErrFileRejected        = sentinel(http.StatusUnprocessableEntity, ErrCodeFileRejected, "File rejected")
+	ErrDownloadFailed      = sentinel(http.StatusBadGateway, ErrCodeDownloadFailed, "Download failed")
+
+	// Database errors
+	ErrDatabase            = sentinel(http.StatusInternalServerError, ErrCodeDatabaseError, "Database operation failed")
+	ErrDuplicateEntry      = sentinel(http.StatusConflict, ErrCodeDuplicateEntry, "Duplicate entry")
+	ErrForeignKeyViolation = sentinel(http.StatusConflict, ErrCodeForeignKeyViolation, "Foreign key violation")
+	ErrRecordNotFound      = sentinel(http.StatusNotFound, ErrCodeRecordNotFound, "Record not found")
+
+	// Authentication errors
+	ErrInvalidCredentials = sentinel(http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid credentials")
+	ErrTokenExpired       = sentinel(http.StatusUnauthorized, ErrCodeTokenExpired, "Authentication token has expired")
+	ErrInvalidToken       = sentinel(http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid authentication token")
+	ErrAccountLocked      = sentinel(http.StatusForbidden, ErrCodeAccountLocked, "Account is locked")
+	ErrInvalidAPIKey      = sentinel(http.StatusUnauthorized, ErrCodeInvalidAPIKey, "Invalid API key")
+	ErrInsufficientScope  = sentinel(http.StatusForbidden, ErrCodeInsufficientScope, "Insufficient scope")
+
+	// Query errors
+	ErrInvalidSort   = sentinel(http.StatusBadRequest, ErrCodeInvalidSort, "Invalid sort")
+	ErrInvalidFilter = sentinel(http.StatusBadRequest, ErrCodeInvalidFilter, "Invalid filter")
+	ErrInvalidCursor = sentinel(http.StatusBadRequest, ErrCodeInvalidCursor, "Invalid cursor")
+	ErrInvalidPage   = sentinel(http.StatusBadRequest, ErrCodeInvalidPage, "Invalid page")
+
+	// Request errors
+	ErrInvalidRequest       = sentinel(http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid request")
+	ErrUnsupportedMediaType = sentinel(http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Unsupported media type")
+)
+
+// sentinelError is the type of the sentinel errors. Its fields cannot be
+// changed outside the package, so sentinels stay the same for every caller.
+type sentinelError struct {
+	code     string
+	message  string
+	httpCode int
+}
+
+func (e *sentinelError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.code, e.message)
+}
+
+// As sets an *AppError target to a new AppError of the sentinel, so a
+// sentinel returned by a handler is sent like the errors it matches
+func (e *sentinelError) As(target interface{}) bool {
+	appErr, ok := target.(**AppError)
+	if !ok {
+		return false
+	}
+	*appErr = &AppError{Code: e.code, Message: e.message, HTTPCode: e.httpCode}
+	return true
+}
+
+// Is reports whether target is a sentinel of the error's code, or of its
+// specific code, which makes errors.Is match the sentinel errors above
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*sentinelError)
+	if !ok {
+		return false
+	}
+	return t.code == e.Code || (e.kind != "" && t.code == e.kind)
+}
+
+// sentinel creates a sentinel error for a code
+func sentinel(httpCode int, code string, message string) error {
+	return &sentinelError{code: code, message: message, httpCode: httpCode}
+}
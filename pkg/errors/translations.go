@@ -0,0 +1,118 @@
+package errors
+
+import (
+	"sync"
+
+	"github.com/anaknegeri/gokit/pkg/i18n"
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	id_translations "github.com/go-playground/validator/v10/translations/id"
+)
+
+// TranslationRegistrar registers the validation messages of a locale on a
+// validator, e.g. the RegisterDefaultTranslations functions of the
+// github.com/go-playground/validator/v10/translations packages
+type TranslationRegistrar func(v *validator.Validate, trans ut.Translator) error
+
+// validatorLocale is a universal-translator locale with its validation messages
+type validatorLocale struct {
+	translator *sharedTranslator
+	register   TranslationRegistrar
+}
+
+var (
+	translatorMu     sync.RWMutex
+	validatorLocales = map[string]validatorLocale{}
+)
+
+func init() {
+	RegisterValidatorLocale(en.New(), en_translations.RegisterDefaultTranslations)
+	RegisterValidatorLocale(id.New(), id_translations.RegisterDefaultTranslations)
+}
+
+// sharedTranslator is a translator shared by every validator. Each validator
+// registers the same messages again, so adding a message replaces it instead
+// of failing as a conflict.
+type sharedTranslator struct {
+	ut.Translator
+}
+
+// Add adds or replaces a message
+func (t *sharedTranslator) Add(key interface{}, text string, _ bool) error {
+	return t.Translator.Add(key, text, true)
+}
+
+// AddCardinal adds or replaces a cardinal plural message
+func (t *sharedTranslator) AddCardinal(key interface{}, text string, rule locales.PluralRule, _ bool) error {
+	return t.Translator.AddCardinal(key, text, rule, true)
+}
+
+// AddOrdinal adds or replaces an ordinal plural message
+func (t *sharedTranslator) AddOrdinal(key interface{}, text string, rule locales.PluralRule, _ bool) error {
+	return t.Translator.AddOrdinal(key, text, rule, true)
+}
+
+// AddRange adds or replaces a range plural message
+func (t *sharedTranslator) AddRange(key interface{}, text string, rule locales.PluralRule, _ bool) error {
+	return t.Translator.AddRange(key, text, rule, true)
+}
+
+// RegisterValidatorLocale adds the validator's own messages for a locale,
+// which are used for validation tags without a message in the catalog:
+//
+//	errors.RegisterValidatorLocale(fr.New(), fr_translations.RegisterDefaultTranslations)
+//
+// English and Indonesian are registered by default. Validators created by
+// validator.NewValidator afterwards render messages in the locale, and
+// i18n.MatchLocale accepts it.
+func RegisterValidatorLocale(translator locales.Translator, register TranslationRegistrar) {
+	trans, _ := ut.New(translator, translator).GetTranslator(translator.Locale())
+	locale := normalizeLocale(translator.Locale())
+
+	translatorMu.Lock()
+	validatorLocales[locale] = validatorLocale{
+		translator: &sharedTranslator{Translator: trans},
+		register:   register,
+	}
+	translatorMu.Unlock()
+
+	// Make the locale one of the catalogs, so requests can negotiate it
+	_ = i18n.Add(locale, nil)
+}
+
+// RegisterValidatorTranslations registers the messages of every validator
+// locale on v, so that its errors are translated by ValidatorErrorLocalized.
+// validator.NewValidator calls it for the validators it creates.
+func RegisterValidatorTranslations(v *validator.Validate) error {
+	translatorMu.RLock()
+	defer translatorMu.RUnlock()
+
+	for _, l := range validatorLocales {
+		if err := l.register(v, l.translator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateValidation renders a validation error with the validator's own
+// messages for the locale. It fails when the locale or tag has no message or
+// the validator that reported the error has no translations registered.
+func translateValidation(fe validator.FieldError, locale string) (string, bool) {
+	translatorMu.RLock()
+	l, ok := validatorLocales[locale]
+	translatorMu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	message := fe.Translate(l.translator)
+	if message == "" || message == fe.Error() {
+		return "", false
+	}
+	return message, true
+}
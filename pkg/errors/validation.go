@@ -0,0 +1,199 @@
+package errors
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationFormat is the form of the details of validation errors
+type ValidationFormat string
+
+// Validation error formats
+const (
+	// ValidationFormatList sends a []ValidationError, one entry per failed
+	// tag, grouped by field
+	ValidationFormatList ValidationFormat = "list"
+
+	// ValidationFormatMap sends the messages by field path, e.g.
+	// {"email": ["Email is required"]}
+	ValidationFormatMap ValidationFormat = "map"
+)
+
+// ValidationOptions configures the details of the errors built by
+// ValidatorError
+type ValidationOptions struct {
+	// FirstErrorPerField keeps only the first failure of each field
+	FirstErrorPerField bool
+
+	// Format defaults to ValidationFormatList
+	Format ValidationFormat
+}
+
+var (
+	validationMu      sync.RWMutex
+	validationOptions ValidationOptions
+)
+
+// SetValidationOptions sets the options of validation errors for the app,
+// used when ValidatorError is called without options
+func SetValidationOptions(opts ValidationOptions) {
+	validationMu.Lock()
+	defer validationMu.Unlock()
+	validationOptions = opts
+}
+
+// CurrentValidationOptions returns the options set with SetValidationOptions
+func CurrentValidationOptions() ValidationOptions {
+	validationMu.RLock()
+	defer validationMu.RUnlock()
+	return validationOptions
+}
+
+// resolveValidationOptions returns the given options, or those of the app
+func resolveValidationOptions(opts []ValidationOptions) ValidationOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return CurrentValidationOptions()
+}
+
+// buildValidationDetails converts validator errors into the details of the
+// format in the options. The failures of a field are kept together, in the
+// order the field first failed, and repeated failures of a tag are dropped.
+func buildValidationDetails(validationErrs validator.ValidationErrors, locale string, opts ValidationOptions) interface{} {
+	details := buildValidationErrors(groupValidationErrors(validationErrs, opts.FirstErrorPerField), locale)
+	if opts.Format != ValidationFormatMap {
+		return details
+	}
+
+	messages := make(map[string][]string, len(details))
+	for _, detail := range details {
+		messages[detail.Field] = append(messages[detail.Field], detail.Message)
+	}
+	return messages
+}
+
+// groupValidationErrors groups failures by field in first-seen order,
+// dropping repeated tags, or every failure but the first of a field
+func groupValidationErrors(validationErrs validator.ValidationErrors, firstPerField bool) validator.ValidationErrors {
+	var fields []string
+	byField := make(map[string]validator.ValidationErrors, len(validationErrs))
+	for _, e := range validationErrs {
+		field := fieldPath(e)
+		failures, seen := byField[field]
+		if !seen {
+			fields = append(fields, field)
+		}
+		if firstPerField && seen {
+			continue
+		}
+		duplicate := false
+		for _, f := range failures {
+			if f.Tag() == e.Tag() && f.Param() == e.Param() {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			byField[field] = append(failures, e)
+		}
+	}
+
+	grouped := make(validator.ValidationErrors, 0, len(validationErrs))
+	for _, field := range fields {
+		grouped = append(grouped, byField[field]...)
+	}
+	return grouped
+}
+
+// SortValidationErrors orders failures by the declaration order of their
+// fields in v, a struct or a pointer to one, and elements of slices by
+// index. The validator reports the failures of one struct in that order
+// already; sorting keeps it when failures of several validations are
+// merged, e.g. of the fields and of the uploaded files of a request.
+// Failures of fields v does not declare come last.
+func SortValidationErrors(validationErrs validator.ValidationErrors, v interface{}) {
+	type keyed struct {
+		err validator.FieldError
+		key []int
+	}
+
+	t := reflect.TypeOf(v)
+	sorted := make([]keyed, len(validationErrs))
+	for i, e := range validationErrs {
+		sorted[i] = keyed{err: e, key: declarationKey(t, e.StructNamespace())}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lessKey(sorted[i].key, sorted[j].key)
+	})
+	for i, k := range sorted {
+		validationErrs[i] = k.err
+	}
+}
+
+// declarationKey returns the field indexes and element indexes along a
+// struct namespace such as "Order.Items[2].Name", below the top-level struct
+func declarationKey(t reflect.Type, namespace string) []int {
+	_, path, nested := strings.Cut(namespace, ".")
+	if !nested {
+		path = namespace
+	}
+
+	var key []int
+	for _, segment := range strings.Split(path, ".") {
+		name, indexes, _ := strings.Cut(segment, "[")
+		t = derefType(t)
+		if t == nil || t.Kind() != reflect.Struct {
+			return append(key, math.MaxInt)
+		}
+		field, ok := t.FieldByName(name)
+		if !ok || len(field.Index) != 1 {
+			return append(key, math.MaxInt)
+		}
+		key = append(key, field.Index[0])
+		t = field.Type
+
+		// Element indexes, e.g. "2]" or "2][0]"
+		for indexes != "" {
+			var index string
+			index, indexes, _ = strings.Cut(indexes, "]")
+			indexes = strings.TrimPrefix(indexes, "[")
+			n, err := strconv.Atoi(index)
+			if err != nil {
+				n = 0
+			}
+			key = append(key, n)
+			if t = derefType(t); t != nil {
+				switch t.Kind() {
+				case reflect.Slice, reflect.Array, reflect.Map:
+					t = t.Elem()
+				}
+			}
+		}
+	}
+	return key
+}
+
+// derefType dereferences pointer types
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// lessKey compares declaration keys, a field before those of its struct
+func lessKey(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,132 @@
+package errors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type validationItem struct {
+	Name string `validate:"required"`
+}
+
+type validationOrder struct {
+	Name     string            `validate:"required"`
+	Email    string            `validate:"required,email"`
+	Items    []validationItem  `validate:"dive"`
+	Customer *validationItem   `validate:"required"`
+	Tags     map[string]string `validate:"required"`
+}
+
+// orderErrors returns the failures of an invalid order
+func orderErrors(t *testing.T) validator.ValidationErrors {
+	t.Helper()
+	err := validator.New().Struct(validationOrder{
+		Email: "not-an-email",
+		Items: []validationItem{{Name: "a"}, {}, {}},
+	})
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		t.Fatalf("Struct() = %v", err)
+	}
+	return errs
+}
+
+// detailFields returns the fields of list details
+func detailFields(t *testing.T, details interface{}) []string {
+	t.Helper()
+	list, ok := details.([]ValidationError)
+	if !ok {
+		t.Fatalf("details are %T", details)
+	}
+	fields := make([]string, len(list))
+	for i, d := range list {
+		fields[i] = d.Field + ":" + d.Tag
+	}
+	return fields
+}
+
+func TestValidatorErrorGroupsFields(t *testing.T) {
+	errs := orderErrors(t)
+	email := errs[1]
+
+	// Failures of several validations, repeating one
+	merged := append(validator.ValidationErrors{}, errs...)
+	merged = append(merged, email, &extraFieldError{FieldError: email, tag: "max"})
+
+	got := detailFields(t, ValidatorError(merged, ValidationOptions{}).Details)
+	want := []string{"name:required", "email:email", "email:max", "items[1].name:required", "items[2].name:required", "customer:required", "tags:required"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+
+	got = detailFields(t, ValidatorError(merged, ValidationOptions{FirstErrorPerField: true}).Details)
+	want = []string{"name:required", "email:email", "items[1].name:required", "items[2].name:required", "customer:required", "tags:required"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("first error per field = %v, want %v", got, want)
+	}
+}
+
+func TestValidatorErrorMapFormat(t *testing.T) {
+	errs := orderErrors(t)
+	errs = append(errs, &extraFieldError{FieldError: errs[1], tag: "max"})
+
+	appErr := ValidatorError(errs, ValidationOptions{Format: ValidationFormatMap})
+	details, ok := appErr.Details.(map[string][]string)
+	if !ok {
+		t.Fatalf("details are %T", appErr.Details)
+	}
+	if len(details) != 6 || len(details["email"]) != 2 || len(details["items[2].name"]) != 1 {
+		t.Errorf("details = %v", details)
+	}
+
+	// Localized copies keep the format
+	if _, ok := appErr.Localize(LocaleIndonesian).Details.(map[string][]string); !ok {
+		t.Error("Localize changed the format")
+	}
+
+	SetValidationOptions(ValidationOptions{Format: ValidationFormatMap, FirstErrorPerField: true})
+	defer SetValidationOptions(ValidationOptions{})
+	details, ok = ValidatorError(errs).Details.(map[string][]string)
+	if !ok || len(details["email"]) != 1 {
+		t.Errorf("app options are not applied: %v", details)
+	}
+}
+
+func TestSortValidationErrors(t *testing.T) {
+	errs := orderErrors(t)
+	want := detailFields(t, ValidatorError(errs, ValidationOptions{}).Details)
+
+	reversed := make(validator.ValidationErrors, 0, len(errs))
+	for i := len(errs) - 1; i >= 0; i-- {
+		reversed = append(reversed, errs[i])
+	}
+	SortValidationErrors(reversed, &validationOrder{})
+	if got := detailFields(t, ValidatorError(reversed, ValidationOptions{}).Details); !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted fields = %v, want %v", got, want)
+	}
+
+	unknown := &extraFieldError{FieldError: errs[0], tag: "custom", namespace: "validationOrder.Missing"}
+	sorted := validator.ValidationErrors{unknown, errs[1], errs[0]}
+	SortValidationErrors(sorted, validationOrder{})
+	if sorted[0] != errs[0] || sorted[1] != errs[1] || sorted[2] != unknown {
+		t.Errorf("undeclared fields are not last: %v", sorted)
+	}
+}
+
+// extraFieldError is a failure of another tag on the field of a failure
+type extraFieldError struct {
+	validator.FieldError
+	tag       string
+	namespace string
+}
+
+func (e *extraFieldError) Tag() string { return e.tag }
+
+func (e *extraFieldError) StructNamespace() string {
+	if e.namespace != "" {
+		return e.namespace
+	}
+	return e.FieldError.StructNamespace()
+}
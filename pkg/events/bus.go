@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// Config configures a bus
+type Config struct {
+	// Middleware wraps every handler, the first one outermost
+	Middleware []Middleware
+
+	// Logger logs failed handlers, defaulting to the logger named "events"
+	Logger *logger.Logger
+}
+
+// SubscribeConfig configures a subscription
+type SubscribeConfig struct {
+	// Group shares the events between the subscribers of the group, e.g. one
+	// group per service so each event is handled once by one of its
+	// instances. Without a group every subscriber receives every event.
+	Group string
+}
+
+// Bus publishes events through a backend and runs the handlers of its
+// subscriptions with middleware
+type Bus struct {
+	backend Backend
+	config  Config
+
+	mu         sync.RWMutex
+	middleware []Middleware
+}
+
+// New creates a bus delivering events through a backend
+func New(backend Backend, config ...Config) *Bus {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("events")
+	}
+	return &Bus{backend: backend, config: cfg, middleware: cfg.Middleware}
+}
+
+// Use adds middleware to the handlers subscribed afterwards
+func (b *Bus) Use(middleware ...Middleware) *Bus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middleware = append(b.middleware[:len(b.middleware):len(b.middleware)], middleware...)
+	return b
+}
+
+// Publish publishes data encoded as JSON on a topic
+func (b *Bus) Publish(ctx context.Context, topic string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("events: encode data: %w", err)
+	}
+	return b.PublishEvent(ctx, &Event{Topic: topic, Data: encoded})
+}
+
+// PublishEvent publishes an event, setting its ID and time when missing
+func (b *Bus) PublishEvent(ctx context.Context, event *Event) error {
+	if event.ID == "" {
+		event.ID = newID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	return b.backend.Publish(ctx, event)
+}
+
+// Subscribe delivers the events of a topic to a handler wrapped with the
+// bus middleware. Panics in the handler are recovered into errors.
+func (b *Bus) Subscribe(topic string, handler Handler, config ...SubscribeConfig) (Subscription, error) {
+	cfg := SubscribeConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	b.mu.RLock()
+	middleware := b.middleware
+	b.mu.RUnlock()
+
+	wrapped := recoverHandler(handler)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		wrapped = middleware[i](wrapped)
+	}
+
+	return b.backend.Subscribe(topic, cfg.Group, func(ctx context.Context, event *Event) error {
+		log := b.config.Logger.WithFields(logger.Fields{
+			"event_id": event.ID,
+			"topic":    event.Topic,
+		})
+		err := wrapped(logger.WithContext(ctx, log), event)
+		if err != nil {
+			log.Errorf("Failed to handle event %s: %v", event.Topic, err)
+		}
+		return err
+	})
+}
+
+// Close stops the subscriptions of the backend
+func (b *Bus) Close() error {
+	return b.backend.Close()
+}
+
+// recoverHandler turns panics of a handler into errors
+func recoverHandler(handler Handler) Handler {
+	return func(ctx context.Context, event *Event) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.FromContext(ctx).Errorf("Event handler panicked: %v\n%s", r, debug.Stack())
+				appErr := errors.InternalServerError("")
+				appErr.Internal = fmt.Errorf("panic: %v", r)
+				err = appErr
+			}
+		}()
+		return handler(ctx, event)
+	}
+}
+
+// newID returns a random event ID
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
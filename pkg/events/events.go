@@ -0,0 +1,87 @@
+// Package events publishes events to subscribers in process or through Redis
+// Streams or NATS, for domain events such as an uploaded file
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Event is a message published on a topic
+type Event struct {
+	ID       string            `json:"id"`
+	Topic    string            `json:"topic"`
+	Data     json.RawMessage   `json:"data,omitempty"`
+	Time     time.Time         `json:"time"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Decode decodes the data of the event into v
+func (e *Event) Decode(v interface{}) error {
+	if len(e.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// Handler handles the events of a subscription
+type Handler func(ctx context.Context, event *Event) error
+
+// Middleware wraps the handlers of a bus, e.g. Logging or Retry
+type Middleware func(next Handler) Handler
+
+// Subscription is a handler receiving the events of a topic
+type Subscription interface {
+	// Unsubscribe stops delivering events to the handler
+	Unsubscribe() error
+}
+
+// Backend delivers events to subscribers, e.g. NewMemory, NewRedis, or
+// ConnectNATS
+type Backend interface {
+	// Publish sends an event to the subscribers of its topic
+	Publish(ctx context.Context, event *Event) error
+
+	// Subscribe delivers the events of a topic to a handler. Subscribers in
+	// the same non-empty group share the events, each going to one of them;
+	// every group and every subscriber without one receives all events.
+	Subscribe(topic, group string, handler Handler) (Subscription, error)
+
+	// Close stops the subscriptions, waiting for the events being handled
+	Close() error
+}
+
+// Topic is a topic whose events carry data of type T:
+//
+//	var UserCreated = events.NewTopic[UserCreatedEvent]("users.created")
+//
+//	err := UserCreated.Publish(ctx, bus, UserCreatedEvent{ID: user.ID})
+//
+//	UserCreated.Subscribe(bus, func(ctx context.Context, e UserCreatedEvent) error {
+//		return mailer.SendWelcome(ctx, e.ID)
+//	})
+type Topic[T any] struct {
+	Name string
+}
+
+// NewTopic creates a typed topic
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{Name: name}
+}
+
+// Publish publishes data on the topic
+func (t Topic[T]) Publish(ctx context.Context, bus *Bus, data T) error {
+	return bus.Publish(ctx, t.Name, data)
+}
+
+// Subscribe delivers the decoded data of the topic's events to fn
+func (t Topic[T]) Subscribe(bus *Bus, fn func(ctx context.Context, data T) error, config ...SubscribeConfig) (Subscription, error) {
+	return bus.Subscribe(t.Name, func(ctx context.Context, event *Event) error {
+		var data T
+		if err := event.Decode(&data); err != nil {
+			return err
+		}
+		return fn(ctx, data)
+	}, config...)
+}
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Memory delivers events to subscribers in the same process. Each
+// subscription handles its events in order in its own goroutine, so
+// publishing never waits for handlers.
+type Memory struct {
+	mu     sync.Mutex
+	subs   map[string][]*memorySub
+	next   map[string]int
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// memorySub is a subscription of a memory backend
+type memorySub struct {
+	memory *Memory
+	topic  string
+	group  string
+	inbox  *inbox
+}
+
+// NewMemory creates an in-process backend
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[string][]*memorySub), next: make(map[string]int)}
+}
+
+// Publish queues an event for the subscribers of its topic
+func (m *Memory) Publish(_ context.Context, event *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return errors.New("events: backend is closed")
+	}
+
+	groups := make(map[string][]*memorySub)
+	for _, sub := range m.subs[event.Topic] {
+		if sub.group == "" {
+			sub.inbox.push(copyEvent(event))
+			continue
+		}
+		groups[sub.group] = append(groups[sub.group], sub)
+	}
+	// Each group takes turns between its subscribers
+	for group, subs := range groups {
+		key := event.Topic + "\x00" + group
+		subs[m.next[key]%len(subs)].inbox.push(copyEvent(event))
+		m.next[key]++
+	}
+	return nil
+}
+
+// Subscribe delivers the events of a topic to a handler
+func (m *Memory) Subscribe(topic, group string, handler Handler) (Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, errors.New("events: backend is closed")
+	}
+
+	sub := &memorySub{memory: m, topic: topic, group: group, inbox: newInbox(handler, &m.wg)}
+	m.subs[topic] = append(m.subs[topic], sub)
+	return sub, nil
+}
+
+// Close stops the subscriptions after handling the queued events
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	for _, subs := range m.subs {
+		for _, sub := range subs {
+			sub.inbox.close()
+		}
+	}
+	m.subs = make(map[string][]*memorySub)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	return nil
+}
+
+// Unsubscribe stops delivering events, letting the queued ones be handled
+func (s *memorySub) Unsubscribe() error {
+	m := s.memory
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subs := m.subs[s.topic]
+	for i, sub := range subs {
+		if sub == s {
+			m.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	s.inbox.close()
+	return nil
+}
+
+// inbox queues events for a handler running in its own goroutine
+type inbox struct {
+	handler Handler
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []*Event
+	closed bool
+}
+
+// newInbox starts the goroutine of an inbox, tracked by wg
+func newInbox(handler Handler, wg *sync.WaitGroup) *inbox {
+	in := &inbox{handler: handler}
+	in.cond = sync.NewCond(&in.mu)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		in.run()
+	}()
+	return in
+}
+
+// push queues an event unless the inbox is closed
+func (in *inbox) push(event *Event) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.closed {
+		return
+	}
+	in.queue = append(in.queue, event)
+	in.cond.Signal()
+}
+
+// close stops the inbox once its queue is empty
+func (in *inbox) close() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.closed = true
+	in.cond.Signal()
+}
+
+// run handles the queued events until the inbox is closed and empty
+func (in *inbox) run() {
+	for {
+		in.mu.Lock()
+		for len(in.queue) == 0 && !in.closed {
+			in.cond.Wait()
+		}
+		if len(in.queue) == 0 {
+			in.mu.Unlock()
+			return
+		}
+		event := in.queue[0]
+		in.queue[0] = nil
+		in.queue = in.queue[1:]
+		in.mu.Unlock()
+
+		// Errors are logged by the bus; there is nothing to retry in process
+		_ = in.handler(context.Background(), event)
+	}
+}
+
+// copyEvent copies an event so subscribers cannot change each other's
+func copyEvent(event *Event) *Event {
+	copied := *event
+	if event.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(event.Metadata))
+		for k, v := range event.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return &copied
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the events handled by named subscribers
+type recorder struct {
+	mu     sync.Mutex
+	events map[string][]string
+}
+
+func newRecorder() *recorder {
+	return &recorder{events: make(map[string][]string)}
+}
+
+// handler returns a handler recording the IDs of its events under a name
+func (r *recorder) handler(name string) Handler {
+	return func(_ context.Context, event *Event) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events[name] = append(r.events[name], event.ID)
+		return nil
+	}
+}
+
+// get returns the IDs of the events a subscriber handled
+func (r *recorder) get(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events[name]...)
+}
+
+func TestMemoryGroups(t *testing.T) {
+	backend := NewMemory()
+	rec := newRecorder()
+	subscribe := func(name, topic, group string) Subscription {
+		t.Helper()
+		sub, err := backend.Subscribe(topic, group, rec.handler(name))
+		if err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+		return sub
+	}
+	for _, name := range []string{"worker1", "worker2", "worker3"} {
+		subscribe(name, "orders", "workers")
+	}
+	subscribe("audit", "orders", "audit")
+	subscribe("all", "orders", "")
+	subscribe("other", "users", "")
+
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		if err := backend.Publish(ctx, &Event{ID: fmt.Sprint(i), Topic: "orders"}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	// Close waits for the queued events to be handled
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"worker1", "[0 3]"},
+		{"worker2", "[1 4]"},
+		{"worker3", "[2 5]"},
+		{"audit", "[0 1 2 3 4 5]"},
+		{"all", "[0 1 2 3 4 5]"},
+		{"other", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(rec.get(tt.name)); got != tt.want {
+			t.Errorf("Expected %s to handle %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if err := backend.Publish(ctx, &Event{Topic: "orders"}); err == nil {
+		t.Error("Expected publishing on a closed backend to fail")
+	}
+}
+
+func TestMemoryUnsubscribe(t *testing.T) {
+	backend := NewMemory()
+	rec := newRecorder()
+	first, _ := backend.Subscribe("orders", "workers", rec.handler("first"))
+	backend.Subscribe("orders", "workers", rec.handler("second"))
+
+	ctx := context.Background()
+	backend.Publish(ctx, &Event{ID: "0", Topic: "orders"})
+	if err := first.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+	backend.Publish(ctx, &Event{ID: "1", Topic: "orders"})
+	backend.Publish(ctx, &Event{ID: "2", Topic: "orders"})
+	backend.Close()
+
+	if got := fmt.Sprint(rec.get("first"), rec.get("second")); got != "[0] [1 2]" {
+		t.Errorf("Expected the remaining subscriber to get the later events, got %s", got)
+	}
+}
+
+func TestBusMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		panics   bool
+		calls    int
+		handled  bool
+	}{
+		{"succeeds", 0, false, 1, true},
+		{"succeeds on retry", 2, false, 3, true},
+		{"out of attempts", 5, false, 3, false},
+		{"panics", 0, true, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := New(NewMemory(), Config{Middleware: []Middleware{Retry(3, time.Millisecond)}})
+			var mu sync.Mutex
+			calls, handled := 0, false
+			_, err := NewTopic[map[string]int]("orders").Subscribe(bus, func(_ context.Context, data map[string]int) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				if tt.panics {
+					panic("boom")
+				}
+				if calls <= tt.failures {
+					return errors.New("unavailable")
+				}
+				handled = data["id"] == 7
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Subscribe failed: %v", err)
+			}
+			if err := NewTopic[map[string]int]("orders").Publish(context.Background(), bus, map[string]int{"id": 7}); err != nil {
+				t.Fatalf("Publish failed: %v", err)
+			}
+			bus.Close()
+
+			if calls != tt.calls || handled != tt.handled {
+				t.Errorf("Expected %d calls handling the event %v, got %d and %v", tt.calls, tt.handled, calls, handled)
+			}
+		})
+	}
+}
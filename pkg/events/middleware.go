@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// Logging logs every event handled successfully and how long it took with
+// the logger of the handler context. Failures are always logged by the bus.
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, event *Event) error {
+			start := time.Now()
+			err := next(ctx, event)
+			if err == nil {
+				logger.FromContext(ctx).Infof("Handled event %s in %s", event.Topic, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// Retry calls a failing handler again up to attempts times in all, waiting
+// delay after the first failure and doubling it after each further one
+func Retry(attempts int, delay time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, event *Event) error {
+			wait := delay
+			var err error
+			for attempt := 1; ; attempt++ {
+				if err = next(ctx, event); err == nil || attempt >= attempts {
+					return err
+				}
+				logger.FromContext(ctx).Warnf("Event handler failed, retrying in %s: %v", wait, err)
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				case <-timer.C:
+				}
+				wait *= 2
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natsServer is a NATS server handling the protocol messages of the client:
+// CONNECT, PING, SUB, UNSUB, and PUB, with queue groups taking turns
+type natsServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	connects []string
+	conns    []net.Conn
+	subs     []*natsServerSub
+	turns    map[string]int
+}
+
+type natsServerSub struct {
+	conn    net.Conn
+	subject string
+	group   string
+	sid     string
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &natsServer{listener: listener, turns: make(map[string]int)}
+	t.Cleanup(func() {
+		listener.Close()
+		s.dropConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// url returns the URL of the server
+func (s *natsServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+// dropConnections closes the connections of the clients
+func (s *natsServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	s.subs = nil
+}
+
+// subscriptions returns the number of subscriptions
+func (s *natsServer) subscriptions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+// serve answers the protocol messages of a connection
+func (s *natsServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	write := func(format string, args ...interface{}) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		fmt.Fprintf(conn, format, args...)
+	}
+
+	write("INFO {\"server_id\":\"test\"}\r\n")
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			return
+		}
+		verb, args, _ := strings.Cut(line, " ")
+		fields := strings.Fields(args)
+		switch verb {
+		case "CONNECT":
+			s.mu.Lock()
+			s.connects = append(s.connects, args)
+			s.mu.Unlock()
+		case "PING":
+			write("PONG\r\n")
+		case "SUB":
+			sub := &natsServerSub{conn: conn, subject: fields[0], sid: fields[len(fields)-1]}
+			if len(fields) == 3 {
+				sub.group = fields[1]
+			}
+			s.mu.Lock()
+			s.subs = append(s.subs, sub)
+			s.mu.Unlock()
+		case "UNSUB":
+			s.mu.Lock()
+			for i, sub := range s.subs {
+				if sub.conn == conn && sub.sid == fields[0] {
+					s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+					break
+				}
+			}
+			s.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.publish(fields[0], payload[:size])
+		}
+	}
+}
+
+// publish sends a message to every subscription without a group and to one
+// subscription of each group
+func (s *natsServer) publish(subject string, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	groups := make(map[string][]*natsServerSub)
+	var targets []*natsServerSub
+	for _, sub := range s.subs {
+		switch {
+		case sub.subject != subject:
+		case sub.group == "":
+			targets = append(targets, sub)
+		default:
+			groups[sub.group] = append(groups[sub.group], sub)
+		}
+	}
+	for group, subs := range groups {
+		targets = append(targets, subs[s.turns[group]%len(subs)])
+		s.turns[group]++
+	}
+	for _, sub := range targets {
+		fmt.Fprintf(sub.conn, "MSG %s %s %d\r\n%s\r\n", subject, sub.sid, len(payload), payload)
+	}
+}
+
+func TestNATS(t *testing.T) {
+	server := newNATSServer(t)
+	backend, err := ConnectNATS(NATSConfig{URL: server.url(), Token: "secret", Name: "test"})
+	if err != nil {
+		t.Fatalf("ConnectNATS failed: %v", err)
+	}
+	defer backend.Close()
+	server.mu.Lock()
+	connect := server.connects[0]
+	server.mu.Unlock()
+	if !strings.Contains(connect, `"auth_token":"secret"`) || !strings.Contains(connect, `"name":"test"`) {
+		t.Errorf("Expected the token and name in CONNECT, got %s", connect)
+	}
+
+	rec := newRecorder()
+	for _, name := range []string{"worker1", "worker2"} {
+		if _, err := backend.Subscribe("orders", "workers", rec.handler(name)); err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+	}
+	all, err := backend.Subscribe("orders", "", rec.handler("all"))
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := backend.Subscribe("bad subject", "", rec.handler("bad")); err == nil {
+		t.Error("Expected a subject with spaces to be refused")
+	}
+	waitFor(t, "the subscriptions", func() bool { return server.subscriptions() == 3 })
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if err := backend.Publish(ctx, &Event{ID: fmt.Sprint(i), Topic: "orders", Data: []byte(`{"id":1}`)}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	waitFor(t, "the events", func() bool { return len(rec.get("all")) == 4 && len(rec.get("worker2")) == 2 })
+	if got := fmt.Sprint(rec.get("all"), rec.get("worker1"), rec.get("worker2")); got != "[0 1 2 3] [0 2] [1 3]" {
+		t.Errorf("Expected the group to share the events, got %s", got)
+	}
+
+	if err := all.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+	waitFor(t, "the unsubscription", func() bool { return server.subscriptions() == 2 })
+}
+
+func TestNATSReconnect(t *testing.T) {
+	server := newNATSServer(t)
+	backend, err := ConnectNATS(NATSConfig{URL: server.url(), ReconnectWait: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("ConnectNATS failed: %v", err)
+	}
+	defer backend.Close()
+
+	rec := newRecorder()
+	if _, err := backend.Subscribe("orders", "", rec.handler("all")); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitFor(t, "the subscription", func() bool { return server.subscriptions() == 1 })
+
+	// The client connects again and subscribes again after losing the
+	// connection
+	server.dropConnections()
+	waitFor(t, "the new subscription", func() bool { return server.subscriptions() == 1 })
+	if err := backend.Publish(context.Background(), &Event{ID: "after", Topic: "orders"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	waitFor(t, "the event", func() bool { return len(rec.get("all")) == 1 })
+}
+
+func TestConnectNATSErrors(t *testing.T) {
+	if _, err := ConnectNATS(NATSConfig{URL: "://invalid"}); err == nil {
+		t.Error("Expected an invalid URL to be refused")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "INFO {}\r\n")
+		reader := bufio.NewReader(conn)
+		readLine(reader)
+		fmt.Fprintf(conn, "-ERR 'Authorization Violation'\r\n")
+	}()
+	if _, err := ConnectNATS(NATSConfig{URL: "nats://" + listener.Addr().String()}); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("Expected the server error, got %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+const (
+	// DefaultMaxLen is roughly how many events a Redis stream keeps by default
+	DefaultMaxLen = 10000
+
+	// DefaultRetryAfter is how long a failed event of a group waits before it
+	// is delivered again by default
+	DefaultRetryAfter = 30 * time.Second
+
+	// DefaultMaxDeliveries is how many times an event of a group is delivered
+	// by default before it is dropped
+	DefaultMaxDeliveries = 5
+
+	// redisBlock is how long a read waits for new events
+	redisBlock = time.Second
+
+	// redisBatch is how many events a read returns at most
+	redisBatch = 10
+)
+
+// Redis delivers events through Redis Streams (Redis 6.2 or later), one
+// stream per topic. Subscribers with a group read through a consumer group:
+// events whose handler fails stay pending and are delivered again after the
+// retry delay, also when the subscriber crashed, until they reach the
+// maximum deliveries. Subscribers without a group receive the events
+// published after they subscribed.
+type Redis struct {
+	client        cache.RedisClient
+	prefix        string
+	maxLen        int
+	retryAfter    time.Duration
+	maxDeliveries int
+	consumer      string
+	logger        *logger.Logger
+
+	mu     sync.Mutex
+	closed bool
+	cancel context.CancelFunc
+	ctx    context.Context
+	wg     sync.WaitGroup
+}
+
+// redisSub is a subscription of a Redis backend
+type redisSub struct {
+	cancel context.CancelFunc
+}
+
+// NewRedis creates a Redis Streams backend whose keys start with prefix,
+// e.g. "myapp:", using a client such as cache.DialRedis. The client must
+// allow commands that block for a second.
+func NewRedis(client cache.RedisClient, prefix string) *Redis {
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Redis{
+		client:        client,
+		prefix:        prefix,
+		maxLen:        DefaultMaxLen,
+		retryAfter:    DefaultRetryAfter,
+		maxDeliveries: DefaultMaxDeliveries,
+		consumer:      fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), newID()[:8]),
+		logger:        logger.Named("events"),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// SetMaxLen sets roughly how many events each stream keeps
+func (r *Redis) SetMaxLen(n int) *Redis {
+	r.maxLen = n
+	return r
+}
+
+// SetRetry sets how long failed events of a group wait before they are
+// delivered again and how many times they are delivered at most
+func (r *Redis) SetRetry(after time.Duration, maxDeliveries int) *Redis {
+	r.retryAfter = after
+	r.maxDeliveries = maxDeliveries
+	return r
+}
+
+// Publish adds an event to the stream of its topic
+func (r *Redis) Publish(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.Do(ctx, "XADD", r.streamKey(event.Topic), "MAXLEN", "~", r.maxLen, "*", "event", data)
+	return err
+}
+
+// Subscribe reads the stream of a topic in the background and delivers its
+// events to a handler
+func (r *Redis) Subscribe(topic, group string, handler Handler) (Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, errors.New("events: backend is closed")
+	}
+
+	key := r.streamKey(topic)
+	if group != "" {
+		_, err := r.client.Do(r.ctx, "XGROUP", "CREATE", key, group, "$", "MKSTREAM")
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return nil, err
+		}
+	}
+
+	// Events are read from the millisecond of the subscription, not from
+	// when the goroutine starts: the last possible ID of the millisecond
+	// before it
+	since := strconv.FormatInt(time.Now().UnixMilli()-1, 10) + "-" + strconv.FormatUint(math.MaxUint64, 10)
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if group == "" {
+			r.read(ctx, key, since, handler)
+		} else {
+			r.readGroup(ctx, key, group, handler)
+		}
+	}()
+	return &redisSub{cancel: cancel}, nil
+}
+
+// Close stops the subscriptions, waiting for the events being handled
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	r.cancel()
+	r.wg.Wait()
+	return nil
+}
+
+// Unsubscribe stops reading the stream
+func (s *redisSub) Unsubscribe() error {
+	s.cancel()
+	return nil
+}
+
+// read delivers the events added to a stream after the ID lastID
+func (r *Redis) read(ctx context.Context, key, lastID string, handler Handler) {
+	for ctx.Err() == nil {
+		reply, err := r.do(ctx, "XREAD", "COUNT", redisBatch, "BLOCK", redisBlock.Milliseconds(), "STREAMS", key, lastID)
+		if err != nil {
+			r.wait(ctx, err)
+			continue
+		}
+		for _, entry := range streamEntries(reply) {
+			lastID = entry.id
+			r.handle(entry, handler)
+		}
+	}
+}
+
+// readGroup delivers the events of a stream through a consumer group,
+// acknowledging those handled successfully
+func (r *Redis) readGroup(ctx context.Context, key, group string, handler Handler) {
+	for ctx.Err() == nil {
+		if err := r.retryPending(ctx, key, group, handler); err != nil {
+			r.wait(ctx, err)
+			continue
+		}
+
+		reply, err := r.do(ctx, "XREADGROUP", "GROUP", group, r.consumer, "COUNT", redisBatch,
+			"BLOCK", redisBlock.Milliseconds(), "STREAMS", key, ">")
+		if err != nil {
+			r.wait(ctx, err)
+			continue
+		}
+		for _, entry := range streamEntries(reply) {
+			if r.handle(entry, handler) == nil {
+				_, _ = r.client.Do(context.Background(), "XACK", key, group, entry.id)
+			}
+		}
+	}
+}
+
+// retryPending claims the events of a group that have been pending for the
+// retry delay and delivers them again, dropping those out of deliveries
+func (r *Redis) retryPending(ctx context.Context, key, group string, handler Handler) error {
+	idle := r.retryAfter.Milliseconds()
+	reply, err := r.client.Do(ctx, "XPENDING", key, group, "IDLE", idle, "-", "+", redisBatch)
+	if err != nil {
+		return err
+	}
+
+	pending, _ := reply.([]interface{})
+	for _, item := range pending {
+		fields, _ := item.([]interface{})
+		if len(fields) < 4 {
+			continue
+		}
+		id := replyString(fields[0])
+		deliveries, _ := fields[3].(int64)
+
+		if int(deliveries) >= r.maxDeliveries {
+			r.logger.Errorf("Dropped event %s of stream %s after %d deliveries", id, key, deliveries)
+			if _, err := r.client.Do(ctx, "XACK", key, group, id); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Claiming fails when another consumer was first
+		reply, err := r.client.Do(ctx, "XCLAIM", key, group, r.consumer, idle, id)
+		if err != nil {
+			return err
+		}
+		entries, _ := reply.([]interface{})
+		for _, entry := range parseEntries(entries) {
+			if r.handle(entry, handler) == nil {
+				_, _ = r.client.Do(context.Background(), "XACK", key, group, entry.id)
+			}
+		}
+	}
+	return nil
+}
+
+// handle decodes an entry and passes it to a handler, which finishes even
+// when the backend is closing
+func (r *Redis) handle(entry streamEntry, handler Handler) error {
+	var event Event
+	if err := json.Unmarshal(entry.data, &event); err != nil {
+		r.logger.Errorf("Failed to decode event %s: %v", entry.id, err)
+		return nil
+	}
+	return handler(context.Background(), &event)
+}
+
+// do runs a blocking command with a deadline past the blocking time
+func (r *Redis) do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisBlock+5*time.Second)
+	defer cancel()
+	return r.client.Do(ctx, args...)
+}
+
+// wait logs a read error and pauses before the next read
+func (r *Redis) wait(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.logger.Errorf("Failed to read events: %v", err)
+	select {
+	case <-ctx.Done():
+	case <-time.After(redisBlock):
+	}
+}
+
+// streamKey returns the key of the stream of a topic
+func (r *Redis) streamKey(topic string) string {
+	return r.prefix + "events:" + topic
+}
+
+// streamEntry is an entry of a stream
+type streamEntry struct {
+	id   string
+	data []byte
+}
+
+// streamEntries returns the entries of an XREAD or XREADGROUP reply
+func streamEntries(reply interface{}) []streamEntry {
+	streams, _ := reply.([]interface{})
+	var entries []streamEntry
+	for _, stream := range streams {
+		pair, _ := stream.([]interface{})
+		if len(pair) != 2 {
+			continue
+		}
+		items, _ := pair[1].([]interface{})
+		entries = append(entries, parseEntries(items)...)
+	}
+	return entries
+}
+
+// parseEntries returns the entries of an array of [id, [field, value, ...]]
+func parseEntries(items []interface{}) []streamEntry {
+	var entries []streamEntry
+	for _, item := range items {
+		pair, _ := item.([]interface{})
+		if len(pair) != 2 {
+			continue
+		}
+		entry := streamEntry{id: replyString(pair[0])}
+		fields, _ := pair[1].([]interface{})
+		for i := 0; i+1 < len(fields); i += 2 {
+			if replyString(fields[i]) == "event" {
+				entry.data = []byte(replyString(fields[i+1]))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// replyString converts a bulk or simple string reply
+func replyString(reply interface{}) string {
+	switch v := reply.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
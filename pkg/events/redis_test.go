@@ -0,0 +1,259 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStreams runs the stream commands of the Redis backend on slices, one
+// at a time as a Redis server does. Reads without new entries return after
+// a short wait instead of blocking for the requested time.
+type fakeStreams struct {
+	mu      sync.Mutex
+	seq     int
+	streams map[string][]fakeEntry
+	groups  map[string]*fakeGroup // By stream key and group name
+}
+
+type fakeEntry struct {
+	id   string
+	data string
+}
+
+type fakeGroup struct {
+	next    int // Index of the next entry to deliver
+	pending map[string]*fakePending
+}
+
+type fakePending struct {
+	deliveries int64
+	since      time.Time
+}
+
+func newFakeStreams() *fakeStreams {
+	return &fakeStreams{streams: make(map[string][]fakeEntry), groups: make(map[string]*fakeGroup)}
+}
+
+func (f *fakeStreams) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	s := make([]string, len(args))
+	for i, arg := range args {
+		if b, ok := arg.([]byte); ok {
+			arg = string(b)
+		}
+		s[i] = fmt.Sprint(arg)
+	}
+
+	f.mu.Lock()
+	reply, err, wait := f.run(s)
+	f.mu.Unlock()
+	if wait {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	return reply, err
+}
+
+// run runs a command, reporting whether a read found nothing to return
+func (f *fakeStreams) run(s []string) (interface{}, error, bool) {
+	switch s[0] {
+	case "XADD":
+		// XADD key MAXLEN ~ n * event data
+		f.seq++
+		entry := fakeEntry{id: fmt.Sprintf("%d-%d", time.Now().UnixMilli(), f.seq), data: s[7]}
+		f.streams[s[1]] = append(f.streams[s[1]], entry)
+		return entry.id, nil, false
+
+	case "XGROUP":
+		// XGROUP CREATE key group $ MKSTREAM
+		key := s[2] + " " + s[3]
+		if _, ok := f.groups[key]; ok {
+			return nil, errors.New("BUSYGROUP Consumer Group name already exists"), false
+		}
+		f.groups[key] = &fakeGroup{next: len(f.streams[s[2]]), pending: make(map[string]*fakePending)}
+		return "OK", nil, false
+
+	case "XREADGROUP":
+		// XREADGROUP GROUP group consumer COUNT n BLOCK ms STREAMS key >
+		group := f.groups[s[9]+" "+s[2]]
+		entries := f.streams[s[9]][group.next:]
+		if len(entries) == 0 {
+			return nil, nil, true
+		}
+		group.next += len(entries)
+		for _, entry := range entries {
+			group.pending[entry.id] = &fakePending{deliveries: 1, since: time.Now()}
+		}
+		return []interface{}{[]interface{}{s[9], entryReplies(entries)}}, nil, false
+
+	case "XREAD":
+		// XREAD COUNT n BLOCK ms STREAMS key lastID
+		var entries []fakeEntry
+		for _, entry := range f.streams[s[6]] {
+			if streamIDLess(s[7], entry.id) {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			return nil, nil, true
+		}
+		return []interface{}{[]interface{}{s[6], entryReplies(entries)}}, nil, false
+
+	case "XACK":
+		delete(f.groups[s[1]+" "+s[2]].pending, s[3])
+		return int64(1), nil, false
+
+	case "XPENDING":
+		// XPENDING key group IDLE ms - + count
+		idle, _ := strconv.ParseInt(s[4], 10, 64)
+		var reply []interface{}
+		for _, entry := range f.streams[s[1]] {
+			p, ok := f.groups[s[1]+" "+s[2]].pending[entry.id]
+			if ok && time.Since(p.since).Milliseconds() >= idle {
+				reply = append(reply, []interface{}{[]byte(entry.id), []byte("consumer"), time.Since(p.since).Milliseconds(), p.deliveries})
+			}
+		}
+		return reply, nil, false
+
+	case "XCLAIM":
+		// XCLAIM key group consumer idle id
+		p, ok := f.groups[s[1]+" "+s[2]].pending[s[5]]
+		if !ok {
+			return []interface{}{}, nil, false
+		}
+		p.deliveries++
+		p.since = time.Now()
+		for _, entry := range f.streams[s[1]] {
+			if entry.id == s[5] {
+				return entryReplies([]fakeEntry{entry}), nil, false
+			}
+		}
+		return []interface{}{}, nil, false
+	}
+	return nil, errors.New("ERR unknown command " + s[0]), false
+}
+
+// pending returns the number of unacknowledged entries of a group
+func (f *fakeStreams) pending(key, group string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.groups[key+" "+group].pending)
+}
+
+// entryReplies formats entries as a stream reply
+func entryReplies(entries []fakeEntry) []interface{} {
+	reply := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		reply[i] = []interface{}{[]byte(entry.id), []interface{}{[]byte("event"), []byte(entry.data)}}
+	}
+	return reply
+}
+
+// streamIDLess reports whether a stream ID is before another
+func streamIDLess(a, b string) bool {
+	aMillis, aSeq, _ := strings.Cut(a, "-")
+	bMillis, bSeq, _ := strings.Cut(b, "-")
+	am, _ := strconv.ParseInt(aMillis, 10, 64)
+	bm, _ := strconv.ParseInt(bMillis, 10, 64)
+	as, _ := strconv.ParseUint(aSeq, 10, 64)
+	bs, _ := strconv.ParseUint(bSeq, 10, 64)
+	return am < bm || am == bm && as < bs
+}
+
+// waitFor waits up to two seconds for a condition
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRedisGroups(t *testing.T) {
+	fake := newFakeStreams()
+	backend := NewRedis(fake, "app:")
+	defer backend.Close()
+	rec := newRecorder()
+	for _, name := range []string{"worker1", "worker2"} {
+		if _, err := backend.Subscribe("orders", "workers", rec.handler(name)); err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+	}
+	if _, err := backend.Subscribe("orders", "", rec.handler("all")); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if err := backend.Publish(ctx, &Event{ID: fmt.Sprint(i), Topic: "orders"}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	waitFor(t, "the events", func() bool {
+		return len(rec.get("worker1"))+len(rec.get("worker2")) == 4 && len(rec.get("all")) == 4
+	})
+	if got := fmt.Sprint(rec.get("all")); got != "[0 1 2 3]" {
+		t.Errorf("Expected the subscriber without a group to get every event in order, got %s", got)
+	}
+	waitFor(t, "the acknowledgements", func() bool { return fake.pending("app:events:orders", "workers") == 0 })
+}
+
+func TestRedisRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		deliveries int
+	}{
+		{"handled first", 0, 1},
+		{"handled on retry", 2, 3},
+		{"dropped", 10, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeStreams()
+			backend := NewRedis(fake, "app:").SetRetry(0, 3)
+			defer backend.Close()
+
+			var mu sync.Mutex
+			deliveries := 0
+			_, err := backend.Subscribe("orders", "workers", func(context.Context, *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				deliveries++
+				if deliveries <= tt.failures {
+					return errors.New("unavailable")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Subscribe failed: %v", err)
+			}
+			if err := backend.Publish(context.Background(), &Event{ID: "1", Topic: "orders"}); err != nil {
+				t.Fatalf("Publish failed: %v", err)
+			}
+
+			// The event leaves the pending list once handled or dropped
+			waitFor(t, "the event to be settled", func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return deliveries > 0 && fake.pending("app:events:orders", "workers") == 0
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if deliveries != tt.deliveries {
+				t.Errorf("Expected %d deliveries, got %d", tt.deliveries, deliveries)
+			}
+		})
+	}
+}
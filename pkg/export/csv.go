@@ -0,0 +1,56 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// csvWriter writes rows as comma-separated values
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter creates a writer of comma-separated values. Text starting with
+// =, +, -, or @ is prefixed with a quote, so spreadsheets do not run it as a
+// formula.
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) WriteHeader(titles []string) error {
+	return w.w.Write(titles)
+}
+
+func (w *csvWriter) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case nil:
+		case string:
+			record[i] = escapeFormula(v)
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case uint64:
+			record[i] = strconv.FormatUint(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			record[i] = strconv.FormatBool(v)
+		}
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// escapeFormula prefixes text that spreadsheets would run as a formula
+func escapeFormula(s string) string {
+	if s != "" && (s[0] == '=' || s[0] == '+' || s[0] == '-' || s[0] == '@') {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,287 @@
+// Package export writes rows to CSV and Excel files
+package export
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Format is an export file format
+type Format string
+
+// Export formats
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// DefaultTimeLayout is the layout of time values without a Format function
+const DefaultTimeLayout = "2006-01-02 15:04:05"
+
+// ParseFormat parses a format name such as csv or xlsx, ignoring case
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case CSV, XLSX:
+		return f, true
+	default:
+		return "", false
+	}
+}
+
+// Column is a column of an export
+type Column struct {
+	// Header is the column title, defaulting to Field
+	Header string
+
+	// Field selects the value of a row: a struct field by its Go or JSON name,
+	// or a map key. Dots select nested fields, e.g. Company.Name.
+	Field string
+
+	// Value computes the value from the row instead of Field
+	Value func(row interface{}) interface{}
+
+	// Format formats the value as text. Without it, numbers and booleans are
+	// written as such, times with DefaultTimeLayout, and other values as text.
+	Format func(value interface{}) string
+}
+
+// Writer writes the rows of an export in a file format
+type Writer interface {
+	// WriteHeader writes the column titles
+	WriteHeader(titles []string) error
+
+	// WriteRow writes the cells of a row, which are nil, string, int64,
+	// uint64, float64, or bool values
+	WriteRow(cells []interface{}) error
+
+	// Close finishes the file
+	Close() error
+}
+
+// NewWriter creates a writer for a format
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case CSV:
+		return NewCSVWriter(w), nil
+	case XLSX:
+		return NewXLSXWriter(w, "Sheet1")
+	default:
+		return nil, fmt.Errorf("export: unsupported format %q", format)
+	}
+}
+
+// Exporter writes rows as columns:
+//
+//	e, err := export.New(w, export.XLSX, []export.Column{
+//		{Header: "ID", Field: "ID"},
+//		{Header: "Name", Field: "name"},
+//		{Header: "Company", Field: "Company.Name"},
+//		{Header: "Joined", Field: "CreatedAt", Format: func(v interface{}) string {
+//			return v.(time.Time).Format("02 Jan 2006")
+//		}},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	if err := e.Write(users); err != nil {
+//		return err
+//	}
+//	return e.Close()
+type Exporter struct {
+	w             Writer
+	columns       []Column
+	headerWritten bool
+}
+
+// New creates an exporter writing the format to w
+func New(w io.Writer, format Format, columns []Column) (*Exporter, error) {
+	writer, err := NewWriter(w, format)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithWriter(writer, columns), nil
+}
+
+// NewWithWriter creates an exporter with a writer for another format
+func NewWithWriter(w Writer, columns []Column) *Exporter {
+	return &Exporter{w: w, columns: columns}
+}
+
+// Write writes rows: a slice of structs, pointers to structs, or maps, or a
+// single struct or map
+func (e *Exporter) Write(rows interface{}) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(rows)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return e.writeRow(rows)
+	}
+	for i := 0; i < value.Len(); i++ {
+		if err := e.writeRow(value.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the header when no rows were written and finishes the file
+func (e *Exporter) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.w.Close()
+}
+
+// writeHeader writes the column titles once
+func (e *Exporter) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+
+	titles := make([]string, len(e.columns))
+	for i, col := range e.columns {
+		titles[i] = col.Header
+		if titles[i] == "" {
+			titles[i] = col.Field
+		}
+	}
+	return e.w.WriteHeader(titles)
+}
+
+// writeRow writes the cells of a row
+func (e *Exporter) writeRow(row interface{}) error {
+	cells := make([]interface{}, len(e.columns))
+	for i, col := range e.columns {
+		var value interface{}
+		if col.Value != nil {
+			value = col.Value(row)
+		} else {
+			value = fieldValue(reflect.ValueOf(row), col.Field)
+		}
+
+		if col.Format != nil {
+			if value == nil {
+				cells[i] = ""
+			} else {
+				cells[i] = col.Format(value)
+			}
+			continue
+		}
+		cells[i] = cellValue(value)
+	}
+	return e.w.WriteRow(cells)
+}
+
+// fieldValue returns the value at a dotted path of struct fields or map keys,
+// or nil when the path does not exist
+func fieldValue(value reflect.Value, path string) interface{} {
+	for _, name := range strings.Split(path, ".") {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil
+			}
+			value = value.Elem()
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			value = structField(value, name)
+		case reflect.Map:
+			if value.Type().Key().Kind() != reflect.String {
+				return nil
+			}
+			value = value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+		default:
+			return nil
+		}
+		if !value.IsValid() {
+			return nil
+		}
+	}
+	if !value.CanInterface() {
+		return nil
+	}
+	return value.Interface()
+}
+
+// structField returns the field of a struct with a Go name, including the
+// fields of embedded structs, or with a JSON name
+func structField(value reflect.Value, name string) reflect.Value {
+	if field := value.FieldByName(name); field.IsValid() {
+		return field
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName == name || strings.EqualFold(field.Name, name) {
+			return value.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// cellValue converts a value to a cell: nil, string, int64, uint64, float64,
+// or bool
+func cellValue(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+		value = rv.Interface()
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		// sql.NullString and similar types write their value
+		if v, err := valuer.Value(); err == nil {
+			return cellValue(v)
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return v.Format(DefaultTimeLayout)
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice, reflect.Map, reflect.Struct, reflect.Array:
+		// Write composite values as JSON
+		if b, err := json.Marshal(value); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,52 @@
+package export
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Send streams an export as a file download. The extension of filename is set
+// to the format, and write is called with the exporter once the headers are
+// sent:
+//
+//	app.Get("/users/export", func(c *fiber.Ctx) error {
+//		format, _ := export.ParseFormat(c.Query("format", "csv"))
+//		query, err := paginator.Query(pagination.GetParams(c), &[]User{})
+//		if err != nil {
+//			return response.Error(c, err)
+//		}
+//		return export.Send(c, format, "users", columns, func(e *export.Exporter) error {
+//			return export.Query[User](e, query, 0)
+//		})
+//	})
+//
+// write runs after the handler returns, so it must not use c. As the status is
+// already sent, errors while writing end the file early and are logged.
+func Send(c *fiber.Ctx, format Format, filename string, columns []Column, write func(e *Exporter) error) error {
+	if _, ok := ParseFormat(string(format)); !ok {
+		return response.Error(c, errors.BadRequestError("Unsupported export format: "+string(format)))
+	}
+	filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + "." + string(format)
+
+	c.Attachment(filename)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer w.Flush()
+
+		e, err := New(w, format, columns)
+		if err == nil {
+			if err = write(e); err == nil {
+				err = e.Close()
+			}
+		}
+		if err != nil {
+			logger.Default().Errorf("export %s: %v", filename, err)
+		}
+	})
+	return nil
+}
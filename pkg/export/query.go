@@ -0,0 +1,39 @@
+package export
+
+import (
+	"gorm.io/gorm"
+)
+
+// DefaultBatchSize is the number of rows Query loads at a time
+const DefaultBatchSize = 1000
+
+// Query writes every row of a query, loading batchSize rows of T at a time so
+// large tables are not held in memory. Order the query by a unique column, as
+// batches are loaded with LIMIT and OFFSET. To export every row matching the
+// filters and sort order of a paginated request, use Paginator.Query:
+//
+//	var users []User
+//	query, err := paginator.Query(pagination.GetParams(c), &users)
+//	if err != nil {
+//		return err
+//	}
+//	return export.Query[User](e, query, 0)
+func Query[T any](e *Exporter, query *gorm.DB, batchSize int) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	query = query.Session(&gorm.Session{})
+
+	for offset := 0; ; offset += batchSize {
+		var batch []T
+		if err := query.Limit(batchSize).Offset(offset).Find(&batch).Error; err != nil {
+			return err
+		}
+		if err := e.Write(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+)
+
+// assetHashLength is the number of hex digits of the content hash in asset
+// names
+const assetHashLength = 8
+
+// AssetsConfig configures the assets served by an Assets
+type AssetsConfig struct {
+	// Provider stores the assets
+	Provider *Provider
+
+	// Dir is the directory of the assets in the storage, e.g. "dist"
+	Dir string
+
+	// URLPrefix is the route the assets are served under, e.g. "/assets"
+	URLPrefix string
+}
+
+// Assets serves the files of a storage directory, such as a frontend bundle,
+// under names containing a hash of their contents: "css/app.css" is served
+// as "css/app.3f2a9c1b.css" with an immutable cache header, so browsers keep
+// it until it changes, which changes its name. The manifest maps the logical
+// names to the hashed names for templates and clients.
+type Assets struct {
+	config AssetsConfig
+
+	mu       sync.RWMutex
+	manifest map[string]string // logical name to hashed name
+	logical  map[string]string // hashed name to logical name
+	hashes   map[string]string // logical name to content hash
+}
+
+// NewAssets creates an Assets and hashes the files of its directory:
+//
+//	assets, err := filesystem.NewAssets(ctx, filesystem.AssetsConfig{
+//		Provider:  fs.Provider,
+//		Dir:       "dist",
+//		URLPrefix: "/assets",
+//	})
+//	app.Get("/assets/*", assets.Handler())
+//	app.Get("/assets-manifest.json", assets.ManifestHandler())
+func NewAssets(ctx context.Context, config AssetsConfig) (*Assets, error) {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+	config.URLPrefix = strings.TrimRight(config.URLPrefix, "/")
+
+	a := &Assets{config: config}
+	if err := a.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Refresh hashes the files of the assets directory again, e.g. after a new
+// bundle was uploaded. Requests are served from the previous manifest until
+// it finishes.
+func (a *Assets) Refresh(ctx context.Context) error {
+	manifest := make(map[string]string)
+	logical := make(map[string]string)
+	hashes := make(map[string]string)
+
+	dir := cleanPath(a.config.Dir)
+	err := a.config.Provider.Walk(ctx, dir, func(filePath string, _ FileInfo) error {
+		name := strings.TrimPrefix(strings.TrimPrefix(filePath, dir), "/")
+		hash, err := a.hash(ctx, name)
+		if err != nil {
+			return err
+		}
+		hashed := hashedAssetName(name, hash)
+		manifest[name] = hashed
+		logical[hashed] = name
+		hashes[name] = hash
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.manifest, a.logical, a.hashes = manifest, logical, hashes
+	a.mu.Unlock()
+	return nil
+}
+
+// Manifest returns a copy of the map of logical names to hashed names, e.g.
+// {"css/app.css": "css/app.3f2a9c1b.css"}
+func (a *Assets) Manifest() map[string]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	manifest := make(map[string]string, len(a.manifest))
+	for name, hashed := range a.manifest {
+		manifest[name] = hashed
+	}
+	return manifest
+}
+
+// URL returns the URL of the hashed name of an asset, e.g. for templates.
+// Unknown assets keep their logical name.
+func (a *Assets) URL(name string) string {
+	name = cleanPath(name)
+
+	a.mu.RLock()
+	hashed, ok := a.manifest[name]
+	a.mu.RUnlock()
+	if !ok {
+		hashed = name
+	}
+	return a.config.URLPrefix + "/" + hashed
+}
+
+// Handler returns a Fiber handler serving the assets, mounted with a
+// wildcard such as "/assets/*". Hashed names are cached for a year as
+// immutable. Logical names are served too, revalidated on every use.
+func (a *Assets) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := cleanPath(c.Params("*"))
+
+		a.mu.RLock()
+		logical, hashed := a.logical[name]
+		if !hashed {
+			logical = name
+		}
+		hash, ok := a.hashes[logical]
+		a.mu.RUnlock()
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fserrors.FormatErrorResponse(
+				fserrors.FileNotFoundError(name),
+			))
+		}
+
+		etag := `"` + hash + `"`
+		c.Set("ETag", etag)
+		if hashed {
+			c.Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			c.Set("Cache-Control", "no-cache")
+		}
+		if c.Get("If-None-Match") == etag {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
+		file, fileInfo, err := a.config.Provider.Get(c.Context(), path.Join(a.config.Dir, logical))
+		if err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to get asset",
+				),
+			))
+		}
+
+		contentType := fileInfo.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.Set("Content-Type", contentType)
+
+		return c.SendStream(file)
+	}
+}
+
+// ManifestHandler returns a Fiber handler responding with the manifest, so
+// clients can look up the hashed names
+func (a *Assets) ManifestHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Cache-Control", "no-cache")
+		return c.JSON(a.Manifest())
+	}
+}
+
+// hash returns the content hash of an asset
+func (a *Assets) hash(ctx context.Context, name string) (string, error) {
+	file, _, err := a.config.Provider.Get(ctx, path.Join(a.config.Dir, name))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to hash asset: "+name,
+		)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:assetHashLength], nil
+}
+
+// hashedAssetName inserts a hash before the extension of a name, so
+// "css/app.css" becomes "css/app.3f2a9c1b.css"
+func hashedAssetName(name, hash string) string {
+	ext := path.Ext(name)
+	if ext == path.Base(name) {
+		// Dotfiles such as ".well-known" have no extension
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
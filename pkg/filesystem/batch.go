@@ -0,0 +1,509 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/jobs"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+	"github.com/anaknegeri/gokit/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Batch operations
+const (
+	BatchMove   = "move"
+	BatchCopy   = "copy"
+	BatchDelete = "delete"
+)
+
+// Batch statuses
+const (
+	BatchPending = "pending"
+	BatchRunning = "running"
+	BatchDone    = "done"
+)
+
+const (
+	// DefaultBatchConcurrency is how many operations of a batch run at once
+	// by default
+	DefaultBatchConcurrency = 8
+
+	// DefaultBatchMaxOperations is how many operations a batch may have by
+	// default
+	DefaultBatchMaxOperations = 1000
+
+	// DefaultBatchAsyncThreshold is how many operations a batch may have
+	// before it runs in the background by default
+	DefaultBatchAsyncThreshold = 50
+
+	// DefaultBatchJobType is the job type of the batches run in the
+	// background by default
+	DefaultBatchJobType = "filesystem.batch"
+
+	// DefaultBatchStatusTTL is how long the status of a batch run in the
+	// background is kept by default
+	DefaultBatchStatusTTL = 24 * time.Hour
+)
+
+// batchProgressInterval is how often the status of a running batch is saved
+const batchProgressInterval = time.Second
+
+// batchValidator checks the validate tags of a BatchRequest
+var batchValidator = validator.NewValidator()
+
+// BatchOperation is an operation of a batch: a move or a copy of the file
+// at Src to Dst, or a delete of the file at Src
+type BatchOperation struct {
+	Op  string `json:"op" validate:"required,oneof=move copy delete"`
+	Src string `json:"src" validate:"required"`
+	Dst string `json:"dst,omitempty" validate:"required_unless=Op delete"`
+}
+
+// BatchRequest is the body of BatchHandler
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" validate:"required,min=1,dive"`
+}
+
+// BatchResult is the outcome of an operation of a batch, with the error
+// code and message of a failed one
+type BatchResult struct {
+	BatchOperation
+	Success bool   `json:"success"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchStatus is the status of a batch, with the results of its operations
+// in the order of the request once it is done. Batches run in the
+// background have an ID to read their status with.
+type BatchStatus struct {
+	ID        string        `json:"id,omitempty"`
+	Status    string        `json:"status"`
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// BatchConfig configures a Batcher
+type BatchConfig struct {
+	// Concurrency is how many operations of a batch run at once,
+	// defaulting to DefaultBatchConcurrency
+	Concurrency int
+
+	// MaxOperations is how many operations a batch may have, defaulting to
+	// DefaultBatchMaxOperations
+	MaxOperations int
+
+	// Queue runs the batches of more than AsyncThreshold operations in the
+	// background. The batcher registers its job handler on it. Without a
+	// queue, batches run before the request responds.
+	Queue *jobs.Queue
+
+	// AsyncThreshold defaults to DefaultBatchAsyncThreshold
+	AsyncThreshold int
+
+	// JobType defaults to DefaultBatchJobType
+	JobType string
+
+	// Statuses keeps the status of the batches run in the background, for
+	// StatusTTL, defaulting to DefaultBatchStatusTTL. Use a cache shared by
+	// the instances, such as cache.Redis, when workers run elsewhere.
+	Statuses  cache.Cache
+	StatusTTL time.Duration
+}
+
+// Batcher runs batches of moves, copies, and deletes on the files of a
+// provider. The operations of a batch run concurrently, in no particular
+// order, so operations on the files of another operation belong in a later
+// batch. A failed operation does not stop the others.
+type Batcher struct {
+	provider *Provider
+	config   BatchConfig
+}
+
+type batchJob struct {
+	ID         string           `json:"id"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// NewBatcher creates a batcher for the files of a provider:
+//
+//	batcher := filesystem.NewBatcher(fs.Provider, filesystem.BatchConfig{
+//		Queue:    queue,
+//		Statuses: cache.NewRedis(redisClient, "batches:"),
+//	})
+//	fs.HandlerConfig.Batch = batcher
+//
+// A batcher with a queue requires a status cache.
+func NewBatcher(provider *Provider, config BatchConfig) *Batcher {
+	if provider == nil {
+		panic("filesystem provider is required")
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultBatchConcurrency
+	}
+	if config.MaxOperations <= 0 {
+		config.MaxOperations = DefaultBatchMaxOperations
+	}
+	if config.AsyncThreshold <= 0 {
+		config.AsyncThreshold = DefaultBatchAsyncThreshold
+	}
+	if config.JobType == "" {
+		config.JobType = DefaultBatchJobType
+	}
+	if config.StatusTTL <= 0 {
+		config.StatusTTL = DefaultBatchStatusTTL
+	}
+
+	b := &Batcher{provider: provider, config: config}
+	if config.Queue != nil {
+		if config.Statuses == nil {
+			panic("filesystem batches with a queue require a status cache")
+		}
+		config.Queue.Register(config.JobType, jobs.Handle(b.runJob))
+	}
+	return b
+}
+
+// Run runs the operations of a batch and returns their results
+func (b *Batcher) Run(ctx context.Context, ops []BatchOperation) *BatchStatus {
+	now := time.Now()
+	status := &BatchStatus{Status: BatchDone, Total: len(ops), CreatedAt: now}
+	status.Results = b.run(ctx, ops, nil)
+	status.count()
+	status.UpdatedAt = time.Now()
+	return status
+}
+
+// Enqueue runs the operations of a batch in the background and returns its
+// pending status, whose ID reads the status later with Status
+func (b *Batcher) Enqueue(ctx context.Context, ops []BatchOperation) (*BatchStatus, error) {
+	if b.config.Queue == nil {
+		return nil, fserrors.NewError(http.StatusNotImplemented, "Batches cannot run in the background without a queue")
+	}
+
+	now := time.Now()
+	status := &BatchStatus{ID: newBatchID(), Status: BatchPending, Total: len(ops), CreatedAt: now, UpdatedAt: now}
+	if err := b.save(ctx, status); err != nil {
+		return nil, err
+	}
+	if _, err := b.config.Queue.Enqueue(ctx, b.config.JobType, batchJob{ID: status.ID, Operations: ops}); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Status returns the status of a batch run in the background, or a
+// NOT_FOUND error once it expired
+func (b *Batcher) Status(ctx context.Context, id string) (*BatchStatus, error) {
+	notFound := fserrors.NewCustomError(http.StatusNotFound, fserrors.ErrCodeNotFound, "Batch not found")
+	if b.config.Statuses == nil {
+		return nil, notFound
+	}
+
+	data, err := b.config.Statuses.Get(ctx, batchKey(id))
+	if fserrors.Is(err, cache.ErrMiss) {
+		return nil, notFound
+	}
+	if err != nil {
+		return nil, fserrors.WrapError(err, http.StatusInternalServerError, "Failed to read the batch status")
+	}
+	var status BatchStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fserrors.WrapError(err, http.StatusInternalServerError, "Failed to read the batch status")
+	}
+	return &status, nil
+}
+
+// async reports whether a batch of n operations runs in the background
+func (b *Batcher) async(n int) bool {
+	return b.config.Queue != nil && n > b.config.AsyncThreshold
+}
+
+// runJob runs a batch in the background, saving its progress as it goes.
+// The batch is not retried, which would repeat the moves and deletes made.
+func (b *Batcher) runJob(ctx context.Context, job batchJob) error {
+	status, err := b.Status(ctx, job.ID)
+	if err != nil {
+		now := time.Now()
+		status = &BatchStatus{ID: job.ID, Total: len(job.Operations), CreatedAt: now}
+	}
+	status.Status = BatchRunning
+	status.UpdatedAt = time.Now()
+	if err := b.save(ctx, status); err != nil {
+		return err
+	}
+
+	// Save the counts at most every batchProgressInterval
+	var mu sync.Mutex
+	saved := time.Now()
+	progress := func(result BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if result.Success {
+			status.Succeeded++
+		} else {
+			status.Failed++
+		}
+		if time.Since(saved) < batchProgressInterval {
+			return
+		}
+		saved = time.Now()
+		status.UpdatedAt = saved
+		if err := b.save(ctx, status); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to save the progress of batch %s: %v", job.ID, err)
+		}
+	}
+
+	results := b.run(ctx, job.Operations, progress)
+	status.Status = BatchDone
+	status.Results = results
+	status.count()
+	status.UpdatedAt = time.Now()
+	if err := b.save(context.WithoutCancel(ctx), status); err != nil {
+		return jobs.Permanent(err)
+	}
+	return nil
+}
+
+// run runs operations concurrently, calling progress with each result
+func (b *Batcher) run(ctx context.Context, ops []BatchOperation, progress func(BatchResult)) []BatchResult {
+	results := make([]BatchResult, len(ops))
+	sem := make(chan struct{}, b.config.Concurrency)
+	var wg sync.WaitGroup
+	for i, op := range ops {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, op BatchOperation) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = b.apply(ctx, op)
+			if progress != nil {
+				progress(results[i])
+			}
+		}(i, op)
+	}
+	wg.Wait()
+	return results
+}
+
+// apply runs an operation
+func (b *Batcher) apply(ctx context.Context, op BatchOperation) BatchResult {
+	var err error
+	switch op.Op {
+	case BatchMove:
+		_, err = b.provider.Move(ctx, op.Src, op.Dst)
+	case BatchCopy:
+		_, err = b.provider.Copy(ctx, op.Src, op.Dst)
+	case BatchDelete:
+		err = b.provider.Delete(ctx, op.Src)
+	default:
+		err = fserrors.NewError(http.StatusBadRequest, fmt.Sprintf("Unknown batch operation: %s", op.Op))
+	}
+
+	result := BatchResult{BatchOperation: op, Success: err == nil}
+	if err != nil {
+		var appErr *fserrors.AppError
+		if !fserrors.As(err, &appErr) || appErr.HTTPCode >= http.StatusInternalServerError {
+			logger.FromContext(ctx).Errorf("Failed to %s %s: %v", op.Op, op.Src, err)
+		}
+		response := fserrors.FormatErrorResponse(err)
+		result.Code = response.Error
+		result.Error = response.Message
+	}
+	return result
+}
+
+// save stores the status of a batch run in the background
+func (b *Batcher) save(ctx context.Context, status *BatchStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	if err := b.config.Statuses.Set(ctx, batchKey(status.ID), data, b.config.StatusTTL); err != nil {
+		return fserrors.WrapError(err, http.StatusInternalServerError, "Failed to save the batch status")
+	}
+	return nil
+}
+
+// count counts the succeeded and failed operations of the results
+func (s *BatchStatus) count() {
+	s.Succeeded, s.Failed = 0, 0
+	for _, result := range s.Results {
+		if result.Success {
+			s.Succeeded++
+		} else {
+			s.Failed++
+		}
+	}
+}
+
+// relative returns a copy of the status with paths relative to a base path
+func (s BatchStatus) relative(base string) BatchStatus {
+	results := make([]BatchResult, len(s.Results))
+	for i, result := range s.Results {
+		result.Src = relativeKey(base, result.Src)
+		result.Dst = relativeKey(base, result.Dst)
+		results[i] = result
+	}
+	s.Results = results
+	return s
+}
+
+// relativeKey returns a key relative to a base path, or the key when it is
+// outside of it
+func relativeKey(base, key string) string {
+	if relative, ok := pathutil.RelativeKey(base, key); ok {
+		return relative
+	}
+	return key
+}
+
+// batchKey is the cache key of the status of a batch
+func batchKey(id string) string {
+	return "fs:batch:" + id
+}
+
+// newBatchID returns a random batch ID
+func newBatchID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// BatchHandler returns a Fiber handler running the move, copy, and delete
+// operations of a BatchRequest on paths under the base path:
+//
+//	POST /files/batch
+//	{"operations": [{"op": "move", "src": "a.pdf", "dst": "archive/a.pdf"}, {"op": "delete", "src": "b.pdf"}]}
+//
+// Batches run before the request responds with their BatchStatus, except
+// those of more operations than the async threshold of a batcher with a
+// queue, which respond 202 Accepted with a pending status whose ID is read
+// with BatchStatusHandler. Needs UploadHandlerConfig.Batch.
+func BatchHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+	if config.Batch == nil {
+		panic("filesystem batcher is required")
+	}
+	batcher := config.Batch
+
+	return func(c *fiber.Ctx) error {
+		var req BatchRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"Invalid batch request body",
+				),
+			))
+		}
+		if err := batchValidator.Struct(&req); err != nil {
+			appErr := fserrors.ValidatorError(err)
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+		if len(req.Operations) > batcher.config.MaxOperations {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					fmt.Sprintf("A batch has at most %d operations", batcher.config.MaxOperations),
+				),
+			))
+		}
+
+		// Resolve the paths of the operations under the base path, refusing
+		// those such as "/" that name no file
+		ops := make([]BatchOperation, len(req.Operations))
+		for i, op := range req.Operations {
+			src, dst := NormalizeKey(op.Src), NormalizeKey(op.Dst)
+			if src == "" || (op.Op != BatchDelete && dst == "") {
+				return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+					fserrors.NewError(http.StatusBadRequest, fmt.Sprintf("Operation %d: path names no file", i)),
+				))
+			}
+
+			ops[i] = BatchOperation{Op: op.Op, Src: JoinKey(config.BasePath, src)}
+			if op.Op != BatchDelete {
+				ops[i].Dst = JoinKey(config.BasePath, dst)
+			}
+		}
+
+		if batcher.async(len(ops)) {
+			status, err := batcher.Enqueue(c.Context(), ops)
+			if err != nil {
+				return batchError(c, err)
+			}
+			c.Location(c.Path() + "/" + status.ID)
+			return c.Status(fiber.StatusAccepted).JSON(Response{
+				Success: true,
+				Message: "Batch accepted",
+				Data:    status,
+			})
+		}
+
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		status := batcher.Run(ctx, ops).relative(config.BasePath)
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Message: "Batch completed",
+			Data:    status,
+		})
+	}
+}
+
+// BatchStatusHandler returns a Fiber handler responding with the
+// BatchStatus of the batch with the id URL parameter, run in the background
+// by BatchHandler. Needs UploadHandlerConfig.Batch.
+func BatchStatusHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Batch == nil {
+		panic("filesystem batcher is required")
+	}
+
+	return func(c *fiber.Ctx) error {
+		status, err := config.Batch.Status(c.Context(), c.Params("id"))
+		if err != nil {
+			return batchError(c, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Data:    status.relative(config.BasePath),
+		})
+	}
+}
+
+// batchError sends the error response for a batch that could not be run or
+// read
+func batchError(c *fiber.Ctx, err error) error {
+	var appErr *fserrors.AppError
+	if fserrors.As(err, &appErr) {
+		return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+		fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to run batch",
+		),
+	))
+}
@@ -0,0 +1,191 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+	"github.com/anaknegeri/gokit/pkg/jobs"
+	"github.com/gofiber/fiber/v2"
+)
+
+// batchApp returns an app serving the batch routes of a local storage
+// holding files a.txt to e.txt under files/
+func batchApp(t *testing.T, config BatchConfig) (*fiber.App, string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "files"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, "files", name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir, CreateDirectories: true})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	provider := NewProvider(storage)
+	handlerConfig := UploadHandlerConfig{
+		Provider:    provider,
+		BasePath:    "files",
+		TimeoutSecs: 5,
+		Batch:       NewBatcher(provider, config),
+	}
+	app := fiber.New()
+	app.Post("/batch", BatchHandler(handlerConfig))
+	app.Get("/batch/:id", BatchStatusHandler(handlerConfig))
+	return app, filepath.Join(tempDir, "files")
+}
+
+// batchStatus sends a request to the batch routes and decodes the status
+func batchStatus(t *testing.T, app *fiber.App, req *http.Request) (int, BatchStatus) {
+	t.Helper()
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Data BatchStatus `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, body.Data
+}
+
+// batchRequest returns the request of a batch
+func batchRequest(t *testing.T, ops ...BatchOperation) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(BatchRequest{Operations: ops})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestBatchHandler(t *testing.T) {
+	app, dir := batchApp(t, BatchConfig{})
+
+	status, batch := batchStatus(t, app, batchRequest(t,
+		BatchOperation{Op: BatchMove, Src: "a.txt", Dst: "archive/a.txt"},
+		BatchOperation{Op: BatchCopy, Src: "b.txt", Dst: "copies/b.txt"},
+		BatchOperation{Op: BatchDelete, Src: "c.txt"},
+		BatchOperation{Op: BatchCopy, Src: "d.txt", Dst: "e.txt"},
+		BatchOperation{Op: BatchDelete, Src: "missing.txt"},
+	))
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if batch.Status != BatchDone || batch.Total != 5 || batch.Succeeded != 3 || batch.Failed != 2 {
+		t.Errorf("Unexpected batch status: %+v", batch)
+	}
+	if len(batch.Results) != 5 || batch.Results[0].Dst != "archive/a.txt" {
+		t.Fatalf("Expected results in request order with relative paths: %+v", batch.Results)
+	}
+	if code := batch.Results[3].Code; code != "FILE_ALREADY_EXISTS" {
+		t.Errorf("Expected FILE_ALREADY_EXISTS for a copy over a file, got %q", code)
+	}
+	if code := batch.Results[4].Code; code != "FILE_NOT_FOUND" {
+		t.Errorf("Expected FILE_NOT_FOUND for a missing file, got %q", code)
+	}
+
+	for name, want := range map[string]bool{
+		"a.txt": false, "archive/a.txt": true,
+		"b.txt": true, "copies/b.txt": true,
+		"c.txt": false,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("Expected %s to exist: %v, got %v", name, want, got)
+		}
+	}
+
+	// Invalid operations are refused before any runs
+	status, _ = batchStatus(t, app, batchRequest(t,
+		BatchOperation{Op: BatchDelete, Src: "d.txt"},
+		BatchOperation{Op: BatchMove, Src: "e.txt"},
+	))
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for a move without dst, got %d", status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "d.txt")); err != nil {
+		t.Errorf("Expected d.txt to be kept: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		ops    []BatchOperation
+		status int
+	}{
+		{"no operations", nil, http.StatusUnprocessableEntity},
+		{"unknown op", []BatchOperation{{Op: "rename", Src: "d.txt", Dst: "f.txt"}}, http.StatusUnprocessableEntity},
+		{"no src", []BatchOperation{{Op: BatchDelete}}, http.StatusUnprocessableEntity},
+		{"root src", []BatchOperation{{Op: BatchDelete, Src: "/"}}, http.StatusBadRequest},
+		{"root dst", []BatchOperation{{Op: BatchCopy, Src: "d.txt", Dst: "."}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := batchStatus(t, app, batchRequest(t, tt.ops...)); status != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, status)
+			}
+		})
+	}
+}
+
+func TestBatchHandlerAsync(t *testing.T) {
+	queue := jobs.New(jobs.NewMemory(), jobs.Config{PollInterval: 10 * time.Millisecond})
+	app, dir := batchApp(t, BatchConfig{Queue: queue, Statuses: cache.NewMemory(0), AsyncThreshold: 2})
+	queue.Start()
+	defer queue.Shutdown(context.Background())
+
+	var ops []BatchOperation
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		ops = append(ops, BatchOperation{Op: BatchCopy, Src: name, Dst: "copies/" + name})
+	}
+	status, batch := batchStatus(t, app, batchRequest(t, ops...))
+	if status != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", status)
+	}
+	if batch.ID == "" || batch.Status != BatchPending {
+		t.Fatalf("Expected a pending batch with an ID: %+v", batch)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for batch.Status != BatchDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("Batch did not finish: %+v", batch)
+		}
+		time.Sleep(10 * time.Millisecond)
+		status, batch = batchStatus(t, app, httptest.NewRequest(http.MethodGet, "/batch/"+batch.ID, nil))
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+	}
+	if batch.Succeeded != 3 || len(batch.Results) != 3 || batch.Results[2].Dst != "copies/c.txt" {
+		t.Errorf("Unexpected batch status: %+v", batch)
+	}
+	for _, op := range ops {
+		if _, err := os.Stat(filepath.Join(dir, op.Dst)); err != nil {
+			t.Errorf("Expected %s to be copied: %v", op.Src, err)
+		}
+	}
+
+	status, _ = batchStatus(t, app, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/batch/%s", "unknown"), nil))
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown batch, got %d", status)
+	}
+}
@@ -0,0 +1,51 @@
+package filesystem
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// copyBufferSize is the size of the buffers files are copied with
+const copyBufferSize = 64 << 10
+
+// copyBuffers pools the buffers of copyBuffer, so copies do not allocate
+var copyBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// copyBuffer copies src to dst with a pooled buffer. Copies from files are
+// left to io.Copy, which lets the kernel copy between files and sockets.
+func copyBuffer(dst io.Writer, src io.Reader) (int64, error) {
+	if _, ok := src.(*os.File); ok {
+		return io.Copy(dst, src)
+	}
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+
+	// Hide io.ReaderFrom, which io.CopyBuffer prefers to the buffer and
+	// which copies with a buffer of its own
+	return io.CopyBuffer(struct{ io.Writer }{dst}, src, *buf)
+}
+
+// sniffContent returns up to the first 512 bytes of a file, as many as
+// http.DetectContentType considers, read into a pooled buffer that must be
+// released with the returned function, and seeks back to its start
+func sniffContent(src io.ReadSeeker) ([]byte, func(), error) {
+	buf := copyBuffers.Get().(*[]byte)
+	release := func() { copyBuffers.Put(buf) }
+
+	n, err := io.ReadFull(src, (*buf)[:512])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		release()
+		return nil, nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return (*buf)[:n], release, nil
+}
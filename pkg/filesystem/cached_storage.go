@@ -0,0 +1,142 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+)
+
+// DefaultMetadataCacheTTL is how long CachedStorage keeps metadata by default
+const DefaultMetadataCacheTTL = 5 * time.Minute
+
+// CachedStorage caches the metadata a storage returns from GetInfo, Exists,
+// and List, which saves a round trip to S3 for every request that only needs
+// a file's size or URL. Uploads and deletes through it remove the cached
+// metadata of the file and its directory. File contents are not cached.
+type CachedStorage struct {
+	storage Storage
+	cache   cache.Cache
+	ttl     time.Duration
+}
+
+// NewCachedStorage wraps a storage with a metadata cache. Changes made
+// without the wrapper, e.g. by another service, show after ttl, which
+// defaults to DefaultMetadataCacheTTL.
+func NewCachedStorage(storage Storage, c cache.Cache, ttl time.Duration) *CachedStorage {
+	if ttl <= 0 {
+		ttl = DefaultMetadataCacheTTL
+	}
+	return &CachedStorage{storage: storage, cache: c, ttl: ttl}
+}
+
+// Upload saves a file and removes the cached metadata of its path
+func (s *CachedStorage) Upload(ctx context.Context, file *multipart.FileHeader, filePath string) (*FileInfo, error) {
+	info, err := s.storage.Upload(ctx, file, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return info, s.invalidate(ctx, filePath)
+}
+
+// Get retrieves a file from the storage
+func (s *CachedStorage) Get(ctx context.Context, filePath string) (io.ReadCloser, *FileInfo, error) {
+	return s.storage.Get(ctx, filePath)
+}
+
+// Delete removes a file and the cached metadata of its path
+func (s *CachedStorage) Delete(ctx context.Context, filePath string) error {
+	if err := s.storage.Delete(ctx, filePath); err != nil {
+		return err
+	}
+	return s.invalidate(ctx, filePath)
+}
+
+// Copy copies a file and removes the cached metadata of the copy's path
+func (s *CachedStorage) Copy(ctx context.Context, src, dst string) (*FileInfo, error) {
+	info, err := copyFile(ctx, s.storage, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	return info, s.invalidate(ctx, dst)
+}
+
+// Exists checks if a file exists, using the cached answer when there is one
+func (s *CachedStorage) Exists(ctx context.Context, filePath string) (bool, error) {
+	key := cleanPath(filePath)
+	value, err := s.cache.GetOrSet(ctx, "fs:exists:"+key, s.ttl, func(ctx context.Context) ([]byte, error) {
+		exists, err := s.storage.Exists(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	}, pathTag(key), dirTag(path.Dir(key)))
+	if err != nil {
+		return false, err
+	}
+	return string(value) == "1", nil
+}
+
+// List returns the files of a directory, using the cached listing when there
+// is one
+func (s *CachedStorage) List(ctx context.Context, dirPath string) ([]FileInfo, error) {
+	key := cleanPath(dirPath)
+	return cache.Remember(ctx, s.cache, "fs:list:"+key, s.ttl, func(ctx context.Context) ([]FileInfo, error) {
+		return s.storage.List(ctx, dirPath)
+	}, dirTag(key))
+}
+
+// GetInfo returns information about a file, using the cached information
+// when there is some
+func (s *CachedStorage) GetInfo(ctx context.Context, filePath string) (*FileInfo, error) {
+	key := cleanPath(filePath)
+	data, err := s.cache.GetOrSet(ctx, "fs:info:"+key, s.ttl, func(ctx context.Context) ([]byte, error) {
+		info, err := s.storage.GetInfo(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(info)
+	}, pathTag(key), dirTag(path.Dir(key)))
+	if err != nil {
+		return nil, err
+	}
+
+	var info FileInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// invalidate removes the cached metadata of a file and the listing of its
+// directory
+func (s *CachedStorage) invalidate(ctx context.Context, filePath string) error {
+	key := cleanPath(filePath)
+	return s.cache.DeleteTags(ctx, pathTag(key), dirTag(path.Dir(key)))
+}
+
+// cleanPath normalizes a path so that a/b, /a/b, and a//b/ share cache keys
+func cleanPath(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// pathTag is the cache tag of the metadata of a file
+func pathTag(p string) string {
+	return "fs:path:" + p
+}
+
+// dirTag is the cache tag of the metadata in a directory
+func dirTag(dir string) string {
+	if dir == "." {
+		dir = ""
+	}
+	return "fs:dir:" + dir
+}
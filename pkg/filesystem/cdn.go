@@ -0,0 +1,189 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+)
+
+// CDN serves the files of a storage, e.g. CloudFront or GenericCDN. A
+// Provider with a CDN returns its URLs in FileInfo and invalidates the
+// cached copies of the files it deletes or replaces.
+type CDN interface {
+	// URLFor returns the CDN URL of a file path of the storage
+	URLFor(filePath string) string
+
+	// Invalidate removes the cached copies of files from the CDN
+	Invalidate(ctx context.Context, paths []string) error
+}
+
+// cdnPath returns the path of a file on a CDN, with a prefix and a leading
+// slash
+func cdnPath(prefix, filePath string) string {
+	return path.Join("/", prefix, cleanPath(filePath))
+}
+
+// cdnURL joins a base URL and a CDN path, escaping the path
+func cdnURL(baseURL, cdnPath string) string {
+	return strings.TrimRight(baseURL, "/") + (&url.URL{Path: cdnPath}).EscapedPath()
+}
+
+// CloudFrontConfig configures a CloudFront distribution
+type CloudFrontConfig struct {
+	// DistributionID is the ID of the distribution, e.g. "E2QWRUHAPOMQZL"
+	DistributionID string
+
+	// BaseURL is the URL of the distribution, e.g. "https://d111111abcdef8.cloudfront.net"
+	// or an alternate domain name
+	BaseURL string
+
+	// Prefix is prepended to file paths, e.g. the S3 prefix of the storage
+	// when the origin is the bucket root
+	Prefix string
+
+	// AWSConfig is used when its region is set; otherwise the default
+	// configuration of the environment is loaded
+	AWSConfig aws.Config
+}
+
+// CloudFront serves files through an Amazon CloudFront distribution
+type CloudFront struct {
+	client *cloudfront.Client
+	config CloudFrontConfig
+}
+
+// NewCloudFront creates a CDN for a CloudFront distribution
+func NewCloudFront(ctx context.Context, cfg CloudFrontConfig) (*CloudFront, error) {
+	awsCfg := cfg.AWSConfig
+	if awsCfg.Region == "" {
+		loaded, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("filesystem: load AWS config: %w", err)
+		}
+		awsCfg = loaded
+	}
+	return &CloudFront{client: cloudfront.NewFromConfig(awsCfg), config: cfg}, nil
+}
+
+// URLFor returns the URL of a file on the distribution
+func (c *CloudFront) URLFor(filePath string) string {
+	return cdnURL(c.config.BaseURL, cdnPath(c.config.Prefix, filePath))
+}
+
+// Invalidate creates an invalidation of the files on the distribution.
+// CloudFront finishes it within minutes, after Invalidate returns.
+func (c *CloudFront) Invalidate(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	items := make([]string, len(paths))
+	for i, p := range paths {
+		items[i] = (&url.URL{Path: cdnPath(c.config.Prefix, p)}).EscapedPath()
+	}
+
+	_, err := c.client.CreateInvalidation(ctx, &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(c.config.DistributionID),
+		InvalidationBatch: &types.InvalidationBatch{
+			CallerReference: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
+			Paths: &types.Paths{
+				Quantity: aws.Int32(int32(len(items))),
+				Items:    items,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("filesystem: invalidate CloudFront paths: %w", err)
+	}
+	return nil
+}
+
+// GenericCDNConfig configures a CDN that pulls files from the storage, such
+// as Cloudflare, Fastly, or BunnyCDN
+type GenericCDNConfig struct {
+	// BaseURL is the URL files are served from, e.g. "https://cdn.example.com"
+	BaseURL string
+
+	// Prefix is prepended to file paths
+	Prefix string
+
+	// PurgeURL receives a POST with the URLs to invalidate as JSON,
+	// {"urls": ["https://cdn.example.com/a.png"]}, e.g. an endpoint of the
+	// CDN's API or a relay for it. Nothing is invalidated without it.
+	PurgeURL string
+
+	// Headers are set on purge requests, e.g. Authorization
+	Headers map[string]string
+
+	// HTTPClient defaults to a client with a 30 second timeout
+	HTTPClient *http.Client
+}
+
+// GenericCDN serves files from a base URL and purges them through a webhook
+type GenericCDN struct {
+	config GenericCDNConfig
+}
+
+// NewGenericCDN creates a CDN serving files from a base URL
+func NewGenericCDN(config GenericCDNConfig) *GenericCDN {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &GenericCDN{config: config}
+}
+
+// URLFor returns the URL of a file on the CDN
+func (g *GenericCDN) URLFor(filePath string) string {
+	return cdnURL(g.config.BaseURL, cdnPath(g.config.Prefix, filePath))
+}
+
+// Invalidate posts the URLs of the files to the purge URL
+func (g *GenericCDN) Invalidate(ctx context.Context, paths []string) error {
+	if g.config.PurgeURL == "" || len(paths) == 0 {
+		return nil
+	}
+
+	urls := make([]string, len(paths))
+	for i, p := range paths {
+		urls[i] = g.URLFor(p)
+	}
+	data, err := json.Marshal(map[string][]string{"urls": urls})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.config.PurgeURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range g.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := g.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("filesystem: purge CDN: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("filesystem: purge CDN responded %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -1,9 +1,16 @@
 package filesystem
 
 import (
+	"context"
+	"errors"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/secrets"
+	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 // Config holds all configuration options for the filesystem
@@ -11,10 +18,15 @@ type Config struct {
 	// Storage type: "local" or "s3"
 	StorageType string
 
+	// TenantScoped keeps the files of each tenant apart, see TenantStorage
+	TenantScoped bool
+
 	// Local storage config
 	LocalStoragePath string
 	LocalBaseURL     string
 	CreateLocalDirs  bool
+	LocalMinFreeMB   int    // Uploads fail with 507 when less disk space would be left
+	LocalSymlinks    string // "follow", "reject", or "skip", see SymlinkPolicy
 
 	// S3 config
 	S3Endpoint   string
@@ -27,11 +39,55 @@ type Config struct {
 	S3UseSSL     bool
 	S3PathStyle  bool
 
+	// S3MaxListEntries caps the entries of a listing, see
+	// S3Config.MaxListEntries
+	S3MaxListEntries int
+
+	// S3StartupTimeout bounds connecting to S3 when the storage is created,
+	// see S3Config.StartupTimeout
+	S3StartupTimeout time.Duration
+
+	// S3LazyConnect defers checking the bucket to the first operation, see
+	// S3Config.LazyConnect
+	S3LazyConnect bool
+
+	// S3SkipBucketCheck never checks the bucket, for roles without the
+	// s3:ListBucket permission, see S3Config.SkipBucketCheck
+	S3SkipBucketCheck bool
+
+	// S3Credentials supplies the keys instead of S3AccessKey and S3SecretKey
+	// when set, e.g. secrets.AWSCredentials to pick up rotated keys
+	S3Credentials aws.CredentialsProvider
+
+	// CDN config: files are served from CDNBaseURL, through CloudFront when
+	// CloudFrontDistributionID is set, or else purged through CDNPurgeURL.
+	// CDN replaces them when set.
+	CDNBaseURL               string
+	CDNPurgeURL              string
+	CloudFrontDistributionID string
+	CDN                      CDN
+
+	// Metadata keeps the metadata of files, such as their processing
+	// status, e.g. NewGormMetadataStore(db)
+	Metadata MetadataStore
+
+	// Indexer indexes files for search, e.g. NewBleveIndex(dir)
+	Indexer Indexer
+
 	// Upload config
 	UploadMaxSizeMB  int
+	UploadMaxSizesMB map[string]int // Limits in MB by extension or content type, e.g. {"image/*": 2, ".mp4": 50}
 	AllowedFileTypes []string
-	UseUUID          bool
+	UseUUID          bool   // Name uploads with a UUID when Naming is empty
+	Naming           string // Naming strategy, see ParseNamingStrategy, e.g. "ulid" or "{date}/{uuid}{ext}"
 	TimeoutSecs      int
+	UploadRules      string // Rules in the upload tag format, e.g. "mime=image/*,maxwidth=4096"
+	UploadStripGPS   bool   // Remove the location from the EXIF data of uploaded JPEGs
+
+	// Dispositions are the "inline" or "attachment" rules of served files
+	// by extension or content type, e.g. {".svg": "inline"}, see
+	// UploadHandlerConfig.Dispositions
+	Dispositions map[string]string
 }
 
 // DefaultConfig returns the default configuration
@@ -47,7 +103,9 @@ func DefaultConfig() Config {
 	}
 }
 
-// NewConfigFromEnv loads configuration from environment variables
+// NewConfigFromEnv loads configuration from environment variables. The S3
+// keys are read from the default secrets as S3_ACCESS_KEY and S3_SECRET_KEY,
+// which are the environment variables unless secrets.SetDefault changed them.
 func NewConfigFromEnv() Config {
 	config := DefaultConfig()
 
@@ -56,6 +114,8 @@ func NewConfigFromEnv() Config {
 		config.StorageType = storageType
 	}
 
+	config.TenantScoped = (os.Getenv("STORAGE_TENANT_SCOPED") == "true")
+
 	// Local storage config
 	if path := os.Getenv("UPLOAD_STORAGE_PATH"); path != "" {
 		config.LocalStoragePath = path
@@ -69,22 +129,53 @@ func NewConfigFromEnv() Config {
 		config.CreateLocalDirs = (createDirs == "true" || createDirs == "1" || createDirs == "yes")
 	}
 
+	config.LocalMinFreeMB = getEnvAsInt("LOCAL_MIN_FREE_MB", 0)
+	config.LocalSymlinks = os.Getenv("LOCAL_SYMLINKS")
+
 	// S3 config
 	config.S3Endpoint = os.Getenv("S3_ENDPOINT")
-	config.S3AccessKey = os.Getenv("S3_ACCESS_KEY")
-	config.S3SecretKey = os.Getenv("S3_SECRET_KEY")
+	config.S3AccessKey = getSecret("S3_ACCESS_KEY")
+	config.S3SecretKey = getSecret("S3_SECRET_KEY")
 	config.S3Bucket = os.Getenv("S3_BUCKET")
 	config.S3BasePrefix = os.Getenv("S3_PREFIX")
 	config.S3BaseURL = os.Getenv("S3_BASE_URL")
 	config.S3Region = os.Getenv("S3_REGION")
 	config.S3UseSSL = (os.Getenv("S3_USE_SSL") == "true")
 	config.S3PathStyle = (os.Getenv("S3_PATH_STYLE") == "true")
+	config.S3MaxListEntries = getEnvAsInt("S3_MAX_LIST_ENTRIES", 0)
+	config.S3LazyConnect = (os.Getenv("S3_LAZY_CONNECT") == "true")
+	config.S3SkipBucketCheck = (os.Getenv("S3_SKIP_BUCKET_CHECK") == "true")
+	if timeout := os.Getenv("S3_STARTUP_TIMEOUT"); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err == nil && d > 0 {
+			config.S3StartupTimeout = d
+		} else {
+			logger.Default().Warnf("Invalid S3_STARTUP_TIMEOUT %q, using the default", timeout)
+		}
+	}
+
+	// CDN config
+	config.CDNBaseURL = os.Getenv("CDN_BASE_URL")
+	config.CDNPurgeURL = os.Getenv("CDN_PURGE_URL")
+	config.CloudFrontDistributionID = os.Getenv("CLOUDFRONT_DISTRIBUTION_ID")
 
 	// Upload config
 	if maxSize := getEnvAsInt("UPLOAD_MAX_SIZE", 10); maxSize > 0 {
 		config.UploadMaxSizeMB = maxSize
 	}
 
+	// Size limits by type, e.g. "image/*=2,.mp4=50"
+	if maxSizes := os.Getenv("UPLOAD_MAX_SIZES"); maxSizes != "" {
+		config.UploadMaxSizesMB = make(map[string]int)
+		for _, entry := range strings.Split(maxSizes, ",") {
+			key, value, _ := strings.Cut(entry, "=")
+			size, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				size = 0 // Reported by Validate
+			}
+			config.UploadMaxSizesMB[strings.ToLower(strings.TrimSpace(key))] = size
+		}
+	}
+
 	if timeout := getEnvAsInt("UPLOAD_TIMEOUT_SECS", 30); timeout > 0 {
 		config.TimeoutSecs = timeout
 	}
@@ -93,6 +184,22 @@ func NewConfigFromEnv() Config {
 		config.UseUUID = (useUUID == "true" || useUUID == "1" || useUUID == "yes")
 	}
 
+	config.UploadRules = os.Getenv("UPLOAD_RULES")
+	config.Naming = os.Getenv("UPLOAD_NAMING")
+
+	if stripGPS := os.Getenv("UPLOAD_STRIP_GPS"); stripGPS != "" {
+		config.UploadStripGPS = (stripGPS == "true" || stripGPS == "1" || stripGPS == "yes")
+	}
+
+	// Dispositions by type, e.g. ".svg=inline,text/plain=attachment"
+	if dispositions := os.Getenv("DOWNLOAD_DISPOSITIONS"); dispositions != "" {
+		config.Dispositions = make(map[string]string)
+		for _, entry := range strings.Split(dispositions, ",") {
+			key, value, _ := strings.Cut(entry, "=")
+			config.Dispositions[strings.ToLower(strings.TrimSpace(key))] = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+
 	if allowedTypes := os.Getenv("ALLOWED_FILE_TYPES"); allowedTypes != "" {
 		types := strings.Split(allowedTypes, ",")
 		var cleanTypes []string
@@ -130,7 +237,7 @@ func (c *Config) Validate() []string {
 		}
 
 		// If using a custom endpoint, access key and secret key are required
-		if c.S3Endpoint != "" {
+		if c.S3Endpoint != "" && c.S3Credentials == nil {
 			if c.S3AccessKey == "" {
 				errors = append(errors, "S3 access key is required when using a custom S3 endpoint")
 			}
@@ -140,11 +247,41 @@ func (c *Config) Validate() []string {
 		}
 	}
 
+	// Check CDN configuration
+	if c.CloudFrontDistributionID != "" && c.CDNBaseURL == "" && c.CDN == nil {
+		errors = append(errors, "CDN base URL is required when using CloudFront")
+	}
+	if c.TenantScoped && (c.CDN != nil || c.CDNBaseURL != "") {
+		errors = append(errors, "CDN cannot be used with tenant-scoped storage")
+	}
+	if c.TenantScoped && c.Indexer != nil {
+		errors = append(errors, "Search index cannot be used with tenant-scoped storage")
+	}
+
 	// Check upload size
 	if c.UploadMaxSizeMB <= 0 {
 		errors = append(errors, "Upload max size must be greater than 0")
 	}
 
+	for key, size := range c.UploadMaxSizesMB {
+		if size <= 0 {
+			errors = append(errors, "Upload max size of "+key+" must be greater than 0")
+		}
+	}
+
+	for key, disposition := range c.Dispositions {
+		if !validDisposition(disposition) {
+			errors = append(errors, "Disposition of "+key+" must be inline or attachment")
+		}
+	}
+
+	// Check naming strategy
+	if c.Naming != "" {
+		if _, err := ParseNamingStrategy(c.Naming); err != nil {
+			errors = append(errors, "Invalid upload naming strategy: "+c.Naming)
+		}
+	}
+
 	// Check timeout
 	if c.TimeoutSecs <= 0 {
 		errors = append(errors, "Timeout seconds must be greater than 0")
@@ -153,6 +290,15 @@ func (c *Config) Validate() []string {
 	return errors
 }
 
+// getSecret reads a secret of the default secrets, or "" when it is missing
+func getSecret(name string) string {
+	value, err := secrets.Get(context.Background(), name)
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		logger.Default().Warnf("Failed to read secret %s: %v", name, err)
+	}
+	return value
+}
+
 // Helper function to get environment variable as integer
 func getEnvAsInt(key string, defaultValue int) int {
 	valueStr := os.Getenv(key)
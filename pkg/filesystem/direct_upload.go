@@ -0,0 +1,440 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultDirectUploadTTL is how long direct upload credentials are valid by
+// default
+const DefaultDirectUploadTTL = 15 * time.Minute
+
+// DirectUploadOptions are the options of direct upload credentials
+type DirectUploadOptions struct {
+	// Method is http.MethodPost for a form with a policy document, the
+	// default, or http.MethodPut for a presigned URL. Only forms limit the
+	// size of the file; the size of PUT uploads is checked once they
+	// complete.
+	Method string
+
+	// ContentType is the content type the file must be uploaded with
+	ContentType string
+
+	// MaxSize limits the size of the file in bytes
+	MaxSize int64
+
+	// TTL is how long the credentials are valid, defaulting to
+	// DefaultDirectUploadTTL
+	TTL time.Duration
+}
+
+// DirectUpload is what a browser needs to upload a file straight to the
+// storage: a form of Fields and the file, in a field named "file" after
+// them, posted to the URL, or the file as the body of a PUT to the URL with
+// the Headers
+type DirectUpload struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Path      string            `json:"path"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+
+	// Token is given back to complete the upload, see
+	// CompleteDirectUploadHandler
+	Token string `json:"token,omitempty"`
+}
+
+// DirectUploader is implemented by storages browsers can upload to
+// directly, such as S3Storage
+type DirectUploader interface {
+	PresignUpload(ctx context.Context, path string, opts DirectUploadOptions) (*DirectUpload, error)
+}
+
+// DirectUploadConfig configures the direct upload handlers
+type DirectUploadConfig struct {
+	// Secret signs the completion tokens, at least 32 bytes
+	Secret []byte
+
+	// Method is the default method of the credentials, see
+	// DirectUploadOptions.Method
+	Method string
+
+	// TTL is how long the credentials are valid, defaulting to
+	// DefaultDirectUploadTTL. Uploads are completed within twice the TTL.
+	TTL time.Duration
+}
+
+// DirectUploadRequest is the JSON body of DirectUploadHandler
+type DirectUploadRequest struct {
+	// Filename is the original name of the file, e.g. "video.mp4"
+	Filename string `json:"filename"`
+
+	// ContentType is the content type the file is uploaded with
+	ContentType string `json:"contentType"`
+
+	// Size is the size of the file, checked against the limits of the
+	// policy before the upload
+	Size int64 `json:"size"`
+
+	// Path is the directory of the file under the base path
+	Path string `json:"path"`
+
+	// Policy is the upload policy
+	Policy string `json:"policy"`
+
+	// Method overrides the method of the config, "POST" or "PUT"
+	Method string `json:"method"`
+}
+
+// directUploadClaims is the signed payload of a completion token
+type directUploadClaims struct {
+	Path         string `json:"path"`
+	Dir          string `json:"dir"`
+	Name         string `json:"name"`
+	OriginalName string `json:"original"`
+	Policy       string `json:"policy,omitempty"`
+	MaxSize      int64  `json:"max"`
+	Expires      int64  `json:"exp"`
+}
+
+// directUploadTokens signs and verifies completion tokens
+type directUploadTokens struct {
+	secret []byte
+}
+
+// issue signs the claims of an upload
+func (t directUploadTokens) issue(claims directUploadClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.sign(encoded)), nil
+}
+
+// verify checks the signature and expiry of a token and returns its claims
+func (t directUploadTokens) verify(token string, now time.Time) (*directUploadClaims, *fserrors.AppError) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fserrors.InvalidTokenError()
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, t.sign(encoded)) {
+		return nil, fserrors.InvalidTokenError()
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fserrors.InvalidTokenError()
+	}
+	var claims directUploadClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fserrors.InvalidTokenError()
+	}
+	if !now.Before(time.Unix(claims.Expires, 0)) {
+		return nil, fserrors.TokenExpiredError()
+	}
+	return &claims, nil
+}
+
+// sign returns the signature of an encoded payload
+func (t directUploadTokens) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// directUploads returns the direct upload config with its defaults, and
+// its tokens
+func (config UploadHandlerConfig) directUploads() (DirectUploadConfig, directUploadTokens) {
+	direct := DirectUploadConfig{}
+	if config.DirectUploads != nil {
+		direct = *config.DirectUploads
+	}
+	if len(direct.Secret) < 32 {
+		panic("filesystem: direct upload secret must be at least 32 bytes")
+	}
+	if direct.Method == "" {
+		direct.Method = http.MethodPost
+	}
+	if direct.TTL <= 0 {
+		direct.TTL = DefaultDirectUploadTTL
+	}
+	return direct, directUploadTokens{secret: direct.Secret}
+}
+
+// PresignUpload returns credentials for a browser to upload a file straight
+// to the storage, when it implements DirectUploader
+func (p *Provider) PresignUpload(ctx context.Context, path string, opts DirectUploadOptions) (*DirectUpload, error) {
+	uploader, ok := p.storage.(DirectUploader)
+	if !ok {
+		return nil, fserrors.NewError(http.StatusNotImplemented, "Storage does not support direct uploads")
+	}
+	return uploader.PresignUpload(ctx, path, opts)
+}
+
+// DirectUploadHandler returns a Fiber handler issuing the credentials of a
+// DirectUploadRequest, so large files are uploaded by browsers straight to
+// the storage instead of through the server. The file is named and checked
+// against the allowed types and size limits of its policy first. Once
+// uploaded, the client completes the upload with the token of the
+// response, see CompleteDirectUploadHandler. Needs
+// UploadHandlerConfig.DirectUploads.
+func DirectUploadHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+	direct, tokens := config.directUploads()
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		var req DirectUploadRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"Invalid direct upload request body",
+				),
+			))
+		}
+		if strings.TrimSpace(req.Filename) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(http.StatusBadRequest, "Filename is required"),
+			))
+		}
+		method := strings.ToUpper(req.Method)
+		if method == "" {
+			method = direct.Method
+		}
+		if method != http.MethodPost && method != http.MethodPut {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(http.StatusBadRequest, "Method must be POST or PUT"),
+			))
+		}
+
+		// Select the upload policy
+		policy, appErr := config.policy(req.Policy)
+		if appErr != nil {
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+
+		// Check the announced file against the policy
+		limit, appliesTo := policy.sizeLimit(req.Filename, req.ContentType)
+		if req.Size > int64(limit) {
+			appErr := fserrors.FileTooLargeError(req.Size, int64(limit))
+			appErr.Details = map[string]interface{}{
+				"size":      req.Size,
+				"maxSize":   limit,
+				"appliesTo": appliesTo,
+				"policy":    req.Policy,
+			}
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+		ext := strings.ToLower(filepath.Ext(req.Filename))
+		if len(policy.AllowedTypes) > 0 && !slices.Contains(policy.AllowedTypes, ext) {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.InvalidFileTypeError(ext, policy.AllowedTypes),
+			))
+		}
+
+		// Name the file without its contents, which naming by hash needs
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Type", req.ContentType)
+		filename, err := policy.Naming.Name(&multipart.FileHeader{Filename: req.Filename, Size: req.Size, Header: header})
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusBadRequest,
+					"Failed to name the file before its upload",
+				),
+			))
+		}
+		dir := NormalizeKey(req.Path)
+		fullPath := JoinKey(config.BasePath, dir, filename)
+
+		exists, err := config.Provider.Exists(ctx, fullPath)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to check file existence",
+				),
+			))
+		}
+		if exists {
+			appErr := fserrors.FileAlreadyExistsError(fullPath)
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+
+		upload, err := config.Provider.PresignUpload(ctx, fullPath, DirectUploadOptions{
+			Method:      method,
+			ContentType: req.ContentType,
+			MaxSize:     int64(limit),
+			TTL:         direct.TTL,
+		})
+		if err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to sign direct upload",
+				),
+			))
+		}
+
+		// The upload may start until the credentials expire, and is
+		// completed within as long again
+		upload.Token, err = tokens.issue(directUploadClaims{
+			Path:         fullPath,
+			Dir:          dir,
+			Name:         filename,
+			OriginalName: req.Filename,
+			Policy:       req.Policy,
+			MaxSize:      int64(limit),
+			Expires:      upload.ExpiresAt.Add(direct.TTL).Unix(),
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to sign direct upload",
+				),
+			))
+		}
+		upload.Path = JoinKey(dir, filename)
+
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Data:    upload,
+		})
+	}
+}
+
+// CompleteDirectUploadHandler returns a Fiber handler registering a file
+// uploaded with the credentials of DirectUploadHandler, for the token in
+// the JSON body: {"token": "..."}. Files larger than their limit are
+// deleted and refused, and the others are processed by the pipeline of
+// their policy like uploads. The rules of the policy and StripGPS need the
+// contents of files, so they are not applied; check files in the pipeline
+// instead. Needs UploadHandlerConfig.DirectUploads.
+func CompleteDirectUploadHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+	_, tokens := config.directUploads()
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"Invalid direct upload completion body",
+				),
+			))
+		}
+		claims, appErr := tokens.verify(body.Token, time.Now())
+		if appErr != nil {
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+		policy, appErr := config.policy(claims.Policy)
+		if appErr != nil {
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+
+		info, err := config.Provider.GetInfo(ctx, claims.Path)
+		if err != nil {
+			var appErr *fserrors.AppError
+			if errors.As(err, &appErr) {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to get file info",
+				),
+			))
+		}
+
+		// PUT uploads are not limited by the storage
+		if info.Size > claims.MaxSize {
+			if err := config.Provider.Delete(ctx, claims.Path); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+					fserrors.WrapError(
+						err,
+						http.StatusInternalServerError,
+						"Failed to delete file",
+					),
+				))
+			}
+			appErr := fserrors.FileTooLargeError(info.Size, claims.MaxSize)
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+
+		// Process the file, or enqueue its processing
+		if policy.Pipeline != nil {
+			if err := policy.Pipeline.Run(ctx, claims.Path, info); err != nil {
+				var appErr *fserrors.AppError
+				if fserrors.As(err, &appErr) {
+					return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+				}
+
+				return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+					fserrors.WrapError(
+						err,
+						http.StatusInternalServerError,
+						"Failed to process file",
+					),
+				))
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Message: "File uploaded successfully",
+			Data: FileResponse{
+				Name:         info.Name,
+				OriginalName: claims.OriginalName,
+				Size:         info.Size,
+				URL:          info.URL,
+				Path:         JoinKey(claims.Dir, claims.Name),
+				ContentType:  info.ContentType,
+				LastModified: info.LastModified,
+				Metadata:     info.Metadata,
+			},
+		})
+	}
+}
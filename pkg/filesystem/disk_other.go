@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd
+
+package filesystem
+
+// diskUsage reports that the disk space cannot be read on this platform, so
+// the free space of LocalStorage is not checked
+func diskUsage(path string) (diskSpace, error) {
+	return diskSpace{}, errDiskUsageUnsupported
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd
+
+package filesystem
+
+import "syscall"
+
+// diskUsage returns the space and inodes of the filesystem of a path
+func diskUsage(path string) (diskSpace, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return diskSpace{}, err
+	}
+	return diskSpace{
+		TotalBytes:  uint64(st.Blocks) * uint64(st.Bsize),
+		FreeBytes:   uint64(st.Bavail) * uint64(st.Bsize),
+		TotalInodes: uint64(st.Files),
+		FreeInodes:  uint64(st.Ffree),
+	}, nil
+}
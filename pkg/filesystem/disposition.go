@@ -0,0 +1,92 @@
+package filesystem
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+)
+
+// Content dispositions of served files
+const (
+	DispositionInline     = "inline"
+	DispositionAttachment = "attachment"
+)
+
+// DefaultDispositions force the download of files browsers would run the
+// scripts of when shown inline, such as HTML and SVG. Rules of
+// UploadHandlerConfig.Dispositions override them, e.g. ".svg": "inline" to
+// let clients show SVGs again.
+var DefaultDispositions = map[string]string{
+	"text/html":              DispositionAttachment,
+	"application/xhtml+xml":  DispositionAttachment,
+	"image/svg+xml":          DispositionAttachment,
+	"text/xml":               DispositionAttachment,
+	"application/xml":        DispositionAttachment,
+	"text/javascript":        DispositionAttachment,
+	"application/javascript": DispositionAttachment,
+}
+
+// validDisposition reports whether a disposition is inline or attachment
+func validDisposition(disposition string) bool {
+	return disposition == DispositionInline || disposition == DispositionAttachment
+}
+
+// dispositions returns the disposition rules of the config over the
+// defaults
+func (config UploadHandlerConfig) dispositions() map[string]string {
+	rules := make(map[string]string, len(DefaultDispositions)+len(config.Dispositions))
+	for key, disposition := range DefaultDispositions {
+		rules[key] = disposition
+	}
+	for key, disposition := range config.Dispositions {
+		rules[strings.ToLower(key)] = disposition
+	}
+	return rules
+}
+
+// contentDisposition returns the disposition of a served file by the rule
+// of the content type it is served with, of its extension, or of the
+// content type of its extension. Attachment rules force the download, while
+// inline rules and files without a rule keep the requested disposition. The
+// served content type is checked first, so a file named "page.txt" stored
+// as HTML is still downloaded. A rule for the extension replaces it only
+// when the file is served as the type of its extension, so ".svg": "inline"
+// shows SVGs but not HTML named "page.svg".
+func contentDisposition(rules map[string]string, requested, filename, contentType string) string {
+	ext := extension(filename)
+	extType := mime.TypeByExtension(ext)
+	rule, _, ok := matchContentType(rules, contentType)
+	if extRule, found := rules[ext]; found && ext != "" && (!ok || sameMediaType(contentType, extType)) {
+		rule, ok = extRule, true
+	}
+	if !ok {
+		rule, _, _ = matchContentType(rules, extType)
+	}
+	if rule == DispositionAttachment {
+		return DispositionAttachment
+	}
+	return requested
+}
+
+// sameMediaType reports whether two content types have the same media type,
+// ignoring their parameters
+func sameMediaType(a, b string) bool {
+	typeA, _, errA := mime.ParseMediaType(a)
+	typeB, _, errB := mime.ParseMediaType(b)
+	return errA == nil && errB == nil && typeA == typeB
+}
+
+// dispositionHeader formats a Content-Disposition header, removing control
+// characters and path separators from the file name and encoding names
+// outside of ASCII as RFC 2231 allows
+func dispositionHeader(disposition, filename string) string {
+	filename = pathutil.SanitizeFilename(filename)
+	if filename == "" {
+		return disposition
+	}
+	if header := mime.FormatMediaType(disposition, map[string]string{"filename": filename}); header != "" {
+		return header
+	}
+	return disposition
+}
@@ -0,0 +1,47 @@
+package filesystem
+
+import "testing"
+
+func TestContentDisposition(t *testing.T) {
+	rules := UploadHandlerConfig{Dispositions: map[string]string{".svg": DispositionInline, "video/*": DispositionAttachment}}.dispositions()
+	tests := []struct {
+		requested, filename, contentType, want string
+	}{
+		{"inline", "photo.png", "image/png", "inline"},
+		{"attachment", "photo.png", "image/png", "attachment"},
+		{"inline", "page.html", "text/html; charset=utf-8", "attachment"},
+		{"inline", "page.txt", "text/html", "attachment"},
+		{"inline", "page.htm", "application/octet-stream", "attachment"},
+		{"inline", "logo.svg", "image/svg+xml", "inline"},
+		{"inline", "clip.mp4", "video/mp4", "attachment"},
+		{"attachment", "logo.svg", "image/svg+xml", "attachment"},
+		{"inline", "page.svg", "text/html", "attachment"},
+		{"inline", "page.svg", "application/xhtml+xml", "attachment"},
+		{"inline", "logo.svg", "application/octet-stream", "inline"},
+		{"inline", "report.pdf", "application/pdf", "inline"},
+	}
+	for _, tt := range tests {
+		if got := contentDisposition(rules, tt.requested, tt.filename, tt.contentType); got != tt.want {
+			t.Errorf("contentDisposition(%q, %q, %q) = %q, want %q", tt.requested, tt.filename, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestDispositionHeader(t *testing.T) {
+	tests := []struct {
+		filename, want string
+	}{
+		{"report.pdf", `inline; filename=report.pdf`},
+		{"my report.pdf", `inline; filename="my report.pdf"`},
+		{"a\r\nSet-Cookie: x=1.pdf", `inline; filename="aSet-Cookie_ x=1.pdf"`},
+		{`evil".pdf`, `inline; filename=evil_.pdf`},
+		{"../../etc/passwd", `inline; filename=passwd`},
+		{"résumé.pdf", `inline; filename*=utf-8''r%C3%A9sum%C3%A9.pdf`},
+		{"", `inline`},
+	}
+	for _, tt := range tests {
+		if got := dispositionHeader(DispositionInline, tt.filename); got != tt.want {
+			t.Errorf("dispositionHeader(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DownloadCounter counts the downloads of grants, e.g.
+// MemoryDownloadCounter or GormDownloadCounter
+type DownloadCounter interface {
+	// Take counts a download of a grant and reports whether it was allowed,
+	// which it is not once the grant was used max times. The count may be
+	// dropped after expiresAt.
+	Take(ctx context.Context, grantID string, max int, expiresAt time.Time) (bool, error)
+}
+
+// downloadSweepInterval is how often MemoryDownloadCounter drops the counts
+// of expired grants
+const downloadSweepInterval = time.Minute
+
+// MemoryDownloadCounter counts downloads in memory, for a single instance.
+// The count of an expired grant is reset when the grant is used again, and
+// the counts of all expired grants are dropped at most once per minute.
+type MemoryDownloadCounter struct {
+	mu        sync.Mutex
+	counts    map[string]*downloadCount
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+type downloadCount struct {
+	n         int
+	expiresAt time.Time
+}
+
+// NewMemoryDownloadCounter creates an empty memory counter
+func NewMemoryDownloadCounter() *MemoryDownloadCounter {
+	return &MemoryDownloadCounter{counts: make(map[string]*downloadCount), now: time.Now}
+}
+
+// Take counts a download of a grant unless it was used max times
+func (m *MemoryDownloadCounter) Take(_ context.Context, grantID string, max int, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if !now.Before(m.nextSweep) {
+		for id, count := range m.counts {
+			if !now.Before(count.expiresAt) {
+				delete(m.counts, id)
+			}
+		}
+		m.nextSweep = now.Add(downloadSweepInterval)
+	}
+
+	count, ok := m.counts[grantID]
+	if !ok || !now.Before(count.expiresAt) {
+		count = &downloadCount{expiresAt: expiresAt}
+		m.counts[grantID] = count
+	}
+	if count.n >= max {
+		return false, nil
+	}
+	count.n++
+	return true, nil
+}
+
+// DownloadCount is the row of a grant in the download_counts table
+type DownloadCount struct {
+	GrantID   string    `gorm:"primaryKey;size:32"`
+	Downloads int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// GormDownloadCounter counts downloads in the download_counts table, shared
+// by every instance. Create the table with
+// db.AutoMigrate(&filesystem.DownloadCount{}).
+type GormDownloadCounter struct {
+	db *gorm.DB
+}
+
+// NewGormDownloadCounter creates a counter backed by a database
+func NewGormDownloadCounter(db *gorm.DB) *GormDownloadCounter {
+	return &GormDownloadCounter{db: db}
+}
+
+// Take counts a download of a grant unless it was used max times. The count
+// is checked and incremented in one statement, so concurrent downloads
+// cannot exceed max.
+func (g *GormDownloadCounter) Take(ctx context.Context, grantID string, max int, expiresAt time.Time) (bool, error) {
+	db := g.db.WithContext(ctx)
+	row := DownloadCount{GrantID: grantID, ExpiresAt: expiresAt}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		return false, fmt.Errorf("filesystem: count download: %w", err)
+	}
+
+	result := db.Model(&DownloadCount{}).
+		Where("grant_id = ? AND downloads < ?", grantID, max).
+		Update("downloads", gorm.Expr("downloads + 1"))
+	if result.Error != nil {
+		return false, fmt.Errorf("filesystem: count download: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteExpired removes the counts of expired grants, e.g. from a scheduled
+// task
+func (g *GormDownloadCounter) DeleteExpired(ctx context.Context) error {
+	err := g.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&DownloadCount{}).Error
+	if err != nil {
+		return fmt.Errorf("filesystem: delete expired download counts: %w", err)
+	}
+	return nil
+}
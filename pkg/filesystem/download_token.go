@@ -0,0 +1,238 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+)
+
+const (
+	// DefaultDownloadTTL is how long download tokens are valid by default
+	DefaultDownloadTTL = 24 * time.Hour
+
+	// DefaultDownloadTokenParam is the query parameter GetFileHandler reads
+	// download tokens from
+	DefaultDownloadTokenParam = "token"
+
+	// MaxDownloadGrantIDLength is the longest grant ID, the size of
+	// DownloadCount.GrantID
+	MaxDownloadGrantIDLength = 32
+)
+
+// DownloadGrant allows downloading the files matching a pattern until it
+// expires
+type DownloadGrant struct {
+	// ID identifies the grant, e.g. to count its downloads, of at most
+	// MaxDownloadGrantIDLength bytes. Generated when the grant is issued.
+	ID string `json:"id"`
+
+	// UserID is the only user who may use the grant, as the subject of the
+	// token verified by the auth middleware. Empty lets anyone with the
+	// token use it.
+	UserID string `json:"sub,omitempty"`
+
+	// Pattern is the file path or a path.Match pattern such as
+	// "reports/*.pdf". A pattern ending with "/**" matches every file under
+	// a directory.
+	Pattern string `json:"path"`
+
+	// MaxDownloads limits how many times the grant is used, zero for no limit
+	MaxDownloads int `json:"max,omitempty"`
+
+	// ExpiresAt defaults to DefaultDownloadTTL from the issue time
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Allows reports whether the grant covers a file path
+func (g *DownloadGrant) Allows(filePath string) bool {
+	filePath = cleanGrantPath(filePath)
+	pattern := cleanGrantPath(g.Pattern)
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return strings.HasPrefix(filePath, dir+"/")
+	}
+	matched, err := path.Match(pattern, filePath)
+	return err == nil && matched
+}
+
+// DownloadTokensConfig configures download tokens
+type DownloadTokensConfig struct {
+	// Secret signs the tokens, at least 32 bytes
+	Secret []byte
+
+	// TTL defaults to DefaultDownloadTTL
+	TTL time.Duration
+
+	// Counter counts the downloads of grants with MaxDownloads, defaulting
+	// to a MemoryDownloadCounter. Use a GormDownloadCounter when several
+	// instances serve downloads.
+	Counter DownloadCounter
+
+	// QueryParam defaults to DefaultDownloadTokenParam
+	QueryParam string
+}
+
+// DownloadTokens issues signed, short-lived download grants, for sharing
+// files without making them public and without S3 presigned URLs
+type DownloadTokens struct {
+	config DownloadTokensConfig
+	now    func() time.Time
+}
+
+// downloadClaims is the signed payload of a download token
+type downloadClaims struct {
+	DownloadGrant
+	Expires int64 `json:"exp"`
+}
+
+// NewDownloadTokens creates a download token service
+func NewDownloadTokens(config DownloadTokensConfig) (*DownloadTokens, error) {
+	if len(config.Secret) < 32 {
+		return nil, errors.New("filesystem: download token secret must be at least 32 bytes")
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultDownloadTTL
+	}
+	if config.Counter == nil {
+		config.Counter = NewMemoryDownloadCounter()
+	}
+	if config.QueryParam == "" {
+		config.QueryParam = DefaultDownloadTokenParam
+	}
+	return &DownloadTokens{config: config, now: time.Now}, nil
+}
+
+// Issue signs a token for a grant, e.g. to share a report for a day:
+//
+//	token, err := tokens.Issue(filesystem.DownloadGrant{
+//		UserID:       "42",
+//		Pattern:      "reports/2024-q1.pdf",
+//		MaxDownloads: 3,
+//	})
+//	url := "/shared/reports/2024-q1.pdf?token=" + token
+func (t *DownloadTokens) Issue(grant DownloadGrant) (string, error) {
+	if grant.Pattern == "" {
+		return "", errors.New("filesystem: download grant without a path pattern")
+	}
+	if _, err := path.Match(cleanGrantPath(grant.Pattern), ""); err != nil {
+		return "", errors.New("filesystem: invalid download grant pattern " + grant.Pattern)
+	}
+	if len(grant.ID) > MaxDownloadGrantIDLength {
+		return "", fmt.Errorf("filesystem: download grant ID longer than %d bytes", MaxDownloadGrantIDLength)
+	}
+	if grant.ID == "" {
+		b := make([]byte, MaxDownloadGrantIDLength/2)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		grant.ID = hex.EncodeToString(b)
+	}
+	if grant.ExpiresAt.IsZero() {
+		grant.ExpiresAt = t.now().Add(t.config.TTL)
+	}
+
+	payload, err := json.Marshal(downloadClaims{DownloadGrant: grant, Expires: grant.ExpiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.sign(encoded)), nil
+}
+
+// Verify checks the signature and expiry of a token and returns its grant
+func (t *DownloadTokens) Verify(token string) (*DownloadGrant, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fserrors.InvalidTokenError()
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, t.sign(encoded)) {
+		return nil, fserrors.InvalidTokenError()
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fserrors.InvalidTokenError()
+	}
+	var claims downloadClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fserrors.InvalidTokenError()
+	}
+
+	grant := claims.DownloadGrant
+	grant.ExpiresAt = time.Unix(claims.Expires, 0)
+	if !t.now().Before(grant.ExpiresAt) {
+		return nil, fserrors.TokenExpiredError()
+	}
+	return &grant, nil
+}
+
+// Check verifies a token for a download of a file by a user, without
+// counting the download
+func (t *DownloadTokens) Check(token, userID, filePath string) (*DownloadGrant, error) {
+	grant, err := t.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if grant.UserID != "" && grant.UserID != userID {
+		if userID == "" {
+			return nil, fserrors.UnauthorizedError("")
+		}
+		return nil, fserrors.ForbiddenError("")
+	}
+	if !grant.Allows(filePath) {
+		return nil, fserrors.ForbiddenError("")
+	}
+	return grant, nil
+}
+
+// Take counts a download of a grant, failing with 403 once it has been
+// used MaxDownloads times
+func (t *DownloadTokens) Take(ctx context.Context, grant *DownloadGrant) error {
+	if grant.MaxDownloads <= 0 {
+		return nil
+	}
+	ok, err := t.config.Counter.Take(ctx, grant.ID, grant.MaxDownloads, grant.ExpiresAt)
+	if err != nil {
+		return fserrors.StorageUnavailableError(err)
+	}
+	if !ok {
+		return fserrors.ForbiddenError("Download limit reached")
+	}
+	return nil
+}
+
+// Redeem checks a token for a download of a file by a user and counts the
+// download
+func (t *DownloadTokens) Redeem(ctx context.Context, token, userID, filePath string) (*DownloadGrant, error) {
+	grant, err := t.Check(token, userID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Take(ctx, grant); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// sign returns the signature of an encoded payload
+func (t *DownloadTokens) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, t.config.Secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// cleanGrantPath returns a path relative to the storage root with slashes
+func cleanGrantPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
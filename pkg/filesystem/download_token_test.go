@@ -0,0 +1,210 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+var testDownloadSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// newTestDownloadTokens returns download tokens whose clock is set to now
+func newTestDownloadTokens(t *testing.T, secret []byte, now time.Time) *DownloadTokens {
+	t.Helper()
+	tokens, err := NewDownloadTokens(DownloadTokensConfig{Secret: secret, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to create download tokens: %v", err)
+	}
+	tokens.now = func() time.Time { return now }
+	return tokens
+}
+
+// appErrorCode returns the code of an AppError, or ""
+func appErrorCode(err error) string {
+	var appErr *fserrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
+
+func TestDownloadTokens(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tokens := newTestDownloadTokens(t, testDownloadSecret, now)
+	token, err := tokens.Issue(DownloadGrant{UserID: "42", Pattern: "reports/*.pdf"})
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// Other services and tokens
+	later := newTestDownloadTokens(t, testDownloadSecret, now.Add(time.Hour))
+	otherSecret := newTestDownloadTokens(t, []byte("fedcba9876543210fedcba9876543210"), now)
+	forged, _ := base64.RawURLEncoding.DecodeString(payload)
+	forged = []byte(strings.Replace(string(forged), "reports/*.pdf", "**", 1))
+	anyone, _ := tokens.Issue(DownloadGrant{Pattern: "shared/**"})
+	invalidToken := fserrors.InvalidTokenError().Code
+
+	tests := []struct {
+		name     string
+		tokens   *DownloadTokens
+		token    string
+		userID   string
+		filePath string
+		code     string // "" for an allowed download
+	}{
+		{"valid", tokens, token, "42", "reports/q1.pdf", ""},
+		{"expired", later, token, "42", "reports/q1.pdf", fserrors.TokenExpiredError().Code},
+		{"other secret", otherSecret, token, "42", "reports/q1.pdf", invalidToken},
+		{"tampered signature", tokens, payload + "." + base64.RawURLEncoding.EncodeToString([]byte("forged")), "42", "reports/q1.pdf", invalidToken},
+		{"tampered payload", tokens, base64.RawURLEncoding.EncodeToString(forged) + "." + signature, "42", "secret/keys.txt", invalidToken},
+		{"malformed", tokens, "not-a-token", "42", "reports/q1.pdf", invalidToken},
+		{"empty", tokens, "", "42", "reports/q1.pdf", invalidToken},
+		{"other user", tokens, token, "7", "reports/q1.pdf", fserrors.ErrCodeForbidden},
+		{"anonymous user", tokens, token, "", "reports/q1.pdf", fserrors.ErrCodeUnauthorized},
+		{"other file", tokens, token, "42", "reports/q1.csv", fserrors.ErrCodeForbidden},
+		{"traversal", tokens, token, "42", "reports/../secret/q1.pdf", fserrors.ErrCodeForbidden},
+		{"any user", tokens, anyone, "", "shared/a/b.txt", ""},
+		{"outside directory", tokens, anyone, "", "shared.txt", fserrors.ErrCodeForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.tokens.Check(tt.token, tt.userID, tt.filePath)
+			if code := appErrorCode(err); code != tt.code {
+				t.Errorf("Expected %q, got %v", tt.code, err)
+			}
+		})
+	}
+
+	if _, err := tokens.Issue(DownloadGrant{ID: strings.Repeat("a", MaxDownloadGrantIDLength+1), Pattern: "a.txt"}); err == nil {
+		t.Error("Expected a grant ID longer than the column to be refused")
+	}
+	if _, err := tokens.Issue(DownloadGrant{}); err == nil {
+		t.Error("Expected a grant without a pattern to be refused")
+	}
+}
+
+func TestDownloadTokensLimit(t *testing.T) {
+	now := time.Now()
+	tokens := newTestDownloadTokens(t, testDownloadSecret, now)
+	token, err := tokens.Issue(DownloadGrant{Pattern: "a.txt", MaxDownloads: 2})
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	unlimited, _ := tokens.Issue(DownloadGrant{Pattern: "a.txt"})
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := tokens.Redeem(ctx, token, "", "a.txt"); err != nil {
+			t.Fatalf("Expected download %d to be allowed, got %v", i+1, err)
+		}
+	}
+	if _, err := tokens.Redeem(ctx, token, "", "a.txt"); appErrorCode(err) != fserrors.ErrCodeForbidden {
+		t.Errorf("Expected the third download to be refused, got %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := tokens.Redeem(ctx, unlimited, "", "a.txt"); err != nil {
+			t.Fatalf("Expected downloads without a limit to be allowed, got %v", err)
+		}
+	}
+}
+
+func TestMemoryDownloadCounter(t *testing.T) {
+	now := time.Now()
+	counter := NewMemoryDownloadCounter()
+	counter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	take := func(grantID string, expiresAt time.Time) bool {
+		t.Helper()
+		ok, err := counter.Take(ctx, grantID, 1, expiresAt)
+		if err != nil {
+			t.Fatalf("Take failed: %v", err)
+		}
+		return ok
+	}
+
+	if !take("a", now.Add(time.Hour)) || take("a", now.Add(time.Hour)) {
+		t.Error("Expected one download of a to be allowed")
+	}
+	if !take("b", now.Add(time.Second)) {
+		t.Error("Expected a download of b to be allowed")
+	}
+
+	// Expired counts are reset on use, and dropped by the next sweep
+	now = now.Add(2 * time.Second)
+	if !take("b", now.Add(time.Second)) {
+		t.Error("Expected the count of an expired grant to be reset")
+	}
+	now = now.Add(downloadSweepInterval)
+	take("c", now.Add(time.Hour))
+	if _, ok := counter.counts["b"]; ok || len(counter.counts) != 2 {
+		t.Errorf("Expected the expired count to be dropped, got %v", counter.counts)
+	}
+}
+
+func TestGetFileHandlerDownloadTokens(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "files", "reports"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "files", "reports", "q1.txt"), []byte("report"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	tokens := newTestDownloadTokens(t, testDownloadSecret, time.Now())
+	app := fiber.New()
+	app.Get("/files/*", GetFileHandler(UploadHandlerConfig{
+		Provider:       NewProvider(storage),
+		BasePath:       "files",
+		TimeoutSecs:    5,
+		DownloadTokens: tokens,
+	}))
+
+	token, _ := tokens.Issue(DownloadGrant{Pattern: "reports/*", MaxDownloads: 1})
+	get := func(target string) (int, string) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, target, nil))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, body := get("/files/reports/q1.txt?token=" + token); status != http.StatusOK || body != "report" {
+		t.Errorf("Expected the file to be sent, got %d %q", status, body)
+	}
+	if status, _ := get("/files/reports/q1.txt?token=" + token); status != http.StatusForbidden {
+		t.Errorf("Expected 403 once the limit is reached, got %d", status)
+	}
+	if status, _ := get("/files/reports/q1.txt"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", status)
+	}
+}
+
+func TestStreamBodyCancelsOnClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	body := &streamBody{ReadCloser: io.NopCloser(strings.NewReader("data")), cancel: cancel}
+	if ctx.Err() != nil {
+		t.Fatal("Expected the context to stay open while the body is sent")
+	}
+	if err := body.Close(); err != nil || ctx.Err() == nil {
+		t.Errorf("Expected closing the body to cancel its context, got %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DuplicateConfig configures a search for duplicate files
+type DuplicateConfig struct {
+	// Dir is the directory to scan, with its subdirectories; empty for the
+	// whole storage
+	Dir string
+
+	// References reports the files in use. Every referenced copy of a file
+	// is kept, and the oldest copy when none is.
+	References References
+
+	// Dedupe deletes the copies that are not kept, which needs References
+	Dedupe bool
+
+	// BatchSize is how many paths are checked for references at once,
+	// defaulting to DefaultGCBatchSize
+	BatchSize int
+}
+
+// DuplicateFile is a copy of a file with the same contents as others
+type DuplicateFile struct {
+	Path         string    `json:"path"`
+	LastModified time.Time `json:"lastModified"`
+
+	// Kept is set on the copies a dedupe keeps
+	Kept bool `json:"kept"`
+
+	// Deleted is set on the copies deleted by a dedupe, and Error on those
+	// that could not be
+	Deleted bool   `json:"deleted,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DuplicateGroup is the copies of a file, oldest first
+type DuplicateGroup struct {
+	Checksum string          `json:"checksum"`
+	Size     int64           `json:"size"`
+	Files    []DuplicateFile `json:"files"`
+
+	// Reclaimable is the size of the copies that are not kept
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// DuplicateReport is the outcome of a search for duplicate files
+type DuplicateReport struct {
+	Dedupe bool `json:"dedupe"`
+
+	// Scanned counts the files with a stored checksum
+	Scanned int `json:"scanned"`
+
+	// Unchecked counts the files without a stored checksum, which cannot be
+	// compared, see MetadataExtractor
+	Unchecked int `json:"unchecked"`
+
+	// Groups lists the files stored more than once, most reclaimable first
+	Groups []DuplicateGroup `json:"groups"`
+
+	// Reclaimable is the size of the copies that are not kept
+	Reclaimable int64 `json:"reclaimable"`
+
+	// Deleted counts the copies deleted by a dedupe, and Freed their size
+	Deleted int   `json:"deleted"`
+	Freed   int64 `json:"freed"`
+}
+
+// FindDuplicates reports the files under a directory stored more than once,
+// compared by the SHA-256 checksums recorded in their metadata by a
+// MetadataExtractor, and deletes the extra copies with Dedupe:
+//
+//	report, err := fs.Provider.FindDuplicates(ctx, filesystem.DuplicateConfig{
+//		Dir:        "documents",
+//		References: filesystem.GormReferences(db, "documents", "file_path"),
+//	})
+//	fmt.Printf("%d bytes reclaimable\n", report.Reclaimable)
+//
+// Files derived from others by a pipeline are left out, as they are deleted
+// with the file they were derived from. A copy that cannot be deleted is
+// reported with its error and the dedupe goes on.
+func (p *Provider) FindDuplicates(ctx context.Context, config DuplicateConfig) (*DuplicateReport, error) {
+	if p.config.Metadata == nil {
+		return nil, fmt.Errorf("filesystem: duplicates without a metadata store")
+	}
+	if config.Dedupe && config.References == nil {
+		return nil, fmt.Errorf("filesystem: dedupe without references")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultGCBatchSize
+	}
+
+	// Group the files by checksum and size
+	report := &DuplicateReport{Dedupe: config.Dedupe, Groups: []DuplicateGroup{}}
+	groups := make(map[string]*DuplicateGroup)
+	err := p.Walk(ctx, cleanPath(config.Dir), func(filePath string, info FileInfo) error {
+		metadata, err := p.config.Metadata.Metadata(ctx, filePath)
+		if err != nil {
+			return err
+		}
+		if metadata["derivedFrom"] != "" {
+			return nil
+		}
+		checksum := metadata["sha256"]
+		if checksum == "" {
+			report.Unchecked++
+			return nil
+		}
+		report.Scanned++
+
+		key := fmt.Sprintf("%s:%d", checksum, info.Size)
+		group, ok := groups[key]
+		if !ok {
+			group = &DuplicateGroup{Checksum: checksum, Size: info.Size}
+			groups[key] = group
+		}
+		group.Files = append(group.Files, DuplicateFile{Path: filePath, LastModified: info.LastModified})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicated []*DuplicateGroup
+	for _, group := range groups {
+		if len(group.Files) > 1 {
+			sort.Slice(group.Files, func(i, j int) bool {
+				a, b := group.Files[i], group.Files[j]
+				if !a.LastModified.Equal(b.LastModified) {
+					return a.LastModified.Before(b.LastModified)
+				}
+				return a.Path < b.Path
+			})
+			duplicated = append(duplicated, group)
+		}
+	}
+
+	referenced, err := p.referencedDuplicates(ctx, duplicated, config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range duplicated {
+		// Keep the referenced copies, or the oldest
+		kept := 0
+		for i := range group.Files {
+			if referenced[group.Files[i].Path] {
+				group.Files[i].Kept = true
+				kept++
+			}
+		}
+		if kept == 0 {
+			group.Files[0].Kept = true
+			kept = 1
+		}
+		group.Reclaimable = int64(len(group.Files)-kept) * group.Size
+		report.Reclaimable += group.Reclaimable
+
+		if config.Dedupe {
+			for i := range group.Files {
+				file := &group.Files[i]
+				if file.Kept {
+					continue
+				}
+				if err := p.Delete(ctx, file.Path); err != nil {
+					file.Error = err.Error()
+					continue
+				}
+				file.Deleted = true
+				report.Deleted++
+				report.Freed += group.Size
+			}
+		}
+		report.Groups = append(report.Groups, *group)
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Reclaimable != b.Reclaimable {
+			return a.Reclaimable > b.Reclaimable
+		}
+		return a.Checksum < b.Checksum
+	})
+	return report, nil
+}
+
+// referencedDuplicates returns the copies in the groups that are in use
+func (p *Provider) referencedDuplicates(ctx context.Context, groups []*DuplicateGroup, config DuplicateConfig) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	if config.References == nil {
+		return referenced, nil
+	}
+
+	var batch []string
+	check := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		found, err := config.References.Referenced(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for filePath := range found {
+			referenced[filePath] = true
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for _, group := range groups {
+		for _, file := range group.Files {
+			batch = append(batch, file.Path)
+			if len(batch) < config.BatchSize {
+				continue
+			}
+			if err := check(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := check(); err != nil {
+		return nil, err
+	}
+	return referenced, nil
+}
+
+// DuplicatesConfig configures DuplicatesHandler and DedupeHandler
+type DuplicatesConfig struct {
+	// References reports the files in use, which are kept. DedupeHandler
+	// needs them.
+	References References
+}
+
+// DuplicatesHandler returns a Fiber handler reporting the files under the
+// prefix query parameter of the base path that are stored more than once,
+// see FindDuplicates:
+//
+//	GET /files/duplicates?prefix=documents
+//	{"success":true,"data":{"scanned":120,"unchecked":0,"groups":[...],"reclaimable":52428800,...}}
+//
+// The provider needs a metadata store.
+func DuplicatesHandler(config UploadHandlerConfig) fiber.Handler {
+	return duplicatesHandler(config, false)
+}
+
+// DedupeHandler returns a Fiber handler deleting the copies of the files
+// under the prefix query parameter of the base path that are not kept, and
+// reporting them like DuplicatesHandler:
+//
+//	POST /files/duplicates/dedupe?prefix=documents
+//
+// Needs UploadHandlerConfig.Duplicates with References.
+func DedupeHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Duplicates == nil || config.Duplicates.References == nil {
+		panic("filesystem dedupe requires references")
+	}
+	return duplicatesHandler(config, true)
+}
+
+// duplicatesHandler returns the handler of DuplicatesHandler, or of
+// DedupeHandler with dedupe
+func duplicatesHandler(config UploadHandlerConfig, dedupe bool) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+	if config.Provider.config.Metadata == nil {
+		panic("filesystem duplicates require a metadata store")
+	}
+	duplicates := DuplicatesConfig{}
+	if config.Duplicates != nil {
+		duplicates = *config.Duplicates
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		prefix := NormalizeKey(c.Query("prefix"))
+		report, err := config.Provider.FindDuplicates(ctx, DuplicateConfig{
+			Dir:        JoinKey(config.BasePath, prefix),
+			References: duplicates.References,
+			Dedupe:     dedupe,
+		})
+		if err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to find duplicate files",
+				),
+			))
+		}
+
+		// Report paths relative to the base path
+		for i := range report.Groups {
+			for j := range report.Groups[i].Files {
+				file := &report.Groups[i].Files[j]
+				file.Path = relativeKey(config.BasePath, file.Path)
+			}
+		}
+
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Data:    report,
+		})
+	}
+}
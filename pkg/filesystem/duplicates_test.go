@@ -0,0 +1,103 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDuplicatesHandler(t *testing.T) {
+	tempDir := t.TempDir()
+	dir := filepath.Join(tempDir, "files", "docs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// a.txt, b.txt, and c.txt are copies, a.txt the oldest; d.txt has no checksum
+	metadata := NewMemoryMetadataStore()
+	files := map[string]string{"a.txt": "same", "b.txt": "same", "c.txt": "same", "d.txt": "same", "e.txt": "other"}
+	checksums := map[string]string{"a.txt": "1234", "b.txt": "1234", "c.txt": "1234", "e.txt": "5678"}
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		modified := time.Now().Add(-time.Hour)
+		if name == "a.txt" {
+			modified = modified.Add(-time.Hour)
+		}
+		if err := os.Chtimes(filePath, modified, modified); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+		if checksum := checksums[name]; checksum != "" {
+			_ = metadata.SetMetadata(context.Background(), "files/docs/"+name, map[string]string{"sha256": checksum})
+		}
+	}
+
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	handlerConfig := UploadHandlerConfig{
+		Provider:    NewProvider(storage, ProviderConfig{Metadata: metadata}),
+		BasePath:    "files",
+		TimeoutSecs: 5,
+		Duplicates:  &DuplicatesConfig{References: ReferenceSet{"files/docs/c.txt": true}},
+	}
+	app := fiber.New()
+	app.Get("/duplicates", DuplicatesHandler(handlerConfig))
+	app.Post("/duplicates/dedupe", DedupeHandler(handlerConfig))
+
+	report := func(method, target string) DuplicateReport {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(method, target, nil))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var body struct {
+			Data DuplicateReport `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body.Data
+	}
+
+	found := report(http.MethodGet, "/duplicates?prefix=docs")
+	if found.Scanned != 4 || found.Unchecked != 1 || len(found.Groups) != 1 {
+		t.Fatalf("Expected 4 scanned, 1 unchecked, and 1 group, got %+v", found)
+	}
+	group := found.Groups[0]
+	if len(group.Files) != 3 || group.Files[0].Path != "docs/a.txt" || group.Reclaimable != 8 || found.Reclaimable != 8 {
+		t.Fatalf("Expected docs/a.txt first and 8 bytes reclaimable, got %+v", group)
+	}
+	for _, file := range group.Files {
+		if file.Kept != (file.Path == "docs/c.txt") {
+			t.Errorf("Expected only the referenced docs/c.txt kept, got %+v", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("Expected the report to delete nothing: %v", err)
+	}
+
+	deduped := report(http.MethodPost, "/duplicates/dedupe?prefix=docs")
+	if deduped.Deleted != 2 || deduped.Freed != 8 {
+		t.Fatalf("Expected 2 copies deleted and 8 bytes freed, got %+v", deduped)
+	}
+	for name, kept := range map[string]bool{"a.txt": false, "b.txt": false, "c.txt": true, "d.txt": true, "e.txt": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != kept {
+			t.Errorf("Expected %s kept: %v, got error %v", name, kept, err)
+		}
+	}
+}
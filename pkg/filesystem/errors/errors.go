@@ -1,290 +1,209 @@
+// Package errors exposes the gokit error types and constructors used by the
+// filesystem package. Errors created here are regular gokit AppErrors, so they
+// work with the response helpers and match the sentinels via errors.Is.
 package errors
 
 import (
-	"errors"
-	"fmt"
-	"net/http"
-	"reflect"
-	"strings"
-
-	"github.com/go-playground/validator/v10"
+	apperrors "github.com/anaknegeri/gokit/pkg/errors"
 )
 
 // Error codes for different error types
 const (
 	// Generic error codes
-	ErrCodeBadRequest         = "BAD_REQUEST"
-	ErrCodeUnauthorized       = "UNAUTHORIZED"
-	ErrCodeForbidden          = "FORBIDDEN"
-	ErrCodeNotFound           = "NOT_FOUND"
-	ErrCodeConflict           = "CONFLICT"
-	ErrCodeValidationError    = "VALIDATION_ERROR"
-	ErrCodeInternalError      = "INTERNAL_ERROR"
-	ErrCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	ErrCodeBadRequest         = apperrors.ErrCodeBadRequest
+	ErrCodeUnauthorized       = apperrors.ErrCodeUnauthorized
+	ErrCodeForbidden          = apperrors.ErrCodeForbidden
+	ErrCodeNotFound           = apperrors.ErrCodeNotFound
+	ErrCodeConflict           = apperrors.ErrCodeConflict
+	ErrCodeValidationError    = apperrors.ErrCodeValidationError
+	ErrCodeInternalError      = apperrors.ErrCodeInternalError
+	ErrCodeServiceUnavailable = apperrors.ErrCodeServiceUnavailable
 
 	// Filesystem specific error codes
-	ErrCodeFileNotFound       = "FILE_NOT_FOUND"
-	ErrCodeFileAlreadyExists  = "FILE_ALREADY_EXISTS"
-	ErrCodeFileTooLarge       = "FILE_TOO_LARGE"
-	ErrCodeInvalidFileType    = "INVALID_FILE_TYPE"
-	ErrCodeStorageUnavailable = "STORAGE_UNAVAILABLE"
-	ErrCodePermissionDenied   = "PERMISSION_DENIED"
-	ErrCodeQuotaExceeded      = "QUOTA_EXCEEDED"
-	ErrCodeInvalidPath        = "INVALID_PATH"
+	ErrCodeFileNotFound        = apperrors.ErrCodeFileNotFound
+	ErrCodeFileAlreadyExists   = apperrors.ErrCodeFileAlreadyExists
+	ErrCodeFileTooLarge        = apperrors.ErrCodeFileTooLarge
+	ErrCodeInvalidFileType     = apperrors.ErrCodeInvalidFileType
+	ErrCodeStorageUnavailable  = apperrors.ErrCodeStorageUnavailable
+	ErrCodePermissionDenied    = apperrors.ErrCodePermissionDenied
+	ErrCodeQuotaExceeded       = apperrors.ErrCodeQuotaExceeded
+	ErrCodeInvalidPath         = apperrors.ErrCodeInvalidPath
+	ErrCodeInsufficientStorage = apperrors.ErrCodeInsufficientStorage
+	ErrCodeFileRejected        = apperrors.ErrCodeFileRejected
+	ErrCodeDownloadFailed      = apperrors.ErrCodeDownloadFailed
 )
 
-// Map HTTP status codes to error codes
-var statusToErrorCode = map[int]string{
-	http.StatusBadRequest:          ErrCodeBadRequest,
-	http.StatusUnauthorized:        ErrCodeUnauthorized,
-	http.StatusForbidden:           ErrCodeForbidden,
-	http.StatusNotFound:            ErrCodeNotFound,
-	http.StatusConflict:            ErrCodeConflict,
-	http.StatusUnprocessableEntity: ErrCodeValidationError,
-	http.StatusInternalServerError: ErrCodeInternalError,
-	http.StatusServiceUnavailable:  ErrCodeServiceUnavailable,
-}
+// Sentinel errors for filesystem error codes
+var (
+	ErrFileNotFound        = apperrors.ErrFileNotFound
+	ErrFileAlreadyExists   = apperrors.ErrFileAlreadyExists
+	ErrFileTooLarge        = apperrors.ErrFileTooLarge
+	ErrInvalidFileType     = apperrors.ErrInvalidFileType
+	ErrStorageUnavailable  = apperrors.ErrStorageUnavailable
+	ErrPermissionDenied    = apperrors.ErrPermissionDenied
+	ErrQuotaExceeded       = apperrors.ErrQuotaExceeded
+	ErrInvalidPath         = apperrors.ErrInvalidPath
+	ErrInsufficientStorage = apperrors.ErrInsufficientStorage
+	ErrFileRejected        = apperrors.ErrFileRejected
+	ErrDownloadFailed      = apperrors.ErrDownloadFailed
+)
 
 // AppError represents an application error with detailed information
-type AppError struct {
-	Code     string      `json:"code"`
-	Message  string      `json:"message"`
-	Details  interface{} `json:"details,omitempty"`
-	HTTPCode int         `json:"-"`
-	Internal error       `json:"-"`
-}
-
-// Error implements the error interface for AppError
-func (e *AppError) Error() string {
-	if e.Internal != nil {
-		return fmt.Sprintf("[%s] %s: %v", e.Code, e.Message, e.Internal)
-	}
-	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
-}
-
-// Unwrap returns the wrapped error
-func (e *AppError) Unwrap() error {
-	return e.Internal
-}
+type AppError = apperrors.AppError
 
 // ErrorResponse is the structure for API error responses
-type ErrorResponse struct {
-	Success bool        `json:"success"`
-	Code    int         `json:"code"`
-	Error   string      `json:"error"`
-	Message string      `json:"message"`
-	Details interface{} `json:"details,omitempty"`
-}
+type ErrorResponse = apperrors.ErrorResponse
+
+// ValidationError represents a field validation error
+type ValidationError = apperrors.ValidationError
 
 // New creates a new standard error
 func New(message string) error {
-	return errors.New(message)
+	return apperrors.New(message)
 }
 
 // NewError creates a new AppError
 func NewError(httpCode int, message string) *AppError {
-	code, ok := statusToErrorCode[httpCode]
-	if !ok {
-		code = statusToErrorCode[http.StatusInternalServerError]
-	}
-
-	return &AppError{
-		Code:     code,
-		Message:  message,
-		HTTPCode: httpCode,
-	}
+	return apperrors.NewError(httpCode, message)
 }
 
 // NewErrorWithDetails creates a new AppError with additional details
 func NewErrorWithDetails(httpCode int, message string, details interface{}) *AppError {
-	err := NewError(httpCode, message)
-	err.Details = details
-	return err
+	return apperrors.NewErrorWithDetails(httpCode, message, details)
 }
 
 // NewCustomError creates a new AppError with a custom error code
 func NewCustomError(httpCode int, code string, message string) *AppError {
-	return &AppError{
-		Code:     code,
-		Message:  message,
-		HTTPCode: httpCode,
-	}
+	return apperrors.NewCustomError(httpCode, code, message)
 }
 
 // WrapError wraps an existing error with additional context
 func WrapError(err error, httpCode int, message string) *AppError {
-	appErr := NewError(httpCode, message)
-	appErr.Internal = err
-	return appErr
+	return apperrors.WrapError(err, httpCode, message)
+}
+
+// Wrapf wraps an existing error with a formatted message, which may wrap
+// more errors with %w
+func Wrapf(err error, httpCode int, format string, args ...interface{}) *AppError {
+	return apperrors.Wrapf(err, httpCode, format, args...)
+}
+
+// WithDetails returns a copy of the AppError in err's chain with details added
+func WithDetails(err error, details interface{}) *AppError {
+	return apperrors.WithDetails(err, details)
 }
 
 // WrapErrorWithCustomCode wraps an error with a custom error code
 func WrapErrorWithCustomCode(err error, httpCode int, code string, message string) *AppError {
-	appErr := NewCustomError(httpCode, code, message)
-	appErr.Internal = err
-	return appErr
+	return apperrors.WrapErrorWithCustomCode(err, httpCode, code, message)
 }
 
 // Is checks if an error is of a specific type
 func Is(err error, target error) bool {
-	return errors.Is(err, target)
+	return apperrors.Is(err, target)
 }
 
 // As attempts to convert an error to a specific type
 func As(err error, target interface{}) bool {
-	return errors.As(err, target)
-}
-
-// ValidationError represents a field validation error
-type ValidationError struct {
-	Field   string      `json:"field"`
-	Message string      `json:"message"`
-	Tag     string      `json:"tag,omitempty"`
-	Value   interface{} `json:"value,omitempty"`
-	Param   string      `json:"param,omitempty"`
+	return apperrors.As(err, target)
 }
 
 // ValidatorError processes validator.ValidationErrors into a consistent format
 func ValidatorError(err error) *AppError {
-	var validationErrors []ValidationError
-
-	if validationErrs, ok := err.(validator.ValidationErrors); ok {
-		for _, e := range validationErrs {
-			validationErrors = append(validationErrors, ValidationError{
-				Field:   formatFieldName(e.Field()),
-				Message: generateValidationMessage(e),
-				Tag:     e.Tag(),
-				Value:   e.Value(),
-				Param:   e.Param(),
-			})
-		}
-	}
-
-	return NewErrorWithDetails(
-		http.StatusUnprocessableEntity,
-		"Validation failed",
-		validationErrors,
-	)
+	return apperrors.ValidatorError(err)
 }
 
 // FormatErrorResponse formats an error into a consistent API response
 func FormatErrorResponse(err error) *ErrorResponse {
-	if appErr, ok := err.(*AppError); ok {
-		return &ErrorResponse{
-			Success: false,
-			Code:    appErr.HTTPCode,
-			Error:   appErr.Code,
-			Message: appErr.Message,
-			Details: appErr.Details,
-		}
-	}
-
-	// Default error handling if not an AppError
-	return &ErrorResponse{
-		Success: false,
-		Code:    http.StatusInternalServerError,
-		Error:   ErrCodeInternalError,
-		Message: err.Error(),
-	}
+	return apperrors.FormatErrorResponse(err)
 }
 
 // FileNotFoundError creates an error for file not found situations
 func FileNotFoundError(path string) *AppError {
-	return NewCustomError(
-		http.StatusNotFound,
-		ErrCodeFileNotFound,
-		fmt.Sprintf("File not found: %s", path),
-	)
+	return apperrors.FileNotFoundError(path)
 }
 
 // FileTooLargeError creates an error for files that exceed size limits
 func FileTooLargeError(size, maxSize int64) *AppError {
-	return NewCustomError(
-		http.StatusBadRequest,
-		ErrCodeFileTooLarge,
-		fmt.Sprintf("File size of %d bytes exceeds the maximum allowed size of %d bytes", size, maxSize),
-	)
+	return apperrors.FileTooLargeError(size, maxSize)
 }
 
 // InvalidFileTypeError creates an error for unsupported file types
 func InvalidFileTypeError(fileType string, allowedTypes []string) *AppError {
-	return NewErrorWithDetails(
-		http.StatusBadRequest,
-		fmt.Sprintf("File type '%s' is not allowed", fileType),
-		map[string]interface{}{
-			"fileType":     fileType,
-			"allowedTypes": allowedTypes,
-		},
-	)
+	return apperrors.InvalidFileTypeError(fileType, allowedTypes)
+}
+
+// FileAlreadyExistsError creates an error for when a file already exists
+func FileAlreadyExistsError(path string) *AppError {
+	return apperrors.FileAlreadyExistsError(path)
 }
 
 // StorageUnavailableError creates an error for when storage is unavailable
 func StorageUnavailableError(err error) *AppError {
-	return WrapErrorWithCustomCode(
-		err,
-		http.StatusServiceUnavailable,
-		ErrCodeStorageUnavailable,
-		"Storage service is currently unavailable",
-	)
+	return apperrors.StorageUnavailableError(err)
+}
+
+// InsufficientStorageError creates an error for an upload refused because
+// the storage is running out of space
+func InsufficientStorageError(size int64) *AppError {
+	return apperrors.InsufficientStorageError(size)
+}
+
+// FileRejectedError creates an error for an uploaded file refused by its
+// processing, e.g. a virus scan, with the reason in the details
+func FileRejectedError(path string, reason string) *AppError {
+	return apperrors.FileRejectedError(path, reason)
+}
+
+// DownloadFailedError creates an error for a remote file that could not be
+// downloaded, with the reason in the details
+func DownloadFailedError(url string, reason string) *AppError {
+	return apperrors.DownloadFailedError(url, reason)
+}
+
+// UnsupportedMediaTypeError creates an error for a content type that is not
+// accepted
+func UnsupportedMediaTypeError(contentType string) *AppError {
+	return apperrors.UnsupportedMediaTypeError(contentType)
+}
+
+// PermissionDeniedError creates an error for a permission the storage
+// refused, e.g. "s3:GetObject"
+func PermissionDeniedError(permission string) *AppError {
+	return apperrors.PermissionDeniedError(permission)
+}
+
+// InvalidPathError creates an error for invalid file paths
+func InvalidPathError(path string, reason string) *AppError {
+	return apperrors.InvalidPathError(path, reason)
+}
+
+// InvalidSortError creates an error for a sort field that is not allowed
+func InvalidSortError(field string, allowed []string) *AppError {
+	return apperrors.InvalidSortError(field, allowed)
+}
+
+// InvalidCursorError creates an error for a list cursor that cannot be decoded
+func InvalidCursorError() *AppError {
+	return apperrors.InvalidCursorError()
+}
+
+// ForbiddenError creates an error for operations the caller may not make
+func ForbiddenError(message string) *AppError {
+	return apperrors.ForbiddenError(message)
+}
+
+// UnauthorizedError creates an error for operations that need a signed in user
+func UnauthorizedError(message string) *AppError {
+	return apperrors.UnauthorizedError(message)
 }
 
-// formatFieldName converts field names to camelCase
-func formatFieldName(field string) string {
-	return strings.ToLower(field[:1]) + field[1:]
+// InvalidTokenError creates an error for a token that cannot be verified
+func InvalidTokenError() *AppError {
+	return apperrors.InvalidTokenError()
 }
 
-// generateValidationMessage generates user-friendly validation messages
-func generateValidationMessage(fe validator.FieldError) string {
-	switch fe.Tag() {
-	case "required":
-		return fmt.Sprintf("%s is required", fe.Field())
-	case "email":
-		return "Invalid email format"
-	case "min":
-		if fe.Kind() == reflect.String {
-			return fmt.Sprintf("%s must be at least %s characters long", fe.Field(), fe.Param())
-		}
-		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
-	case "max":
-		if fe.Kind() == reflect.String {
-			return fmt.Sprintf("%s must not exceed %s characters", fe.Field(), fe.Param())
-		}
-		return fmt.Sprintf("%s must not exceed %s", fe.Field(), fe.Param())
-	case "uuid":
-		return fmt.Sprintf("%s must be a valid UUID", fe.Field())
-	case "oneof":
-		return fmt.Sprintf("%s must be one of [%s]", fe.Field(), fe.Param())
-	case "unique":
-		return fmt.Sprintf("%s must be unique", fe.Field())
-	case "numeric":
-		return fmt.Sprintf("%s must be numeric", fe.Field())
-	case "json":
-		return fmt.Sprintf("%s must be valid JSON", fe.Field())
-	case "url":
-		return fmt.Sprintf("%s must be a valid URL", fe.Field())
-	case "gt":
-		return fmt.Sprintf("%s must be greater than %s", fe.Field(), fe.Param())
-	case "lt":
-		return fmt.Sprintf("%s must be less than %s", fe.Field(), fe.Param())
-	case "gte":
-		return fmt.Sprintf("%s must be greater than or equal to %s", fe.Field(), fe.Param())
-	case "lte":
-		return fmt.Sprintf("%s must be less than or equal to %s", fe.Field(), fe.Param())
-	case "alpha":
-		return fmt.Sprintf("%s must contain only letters", fe.Field())
-	case "alphanum":
-		return fmt.Sprintf("%s must contain only letters and numbers", fe.Field())
-	case "datetime":
-		return fmt.Sprintf("%s must be a valid datetime", fe.Field())
-	case "file":
-		return fmt.Sprintf("%s must be a valid file", fe.Field())
-	case "image":
-		return fmt.Sprintf("%s must be a valid image", fe.Field())
-	case "mime":
-		return fmt.Sprintf("%s must be of type %s", fe.Field(), fe.Param())
-	case "password":
-		return fmt.Sprintf("%s must meet password requirements", fe.Field())
-	default:
-		return fmt.Sprintf("%s failed validation for tag %s", fe.Field(), fe.Tag())
-	}
+// TokenExpiredError creates an error for an expired token
+func TokenExpiredError() *AppError {
+	return apperrors.TokenExpiredError()
 }
@@ -0,0 +1,100 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+
+	"github.com/anaknegeri/gokit/pkg/events"
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// FileEvent is the data of the file events published by EventStorage
+type FileEvent struct {
+	Path string    `json:"path"`
+	File *FileInfo `json:"file,omitempty"`
+}
+
+// File events published by EventStorage, e.g. to create thumbnails:
+//
+//	filesystem.FileUploaded.Subscribe(bus, func(ctx context.Context, e filesystem.FileEvent) error {
+//		return thumbnails.Create(ctx, e.Path)
+//	}, events.SubscribeConfig{Group: "thumbnails"})
+var (
+	FileUploaded = events.NewTopic[FileEvent]("filesystem.file.uploaded")
+	FileDeleted  = events.NewTopic[FileEvent]("filesystem.file.deleted")
+)
+
+// EventStorage publishes FileUploaded and FileDeleted events for the uploads
+// and deletes made through it
+type EventStorage struct {
+	storage Storage
+	bus     *events.Bus
+}
+
+// NewEventStorage wraps a storage to publish file events on a bus
+func NewEventStorage(storage Storage, bus *events.Bus) *EventStorage {
+	return &EventStorage{storage: storage, bus: bus}
+}
+
+// Upload saves a file and publishes FileUploaded. A failure to publish is
+// logged and does not fail the upload, which has already been saved.
+func (s *EventStorage) Upload(ctx context.Context, file *multipart.FileHeader, filePath string) (*FileInfo, error) {
+	info, err := s.storage.Upload(ctx, file, filePath)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, FileUploaded, FileEvent{Path: filePath, File: info})
+	return info, nil
+}
+
+// Get retrieves a file from the storage
+func (s *EventStorage) Get(ctx context.Context, filePath string) (io.ReadCloser, *FileInfo, error) {
+	return s.storage.Get(ctx, filePath)
+}
+
+// Delete removes a file and publishes FileDeleted
+func (s *EventStorage) Delete(ctx context.Context, filePath string) error {
+	if err := s.storage.Delete(ctx, filePath); err != nil {
+		return err
+	}
+	s.publish(ctx, FileDeleted, FileEvent{Path: filePath})
+	return nil
+}
+
+// Copy copies a file and publishes FileUploaded for the copy
+func (s *EventStorage) Copy(ctx context.Context, src, dst string) (*FileInfo, error) {
+	info, err := copyFile(ctx, s.storage, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, FileUploaded, FileEvent{Path: dst, File: info})
+	return info, nil
+}
+
+// Exists checks if a file exists
+func (s *EventStorage) Exists(ctx context.Context, filePath string) (bool, error) {
+	return s.storage.Exists(ctx, filePath)
+}
+
+// List returns a list of files from a directory
+func (s *EventStorage) List(ctx context.Context, filePath string) ([]FileInfo, error) {
+	return s.storage.List(ctx, filePath)
+}
+
+// GetInfo returns information about a file without fetching its contents
+func (s *EventStorage) GetInfo(ctx context.Context, filePath string) (*FileInfo, error) {
+	return s.storage.GetInfo(ctx, filePath)
+}
+
+// ListPage returns a page of a directory listing when the storage supports it
+func (s *EventStorage) ListPage(ctx context.Context, filePath string, opts ListOptions) (*ListPage, error) {
+	return NewProvider(s.storage).ListPage(ctx, filePath, opts)
+}
+
+// publish publishes a file event, logging failures
+func (s *EventStorage) publish(ctx context.Context, topic events.Topic[FileEvent], event FileEvent) {
+	if err := topic.Publish(ctx, s.bus, event); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to publish %s for %s: %v", topic.Name, event.Path, err)
+	}
+}
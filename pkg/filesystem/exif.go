@@ -0,0 +1,350 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"io"
+	"mime/multipart"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EXIF tags read from photos
+const (
+	exifTagOrientation        = 0x0112
+	exifTagDateTime           = 0x0132
+	exifTagExifIFD            = 0x8769
+	exifTagGPSIFD             = 0x8825
+	exifTagDateTimeOriginal   = 0x9003
+	exifTagOffsetTimeOriginal = 0x9011
+	gpsTagLatitudeRef         = 0x0001
+	gpsTagLatitude            = 0x0002
+	gpsTagLongitudeRef        = 0x0003
+	gpsTagLongitude           = 0x0004
+)
+
+// exifTypeSizes are the sizes of the EXIF value types, by type
+var exifTypeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// ExifInfo is what the EXIF data of a photo tells
+type ExifInfo struct {
+	// Orientation is the EXIF orientation, 1 to 8, or 0 when missing
+	Orientation int
+
+	// TakenAt is when the photo was taken, in UTC unless the camera
+	// recorded its offset, or zero when missing
+	TakenAt time.Time
+
+	// HasGPS is set when the photo has a location
+	HasGPS    bool
+	Latitude  float64
+	Longitude float64
+}
+
+// exifIFD is a directory of EXIF entries in the TIFF structure of the data
+type exifIFD struct {
+	tiff    []byte
+	order   binary.ByteOrder
+	offset  uint32
+	entries map[uint16]exifEntry
+}
+
+// exifEntry is an entry of an IFD
+type exifEntry struct {
+	typ    uint16
+	count  uint32
+	at     uint32 // offset of the entry in the TIFF data
+	values uint32 // offset of the values in the TIFF data
+}
+
+// ReadExif reads the EXIF data of a JPEG image, returning nil for images
+// without it and for other formats
+func ReadExif(data []byte) *ExifInfo {
+	tiff := jpegExif(data)
+	if tiff == nil {
+		return nil
+	}
+	ifd0, order := readTIFF(tiff)
+	if ifd0 == nil {
+		return nil
+	}
+
+	info := &ExifInfo{}
+	if v, ok := ifd0.short(exifTagOrientation); ok && v >= 1 && v <= 8 {
+		info.Orientation = int(v)
+	}
+
+	takenAt, offset := ifd0.ascii(exifTagDateTime), ""
+	if exif := ifd0.sub(exifTagExifIFD, order); exif != nil {
+		if original := exif.ascii(exifTagDateTimeOriginal); original != "" {
+			takenAt = original
+			offset = exif.ascii(exifTagOffsetTimeOriginal)
+		}
+	}
+	if t, err := time.Parse("2006:01:02 15:04:05-07:00", takenAt+offset); err == nil {
+		info.TakenAt = t
+	} else if t, err := time.Parse("2006:01:02 15:04:05", takenAt); err == nil {
+		info.TakenAt = t
+	}
+
+	if gps := ifd0.sub(exifTagGPSIFD, order); gps != nil {
+		lat, latOK := gps.degrees(gpsTagLatitude)
+		lon, lonOK := gps.degrees(gpsTagLongitude)
+		if latOK && lonOK {
+			if gps.ascii(gpsTagLatitudeRef) == "S" {
+				lat = -lat
+			}
+			if gps.ascii(gpsTagLongitudeRef) == "W" {
+				lon = -lon
+			}
+			info.HasGPS, info.Latitude, info.Longitude = true, lat, lon
+		}
+	}
+	return info
+}
+
+// StripGPS returns a copy of a JPEG image with the location removed from its
+// EXIF data, keeping the rest such as the orientation. Other images are
+// returned as they are.
+func StripGPS(data []byte) []byte {
+	stripped := bytes.Clone(data)
+	tiff := jpegExif(stripped)
+	if tiff == nil {
+		return data
+	}
+	ifd0, order := readTIFF(tiff)
+	if ifd0 == nil {
+		return data
+	}
+	gps := ifd0.sub(exifTagGPSIFD, order)
+	if gps == nil {
+		return data
+	}
+
+	// Clear the values and entries in place, so no offset moves, and leave
+	// an empty directory
+	for _, entry := range gps.entries {
+		size := exifTypeSizes[entry.typ] * entry.count
+		if size > 4 && uint64(entry.values)+uint64(size) <= uint64(len(tiff)) {
+			clear(tiff[entry.values : entry.values+size])
+		}
+		clear(tiff[entry.at : entry.at+12])
+	}
+	order.PutUint16(tiff[gps.offset:], 0)
+	return stripped
+}
+
+// jpegExif returns the TIFF data of the EXIF segment of a JPEG image, or
+// nil without one
+func jpegExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// The image data starts without an EXIF segment before it
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i = end
+	}
+	return nil
+}
+
+// readTIFF reads the first IFD of TIFF data
+func readTIFF(tiff []byte) (*exifIFD, binary.ByteOrder) {
+	if len(tiff) < 8 {
+		return nil, nil
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return nil, nil
+	}
+	return readIFD(tiff, order, order.Uint32(tiff[4:])), order
+}
+
+// readIFD reads the IFD at an offset of TIFF data, or returns nil when it
+// does not fit
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) *exifIFD {
+	if offset < 8 || uint64(offset)+2 > uint64(len(tiff)) {
+		return nil
+	}
+	count := uint32(order.Uint16(tiff[offset:]))
+	if uint64(offset)+2+uint64(count)*12 > uint64(len(tiff)) {
+		return nil
+	}
+
+	ifd := &exifIFD{tiff: tiff, order: order, offset: offset, entries: make(map[uint16]exifEntry, count)}
+	for i := uint32(0); i < count; i++ {
+		at := offset + 2 + i*12
+		entry := exifEntry{
+			typ:   order.Uint16(tiff[at+2:]),
+			count: order.Uint32(tiff[at+4:]),
+			at:    at,
+		}
+		if exifTypeSizes[entry.typ]*entry.count > 4 {
+			entry.values = order.Uint32(tiff[at+8:])
+		} else {
+			entry.values = at + 8
+		}
+		ifd.entries[order.Uint16(tiff[at:])] = entry
+	}
+	return ifd
+}
+
+// value returns the bytes of the values of an entry of a type, checking
+// they fit in the data
+func (ifd *exifIFD) value(tag uint16, typ uint16) ([]byte, exifEntry, bool) {
+	entry, ok := ifd.entries[tag]
+	if !ok || entry.typ != typ || entry.count == 0 {
+		return nil, entry, false
+	}
+	size := uint64(exifTypeSizes[typ]) * uint64(entry.count)
+	if uint64(entry.values)+size > uint64(len(ifd.tiff)) {
+		return nil, entry, false
+	}
+	return ifd.tiff[entry.values : uint64(entry.values)+size], entry, true
+}
+
+// short returns the first SHORT value of an entry
+func (ifd *exifIFD) short(tag uint16) (uint16, bool) {
+	b, _, ok := ifd.value(tag, 3)
+	if !ok {
+		return 0, false
+	}
+	return ifd.order.Uint16(b), true
+}
+
+// ascii returns the ASCII value of an entry, without its terminating NUL
+func (ifd *exifIFD) ascii(tag uint16) string {
+	b, _, ok := ifd.value(tag, 2)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+}
+
+// degrees returns the degrees, minutes, and seconds RATIONAL values of a
+// GPS entry in decimal degrees
+func (ifd *exifIFD) degrees(tag uint16) (float64, bool) {
+	b, entry, ok := ifd.value(tag, 5)
+	if !ok || entry.count < 3 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		num := ifd.order.Uint32(b[i*8:])
+		den := ifd.order.Uint32(b[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+	return parts[0] + parts[1]/60 + parts[2]/3600, true
+}
+
+// sub returns the IFD an entry points to, such as the EXIF or GPS IFD
+func (ifd *exifIFD) sub(tag uint16, order binary.ByteOrder) *exifIFD {
+	b, _, ok := ifd.value(tag, 4)
+	if !ok {
+		return nil
+	}
+	return readIFD(ifd.tiff, order, order.Uint32(b))
+}
+
+// stripGPS returns an uploaded JPEG with its location removed
+func stripGPS(file *multipart.FileHeader) (*multipart.FileHeader, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return nil, err
+	}
+	return newFileHeader(file.Filename, StripGPS(data))
+}
+
+// isJPEG reports whether a file is a JPEG image by its name
+func isJPEG(filename string) bool {
+	ext := strings.ToLower(path.Ext(filename))
+	return ext == ".jpg" || ext == ".jpeg"
+}
+
+// ExifProcessor is a processor recording what images tell about themselves
+// in their metadata: "width" and "height" for GIF, JPEG, and PNG images,
+// and for JPEG photos with EXIF data "orientation", "takenAt" (RFC 3339),
+// and "gpsLatitude" and "gpsLongitude". To keep locations private, strip
+// them before storage with UploadPolicy.StripGPS. Its name is "exif".
+type ExifProcessor struct{}
+
+// NewExifProcessor creates a processor reading image metadata
+func NewExifProcessor() *ExifProcessor {
+	return &ExifProcessor{}
+}
+
+// Name returns "exif"
+func (p *ExifProcessor) Name() string {
+	return "exif"
+}
+
+// Process reads the dimensions and EXIF data of an image
+func (p *ExifProcessor) Process(ctx context.Context, file *ProcessFile) error {
+	if !strings.HasPrefix(file.Info.ContentType, "image/") && !isJPEG(file.Path) {
+		return nil
+	}
+	data, err := file.ReadAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		file.Metadata["width"] = strconv.Itoa(config.Width)
+		file.Metadata["height"] = strconv.Itoa(config.Height)
+	}
+
+	info := ReadExif(data)
+	if info == nil {
+		return nil
+	}
+	if info.Orientation > 0 {
+		file.Metadata["orientation"] = strconv.Itoa(info.Orientation)
+	}
+	if !info.TakenAt.IsZero() {
+		file.Metadata["takenAt"] = info.TakenAt.Format(time.RFC3339)
+	}
+	if info.HasGPS {
+		file.Metadata["gpsLatitude"] = strconv.FormatFloat(info.Latitude, 'f', 6, 64)
+		file.Metadata["gpsLongitude"] = strconv.FormatFloat(info.Longitude, 'f', 6, 64)
+	}
+	return nil
+}
@@ -6,10 +6,12 @@ import (
 	"net/http"
 
 	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/lifecycle"
 	"github.com/aws/aws-sdk-go-v2/config"
 )
 
-// NewStorageProvider creates a storage provider based on the provided configuration
+// NewStorageProvider creates a storage provider based on the provided
+// configuration, registered with the default lifecycle manager
 func NewStorageProvider(ctx context.Context, cfg Config) (*Provider, error) {
 	// Validate config
 	if errors := cfg.Validate(); len(errors) > 0 {
@@ -32,18 +34,30 @@ func NewStorageProvider(ctx context.Context, cfg Config) (*Provider, error) {
 				Endpoint:     cfg.S3Endpoint,
 				AccessKey:    cfg.S3AccessKey,
 				SecretKey:    cfg.S3SecretKey,
+				Credentials:  cfg.S3Credentials,
 				Bucket:       cfg.S3Bucket,
 				BasePrefix:   cfg.S3BasePrefix,
 				BaseURL:      cfg.S3BaseURL,
 				Region:       cfg.S3Region,
 				UseSSL:       cfg.S3UseSSL,
 				UsePathStyle: cfg.S3PathStyle,
+
+				MaxListEntries:  cfg.S3MaxListEntries,
+				StartupTimeout:  cfg.S3StartupTimeout,
+				LazyConnect:     cfg.S3LazyConnect,
+				SkipBucketCheck: cfg.S3SkipBucketCheck,
 			}
 		} else {
 			// Standard AWS S3
-			awsCfg, err := config.LoadDefaultConfig(ctx,
+			timeout := cfg.S3StartupTimeout
+			if timeout <= 0 {
+				timeout = DefaultS3StartupTimeout
+			}
+			loadCtx, cancel := context.WithTimeout(ctx, timeout)
+			awsCfg, err := config.LoadDefaultConfig(loadCtx,
 				config.WithRegion(cfg.S3Region),
 			)
+			cancel()
 			if err != nil {
 				return nil, fserrors.WrapError(
 					err,
@@ -53,15 +67,21 @@ func NewStorageProvider(ctx context.Context, cfg Config) (*Provider, error) {
 			}
 
 			s3Config = S3Config{
-				AWSConfig:  awsCfg,
-				Bucket:     cfg.S3Bucket,
-				BasePrefix: cfg.S3BasePrefix,
-				BaseURL:    cfg.S3BaseURL,
-				Region:     cfg.S3Region,
+				AWSConfig:   awsCfg,
+				Credentials: cfg.S3Credentials,
+				Bucket:      cfg.S3Bucket,
+				BasePrefix:  cfg.S3BasePrefix,
+				BaseURL:     cfg.S3BaseURL,
+				Region:      cfg.S3Region,
+
+				MaxListEntries:  cfg.S3MaxListEntries,
+				StartupTimeout:  cfg.S3StartupTimeout,
+				LazyConnect:     cfg.S3LazyConnect,
+				SkipBucketCheck: cfg.S3SkipBucketCheck,
 			}
 		}
 
-		s3Storage, err := NewS3Storage(s3Config)
+		s3Storage, err := NewS3StorageContext(ctx, s3Config)
 		if err != nil {
 			return nil, err
 		}
@@ -73,6 +93,8 @@ func NewStorageProvider(ctx context.Context, cfg Config) (*Provider, error) {
 			BasePath:          cfg.LocalStoragePath,
 			BaseURL:           cfg.LocalBaseURL,
 			CreateDirectories: cfg.CreateLocalDirs,
+			MinFreeBytes:      int64(cfg.LocalMinFreeMB) * 1024 * 1024,
+			Symlinks:          SymlinkPolicy(cfg.LocalSymlinks),
 		}
 
 		localStorage, err := NewLocalStorage(localConfig)
@@ -92,10 +114,58 @@ func NewStorageProvider(ctx context.Context, cfg Config) (*Provider, error) {
 		)
 	}
 
-	provider := NewProvider(storage)
+	if cfg.TenantScoped {
+		storage = NewTenantStorage(storage)
+	}
+
+	cdn, err := newCDN(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Uploads in flight finish before the process exits
+	provider := NewProvider(storage, ProviderConfig{CDN: cdn, Metadata: cfg.Metadata, Indexer: cfg.Indexer})
+	lifecycle.Register("storage", provider)
 	return provider, nil
 }
 
+// newCDN creates the CDN of the configuration, or nil without one. The
+// origin of the CDN is the bucket root or the local storage directory.
+func newCDN(ctx context.Context, cfg Config) (CDN, error) {
+	if cfg.CDN != nil {
+		return cfg.CDN, nil
+	}
+	if cfg.CDNBaseURL == "" {
+		return nil, nil
+	}
+
+	prefix := ""
+	if cfg.StorageType == "s3" {
+		prefix = cfg.S3BasePrefix
+	}
+	if cfg.CloudFrontDistributionID == "" {
+		return NewGenericCDN(GenericCDNConfig{
+			BaseURL:  cfg.CDNBaseURL,
+			Prefix:   prefix,
+			PurgeURL: cfg.CDNPurgeURL,
+		}), nil
+	}
+
+	cloudFront, err := NewCloudFront(ctx, CloudFrontConfig{
+		DistributionID: cfg.CloudFrontDistributionID,
+		BaseURL:        cfg.CDNBaseURL,
+		Prefix:         prefix,
+	})
+	if err != nil {
+		return nil, fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to initialize CloudFront",
+		)
+	}
+	return cloudFront, nil
+}
+
 // GetUploadHandlerConfig creates a handler configuration from the filesystem config
 func GetUploadHandlerConfig(provider *Provider, cfg Config) UploadHandlerConfig {
 	handlerConfig := UploadHandlerConfig{
@@ -105,6 +175,19 @@ func GetUploadHandlerConfig(provider *Provider, cfg Config) UploadHandlerConfig
 		MaxFileSize:  cfg.UploadMaxSizeMB * 1024 * 1024,
 		UseUUID:      cfg.UseUUID,
 		TimeoutSecs:  cfg.TimeoutSecs,
+		Rules:        cfg.UploadRules,
+		StripGPS:     cfg.UploadStripGPS,
+		Dispositions: cfg.Dispositions,
+	}
+	if len(cfg.UploadMaxSizesMB) > 0 {
+		handlerConfig.MaxFileSizes = make(map[string]int, len(cfg.UploadMaxSizesMB))
+		for key, size := range cfg.UploadMaxSizesMB {
+			handlerConfig.MaxFileSizes[key] = size * 1024 * 1024
+		}
+	}
+	if cfg.Naming != "" {
+		// Checked by Validate
+		handlerConfig.Naming, _ = ParseNamingStrategy(cfg.Naming)
 	}
 
 	return handlerConfig
@@ -2,9 +2,18 @@ package filesystem
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
 )
 
 // FileInfo represents metadata about a file
@@ -15,6 +24,10 @@ type FileInfo struct {
 	URL          string    `json:"url"`
 	ContentType  string    `json:"contentType,omitempty"`
 	IsDirectory  bool      `json:"isDirectory,omitempty"`
+
+	// Metadata holds what is known about the file besides its storage,
+	// such as the status of its processing, see MetadataStore
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // Storage defines the interface that must be implemented by storage providers
@@ -38,31 +51,271 @@ type Storage interface {
 	GetInfo(ctx context.Context, path string) (*FileInfo, error)
 }
 
+// ListOptions selects a page of a directory listing
+type ListOptions struct {
+	// Limit is the maximum number of entries, or 0 for the storage default
+	Limit int
+
+	// Token continues a listing from the NextToken of the previous page
+	Token string
+}
+
+// ListPage is one page of a directory listing
+type ListPage struct {
+	Files []FileInfo
+
+	// NextToken continues the listing, and is empty on the last page
+	NextToken string
+}
+
+// Truncated reports whether entries remain after the page
+func (p *ListPage) Truncated() bool {
+	return p.NextToken != ""
+}
+
+// PagedLister is implemented by storages that can list a directory one page
+// at a time, such as S3 with continuation tokens
+type PagedLister interface {
+	ListPage(ctx context.Context, path string, opts ListOptions) (*ListPage, error)
+}
+
+// Copier is implemented by storages that copy a file without downloading
+// it, such as S3 with CopyObject. Like Upload, Copy refuses to replace an
+// existing file.
+type Copier interface {
+	Copy(ctx context.Context, src, dst string) (*FileInfo, error)
+}
+
+// ErrShuttingDown is the internal error of uploads and deletes refused during
+// Shutdown
+var ErrShuttingDown = fserrors.New("filesystem: shutting down")
+
+// ProviderConfig configures a provider
+type ProviderConfig struct {
+	// CDN serves the files when set: the URLs of FileInfo are its URLs, and
+	// deleted or replaced files are invalidated
+	CDN CDN
+
+	// Metadata keeps the metadata of the files, returned by Get and GetInfo
+	// and removed with the files
+	Metadata MetadataStore
+
+	// Indexer indexes the files for Search, e.g. through an IndexProcessor,
+	// and has deleted files removed
+	Indexer Indexer
+}
+
 // Provider represents the filesystem provider that wraps a storage implementation
 type Provider struct {
 	storage Storage
+	config  ProviderConfig
+
+	mu      sync.Mutex
+	closing bool
+	writes  sync.WaitGroup
 }
 
 // NewProvider creates a new filesystem provider with the specified storage
-func NewProvider(storage Storage) *Provider {
-	return &Provider{
+func NewProvider(storage Storage, config ...ProviderConfig) *Provider {
+	p := &Provider{
 		storage: storage,
 	}
+	if len(config) > 0 {
+		p.config = config[0]
+	}
+	return p
 }
 
 // Upload uploads a file to the storage
 func (p *Provider) Upload(ctx context.Context, file *multipart.FileHeader, path string) (*FileInfo, error) {
-	return p.storage.Upload(ctx, file, path)
+	if err := p.beginWrite(); err != nil {
+		return nil, err
+	}
+	defer p.writes.Done()
+
+	// Storages that replace files leave the old copy on the CDN
+	replaced := false
+	if p.config.CDN != nil {
+		replaced, _ = p.storage.Exists(ctx, path)
+	}
+
+	info, err := p.storage.Upload(ctx, file, path)
+	if err != nil {
+		return nil, err
+	}
+	if replaced {
+		p.invalidate(ctx, path)
+	}
+	p.clearMetadata(ctx, path)
+	p.cdnURL(path, info)
+	return info, nil
+}
+
+// replace replaces the contents of a file, keeping its metadata and the
+// files derived from it
+func (p *Provider) replace(ctx context.Context, file *multipart.FileHeader, path string) (*FileInfo, error) {
+	if err := p.beginWrite(); err != nil {
+		return nil, err
+	}
+	defer p.writes.Done()
+
+	if err := p.storage.Delete(ctx, path); err != nil {
+		return nil, err
+	}
+	info, err := p.storage.Upload(ctx, file, path)
+	if err != nil {
+		return nil, err
+	}
+	p.invalidate(ctx, path)
+	if err := p.loadMetadata(ctx, path, info); err != nil {
+		return nil, err
+	}
+	p.cdnURL(path, info)
+	return info, nil
 }
 
 // Get retrieves a file from storage
 func (p *Provider) Get(ctx context.Context, path string) (io.ReadCloser, *FileInfo, error) {
-	return p.storage.Get(ctx, path)
+	file, info, err := p.storage.Get(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := p.loadMetadata(ctx, path, info); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	p.cdnURL(path, info)
+	return file, info, nil
+}
+
+// Copy copies a file to a path where no file exists yet, with its metadata
+// but not the files derived from it by a pipeline. Storages that implement
+// Copier copy it without downloading it.
+func (p *Provider) Copy(ctx context.Context, src, dst string) (*FileInfo, error) {
+	if err := p.beginWrite(); err != nil {
+		return nil, err
+	}
+	defer p.writes.Done()
+
+	return p.copy(ctx, src, dst, false)
+}
+
+// Move moves a file to a path where no file exists yet, with its metadata
+// and the files derived from it. The file is copied and then deleted, so
+// when the delete fails the file is at both paths.
+func (p *Provider) Move(ctx context.Context, src, dst string) (*FileInfo, error) {
+	if err := p.beginWrite(); err != nil {
+		return nil, err
+	}
+	defer p.writes.Done()
+
+	info, err := p.copy(ctx, src, dst, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.storage.Delete(ctx, src); err != nil {
+		return nil, err
+	}
+	p.clearMetadata(ctx, src)
+	p.unindex(ctx, src)
+	p.invalidate(ctx, src)
+	return info, nil
 }
 
-// Delete removes a file from storage
+// copy copies a file and its metadata, listing the files derived from it
+// in the metadata of the copy when it takes them over
+func (p *Provider) copy(ctx context.Context, src, dst string, withDerived bool) (*FileInfo, error) {
+	if NormalizeKey(src) == NormalizeKey(dst) {
+		return nil, fserrors.InvalidPathError(dst, "destination is the source file")
+	}
+	exists, err := p.storage.Exists(ctx, dst)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fserrors.NewCustomError(
+			http.StatusConflict,
+			fserrors.ErrCodeFileAlreadyExists,
+			fmt.Sprintf("File already exists: %s", dst),
+		)
+	}
+
+	metadata, err := p.Metadata(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	info, err := copyFile(ctx, p.storage, src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	p.clearMetadata(ctx, dst)
+	if !withDerived {
+		delete(metadata, derivedKey)
+	}
+	if len(metadata) > 0 {
+		if err := p.config.Metadata.SetMetadata(ctx, dst, metadata); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to copy the metadata of %s to %s: %v", src, dst, err)
+		} else {
+			info.Metadata = metadata
+		}
+	}
+	p.cdnURL(dst, info)
+	return info, nil
+}
+
+// copyFile copies a file with the Copier of a storage, or by downloading
+// it to a temporary file and uploading it again
+func copyFile(ctx context.Context, storage Storage, src, dst string) (*FileInfo, error) {
+	if copier, ok := storage.(Copier); ok {
+		return copier.Copy(ctx, src, dst)
+	}
+
+	reader, _, err := storage.Get(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	file, remove, err := newFileHeaderFrom(path.Base(dst), reader)
+	if err != nil {
+		return nil, fserrors.WrapError(err, http.StatusInternalServerError, fmt.Sprintf("Failed to read file: %s", src))
+	}
+	defer remove()
+	return storage.Upload(ctx, file, dst)
+}
+
+// Delete removes a file from storage, with the files derived from it by a
+// pipeline when there is a metadata store
 func (p *Provider) Delete(ctx context.Context, path string) error {
-	return p.storage.Delete(ctx, path)
+	if err := p.beginWrite(); err != nil {
+		return err
+	}
+	defer p.writes.Done()
+
+	var derived []string
+	if p.config.Metadata != nil {
+		if metadata, err := p.config.Metadata.Metadata(ctx, path); err == nil {
+			derived = derivedPaths(metadata)
+		}
+	}
+
+	if err := p.storage.Delete(ctx, path); err != nil {
+		return err
+	}
+	p.clearMetadata(ctx, path)
+	p.unindex(ctx, path)
+	p.invalidate(ctx, path)
+
+	for _, derivedPath := range derived {
+		if err := p.storage.Delete(ctx, derivedPath); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to delete %s, derived from %s: %v", derivedPath, path, err)
+			continue
+		}
+		p.clearMetadata(ctx, derivedPath)
+		p.invalidate(ctx, derivedPath)
+	}
+	return nil
 }
 
 // Exists checks if a file exists
@@ -72,10 +325,258 @@ func (p *Provider) Exists(ctx context.Context, path string) (bool, error) {
 
 // List returns a list of files from a directory
 func (p *Provider) List(ctx context.Context, path string) ([]FileInfo, error) {
-	return p.storage.List(ctx, path)
+	files, err := p.storage.List(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	p.cdnURLs(path, files)
+	return files, nil
+}
+
+// ListPage returns a page of the files in a directory. Storages that do not
+// implement PagedLister are listed in full and paged with offset tokens.
+func (p *Provider) ListPage(ctx context.Context, path string, opts ListOptions) (*ListPage, error) {
+	page, err := p.listPage(ctx, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.cdnURLs(path, page.Files)
+	return page, nil
+}
+
+// listPage returns a page of the files in a directory from the storage
+func (p *Provider) listPage(ctx context.Context, path string, opts ListOptions) (*ListPage, error) {
+	if lister, ok := p.storage.(PagedLister); ok {
+		return lister.ListPage(ctx, path, opts)
+	}
+
+	offset := 0
+	if opts.Token != "" {
+		var err error
+		offset, err = strconv.Atoi(opts.Token)
+		if err != nil || offset < 0 {
+			return nil, fserrors.InvalidCursorError()
+		}
+	}
+
+	files, err := p.storage.List(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if offset > len(files) {
+		offset = len(files)
+	}
+
+	page := &ListPage{Files: files[offset:]}
+	if opts.Limit > 0 && len(page.Files) > opts.Limit {
+		page.Files = page.Files[:opts.Limit]
+		page.NextToken = strconv.Itoa(offset + opts.Limit)
+	}
+	return page, nil
+}
+
+// ListAll returns every file in a directory, following the pages of storages
+// that implement PagedLister
+func (p *Provider) ListAll(ctx context.Context, path string) ([]FileInfo, error) {
+	lister, ok := p.storage.(PagedLister)
+	if !ok {
+		return p.List(ctx, path)
+	}
+
+	var files []FileInfo
+	opts := ListOptions{}
+	for {
+		page, err := lister.ListPage(ctx, path, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, page.Files...)
+		if page.NextToken == "" {
+			p.cdnURLs(path, files)
+			return files, nil
+		}
+		opts.Token = page.NextToken
+	}
+}
+
+// Walk calls fn with the path and information of every file under a
+// directory and its subdirectories, in name order. Directories are not passed
+// to fn. An error returned by fn stops the walk and is returned.
+func (p *Provider) Walk(ctx context.Context, dir string, fn func(filePath string, info FileInfo) error) error {
+	files, err := p.ListAll(ctx, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		filePath := path.Join(dir, file.Name)
+		if file.IsDirectory {
+			err = p.Walk(ctx, filePath, fn)
+		} else {
+			err = fn(filePath, file)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetInfo returns information about a file without fetching its contents
 func (p *Provider) GetInfo(ctx context.Context, path string) (*FileInfo, error) {
-	return p.storage.GetInfo(ctx, path)
+	info, err := p.storage.GetInfo(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.loadMetadata(ctx, path, info); err != nil {
+		return nil, err
+	}
+	p.cdnURL(path, info)
+	return info, nil
+}
+
+// Metadata returns the metadata of a file from the metadata store, empty
+// without one
+func (p *Provider) Metadata(ctx context.Context, path string) (map[string]string, error) {
+	if p.config.Metadata == nil {
+		return map[string]string{}, nil
+	}
+	return p.config.Metadata.Metadata(ctx, path)
+}
+
+// SetMetadata merges values into the metadata of a file, removing the keys
+// with an empty value. It fails without a metadata store.
+func (p *Provider) SetMetadata(ctx context.Context, path string, values map[string]string) error {
+	if p.config.Metadata == nil {
+		return fserrors.New("filesystem: no metadata store configured")
+	}
+	return p.config.Metadata.SetMetadata(ctx, path, values)
+}
+
+// Search returns the files matching a query in the index, with their
+// information. Files deleted without the provider are left out.
+func (p *Provider) Search(ctx context.Context, q string, opts SearchOptions) ([]SearchHit, error) {
+	if p.config.Indexer == nil {
+		return nil, fserrors.New("filesystem: no indexer configured")
+	}
+	hits, err := p.config.Indexer.Search(ctx, q, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	found := hits[:0]
+	for _, hit := range hits {
+		info, err := p.GetInfo(ctx, hit.Path)
+		if err != nil {
+			if exists, existsErr := p.storage.Exists(ctx, hit.Path); existsErr == nil && !exists {
+				continue
+			}
+			return nil, err
+		}
+		hit.Info = info
+		found = append(found, hit)
+	}
+	return found, nil
+}
+
+// Shutdown refuses new uploads, copies, moves, and deletes with 503 STORAGE_UNAVAILABLE and
+// waits for those in flight to finish, until ctx is done
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closing = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.writes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// beginWrite counts a write in flight, unless shutting down
+func (p *Provider) beginWrite() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closing {
+		return fserrors.StorageUnavailableError(ErrShuttingDown)
+	}
+	p.writes.Add(1)
+	return nil
+}
+
+// cdnURL sets the URL of a file to its CDN URL, when there is a CDN
+func (p *Provider) cdnURL(filePath string, info *FileInfo) {
+	if p.config.CDN != nil && info != nil && !info.IsDirectory {
+		info.URL = p.config.CDN.URLFor(filePath)
+	}
+}
+
+// cdnURLs sets the URLs of the files of a directory to their CDN URLs
+func (p *Provider) cdnURLs(dir string, files []FileInfo) {
+	if p.config.CDN == nil {
+		return
+	}
+	for i := range files {
+		p.cdnURL(path.Join(dir, files[i].Name), &files[i])
+	}
+}
+
+// loadMetadata sets the metadata of a file from the metadata store, when
+// there is one
+func (p *Provider) loadMetadata(ctx context.Context, filePath string, info *FileInfo) error {
+	if p.config.Metadata == nil || info == nil || info.IsDirectory {
+		return nil
+	}
+	metadata, err := p.config.Metadata.Metadata(ctx, filePath)
+	if err != nil {
+		return fserrors.WrapError(err, http.StatusInternalServerError, "Failed to read file metadata")
+	}
+	if len(metadata) > 0 {
+		info.Metadata = metadata
+	}
+	return nil
+}
+
+// clearMetadata removes the metadata of a file that was deleted, or of a
+// previous file at the path of an upload. A failure is logged and does not
+// fail the operation, which has already been made.
+func (p *Provider) clearMetadata(ctx context.Context, filePath string) {
+	if p.config.Metadata == nil {
+		return
+	}
+	if err := p.config.Metadata.DeleteMetadata(ctx, filePath); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete the metadata of %s: %v", filePath, err)
+	}
+}
+
+// unindex removes a deleted file from the index. A failure is logged and
+// does not fail the delete, which has already been made.
+func (p *Provider) unindex(ctx context.Context, filePath string) {
+	if p.config.Indexer == nil {
+		return
+	}
+	if err := p.config.Indexer.Remove(ctx, filePath); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to remove %s from the index: %v", filePath, err)
+	}
+}
+
+// invalidate removes a file from the CDN. A failure is logged and does not
+// fail the operation, which has already been made.
+func (p *Provider) invalidate(ctx context.Context, filePath string) {
+	if p.config.CDN == nil {
+		return
+	}
+	if err := p.config.CDN.Invalidate(ctx, []string{filePath}); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to invalidate %s on the CDN: %v", filePath, err)
+	}
 }
@@ -0,0 +1,45 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// plainStorage hides the Copier of a storage
+type plainStorage struct {
+	Storage
+}
+
+func TestCopyFileWithoutCopier(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "copies"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	data := bytes.Repeat([]byte("0123456789"), 1<<10)
+	if err := os.WriteFile(filepath.Join(tempDir, "a.txt"), data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+
+	info, err := copyFile(context.Background(), plainStorage{storage}, "a.txt", "copies/a.txt")
+	if err != nil {
+		t.Fatalf("Failed to copy: %v", err)
+	}
+	if info.Size != int64(len(data)) {
+		t.Errorf("Expected size %d, got %d", len(data), info.Size)
+	}
+	copied, err := os.ReadFile(filepath.Join(tempDir, "copies", "a.txt"))
+	if err != nil || !bytes.Equal(copied, data) {
+		t.Errorf("Expected the content to be copied, got %d bytes and %v", len(copied), err)
+	}
+
+	if _, err := copyFile(context.Background(), plainStorage{storage}, "missing.txt", "copies/b.txt"); err == nil {
+		t.Error("Expected copying a missing file to fail")
+	}
+}
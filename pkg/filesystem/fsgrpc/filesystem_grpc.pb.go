@@ -0,0 +1,286 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: filesystem.proto
+
+package fsgrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Filesystem_Upload_FullMethodName = "/gokit.filesystem.v1.Filesystem/Upload"
+	Filesystem_Get_FullMethodName    = "/gokit.filesystem.v1.Filesystem/Get"
+	Filesystem_List_FullMethodName   = "/gokit.filesystem.v1.Filesystem/List"
+	Filesystem_Delete_FullMethodName = "/gokit.filesystem.v1.Filesystem/Delete"
+	Filesystem_Stat_FullMethodName   = "/gokit.filesystem.v1.Filesystem/Stat"
+)
+
+// FilesystemClient is the client API for Filesystem service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Filesystem serves the files of a filesystem provider
+type FilesystemClient interface {
+	// Upload stores a file, sent as its metadata followed by its content in
+	// chunks
+	Upload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadRequest, FileInfo], error)
+	// Get streams a file, as its info followed by its content in chunks
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetResponse], error)
+	// List returns a page of the files in a directory
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Delete removes a file
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Stat returns the info of a file without its content
+	Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*FileInfo, error)
+}
+
+type filesystemClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFilesystemClient(cc grpc.ClientConnInterface) FilesystemClient {
+	return &filesystemClient{cc}
+}
+
+func (c *filesystemClient) Upload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadRequest, FileInfo], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Filesystem_ServiceDesc.Streams[0], Filesystem_Upload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadRequest, FileInfo]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Filesystem_UploadClient = grpc.ClientStreamingClient[UploadRequest, FileInfo]
+
+func (c *filesystemClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Filesystem_ServiceDesc.Streams[1], Filesystem_Get_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetRequest, GetResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Filesystem_GetClient = grpc.ServerStreamingClient[GetResponse]
+
+func (c *filesystemClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, Filesystem_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *filesystemClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, Filesystem_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *filesystemClient) Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*FileInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileInfo)
+	err := c.cc.Invoke(ctx, Filesystem_Stat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FilesystemServer is the server API for Filesystem service.
+// All implementations must embed UnimplementedFilesystemServer
+// for forward compatibility.
+//
+// Filesystem serves the files of a filesystem provider
+type FilesystemServer interface {
+	// Upload stores a file, sent as its metadata followed by its content in
+	// chunks
+	Upload(grpc.ClientStreamingServer[UploadRequest, FileInfo]) error
+	// Get streams a file, as its info followed by its content in chunks
+	Get(*GetRequest, grpc.ServerStreamingServer[GetResponse]) error
+	// List returns a page of the files in a directory
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Delete removes a file
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// Stat returns the info of a file without its content
+	Stat(context.Context, *StatRequest) (*FileInfo, error)
+	mustEmbedUnimplementedFilesystemServer()
+}
+
+// UnimplementedFilesystemServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFilesystemServer struct{}
+
+func (UnimplementedFilesystemServer) Upload(grpc.ClientStreamingServer[UploadRequest, FileInfo]) error {
+	return status.Errorf(codes.Unimplemented, "method Upload not implemented")
+}
+func (UnimplementedFilesystemServer) Get(*GetRequest, grpc.ServerStreamingServer[GetResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedFilesystemServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedFilesystemServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedFilesystemServer) Stat(context.Context, *StatRequest) (*FileInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stat not implemented")
+}
+func (UnimplementedFilesystemServer) mustEmbedUnimplementedFilesystemServer() {}
+func (UnimplementedFilesystemServer) testEmbeddedByValue()                    {}
+
+// UnsafeFilesystemServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FilesystemServer will
+// result in compilation errors.
+type UnsafeFilesystemServer interface {
+	mustEmbedUnimplementedFilesystemServer()
+}
+
+func RegisterFilesystemServer(s grpc.ServiceRegistrar, srv FilesystemServer) {
+	// If the following call pancis, it indicates UnimplementedFilesystemServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Filesystem_ServiceDesc, srv)
+}
+
+func _Filesystem_Upload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FilesystemServer).Upload(&grpc.GenericServerStream[UploadRequest, FileInfo]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Filesystem_UploadServer = grpc.ClientStreamingServer[UploadRequest, FileInfo]
+
+func _Filesystem_Get_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FilesystemServer).Get(m, &grpc.GenericServerStream[GetRequest, GetResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Filesystem_GetServer = grpc.ServerStreamingServer[GetResponse]
+
+func _Filesystem_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FilesystemServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Filesystem_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FilesystemServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Filesystem_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FilesystemServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Filesystem_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FilesystemServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Filesystem_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FilesystemServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Filesystem_Stat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FilesystemServer).Stat(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Filesystem_ServiceDesc is the grpc.ServiceDesc for Filesystem service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Filesystem_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gokit.filesystem.v1.Filesystem",
+	HandlerType: (*FilesystemServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _Filesystem_List_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Filesystem_Delete_Handler,
+		},
+		{
+			MethodName: "Stat",
+			Handler:    _Filesystem_Stat_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Upload",
+			Handler:       _Filesystem_Upload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Get",
+			Handler:       _Filesystem_Get_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "filesystem.proto",
+}
@@ -0,0 +1,173 @@
+package fsgrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details of errors converted by
+// Status
+const ErrorDomain = "gokit"
+
+// MetadataRequestID is the metadata key of the request ID of calls
+const MetadataRequestID = "x-request-id"
+
+// httpCodes maps the HTTP status of AppErrors to gRPC codes
+var httpCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.AlreadyExists,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusUnsupportedMediaType:  codes.InvalidArgument,
+	http.StatusUnprocessableEntity:   codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusInternalServerError:   codes.Internal,
+	http.StatusNotImplemented:        codes.Unimplemented,
+	http.StatusBadGateway:            codes.Unavailable,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+	http.StatusGatewayTimeout:        codes.DeadlineExceeded,
+}
+
+// Status converts an error to a gRPC status error. Status errors are kept,
+// AppErrors get the code of their HTTP status and their message, with their
+// error code as the reason of an ErrorInfo detail, context errors get their
+// code, and other errors are Internal without their message, which may
+// reveal internals.
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	var appErr *fserrors.AppError
+	if !errors.As(err, &appErr) {
+		return status.Error(codes.Internal, "Internal server error")
+	}
+	code, ok := httpCodes[appErr.HTTPCode]
+	if !ok {
+		code = codes.Internal
+		if appErr.HTTPCode < http.StatusInternalServerError {
+			code = codes.FailedPrecondition
+		}
+	}
+	st, detailErr := status.New(code, appErr.Message).WithDetails(&errdetails.ErrorInfo{
+		Reason: appErr.Code,
+		Domain: ErrorDomain,
+	})
+	if detailErr != nil {
+		return status.Error(code, appErr.Message)
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor returns an interceptor converting the errors of
+// calls with Status and logging every call with l, or the default logger
+// when nil
+func UnaryServerInterceptor(l *logger.Logger) grpc.UnaryServerInterceptor {
+	if l == nil {
+		l = logger.Default()
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withRequestID(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		err = Status(err)
+		logCall(ctx, l, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor converting the errors of
+// streams with Status and logging every stream with l, or the default
+// logger when nil
+func StreamServerInterceptor(l *logger.Logger) grpc.StreamServerInterceptor {
+	if l == nil {
+		l = logger.Default()
+	}
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestID(stream.Context())
+		start := time.Now()
+		err := Status(handler(srv, &serverStream{ServerStream: stream, ctx: ctx}))
+		logCall(ctx, l, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// ServerOptions returns the server options installing both interceptors
+func ServerOptions(l *logger.Logger) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(l)),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(l)),
+	}
+}
+
+// serverStream is a server stream with the context of the interceptor
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// withRequestID adds the request ID sent by the client to the context of
+// a call, for the loggers of its handler
+func withRequestID(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if ids := md.Get(MetadataRequestID); len(ids) > 0 && ids[0] != "" {
+		return logger.ContextWithRequestID(ctx, ids[0])
+	}
+	return ctx
+}
+
+// logCall logs one structured entry per call with the method, code, and
+// latency. Server errors are logged at ERROR level and everything else at
+// INFO level, like the access log of HTTP requests.
+func logCall(ctx context.Context, l *logger.Logger, method string, start time.Time, err error) {
+	latency := time.Since(start)
+	code := status.Code(err)
+
+	fields := logger.Fields{
+		"method":     method,
+		"code":       code.String(),
+		"latency_ms": float64(latency.Microseconds()) / 1000,
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+
+	level := logger.INFO
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable,
+		codes.Unimplemented, codes.DeadlineExceeded:
+		level = logger.ERROR
+	}
+
+	l.Ctx(ctx).WithFields(fields).Logf(level, "%s %s %s", method, code, latency)
+}
@@ -0,0 +1,232 @@
+// Package fsgrpc serves a filesystem provider over gRPC, for internal
+// services that prefer streams to multipart HTTP. The service is defined in
+// filesystem.proto:
+//
+//	srv := grpc.NewServer(fsgrpc.ServerOptions(log)...)
+//	fsgrpc.RegisterFilesystemServer(srv, fsgrpc.NewServer(provider))
+package fsgrpc
+
+// The generated files are produced by protoc 29.3 and the protoc-gen-go and
+// protoc-gen-go-grpc versions pinned by the tool directives of go.mod
+//go:generate sh -c "protoc --version | grep -qx 'libprotoc 29.3' || { echo 'fsgrpc: protoc 29.3 is required' >&2; exit 1; }"
+//go:generate sh -c "protoc --plugin=protoc-gen-go=$(go tool -n protoc-gen-go) --plugin=protoc-gen-go-grpc=$(go tool -n protoc-gen-go-grpc) --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative filesystem.proto"
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// DefaultMaxFileSize is the default size limit of uploads
+	DefaultMaxFileSize = 32 << 20
+
+	// DefaultChunkSize is the default size of the chunks of Get
+	DefaultChunkSize = 64 << 10
+)
+
+// ServerConfig configures a Server
+type ServerConfig struct {
+	// MaxFileSize limits the size of uploads, which are streamed to a
+	// temporary file until stored, defaulting to DefaultMaxFileSize
+	MaxFileSize int64
+
+	// ChunkSize is the size of the chunks files are streamed in by Get,
+	// defaulting to DefaultChunkSize
+	ChunkSize int
+}
+
+// Server implements FilesystemServer over a filesystem provider. Its errors
+// are gRPC status errors, converted by Status.
+type Server struct {
+	UnimplementedFilesystemServer
+
+	provider *filesystem.Provider
+	config   ServerConfig
+}
+
+// NewServer creates a server of the files of a provider
+func NewServer(provider *filesystem.Provider, config ...ServerConfig) *Server {
+	if provider == nil {
+		panic("filesystem provider is required")
+	}
+	s := &Server{provider: provider}
+	if len(config) > 0 {
+		s.config = config[0]
+	}
+	if s.config.MaxFileSize <= 0 {
+		s.config.MaxFileSize = DefaultMaxFileSize
+	}
+	if s.config.ChunkSize <= 0 {
+		s.config.ChunkSize = DefaultChunkSize
+	}
+	return s
+}
+
+// Upload stores a file sent as an UploadMetadata followed by chunks
+func (s *Server) Upload(stream grpc.ClientStreamingServer[UploadRequest, FileInfo]) error {
+	ctx := stream.Context()
+
+	first, err := stream.Recv()
+	if err != nil {
+		return Status(err)
+	}
+	metadata := first.GetMetadata()
+	if metadata == nil {
+		return Status(fserrors.NewError(http.StatusBadRequest, "The first message must be the upload metadata"))
+	}
+	filePath := filesystem.NormalizeKey(metadata.Path)
+	if filePath == "" {
+		return Status(fserrors.NewError(http.StatusBadRequest, "File path is required"))
+	}
+	filename := metadata.Filename
+	if filename == "" {
+		filename = path.Base(filePath)
+	}
+
+	// Stream the content to a temporary file, up to the size limit
+	file, remove, err := filesystem.NewFileHeaderFrom(filename, &chunkReader{stream: stream, limit: s.config.MaxFileSize})
+	if err != nil {
+		return Status(err)
+	}
+	defer remove()
+
+	info, err := s.provider.Upload(ctx, file, filePath)
+	if err != nil {
+		return Status(err)
+	}
+	return stream.SendAndClose(fileInfo(info))
+}
+
+// chunkReader reads the chunks following the metadata of an upload, up to a
+// size limit
+type chunkReader struct {
+	stream grpc.ClientStreamingServer[UploadRequest, FileInfo]
+	limit  int64
+	size   int64
+	chunk  []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := req.Data.(*UploadRequest_Chunk); !ok {
+			return 0, fserrors.NewError(http.StatusBadRequest, "Only chunks may follow the upload metadata")
+		}
+		if r.size += int64(len(req.GetChunk())); r.size > r.limit {
+			return 0, fserrors.FileTooLargeError(r.size, r.limit)
+		}
+		r.chunk = req.GetChunk()
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+// Get streams a file as its FileInfo followed by chunks
+func (s *Server) Get(req *GetRequest, stream grpc.ServerStreamingServer[GetResponse]) error {
+	filePath := filesystem.NormalizeKey(req.Path)
+	if filePath == "" {
+		return Status(fserrors.NewError(http.StatusBadRequest, "File path is required"))
+	}
+
+	file, info, err := s.provider.Get(stream.Context(), filePath)
+	if err != nil {
+		return Status(err)
+	}
+	defer file.Close()
+
+	if err := stream.Send(&GetResponse{Data: &GetResponse_Info{Info: fileInfo(info)}}); err != nil {
+		return err
+	}
+	buf := make([]byte, s.config.ChunkSize)
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			if err := stream.Send(&GetResponse{Data: &GetResponse_Chunk{Chunk: buf[:n]}}); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return Status(fserrors.WrapError(err, http.StatusInternalServerError, "Failed to read file"))
+		}
+	}
+}
+
+// List returns a page of the files in a directory
+func (s *Server) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	if req.Limit < 0 {
+		return nil, Status(fserrors.NewError(http.StatusBadRequest, "Limit must not be negative"))
+	}
+
+	page, err := s.provider.ListPage(ctx, filesystem.NormalizeKey(req.Path), filesystem.ListOptions{
+		Limit: int(req.Limit),
+		Token: req.PageToken,
+	})
+	if err != nil {
+		return nil, Status(err)
+	}
+
+	resp := &ListResponse{
+		Files:         make([]*FileInfo, 0, len(page.Files)),
+		NextPageToken: page.NextToken,
+	}
+	for i := range page.Files {
+		resp.Files = append(resp.Files, fileInfo(&page.Files[i]))
+	}
+	return resp, nil
+}
+
+// Delete removes a file
+func (s *Server) Delete(ctx context.Context, req *DeleteRequest) (*DeleteResponse, error) {
+	filePath := filesystem.NormalizeKey(req.Path)
+	if filePath == "" {
+		return nil, Status(fserrors.NewError(http.StatusBadRequest, "File path is required"))
+	}
+	if err := s.provider.Delete(ctx, filePath); err != nil {
+		return nil, Status(err)
+	}
+	return &DeleteResponse{}, nil
+}
+
+// Stat returns the info of a file
+func (s *Server) Stat(ctx context.Context, req *StatRequest) (*FileInfo, error) {
+	filePath := filesystem.NormalizeKey(req.Path)
+	if filePath == "" {
+		return nil, Status(fserrors.NewError(http.StatusBadRequest, "File path is required"))
+	}
+	info, err := s.provider.GetInfo(ctx, filePath)
+	if err != nil {
+		return nil, Status(err)
+	}
+	return fileInfo(info), nil
+}
+
+// fileInfo converts the info of a file to its message
+func fileInfo(info *filesystem.FileInfo) *FileInfo {
+	msg := &FileInfo{
+		Name:        info.Name,
+		Size:        info.Size,
+		Url:         info.URL,
+		ContentType: info.ContentType,
+		IsDirectory: info.IsDirectory,
+		Metadata:    info.Metadata,
+	}
+	if !info.LastModified.IsZero() {
+		msg.LastModified = timestamppb.New(info.LastModified)
+	}
+	return msg
+}
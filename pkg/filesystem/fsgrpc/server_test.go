@@ -0,0 +1,220 @@
+package fsgrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the files of a temporary directory over an in-memory
+// connection and returns a client of them
+func newTestClient(t *testing.T, config ServerConfig) (FilesystemClient, string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "docs"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	storage, err := filesystem.NewLocalStorage(filesystem.LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(ServerOptions(logger.Default())...)
+	RegisterFilesystemServer(srv, NewServer(filesystem.NewProvider(storage), config))
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewFilesystemClient(conn), tempDir
+}
+
+// upload sends a file in chunks of chunkSize
+func upload(ctx context.Context, client FilesystemClient, path string, data []byte, chunkSize int) (*FileInfo, error) {
+	stream, err := client.Upload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&UploadRequest{Data: &UploadRequest_Metadata{Metadata: &UploadMetadata{Path: path}}}); err != nil {
+		return nil, err
+	}
+	for len(data) > 0 {
+		n := min(chunkSize, len(data))
+		if err := stream.Send(&UploadRequest{Data: &UploadRequest_Chunk{Chunk: data[:n]}}); err != nil {
+			// The server stopped the stream, its error is returned by CloseAndRecv
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		data = data[n:]
+	}
+	return stream.CloseAndRecv()
+}
+
+func TestServerUpload(t *testing.T) {
+	client, tempDir := newTestClient(t, ServerConfig{MaxFileSize: 1 << 10})
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	info, err := upload(ctx, client, "docs/notes.txt", data, 64)
+	if err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+	if info.Size != int64(len(data)) {
+		t.Errorf("Expected size %d, got %d", len(data), info.Size)
+	}
+	stored, err := os.ReadFile(filepath.Join(tempDir, "docs", "notes.txt"))
+	if err != nil || !bytes.Equal(stored, data) {
+		t.Errorf("Expected the uploaded content to be stored, got %d bytes and %v", len(stored), err)
+	}
+
+	tests := []struct {
+		name string
+		send func() error
+		code codes.Code
+	}{
+		{"too large", func() error {
+			_, err := upload(ctx, client, "docs/large.txt", bytes.Repeat([]byte("x"), 2<<10), 256)
+			return err
+		}, codes.InvalidArgument},
+		{"no path", func() error {
+			_, err := upload(ctx, client, "", data, 64)
+			return err
+		}, codes.InvalidArgument},
+		{"chunk first", func() error {
+			stream, err := client.Upload(ctx)
+			if err != nil {
+				return err
+			}
+			stream.Send(&UploadRequest{Data: &UploadRequest_Chunk{Chunk: data}})
+			_, err = stream.CloseAndRecv()
+			return err
+		}, codes.InvalidArgument},
+		{"metadata twice", func() error {
+			stream, err := client.Upload(ctx)
+			if err != nil {
+				return err
+			}
+			metadata := &UploadRequest{Data: &UploadRequest_Metadata{Metadata: &UploadMetadata{Path: "docs/twice.txt"}}}
+			stream.Send(metadata)
+			stream.Send(metadata)
+			_, err = stream.CloseAndRecv()
+			return err
+		}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.send()); code != tt.code {
+				t.Errorf("Expected %s, got %s", tt.code, code)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "docs", "large.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the file over the limit not to be stored, got %v", err)
+	}
+}
+
+func TestServerGet(t *testing.T) {
+	client, _ := newTestClient(t, ServerConfig{ChunkSize: 100})
+	ctx := context.Background()
+
+	data := bytes.Repeat([]byte("0123456789"), 25)
+	if _, err := upload(ctx, client, "docs/notes.txt", data, 64); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	stream, err := client.Get(ctx, &GetRequest{Path: "docs/notes.txt"})
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive the file info: %v", err)
+	}
+	if first.GetInfo().GetName() != "notes.txt" || first.GetInfo().GetSize() != int64(len(data)) {
+		t.Errorf("Expected the info of notes.txt first, got %v", first)
+	}
+	var got []byte
+	chunks := 0
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to receive a chunk: %v", err)
+		}
+		got = append(got, resp.GetChunk()...)
+		chunks++
+	}
+	if !bytes.Equal(got, data) || chunks != 3 {
+		t.Errorf("Expected the content in 3 chunks, got %d bytes in %d", len(got), chunks)
+	}
+
+	stream, err = client.Get(ctx, &GetRequest{Path: "docs/missing.txt"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("Expected NotFound for a missing file, got %s", code)
+	}
+}
+
+func TestServerList(t *testing.T) {
+	client, _ := newTestClient(t, ServerConfig{})
+	ctx := context.Background()
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if _, err := upload(ctx, client, "docs/"+name, []byte(name), 64); err != nil {
+			t.Fatalf("Failed to upload %s: %v", name, err)
+		}
+	}
+
+	var names []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("Expected the pages to end, got %v", names)
+		}
+		resp, err := client.List(ctx, &ListRequest{Path: "docs", Limit: 2, PageToken: token})
+		if err != nil {
+			t.Fatalf("Failed to list: %v", err)
+		}
+		for _, file := range resp.Files {
+			names = append(names, file.Name)
+		}
+		if token = resp.NextPageToken; token == "" {
+			break
+		}
+	}
+	if len(names) != 3 || names[0] != "a.txt" || names[2] != "c.txt" {
+		t.Errorf("Expected a.txt, b.txt, and c.txt, got %v", names)
+	}
+
+	if _, err := client.List(ctx, &ListRequest{Path: "docs", Limit: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a negative limit, got %v", err)
+	}
+}
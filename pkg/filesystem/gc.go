@@ -0,0 +1,226 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultGCGracePeriod is how old a file must be before garbage
+	// collection may delete it, so uploads whose references are not saved
+	// yet are kept
+	DefaultGCGracePeriod = 24 * time.Hour
+
+	// DefaultGCBatchSize is how many paths are checked for references at once
+	DefaultGCBatchSize = 500
+)
+
+// References reports which stored files are still in use, e.g. by the rows
+// of a table, for garbage collection
+type References interface {
+	// Referenced returns the paths of a batch that are in use
+	Referenced(ctx context.Context, paths []string) (map[string]bool, error)
+}
+
+// ReferencesFunc adapts a function to References
+type ReferencesFunc func(ctx context.Context, paths []string) (map[string]bool, error)
+
+// Referenced calls f
+func (f ReferencesFunc) Referenced(ctx context.Context, paths []string) (map[string]bool, error) {
+	return f(ctx, paths)
+}
+
+// ReferenceSet is References of a fixed set of paths, e.g. read from a file
+type ReferenceSet map[string]bool
+
+// Referenced returns the paths of a batch that are in the set
+func (s ReferenceSet) Referenced(_ context.Context, paths []string) (map[string]bool, error) {
+	found := make(map[string]bool)
+	for _, p := range paths {
+		if s[p] || s[cleanPath(p)] {
+			found[p] = true
+		}
+	}
+	return found, nil
+}
+
+// gormReferences finds references in a column of a table
+type gormReferences struct {
+	db     *gorm.DB
+	table  string
+	column string
+}
+
+// GormReferences returns References to the paths stored in a column of a
+// table, e.g. GormReferences(db, "documents", "file_path"). The column holds
+// the paths files were uploaded to.
+func GormReferences(db *gorm.DB, table, column string) References {
+	return &gormReferences{db: db, table: table, column: column}
+}
+
+// Referenced queries which paths of a batch are in the column
+func (g *gormReferences) Referenced(ctx context.Context, paths []string) (map[string]bool, error) {
+	values := make([]interface{}, len(paths))
+	for i, p := range paths {
+		values[i] = p
+	}
+
+	var found []string
+	err := g.db.WithContext(ctx).
+		Table(g.table).
+		Where(clause.IN{Column: clause.Column{Name: g.column}, Values: values}).
+		Distinct().
+		Pluck(g.column, &found).Error
+	if err != nil {
+		return nil, fmt.Errorf("filesystem: query references in %s.%s: %w", g.table, g.column, err)
+	}
+
+	referenced := make(map[string]bool, len(found))
+	for _, p := range found {
+		referenced[p] = true
+	}
+	return referenced, nil
+}
+
+// GCConfig configures a garbage collection of files
+type GCConfig struct {
+	// Dir is the directory to collect, with its subdirectories; empty for
+	// the whole storage
+	Dir string
+
+	// References reports the files in use, which are kept
+	References References
+
+	// GracePeriod defaults to DefaultGCGracePeriod
+	GracePeriod time.Duration
+
+	// DryRun reports the orphans without deleting them
+	DryRun bool
+
+	// BatchSize defaults to DefaultGCBatchSize
+	BatchSize int
+}
+
+// GCOrphan is a file that no reference uses
+type GCOrphan struct {
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	Deleted      bool      `json:"deleted"`
+
+	// Error is why the file could not be deleted
+	Error string `json:"error,omitempty"`
+}
+
+// GCReport is the outcome of a garbage collection
+type GCReport struct {
+	DryRun bool `json:"dryRun"`
+
+	// Scanned counts the files old enough to be checked
+	Scanned int `json:"scanned"`
+
+	// Orphans lists the unreferenced files, deleted unless DryRun
+	Orphans []GCOrphan `json:"orphans"`
+
+	// Bytes is the size of the orphans
+	Bytes int64 `json:"bytes"`
+
+	// Deleted counts the orphans deleted
+	Deleted int `json:"deleted"`
+}
+
+// CollectGarbage deletes the files under a directory that no reference uses
+// and that are older than the grace period, e.g. from a scheduled task:
+//
+//	report, err := fs.Provider.CollectGarbage(ctx, filesystem.GCConfig{
+//		Dir:        "documents",
+//		References: filesystem.GormReferences(db, "documents", "file_path"),
+//	})
+//
+// A file that cannot be deleted is reported with its error and the
+// collection goes on.
+func (p *Provider) CollectGarbage(ctx context.Context, config GCConfig) (*GCReport, error) {
+	if config.References == nil {
+		return nil, fmt.Errorf("filesystem: garbage collection without references")
+	}
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = DefaultGCGracePeriod
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultGCBatchSize
+	}
+
+	report := &GCReport{DryRun: config.DryRun, Orphans: []GCOrphan{}}
+	cutoff := time.Now().Add(-config.GracePeriod)
+
+	var batch []GCOrphan
+	check := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		paths := make([]string, len(batch))
+		for i, file := range batch {
+			paths[i] = file.Path
+		}
+		referenced, err := config.References.Referenced(ctx, paths)
+		if err != nil {
+			return err
+		}
+
+		for _, orphan := range batch {
+			if referenced[orphan.Path] || p.derivedFromExisting(ctx, orphan.Path) {
+				continue
+			}
+			if !config.DryRun {
+				if err := p.Delete(ctx, orphan.Path); err != nil {
+					orphan.Error = err.Error()
+				} else {
+					orphan.Deleted = true
+					report.Deleted++
+				}
+			}
+			report.Orphans = append(report.Orphans, orphan)
+			report.Bytes += orphan.Size
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	err := p.Walk(ctx, cleanPath(config.Dir), func(filePath string, info FileInfo) error {
+		if info.LastModified.After(cutoff) {
+			return nil
+		}
+		report.Scanned++
+		batch = append(batch, GCOrphan{Path: filePath, Size: info.Size, LastModified: info.LastModified})
+		if len(batch) < config.BatchSize {
+			return nil
+		}
+		return check()
+	})
+	if err == nil {
+		err = check()
+	}
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// derivedFromExisting reports whether a file was derived by a pipeline from
+// a file that still exists, which deletes it along with that file
+func (p *Provider) derivedFromExisting(ctx context.Context, filePath string) bool {
+	if p.config.Metadata == nil {
+		return false
+	}
+	metadata, err := p.config.Metadata.Metadata(ctx, filePath)
+	if err != nil || metadata["derivedFrom"] == "" {
+		return false
+	}
+	exists, err := p.storage.Exists(ctx, metadata["derivedFrom"])
+	// Keep the file when unsure
+	return err != nil || exists
+}
@@ -2,16 +2,26 @@ package filesystem
 
 import (
 	"context"
-	"fmt"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	playground "github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 
+	"github.com/anaknegeri/gokit/pkg/auth"
 	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/pagination"
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/anaknegeri/gokit/pkg/validator"
 )
 
 // UploadHandlerConfig configures the upload handler
@@ -20,8 +30,213 @@ type UploadHandlerConfig struct {
 	BasePath     string
 	AllowedTypes []string
 	MaxFileSize  int
-	UseUUID      bool // Use UUID for filenames instead of original name
-	TimeoutSecs  int  // Context timeout in seconds
+	TimeoutSecs  int // Context timeout in seconds
+
+	// MaxFileSizes limit the size of files by extension, e.g. ".mp4", or
+	// by content type, e.g. "video/mp4" or "image/*", instead of
+	// MaxFileSize. The extension wins over the content type, and a
+	// content type over a wildcard.
+	MaxFileSizes map[string]int
+
+	// Naming names the uploaded files, e.g. UUIDv7Naming or
+	// TemplateNaming{Template: "{date}/{uuid}{ext}"}, defaulting to
+	// UUIDNaming with UseUUID and OriginalNaming without
+	Naming NamingStrategy
+
+	// Deprecated: Use Naming. UseUUID names uploads with a UUID when no
+	// Naming is set.
+	UseUUID bool
+
+	// Rules checks the uploaded file with the rules of validator.FileValidator,
+	// e.g. "maxsize=5MB,mime=image/png image/jpeg,minwidth=200"
+	Rules string
+
+	// StripGPS removes the location from the EXIF data of uploaded JPEG
+	// photos before they are stored
+	StripGPS bool
+
+	// DownloadTokens makes GetFileHandler serve only files granted by the
+	// download token in the query, for the user of the request when the
+	// grant is bound to one
+	DownloadTokens *DownloadTokens
+
+	// Pipeline processes the files uploaded without a policy
+	Pipeline *Pipeline
+
+	// PreviewWatermark draws a watermark on the previews served by
+	// GetPreviewHandler as they are served, leaving the stored previews
+	// as they are. To watermark the files themselves on upload, add a
+	// WatermarkProcessor to the pipeline of their policy.
+	PreviewWatermark *Watermarker
+
+	// Dispositions are the Content-Disposition rules of the files served
+	// by GetFileHandler by extension, e.g. ".svg", or content type, e.g.
+	// "text/html" or "image/*". "attachment" forces the download over the
+	// disposition query, while "inline" leaves the choice to the client.
+	// They add to and override DefaultDispositions. The rule of the served
+	// content type comes first, and an extension rule replaces it only for
+	// files served as the type of their extension.
+	Dispositions map[string]string
+
+	// Ingest enables IngestHandler on the routes of a FilesystemProvider
+	// and configures its downloads
+	Ingest *IngestConfig
+
+	// Batch enables BatchHandler and BatchStatusHandler on the routes of a
+	// FilesystemProvider, running the batches, see NewBatcher
+	Batch *Batcher
+
+	// Duplicates enables DuplicatesHandler on the routes of a
+	// FilesystemProvider, and DedupeHandler when it has References. The
+	// provider needs a metadata store.
+	Duplicates *DuplicatesConfig
+
+	// Usage enables UsageHandler on the routes of a FilesystemProvider
+	Usage bool
+
+	// DirectUploads enables DirectUploadHandler and
+	// CompleteDirectUploadHandler on the routes of a FilesystemProvider,
+	// for storages that implement DirectUploader
+	DirectUploads *DirectUploadConfig
+
+	// Policies are the upload policies clients select with the policy form
+	// field, e.g. "avatar" or "document". Uploads naming another policy are
+	// refused.
+	Policies map[string]UploadPolicy
+}
+
+// UploadPolicy is what an upload accepts and how its file is processed. Its
+// zero fields keep the settings of the UploadHandlerConfig.
+type UploadPolicy struct {
+	AllowedTypes []string
+	MaxFileSize  int
+
+	// MaxFileSizes limit the size of files by extension or content type,
+	// see UploadHandlerConfig.MaxFileSizes, adding to and overriding those
+	// of the config
+	MaxFileSizes map[string]int
+
+	// Rules checks the uploaded file, see UploadHandlerConfig.Rules
+	Rules string
+
+	// StripGPS removes the location of photos, see
+	// UploadHandlerConfig.StripGPS
+	StripGPS bool
+
+	// Naming names the uploaded file, see UploadHandlerConfig.Naming
+	Naming NamingStrategy
+
+	// Pipeline processes the uploaded file
+	Pipeline *Pipeline
+}
+
+// policy returns the upload policy of a name, or the settings of the config
+// for an empty name
+func (config UploadHandlerConfig) policy(name string) (UploadPolicy, *fserrors.AppError) {
+	policy := UploadPolicy{
+		AllowedTypes: config.AllowedTypes,
+		MaxFileSize:  config.MaxFileSize,
+		MaxFileSizes: config.MaxFileSizes,
+		Rules:        config.Rules,
+		StripGPS:     config.StripGPS,
+		Naming:       config.Naming,
+		Pipeline:     config.Pipeline,
+	}
+	if policy.Naming == nil {
+		if config.UseUUID {
+			policy.Naming = UUIDNaming{}
+		} else {
+			policy.Naming = OriginalNaming{}
+		}
+	}
+	if name == "" {
+		return policy, nil
+	}
+
+	named, ok := config.Policies[name]
+	if !ok {
+		return policy, fserrors.NewError(http.StatusBadRequest, "Unknown upload policy: "+name)
+	}
+	if len(named.AllowedTypes) > 0 {
+		policy.AllowedTypes = named.AllowedTypes
+	}
+	if named.MaxFileSize > 0 {
+		policy.MaxFileSize = named.MaxFileSize
+	}
+	if len(named.MaxFileSizes) > 0 {
+		sizes := maps.Clone(policy.MaxFileSizes)
+		if sizes == nil {
+			sizes = make(map[string]int, len(named.MaxFileSizes))
+		}
+		maps.Copy(sizes, named.MaxFileSizes)
+		policy.MaxFileSizes = sizes
+	}
+	if named.Rules != "" {
+		policy.Rules = named.Rules
+	}
+	if named.StripGPS {
+		policy.StripGPS = true
+	}
+	if named.Naming != nil {
+		policy.Naming = named.Naming
+	}
+	if named.Pipeline != nil {
+		policy.Pipeline = named.Pipeline
+	}
+	return policy, nil
+}
+
+// maxFileSize is the largest file size of the config and its policies
+func (config UploadHandlerConfig) maxFileSize() int {
+	size := UploadPolicy{MaxFileSize: config.MaxFileSize, MaxFileSizes: config.MaxFileSizes}.maxSize()
+	for _, policy := range config.Policies {
+		size = max(size, policy.maxSize())
+	}
+	return size
+}
+
+// maxSize is the largest file size of a policy
+func (policy UploadPolicy) maxSize() int {
+	size := policy.MaxFileSize
+	for _, limit := range policy.MaxFileSizes {
+		size = max(size, limit)
+	}
+	return size
+}
+
+// sizeLimit returns the size limit of a file and what it applies to: its
+// extension, its content type or wildcard, or "default" for MaxFileSize
+func (policy UploadPolicy) sizeLimit(filename, contentType string) (int, string) {
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" {
+		if limit, ok := policy.MaxFileSizes[ext]; ok {
+			return limit, ext
+		}
+	}
+	if byExt := mime.TypeByExtension(filepath.Ext(filename)); byExt != "" {
+		contentType = byExt
+	}
+	if limit, key, ok := matchContentType(policy.MaxFileSizes, contentType); ok {
+		return limit, key
+	}
+	return policy.MaxFileSize, "default"
+}
+
+// matchContentType returns the rule of a content type, or else of its
+// wildcard, e.g. "image/*", and the key it was found under
+func matchContentType[V any](rules map[string]V, contentType string) (V, string, bool) {
+	var zero V
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return zero, "", false
+	}
+	if rule, ok := rules[mediaType]; ok {
+		return rule, mediaType, true
+	}
+	wildcard := strings.SplitN(mediaType, "/", 2)[0] + "/*"
+	if rule, ok := rules[wildcard]; ok {
+		return rule, wildcard, true
+	}
+	return zero, "", false
 }
 
 // Response is a standardized API response
@@ -41,9 +256,14 @@ type FileResponse struct {
 	Path         string    `json:"path"`
 	LastModified time.Time `json:"lastModified,omitempty"`
 	IsDirectory  bool      `json:"isDirectory,omitempty"`
+
+	// Metadata holds the processing status and what processors found, see
+	// Pipeline
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
-// UploadHandler returns a Fiber handler for file uploads
+// UploadHandler returns a Fiber handler for file uploads, taking the file
+// field of a multipart form or the UploadRequest of a JSON body
 func UploadHandler(config UploadHandlerConfig) fiber.Handler {
 	if config.Provider == nil {
 		panic("filesystem provider is required")
@@ -54,68 +274,149 @@ func UploadHandler(config UploadHandlerConfig) fiber.Handler {
 		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
 		defer cancel()
 
-		// Get the uploaded file
-		file, err := c.FormFile("file")
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
-				fserrors.NewError(
-					http.StatusBadRequest,
-					"Failed to get uploaded file",
-				),
-			))
+		// Get the uploaded file, from a form or a JSON body
+		var upload UploadRequest
+		var file *multipart.FileHeader
+		if c.Is("json") {
+			if err := c.BodyParser(&upload); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+					fserrors.NewError(
+						http.StatusBadRequest,
+						"Invalid upload request body",
+					),
+				))
+			}
+		} else {
+			var err error
+			if file, err = c.FormFile("file"); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+					fserrors.NewError(
+						http.StatusBadRequest,
+						"Failed to get uploaded file",
+					),
+				))
+			}
+			upload.Path = c.FormValue("path", "")
+			upload.Policy = c.FormValue("policy")
 		}
 
-		// Check file size
-		if file.Size > int64(config.MaxFileSize) {
+		// Select the upload policy
+		policy, appErr := config.policy(upload.Policy)
+		if appErr != nil {
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+
+		// Decode the file of a JSON body
+		if file == nil {
+			if file, appErr = upload.file(); appErr != nil {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+		}
+
+		return config.store(ctx, c, file, policy, upload.Policy, upload.Path)
+	}
+}
+
+// store checks an uploaded file against an upload policy, stores it under
+// a directory, processes it, and responds with it
+func (config UploadHandlerConfig) store(ctx context.Context, c *fiber.Ctx, file *multipart.FileHeader, policy UploadPolicy, policyName, dir string) error {
+	// Check file size against the limit of its type
+	if limit, appliesTo := policy.sizeLimit(file.Filename, file.Header.Get("Content-Type")); file.Size > int64(limit) {
+		appErr := fserrors.FileTooLargeError(file.Size, int64(limit))
+		appErr.Details = map[string]interface{}{
+			"size":      file.Size,
+			"maxSize":   limit,
+			"appliesTo": appliesTo,
+			"policy":    policyName,
+		}
+		return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+	}
+
+	// Check file type if specified
+	if len(policy.AllowedTypes) > 0 {
+		ext := strings.ToLower(filepath.Ext(file.Filename))
+		allowed := false
+		for _, allowedType := range policy.AllowedTypes {
+			if ext == allowedType {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
 			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
-				fserrors.FileTooLargeError(file.Size, int64(config.MaxFileSize)),
+				fserrors.InvalidFileTypeError(ext, policy.AllowedTypes),
 			))
 		}
+	}
 
-		// Check file type if specified
-		if len(config.AllowedTypes) > 0 {
-			ext := strings.ToLower(filepath.Ext(file.Filename))
-			allowed := false
-			for _, allowedType := range config.AllowedTypes {
-				if ext == allowedType {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
-					fserrors.InvalidFileTypeError(ext, config.AllowedTypes),
-				))
+	// Check the file against the upload rules
+	if policy.Rules != "" {
+		if err := validator.NewFileValidator().File(file, policy.Rules); err != nil {
+			appErr := fserrors.ValidatorError(err)
+			var validationErrs playground.ValidationErrors
+			if !fserrors.As(err, &validationErrs) {
+				appErr = fserrors.WrapError(err, http.StatusInternalServerError, "Failed to check uploaded file")
 			}
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
 		}
+	}
 
-		// Generate file path
-		var filename string
-		originalName := file.Filename
-		if config.UseUUID {
-			ext := filepath.Ext(file.Filename)
-			filename = fmt.Sprintf("%s%s", uuid.New().String(), ext)
-		} else {
-			// Sanitize filename to prevent directory traversal
-			filename = sanitizeFilename(file.Filename)
+	// Remove the location of photos before they are stored
+	if policy.StripGPS && isJPEG(file.Filename) {
+		var err error
+		if file, err = stripGPS(file); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to read uploaded file",
+				),
+			))
 		}
+	}
+
+	// Generate file path
+	originalName := file.Filename
+	filename, err := policy.Naming.Name(file)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+			fserrors.WrapError(
+				err,
+				http.StatusInternalServerError,
+				"Failed to name uploaded file",
+			),
+		))
+	}
 
-		// Get custom path from form if provided, otherwise use default
-		customPath := c.FormValue("path", "")
-		if customPath != "" {
-			// Sanitize custom path - remove any ".." to prevent directory traversal
-			customPath = filepath.Clean(customPath)
-			customPath = strings.TrimPrefix(customPath, "../")
+	// Sanitize custom path - resolve any ".." to prevent directory traversal
+	customPath := NormalizeKey(dir)
+
+	// Combine with base path
+	fullPath := JoinKey(config.BasePath, customPath, filename)
+
+	// Upload the file using the provider
+	fileInfo, err := config.Provider.Upload(ctx, file, fullPath)
+	if err != nil {
+		// Convert to appropriate error response
+		var appErr *fserrors.AppError
+		if fserrors.As(err, &appErr) {
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
 		}
 
-		// Combine with base path
-		fullPath := filepath.Join(config.BasePath, customPath, filename)
+		return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+			fserrors.WrapError(
+				err,
+				http.StatusInternalServerError,
+				"Failed to upload file",
+			),
+		))
+	}
 
-		// Upload the file using the provider
-		fileInfo, err := config.Provider.Upload(ctx, file, fullPath)
-		if err != nil {
-			// Convert to appropriate error response
-			if appErr, ok := err.(*fserrors.AppError); ok {
+	// Process the file, or enqueue its processing
+	if policy.Pipeline != nil {
+		if err := policy.Pipeline.Run(ctx, fullPath, fileInfo); err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
 				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
 			}
 
@@ -123,28 +424,44 @@ func UploadHandler(config UploadHandlerConfig) fiber.Handler {
 				fserrors.WrapError(
 					err,
 					http.StatusInternalServerError,
-					"Failed to upload file",
+					"Failed to process file",
 				),
 			))
 		}
+	}
 
-		// Create response with additional info
-		fileResponse := FileResponse{
-			Name:         fileInfo.Name,
-			OriginalName: originalName,
-			Size:         fileInfo.Size,
-			URL:          fileInfo.URL,
-			Path:         filepath.Join(customPath, filename),
-			ContentType:  fileInfo.ContentType,
-			LastModified: fileInfo.LastModified,
-		}
-
-		return c.Status(fiber.StatusOK).JSON(Response{
-			Success: true,
-			Message: "File uploaded successfully",
-			Data:    fileResponse,
-		})
+	// Create response with additional info
+	fileResponse := FileResponse{
+		Name:         fileInfo.Name,
+		OriginalName: originalName,
+		Size:         fileInfo.Size,
+		URL:          fileInfo.URL,
+		Path:         JoinKey(customPath, filename),
+		ContentType:  fileInfo.ContentType,
+		LastModified: fileInfo.LastModified,
+		Metadata:     fileInfo.Metadata,
 	}
+
+	return c.Status(fiber.StatusOK).JSON(Response{
+		Success: true,
+		Message: "File uploaded successfully",
+		Data:    fileResponse,
+	})
+}
+
+// streamBody is a file sent as a response body, which fasthttp closes once
+// it has been sent after the handler returned. Closing it cancels the
+// context the file was read with too.
+type streamBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the file and cancels its context
+func (b *streamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
 }
 
 // GetFileHandler returns a Fiber handler to serve files
@@ -152,14 +469,21 @@ func GetFileHandler(config UploadHandlerConfig) fiber.Handler {
 	if config.Provider == nil {
 		panic("filesystem provider is required")
 	}
+	dispositions := config.dispositions()
 
 	return func(c *fiber.Ctx) error {
-		// Set timeout context
+		// Set timeout context, cancelled once the file is sent when it is
+		// streamed after the handler returns
 		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
-		defer cancel()
+		streaming := false
+		defer func() {
+			if !streaming {
+				cancel()
+			}
+		}()
 
-		// Get the file path from URL parameter
-		path := c.Params("*")
+		// Get the sanitized file path from URL parameter
+		path := NormalizeKey(c.Params("*"))
 		if path == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
 				fserrors.NewError(
@@ -169,11 +493,33 @@ func GetFileHandler(config UploadHandlerConfig) fiber.Handler {
 			))
 		}
 
-		// Sanitize path
-		path = sanitizePath(path)
+		// inline or attachment
+		disposition := strings.ToLower(c.Query("disposition", DispositionInline))
+		if !validDisposition(disposition) {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"Disposition must be inline or attachment",
+				),
+			))
+		}
+
+		// Check the download grant
+		var grant *DownloadGrant
+		if tokens := config.DownloadTokens; tokens != nil {
+			var userID string
+			if claims, ok := auth.CurrentClaims(c); ok {
+				userID = claims.Subject
+			}
+			var err error
+			grant, err = tokens.Check(c.Query(tokens.config.QueryParam), userID, path)
+			if err != nil {
+				return grantError(c, err)
+			}
+		}
 
 		// Combine with base path
-		fullPath := filepath.Join(config.BasePath, path)
+		fullPath := JoinKey(config.BasePath, path)
 
 		// Check if file exists
 		exists, err := config.Provider.Exists(ctx, fullPath)
@@ -193,10 +539,18 @@ func GetFileHandler(config UploadHandlerConfig) fiber.Handler {
 			))
 		}
 
+		// Count the download once the file is known to exist
+		if grant != nil {
+			if err := config.DownloadTokens.Take(ctx, grant); err != nil {
+				return grantError(c, err)
+			}
+		}
+
 		// Get the file from storage
 		file, fileInfo, err := config.Provider.Get(ctx, fullPath)
 		if err != nil {
-			if appErr, ok := err.(*fserrors.AppError); ok {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
 				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
 			}
 
@@ -208,11 +562,6 @@ func GetFileHandler(config UploadHandlerConfig) fiber.Handler {
 				),
 			))
 		}
-		defer file.Close()
-
-		// Get query parameters if any
-		disposition := c.Query("disposition", "inline") // inline or attachment
-		filename := c.Query("filename", fileInfo.Name)  // custom filename if provided
 
 		// Set content type based on fileInfo
 		contentType := fileInfo.ContentType
@@ -220,11 +569,167 @@ func GetFileHandler(config UploadHandlerConfig) fiber.Handler {
 			contentType = "application/octet-stream"
 		}
 
+		// Risky types are downloaded whatever the client asks for, under
+		// the custom filename if provided
+		disposition = contentDisposition(dispositions, disposition, fileInfo.Name, contentType)
+		filename := c.Query("filename", fileInfo.Name)
+
 		c.Set("Content-Type", contentType)
-		c.Set("Content-Disposition", fmt.Sprintf("%s; filename=\"%s\"", disposition, filename))
-		c.Set("Cache-Control", "public, max-age=31536000") // 1 year cache
+		c.Set("Content-Disposition", dispositionHeader(disposition, filename))
+		c.Set("X-Content-Type-Options", "nosniff")
+		if grant != nil {
+			// Shared files must not outlive the grant in caches
+			c.Set("Cache-Control", "private, no-store")
+		} else {
+			c.Set("Cache-Control", "public, max-age=31536000") // 1 year cache
+		}
+
+		streaming = true
+		return c.SendStream(&streamBody{ReadCloser: file, cancel: cancel})
+	}
+}
+
+// GetPreviewHandler returns a Fiber handler serving the PNG preview of a
+// document, rendered by a PreviewProcessor, for the path of the document.
+// Documents without a preview get 404, as do those still being processed.
+func GetPreviewHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context, cancelled once the file is sent when it is
+		// streamed after the handler returns
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		streaming := false
+		defer func() {
+			if !streaming {
+				cancel()
+			}
+		}()
+
+		// Get the sanitized document path from URL parameter
+		path := NormalizeKey(c.Params("*"))
+		if path == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"File path is required",
+				),
+			))
+		}
+
+		// Check the download grant of the document, without counting a download
+		var grant *DownloadGrant
+		if tokens := config.DownloadTokens; tokens != nil {
+			var userID string
+			if claims, ok := auth.CurrentClaims(c); ok {
+				userID = claims.Subject
+			}
+			var err error
+			grant, err = tokens.Check(c.Query(tokens.config.QueryParam), userID, path)
+			if err != nil {
+				return grantError(c, err)
+			}
+		}
+
+		// Get the preview from storage
+		file, _, err := config.Provider.Get(ctx, PreviewPath(JoinKey(config.BasePath, path)))
+		if err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
+				if appErr.Code == fserrors.ErrCodeFileNotFound {
+					appErr = fserrors.FileNotFoundError(PreviewPath(path))
+				}
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to get preview",
+				),
+			))
+		}
 
-		return c.SendStream(file)
+		var marked []byte
+		if config.PreviewWatermark != nil {
+			marked, err = config.PreviewWatermark.Watermark(ctx, file, PreviewPath(path))
+			file.Close()
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+					fserrors.WrapError(
+						err,
+						http.StatusInternalServerError,
+						"Failed to watermark preview",
+					),
+				))
+			}
+		}
+
+		c.Set("Content-Type", "image/png")
+		if grant != nil {
+			c.Set("Cache-Control", "private, no-store")
+		} else {
+			// Previews are rendered again when their document is replaced
+			c.Set("Cache-Control", "public, max-age=3600")
+		}
+
+		if marked != nil {
+			return c.Send(marked)
+		}
+		streaming = true
+		return c.SendStream(&streamBody{ReadCloser: file, cancel: cancel})
+	}
+}
+
+// GetStatusHandler returns a Fiber handler responding with the processing
+// status of a file, see ProcessingStatus, for clients to poll after an
+// upload processed in the background
+func GetStatusHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		// Get the sanitized file path from URL parameter
+		path := NormalizeKey(c.Params("*"))
+		if path == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"File path is required",
+				),
+			))
+		}
+
+		// Get the file with its metadata
+		fileInfo, err := config.Provider.GetInfo(ctx, JoinKey(config.BasePath, path))
+		if err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to get processing status",
+				),
+			))
+		}
+
+		c.Set("Cache-Control", "no-store")
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Data:    NewProcessingStatus(path, fileInfo.Metadata),
+		})
 	}
 }
 
@@ -239,8 +744,8 @@ func GetFileInfoHandler(config UploadHandlerConfig) fiber.Handler {
 		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
 		defer cancel()
 
-		// Get the file path from URL parameter
-		path := c.Params("*")
+		// Get the sanitized file path from URL parameter
+		path := NormalizeKey(c.Params("*"))
 		if path == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
 				fserrors.NewError(
@@ -250,16 +755,14 @@ func GetFileInfoHandler(config UploadHandlerConfig) fiber.Handler {
 			))
 		}
 
-		// Sanitize path
-		path = sanitizePath(path)
-
 		// Combine with base path
-		fullPath := filepath.Join(config.BasePath, path)
+		fullPath := JoinKey(config.BasePath, path)
 
 		// Get file info
 		fileInfo, err := config.Provider.GetInfo(ctx, fullPath)
 		if err != nil {
-			if appErr, ok := err.(*fserrors.AppError); ok {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
 				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
 			}
 
@@ -281,6 +784,7 @@ func GetFileInfoHandler(config UploadHandlerConfig) fiber.Handler {
 			ContentType:  fileInfo.ContentType,
 			LastModified: fileInfo.LastModified,
 			IsDirectory:  fileInfo.IsDirectory,
+			Metadata:     fileInfo.Metadata,
 		}
 
 		return c.Status(fiber.StatusOK).JSON(Response{
@@ -301,8 +805,8 @@ func DeleteFileHandler(config UploadHandlerConfig) fiber.Handler {
 		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
 		defer cancel()
 
-		// Get the file path from URL parameter
-		path := c.Params("*")
+		// Get the sanitized file path from URL parameter
+		path := NormalizeKey(c.Params("*"))
 		if path == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
 				fserrors.NewError(
@@ -312,11 +816,8 @@ func DeleteFileHandler(config UploadHandlerConfig) fiber.Handler {
 			))
 		}
 
-		// Sanitize path
-		path = sanitizePath(path)
-
 		// Combine with base path
-		fullPath := filepath.Join(config.BasePath, path)
+		fullPath := JoinKey(config.BasePath, path)
 
 		// Check if file exists
 		exists, err := config.Provider.Exists(ctx, fullPath)
@@ -338,7 +839,8 @@ func DeleteFileHandler(config UploadHandlerConfig) fiber.Handler {
 
 		// Delete the file
 		if err := config.Provider.Delete(ctx, fullPath); err != nil {
-			if appErr, ok := err.(*fserrors.AppError); ok {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
 				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
 			}
 
@@ -361,7 +863,17 @@ func DeleteFileHandler(config UploadHandlerConfig) fiber.Handler {
 	}
 }
 
-// ListFilesHandler returns a Fiber handler to list files
+// ListFilesHandler returns a Fiber handler to list files. With a cursor or
+// pageSize query parameter, it lists one page of the directory, following
+// the storage's own pages, e.g. S3 continuation tokens, so folders with tens
+// of thousands of files can be loaded lazily:
+//
+//	GET /files/photos?pageSize=200
+//	{"success":true,"data":[...],"cursor":"eyJkIjoi..."}
+//	GET /files/photos?pageSize=200&cursor=eyJkIjoi...
+//
+// The cursor of the last page is empty. Cursors are opaque and only valid
+// for the directory they were returned for.
 func ListFilesHandler(config UploadHandlerConfig) fiber.Handler {
 	if config.Provider == nil {
 		panic("filesystem provider is required")
@@ -372,19 +884,23 @@ func ListFilesHandler(config UploadHandlerConfig) fiber.Handler {
 		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
 		defer cancel()
 
-		// Get the directory path from URL parameter
-		path := c.Params("*", "")
-
-		// Sanitize path
-		path = sanitizePath(path)
+		// Get the sanitized directory path from URL parameter
+		path := NormalizeKey(c.Params("*", ""))
 
 		// Combine with base path
-		fullPath := filepath.Join(config.BasePath, path)
+		fullPath := JoinKey(config.BasePath, path)
+
+		// List a single page when a cursor or page size is given
+		args := c.Context().QueryArgs()
+		if args.Has("cursor") || args.Has("pageSize") {
+			return listFilesPage(ctx, c, config.Provider, path, fullPath)
+		}
 
 		// List files in the directory
 		files, err := config.Provider.List(ctx, fullPath)
 		if err != nil {
-			if appErr, ok := err.(*fserrors.AppError); ok {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
 				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
 			}
 
@@ -400,7 +916,7 @@ func ListFilesHandler(config UploadHandlerConfig) fiber.Handler {
 		// Convert to response format
 		var fileList []FileResponse
 		for _, file := range files {
-			relativePath := filepath.Join(path, file.Name)
+			relativePath := JoinKey(path, file.Name)
 			fileList = append(fileList, FileResponse{
 				Name:         file.Name,
 				Size:         file.Size,
@@ -419,36 +935,325 @@ func ListFilesHandler(config UploadHandlerConfig) fiber.Handler {
 	}
 }
 
-// sanitizeFilename removes potentially dangerous characters from a filename
-func sanitizeFilename(filename string) string {
-	// Get only the base name without path components
-	filename = filepath.Base(filename)
-
-	// Replace any characters that could be problematic
-	replacer := strings.NewReplacer(
-		"/", "_",
-		"\\", "_",
-		":", "_",
-		"*", "_",
-		"?", "_",
-		"\"", "_",
-		"<", "_",
-		">", "_",
-		"|", "_",
-		"%", "_",
-	)
-
-	return replacer.Replace(filename)
+// FileListResponse is the body of ListFilesHandler for a page of a listing
+type FileListResponse struct {
+	Success bool           `json:"success"`
+	Data    []FileResponse `json:"data"`
+
+	// Cursor continues the listing, and is empty on the last page
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// listFilesPage responds with one page of the storage's listing and the
+// cursor of the next
+func listFilesPage(ctx context.Context, c *fiber.Ctx, provider *Provider, path, fullPath string) error {
+	token, err := decodeListCursor(fullPath, c.Query("cursor"))
+	if err != nil {
+		return listError(c, err)
+	}
+	pageSize := c.QueryInt("pageSize", DefaultListPageSize)
+	if pageSize <= 0 {
+		pageSize = DefaultListPageSize
+	}
+	if pageSize > MaxListPageSize {
+		pageSize = MaxListPageSize
+	}
+
+	page, err := provider.ListPage(ctx, fullPath, ListOptions{Limit: pageSize, Token: token})
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(FileListResponse{
+		Success: true,
+		Data:    fileResponses(path, page.Files),
+		Cursor:  encodeListCursor(fullPath, page.NextToken),
+	})
+}
+
+// SearchHandler returns a Fiber handler searching the indexed files under
+// the base path for the words of the q query parameter, returning up to
+// limit files, best first. See IndexProcessor.
+func SearchHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		q := strings.TrimSpace(c.Query("q"))
+		if q == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"Search query is required",
+				),
+			))
+		}
+		limit := c.QueryInt("limit", DefaultSearchLimit)
+		if limit <= 0 || limit > pagination.DefaultMaxPageSize {
+			limit = pagination.DefaultMaxPageSize
+		}
+
+		hits, err := config.Provider.Search(ctx, q, SearchOptions{Dir: config.BasePath, Limit: limit})
+		if err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to search files",
+				),
+			))
+		}
+
+		// Convert to response format, with paths relative to the base path
+		fileList := make([]FileResponse, 0, len(hits))
+		for _, hit := range hits {
+			relativePath, ok := pathutil.RelativeKey(config.BasePath, hit.Path)
+			if !ok {
+				continue
+			}
+			fileList = append(fileList, FileResponse{
+				Name:         hit.Info.Name,
+				Size:         hit.Info.Size,
+				URL:          hit.Info.URL,
+				Path:         relativePath,
+				ContentType:  hit.Info.ContentType,
+				LastModified: hit.Info.LastModified,
+				Metadata:     hit.Info.Metadata,
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Data:    fileList,
+		})
+	}
+}
+
+// ListFilesPagedHandler returns a Fiber handler that lists files one page at a
+// time in the standard paginated envelope. It accepts:
+//
+//   - page and pageSize to select a page, with a total count
+//   - sort by name, size, or lastModified, e.g. ?sort=-lastModified
+//   - q to match file names, case-insensitively
+//   - cursor and limit to follow the storage's own pages instead, which avoids
+//     listing the whole directory on S3; ?limit=50 starts from the first page
+//
+// Page, sort, and search requests list the whole directory before paging it.
+func ListFilesPagedHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		// Get the sanitized directory path from URL parameter
+		path := NormalizeKey(c.Params("*", ""))
+
+		// Combine with base path
+		fullPath := JoinKey(config.BasePath, path)
+
+		// Follow the storage's pages when a cursor or limit is given without sort or search
+		args := c.Context().QueryArgs()
+		if (args.Has("cursor") || args.Has("limit")) && c.Query("sort") == "" && c.Query("q") == "" {
+			return listFilesByCursor(ctx, c, config.Provider, path, fullPath)
+		}
+
+		params, err := pagination.GetPaginationFromRequest(c)
+		if err != nil {
+			return listError(c, err)
+		}
+
+		// List the whole directory
+		files, err := config.Provider.ListAll(ctx, fullPath)
+		if err != nil {
+			return listError(c, err)
+		}
+
+		// Match the search term against file names
+		if search := strings.ToLower(params.Filter.Search); search != "" {
+			matched := files[:0]
+			for _, file := range files {
+				if strings.Contains(strings.ToLower(file.Name), search) {
+					matched = append(matched, file)
+				}
+			}
+			files = matched
+		}
+
+		if err := sortFiles(files, params.Sort); err != nil {
+			return listError(c, err)
+		}
+
+		// Select the page
+		total := len(files)
+		start := (params.Page - 1) * params.PageSize
+		if start > total {
+			start = total
+		}
+		end := start + params.PageSize
+		if end > total {
+			end = total
+		}
+		totalPages := int(math.Ceil(float64(total) / float64(params.PageSize)))
+
+		return response.SuccessWithPagination(c, "Files retrieved successfully", &pagination.PaginationResult{
+			Data: fileResponses(path, files[start:end]),
+			Meta: pagination.PaginationMeta{
+				Total:      int64(total),
+				Page:       params.Page,
+				PageSize:   params.PageSize,
+				TotalPages: totalPages,
+				HasNext:    params.Page < totalPages,
+				CountMode:  pagination.CountExact,
+				Sort:       params.Sort,
+			},
+		})
+	}
 }
 
-// sanitizePath cleans a file path and prevents directory traversal
-func sanitizePath(path string) string {
-	// Clean the path to resolve any ".." elements
-	path = filepath.Clean(path)
+// listFilesByCursor responds with one page of the storage's listing
+func listFilesByCursor(ctx context.Context, c *fiber.Ctx, provider *Provider, path, fullPath string) error {
+	params, err := pagination.GetPaginationFromRequest(c, pagination.ParamConfig{
+		PageSizeParams: []string{"limit"},
+	})
+	if err != nil {
+		return listError(c, err)
+	}
+
+	token, err := decodeListCursor(fullPath, c.Query("cursor"))
+	if err != nil {
+		return listError(c, err)
+	}
+
+	page, err := provider.ListPage(ctx, fullPath, ListOptions{
+		Limit: params.PageSize,
+		Token: token,
+	})
+	if err != nil {
+		return listError(c, err)
+	}
 
-	// Remove any leading ".." or "/"
-	path = strings.TrimPrefix(path, "../")
-	path = strings.TrimPrefix(path, "/")
+	return response.SuccessWithPagination(c, "Files retrieved successfully", &pagination.CursorResult{
+		Data: fileResponses(path, page.Files),
+		Meta: pagination.CursorMeta{
+			Limit:      params.PageSize,
+			NextCursor: encodeListCursor(fullPath, page.NextToken),
+			HasNext:    page.NextToken != "",
+			HasPrev:    c.Query("cursor") != "",
+		},
+	})
+}
+
+// fileSortKeys are the fields files can be sorted by
+var fileSortKeys = []string{"lastModified", "name", "size"}
+
+// sortFiles sorts files by the sort fields, keeping the storage order for ties
+func sortFiles(files []FileInfo, fields []pagination.SortField) error {
+	for _, field := range fields {
+		switch field.Field {
+		case "name", "size", "lastModified":
+		default:
+			return fserrors.InvalidSortError(field.Field, fileSortKeys)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		for _, field := range fields {
+			var cmp int
+			switch field.Field {
+			case "name":
+				cmp = strings.Compare(strings.ToLower(files[i].Name), strings.ToLower(files[j].Name))
+			case "size":
+				cmp = compareInt64(files[i].Size, files[j].Size)
+			case "lastModified":
+				cmp = files[i].LastModified.Compare(files[j].LastModified)
+			}
+			if cmp != 0 {
+				if field.Direction == pagination.SortDesc {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// compareInt64 returns -1, 0, or 1 as a is less than, equal to, or greater than b
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// fileResponses converts file info to the response format, encoding an empty
+// page as [] rather than null
+func fileResponses(path string, files []FileInfo) []FileResponse {
+	fileList := make([]FileResponse, 0, len(files))
+	for _, file := range files {
+		fileList = append(fileList, FileResponse{
+			Name:         file.Name,
+			Size:         file.Size,
+			URL:          file.URL,
+			Path:         JoinKey(path, file.Name),
+			ContentType:  file.ContentType,
+			LastModified: file.LastModified,
+			IsDirectory:  file.IsDirectory,
+		})
+	}
+	return fileList
+}
+
+// listError sends the error response for a failed listing
+func listError(c *fiber.Ctx, err error) error {
+	var appErr *fserrors.AppError
+	if fserrors.As(err, &appErr) {
+		return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+		fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to list files",
+		),
+	))
+}
+
+// grantError sends the error response for a refused download grant
+func grantError(c *fiber.Ctx, err error) error {
+	var appErr *fserrors.AppError
+	if fserrors.As(err, &appErr) {
+		return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+	}
 
-	return path
+	return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+		fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to check the download grant",
+		),
+	))
 }
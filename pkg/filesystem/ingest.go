@@ -0,0 +1,339 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// IngestConfig configures IngestHandler, which downloads remote files on
+// behalf of clients. The addresses of the server's own network are refused
+// unless allowed, so clients cannot reach internal services through it.
+type IngestConfig struct {
+	// Timeout limits the download, defaulting to one minute
+	Timeout time.Duration
+
+	// MaxRedirects is how many redirects are followed, defaulting to 5. A
+	// negative value follows none.
+	MaxRedirects int
+
+	// AllowedNetworks are the only networks downloaded from when set, e.g.
+	// "203.0.113.0/24". They may include private networks, which are
+	// refused otherwise.
+	AllowedNetworks []netip.Prefix
+
+	// DeniedNetworks are never downloaded from, in addition to loopback,
+	// private, link-local, and other special-purpose addresses
+	DeniedNetworks []netip.Prefix
+
+	// AllowedContentTypes are the content types downloaded, e.g.
+	// "image/*" or "application/pdf", all when empty. The upload policy
+	// checks the file like an uploaded one too.
+	AllowedContentTypes []string
+}
+
+// IngestRequest is the JSON body of IngestHandler
+type IngestRequest struct {
+	// URL is the http or https URL of the file
+	URL string `json:"url"`
+
+	// Filename names the file, defaulting to the name sent by the server
+	// or the last segment of the URL
+	Filename string `json:"filename"`
+
+	// Path is the directory of the file under the base path, like the
+	// path form field of uploads
+	Path string `json:"path"`
+
+	// Policy is the upload policy, like the policy form field of uploads
+	Policy string `json:"policy"`
+}
+
+// deniedNetworks are the special-purpose networks refused by default
+var deniedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// errAddressDenied is the cause of downloads refused for their address
+var errAddressDenied = errors.New("filesystem: address not allowed")
+
+// redirectError is the cause of downloads stopped at a redirect the client
+// is told about, e.g. "stopped after 5 redirects"
+type redirectError struct {
+	reason string
+}
+
+func (e *redirectError) Error() string {
+	return "filesystem: " + e.reason
+}
+
+// allowed reports whether files may be downloaded from an address
+func (config IngestConfig) allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, network := range config.DeniedNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	if len(config.AllowedNetworks) > 0 {
+		for _, network := range config.AllowedNetworks {
+			if network.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, network := range deniedNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// client returns an HTTP client checking the address of every connection,
+// after the name is resolved, so names resolving to internal addresses are
+// refused however often they change
+func (config IngestConfig) client() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !config.allowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errAddressDenied, address)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		// No proxy, which would connect on the client's behalf
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return &redirectError{fmt.Sprintf("stopped after %d redirects", config.MaxRedirects)}
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return &redirectError{fmt.Sprintf("redirect to unsupported scheme %q", req.URL.Scheme)}
+			}
+			return nil
+		},
+	}
+}
+
+// contentTypeAllowed reports whether a downloaded content type is allowed
+func (config IngestConfig) contentTypeAllowed(contentType string) bool {
+	if len(config.AllowedContentTypes) == 0 {
+		return true
+	}
+	rules := make(map[string]bool, len(config.AllowedContentTypes))
+	for _, allowed := range config.AllowedContentTypes {
+		rules[strings.ToLower(allowed)] = true
+	}
+	_, _, ok := matchContentType(rules, contentType)
+	return ok
+}
+
+// IngestHandler returns a Fiber handler downloading the file at the URL of
+// an IngestRequest and storing it like an upload, checked against the
+// upload policy of the request. Addresses are checked as configured by
+// UploadHandlerConfig.Ingest, and downloads larger than the largest limit
+// of the policy are stopped.
+func IngestHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+	ingest := IngestConfig{}
+	if config.Ingest != nil {
+		ingest = *config.Ingest
+	}
+	if ingest.Timeout <= 0 {
+		ingest.Timeout = time.Minute
+	}
+	if ingest.MaxRedirects == 0 {
+		ingest.MaxRedirects = 5
+	} else if ingest.MaxRedirects < 0 {
+		ingest.MaxRedirects = 0
+	}
+	client := ingest.client()
+
+	return func(c *fiber.Ctx) error {
+		var req IngestRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"Invalid ingest request body",
+				),
+			))
+		}
+
+		source, err := url.Parse(req.URL)
+		if err != nil || (source.Scheme != "http" && source.Scheme != "https") || source.Host == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fserrors.FormatErrorResponse(
+				fserrors.NewError(
+					http.StatusBadRequest,
+					"URL must be an http or https URL",
+				),
+			))
+		}
+
+		// Select the upload policy
+		policy, appErr := config.policy(req.Policy)
+		if appErr != nil {
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+
+		// Download the file
+		file, remove, appErr := download(c.Context(), client, ingest, source, req.Filename, policy)
+		if appErr != nil {
+			return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+		}
+		defer remove()
+
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		return config.store(ctx, c, file, policy, req.Policy, req.Path)
+	}
+}
+
+// download streams a remote file, of at most the largest size of a policy,
+// into an uploaded file backed by a temporary file, which remove deletes
+func download(ctx context.Context, client *http.Client, ingest IngestConfig, source *url.URL, filename string, policy UploadPolicy) (file *multipart.FileHeader, remove func(), appErr *fserrors.AppError) {
+	ctx, cancel := context.WithTimeout(ctx, ingest.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.String(), nil)
+	if err != nil {
+		return nil, nil, fserrors.NewError(http.StatusBadRequest, "URL must be an http or https URL")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, downloadError(source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fserrors.DownloadFailedError(displayURL(source), resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !ingest.contentTypeAllowed(contentType) {
+		return nil, nil, fserrors.UnsupportedMediaTypeError(contentType)
+	}
+
+	// Stop reading past the largest limit, the limit of the file's type is
+	// checked once it is named
+	maxSize := int64(policy.maxSize())
+	if resp.ContentLength > maxSize {
+		return nil, nil, fserrors.FileTooLargeError(resp.ContentLength, maxSize)
+	}
+	file, remove, err = newFileHeaderFrom(ingestFilename(filename, resp, policy.AllowedTypes), io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, nil, downloadError(source, err)
+	}
+	if file.Size > maxSize {
+		remove()
+		return nil, nil, fserrors.FileTooLargeError(file.Size, maxSize)
+	}
+	return file, remove, nil
+}
+
+// downloadError returns the error of a failed download: 403 for refused
+// addresses, DOWNLOAD_FAILED with 400 for refused redirects, 504 for
+// timeouts and 502 otherwise
+func downloadError(source *url.URL, err error) *fserrors.AppError {
+	if errors.Is(err, errAddressDenied) {
+		return fserrors.ForbiddenError("URL is not allowed")
+	}
+	var redirectErr *redirectError
+	if errors.As(err, &redirectErr) {
+		appErr := fserrors.DownloadFailedError(displayURL(source), redirectErr.reason)
+		appErr.HTTPCode = http.StatusBadRequest
+		return appErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		appErr := fserrors.DownloadFailedError(displayURL(source), "timeout")
+		appErr.HTTPCode = http.StatusGatewayTimeout
+		return appErr
+	}
+	appErr := fserrors.DownloadFailedError(displayURL(source), "unreachable")
+	appErr.Internal = err
+	return appErr
+}
+
+// ingestFilename names a downloaded file by the name of the request, or
+// else of the response or the last segment of its URL, adding the
+// extension of its content type when it has none
+func ingestFilename(filename string, resp *http.Response, allowedTypes []string) string {
+	if filename == "" {
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+			filename = params["filename"]
+		}
+	}
+	if filename == "" {
+		filename = path.Base(resp.Request.URL.Path)
+	}
+	if filename == "" || filename == "." || filename == "/" {
+		filename = "download"
+	}
+	filename = pathutil.SanitizeFilename(filename)
+
+	if extension(filename) == "" {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		extensions, _ := mime.ExtensionsByType(mediaType)
+		for _, ext := range extensions {
+			if slices.Contains(allowedTypes, ext) {
+				return filename + ext
+			}
+		}
+		if len(extensions) > 0 {
+			return filename + extensions[0]
+		}
+	}
+	return filename
+}
+
+// displayURL returns a URL without its credentials and query, which may
+// hold secrets, for errors
+func displayURL(u *url.URL) string {
+	shown := *u
+	shown.User, shown.RawQuery, shown.Fragment = nil, "", ""
+	return shown.String()
+}
@@ -0,0 +1,141 @@
+package filesystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIngestHandler(t *testing.T) {
+	// The remote server, on loopback like an internal service
+	remote := http.NewServeMux()
+	remote.HandleFunc("/photo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png data"))
+	})
+	remote.HandleFunc("/notes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("notes"))
+	})
+	remote.HandleFunc("/large.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(bytes.Repeat([]byte("x"), 64))
+	})
+	remote.HandleFunc("/streamed.png", func(w http.ResponseWriter, r *http.Request) {
+		// Flushed without a Content-Length, so the size is only known once read
+		w.Header().Set("Content-Type", "image/png")
+		for i := 0; i < 8; i++ {
+			w.Write(bytes.Repeat([]byte("x"), 8))
+			w.(http.Flusher).Flush()
+		}
+	})
+	remote.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/photo.png", http.StatusFound)
+	})
+	remote.HandleFunc("/redirect-ftp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/photo.png", http.StatusFound)
+	})
+	server := httptest.NewServer(remote)
+	defer server.Close()
+
+	// An internal host, on another loopback address, redirected to
+	internalURL := ""
+	if listener, err := net.Listen("tcp", "127.0.0.2:0"); err == nil {
+		internal := httptest.NewUnstartedServer(remote)
+		internal.Listener.Close()
+		internal.Listener = listener
+		internal.Start()
+		defer internal.Close()
+		internalURL = internal.URL
+	}
+	remote.HandleFunc("/redirect-internal", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internalURL+"/photo.png", http.StatusFound)
+	})
+
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	server1 := []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+
+	tests := []struct {
+		name   string
+		ingest IngestConfig
+		path   string
+		status int
+		code   string
+	}{
+		{"loopback refused", IngestConfig{}, "/photo.png", http.StatusForbidden, "FORBIDDEN"},
+		{"allowed network", IngestConfig{AllowedNetworks: loopback}, "/photo.png", http.StatusOK, ""},
+		{"denied network", IngestConfig{AllowedNetworks: loopback, DeniedNetworks: server1}, "/photo.png", http.StatusForbidden, "FORBIDDEN"},
+		{"redirect followed", IngestConfig{AllowedNetworks: server1}, "/redirect", http.StatusOK, ""},
+		{"redirect to internal host", IngestConfig{AllowedNetworks: server1}, "/redirect-internal", http.StatusForbidden, "FORBIDDEN"},
+		{"redirect limit", IngestConfig{AllowedNetworks: server1, MaxRedirects: -1}, "/redirect", http.StatusBadRequest, "DOWNLOAD_FAILED"},
+		{"redirect scheme", IngestConfig{AllowedNetworks: server1}, "/redirect-ftp", http.StatusBadRequest, "DOWNLOAD_FAILED"},
+		{"size cap", IngestConfig{AllowedNetworks: server1}, "/large.png", http.StatusBadRequest, "FILE_TOO_LARGE"},
+		{"size cap streamed", IngestConfig{AllowedNetworks: server1}, "/streamed.png", http.StatusBadRequest, "FILE_TOO_LARGE"},
+		{"content type allowed", IngestConfig{AllowedNetworks: server1, AllowedContentTypes: []string{"image/*"}}, "/photo.png", http.StatusOK, ""},
+		{"content type refused", IngestConfig{AllowedNetworks: server1, AllowedContentTypes: []string{"image/*"}}, "/notes", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.path == "/redirect-internal" && internalURL == "" {
+				t.Skip("127.0.0.2 is not available")
+			}
+			tempDir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(tempDir, "files"), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+			if err != nil {
+				t.Fatalf("Failed to create local storage: %v", err)
+			}
+			ingest := tt.ingest
+			app := fiber.New()
+			app.Post("/ingest", IngestHandler(UploadHandlerConfig{
+				Provider:    NewProvider(storage),
+				BasePath:    "files",
+				MaxFileSize: 32,
+				TimeoutSecs: 5,
+				Ingest:      &ingest,
+			}))
+
+			body, _ := json.Marshal(IngestRequest{URL: server.URL + tt.path})
+			req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				message, _ := io.ReadAll(resp.Body)
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, resp.StatusCode, message)
+			}
+
+			var stored struct {
+				Code string       `json:"error"`
+				Data FileResponse `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&stored); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if tt.status != http.StatusOK {
+				if stored.Code != tt.code {
+					t.Errorf("Expected error %s, got %s", tt.code, stored.Code)
+				}
+				return
+			}
+			data, err := os.ReadFile(filepath.Join(tempDir, "files", stored.Data.Path))
+			if err != nil || string(data) != "png data" {
+				t.Errorf("Expected the downloaded file to be stored, got %q and %v", data, err)
+			}
+		})
+	}
+}
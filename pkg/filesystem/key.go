@@ -0,0 +1,18 @@
+package filesystem
+
+import "github.com/anaknegeri/gokit/pkg/pathutil"
+
+// NormalizeKey returns the storage key of a path: "/" separators, also for
+// Windows paths, cleaned of "." and ".." elements, without leading or
+// trailing slashes. Keys never leave the root, so "../a" is "a", and the
+// root is "". See pathutil.NormalizeKey.
+func NormalizeKey(p string) string {
+	return pathutil.NormalizeKey(p)
+}
+
+// JoinKey joins path elements into a key, normalizing each on its own so
+// no element leaves the one before it: JoinKey("uploads", "../a") is
+// "uploads/a". See pathutil.JoinKey.
+func JoinKey(elem ...string) string {
+	return pathutil.JoinKey(elem...)
+}
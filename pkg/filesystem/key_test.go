@@ -0,0 +1,54 @@
+package filesystem
+
+import "testing"
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", ""},
+		{"/", ""},
+		{".", ""},
+		{"a.txt", "a.txt"},
+		{"/docs/a.txt", "docs/a.txt"},
+		{"docs/", "docs"},
+		{"docs//2024/./a.txt", "docs/2024/a.txt"},
+		{"../../etc/passwd", "etc/passwd"},
+		{"docs/../../a.txt", "a.txt"},
+		{`docs\2024\a.txt`, "docs/2024/a.txt"},
+		{`\docs\a.txt`, "docs/a.txt"},
+		{`docs\..\..\a.txt`, "a.txt"},
+		{`uploads\`, "uploads"},
+		{`C:\Users\me\a.txt`, "C:/Users/me/a.txt"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeKey(tt.path); got != tt.want {
+			t.Errorf("NormalizeKey(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJoinKey(t *testing.T) {
+	tests := []struct {
+		elem []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"", ""}, ""},
+		{[]string{"uploads", "a.txt"}, "uploads/a.txt"},
+		{[]string{"uploads/", "/a.txt"}, "uploads/a.txt"},
+		{[]string{"", "docs", "a.txt"}, "docs/a.txt"},
+		{[]string{`uploads\2024`, `docs\a.txt`}, "uploads/2024/docs/a.txt"},
+		{[]string{"uploads", `..\..\a.txt`}, "uploads/a.txt"},
+		{[]string{"uploads", "../secret", "a.txt"}, "uploads/secret/a.txt"},
+		{[]string{"uploads", "."}, "uploads"},
+	}
+
+	for _, tt := range tests {
+		if got := JoinKey(tt.elem...); got != tt.want {
+			t.Errorf("JoinKey(%q) = %q, want %q", tt.elem, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package filesystem
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+)
+
+const (
+	// DefaultListPageSize is how many entries ListFilesHandler returns per
+	// page when a cursor is given without a page size
+	DefaultListPageSize = 100
+
+	// MaxListPageSize is the most entries ListFilesHandler returns per page,
+	// the most S3 returns in a single listing request
+	MaxListPageSize = s3MaxKeys
+)
+
+// listCursor is the content of the cursors of the list handlers, binding
+// the token of the storage to the directory it lists
+type listCursor struct {
+	Dir   string `json:"d"`
+	Token string `json:"t"`
+}
+
+// encodeListCursor returns the opaque, URL-safe cursor continuing the
+// listing of a directory from a storage token, or "" for the last page
+func encodeListCursor(dir, token string) string {
+	if token == "" {
+		return ""
+	}
+	data, _ := json.Marshal(listCursor{Dir: dir, Token: token})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor returns the storage token of a cursor of a directory, or
+// an INVALID_CURSOR error for cursors that are malformed or were issued for
+// another directory
+func decodeListCursor(dir, cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fserrors.InvalidCursorError()
+	}
+	var decoded listCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Dir != dir || decoded.Token == "" {
+		return "", fserrors.InvalidCursorError()
+	}
+	return decoded.Token, nil
+}
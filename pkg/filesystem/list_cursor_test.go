@@ -0,0 +1,85 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestListFilesHandlerCursor(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "photos"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		name := filepath.Join(tempDir, "photos", fmt.Sprintf("photo-%d.jpg", i))
+		if err := os.WriteFile(name, []byte("jpeg"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	app := fiber.New()
+	app.Get("/files/*", ListFilesHandler(UploadHandlerConfig{Provider: NewProvider(storage), TimeoutSecs: 5}))
+
+	list := func(query string) (int, FileListResponse) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/files/photos?"+query, nil))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var body FileListResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp.StatusCode, body
+	}
+
+	// Follow the cursors to the last page
+	seen := map[string]bool{}
+	query := "pageSize=2"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("Expected the listing to end")
+		}
+		status, body := list(query)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+		if len(body.Data) > 2 {
+			t.Errorf("Expected at most 2 files, got %d", len(body.Data))
+		}
+		for _, file := range body.Data {
+			if seen[file.Path] {
+				t.Errorf("File %q listed twice", file.Path)
+			}
+			seen[file.Path] = true
+		}
+		if body.Cursor == "" {
+			break
+		}
+		query = "pageSize=2&cursor=" + url.QueryEscape(body.Cursor)
+	}
+	if len(seen) != 5 {
+		t.Errorf("Expected 5 files across pages, got %d", len(seen))
+	}
+
+	// Cursors are rejected when malformed or issued for another directory
+	if status, _ := list("cursor=invalid"); status != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed cursor, got %d", status)
+	}
+	if status, _ := list("cursor=" + encodeListCursor("videos", "2")); status != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a cursor of another directory, got %d", status)
+	}
+}
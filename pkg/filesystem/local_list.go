@@ -0,0 +1,205 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+)
+
+// DefaultLocalListConcurrency is how many entries of a large directory
+// LocalStorage.List stats at once
+const DefaultLocalListConcurrency = 8
+
+const (
+	// localListBatchSize is how many entries are read from a directory at
+	// once, so huge directories are never held in memory twice
+	localListBatchSize = 1024
+
+	// localListParallelMin is the fewest entries stat'ed concurrently.
+	// Smaller directories are stat'ed one by one, which is faster.
+	localListParallelMin = 256
+)
+
+// List returns a list of files from a directory in local storage, in name
+// order, see ListFunc
+func (ls *LocalStorage) List(ctx context.Context, path string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := ls.ListFunc(ctx, path, true, func(info FileInfo) error {
+		files = append(files, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// ListFunc calls fn with the entries of a directory as they are read, in
+// directory order, without holding the listing in memory:
+//
+//	err := local.ListFunc(ctx, "uploads", false, func(info filesystem.FileInfo) error {
+//		fmt.Println(info.Name)
+//		return nil
+//	})
+//
+// Links are listed as their target, or left out, see SymlinkPolicy. Without
+// stat, the entries carry their name, type, URL, and content type,
+// saving a system call per entry; GetInfo returns their size and
+// modification time. Entries are stat'ed ListConcurrency at a time in large
+// directories. Entries removed while listed are skipped. The listing stops
+// when ctx is done, or with the error returned by fn.
+func (ls *LocalStorage) ListFunc(ctx context.Context, path string, stat bool, fn func(info FileInfo) error) error {
+	fullPath, err := ls.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	// Check if directory exists
+	fileInfo, err := os.Stat(fullPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fserrors.FileNotFoundError(path)
+		}
+		return fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			fmt.Sprintf("Failed to access directory: %s", path),
+		)
+	}
+
+	// If path is a file, return it as a single item
+	if !fileInfo.IsDir() {
+		return fn(FileInfo{
+			Name:         filepath.Base(path),
+			Size:         fileInfo.Size(),
+			LastModified: fileInfo.ModTime(),
+			URL:          ls.url(path),
+			ContentType:  ls.getContentType(filepath.Ext(fullPath)),
+		})
+	}
+
+	dir, err := os.Open(fullPath)
+	if err != nil {
+		return fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			fmt.Sprintf("Failed to read directory: %s", path),
+		)
+	}
+	defer dir.Close()
+
+	infos := make([]fs.FileInfo, localListBatchSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entries, readErr := dir.ReadDir(localListBatchSize)
+		if stat {
+			ls.statEntries(ctx, entries, infos[:len(entries)])
+		}
+		for i, entry := range entries {
+			info := FileInfo{
+				Name:        entry.Name(),
+				URL:         ls.url(filepath.Join(path, entry.Name())),
+				IsDirectory: entry.IsDir(),
+			}
+			entryInfo := infos[i]
+			if entry.Type()&fs.ModeSymlink != 0 {
+				// Links are listed as their target, or left out by the symlink policy
+				target, ok := ls.followEntry(filepath.Join(fullPath, entry.Name()))
+				if !ok {
+					continue
+				}
+				info.IsDirectory = target.IsDir()
+				entryInfo = target
+			}
+			if stat {
+				// Skip entries removed since they were read
+				if entryInfo == nil {
+					continue
+				}
+				info.Size = entryInfo.Size()
+				info.LastModified = entryInfo.ModTime()
+				info.IsDirectory = entryInfo.IsDir()
+			}
+			if !info.IsDirectory {
+				info.ContentType = ls.getContentType(filepath.Ext(entry.Name()))
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			return ctx.Err()
+		}
+		if readErr != nil {
+			return fserrors.WrapError(
+				readErr,
+				http.StatusInternalServerError,
+				fmt.Sprintf("Failed to read directory: %s", path),
+			)
+		}
+	}
+}
+
+// statEntries stats the entries of a directory into infos, nil for the
+// entries that cannot be stat'ed or when ctx is done
+func (ls *LocalStorage) statEntries(ctx context.Context, entries []fs.DirEntry, infos []fs.FileInfo) {
+	stat := func(i int) {
+		infos[i] = nil
+		if ctx.Err() != nil {
+			return
+		}
+		if info, err := entries[i].Info(); err == nil {
+			infos[i] = info
+		}
+	}
+
+	workers := ls.listConcurrency
+	if workers <= 1 || len(entries) < localListParallelMin {
+		for i := range entries {
+			stat(i)
+		}
+		return
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(entries) {
+					return
+				}
+				stat(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// url returns the URL of a path, under the base URL when one is set
+func (ls *LocalStorage) url(path string) string {
+	if ls.baseURL == "" {
+		return path
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimRight(ls.baseURL, "/"), strings.TrimLeft(path, "/"))
+}
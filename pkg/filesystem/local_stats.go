@@ -0,0 +1,163 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/metrics"
+)
+
+// errDiskUsageUnsupported is returned by diskUsage on platforms where the
+// disk space cannot be read
+var errDiskUsageUnsupported = errors.New("filesystem: disk usage is not supported on this platform")
+
+// diskSpace is the space and inodes of a filesystem
+type diskSpace struct {
+	TotalBytes  uint64
+	FreeBytes   uint64
+	TotalInodes uint64
+	FreeInodes  uint64
+}
+
+// LocalStats reports the disk usage of a LocalStorage
+type LocalStats struct {
+	// TotalBytes and FreeBytes are the size of the filesystem of the base
+	// path and the space left for the process
+	TotalBytes uint64 `json:"totalBytes"`
+	FreeBytes  uint64 `json:"freeBytes"`
+
+	// TotalInodes and FreeInodes are zero on filesystems without a fixed
+	// number of inodes, such as btrfs
+	TotalInodes uint64 `json:"totalInodes"`
+	FreeInodes  uint64 `json:"freeInodes"`
+
+	// UsedBytes, Files, and Directories count what is under the base path
+	UsedBytes   int64 `json:"usedBytes"`
+	Files       int64 `json:"files"`
+	Directories int64 `json:"directories"`
+}
+
+// Stats reports the free space of the filesystem and counts the files under
+// the base path, which walks the whole tree, so it suits a scheduled task
+// rather than every request. It also updates the file gauges of
+// ExportMetrics.
+func (ls *LocalStorage) Stats(ctx context.Context) (*LocalStats, error) {
+	stats := &LocalStats{}
+
+	disk, err := diskUsage(ls.basePath)
+	if err != nil && !errors.Is(err, errDiskUsageUnsupported) {
+		return nil, fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to read disk usage",
+		)
+	}
+	stats.TotalBytes = disk.TotalBytes
+	stats.FreeBytes = disk.FreeBytes
+	stats.TotalInodes = disk.TotalInodes
+	stats.FreeInodes = disk.FreeInodes
+
+	err = filepath.WalkDir(ls.basePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == ls.basePath {
+			return nil
+		}
+		if entry.IsDir() {
+			stats.Directories++
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed while walking
+			return nil
+		}
+		stats.Files++
+		stats.UsedBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to count files",
+		)
+	}
+
+	ls.usedBytes.Store(stats.UsedBytes)
+	ls.files.Store(stats.Files)
+	return stats, nil
+}
+
+// ExportMetrics records the disk usage of the storage in a registry,
+// defaulting to metrics.Default():
+//
+//	storage_local_disk_total_bytes    size of the filesystem
+//	storage_local_disk_free_bytes     space left, read at each export
+//	storage_local_disk_free_inodes    inodes left, read at each export
+//	storage_local_used_bytes          bytes under the base path, as of the last Stats
+//	storage_local_files               files under the base path, as of the last Stats
+func (ls *LocalStorage) ExportMetrics(r *metrics.Registry) {
+	if r == nil {
+		r = metrics.Default()
+	}
+	disk := func(field func(diskSpace) uint64) func() float64 {
+		return func() float64 {
+			space, err := diskUsage(ls.basePath)
+			if err != nil {
+				return 0
+			}
+			return float64(field(space))
+		}
+	}
+
+	r.GaugeFunc("storage_local_disk_total_bytes", "Size of the filesystem of the local storage in bytes",
+		disk(func(s diskSpace) uint64 { return s.TotalBytes }))
+	r.GaugeFunc("storage_local_disk_free_bytes", "Free space of the filesystem of the local storage in bytes",
+		disk(func(s diskSpace) uint64 { return s.FreeBytes }))
+	r.GaugeFunc("storage_local_disk_free_inodes", "Free inodes of the filesystem of the local storage",
+		disk(func(s diskSpace) uint64 { return s.FreeInodes }))
+	r.GaugeFunc("storage_local_used_bytes", "Bytes of the files in the local storage",
+		func() float64 { return float64(ls.usedBytes.Load()) })
+	r.GaugeFunc("storage_local_files", "Files in the local storage",
+		func() float64 { return float64(ls.files.Load()) })
+}
+
+// checkFreeSpace refuses an upload of size bytes with 507 when it would
+// leave less free space or inodes than configured
+func (ls *LocalStorage) checkFreeSpace(size int64) error {
+	if ls.minFreeBytes <= 0 && ls.minFreeInodes == 0 {
+		return nil
+	}
+
+	disk, err := diskUsage(ls.basePath)
+	if errors.Is(err, errDiskUsageUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to read disk usage",
+		)
+	}
+
+	if size < 0 {
+		size = 0
+	}
+	if disk.FreeBytes < uint64(size)+uint64(max(ls.minFreeBytes, 0)) {
+		return fserrors.InsufficientStorageError(size)
+	}
+	if disk.TotalInodes > 0 && disk.FreeInodes <= ls.minFreeInodes {
+		return fserrors.InsufficientStorageError(size)
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
 )
@@ -20,6 +21,17 @@ type LocalStorage struct {
 	basePath          string
 	baseURL           string
 	createDirectories bool
+	minFreeBytes      int64
+	minFreeInodes     uint64
+	listConcurrency   int
+	symlinks          SymlinkPolicy
+
+	// realBasePath is the absolute base path with its links resolved
+	realBasePath string
+
+	// usedBytes and files are the totals of the last Stats
+	usedBytes atomic.Int64
+	files     atomic.Int64
 }
 
 // LocalStorageConfig holds configuration for the local storage provider
@@ -27,6 +39,20 @@ type LocalStorageConfig struct {
 	BasePath          string
 	BaseURL           string
 	CreateDirectories bool
+
+	// MinFreeBytes and MinFreeInodes refuse uploads with 507 Insufficient
+	// Storage when they would leave less free space or inodes on the disk.
+	// Zero disables the check.
+	MinFreeBytes  int64
+	MinFreeInodes uint64
+
+	// ListConcurrency is how many entries of a large directory List stats at
+	// once, defaulting to DefaultLocalListConcurrency; 1 stats them one by one
+	ListConcurrency int
+
+	// Symlinks is how the symbolic links under the base path are treated,
+	// defaulting to SymlinkFollow
+	Symlinks SymlinkPolicy
 }
 
 // NewLocalStorage creates a new local storage provider
@@ -45,16 +71,78 @@ func NewLocalStorage(config LocalStorageConfig) (*LocalStorage, error) {
 		)
 	}
 
+	realBasePath, err := filepath.EvalSymlinks(basePath)
+	if err == nil {
+		realBasePath, err = filepath.Abs(realBasePath)
+	}
+	if err != nil {
+		return nil, fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			fmt.Sprintf("Failed to resolve base directory: %s", basePath),
+		)
+	}
+
+	symlinks := config.Symlinks
+	switch symlinks {
+	case "":
+		symlinks = SymlinkFollow
+	case SymlinkFollow, SymlinkReject, SymlinkSkip:
+	default:
+		return nil, fmt.Errorf("filesystem: unknown symlink policy: %s", symlinks)
+	}
+
+	listConcurrency := config.ListConcurrency
+	if listConcurrency <= 0 {
+		listConcurrency = DefaultLocalListConcurrency
+	}
+
 	return &LocalStorage{
 		basePath:          basePath,
 		baseURL:           config.BaseURL,
 		createDirectories: config.CreateDirectories,
+		minFreeBytes:      config.MinFreeBytes,
+		minFreeInodes:     config.MinFreeInodes,
+		listConcurrency:   listConcurrency,
+		symlinks:          symlinks,
+		realBasePath:      realBasePath,
 	}, nil
 }
 
 // Upload saves a file to local storage
 func (ls *LocalStorage) Upload(ctx context.Context, file *multipart.FileHeader, path string) (*FileInfo, error) {
-	fullPath := filepath.Join(ls.basePath, path)
+	// Open the uploaded file
+	src, err := file.Open()
+	if err != nil {
+		return nil, fserrors.WrapError(
+			err,
+			http.StatusInternalServerError,
+			"Failed to open uploaded file",
+		)
+	}
+	defer src.Close()
+
+	return ls.write(path, src, file.Size)
+}
+
+// Copy copies a file to a path where no file exists yet
+func (ls *LocalStorage) Copy(ctx context.Context, srcPath, dstPath string) (*FileInfo, error) {
+	src, info, err := ls.Get(ctx, srcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return ls.write(dstPath, src, info.Size)
+}
+
+// write saves the contents of a file of a size to a path where no file
+// exists yet
+func (ls *LocalStorage) write(path string, src io.Reader, size int64) (*FileInfo, error) {
+	fullPath, err := ls.resolve(path)
+	if err != nil {
+		return nil, err
+	}
 
 	// Ensure the directory exists if createDirectories is true
 	if ls.createDirectories {
@@ -87,16 +175,10 @@ func (ls *LocalStorage) Upload(ctx context.Context, file *multipart.FileHeader,
 		)
 	}
 
-	// Open the uploaded file
-	src, err := file.Open()
-	if err != nil {
-		return nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			"Failed to open uploaded file",
-		)
+	// Keep space free for the rest of the system
+	if err := ls.checkFreeSpace(size); err != nil {
+		return nil, err
 	}
-	defer src.Close()
 
 	// Create the destination file
 	dst, err := os.Create(fullPath)
@@ -110,7 +192,7 @@ func (ls *LocalStorage) Upload(ctx context.Context, file *multipart.FileHeader,
 	defer dst.Close()
 
 	// Copy the file contents
-	if _, err = io.Copy(dst, src); err != nil {
+	if _, err = copyBuffer(dst, src); err != nil {
 		return nil, fserrors.WrapError(
 			err,
 			http.StatusInternalServerError,
@@ -149,7 +231,10 @@ func (ls *LocalStorage) Upload(ctx context.Context, file *multipart.FileHeader,
 
 // Get retrieves a file from local storage
 func (ls *LocalStorage) Get(ctx context.Context, path string) (io.ReadCloser, *FileInfo, error) {
-	fullPath := filepath.Join(ls.basePath, path)
+	fullPath, err := ls.resolve(path)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Check if file exists
 	fileInfo, err := os.Stat(fullPath)
@@ -204,7 +289,10 @@ func (ls *LocalStorage) Get(ctx context.Context, path string) (io.ReadCloser, *F
 
 // Delete removes a file from local storage
 func (ls *LocalStorage) Delete(ctx context.Context, path string) error {
-	fullPath := filepath.Join(ls.basePath, path)
+	fullPath, err := ls.resolve(path)
+	if err != nil {
+		return err
+	}
 
 	// Check if file exists
 	fileInfo, err := os.Stat(fullPath)
@@ -242,109 +330,35 @@ func (ls *LocalStorage) Delete(ctx context.Context, path string) error {
 
 // Exists checks if a file exists in local storage
 func (ls *LocalStorage) Exists(ctx context.Context, path string) (bool, error) {
-	fullPath := filepath.Join(ls.basePath, path)
-
-	_, err := os.Stat(fullPath)
+	fullPath, err := ls.resolve(path)
 	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
+		if fserrors.Is(err, fserrors.ErrFileNotFound) {
 			return false, nil
 		}
-		return false, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to check file existence: %s", path),
-		)
+		return false, err
 	}
 
-	return true, nil
-}
-
-// List returns a list of files from a directory in local storage
-func (ls *LocalStorage) List(ctx context.Context, path string) ([]FileInfo, error) {
-	fullPath := filepath.Join(ls.basePath, path)
-
-	// Check if directory exists
-	fileInfo, err := os.Stat(fullPath)
+	_, err = os.Stat(fullPath)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			return nil, fserrors.FileNotFoundError(path)
-		}
-		return nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to access directory: %s", path),
-		)
-	}
-
-	// If path is a file, return it as a single item
-	if !fileInfo.IsDir() {
-		contentType := ls.getContentType(filepath.Ext(fullPath))
-
-		// Construct URL
-		url := path
-		if ls.baseURL != "" {
-			url = fmt.Sprintf("%s/%s", strings.TrimRight(ls.baseURL, "/"), strings.TrimLeft(path, "/"))
+			return false, nil
 		}
-
-		return []FileInfo{
-			{
-				Name:         filepath.Base(path),
-				Size:         fileInfo.Size(),
-				LastModified: fileInfo.ModTime(),
-				URL:          url,
-				ContentType:  contentType,
-				IsDirectory:  false,
-			},
-		}, nil
-	}
-
-	// Read directory contents
-	entries, err := os.ReadDir(fullPath)
-	if err != nil {
-		return nil, fserrors.WrapError(
+		return false, fserrors.WrapError(
 			err,
 			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to read directory: %s", path),
+			fmt.Sprintf("Failed to check file existence: %s", path),
 		)
 	}
 
-	var files []FileInfo
-	for _, entry := range entries {
-		entryInfo, err := entry.Info()
-		if err != nil {
-			// Skip entries with errors
-			continue
-		}
-
-		relativePath := filepath.Join(path, entry.Name())
-
-		// Construct URL
-		url := relativePath
-		if ls.baseURL != "" {
-			url = fmt.Sprintf("%s/%s", strings.TrimRight(ls.baseURL, "/"), strings.TrimLeft(relativePath, "/"))
-		}
-
-		contentType := ""
-		if !entryInfo.IsDir() {
-			contentType = ls.getContentType(filepath.Ext(entry.Name()))
-		}
-
-		files = append(files, FileInfo{
-			Name:         entry.Name(),
-			Size:         entryInfo.Size(),
-			LastModified: entryInfo.ModTime(),
-			URL:          url,
-			ContentType:  contentType,
-			IsDirectory:  entryInfo.IsDir(),
-		})
-	}
-
-	return files, nil
+	return true, nil
 }
 
 // GetInfo returns information about a file without fetching its contents
 func (ls *LocalStorage) GetInfo(ctx context.Context, path string) (*FileInfo, error) {
-	fullPath := filepath.Join(ls.basePath, path)
+	fullPath, err := ls.resolve(path)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check if file exists
 	fileInfo, err := os.Stat(fullPath)
@@ -435,6 +449,12 @@ func (ls *LocalStorage) getContentType(ext string) string {
 		return "video/quicktime"
 	case ".webm":
 		return "video/webm"
+	case ".mkv":
+		return "video/x-matroska"
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
 	default:
 		return "application/octet-stream"
 	}
@@ -3,12 +3,16 @@ package filesystem
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
 )
 
 func TestLocalStorage(t *testing.T) {
@@ -108,12 +112,14 @@ func TestLocalStorage(t *testing.T) {
 			t.Fatalf("Error closing writer: %v", err)
 		}
 
-		// Create file header
-		header := &multipart.FileHeader{
-			Filename: "upload-test.txt",
-			Size:     int64(len(uploadContent)),
-			Header:   make(map[string][]string),
+		// Parse the multipart body to get a file header backed by the content
+		form, err := multipart.NewReader(buffer, writer.Boundary()).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatalf("Error reading multipart form: %v", err)
 		}
+		defer form.RemoveAll()
+
+		header := form.File["file"][0]
 
 		// Test upload
 		fileInfo, err := storage.Upload(ctx, header, "subfolder/upload-test.txt")
@@ -227,4 +233,203 @@ func TestLocalStorage(t *testing.T) {
 			t.Errorf("Expected IsDirectory to be false, got true")
 		}
 	})
+
+	// Test ListPage through the provider, which pages local listings with offset tokens
+	t.Run("ListPage", func(t *testing.T) {
+		provider := NewProvider(storage)
+
+		all, err := provider.ListAll(ctx, "")
+		if err != nil {
+			t.Fatalf("Error listing files: %v", err)
+		}
+
+		var paged []FileInfo
+		opts := ListOptions{Limit: 2}
+		for {
+			page, err := provider.ListPage(ctx, "", opts)
+			if err != nil {
+				t.Fatalf("Error listing page: %v", err)
+			}
+			if len(page.Files) > opts.Limit {
+				t.Errorf("Expected at most %d files, got %d", opts.Limit, len(page.Files))
+			}
+			paged = append(paged, page.Files...)
+			if page.NextToken == "" {
+				break
+			}
+			opts.Token = page.NextToken
+		}
+
+		if len(paged) != len(all) {
+			t.Errorf("Expected %d files across pages, got %d", len(all), len(paged))
+		}
+
+		// Invalid tokens are rejected
+		if _, err := provider.ListPage(ctx, "", ListOptions{Token: "invalid"}); err == nil {
+			t.Errorf("Expected an error for an invalid token")
+		}
+	})
+}
+
+func TestLocalStorageListLarge(t *testing.T) {
+	tempDir := t.TempDir()
+	dir := filepath.Join(tempDir, "big")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	// More entries than a read batch, stat'ed concurrently
+	const count = localListBatchSize + localListParallelMin
+	for i := 0; i < count; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("%05d.txt", count-i))
+		if err := os.WriteFile(name, bytes.Repeat([]byte("x"), i%7), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	ctx := context.Background()
+
+	files, err := storage.List(ctx, "big")
+	if err != nil {
+		t.Fatalf("Failed to list files: %v", err)
+	}
+	if len(files) != count+1 {
+		t.Fatalf("Expected %d entries, got %d", count+1, len(files))
+	}
+	for i, file := range files {
+		if i > 0 && files[i-1].Name >= file.Name {
+			t.Fatalf("Expected entries in name order, got %s before %s", files[i-1].Name, file.Name)
+		}
+		if file.IsDirectory {
+			continue
+		}
+		var n int
+		fmt.Sscanf(file.Name, "%05d.txt", &n)
+		if want := int64((count - n) % 7); file.Size != want || file.LastModified.IsZero() {
+			t.Fatalf("Expected %s of %d bytes with a modification time, got %+v", file.Name, want, file)
+		}
+	}
+
+	// Without stat, entries have their type but no size
+	dirs := 0
+	err = storage.ListFunc(ctx, "big", false, func(info FileInfo) error {
+		if info.IsDirectory {
+			dirs++
+		}
+		if info.Size != 0 || !info.LastModified.IsZero() {
+			t.Fatalf("Expected %s without stat, got %+v", info.Name, info)
+		}
+		return nil
+	})
+	if err != nil || dirs != 1 {
+		t.Fatalf("Expected 1 directory and no error, got %d and %v", dirs, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := storage.List(canceled, "big"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLocalStorageSymlinks(t *testing.T) {
+	root := t.TempDir()
+	basePath := filepath.Join(root, "base")
+	outside := filepath.Join(root, "outside")
+	for _, dir := range []string{filepath.Join(basePath, "docs"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(basePath, "docs", "a.txt"), []byte("inside"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("outside"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	links := map[string]string{
+		"in.txt":  filepath.Join(basePath, "docs", "a.txt"),
+		"indir":   "docs",
+		"out.txt": filepath.Join(outside, "secret.txt"),
+		"outdir":  "../outside",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(basePath, name)); err != nil {
+			t.Skipf("Symbolic links are not supported: %v", err)
+		}
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		policy SymlinkPolicy
+		get    map[string]int // path -> HTTP status, 0 for success
+		listed []string
+	}{
+		{
+			policy: SymlinkFollow,
+			get:    map[string]int{"in.txt": 0, "indir/a.txt": 0, "out.txt": 400, "outdir/secret.txt": 400},
+			listed: []string{"docs", "in.txt", "indir"},
+		},
+		{
+			policy: SymlinkReject,
+			get:    map[string]int{"docs/a.txt": 0, "in.txt": 400, "indir/a.txt": 400, "out.txt": 400},
+			listed: []string{"docs"},
+		},
+		{
+			policy: SymlinkSkip,
+			get:    map[string]int{"docs/a.txt": 0, "in.txt": 404, "indir/a.txt": 404, "outdir/secret.txt": 404},
+			listed: []string{"docs"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			storage, err := NewLocalStorage(LocalStorageConfig{BasePath: basePath, Symlinks: tt.policy})
+			if err != nil {
+				t.Fatalf("Failed to create local storage: %v", err)
+			}
+
+			for path, status := range tt.get {
+				file, _, err := storage.Get(ctx, path)
+				if err == nil {
+					file.Close()
+				}
+				var appErr *fserrors.AppError
+				switch {
+				case status == 0 && err != nil:
+					t.Errorf("Expected %s to be read, got %v", path, err)
+				case status != 0 && (!fserrors.As(err, &appErr) || appErr.HTTPCode != status):
+					t.Errorf("Expected status %d for %s, got %v", status, path, err)
+				}
+			}
+
+			files, err := storage.List(ctx, "")
+			if err != nil {
+				t.Fatalf("Failed to list files: %v", err)
+			}
+			var names []string
+			for _, file := range files {
+				names = append(names, file.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.listed) {
+				t.Errorf("Expected %v listed, got %v", tt.listed, names)
+			}
+
+			// Nothing is written or deleted outside of the base path
+			if err := storage.Delete(ctx, "outdir/secret.txt"); err == nil {
+				t.Errorf("Expected the deletion through outdir to be refused")
+			}
+			if _, err := storage.write("outdir/new.txt", bytes.NewReader([]byte("x")), 1); err == nil {
+				t.Errorf("Expected the write through outdir to be refused")
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(outside, "secret.txt")); err != nil {
+		t.Errorf("Expected the file outside of the base path to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Errorf("Expected no file written outside of the base path")
+	}
 }
@@ -0,0 +1,96 @@
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+)
+
+// SymlinkPolicy is how LocalStorage treats the symbolic links under its
+// base path. Whatever the policy, links never lead outside of the base path.
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow follows the links to files and directories under the
+	// base path. Paths through links leading outside of it are refused with
+	// InvalidPathError, and such links are left out of listings.
+	SymlinkFollow SymlinkPolicy = "follow"
+
+	// SymlinkReject refuses paths through links with InvalidPathError.
+	// Links are left out of listings.
+	SymlinkReject SymlinkPolicy = "reject"
+
+	// SymlinkSkip treats links as if they did not exist: paths through them
+	// are not found, and they are left out of listings
+	SymlinkSkip SymlinkPolicy = "skip"
+)
+
+// resolve returns the local path of a storage path after checking the
+// links along it against the symlink policy. Parts of the path that do not
+// exist yet are left to the caller.
+func (ls *LocalStorage) resolve(path string) (string, error) {
+	fullPath := pathutil.Join(ls.basePath, path)
+
+	current := ls.basePath
+	for _, part := range strings.Split(pathutil.NormalizeKey(path), "/") {
+		if part == "" {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			break
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		switch ls.symlinks {
+		case SymlinkReject:
+			return "", fserrors.InvalidPathError(path, "symbolic links are not allowed")
+		case SymlinkSkip:
+			return "", fserrors.FileNotFoundError(path)
+		}
+		target, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			// A dangling link would create its target wherever it points
+			return "", fserrors.FileNotFoundError(path)
+		}
+		if !ls.underBase(target) {
+			return "", fserrors.InvalidPathError(path, "symbolic link leads outside of the storage")
+		}
+	}
+	return fullPath, nil
+}
+
+// followEntry returns the information of the target of a link listed in a
+// directory, or false when the policy leaves the link out of listings
+func (ls *LocalStorage) followEntry(fullPath string) (fs.FileInfo, bool) {
+	if ls.symlinks != SymlinkFollow {
+		return nil, false
+	}
+	target, err := filepath.EvalSymlinks(fullPath)
+	if err != nil || !ls.underBase(target) {
+		return nil, false
+	}
+	info, err := os.Stat(target)
+	return info, err == nil
+}
+
+// underBase reports whether a path with its links resolved is under the
+// base path
+func (ls *LocalStorage) underBase(target string) bool {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(ls.realBasePath, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
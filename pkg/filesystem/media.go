@@ -0,0 +1,217 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// HLSSuffix is appended to the path of a video for the directory of its HLS
+// stream, so "videos/intro.mp4" is streamed from
+// "videos/intro.mp4.hls/master.m3u8"
+const HLSSuffix = ".hls"
+
+// DefaultVideoExtensions are the videos transcoded by a VideoProcessor by
+// default
+var DefaultVideoExtensions = []string{".mp4", ".mov", ".m4v", ".mkv", ".webm", ".avi"}
+
+// DefaultRenditions are the renditions of videos by default
+var DefaultRenditions = []Rendition{
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+}
+
+// HLSPath returns the path of the master playlist of a video
+func HLSPath(filePath string) string {
+	return path.Join(cleanPath(filePath)+HLSSuffix, "master.m3u8")
+}
+
+// Rendition is a quality of a transcoded video
+type Rendition struct {
+	// Name names the directory of the rendition, e.g. "720p"
+	Name string
+
+	// Height is the height of the video in pixels; the width keeps the
+	// aspect ratio
+	Height int
+
+	// VideoBitrate and AudioBitrate are in kilobits per second
+	VideoBitrate int
+	AudioBitrate int
+}
+
+// bandwidth is the peak bandwidth of a rendition in bits per second, for
+// the master playlist
+func (r Rendition) bandwidth() int {
+	return (r.VideoBitrate + r.AudioBitrate) * 1000
+}
+
+// MediaRunner transcodes videos, e.g. FFmpegRunner
+type MediaRunner interface {
+	// HLS transcodes the video at input to a rendition, writing its playlist
+	// "index.m3u8" and segments to dir
+	HLS(ctx context.Context, input, dir string, rendition Rendition) error
+}
+
+// FFmpegRunner transcodes videos with ffmpeg, which must be installed
+type FFmpegRunner struct {
+	// Path is the ffmpeg executable, defaulting to "ffmpeg"
+	Path string
+
+	// SegmentSeconds is the length of HLS segments, defaulting to 6
+	SegmentSeconds int
+
+	// Preset is the x264 preset, defaulting to "veryfast"
+	Preset string
+}
+
+// HLS runs ffmpeg to transcode a video to H.264 and AAC in HLS segments
+func (f *FFmpegRunner) HLS(ctx context.Context, input, dir string, rendition Rendition) error {
+	executable := f.Path
+	if executable == "" {
+		executable = "ffmpeg"
+	}
+	segment := f.SegmentSeconds
+	if segment <= 0 {
+		segment = 6
+	}
+	preset := f.Preset
+	if preset == "" {
+		preset = "veryfast"
+	}
+
+	videoBitrate := strconv.Itoa(rendition.VideoBitrate) + "k"
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", input,
+		"-vf", fmt.Sprintf("scale=-2:%d", rendition.Height),
+		"-c:v", "libx264", "-preset", preset, "-profile:v", "main",
+		"-b:v", videoBitrate, "-maxrate", videoBitrate, "-bufsize", strconv.Itoa(rendition.VideoBitrate*2) + "k",
+		"-c:a", "aac", "-b:a", strconv.Itoa(rendition.AudioBitrate) + "k",
+		"-f", "hls", "-hls_time", strconv.Itoa(segment), "-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%04d.ts"),
+		filepath.Join(dir, "index.m3u8"),
+	}
+
+	cmd := exec.CommandContext(ctx, executable, args...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("filesystem: ffmpeg %s: %w: %s", rendition.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// VideoConfig configures a VideoProcessor
+type VideoConfig struct {
+	// Runner defaults to an FFmpegRunner
+	Runner MediaRunner
+
+	// Renditions default to DefaultRenditions
+	Renditions []Rendition
+
+	// Extensions default to DefaultVideoExtensions
+	Extensions []string
+}
+
+// VideoProcessor is a processor packaging videos for HLS streaming: each
+// rendition is transcoded into its own playlist, listed by a master
+// playlist at HLSPath, recorded in the "hls" metadata. The outputs are
+// saved in the storage of the video and deleted with it. Transcoding takes
+// long, so run it in a pipeline with a queue whose Timeout allows for the
+// longest videos, and poll GetStatusHandler for its status. Its name is
+// "video".
+type VideoProcessor struct {
+	config VideoConfig
+}
+
+// NewVideoProcessor creates a processor transcoding videos
+func NewVideoProcessor(config VideoConfig) *VideoProcessor {
+	if config.Runner == nil {
+		config.Runner = &FFmpegRunner{}
+	}
+	if len(config.Renditions) == 0 {
+		config.Renditions = DefaultRenditions
+	}
+	if len(config.Extensions) == 0 {
+		config.Extensions = DefaultVideoExtensions
+	}
+	return &VideoProcessor{config: config}
+}
+
+// Name returns "video"
+func (v *VideoProcessor) Name() string {
+	return "video"
+}
+
+// Process transcodes a video into its renditions and saves the playlists
+// and segments
+func (v *VideoProcessor) Process(ctx context.Context, file *ProcessFile) error {
+	if !slices.Contains(v.config.Extensions, strings.ToLower(path.Ext(file.Path))) {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "gokit-video-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	src, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	input := filepath.Join(dir, "input"+path.Ext(file.Path))
+	err = writeFile(input, src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+
+	streamDir := cleanPath(file.Path) + HLSSuffix
+	master := &strings.Builder{}
+	master.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	names := make([]string, 0, len(v.config.Renditions))
+
+	for _, rendition := range v.config.Renditions {
+		out := filepath.Join(dir, rendition.Name)
+		if err := os.Mkdir(out, 0o755); err != nil {
+			return err
+		}
+		if err := v.config.Runner.HLS(ctx, input, out, rendition); err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(out)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(out, entry.Name()))
+			if err != nil {
+				return err
+			}
+			if _, err := file.SaveDerived(ctx, path.Join(streamDir, rendition.Name, entry.Name()), data); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(master, "#EXT-X-STREAM-INF:BANDWIDTH=%d\n%s/index.m3u8\n", rendition.bandwidth(), rendition.Name)
+		names = append(names, rendition.Name)
+	}
+
+	if _, err := file.SaveDerived(ctx, HLSPath(file.Path), []byte(master.String())); err != nil {
+		return err
+	}
+	file.Metadata["hls"] = HLSPath(file.Path)
+	file.Metadata["renditions"] = strings.Join(names, ",")
+	return nil
+}
@@ -0,0 +1,166 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MetadataStore keeps the metadata of stored files, such as the status of
+// their processing, which storages cannot keep themselves, e.g.
+// MemoryMetadataStore or GormMetadataStore. A Provider with a store returns
+// the metadata in the FileInfo of Get and GetInfo.
+type MetadataStore interface {
+	// Metadata returns the metadata of a file, empty when it has none
+	Metadata(ctx context.Context, filePath string) (map[string]string, error)
+
+	// SetMetadata merges values into the metadata of a file. Keys with an
+	// empty value are removed.
+	SetMetadata(ctx context.Context, filePath string, values map[string]string) error
+
+	// DeleteMetadata removes the metadata of a file
+	DeleteMetadata(ctx context.Context, filePath string) error
+}
+
+// MemoryMetadataStore keeps metadata in memory, for tests and a single
+// instance
+type MemoryMetadataStore struct {
+	mu    sync.RWMutex
+	files map[string]map[string]string
+}
+
+// NewMemoryMetadataStore creates an empty memory store
+func NewMemoryMetadataStore() *MemoryMetadataStore {
+	return &MemoryMetadataStore{files: make(map[string]map[string]string)}
+}
+
+// Metadata returns a copy of the metadata of a file
+func (m *MemoryMetadataStore) Metadata(_ context.Context, filePath string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	values := make(map[string]string, len(m.files[cleanPath(filePath)]))
+	for key, value := range m.files[cleanPath(filePath)] {
+		values[key] = value
+	}
+	return values, nil
+}
+
+// SetMetadata merges values into the metadata of a file
+func (m *MemoryMetadataStore) SetMetadata(_ context.Context, filePath string, values map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	filePath = cleanPath(filePath)
+	metadata, ok := m.files[filePath]
+	if !ok {
+		metadata = make(map[string]string, len(values))
+		m.files[filePath] = metadata
+	}
+	for key, value := range values {
+		if value == "" {
+			delete(metadata, key)
+		} else {
+			metadata[key] = value
+		}
+	}
+	if len(metadata) == 0 {
+		delete(m.files, filePath)
+	}
+	return nil
+}
+
+// DeleteMetadata removes the metadata of a file
+func (m *MemoryMetadataStore) DeleteMetadata(_ context.Context, filePath string) error {
+	m.mu.Lock()
+	delete(m.files, cleanPath(filePath))
+	m.mu.Unlock()
+	return nil
+}
+
+// FileMetadata is a row of the file_metadata table, one per key of a file
+type FileMetadata struct {
+	Path  string `gorm:"primaryKey;size:768"`
+	Key   string `gorm:"primaryKey;size:128"`
+	Value string `gorm:"type:text;not null"`
+}
+
+// TableName keeps the table name singular, like the metadata it holds
+func (FileMetadata) TableName() string {
+	return "file_metadata"
+}
+
+// GormMetadataStore keeps metadata in the file_metadata table, shared by
+// every instance. Create the table with
+// db.AutoMigrate(&filesystem.FileMetadata{}).
+type GormMetadataStore struct {
+	db *gorm.DB
+}
+
+// NewGormMetadataStore creates a store backed by a database
+func NewGormMetadataStore(db *gorm.DB) *GormMetadataStore {
+	return &GormMetadataStore{db: db}
+}
+
+// Metadata returns the metadata of a file
+func (g *GormMetadataStore) Metadata(ctx context.Context, filePath string) (map[string]string, error) {
+	var rows []FileMetadata
+	if err := g.db.WithContext(ctx).Where("path = ?", cleanPath(filePath)).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("filesystem: read metadata: %w", err)
+	}
+
+	values := make(map[string]string, len(rows))
+	for _, row := range rows {
+		values[row.Key] = row.Value
+	}
+	return values, nil
+}
+
+// SetMetadata merges values into the metadata of a file in a transaction
+func (g *GormMetadataStore) SetMetadata(ctx context.Context, filePath string, values map[string]string) error {
+	filePath = cleanPath(filePath)
+
+	var upserts []FileMetadata
+	var removed []interface{}
+	for key, value := range values {
+		if value == "" {
+			removed = append(removed, key)
+		} else {
+			upserts = append(upserts, FileMetadata{Path: filePath, Key: key, Value: value})
+		}
+	}
+
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(removed) > 0 {
+			// The column is quoted by the clause, as key is reserved in MySQL
+			err := tx.Where("path = ?", filePath).
+				Where(clause.IN{Column: clause.Column{Name: "key"}, Values: removed}).
+				Delete(&FileMetadata{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(upserts) > 0 {
+			return tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "path"}, {Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value"}),
+			}).Create(&upserts).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("filesystem: save metadata: %w", err)
+	}
+	return nil
+}
+
+// DeleteMetadata removes the metadata of a file
+func (g *GormMetadataStore) DeleteMetadata(ctx context.Context, filePath string) error {
+	if err := g.db.WithContext(ctx).Where("path = ?", cleanPath(filePath)).Delete(&FileMetadata{}).Error; err != nil {
+		return fmt.Errorf("filesystem: delete metadata: %w", err)
+	}
+	return nil
+}
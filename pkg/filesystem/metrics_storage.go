@@ -0,0 +1,116 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/metrics"
+)
+
+// MetricsStorage records the operations made through it:
+//
+//	storage_operations_total{storage, operation, result}   calls, with result "success" or "error"
+//	storage_operation_duration_seconds{storage, operation} latency
+//	storage_upload_bytes_total{storage}                     bytes uploaded
+type MetricsStorage struct {
+	storage    Storage
+	name       string
+	operations *metrics.Counter
+	duration   *metrics.Timer
+	uploaded   *metrics.Counter
+}
+
+// NewMetricsStorage wraps a storage to record its operations in a registry,
+// defaulting to metrics.Default(), labelled with a name such as "s3"
+func NewMetricsStorage(storage Storage, r *metrics.Registry, name string) *MetricsStorage {
+	if r == nil {
+		r = metrics.Default()
+	}
+	return &MetricsStorage{
+		storage:    storage,
+		name:       name,
+		operations: r.Counter("storage_operations_total", "Storage operations by result", "storage", "operation", "result"),
+		duration:   r.Timer("storage_operation_duration_seconds", "Storage operation latency in seconds", "storage", "operation"),
+		uploaded:   r.Counter("storage_upload_bytes_total", "Bytes uploaded to storage", "storage"),
+	}
+}
+
+// Upload saves a file to the storage
+func (s *MetricsStorage) Upload(ctx context.Context, file *multipart.FileHeader, filePath string) (*FileInfo, error) {
+	start := time.Now()
+	info, err := s.storage.Upload(ctx, file, filePath)
+	s.done("upload", start, err)
+	if err == nil {
+		s.uploaded.Add(float64(file.Size), s.name)
+	}
+	return info, err
+}
+
+// Get retrieves a file from the storage. The latency is until the file is
+// opened, not read.
+func (s *MetricsStorage) Get(ctx context.Context, filePath string) (io.ReadCloser, *FileInfo, error) {
+	start := time.Now()
+	reader, info, err := s.storage.Get(ctx, filePath)
+	s.done("get", start, err)
+	return reader, info, err
+}
+
+// Delete removes a file from the storage
+func (s *MetricsStorage) Delete(ctx context.Context, filePath string) error {
+	start := time.Now()
+	err := s.storage.Delete(ctx, filePath)
+	s.done("delete", start, err)
+	return err
+}
+
+// Copy copies a file within the storage
+func (s *MetricsStorage) Copy(ctx context.Context, src, dst string) (*FileInfo, error) {
+	start := time.Now()
+	info, err := copyFile(ctx, s.storage, src, dst)
+	s.done("copy", start, err)
+	return info, err
+}
+
+// Exists checks if a file exists
+func (s *MetricsStorage) Exists(ctx context.Context, filePath string) (bool, error) {
+	start := time.Now()
+	exists, err := s.storage.Exists(ctx, filePath)
+	s.done("exists", start, err)
+	return exists, err
+}
+
+// List returns a list of files from a directory
+func (s *MetricsStorage) List(ctx context.Context, filePath string) ([]FileInfo, error) {
+	start := time.Now()
+	files, err := s.storage.List(ctx, filePath)
+	s.done("list", start, err)
+	return files, err
+}
+
+// GetInfo returns information about a file without fetching its contents
+func (s *MetricsStorage) GetInfo(ctx context.Context, filePath string) (*FileInfo, error) {
+	start := time.Now()
+	info, err := s.storage.GetInfo(ctx, filePath)
+	s.done("get_info", start, err)
+	return info, err
+}
+
+// ListPage returns a page of a directory listing when the storage supports it
+func (s *MetricsStorage) ListPage(ctx context.Context, filePath string, opts ListOptions) (*ListPage, error) {
+	start := time.Now()
+	page, err := NewProvider(s.storage).ListPage(ctx, filePath, opts)
+	s.done("list", start, err)
+	return page, err
+}
+
+// done records an operation started at start
+func (s *MetricsStorage) done(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	s.operations.Inc(s.name, operation, result)
+	s.duration.Since(start, s.name, operation)
+}
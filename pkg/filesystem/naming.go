@@ -0,0 +1,248 @@
+package filesystem
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NamingStrategy names uploaded files. The name may contain slashes for
+// subdirectories of the upload directory. Storage refuses to overwrite
+// files, so names should not collide.
+type NamingStrategy interface {
+	Name(file *multipart.FileHeader) (string, error)
+}
+
+// NamingFunc adapts a function to a NamingStrategy
+type NamingFunc func(file *multipart.FileHeader) (string, error)
+
+// Name calls the function
+func (f NamingFunc) Name(file *multipart.FileHeader) (string, error) {
+	return f(file)
+}
+
+// OriginalNaming keeps the original name of uploads, sanitized. Uploads of
+// a file name already taken in the directory are refused.
+type OriginalNaming struct{}
+
+// Name returns the sanitized original name
+func (OriginalNaming) Name(file *multipart.FileHeader) (string, error) {
+	return pathutil.SanitizeFilename(file.Filename), nil
+}
+
+// UUIDNaming names uploads with a random UUID and their extension
+type UUIDNaming struct{}
+
+// Name returns a random UUID with the extension
+func (UUIDNaming) Name(file *multipart.FileHeader) (string, error) {
+	return uuid.NewString() + extension(file.Filename), nil
+}
+
+// UUIDv7Naming names uploads with a UUIDv7 and their extension, so names
+// sort by upload time
+type UUIDv7Naming struct{}
+
+// Name returns a UUIDv7 with the extension
+func (UUIDv7Naming) Name(file *multipart.FileHeader) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String() + extension(file.Filename), nil
+}
+
+// ULIDNaming names uploads with a ULID and their extension, so names sort
+// by upload time
+type ULIDNaming struct{}
+
+// Name returns a ULID with the extension
+func (ULIDNaming) Name(file *multipart.FileHeader) (string, error) {
+	id, err := newULID(time.Now())
+	if err != nil {
+		return "", err
+	}
+	return id + extension(file.Filename), nil
+}
+
+// HashNaming names uploads with the SHA-256 of their contents and their
+// extension. Uploading the same file twice is refused as it already
+// exists.
+type HashNaming struct{}
+
+// Name returns the hex SHA-256 of the file with the extension
+func (HashNaming) Name(file *multipart.FileHeader) (string, error) {
+	sum, err := hashFile(file)
+	if err != nil {
+		return "", err
+	}
+	return sum + extension(file.Filename), nil
+}
+
+// SlugNaming names uploads with their slugified original name and the
+// upload time, e.g. "quarterly-report-20250102-150405123.pdf"
+type SlugNaming struct{}
+
+// Name returns the slug of the original name with a timestamp
+func (SlugNaming) Name(file *multipart.FileHeader) (string, error) {
+	now := time.Now().UTC()
+	return fmt.Sprintf("%s-%s%03d%s", slugify(file.Filename), now.Format("20060102-150405"), now.Nanosecond()/1e6, extension(file.Filename)), nil
+}
+
+// TemplateNaming names uploads with a template of placeholders, e.g.
+// "{date}/{uuid}{ext}":
+//
+//	{date}  upload date, "2006-01-02"
+//	{time}  upload time, "150405"
+//	{uuid}  UUIDv7
+//	{ulid}  ULID
+//	{hash}  hex SHA-256 of the contents
+//	{name}  slugified original name
+//	{ext}   lowercase extension with its dot, e.g. ".pdf"
+type TemplateNaming struct {
+	Template string
+}
+
+// Name fills the placeholders of the template
+func (t TemplateNaming) Name(file *multipart.FileHeader) (string, error) {
+	now := time.Now().UTC()
+	replacements := []string{
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{name}", slugify(file.Filename),
+		"{ext}", extension(file.Filename),
+	}
+	if strings.Contains(t.Template, "{uuid}") {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return "", err
+		}
+		replacements = append(replacements, "{uuid}", id.String())
+	}
+	if strings.Contains(t.Template, "{ulid}") {
+		id, err := newULID(now)
+		if err != nil {
+			return "", err
+		}
+		replacements = append(replacements, "{ulid}", id)
+	}
+	if strings.Contains(t.Template, "{hash}") {
+		sum, err := hashFile(file)
+		if err != nil {
+			return "", err
+		}
+		replacements = append(replacements, "{hash}", sum)
+	}
+
+	name := strings.NewReplacer(replacements...).Replace(t.Template)
+	if strings.HasSuffix(name, "/") || cleanPath(name) == "" {
+		return "", fmt.Errorf("filesystem: naming template %q gives no file name", t.Template)
+	}
+	return cleanPath(name), nil
+}
+
+// ParseNamingStrategy returns the strategy of a name: "original", "uuid",
+// "uuidv7", "ulid", "hash", or "slug". Names with placeholders are
+// templates, see TemplateNaming.
+func ParseNamingStrategy(name string) (NamingStrategy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "original":
+		return OriginalNaming{}, nil
+	case "uuid":
+		return UUIDNaming{}, nil
+	case "uuidv7":
+		return UUIDv7Naming{}, nil
+	case "ulid":
+		return ULIDNaming{}, nil
+	case "hash":
+		return HashNaming{}, nil
+	case "slug":
+		return SlugNaming{}, nil
+	}
+	if strings.Contains(name, "{") {
+		return TemplateNaming{Template: name}, nil
+	}
+	return nil, fmt.Errorf("filesystem: unknown naming strategy %q", name)
+}
+
+// extension returns the lowercase extension of a file name
+func extension(filename string) string {
+	return strings.ToLower(filepath.Ext(pathutil.SanitizeFilename(filename)))
+}
+
+// slugify returns the name of a file without its extension in lowercase
+// ASCII letters and digits separated by hyphens
+func slugify(filename string) string {
+	name := pathutil.SanitizeFilename(filename)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	slug := &strings.Builder{}
+	hyphen := false
+	for _, r := range norm.NFKD.String(name) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(unicode.ToLower(r))
+			hyphen = false
+		case unicode.Is(unicode.Mn, r):
+			// Accents decomposed from their letters
+		default:
+			hyphen = true
+		}
+	}
+	if slug.Len() == 0 {
+		return "file"
+	}
+	return slug.String()
+}
+
+// hashFile returns the hex SHA-256 of an uploaded file
+func hashFile(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	hash := sha256.New()
+	if _, err := copyBuffer(hash, src); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// crockford is the Crockford base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID of a time: 48 bits of milliseconds and 80 random
+// bits in Crockford base32
+func newULID(t time.Time) (string, error) {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(t.UnixMilli())<<16)
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", err
+	}
+
+	// 128 bits in 26 characters of 5 bits, the first holding 3 bits
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
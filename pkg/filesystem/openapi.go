@@ -0,0 +1,200 @@
+package filesystem
+
+import (
+	"mime/multipart"
+
+	"github.com/anaknegeri/gokit/pkg/openapi"
+	"github.com/gofiber/fiber/v2"
+)
+
+// filesTag groups the file routes in OpenAPI documents
+const filesTag = "Files"
+
+// responseBody documents the bodies of the file routes, which are sent as a
+// Response
+type responseBody[T any] struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Data    T      `json:"data"`
+}
+
+// uploadForm documents the form of UploadHandler
+type uploadForm struct {
+	File   *multipart.FileHeader `form:"file" validate:"required"`
+	Path   string                `form:"path"`
+	Policy string                `form:"policy"`
+}
+
+// completeDirectUpload documents the body of CompleteDirectUploadHandler
+type completeDirectUpload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// listQuery documents the query of ListFilesPagedHandler besides its page
+type listQuery struct {
+	Q      string `query:"q"`
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1"`
+}
+
+// pageQuery documents the query of ListFilesHandler
+type pageQuery struct {
+	Cursor   string `query:"cursor"`
+	PageSize int    `query:"pageSize" validate:"omitempty,min=1,max=1000"`
+}
+
+// searchQuery documents the query of SearchHandler
+type searchQuery struct {
+	Q     string `query:"q" validate:"required"`
+	Limit int    `query:"limit" validate:"omitempty,min=1"`
+}
+
+// duplicatesQuery documents the query of DuplicatesHandler and DedupeHandler
+type duplicatesQuery struct {
+	Prefix string `query:"prefix"`
+}
+
+// usageQuery documents the query of UsageHandler
+type usageQuery struct {
+	Prefix  string `query:"prefix"`
+	GroupBy string `query:"groupBy"`
+}
+
+// fileQuery documents the query of GetFileHandler
+type fileQuery struct {
+	Disposition string `query:"disposition" validate:"omitempty,oneof=inline attachment"`
+	Filename    string `query:"filename"`
+}
+
+// describe adds the routes mounted by Routes on the files router to a spec
+func (f *FilesystemProvider) describe(files fiber.Router, spec *openapi.Spec) {
+	tags := []string{filesTag}
+
+	spec.Describe(files, fiber.MethodPost, "/upload", openapi.Route{
+		Summary:     "Upload a file",
+		Description: "The file may also be sent as JSON with its content in base64, see UploadRequest.",
+		Tags:        tags,
+		Request:     uploadForm{},
+		Body:        responseBody[FileResponse]{},
+		Errors:      []int{fiber.StatusUnsupportedMediaType},
+	})
+	if f.HandlerConfig.Ingest != nil {
+		spec.Describe(files, fiber.MethodPost, "/ingest", openapi.Route{
+			Summary: "Download a remote file",
+			Tags:    tags,
+			Request: IngestRequest{},
+			Body:    responseBody[FileResponse]{},
+			Errors:  []int{fiber.StatusForbidden, fiber.StatusUnsupportedMediaType, fiber.StatusBadGateway, fiber.StatusGatewayTimeout},
+		})
+	}
+	if f.HandlerConfig.DirectUploads != nil {
+		spec.Describe(files, fiber.MethodPost, "/direct", openapi.Route{
+			Summary: "Get credentials to upload a file straight to the storage",
+			Tags:    tags,
+			Request: DirectUploadRequest{},
+			Body:    responseBody[DirectUpload]{},
+			Errors:  []int{fiber.StatusConflict, fiber.StatusNotImplemented},
+		})
+		spec.Describe(files, fiber.MethodPost, "/direct/complete", openapi.Route{
+			Summary: "Register a direct upload",
+			Tags:    tags,
+			Request: completeDirectUpload{},
+			Body:    responseBody[FileResponse]{},
+			Errors:  []int{fiber.StatusUnauthorized, fiber.StatusNotFound},
+		})
+	}
+	spec.Describe(files, fiber.MethodGet, "/", openapi.Route{
+		Summary:     "List the root directory",
+		Description: "cursor or pageSize lists a single page, with the cursor of the next in the response.",
+		Tags:        tags,
+		Query:       pageQuery{},
+		Body:        FileListResponse{},
+	})
+	spec.Describe(files, fiber.MethodGet, "/list/*", openapi.Route{
+		Summary:     "List a directory one page at a time",
+		Description: "cursor and limit follow the pages of the storage, without sort or q.",
+		Tags:        tags,
+		Query:       listQuery{},
+		Response:    FileResponse{},
+		Paginated:   true,
+	})
+	spec.Describe(files, fiber.MethodGet, "/search", openapi.Route{
+		Summary: "Search the indexed files",
+		Tags:    tags,
+		Query:   searchQuery{},
+		Body:    responseBody[[]FileResponse]{},
+	})
+	if f.HandlerConfig.Batch != nil {
+		spec.Describe(files, fiber.MethodPost, "/batch", openapi.Route{
+			Summary:     "Move, copy, or delete files",
+			Description: "Large batches run in the background and respond 202 with a pending status, read at /batch/{id}.",
+			Tags:        tags,
+			Request:     BatchRequest{},
+			Body:        responseBody[BatchStatus]{},
+		})
+		spec.Describe(files, fiber.MethodGet, "/batch/:id", openapi.Route{
+			Summary: "Get the status of a batch run in the background",
+			Tags:    tags,
+			Body:    responseBody[BatchStatus]{},
+			Errors:  []int{fiber.StatusNotFound},
+		})
+	}
+	if f.HandlerConfig.Duplicates != nil {
+		spec.Describe(files, fiber.MethodGet, "/duplicates", openapi.Route{
+			Summary:     "Find the files stored more than once",
+			Description: "Files are compared by the checksums stored in their metadata; files without one are counted as unchecked.",
+			Tags:        tags,
+			Query:       duplicatesQuery{},
+			Body:        responseBody[DuplicateReport]{},
+		})
+		if f.HandlerConfig.Duplicates.References != nil {
+			spec.Describe(files, fiber.MethodPost, "/duplicates/dedupe", openapi.Route{
+				Summary:     "Delete the extra copies of the files stored more than once",
+				Description: "Referenced copies are kept, or the oldest copy when none is.",
+				Tags:        tags,
+				Query:       duplicatesQuery{},
+				Body:        responseBody[DuplicateReport]{},
+			})
+		}
+	}
+	if f.HandlerConfig.Usage {
+		spec.Describe(files, fiber.MethodGet, "/usage", openapi.Route{
+			Summary:     "Report the storage used",
+			Description: "groupBy selects extension, folder, or age, comma separated; every group by default.",
+			Tags:        tags,
+			Query:       usageQuery{},
+			Body:        responseBody[UsageReport]{},
+		})
+	}
+	spec.Describe(files, fiber.MethodGet, "/info/*", openapi.Route{
+		Summary: "Get the information of a file",
+		Tags:    tags,
+		Body:    responseBody[FileResponse]{},
+		Errors:  []int{fiber.StatusNotFound},
+	})
+	spec.Describe(files, fiber.MethodGet, "/preview/*", openapi.Route{
+		Summary:  "Get the preview of a document",
+		Tags:     tags,
+		Produces: "image/png",
+		Errors:   []int{fiber.StatusNotFound},
+	})
+	spec.Describe(files, fiber.MethodGet, "/status/*", openapi.Route{
+		Summary: "Get the processing status of a file",
+		Tags:    tags,
+		Body:    responseBody[ProcessingStatus]{},
+		Errors:  []int{fiber.StatusNotFound},
+	})
+	spec.Describe(files, fiber.MethodGet, "/*", openapi.Route{
+		Summary:  "Download a file",
+		Tags:     tags,
+		Query:    fileQuery{},
+		Produces: fiber.MIMEOctetStream,
+		Errors:   []int{fiber.StatusForbidden, fiber.StatusNotFound},
+	})
+	spec.Describe(files, fiber.MethodDelete, "/*", openapi.Route{
+		Summary: "Delete a file",
+		Tags:    tags,
+		Body:    responseBody[map[string]string]{},
+		Errors:  []int{fiber.StatusNotFound},
+	})
+}
@@ -0,0 +1,487 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path"
+	"slices"
+	"strings"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/jobs"
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// DefaultPipelineJobType is the job type of pipelines run through a queue
+const DefaultPipelineJobType = "filesystem.pipeline"
+
+// Statuses of a processor, kept in the metadata of a file under
+// "process.<name>", with the error of a failure under "process.<name>.error"
+const (
+	ProcessPending  = "pending"
+	ProcessDone     = "done"
+	ProcessFailed   = "failed"
+	ProcessRejected = "rejected"
+)
+
+// Processor processes uploaded files, e.g. to scan, resize, or describe
+// them
+type Processor interface {
+	// Name identifies the processor in the metadata of files, e.g. "scan"
+	Name() string
+
+	// Process processes a file. It returns RejectFile to have the file
+	// deleted, such as when a virus is found.
+	Process(ctx context.Context, file *ProcessFile) error
+}
+
+type processorFunc struct {
+	name string
+	fn   func(ctx context.Context, file *ProcessFile) error
+}
+
+func (p *processorFunc) Name() string { return p.name }
+
+func (p *processorFunc) Process(ctx context.Context, file *ProcessFile) error {
+	return p.fn(ctx, file)
+}
+
+// NewProcessor adapts a function to a Processor with a name
+func NewProcessor(name string, fn func(ctx context.Context, file *ProcessFile) error) Processor {
+	return &processorFunc{name: name, fn: fn}
+}
+
+// ProcessFile is an uploaded file going through a pipeline
+type ProcessFile struct {
+	// Path is the path of the file in the storage
+	Path string
+
+	// Info is the information of the file, updated by Replace
+	Info FileInfo
+
+	// Metadata is saved with the file once the processor returns, e.g.
+	// file.Metadata["width"] = "640"
+	Metadata map[string]string
+
+	provider *Provider
+	derived  []string
+}
+
+// Open opens the file for reading
+func (f *ProcessFile) Open(ctx context.Context) (io.ReadCloser, error) {
+	file, _, err := f.provider.Get(ctx, f.Path)
+	return file, err
+}
+
+// ReadAll reads the whole file
+func (f *ProcessFile) ReadAll(ctx context.Context) ([]byte, error) {
+	file, err := f.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// Replace replaces the contents of the file, e.g. with a resized image
+func (f *ProcessFile) Replace(ctx context.Context, data []byte) error {
+	header, err := newFileHeader(path.Base(f.Path), data)
+	if err != nil {
+		return err
+	}
+	info, err := f.provider.replace(ctx, header, f.Path)
+	if err != nil {
+		return err
+	}
+	f.Info = *info
+	return nil
+}
+
+// SaveDerived saves a file derived from the file, such as a preview,
+// replacing an earlier version. Derived files are listed in the "derived"
+// metadata of the file and deleted with it; garbage collection keeps them
+// while the file exists.
+func (f *ProcessFile) SaveDerived(ctx context.Context, filePath string, data []byte) (*FileInfo, error) {
+	header, err := newFileHeader(path.Base(filePath), data)
+	if err != nil {
+		return nil, err
+	}
+	if exists, err := f.provider.Exists(ctx, filePath); err != nil {
+		return nil, err
+	} else if exists {
+		if err := f.provider.Delete(ctx, filePath); err != nil {
+			return nil, err
+		}
+	}
+	info, err := f.provider.Upload(ctx, header, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if f.provider.config.Metadata != nil {
+		if err := f.provider.SetMetadata(ctx, filePath, map[string]string{"derivedFrom": cleanPath(f.Path)}); err != nil {
+			return nil, err
+		}
+	}
+	f.derived = append(f.derived, cleanPath(filePath))
+	return info, nil
+}
+
+// Provider returns the provider of the file, e.g. to save files derived
+// from it
+func (f *ProcessFile) Provider() *Provider {
+	return f.provider
+}
+
+// rejectedError is returned by processors refusing a file
+type rejectedError struct {
+	reason string
+}
+
+func (e *rejectedError) Error() string { return "file rejected: " + e.reason }
+
+// RejectFile returns the error of a processor refusing a file for a reason,
+// which has the pipeline delete the file
+func RejectFile(reason string) error {
+	return &rejectedError{reason: reason}
+}
+
+// PipelineConfig configures a pipeline
+type PipelineConfig struct {
+	// Queue runs the pipeline in the background after the upload responds.
+	// The pipeline registers its job handler on it. Without a queue the
+	// pipeline runs before the upload responds.
+	Queue *jobs.Queue
+
+	// JobType defaults to DefaultPipelineJobType. Pipelines sharing a queue
+	// need their own job types.
+	JobType string
+}
+
+// Pipeline runs processors in order on uploaded files, recording the status
+// of each in the metadata of the files. A processor that fails stops the
+// pipeline; one that rejects the file also has it deleted. Run through a
+// queue, a failed pipeline is retried, skipping the processors already done.
+type Pipeline struct {
+	provider   *Provider
+	config     PipelineConfig
+	processors []Processor
+}
+
+type pipelineJob struct {
+	Path string `json:"path"`
+}
+
+// NewPipeline creates a pipeline of processors for the files of a provider:
+//
+//	pipeline := filesystem.NewPipeline(fs.Provider, filesystem.PipelineConfig{Queue: queue},
+//		filesystem.NewClamAVScanner(filesystem.ClamAVConfig{Address: "clamav:3310"}),
+//		filesystem.NewMetadataExtractor(),
+//	)
+//	fs.HandlerConfig.Pipeline = pipeline
+//
+// A pipeline run through a queue records the statuses in the metadata store
+// of the provider, which it requires.
+func NewPipeline(provider *Provider, config PipelineConfig, processors ...Processor) *Pipeline {
+	if provider == nil {
+		panic("filesystem provider is required")
+	}
+	if config.JobType == "" {
+		config.JobType = DefaultPipelineJobType
+	}
+
+	p := &Pipeline{provider: provider, config: config, processors: processors}
+	if config.Queue != nil {
+		if provider.config.Metadata == nil {
+			panic("filesystem pipeline with a queue requires a metadata store")
+		}
+		config.Queue.Register(config.JobType, jobs.Handle(p.runJob))
+	}
+	return p
+}
+
+// Run processes a file that was just uploaded, or enqueues it with its
+// processors marked pending. Run inline, it sets the metadata of info and
+// returns a FILE_REJECTED error when a processor rejected the file. Other
+// failures are only recorded.
+func (p *Pipeline) Run(ctx context.Context, filePath string, info *FileInfo) error {
+	if p.config.Queue != nil {
+		pending := make(map[string]string, len(p.processors))
+		for _, processor := range p.processors {
+			pending[statusKey(processor)] = ProcessPending
+		}
+		if err := p.provider.SetMetadata(ctx, filePath, pending); err != nil {
+			return err
+		}
+		if _, err := p.config.Queue.Enqueue(ctx, p.config.JobType, pipelineJob{Path: filePath}); err != nil {
+			return err
+		}
+		info.Metadata = mergeMetadata(info.Metadata, pending)
+		return nil
+	}
+
+	metadata, err := p.process(ctx, filePath, *info)
+	info.Metadata = metadata
+	if replaced, statErr := p.provider.GetInfo(ctx, filePath); statErr == nil {
+		// Processors may have replaced the file
+		info.Size = replaced.Size
+		info.ContentType = replaced.ContentType
+		info.LastModified = replaced.LastModified
+	}
+
+	var rejected *rejectedError
+	if errors.As(err, &rejected) {
+		return fserrors.FileRejectedError(filePath, rejected.reason)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to process %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// runJob processes a queued file, failing at once when it was rejected or
+// is gone
+func (p *Pipeline) runJob(ctx context.Context, job pipelineJob) error {
+	info, err := p.provider.GetInfo(ctx, job.Path)
+	if err != nil {
+		if exists, existsErr := p.provider.Exists(ctx, job.Path); existsErr == nil && !exists {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+
+	_, err = p.process(ctx, job.Path, *info)
+	var rejected *rejectedError
+	if errors.As(err, &rejected) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// process runs the processors not done yet on a file and returns its
+// metadata. The metadata is saved after each processor when there is a
+// store.
+func (p *Pipeline) process(ctx context.Context, filePath string, info FileInfo) (map[string]string, error) {
+	metadata, err := p.provider.Metadata(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	save := func() error {
+		if p.provider.config.Metadata == nil {
+			return nil
+		}
+		return p.provider.SetMetadata(ctx, filePath, metadata)
+	}
+
+	file := &ProcessFile{Path: filePath, Info: info, provider: p.provider}
+	for _, processor := range p.processors {
+		key := statusKey(processor)
+		if metadata[key] == ProcessDone {
+			continue
+		}
+
+		file.Metadata = make(map[string]string)
+		file.derived = nil
+		err := processor.Process(ctx, file)
+		for k, v := range file.Metadata {
+			metadata[k] = v
+		}
+		if len(file.derived) > 0 {
+			metadata[derivedKey] = strings.Join(addDerived(derivedPaths(metadata), file.derived), "\n")
+		}
+
+		var rejected *rejectedError
+		switch {
+		case errors.As(err, &rejected):
+			metadata[key] = ProcessRejected
+			metadata[key+".error"] = rejected.reason
+			if deleteErr := p.provider.Delete(ctx, filePath); deleteErr != nil {
+				logger.FromContext(ctx).Errorf("Failed to delete rejected file %s: %v", filePath, deleteErr)
+			}
+			return metadata, err
+		case err != nil:
+			metadata[key] = ProcessFailed
+			metadata[key+".error"] = err.Error()
+			if saveErr := save(); saveErr != nil {
+				logger.FromContext(ctx).Errorf("Failed to save the metadata of %s: %v", filePath, saveErr)
+			}
+			return metadata, fmt.Errorf("filesystem: processor %s: %w", processor.Name(), err)
+		}
+
+		metadata[key] = ProcessDone
+		metadata[key+".error"] = ""
+		if err := save(); err != nil {
+			return metadata, err
+		}
+	}
+
+	// Empty values only remove keys from the store
+	for k, v := range metadata {
+		if v == "" {
+			delete(metadata, k)
+		}
+	}
+	return metadata, nil
+}
+
+// ProcessorStatus is the status of a processor on a file
+type ProcessorStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ProcessingStatus is the status of the processing of a file, e.g. for
+// clients polling until a video is transcoded
+type ProcessingStatus struct {
+	Path string `json:"path"`
+
+	// Status is ProcessRejected or ProcessFailed when a processor was,
+	// ProcessPending while any is, and ProcessDone otherwise, including
+	// for files without processors
+	Status string `json:"status"`
+
+	// Processors are the statuses by processor name
+	Processors map[string]ProcessorStatus `json:"processors"`
+}
+
+// NewProcessingStatus reads the processing status of a file from its
+// metadata
+func NewProcessingStatus(filePath string, metadata map[string]string) ProcessingStatus {
+	status := ProcessingStatus{Path: filePath, Status: ProcessDone, Processors: map[string]ProcessorStatus{}}
+	for key, value := range metadata {
+		name, ok := strings.CutPrefix(key, "process.")
+		if !ok || strings.HasSuffix(name, ".error") {
+			continue
+		}
+		status.Processors[name] = ProcessorStatus{Status: value, Error: metadata[key+".error"]}
+	}
+
+	for _, rank := range []string{ProcessRejected, ProcessFailed, ProcessPending} {
+		for _, processor := range status.Processors {
+			if processor.Status == rank {
+				status.Status = rank
+				return status
+			}
+		}
+	}
+	return status
+}
+
+// derivedKey is the metadata key listing the files derived from a file, one
+// path per line
+const derivedKey = "derived"
+
+// derivedPaths returns the paths of the files derived from a file
+func derivedPaths(metadata map[string]string) []string {
+	if metadata[derivedKey] == "" {
+		return nil
+	}
+	return strings.Split(metadata[derivedKey], "\n")
+}
+
+// addDerived adds paths to a list of derived files, without duplicates
+func addDerived(paths, added []string) []string {
+	for _, p := range added {
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// statusKey is the metadata key of the status of a processor
+func statusKey(processor Processor) string {
+	return "process." + processor.Name()
+}
+
+// mergeMetadata returns metadata with values added
+func mergeMetadata(metadata, values map[string]string) map[string]string {
+	if metadata == nil {
+		metadata = make(map[string]string, len(values))
+	}
+	for k, v := range values {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// NewFileHeader wraps data in a multipart file header, the form storages
+// upload, for files that are not uploaded through a form
+func NewFileHeader(filename string, data []byte) (*multipart.FileHeader, error) {
+	return newFileHeader(filename, data)
+}
+
+// newFileHeader wraps data in a multipart file header, the form storages
+// upload
+func newFileHeader(filename string, data []byte) (*multipart.FileHeader, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	header.Set("Content-Type", "application/octet-stream")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(int64(len(data)) + 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	return form.File["file"][0], nil
+}
+
+// NewFileHeaderFrom streams r into a multipart file header backed by a
+// temporary file, for files too large to hold in memory. remove deletes
+// the temporary file once the file is no longer needed.
+func NewFileHeaderFrom(filename string, r io.Reader) (file *multipart.FileHeader, remove func(), err error) {
+	return newFileHeaderFrom(filename, r)
+}
+
+// newFileHeaderFrom streams r into a multipart file header backed by a
+// temporary file, writing the form through a pipe as it is parsed
+func newFileHeaderFrom(filename string, r io.Reader) (*multipart.FileHeader, func(), error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	copied := make(chan error, 1)
+	go func() {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+		header.Set("Content-Type", "application/octet-stream")
+		part, err := writer.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		copied <- err
+		pw.CloseWithError(err)
+	}()
+
+	// Files of any size are written to disk
+	form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(0)
+	pr.Close()
+	if copyErr := <-copied; copyErr != nil && !errors.Is(copyErr, io.ErrClosedPipe) {
+		err = copyErr
+	}
+	if err != nil {
+		if form != nil {
+			form.RemoveAll()
+		}
+		return nil, nil, err
+	}
+	return form.File["file"][0], func() { form.RemoveAll() }, nil
+}
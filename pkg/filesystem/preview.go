@@ -0,0 +1,206 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PreviewSuffix is appended to the path of a file for the path of its
+// preview, so "docs/report.pdf" is previewed by "docs/report.pdf.preview.png"
+const PreviewSuffix = ".preview.png"
+
+// DefaultOfficeExtensions are the office documents previewed by a
+// CommandConverter by default
+var DefaultOfficeExtensions = []string{
+	".doc", ".docx", ".odt", ".rtf",
+	".xls", ".xlsx", ".ods",
+	".ppt", ".pptx", ".odp",
+}
+
+// PreviewPath returns the path of the preview of a file
+func PreviewPath(filePath string) string {
+	return cleanPath(filePath) + PreviewSuffix
+}
+
+// PreviewConverter renders the first page of documents as PNG images, e.g.
+// CommandConverter
+type PreviewConverter interface {
+	// Supports reports whether a file can be previewed, by its name and
+	// content type
+	Supports(filename, contentType string) bool
+
+	// Preview renders the first page of a document as a PNG image
+	Preview(ctx context.Context, src io.Reader, filename string) ([]byte, error)
+}
+
+// CommandConverterConfig configures the commands of a CommandConverter. In
+// the arguments, {input} is replaced by the path of the document, {output}
+// by the path of the PNG image to write, and {outdir} by their directory.
+type CommandConverterConfig struct {
+	// PDFCommand renders the first page of a PDF to a PNG image, defaulting
+	// to ImageMagick:
+	//
+	//	magick -density 110 {input}[0] -thumbnail 600x600 -background white -alpha remove png:{output}
+	PDFCommand []string
+
+	// OfficeCommand converts an office document to a PDF in {outdir} named
+	// after the input, which PDFCommand then renders, defaulting to
+	// LibreOffice:
+	//
+	//	soffice --headless --convert-to pdf --outdir {outdir} {input}
+	OfficeCommand []string
+
+	// OfficeExtensions defaults to DefaultOfficeExtensions
+	OfficeExtensions []string
+
+	// Timeout limits each command, defaulting to two minutes
+	Timeout time.Duration
+}
+
+// CommandConverter renders previews with external commands, ImageMagick for
+// PDFs and LibreOffice for office documents by default, which must be
+// installed
+type CommandConverter struct {
+	config CommandConverterConfig
+}
+
+// NewCommandConverter creates a converter running commands
+func NewCommandConverter(config CommandConverterConfig) *CommandConverter {
+	if len(config.PDFCommand) == 0 {
+		config.PDFCommand = []string{
+			"magick", "-density", "110", "{input}[0]",
+			"-thumbnail", "600x600", "-background", "white", "-alpha", "remove", "png:{output}",
+		}
+	}
+	if len(config.OfficeCommand) == 0 {
+		config.OfficeCommand = []string{"soffice", "--headless", "--convert-to", "pdf", "--outdir", "{outdir}", "{input}"}
+	}
+	if len(config.OfficeExtensions) == 0 {
+		config.OfficeExtensions = DefaultOfficeExtensions
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	return &CommandConverter{config: config}
+}
+
+// Supports reports whether a file is a PDF or an office document
+func (c *CommandConverter) Supports(filename, contentType string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".pdf" || contentType == "application/pdf" || slices.Contains(c.config.OfficeExtensions, ext)
+}
+
+// Preview writes the document to a temporary directory and runs the
+// commands on it
+func (c *CommandConverter) Preview(ctx context.Context, src io.Reader, filename string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "gokit-preview-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	input := filepath.Join(dir, "input"+ext)
+	if err := writeFile(input, src); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(c.config.OfficeExtensions, ext) {
+		if err := c.run(ctx, c.config.OfficeCommand, input, "", dir); err != nil {
+			return nil, err
+		}
+		input = filepath.Join(dir, "input.pdf")
+	}
+
+	output := filepath.Join(dir, "preview.png")
+	if err := c.run(ctx, c.config.PDFCommand, input, output, dir); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(output)
+}
+
+// run runs a command with its placeholders replaced
+func (c *CommandConverter) run(ctx context.Context, command []string, input, output, dir string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	replacer := strings.NewReplacer("{input}", input, "{output}", output, "{outdir}", dir)
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = replacer.Replace(arg)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	// LibreOffice needs a writable profile directory
+	cmd.Env = append(os.Environ(), "HOME="+dir)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("filesystem: %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// writeFile copies a reader into a new file
+func writeFile(name string, src io.Reader) error {
+	dst, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// PreviewProcessor is a processor rendering previews of documents, saved at
+// PreviewPath next to them and recorded in the "preview" metadata. Files
+// the converter does not support are left as they are. Its name is
+// "preview".
+type PreviewProcessor struct {
+	converter PreviewConverter
+}
+
+// NewPreviewProcessor creates a processor rendering previews with a
+// converter
+func NewPreviewProcessor(converter PreviewConverter) *PreviewProcessor {
+	return &PreviewProcessor{converter: converter}
+}
+
+// Name returns "preview"
+func (p *PreviewProcessor) Name() string {
+	return "preview"
+}
+
+// Process renders and saves the preview of a document
+func (p *PreviewProcessor) Process(ctx context.Context, file *ProcessFile) error {
+	if !p.converter.Supports(file.Path, file.Info.ContentType) {
+		return nil
+	}
+
+	src, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	image, err := p.converter.Preview(ctx, src, file.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.SaveDerived(ctx, PreviewPath(file.Path), image); err != nil {
+		return err
+	}
+	file.Metadata["preview"] = PreviewPath(file.Path)
+	return nil
+}
@@ -0,0 +1,158 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register the GIF decoder for image dimensions
+	_ "image/jpeg" // Register the JPEG decoder for image dimensions
+	_ "image/png"  // Register the PNG decoder for image dimensions
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clamAVChunkSize is the size of the chunks streamed to clamd
+const clamAVChunkSize = 64 << 10
+
+// ClamAVConfig configures a virus scan with clamd
+type ClamAVConfig struct {
+	// Address is the TCP address of clamd, e.g. "clamav:3310"
+	Address string
+
+	// Timeout limits a scan, defaulting to a minute
+	Timeout time.Duration
+}
+
+// ClamAVScanner is a processor scanning files with clamd, rejecting the
+// infected ones. Its name is "scan".
+type ClamAVScanner struct {
+	config ClamAVConfig
+}
+
+// NewClamAVScanner creates a processor scanning files with clamd
+func NewClamAVScanner(config ClamAVConfig) *ClamAVScanner {
+	if config.Timeout <= 0 {
+		config.Timeout = time.Minute
+	}
+	return &ClamAVScanner{config: config}
+}
+
+// Name returns "scan"
+func (s *ClamAVScanner) Name() string {
+	return "scan"
+}
+
+// Process streams the file to clamd and rejects it when a virus is found,
+// recording the virus in the "scan.virus" metadata
+func (s *ClamAVScanner) Process(ctx context.Context, file *ProcessFile) error {
+	src, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dialer := net.Dialer{Timeout: s.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Address)
+	if err != nil {
+		return fmt.Errorf("filesystem: connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(s.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	// INSTREAM takes chunks prefixed with their length, ending with an
+	// empty chunk
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("filesystem: scan: %w", err)
+	}
+	buf := make([]byte, 4+clamAVChunkSize)
+	for {
+		n, readErr := src.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return fmt.Errorf("filesystem: scan: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("filesystem: scan: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("filesystem: read scan result: %w", err)
+	}
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	// Replies are "stream: OK", "stream: <virus> FOUND", or "... ERROR"
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		virus := strings.TrimSuffix(result, " FOUND")
+		file.Metadata["scan.virus"] = virus
+		return RejectFile("virus found: " + virus)
+	default:
+		return fmt.Errorf("filesystem: clamd: %s", reply)
+	}
+}
+
+// MetadataExtractor is a processor recording the SHA-256 checksum of files
+// as "sha256", and the dimensions of GIF, JPEG, and PNG images as "width"
+// and "height". Its name is "metadata".
+type MetadataExtractor struct{}
+
+// NewMetadataExtractor creates a processor describing files
+func NewMetadataExtractor() *MetadataExtractor {
+	return &MetadataExtractor{}
+}
+
+// Name returns "metadata"
+func (e *MetadataExtractor) Name() string {
+	return "metadata"
+}
+
+// Process hashes the file and decodes the image header from its start
+func (e *MetadataExtractor) Process(ctx context.Context, file *ProcessFile) error {
+	src, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	h := sha256.New()
+	head := &bytes.Buffer{}
+	tee := io.TeeReader(src, h)
+	if _, err := io.CopyN(head, tee, 64<<10); err != nil && err != io.EOF {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return err
+	}
+	file.Metadata["sha256"] = hex.EncodeToString(h.Sum(nil))
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(head.Bytes())); err == nil {
+		file.Metadata["width"] = strconv.Itoa(config.Width)
+		file.Metadata["height"] = strconv.Itoa(config.Height)
+	}
+	return nil
+}
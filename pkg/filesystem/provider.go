@@ -5,14 +5,24 @@ import (
 	"net/http"
 
 	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/middleware"
+	"github.com/anaknegeri/gokit/pkg/openapi"
+	"github.com/gofiber/fiber/v2"
 )
 
+// multipartOverhead is room for the boundaries, part headers, and other
+// fields of an upload form besides the file
+const multipartOverhead = 64 << 10
+
 // FilesystemProvider is a high-level provider that integrates configuration
 // and handlers for easy usage in applications
 type FilesystemProvider struct {
 	Provider      *Provider
 	HandlerConfig UploadHandlerConfig
 	Config        Config
+
+	// OpenAPI describes the routes mounted by Routes in a spec when set
+	OpenAPI *openapi.Spec
 }
 
 // NewFilesystemProvider creates a new filesystem provider with configuration
@@ -104,6 +114,26 @@ func (f *FilesystemProvider) GetFileInfoHandler() func(string) interface{} {
 	}
 }
 
+// GetPreviewHandler returns a handler to serve the previews of documents
+// Takes a base path to be prepended to file paths
+func (f *FilesystemProvider) GetPreviewHandler() func(string) interface{} {
+	return func(basePath string) interface{} {
+		config := f.HandlerConfig
+		config.BasePath = basePath
+		return GetPreviewHandler(config)
+	}
+}
+
+// GetStatusHandler returns a handler to get the processing status of files
+// Takes a base path to be prepended to file paths
+func (f *FilesystemProvider) GetStatusHandler() func(string) interface{} {
+	return func(basePath string) interface{} {
+		config := f.HandlerConfig
+		config.BasePath = basePath
+		return GetStatusHandler(config)
+	}
+}
+
 // GetDeleteFileHandler returns a handler to delete files
 // Takes a base path to be prepended to file paths
 func (f *FilesystemProvider) GetDeleteFileHandler() func(string) interface{} {
@@ -123,3 +153,88 @@ func (f *FilesystemProvider) GetListFilesHandler() func(string) interface{} {
 		return ListFilesHandler(config)
 	}
 }
+
+// GetListFilesPagedHandler returns a handler to list files one page at a time
+// Takes a base path to be prepended to file paths
+func (f *FilesystemProvider) GetListFilesPagedHandler() func(string) interface{} {
+	return func(basePath string) interface{} {
+		config := f.HandlerConfig
+		config.BasePath = basePath
+		return ListFilesPagedHandler(config)
+	}
+}
+
+// GetSearchHandler returns a handler to search the indexed files
+// Takes a base path to be prepended to file paths
+func (f *FilesystemProvider) GetSearchHandler() func(string) interface{} {
+	return func(basePath string) interface{} {
+		config := f.HandlerConfig
+		config.BasePath = basePath
+		return SearchHandler(config)
+	}
+}
+
+// Routes registers the file routes under /files, so the provider can be
+// mounted on a server app. Upload bodies that are not multipart forms or
+// JSON, or larger than the MaxFileSize of every upload policy allows, are
+// rejected before they are parsed, see middleware.BodyGuard.
+//
+//	POST   /files/upload            upload a file
+//	POST   /files/ingest            download a remote file, with HandlerConfig.Ingest
+//	POST   /files/direct            credentials to upload straight to the storage, with HandlerConfig.DirectUploads
+//	POST   /files/direct/complete   register a direct upload
+//	GET    /files                   list the root directory
+//	GET    /files/list/*            list a directory one page at a time
+//	GET    /files/search            search the indexed files, ?q=invoice
+//	POST   /files/batch             move, copy, or delete files, with HandlerConfig.Batch
+//	GET    /files/batch/:id         status of a batch run in the background
+//	GET    /files/duplicates        files stored more than once, with HandlerConfig.Duplicates
+//	POST   /files/duplicates/dedupe delete the extra copies, with HandlerConfig.Duplicates.References
+//	GET    /files/usage             storage used by extension, folder, and age, with HandlerConfig.Usage
+//	GET    /files/info/*            file information
+//	GET    /files/preview/*         preview of a document
+//	GET    /files/status/*          processing status of a file
+//	GET    /files/*                 download a file
+//	DELETE /files/*                 delete a file
+//
+// The routes are described in the OpenAPI spec when one is set.
+func (f *FilesystemProvider) Routes(router fiber.Router) {
+	files := router.Group("/files")
+	guard := middleware.BodyGuardConfig{ContentTypes: []string{fiber.MIMEMultipartForm, fiber.MIMEApplicationJSON}}
+	if maxFileSize := f.HandlerConfig.maxFileSize(); maxFileSize > 0 {
+		// Files are a third larger in base64 in JSON bodies
+		guard.MaxSize = (int64(maxFileSize)+2)/3*4 + multipartOverhead
+	}
+	files.Post("/upload", middleware.BodyGuard(guard), UploadHandler(f.HandlerConfig))
+	if f.HandlerConfig.Ingest != nil {
+		files.Post("/ingest", IngestHandler(f.HandlerConfig))
+	}
+	if f.HandlerConfig.DirectUploads != nil {
+		files.Post("/direct", DirectUploadHandler(f.HandlerConfig))
+		files.Post("/direct/complete", CompleteDirectUploadHandler(f.HandlerConfig))
+	}
+	files.Get("/", ListFilesHandler(f.HandlerConfig))
+	files.Get("/info/*", GetFileInfoHandler(f.HandlerConfig))
+	files.Get("/list/*", ListFilesPagedHandler(f.HandlerConfig))
+	files.Get("/search", SearchHandler(f.HandlerConfig))
+	if f.HandlerConfig.Batch != nil {
+		files.Post("/batch", BatchHandler(f.HandlerConfig))
+		files.Get("/batch/:id", BatchStatusHandler(f.HandlerConfig))
+	}
+	if f.HandlerConfig.Duplicates != nil {
+		files.Get("/duplicates", DuplicatesHandler(f.HandlerConfig))
+		if f.HandlerConfig.Duplicates.References != nil {
+			files.Post("/duplicates/dedupe", DedupeHandler(f.HandlerConfig))
+		}
+	}
+	if f.HandlerConfig.Usage {
+		files.Get("/usage", UsageHandler(f.HandlerConfig))
+	}
+	files.Get("/preview/*", GetPreviewHandler(f.HandlerConfig))
+	files.Get("/status/*", GetStatusHandler(f.HandlerConfig))
+	files.Get("/*", GetFileHandler(f.HandlerConfig))
+	files.Delete("/*", DeleteFileHandler(f.HandlerConfig))
+	if f.OpenAPI != nil {
+		f.describe(files, f.OpenAPI)
+	}
+}
@@ -1,14 +1,17 @@
 package filesystem
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -16,18 +19,36 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
 
 	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
 )
 
+// DefaultS3MaxListEntries is how many entries S3Storage.List returns at most
+// by default
+const DefaultS3MaxListEntries = 100000
+
+// s3MaxKeys is the most keys S3 returns in a single listing request
+const s3MaxKeys = 1000
+
+// DefaultS3StartupTimeout bounds loading the AWS configuration and checking
+// the bucket when an S3 storage is created
+const DefaultS3StartupTimeout = 30 * time.Second
+
 type S3Storage struct {
-	client     *s3.Client
-	uploader   *manager.Uploader
-	downloader *manager.Downloader
-	bucket     string
-	basePrefix string
-	baseURL    string
-	region     string
+	client         *s3.Client
+	uploader       *manager.Uploader
+	downloader     *manager.Downloader
+	bucket         string
+	basePrefix     string
+	baseURL        string
+	region         string
+	maxListEntries int
+
+	// connected is set once the bucket has been checked
+	connected atomic.Bool
+	connectMu sync.Mutex
 }
 
 type S3Config struct {
@@ -39,17 +60,56 @@ type S3Config struct {
 	Endpoint     string
 	AccessKey    string
 	SecretKey    string
+	Credentials  aws.CredentialsProvider // Used instead of AccessKey and SecretKey when set
 	UseSSL       bool
 	UsePathStyle bool
+
+	// MaxListEntries is how many entries List returns at most, following
+	// the pages of the listing, defaulting to DefaultS3MaxListEntries. Use
+	// ListWithOptions to know whether a listing was cut short.
+	MaxListEntries int
+
+	// StartupTimeout bounds loading the AWS configuration and checking the
+	// bucket in NewS3StorageContext, defaulting to DefaultS3StartupTimeout
+	StartupTimeout time.Duration
+
+	// LazyConnect defers checking the bucket to the first operation, so the
+	// storage can be created while S3 is unreachable. A failed check is
+	// retried by the next operation.
+	LazyConnect bool
+
+	// SkipBucketCheck never checks the bucket, which needs the
+	// s3:ListBucket permission, so roles allowed only to read and write
+	// objects can use the storage. See ValidateBucket.
+	SkipBucketCheck bool
 }
 
+// NewS3Storage creates an S3 storage, see NewS3StorageContext
 func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	return NewS3StorageContext(context.Background(), cfg)
+}
+
+// NewS3StorageContext creates an S3 storage, loading the AWS configuration
+// and checking the bucket within ctx and the StartupTimeout of the config.
+// With LazyConnect the bucket is checked by the first operation instead, and
+// with SkipBucketCheck not at all.
+func NewS3StorageContext(ctx context.Context, cfg S3Config) (*S3Storage, error) {
 	var s3Client *s3.Client
 	var err error
 
+	if cfg.StartupTimeout <= 0 {
+		cfg.StartupTimeout = DefaultS3StartupTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.StartupTimeout)
+	defer cancel()
+
 	if cfg.Endpoint != "" {
+		creds := cfg.Credentials
+		if creds == nil {
+			creds = credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")
+		}
 		customConfig := aws.Config{
-			Credentials: credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""),
+			Credentials: creds,
 			Region:      cfg.Region,
 		}
 
@@ -58,10 +118,9 @@ func NewS3Storage(cfg S3Config) (*S3Storage, error) {
 			o.BaseEndpoint = aws.String(cfg.Endpoint)
 		})
 	} else {
-		if cfg.AWSConfig.Region != "" {
-			s3Client = s3.NewFromConfig(cfg.AWSConfig)
-		} else {
-			awsCfg, err := config.LoadDefaultConfig(context.TODO(),
+		awsCfg := cfg.AWSConfig
+		if awsCfg.Region == "" {
+			awsCfg, err = config.LoadDefaultConfig(ctx,
 				config.WithRegion(cfg.Region),
 			)
 			if err != nil {
@@ -71,40 +130,71 @@ func NewS3Storage(cfg S3Config) (*S3Storage, error) {
 					"Failed to load AWS configuration",
 				)
 			}
-			s3Client = s3.NewFromConfig(awsCfg)
 		}
-	}
-
-	_, err = s3Client.HeadBucket(context.TODO(), &s3.HeadBucketInput{
-		Bucket: aws.String(cfg.Bucket),
-	})
-	if err != nil {
-		return nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to access S3 bucket '%s'", cfg.Bucket),
-		)
+		if cfg.Credentials != nil {
+			awsCfg.Credentials = cfg.Credentials
+		}
+		s3Client = s3.NewFromConfig(awsCfg)
 	}
 
 	uploader := manager.NewUploader(s3Client)
 	downloader := manager.NewDownloader(s3Client)
 
-	return &S3Storage{
-		client:     s3Client,
-		uploader:   uploader,
-		downloader: downloader,
-		bucket:     cfg.Bucket,
-		basePrefix: cfg.BasePrefix,
-		baseURL:    cfg.BaseURL,
-		region:     cfg.Region,
-	}, nil
+	maxListEntries := cfg.MaxListEntries
+	if maxListEntries <= 0 {
+		maxListEntries = DefaultS3MaxListEntries
+	}
+
+	storage := &S3Storage{
+		client:         s3Client,
+		uploader:       uploader,
+		downloader:     downloader,
+		bucket:         cfg.Bucket,
+		basePrefix:     cfg.BasePrefix,
+		baseURL:        cfg.BaseURL,
+		region:         cfg.Region,
+		maxListEntries: maxListEntries,
+	}
+	if cfg.SkipBucketCheck {
+		storage.connected.Store(true)
+	} else if !cfg.LazyConnect {
+		if err := storage.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return storage, nil
 }
 
-func (s *S3Storage) getFullKey(path string) string {
-	if s.basePrefix == "" {
-		return path
+// connect checks the bucket once, before the first operation of a storage
+// created with LazyConnect
+func (s *S3Storage) connect(ctx context.Context) error {
+	if s.connected.Load() {
+		return nil
 	}
-	return filepath.Join(s.basePrefix, path)
+	s.connectMu.Lock()
+	defer s.connectMu.Unlock()
+	if s.connected.Load() {
+		return nil
+	}
+	return s.ValidateBucket(ctx)
+}
+
+// ValidateBucket checks the bucket exists and can be accessed, e.g. for a
+// health check of a storage created with SkipBucketCheck. It needs the
+// s3:ListBucket permission.
+func (s *S3Storage) ValidateBucket(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	if err != nil {
+		return s3Error(err, "", "s3:ListBucket", fmt.Sprintf("Failed to access S3 bucket '%s'", s.bucket))
+	}
+	s.connected.Store(true)
+	return nil
+}
+
+func (s *S3Storage) getFullKey(path string) string {
+	return JoinKey(s.basePrefix, path)
 }
 
 func (s *S3Storage) getURL(key string) string {
@@ -119,6 +209,10 @@ func (s *S3Storage) getURL(key string) string {
 }
 
 func (s *S3Storage) Upload(ctx context.Context, file *multipart.FileHeader, path string) (*FileInfo, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+
 	src, err := file.Open()
 	if err != nil {
 		return nil, fserrors.WrapError(
@@ -129,8 +223,9 @@ func (s *S3Storage) Upload(ctx context.Context, file *multipart.FileHeader, path
 	}
 	defer src.Close()
 
-	buffer := &bytes.Buffer{}
-	size, err := io.Copy(buffer, src)
+	// Detect the content type from the first bytes. The file is uploaded
+	// as it is, which the uploader reads in parts without copying it.
+	head, release, err := sniffContent(src)
 	if err != nil {
 		return nil, fserrors.WrapError(
 			err,
@@ -138,13 +233,17 @@ func (s *S3Storage) Upload(ctx context.Context, file *multipart.FileHeader, path
 			"Failed to read file",
 		)
 	}
-
-	contentType := http.DetectContentType(buffer.Bytes())
+	contentType := http.DetectContentType(head)
+	release()
 	if strings.HasPrefix(contentType, "application/octet-stream") {
 		contentType = getContentTypeByExt(filepath.Ext(file.Filename))
 	}
 
-	if _, err := src.Seek(0, io.SeekStart); err != nil {
+	size, err := src.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = src.Seek(0, io.SeekStart)
+	}
+	if err != nil {
 		return nil, fserrors.WrapError(
 			err,
 			http.StatusInternalServerError,
@@ -156,11 +255,7 @@ func (s *S3Storage) Upload(ctx context.Context, file *multipart.FileHeader, path
 
 	exists, err := s.Exists(ctx, path)
 	if err != nil {
-		return nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			"Failed to check if file exists",
-		)
+		return nil, err
 	}
 	if exists {
 		return nil, fserrors.NewCustomError(
@@ -173,7 +268,7 @@ func (s *S3Storage) Upload(ctx context.Context, file *multipart.FileHeader, path
 	output, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
 		Key:         aws.String(fullKey),
-		Body:        bytes.NewReader(buffer.Bytes()),
+		Body:        src,
 		ContentType: aws.String(contentType),
 		Metadata: map[string]string{
 			"OriginalFilename": file.Filename,
@@ -181,11 +276,7 @@ func (s *S3Storage) Upload(ctx context.Context, file *multipart.FileHeader, path
 		},
 	})
 	if err != nil {
-		return nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to upload file to S3: %s", err.Error()),
-		)
+		return nil, s3Error(err, path, "s3:PutObject", fmt.Sprintf("Failed to upload file to S3: %s", err.Error()))
 	}
 
 	fileURL := output.Location
@@ -204,6 +295,10 @@ func (s *S3Storage) Upload(ctx context.Context, file *multipart.FileHeader, path
 }
 
 func (s *S3Storage) Get(ctx context.Context, path string) (io.ReadCloser, *FileInfo, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, nil, err
+	}
+
 	fullKey := s.getFullKey(path)
 
 	headOutput, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
@@ -211,14 +306,7 @@ func (s *S3Storage) Get(ctx context.Context, path string) (io.ReadCloser, *FileI
 		Key:    aws.String(fullKey),
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "404") {
-			return nil, nil, fserrors.FileNotFoundError(path)
-		}
-		return nil, nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to get file metadata from S3: %s", path),
-		)
+		return nil, nil, s3Error(err, path, "s3:GetObject", fmt.Sprintf("Failed to get file metadata from S3: %s", path))
 	}
 
 	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
@@ -226,11 +314,7 @@ func (s *S3Storage) Get(ctx context.Context, path string) (io.ReadCloser, *FileI
 		Key:    aws.String(fullKey),
 	})
 	if err != nil {
-		return nil, nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to get file from S3: %s", path),
-		)
+		return nil, nil, s3Error(err, path, "s3:GetObject", fmt.Sprintf("Failed to get file from S3: %s", path))
 	}
 
 	contentType := "application/octet-stream"
@@ -251,15 +335,15 @@ func (s *S3Storage) Get(ctx context.Context, path string) (io.ReadCloser, *FileI
 }
 
 func (s *S3Storage) Delete(ctx context.Context, path string) error {
+	if err := s.connect(ctx); err != nil {
+		return err
+	}
+
 	fullKey := s.getFullKey(path)
 
 	exists, err := s.Exists(ctx, path)
 	if err != nil {
-		return fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to check if file exists: %s", path),
-		)
+		return err
 	}
 	if !exists {
 		return fserrors.FileNotFoundError(path)
@@ -270,17 +354,53 @@ func (s *S3Storage) Delete(ctx context.Context, path string) error {
 		Key:    aws.String(fullKey),
 	})
 	if err != nil {
-		return fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to delete file from S3: %s", path),
-		)
+		return s3Error(err, path, "s3:DeleteObject", fmt.Sprintf("Failed to delete file from S3: %s", path))
 	}
 
 	return nil
 }
 
+// Copy copies a file to a path where no file exists yet with CopyObject,
+// without downloading it. S3 copies files of up to 5 GB this way.
+func (s *S3Storage) Copy(ctx context.Context, srcPath, dstPath string) (*FileInfo, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.Exists(ctx, dstPath)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fserrors.NewCustomError(
+			http.StatusConflict,
+			fserrors.ErrCodeFileAlreadyExists,
+			fmt.Sprintf("File already exists: %s", dstPath),
+		)
+	}
+
+	// The source is the bucket and key, URL-encoded
+	source := strings.Split(s.bucket+"/"+s.getFullKey(srcPath), "/")
+	for i, segment := range source {
+		source[i] = url.PathEscape(segment)
+	}
+	_, err = s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(s.getFullKey(dstPath)),
+		CopySource: aws.String(strings.Join(source, "/")),
+	})
+	if err != nil {
+		return nil, s3Error(err, srcPath, "s3:PutObject", fmt.Sprintf("Failed to copy file in S3: %s", srcPath))
+	}
+
+	return s.GetInfo(ctx, dstPath)
+}
+
 func (s *S3Storage) Exists(ctx context.Context, path string) (bool, error) {
+	if err := s.connect(ctx); err != nil {
+		return false, err
+	}
+
 	fullKey := s.getFullKey(path)
 
 	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
@@ -288,46 +408,135 @@ func (s *S3Storage) Exists(ctx context.Context, path string) (bool, error) {
 		Key:    aws.String(fullKey),
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "404") {
+		appErr := s3Error(err, path, "s3:GetObject", fmt.Sprintf("Failed to check if file exists in S3: %s", path))
+		if appErr.Code == fserrors.ErrCodeFileNotFound {
 			return false, nil
 		}
-
-		return false, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to check if file exists in S3: %s", path),
-		)
+		return false, appErr
 	}
 
 	return true, nil
 }
 
+// List returns the files in a directory, following the pages of the
+// listing up to MaxListEntries entries. Longer listings are cut short with
+// a warning.
 func (s *S3Storage) List(ctx context.Context, path string) ([]FileInfo, error) {
-	fullPrefix := s.getFullKey(path)
-	if fullPrefix != "" && !strings.HasSuffix(fullPrefix, "/") {
-		fullPrefix += "/"
+	page, err := s.ListWithOptions(ctx, path, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if page.Truncated() {
+		logger.Default().Warnf("S3 listing of %q cut short at %d entries", path, s.maxListEntries)
 	}
 
-	if path == "" || path == "/" {
-		fullPrefix = s.basePrefix
-		if fullPrefix != "" && !strings.HasSuffix(fullPrefix, "/") {
-			fullPrefix += "/"
+	files := page.Files
+	fullPrefix := s.listPrefix(path)
+	if len(files) == 0 && !strings.HasSuffix(fullPrefix, "/") {
+		fileInfo, err := s.GetInfo(ctx, path)
+		if err == nil {
+			return []FileInfo{*fileInfo}, nil
 		}
 	}
 
-	output, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+	return files, nil
+}
+
+// ListPage returns a page of the files in a directory, continuing from an
+// S3 continuation token. Limit counts both files and subdirectories.
+func (s *S3Storage) ListPage(ctx context.Context, path string, opts ListOptions) (*ListPage, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	fullPrefix := s.listPrefix(path)
+
+	input := &s3.ListObjectsV2Input{
 		Bucket:    aws.String(s.bucket),
 		Prefix:    aws.String(fullPrefix),
 		Delimiter: aws.String("/"),
-	})
+	}
+	if opts.Limit > 0 {
+		input.MaxKeys = aws.Int32(int32(opts.Limit))
+	}
+	if opts.Token != "" {
+		input.ContinuationToken = aws.String(opts.Token)
+	}
+
+	output, err := s.client.ListObjectsV2(ctx, input)
 	if err != nil {
-		return nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to list files in S3: %s", path),
-		)
+		// S3 rejects continuation tokens it did not issue for the prefix
+		var apiErr smithy.APIError
+		if opts.Token != "" && errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidArgument" {
+			return nil, fserrors.InvalidCursorError()
+		}
+		return nil, s3Error(err, path, "s3:ListBucket", fmt.Sprintf("Failed to list files in S3: %s", path))
 	}
 
+	page := &ListPage{Files: s.listedFiles(output, fullPrefix)}
+	if aws.ToBool(output.IsTruncated) {
+		page.NextToken = aws.ToString(output.NextContinuationToken)
+	}
+	return page, nil
+}
+
+// ListWithOptions returns the files in a directory, following the pages of
+// the listing from opts.Token until opts.Limit entries, or MaxListEntries
+// without a limit. Limit counts both files and subdirectories. When entries
+// remain, the listing is truncated and its NextToken continues it.
+func (s *S3Storage) ListWithOptions(ctx context.Context, path string, opts ListOptions) (*ListPage, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = s.maxListEntries
+	}
+	fullPrefix := s.listPrefix(path)
+
+	page := &ListPage{}
+	token := opts.Token
+	for remaining := limit; remaining > 0; remaining = limit - len(page.Files) {
+		input := &s3.ListObjectsV2Input{
+			Bucket:    aws.String(s.bucket),
+			Prefix:    aws.String(fullPrefix),
+			Delimiter: aws.String("/"),
+			MaxKeys:   aws.Int32(int32(min(remaining, s3MaxKeys))),
+		}
+		if token != "" {
+			input.ContinuationToken = aws.String(token)
+		}
+
+		output, err := s.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, s3Error(err, path, "s3:ListBucket", fmt.Sprintf("Failed to list files in S3: %s", path))
+		}
+		page.Files = append(page.Files, s.listedFiles(output, fullPrefix)...)
+
+		token = ""
+		if aws.ToBool(output.IsTruncated) {
+			token = aws.ToString(output.NextContinuationToken)
+		}
+		if token == "" {
+			break
+		}
+	}
+	page.NextToken = token
+	return page, nil
+}
+
+// listPrefix returns the key prefix of a directory
+func (s *S3Storage) listPrefix(path string) string {
+	fullPrefix := s.getFullKey(path)
+	if fullPrefix != "" {
+		fullPrefix += "/"
+	}
+	return fullPrefix
+}
+
+// listedFiles converts the directories and objects of a listing to file info
+func (s *S3Storage) listedFiles(output *s3.ListObjectsV2Output, fullPrefix string) []FileInfo {
 	var files []FileInfo
 
 	for _, prefix := range output.CommonPrefixes {
@@ -366,17 +575,14 @@ func (s *S3Storage) List(ctx context.Context, path string) ([]FileInfo, error) {
 		})
 	}
 
-	if len(files) == 0 && !strings.HasSuffix(fullPrefix, "/") {
-		fileInfo, err := s.GetInfo(ctx, path)
-		if err == nil {
-			return []FileInfo{*fileInfo}, nil
-		}
-	}
-
-	return files, nil
+	return files
 }
 
 func (s *S3Storage) GetInfo(ctx context.Context, path string) (*FileInfo, error) {
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+
 	fullKey := s.getFullKey(path)
 
 	headOutput, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
@@ -384,14 +590,7 @@ func (s *S3Storage) GetInfo(ctx context.Context, path string) (*FileInfo, error)
 		Key:    aws.String(fullKey),
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "404") {
-			return nil, fserrors.FileNotFoundError(path)
-		}
-		return nil, fserrors.WrapError(
-			err,
-			http.StatusInternalServerError,
-			fmt.Sprintf("Failed to get file metadata from S3: %s", path),
-		)
+		return nil, s3Error(err, path, "s3:GetObject", fmt.Sprintf("Failed to get file metadata from S3: %s", path))
 	}
 
 	contentType := "application/octet-stream"
@@ -411,6 +610,100 @@ func (s *S3Storage) GetInfo(ctx context.Context, path string) (*FileInfo, error)
 	}, nil
 }
 
+// PresignUpload returns credentials for a browser to upload a file straight
+// to S3: a form with a policy document limiting the size and content type
+// of the file, or a presigned PUT URL refusing to replace an existing file
+func (s *S3Storage) PresignUpload(ctx context.Context, path string, opts DirectUploadOptions) (*DirectUpload, error) {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultDirectUploadTTL
+	}
+	presigner := s3.NewPresignClient(s.client)
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.getFullKey(path)),
+	}
+	upload := &DirectUpload{
+		Method:    opts.Method,
+		Path:      path,
+		ExpiresAt: time.Now().Add(opts.TTL),
+	}
+
+	switch opts.Method {
+	case http.MethodPut:
+		input.IfNoneMatch = aws.String("*")
+		if opts.ContentType != "" {
+			input.ContentType = aws.String(opts.ContentType)
+		}
+		req, err := presigner.PresignPutObject(ctx, input, s3.WithPresignExpires(opts.TTL))
+		if err != nil {
+			return nil, fserrors.WrapError(err, http.StatusInternalServerError, "Failed to presign S3 upload")
+		}
+		upload.URL = req.URL
+		upload.Headers = make(map[string]string, len(req.SignedHeader))
+		for name, values := range req.SignedHeader {
+			if !strings.EqualFold(name, "Host") && len(values) > 0 {
+				upload.Headers[name] = values[0]
+			}
+		}
+
+	case http.MethodPost, "":
+		upload.Method = http.MethodPost
+		var conditions []interface{}
+		if opts.MaxSize > 0 {
+			conditions = append(conditions, []interface{}{"content-length-range", 0, opts.MaxSize})
+		}
+		if opts.ContentType != "" {
+			conditions = append(conditions, map[string]string{"Content-Type": opts.ContentType})
+		}
+		req, err := presigner.PresignPostObject(ctx, input, func(o *s3.PresignPostOptions) {
+			o.Expires = opts.TTL
+			o.Conditions = conditions
+		})
+		if err != nil {
+			return nil, fserrors.WrapError(err, http.StatusInternalServerError, "Failed to presign S3 upload")
+		}
+		upload.URL = req.URL
+		upload.Fields = req.Values
+		if opts.ContentType != "" {
+			upload.Fields["Content-Type"] = opts.ContentType
+		}
+
+	default:
+		return nil, fserrors.NewError(http.StatusBadRequest, "Direct upload method must be POST or PUT")
+	}
+	return upload, nil
+}
+
+// s3Error maps an S3 error to the error of its HTTP status or error code:
+// 404 to FileNotFound for the path, 403 to PermissionDenied for the action,
+// and 503, throttling, and a missing bucket to StorageUnavailable. Other
+// errors are wrapped in a 500 with the message.
+func s3Error(err error, path, action, message string) *fserrors.AppError {
+	var status int
+	var responseErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &responseErr) {
+		status = responseErr.HTTPStatusCode()
+	}
+	var code string
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+	}
+
+	switch {
+	case code == "NoSuchBucket" || code == "SlowDown" || code == "ServiceUnavailable" ||
+		status == http.StatusServiceUnavailable:
+		return fserrors.StorageUnavailableError(err)
+	case code == "NoSuchKey" || code == "NotFound" || status == http.StatusNotFound:
+		return fserrors.FileNotFoundError(path)
+	case code == "AccessDenied" || status == http.StatusForbidden:
+		appErr := fserrors.PermissionDeniedError(action)
+		appErr.Internal = err
+		return appErr
+	}
+	return fserrors.WrapError(err, http.StatusInternalServerError, message)
+}
+
 func getContentTypeByExt(ext string) string {
 	ext = strings.ToLower(ext)
 
@@ -465,6 +758,12 @@ func getContentTypeByExt(ext string) string {
 		return "video/quicktime"
 	case ".webm":
 		return "video/webm"
+	case ".mkv":
+		return "video/x-matroska"
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
 	default:
 		return "application/octet-stream"
 	}
@@ -0,0 +1,307 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// DefaultIndexMaxBytes is how much of a file is read for indexing
+	DefaultIndexMaxBytes = 10 << 20
+
+	// DefaultSearchLimit is how many files a search returns by default
+	DefaultSearchLimit = 20
+)
+
+// Indexer indexes the text of stored files for search, e.g. BleveIndex. A
+// Provider with an indexer removes deleted files from it and searches it.
+type Indexer interface {
+	// Index adds or replaces a file in the index, with its text content,
+	// which is empty for files without text
+	Index(ctx context.Context, filePath string, info FileInfo, content io.Reader) error
+
+	// Remove removes a file from the index
+	Remove(ctx context.Context, filePath string) error
+
+	// Search returns the paths of the files matching a query, best first
+	Search(ctx context.Context, q string, opts SearchOptions) ([]SearchHit, error)
+}
+
+// SearchOptions narrows a search
+type SearchOptions struct {
+	// Dir limits the search to a directory and its subdirectories
+	Dir string
+
+	// Limit defaults to DefaultSearchLimit
+	Limit int
+}
+
+// SearchHit is a file matching a search
+type SearchHit struct {
+	Path  string  `json:"path"`
+	Score float64 `json:"score"`
+
+	// Info is set by Provider.Search
+	Info *FileInfo `json:"info,omitempty"`
+}
+
+// TextExtractor extracts the text of files for indexing, e.g.
+// PlainTextExtractor or PDFTextExtractor
+type TextExtractor interface {
+	// Supports reports whether the text of a file can be extracted, by its
+	// name and content type
+	Supports(filename, contentType string) bool
+
+	// Extract returns the text of a file
+	Extract(ctx context.Context, src io.Reader, filename string) (io.Reader, error)
+}
+
+// PlainTextExtractor reads text files as they are: text/* content types and
+// common text formats such as Markdown, CSV, and JSON
+type PlainTextExtractor struct{}
+
+// plainTextExtensions are text formats whose content type may not be text/*
+var plainTextExtensions = []string{".txt", ".md", ".csv", ".json", ".xml", ".yaml", ".yml", ".log"}
+
+// Supports reports whether a file is text
+func (PlainTextExtractor) Supports(filename, contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		slices.Contains(plainTextExtensions, strings.ToLower(filepath.Ext(filename)))
+}
+
+// Extract returns the file itself
+func (PlainTextExtractor) Extract(_ context.Context, src io.Reader, _ string) (io.Reader, error) {
+	return src, nil
+}
+
+// PDFTextExtractor extracts the text of PDFs with pdftotext from poppler,
+// which must be installed
+type PDFTextExtractor struct {
+	// Path is the pdftotext executable, defaulting to "pdftotext"
+	Path string
+}
+
+// Supports reports whether a file is a PDF
+func (PDFTextExtractor) Supports(filename, contentType string) bool {
+	return contentType == "application/pdf" || strings.ToLower(filepath.Ext(filename)) == ".pdf"
+}
+
+// Extract runs pdftotext on a temporary copy of the PDF
+func (e PDFTextExtractor) Extract(ctx context.Context, src io.Reader, _ string) (io.Reader, error) {
+	executable := e.Path
+	if executable == "" {
+		executable = "pdftotext"
+	}
+
+	dir, err := os.MkdirTemp("", "gokit-pdftext-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "input.pdf")
+	if err := writeFile(input, src); err != nil {
+		return nil, err
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, executable, "-q", "-enc", "UTF-8", input, "-")
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("filesystem: pdftotext: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout, nil
+}
+
+// IndexProcessor is a processor adding files to the indexer of their
+// provider, with the text of the extractors supporting them. Other files
+// are indexed by name. Its name is "index".
+type IndexProcessor struct {
+	extractors []TextExtractor
+
+	// MaxBytes is how much text is indexed, defaulting to
+	// DefaultIndexMaxBytes
+	MaxBytes int64
+}
+
+// NewIndexProcessor creates a processor indexing files with extractors,
+// defaulting to PlainTextExtractor and PDFTextExtractor
+func NewIndexProcessor(extractors ...TextExtractor) *IndexProcessor {
+	if len(extractors) == 0 {
+		extractors = []TextExtractor{PlainTextExtractor{}, PDFTextExtractor{}}
+	}
+	return &IndexProcessor{extractors: extractors, MaxBytes: DefaultIndexMaxBytes}
+}
+
+// Name returns "index"
+func (p *IndexProcessor) Name() string {
+	return "index"
+}
+
+// Process extracts the text of a file and indexes it
+func (p *IndexProcessor) Process(ctx context.Context, file *ProcessFile) error {
+	indexer := file.Provider().config.Indexer
+	if indexer == nil {
+		return errors.New("filesystem: no indexer configured")
+	}
+
+	var content io.Reader = strings.NewReader("")
+	for _, extractor := range p.extractors {
+		if !extractor.Supports(file.Path, file.Info.ContentType) {
+			continue
+		}
+		src, err := file.Open(ctx)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		text, err := extractor.Extract(ctx, src, file.Path)
+		if err != nil {
+			return err
+		}
+		content = io.LimitReader(text, p.MaxBytes)
+		break
+	}
+
+	return indexer.Index(ctx, file.Path, file.Info, content)
+}
+
+// indexedFile is the document of a file in a BleveIndex
+type indexedFile struct {
+	Path        string `json:"path"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+// BleveIndex is an Indexer keeping a Bleve full-text index in process, in a
+// directory or in memory. It suits a single instance.
+type BleveIndex struct {
+	index bleve.Index
+
+	closeOnce sync.Once
+}
+
+// NewBleveIndex opens the index in a directory, creating it when missing,
+// or creates an index in memory for an empty dir
+func NewBleveIndex(dir string) (*BleveIndex, error) {
+	if dir == "" {
+		index, err := bleve.NewMemOnly(bleveMapping())
+		if err != nil {
+			return nil, fmt.Errorf("filesystem: create search index: %w", err)
+		}
+		return &BleveIndex{index: index}, nil
+	}
+
+	index, err := bleve.Open(dir)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(dir, bleveMapping())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filesystem: open search index %s: %w", dir, err)
+	}
+	return &BleveIndex{index: index}, nil
+}
+
+// bleveMapping indexes the name and content of files as text, and their
+// path and content type as keywords
+func bleveMapping() *mapping.IndexMappingImpl {
+	keyword := bleve.NewKeywordFieldMapping()
+	keyword.IncludeInAll = false
+	text := bleve.NewTextFieldMapping()
+	text.Store = false
+
+	file := bleve.NewDocumentMapping()
+	file.AddFieldMappingsAt("path", keyword)
+	file.AddFieldMappingsAt("contentType", keyword)
+	file.AddFieldMappingsAt("name", bleve.NewTextFieldMapping())
+	file.AddFieldMappingsAt("content", text)
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = file
+	return m
+}
+
+// Index adds or replaces a file, reading its content as UTF-8 text
+func (b *BleveIndex) Index(_ context.Context, filePath string, info FileInfo, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, " ")
+	}
+
+	filePath = cleanPath(filePath)
+	err = b.index.Index(filePath, indexedFile{
+		Path:        filePath,
+		Name:        path.Base(filePath),
+		ContentType: info.ContentType,
+		Content:     text,
+	})
+	if err != nil {
+		return fmt.Errorf("filesystem: index %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// Remove removes a file from the index
+func (b *BleveIndex) Remove(_ context.Context, filePath string) error {
+	if err := b.index.Delete(cleanPath(filePath)); err != nil {
+		return fmt.Errorf("filesystem: remove %s from the index: %w", filePath, err)
+	}
+	return nil
+}
+
+// Search matches the words of a query against the names and contents of
+// files
+func (b *BleveIndex) Search(ctx context.Context, q string, opts SearchOptions) ([]SearchHit, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultSearchLimit
+	}
+
+	name := bleve.NewMatchQuery(q)
+	name.SetField("name")
+	content := bleve.NewMatchQuery(q)
+	content.SetField("content")
+	var match query.Query = bleve.NewDisjunctionQuery(name, content)
+
+	if dir := cleanPath(opts.Dir); dir != "" && dir != "." {
+		prefix := bleve.NewPrefixQuery(dir + "/")
+		prefix.SetField("path")
+		match = bleve.NewConjunctionQuery(match, prefix)
+	}
+
+	result, err := b.index.SearchInContext(ctx, bleve.NewSearchRequestOptions(match, opts.Limit, 0, false))
+	if err != nil {
+		return nil, fmt.Errorf("filesystem: search: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		hits = append(hits, SearchHit{Path: hit.ID, Score: hit.Score})
+	}
+	return hits, nil
+}
+
+// Close closes the index, e.g. on shutdown
+func (b *BleveIndex) Close() error {
+	var err error
+	b.closeOnce.Do(func() { err = b.index.Close() })
+	return err
+}
@@ -0,0 +1,237 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkFileSize is the size of the files of the benchmarks
+const benchmarkFileSize = 10 << 20
+
+// benchmarkFile returns an upload of benchmarkFileSize bytes. Uploads up to
+// the memory limit of forms are held in memory, larger ones on disk.
+func benchmarkFile(b *testing.B, onDisk bool) *multipart.FileHeader {
+	b.Helper()
+	data := bytes.Repeat([]byte("0123456789abcdef"), benchmarkFileSize/16)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "bench.bin")
+	if err != nil {
+		b.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	maxMemory := int64(benchmarkFileSize) << 1
+	if onDisk {
+		maxMemory = 0
+	}
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(maxMemory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+// newBenchmarkS3 returns an S3 storage against a fake S3 serving a file of
+// benchmarkFileSize bytes at "bench/get.bin" and discarding uploads, whole
+// or in parts
+func newBenchmarkS3(b *testing.B) *S3Storage {
+	b.Helper()
+	content := bytes.Repeat([]byte("0123456789abcdef"), benchmarkFileSize/16)
+	modified := time.Now()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		found := strings.HasSuffix(r.URL.Path, "/bench/get.bin")
+		switch r.Method {
+		case http.MethodHead, http.MethodGet:
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+			if r.Method == http.MethodGet {
+				w.Write(content)
+			}
+		case http.MethodPut:
+			io.Copy(io.Discard, r.Body)
+			w.Header().Set("ETag", `"bench"`)
+		case http.MethodPost:
+			io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "application/xml")
+			if r.URL.Query().Has("uploads") {
+				fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>bench</Bucket><Key>key</Key><UploadId>upload</UploadId></InitiateMultipartUploadResult>`)
+				return
+			}
+			fmt.Fprint(w, `<CompleteMultipartUploadResult><Bucket>bench</Bucket><Key>key</Key><ETag>"bench"</ETag></CompleteMultipartUploadResult>`)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	b.Cleanup(server.Close)
+
+	storage, err := NewS3Storage(S3Config{
+		Bucket:          "bench",
+		Region:          "us-east-1",
+		Endpoint:        server.URL,
+		AccessKey:       "key",
+		SecretKey:       "secret",
+		UsePathStyle:    true,
+		SkipBucketCheck: true,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return storage
+}
+
+// newBenchmarkLocal returns a local storage in a temporary directory
+func newBenchmarkLocal(b *testing.B) *LocalStorage {
+	b.Helper()
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: b.TempDir(), CreateDirectories: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return storage
+}
+
+func BenchmarkLocalUpload(b *testing.B) {
+	for _, onDisk := range []bool{false, true} {
+		b.Run(fmt.Sprintf("onDisk=%v", onDisk), func(b *testing.B) {
+			storage := newBenchmarkLocal(b)
+			file := benchmarkFile(b, onDisk)
+			ctx := context.Background()
+
+			b.SetBytes(benchmarkFileSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := storage.Upload(ctx, file, fmt.Sprintf("bench/%d.bin", i)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLocalGet(b *testing.B) {
+	storage := newBenchmarkLocal(b)
+	ctx := context.Background()
+	if _, err := storage.Upload(ctx, benchmarkFile(b, false), "bench/get.bin"); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(benchmarkFileSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchmarkRead(b, storage, "bench/get.bin")
+	}
+}
+
+// benchmarkListFiles is how many files the directory of the list
+// benchmarks holds
+const benchmarkListFiles = 20000
+
+func BenchmarkLocalList(b *testing.B) {
+	basePath := b.TempDir()
+	dir := filepath.Join(basePath, "bench")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < benchmarkListFiles; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%06d.txt", i)), nil, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	for _, concurrency := range []int{1, DefaultLocalListConcurrency} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			storage, err := NewLocalStorage(LocalStorageConfig{BasePath: basePath, ListConcurrency: concurrency})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := storage.List(ctx, "bench"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("stat=false", func(b *testing.B) {
+		storage, err := NewLocalStorage(LocalStorageConfig{BasePath: basePath})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			err := storage.ListFunc(ctx, "bench", false, func(FileInfo) error { return nil })
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkS3Upload(b *testing.B) {
+	for _, onDisk := range []bool{false, true} {
+		b.Run(fmt.Sprintf("onDisk=%v", onDisk), func(b *testing.B) {
+			storage := newBenchmarkS3(b)
+			file := benchmarkFile(b, onDisk)
+			ctx := context.Background()
+
+			b.SetBytes(benchmarkFileSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := storage.Upload(ctx, file, fmt.Sprintf("bench/%d.bin", i)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkS3Get(b *testing.B) {
+	storage := newBenchmarkS3(b)
+
+	b.SetBytes(benchmarkFileSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchmarkRead(b, storage, "bench/get.bin")
+	}
+}
+
+// benchmarkRead reads a whole file, as the download handler does
+func benchmarkRead(b *testing.B, storage Storage, path string) {
+	file, _, err := storage.Get(context.Background(), path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	n, err := io.Copy(io.Discard, file)
+	file.Close()
+	if err != nil || n != benchmarkFileSize {
+		b.Fatalf("read %d bytes: %v", n, err)
+	}
+}
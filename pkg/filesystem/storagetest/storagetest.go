@@ -0,0 +1,428 @@
+// Package storagetest checks that Storage implementations behave like the
+// storages of the filesystem package, so other backends can verify they
+// work with the provider and handlers:
+//
+//	func TestGCSStorage(t *testing.T) {
+//		storagetest.RunSuite(t, func() filesystem.Storage {
+//			return newTestGCSStorage(t)
+//		})
+//	}
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+)
+
+// ConcurrentWrites is the number of files uploaded at once by the
+// concurrent writes test
+const ConcurrentWrites = 16
+
+// RunSuite runs the contract tests of Storage, and of PagedLister and
+// Copier when implemented, against the storages returned by newStorage. Each test
+// calls newStorage for an empty storage, which must create the directories
+// of nested paths on upload.
+//
+// The suite does not cover uploads to existing paths, which some storages
+// replace and others reject, nor listings of missing directories.
+func RunSuite(t *testing.T, newStorage func() filesystem.Storage) {
+	t.Helper()
+
+	t.Run("UploadAndGet", func(t *testing.T) {
+		s := newStorage()
+		content := []byte("Hello, world!")
+
+		info := upload(t, s, "hello.txt", content)
+		if info.Name != "hello.txt" {
+			t.Errorf("Upload: name = %q, want %q", info.Name, "hello.txt")
+		}
+		if info.Size != int64(len(content)) {
+			t.Errorf("Upload: size = %d, want %d", info.Size, len(content))
+		}
+		if info.IsDirectory {
+			t.Errorf("Upload: file is a directory")
+		}
+
+		got, gotInfo := get(t, s, "hello.txt")
+		if !bytes.Equal(got, content) {
+			t.Errorf("Get: content = %q, want %q", got, content)
+		}
+		if gotInfo.Size != int64(len(content)) {
+			t.Errorf("Get: size = %d, want %d", gotInfo.Size, len(content))
+		}
+	})
+
+	t.Run("EmptyFile", func(t *testing.T) {
+		s := newStorage()
+
+		if info := upload(t, s, "empty.txt", nil); info.Size != 0 {
+			t.Errorf("Upload: size = %d, want 0", info.Size)
+		}
+		if got, _ := get(t, s, "empty.txt"); len(got) != 0 {
+			t.Errorf("Get: content = %q, want none", got)
+		}
+		if info := getInfo(t, s, "empty.txt"); info.Size != 0 {
+			t.Errorf("GetInfo: size = %d, want 0", info.Size)
+		}
+	})
+
+	t.Run("BinaryContent", func(t *testing.T) {
+		s := newStorage()
+		content := make([]byte, 1<<20)
+		for i := range content {
+			content[i] = byte(i * 31)
+		}
+
+		upload(t, s, "data.bin", content)
+		if got, _ := get(t, s, "data.bin"); !bytes.Equal(got, content) {
+			t.Errorf("Get: content of %d bytes differs from the %d bytes uploaded", len(got), len(content))
+		}
+	})
+
+	t.Run("NestedPaths", func(t *testing.T) {
+		s := newStorage()
+		content := []byte("deep")
+
+		if info := upload(t, s, "a/b/c/deep.txt", content); info.Name != "deep.txt" {
+			t.Errorf("Upload: name = %q, want %q", info.Name, "deep.txt")
+		}
+		if got, _ := get(t, s, "a/b/c/deep.txt"); !bytes.Equal(got, content) {
+			t.Errorf("Get: content = %q, want %q", got, content)
+		}
+		wantEntries(t, s, "a/b", map[string]bool{"c": true})
+		wantEntries(t, s, "a/b/c", map[string]bool{"deep.txt": false})
+	})
+
+	t.Run("UnicodeNames", func(t *testing.T) {
+		s := newStorage()
+		name := "résumé 履歴書 📄.txt"
+		content := []byte("unicode")
+
+		upload(t, s, "dokumen/"+name, content)
+		if got, _ := get(t, s, "dokumen/"+name); !bytes.Equal(got, content) {
+			t.Errorf("Get: content = %q, want %q", got, content)
+		}
+		if info := getInfo(t, s, "dokumen/"+name); info.Name != name {
+			t.Errorf("GetInfo: name = %q, want %q", info.Name, name)
+		}
+		wantEntries(t, s, "dokumen", map[string]bool{name: false})
+	})
+
+	t.Run("Exists", func(t *testing.T) {
+		s := newStorage()
+
+		if exists(t, s, "exists.txt") {
+			t.Errorf("Exists: missing file exists")
+		}
+		upload(t, s, "exists.txt", []byte("here"))
+		if !exists(t, s, "exists.txt") {
+			t.Errorf("Exists: uploaded file does not exist")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		s := newStorage()
+		ctx := context.Background()
+
+		upload(t, s, "docs/delete.txt", []byte("bye"))
+		if err := s.Delete(ctx, "docs/delete.txt"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if exists(t, s, "docs/delete.txt") {
+			t.Errorf("Exists: deleted file exists")
+		}
+		if _, _, err := s.Get(ctx, "docs/delete.txt"); !isNotFound(err) {
+			t.Errorf("Get: error of a deleted file = %v, want %s", err, fserrors.ErrCodeFileNotFound)
+		}
+		if err := s.Delete(ctx, "docs/delete.txt"); !isNotFound(err) {
+			t.Errorf("Delete: error of a deleted file = %v, want %s", err, fserrors.ErrCodeFileNotFound)
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		s := newStorage()
+		ctx := context.Background()
+
+		if file, _, err := s.Get(ctx, "missing.txt"); !isNotFound(err) {
+			if file != nil {
+				file.Close()
+			}
+			t.Errorf("Get: error = %v, want %s", err, fserrors.ErrCodeFileNotFound)
+		}
+		if _, err := s.GetInfo(ctx, "missing.txt"); !isNotFound(err) {
+			t.Errorf("GetInfo: error = %v, want %s", err, fserrors.ErrCodeFileNotFound)
+		}
+		if err := s.Delete(ctx, "missing.txt"); !isNotFound(err) {
+			t.Errorf("Delete: error = %v, want %s", err, fserrors.ErrCodeFileNotFound)
+		}
+	})
+
+	t.Run("GetInfo", func(t *testing.T) {
+		s := newStorage()
+		content := []byte("info")
+
+		upload(t, s, "docs/info.txt", content)
+		info := getInfo(t, s, "docs/info.txt")
+		if info.Name != "info.txt" {
+			t.Errorf("GetInfo: name = %q, want %q", info.Name, "info.txt")
+		}
+		if info.Size != int64(len(content)) {
+			t.Errorf("GetInfo: size = %d, want %d", info.Size, len(content))
+		}
+		if info.LastModified.IsZero() {
+			t.Errorf("GetInfo: no modification time")
+		}
+		if info.IsDirectory {
+			t.Errorf("GetInfo: file is a directory")
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		s := newStorage()
+
+		upload(t, s, "list/one.txt", []byte("1"))
+		upload(t, s, "list/two.txt", []byte("22"))
+		upload(t, s, "list/sub/three.txt", []byte("333"))
+		upload(t, s, "other/four.txt", []byte("4444"))
+
+		wantEntries(t, s, "list", map[string]bool{"one.txt": false, "two.txt": false, "sub": true})
+		files, err := s.List(context.Background(), "list")
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, file := range files {
+			if file.Name == "two.txt" && file.Size != 2 {
+				t.Errorf("List: size of two.txt = %d, want 2", file.Size)
+			}
+		}
+	})
+
+	t.Run("ConcurrentWrites", func(t *testing.T) {
+		s := newStorage()
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		errs := make([]error, ConcurrentWrites)
+		for i := 0; i < ConcurrentWrites; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				name := fmt.Sprintf("file-%02d.txt", i)
+				file, err := filesystem.NewFileHeader(name, []byte(name))
+				if err == nil {
+					_, err = s.Upload(ctx, file, "concurrent/"+name)
+				}
+				errs[i] = err
+			}(i)
+		}
+		wg.Wait()
+
+		want := map[string]bool{}
+		for i, err := range errs {
+			name := fmt.Sprintf("file-%02d.txt", i)
+			if err != nil {
+				t.Errorf("Upload %s: %v", name, err)
+				continue
+			}
+			want[name] = false
+			if got, _ := get(t, s, "concurrent/"+name); string(got) != name {
+				t.Errorf("Get %s: content = %q, want %q", name, got, name)
+			}
+		}
+		wantEntries(t, s, "concurrent", want)
+	})
+
+	t.Run("ListPage", func(t *testing.T) {
+		s := newStorage()
+		lister, ok := s.(filesystem.PagedLister)
+		if !ok {
+			t.Skip("storage does not list pages")
+		}
+
+		const files = 5
+		want := map[string]bool{}
+		for i := 0; i < files; i++ {
+			name := fmt.Sprintf("page-%d.txt", i)
+			upload(t, s, "pages/"+name, []byte(name))
+			want[name] = true
+		}
+
+		opts := filesystem.ListOptions{Limit: 2}
+		for pages := 0; ; pages++ {
+			if pages > files {
+				t.Fatalf("ListPage: more pages than files")
+			}
+			page, err := lister.ListPage(context.Background(), "pages", opts)
+			if err != nil {
+				t.Fatalf("ListPage: %v", err)
+			}
+			if len(page.Files) > opts.Limit {
+				t.Errorf("ListPage: %d files, want at most %d", len(page.Files), opts.Limit)
+			}
+			for _, file := range page.Files {
+				if !want[file.Name] {
+					t.Errorf("ListPage: unexpected or repeated entry %q", file.Name)
+				}
+				delete(want, file.Name)
+			}
+			if !page.Truncated() {
+				break
+			}
+			opts.Token = page.NextToken
+		}
+		if len(want) > 0 {
+			t.Errorf("ListPage: missing entries %v", sortedKeys(want))
+		}
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		s := newStorage()
+		copier, ok := s.(filesystem.Copier)
+		if !ok {
+			t.Skip("storage does not copy files")
+		}
+		ctx := context.Background()
+		content := []byte("copied content")
+		upload(t, s, "original.txt", content)
+
+		info, err := copier.Copy(ctx, "original.txt", "copies/copy.txt")
+		if err != nil {
+			t.Fatalf("Copy: %v", err)
+		}
+		if info == nil || info.Size != int64(len(content)) {
+			t.Errorf("Copy: info = %+v, want size %d", info, len(content))
+		}
+		if got, _ := get(t, s, "copies/copy.txt"); !bytes.Equal(got, content) {
+			t.Errorf("Copy: content = %q, want %q", got, content)
+		}
+		if !exists(t, s, "original.txt") {
+			t.Errorf("Copy: source was removed")
+		}
+
+		if _, err := copier.Copy(ctx, "original.txt", "copies/copy.txt"); !hasCode(err, fserrors.ErrCodeFileAlreadyExists) {
+			t.Errorf("Copy to an existing file: error = %v, want %s", err, fserrors.ErrCodeFileAlreadyExists)
+		}
+		if _, err := copier.Copy(ctx, "missing.txt", "copies/missing.txt"); !isNotFound(err) {
+			t.Errorf("Copy of a missing file: error = %v, want %s", err, fserrors.ErrCodeFileNotFound)
+		}
+	})
+}
+
+// upload stores content at a path
+func upload(t *testing.T, s filesystem.Storage, path string, content []byte) *filesystem.FileInfo {
+	t.Helper()
+	file, err := filesystem.NewFileHeader(path, content)
+	if err != nil {
+		t.Fatalf("NewFileHeader: %v", err)
+	}
+	info, err := s.Upload(context.Background(), file, path)
+	if err != nil {
+		t.Fatalf("Upload %s: %v", path, err)
+	}
+	if info == nil {
+		t.Fatalf("Upload %s: no file info", path)
+	}
+	return info
+}
+
+// get returns the content and info of a file
+func get(t *testing.T, s filesystem.Storage, path string) ([]byte, *filesystem.FileInfo) {
+	t.Helper()
+	file, info, err := s.Get(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Get %s: %v", path, err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("Get %s: reading: %v", path, err)
+	}
+	if info == nil {
+		t.Fatalf("Get %s: no file info", path)
+	}
+	return content, info
+}
+
+// getInfo returns the info of a file
+func getInfo(t *testing.T, s filesystem.Storage, path string) *filesystem.FileInfo {
+	t.Helper()
+	info, err := s.GetInfo(context.Background(), path)
+	if err != nil {
+		t.Fatalf("GetInfo %s: %v", path, err)
+	}
+	if info == nil {
+		t.Fatalf("GetInfo %s: no file info", path)
+	}
+	return info
+}
+
+// exists reports whether a file exists
+func exists(t *testing.T, s filesystem.Storage, path string) bool {
+	t.Helper()
+	ok, err := s.Exists(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Exists %s: %v", path, err)
+	}
+	return ok
+}
+
+// wantEntries checks the names of the entries of a directory, and whether
+// each is a directory
+func wantEntries(t *testing.T, s filesystem.Storage, dir string, want map[string]bool) {
+	t.Helper()
+	files, err := s.List(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("List %s: %v", dir, err)
+	}
+
+	got := map[string]bool{}
+	for _, file := range files {
+		if _, seen := got[file.Name]; seen {
+			t.Errorf("List %s: repeated entry %q", dir, file.Name)
+		}
+		got[file.Name] = file.IsDirectory
+	}
+	for name, isDir := range want {
+		gotDir, ok := got[name]
+		switch {
+		case !ok:
+			t.Errorf("List %s: missing entry %q, got %v", dir, name, sortedKeys(got))
+		case gotDir != isDir:
+			t.Errorf("List %s: entry %q is a directory: %v, want %v", dir, name, gotDir, isDir)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("List %s: unexpected entry %q", dir, name)
+		}
+	}
+}
+
+// isNotFound reports whether an error is the not found error of a file
+func isNotFound(err error) bool {
+	return hasCode(err, fserrors.ErrCodeFileNotFound)
+}
+
+// hasCode reports whether an error is an AppError with a code
+func hasCode(err error, code string) bool {
+	var appErr *fserrors.AppError
+	return fserrors.As(err, &appErr) && appErr.Code == code
+}
+
+// sortedKeys returns the keys of a map, sorted
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
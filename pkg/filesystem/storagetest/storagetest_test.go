@@ -0,0 +1,25 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+	"github.com/anaknegeri/gokit/pkg/gokittest"
+)
+
+func TestLocalStorage(t *testing.T) {
+	RunSuite(t, func() filesystem.Storage {
+		storage, err := filesystem.NewLocalStorage(filesystem.LocalStorageConfig{
+			BasePath:          t.TempDir(),
+			CreateDirectories: true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create local storage: %v", err)
+		}
+		return storage
+	})
+}
+
+func TestMemoryStorage(t *testing.T) {
+	RunSuite(t, func() filesystem.Storage { return gokittest.NewStorage() })
+}
@@ -0,0 +1,155 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"strings"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+	"github.com/anaknegeri/gokit/pkg/tenant"
+)
+
+// ErrNoTenant is the internal error of operations made through a
+// TenantStorage without a tenant in the context
+var ErrNoTenant = fserrors.New("filesystem: no tenant in context")
+
+// TenantStorage keeps the files of each tenant under a directory named
+// after its ID, taken from the context: "reports/q1.pdf" is stored as
+// "acme/reports/q1.pdf" for the tenant acme. Paths cannot leave the
+// directory of the tenant, and operations without a tenant are refused.
+type TenantStorage struct {
+	storage Storage
+}
+
+// NewTenantStorage wraps a storage to scope it to the tenant of each
+// operation, set by the tenant middleware or tenant.WithTenant:
+//
+//	storage := filesystem.NewTenantStorage(s3Storage)
+//	app.Use(tenant.Middleware())
+func NewTenantStorage(storage Storage) *TenantStorage {
+	return &TenantStorage{storage: storage}
+}
+
+// Upload saves a file to the storage of the tenant
+func (s *TenantStorage) Upload(ctx context.Context, file *multipart.FileHeader, filePath string) (*FileInfo, error) {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.Upload(ctx, file, scoped)
+}
+
+// Get retrieves a file of the tenant
+func (s *TenantStorage) Get(ctx context.Context, filePath string) (io.ReadCloser, *FileInfo, error) {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.storage.Get(ctx, scoped)
+}
+
+// Delete removes a file of the tenant
+func (s *TenantStorage) Delete(ctx context.Context, filePath string) error {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, scoped)
+}
+
+// Copy copies a file of the tenant
+func (s *TenantStorage) Copy(ctx context.Context, src, dst string) (*FileInfo, error) {
+	scopedSrc, err := s.scope(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	scopedDst, err := s.scope(ctx, dst)
+	if err != nil {
+		return nil, err
+	}
+	return copyFile(ctx, s.storage, scopedSrc, scopedDst)
+}
+
+// Exists checks if a file of the tenant exists
+func (s *TenantStorage) Exists(ctx context.Context, filePath string) (bool, error) {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return false, err
+	}
+	return s.storage.Exists(ctx, scoped)
+}
+
+// List returns the files of a directory of the tenant
+func (s *TenantStorage) List(ctx context.Context, filePath string) ([]FileInfo, error) {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.List(ctx, scoped)
+}
+
+// GetInfo returns information about a file of the tenant
+func (s *TenantStorage) GetInfo(ctx context.Context, filePath string) (*FileInfo, error) {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.GetInfo(ctx, scoped)
+}
+
+// ListPage returns a page of a directory listing of the tenant
+func (s *TenantStorage) ListPage(ctx context.Context, filePath string, opts ListOptions) (*ListPage, error) {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return NewProvider(s.storage).ListPage(ctx, scoped, opts)
+}
+
+// PresignUpload returns credentials to upload a file of the tenant straight
+// to the storage, when it supports it
+func (s *TenantStorage) PresignUpload(ctx context.Context, filePath string, opts DirectUploadOptions) (*DirectUpload, error) {
+	scoped, err := s.scope(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	upload, err := NewProvider(s.storage).PresignUpload(ctx, scoped, opts)
+	if err != nil {
+		return nil, err
+	}
+	upload.Path = filePath
+	return upload, nil
+}
+
+// scope returns the path of a file in the directory of the tenant of ctx
+func (s *TenantStorage) scope(ctx context.Context, filePath string) (string, error) {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		appErr := fserrors.ForbiddenError("")
+		appErr.Internal = ErrNoTenant
+		return "", appErr
+	}
+	if !tenant.ValidID(id) {
+		return "", fserrors.InvalidPathError(id, "invalid tenant")
+	}
+
+	// Storages treat "\" as a separator on every system, so it splits
+	// segments here too
+	for _, segment := range strings.FieldsFunc(filePath, isSeparator) {
+		if segment == ".." {
+			return "", fserrors.InvalidPathError(filePath, "path leaves the tenant directory")
+		}
+	}
+	key := pathutil.JoinKey(id, filePath)
+	if _, ok := pathutil.RelativeKey(id, key); !ok && key != id {
+		return "", fserrors.InvalidPathError(filePath, "path leaves the tenant directory")
+	}
+	return key, nil
+}
+
+// isSeparator reports whether a rune separates the segments of a path
+func isSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
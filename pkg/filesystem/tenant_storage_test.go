@@ -0,0 +1,208 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/tenant"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTenantTestStorage returns a tenant storage over a directory with the
+// files of the tenants acme and globex
+func newTenantTestStorage(t *testing.T) *TenantStorage {
+	t.Helper()
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		"acme/reports/q1.txt":   "acme q1",
+		"globex/reports/q1.txt": "globex q1",
+		"globex/secret.txt":     "globex secret",
+		"root.txt":              "root",
+	} {
+		filePath := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	return NewTenantStorage(storage)
+}
+
+func TestTenantStorageScope(t *testing.T) {
+	storage := NewTenantStorage(nil)
+	acme := tenant.WithTenant(context.Background(), "acme")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		filePath string
+		want     string
+		code     string // "" when the path is scoped
+	}{
+		{"relative", acme, "reports/q1.txt", "acme/reports/q1.txt", ""},
+		{"root", acme, "", "acme", ""},
+		{"dot", acme, ".", "acme", ""},
+		{"absolute", acme, "/globex/secret.txt", "acme/globex/secret.txt", ""},
+		{"double slash", acme, "//globex/secret.txt", "acme/globex/secret.txt", ""},
+		{"dot segments", acme, "./reports/./q1.txt", "acme/reports/q1.txt", ""},
+		{"encoded slash", acme, "..%2fglobex%2fsecret.txt", "acme/..%2fglobex%2fsecret.txt", ""},
+		{"encoded dots", acme, "%2e%2e/globex/secret.txt", "acme/%2e%2e/globex/secret.txt", ""},
+		{"parent", acme, "../globex/secret.txt", "", fserrors.ErrCodeInvalidPath},
+		{"parent after a directory", acme, "reports/../../globex/secret.txt", "", fserrors.ErrCodeInvalidPath},
+		{"parent inside the tenant", acme, "reports/../q1.txt", "", fserrors.ErrCodeInvalidPath},
+		{"absolute parent", acme, "/../globex/secret.txt", "", fserrors.ErrCodeInvalidPath},
+		{"parent only", acme, "..", "", fserrors.ErrCodeInvalidPath},
+		{"backslashes", acme, `reports\q1.txt`, "acme/reports/q1.txt", ""},
+		{"backslash parent", acme, `..\globex\secret.txt`, "", fserrors.ErrCodeInvalidPath},
+		{"mixed separators", acme, `a/..\..\globex/secret.txt`, "", fserrors.ErrCodeInvalidPath},
+		{"absolute backslash parent", acme, `\..\globex\secret.txt`, "", fserrors.ErrCodeInvalidPath},
+		{"no tenant", context.Background(), "reports/q1.txt", "", fserrors.ErrCodeForbidden},
+		{"tenant with a parent", tenant.WithTenant(context.Background(), "../globex"), "secret.txt", "", fserrors.ErrCodeInvalidPath},
+		{"tenant with a slash", tenant.WithTenant(context.Background(), "acme/../globex"), "secret.txt", "", fserrors.ErrCodeInvalidPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storage.scope(tt.ctx, tt.filePath)
+			if tt.code != "" {
+				// Invalid paths are sent as BAD_REQUEST but match ErrInvalidPath
+				matched := appErrorCode(err) == tt.code
+				if tt.code == fserrors.ErrCodeInvalidPath {
+					matched = fserrors.Is(err, fserrors.ErrInvalidPath)
+				}
+				if !matched {
+					t.Errorf("Expected %s, got %q and %v", tt.code, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %q, got %q and %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestTenantStorageIsolation(t *testing.T) {
+	storage := newTenantTestStorage(t)
+	acme := tenant.WithTenant(context.Background(), "acme")
+
+	// Files of the tenant are read from its directory
+	reader, _, err := storage.Get(acme, "reports/q1.txt")
+	if err != nil {
+		t.Fatalf("Failed to get the file of the tenant: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "acme q1" {
+		t.Errorf("Expected the file of acme, got %q", data)
+	}
+
+	// Files of other tenants and outside the tenants cannot be reached
+	for _, filePath := range []string{
+		"../globex/secret.txt",
+		"/globex/secret.txt",
+		"%2e%2e/globex/secret.txt",
+		"..%2fglobex%2fsecret.txt",
+		"../root.txt",
+		"/root.txt",
+		`..\globex\secret.txt`,
+		`reports/..\..\globex\secret.txt`,
+		`..\root.txt`,
+	} {
+		if _, _, err := storage.Get(acme, filePath); err == nil {
+			t.Errorf("Expected %s not to be read", filePath)
+		}
+		if _, err := storage.GetInfo(acme, filePath); err == nil {
+			t.Errorf("Expected %s not to be stat'ed", filePath)
+		}
+		if exists, _ := storage.Exists(acme, filePath); exists {
+			t.Errorf("Expected %s not to exist for acme", filePath)
+		}
+	}
+	if _, err := storage.Copy(acme, "../globex/secret.txt", "stolen.txt"); err == nil {
+		t.Error("Expected a copy from another tenant to be refused")
+	}
+	if err := storage.Delete(acme, "../globex/secret.txt"); err == nil {
+		t.Error("Expected a delete in another tenant to be refused")
+	}
+	if exists, _ := storage.Exists(tenant.WithTenant(context.Background(), "globex"), "secret.txt"); !exists {
+		t.Error("Expected the file of globex to be kept")
+	}
+
+	// Listings and stats only see the files of the tenant
+	for _, dir := range []string{"", "/", "."} {
+		files, err := storage.List(acme, dir)
+		if err != nil {
+			t.Fatalf("Failed to list %q: %v", dir, err)
+		}
+		if len(files) != 1 || files[0].Name != "reports" {
+			t.Errorf("Expected only the reports directory of acme in %q, got %v", dir, files)
+		}
+	}
+	if _, err := storage.List(acme, ".."); err == nil {
+		t.Error("Expected listing the parent directory to be refused")
+	}
+	report, err := NewProvider(storage).UsageReport(acme, "")
+	if err != nil {
+		t.Fatalf("Failed to report usage: %v", err)
+	}
+	if report.Count != 1 || report.Bytes != int64(len("acme q1")) {
+		t.Errorf("Expected the usage of acme only, got %d files of %d bytes", report.Count, report.Bytes)
+	}
+	info, err := storage.GetInfo(acme, "reports/q1.txt")
+	if err != nil || info.Size != int64(len("acme q1")) {
+		t.Errorf("Expected the info of the file of acme, got %v and %v", info, err)
+	}
+}
+
+func TestTenantStorageHandler(t *testing.T) {
+	storage := newTenantTestStorage(t)
+	app := fiber.New()
+	app.Use(tenant.Middleware(tenant.Config{TrustHeader: true}))
+	app.Get("/files/*", GetFileHandler(UploadHandlerConfig{
+		Provider:    NewProvider(storage),
+		TimeoutSecs: 5,
+	}))
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/files/reports/q1.txt", http.StatusOK},
+		{"/files/../globex/secret.txt", http.StatusNotFound},
+		{"/files/..%2fglobex%2fsecret.txt", http.StatusNotFound},
+		{"/files/%2e%2e%2fglobex%2fsecret.txt", http.StatusNotFound},
+		{"/files/%2fglobex%2fsecret.txt", http.StatusNotFound},
+		{"/files/..%5cglobex%5csecret.txt", http.StatusNotFound},
+		{"/files/reports/..%5c..%5cglobex%5csecret.txt", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set(tenant.DefaultHeader, "acme")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, resp.StatusCode, body)
+			}
+			if string(body) == "globex secret" {
+				t.Error("Expected the file of globex not to be sent")
+			}
+		})
+	}
+}
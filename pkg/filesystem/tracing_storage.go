@@ -0,0 +1,103 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+
+	"github.com/anaknegeri/gokit/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingStorage starts a span for each operation made through it, such as
+// "storage.upload", as a child of the span in the context
+type TracingStorage struct {
+	storage Storage
+	name    string
+}
+
+// NewTracingStorage wraps a storage to trace its operations, labelled with a
+// name such as "s3"
+func NewTracingStorage(storage Storage, name string) *TracingStorage {
+	return &TracingStorage{storage: storage, name: name}
+}
+
+// Upload saves a file to the storage
+func (s *TracingStorage) Upload(ctx context.Context, file *multipart.FileHeader, filePath string) (*FileInfo, error) {
+	ctx, span := s.start(ctx, "upload", filePath)
+	span.SetAttributes(attribute.Int64("storage.size", file.Size))
+	info, err := s.storage.Upload(ctx, file, filePath)
+	tracing.End(span, err)
+	return info, err
+}
+
+// Get retrieves a file from the storage. The span ends when the file is
+// opened, not read.
+func (s *TracingStorage) Get(ctx context.Context, filePath string) (io.ReadCloser, *FileInfo, error) {
+	ctx, span := s.start(ctx, "get", filePath)
+	reader, info, err := s.storage.Get(ctx, filePath)
+	tracing.End(span, err)
+	return reader, info, err
+}
+
+// Delete removes a file from the storage
+func (s *TracingStorage) Delete(ctx context.Context, filePath string) error {
+	ctx, span := s.start(ctx, "delete", filePath)
+	err := s.storage.Delete(ctx, filePath)
+	tracing.End(span, err)
+	return err
+}
+
+// Copy copies a file within the storage
+func (s *TracingStorage) Copy(ctx context.Context, src, dst string) (*FileInfo, error) {
+	ctx, span := s.start(ctx, "copy", src)
+	span.SetAttributes(attribute.String("storage.destination", dst))
+	info, err := copyFile(ctx, s.storage, src, dst)
+	tracing.End(span, err)
+	return info, err
+}
+
+// Exists checks if a file exists
+func (s *TracingStorage) Exists(ctx context.Context, filePath string) (bool, error) {
+	ctx, span := s.start(ctx, "exists", filePath)
+	exists, err := s.storage.Exists(ctx, filePath)
+	tracing.End(span, err)
+	return exists, err
+}
+
+// List returns a list of files from a directory
+func (s *TracingStorage) List(ctx context.Context, filePath string) ([]FileInfo, error) {
+	ctx, span := s.start(ctx, "list", filePath)
+	files, err := s.storage.List(ctx, filePath)
+	tracing.End(span, err)
+	return files, err
+}
+
+// GetInfo returns information about a file without fetching its contents
+func (s *TracingStorage) GetInfo(ctx context.Context, filePath string) (*FileInfo, error) {
+	ctx, span := s.start(ctx, "get_info", filePath)
+	info, err := s.storage.GetInfo(ctx, filePath)
+	tracing.End(span, err)
+	return info, err
+}
+
+// ListPage returns a page of a directory listing when the storage supports it
+func (s *TracingStorage) ListPage(ctx context.Context, filePath string, opts ListOptions) (*ListPage, error) {
+	ctx, span := s.start(ctx, "list", filePath)
+	page, err := NewProvider(s.storage).ListPage(ctx, filePath, opts)
+	tracing.End(span, err)
+	return page, err
+}
+
+// start starts the span of an operation on a path
+func (s *TracingStorage) start(ctx context.Context, operation, filePath string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.name", s.name),
+			attribute.String("storage.operation", operation),
+			attribute.String("storage.path", filePath),
+		),
+	)
+}
@@ -0,0 +1,56 @@
+package filesystem
+
+import (
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+)
+
+// UploadRequest is the JSON body of an upload, for clients such as mobile
+// SDKs and webhooks that send files without a multipart form. UploadHandler
+// accepts it for requests of the JSON content type and checks the file
+// like an uploaded one.
+type UploadRequest struct {
+	// Filename is the original name of the file, e.g. "report.pdf"
+	Filename string `json:"filename"`
+
+	// ContentBase64 is the file in standard base64, or a data URL such as
+	// "data:image/png;base64,iVBORw0..."
+	ContentBase64 string `json:"content_base64"`
+
+	// Path is the directory of the file under the base path, like the
+	// path form field
+	Path string `json:"path"`
+
+	// Policy is the upload policy, like the policy form field
+	Policy string `json:"policy"`
+}
+
+// file decodes the content of the request into an uploaded file
+func (r UploadRequest) file() (*multipart.FileHeader, *fserrors.AppError) {
+	if strings.TrimSpace(r.Filename) == "" {
+		return nil, fserrors.NewError(http.StatusBadRequest, "Filename is required")
+	}
+
+	content := r.ContentBase64
+	if strings.HasPrefix(content, "data:") {
+		meta, data, ok := strings.Cut(content, ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil, fserrors.NewError(http.StatusBadRequest, "Content must be a base64 data URL")
+		}
+		content = data
+	}
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, fserrors.NewError(http.StatusBadRequest, "Content is not valid base64")
+	}
+
+	file, err := newFileHeader(r.Filename, data)
+	if err != nil {
+		return nil, fserrors.WrapError(err, http.StatusInternalServerError, "Failed to read uploaded file")
+	}
+	return file, nil
+}
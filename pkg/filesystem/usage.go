@@ -0,0 +1,217 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+	"github.com/anaknegeri/gokit/pkg/pathutil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// UsageGroup is how a usage report groups the files
+type UsageGroup string
+
+const (
+	// UsageByExtension groups the files by lowercase extension, e.g. ".pdf",
+	// with "" for files without one
+	UsageByExtension UsageGroup = "extension"
+
+	// UsageByFolder groups the files by the top-level folder under the
+	// prefix, with "" for the files right in it
+	UsageByFolder UsageGroup = "folder"
+
+	// UsageByAge groups the files by the time since they were last
+	// modified, see UsageAgeBuckets
+	UsageByAge UsageGroup = "age"
+)
+
+// UsageGroups are the groups of a usage report when none is given
+var UsageGroups = []UsageGroup{UsageByExtension, UsageByFolder, UsageByAge}
+
+// UsageAgeBucket is a range of file ages of a usage report, up to Max
+type UsageAgeBucket struct {
+	Key string
+	Max time.Duration
+}
+
+// UsageAgeBuckets are the age buckets of a usage report, youngest first.
+// Files older than the last are counted under "older".
+var UsageAgeBuckets = []UsageAgeBucket{
+	{Key: "1d", Max: 24 * time.Hour},
+	{Key: "7d", Max: 7 * 24 * time.Hour},
+	{Key: "30d", Max: 30 * 24 * time.Hour},
+	{Key: "90d", Max: 90 * 24 * time.Hour},
+	{Key: "365d", Max: 365 * 24 * time.Hour},
+}
+
+// UsageBucket counts the files of a group of a usage report
+type UsageBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+// UsageReport is the storage used by the files under a prefix
+type UsageReport struct {
+	Prefix string `json:"prefix"`
+	Count  int    `json:"count"`
+	Bytes  int64  `json:"bytes"`
+
+	// Groups holds the buckets of each group asked for, the largest first,
+	// or the youngest first by age
+	Groups map[UsageGroup][]UsageBucket `json:"groups"`
+}
+
+// UsageReport counts the files under a prefix and their bytes, grouped by
+// extension, top-level folder, or age, every group when none is given:
+//
+//	report, err := fs.Provider.UsageReport(ctx, "tenants/acme", filesystem.UsageByFolder)
+//	for _, bucket := range report.Groups[filesystem.UsageByFolder] {
+//		fmt.Println(bucket.Key, bucket.Count, bucket.Bytes)
+//	}
+//
+// Every file is listed, so reports of large storages are best run from
+// scheduled tasks or the command line.
+func (p *Provider) UsageReport(ctx context.Context, prefix string, groupBy ...UsageGroup) (*UsageReport, error) {
+	if len(groupBy) == 0 {
+		groupBy = UsageGroups
+	}
+	for _, group := range groupBy {
+		switch group {
+		case UsageByExtension, UsageByFolder, UsageByAge:
+		default:
+			return nil, fserrors.NewError(http.StatusBadRequest, fmt.Sprintf("Unknown usage group: %s", group))
+		}
+	}
+
+	dir := cleanPath(prefix)
+	report := &UsageReport{Prefix: dir, Groups: make(map[UsageGroup][]UsageBucket)}
+	buckets := make(map[UsageGroup]map[string]*UsageBucket)
+	for _, group := range groupBy {
+		buckets[group] = make(map[string]*UsageBucket)
+	}
+	now := time.Now()
+
+	err := p.Walk(ctx, dir, func(filePath string, info FileInfo) error {
+		report.Count++
+		report.Bytes += info.Size
+		for group, keys := range buckets {
+			key := usageKey(group, dir, filePath, now.Sub(info.LastModified))
+			bucket, ok := keys[key]
+			if !ok {
+				bucket = &UsageBucket{Key: key}
+				keys[key] = bucket
+			}
+			bucket.Count++
+			bucket.Bytes += info.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for group, keys := range buckets {
+		list := make([]UsageBucket, 0, len(keys))
+		for _, bucket := range keys {
+			list = append(list, *bucket)
+		}
+		if group == UsageByAge {
+			sort.Slice(list, func(i, j int) bool { return ageOrder(list[i].Key) < ageOrder(list[j].Key) })
+		} else {
+			sort.Slice(list, func(i, j int) bool {
+				if list[i].Bytes != list[j].Bytes {
+					return list[i].Bytes > list[j].Bytes
+				}
+				return list[i].Key < list[j].Key
+			})
+		}
+		report.Groups[group] = list
+	}
+	return report, nil
+}
+
+// usageKey returns the bucket of a file in a group of a usage report
+func usageKey(group UsageGroup, dir, filePath string, age time.Duration) string {
+	switch group {
+	case UsageByExtension:
+		return strings.ToLower(path.Ext(filePath))
+	case UsageByFolder:
+		relative, _ := pathutil.RelativeKey(dir, filePath)
+		if folder, _, ok := strings.Cut(relative, "/"); ok {
+			return folder
+		}
+		return ""
+	default:
+		for _, bucket := range UsageAgeBuckets {
+			if age < bucket.Max {
+				return bucket.Key
+			}
+		}
+		return "older"
+	}
+}
+
+// ageOrder returns the position of an age bucket in UsageAgeBuckets
+func ageOrder(key string) int {
+	for i, bucket := range UsageAgeBuckets {
+		if bucket.Key == key {
+			return i
+		}
+	}
+	return len(UsageAgeBuckets)
+}
+
+// UsageHandler returns a Fiber handler reporting the storage used under the
+// prefix query parameter of the base path, see Provider.UsageReport. groupBy
+// selects the groups, comma separated:
+//
+//	GET /files/usage?prefix=documents&groupBy=extension,age
+//	{"success":true,"data":{"prefix":"documents","count":120,"bytes":52428800,"groups":{"extension":[...],"age":[...]}}}
+func UsageHandler(config UploadHandlerConfig) fiber.Handler {
+	if config.Provider == nil {
+		panic("filesystem provider is required")
+	}
+
+	return func(c *fiber.Ctx) error {
+		// Set timeout context
+		ctx, cancel := context.WithTimeout(c.Context(), time.Duration(config.TimeoutSecs)*time.Second)
+		defer cancel()
+
+		var groupBy []UsageGroup
+		for _, group := range strings.Split(c.Query("groupBy"), ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				groupBy = append(groupBy, UsageGroup(group))
+			}
+		}
+
+		prefix := NormalizeKey(c.Query("prefix"))
+		report, err := config.Provider.UsageReport(ctx, JoinKey(config.BasePath, prefix), groupBy...)
+		if err != nil {
+			var appErr *fserrors.AppError
+			if fserrors.As(err, &appErr) {
+				return c.Status(appErr.HTTPCode).JSON(fserrors.FormatErrorResponse(appErr))
+			}
+
+			return c.Status(fiber.StatusInternalServerError).JSON(fserrors.FormatErrorResponse(
+				fserrors.WrapError(
+					err,
+					http.StatusInternalServerError,
+					"Failed to report storage usage",
+				),
+			))
+		}
+		report.Prefix = prefix
+
+		return c.Status(fiber.StatusOK).JSON(Response{
+			Success: true,
+			Data:    report,
+		})
+	}
+}
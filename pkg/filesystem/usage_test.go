@@ -0,0 +1,94 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUsageHandler(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"files/docs/a.pdf":        "aaaa",
+		"files/docs/old/b.PDF":    "bb",
+		"files/docs/img/c.png":    "cccccc",
+		"files/docs/readme":       "r",
+		"files/other/skipped.txt": "skipped",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(tempDir, "files/docs/old/b.PDF"), old, old); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	storage, err := NewLocalStorage(LocalStorageConfig{BasePath: tempDir})
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	app := fiber.New()
+	app.Get("/usage", UsageHandler(UploadHandlerConfig{
+		Provider:    NewProvider(storage),
+		BasePath:    "files",
+		TimeoutSecs: 5,
+	}))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/usage?prefix=docs", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Data UsageReport `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	report := body.Data
+	if report.Prefix != "docs" || report.Count != 4 || report.Bytes != 13 {
+		t.Fatalf("Expected 4 files of 13 bytes under docs, got %+v", report)
+	}
+	expected := map[UsageGroup][]UsageBucket{
+		UsageByExtension: {{Key: ".pdf", Count: 2, Bytes: 6}, {Key: ".png", Count: 1, Bytes: 6}, {Key: "", Count: 1, Bytes: 1}},
+		UsageByFolder:    {{Key: "img", Count: 1, Bytes: 6}, {Key: "", Count: 2, Bytes: 5}, {Key: "old", Count: 1, Bytes: 2}},
+		UsageByAge:       {{Key: "1d", Count: 3, Bytes: 11}, {Key: "90d", Count: 1, Bytes: 2}},
+	}
+	for group, buckets := range expected {
+		got := report.Groups[group]
+		if len(got) != len(buckets) {
+			t.Errorf("Expected %d %s buckets, got %+v", len(buckets), group, got)
+			continue
+		}
+		for i := range buckets {
+			if got[i] != buckets[i] {
+				t.Errorf("Expected %s bucket %d to be %+v, got %+v", group, i, buckets[i], got[i])
+			}
+		}
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/usage?groupBy=size", nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown group, got %d", resp.StatusCode)
+	}
+}
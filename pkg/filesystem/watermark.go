@@ -0,0 +1,302 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// WatermarkPosition is where a watermark is placed on an image or page
+type WatermarkPosition string
+
+// Watermark positions
+const (
+	WatermarkCenter      WatermarkPosition = "center"
+	WatermarkTopLeft     WatermarkPosition = "top-left"
+	WatermarkTopRight    WatermarkPosition = "top-right"
+	WatermarkBottomLeft  WatermarkPosition = "bottom-left"
+	WatermarkBottomRight WatermarkPosition = "bottom-right"
+)
+
+// pdfcpuPositions are the anchors of the positions in pdfcpu descriptions
+var pdfcpuPositions = map[WatermarkPosition]string{
+	WatermarkCenter:      "c",
+	WatermarkTopLeft:     "tl",
+	WatermarkTopRight:    "tr",
+	WatermarkBottomLeft:  "bl",
+	WatermarkBottomRight: "br",
+}
+
+// WatermarkConfig configures a Watermarker
+type WatermarkConfig struct {
+	// Text is drawn as the watermark, unless Image is set
+	Text string
+
+	// Image is a PNG or JPEG image drawn as the watermark, e.g. a logo with
+	// a transparent background
+	Image []byte
+
+	// Position defaults to WatermarkCenter
+	Position WatermarkPosition
+
+	// Opacity is between 0 and 1, defaulting to 0.3
+	Opacity float64
+
+	// Width is the width of the watermark relative to the width of the
+	// image or page, defaulting to 0.3
+	Width float64
+
+	// PDFCommand stamps PDFs, defaulting to pdfcpu, which must be installed:
+	//
+	//	pdfcpu watermark add -mode {mode} -- {mark} {options} {input} {output}
+	//
+	// {mode} is "text" or "image", {mark} the text or the path of the
+	// image, and {options} the pdfcpu description of the position, opacity,
+	// and width.
+	PDFCommand []string
+
+	// Timeout limits the PDF command, defaulting to two minutes
+	Timeout time.Duration
+}
+
+// Watermarker draws a watermark on PNG and JPEG images, and stamps it on
+// the pages of PDFs with an external command
+type Watermarker struct {
+	config  WatermarkConfig
+	overlay image.Image
+}
+
+// NewWatermarker creates a watermarker, checking its text or image
+func NewWatermarker(config WatermarkConfig) (*Watermarker, error) {
+	if config.Text == "" && len(config.Image) == 0 {
+		return nil, errors.New("filesystem: watermark needs a text or an image")
+	}
+	if config.Position == "" {
+		config.Position = WatermarkCenter
+	}
+	if _, ok := pdfcpuPositions[config.Position]; !ok {
+		return nil, fmt.Errorf("filesystem: unknown watermark position %q", config.Position)
+	}
+	if config.Opacity <= 0 || config.Opacity > 1 {
+		config.Opacity = 0.3
+	}
+	if config.Width <= 0 || config.Width > 1 {
+		config.Width = 0.3
+	}
+	if len(config.PDFCommand) == 0 {
+		config.PDFCommand = []string{"pdfcpu", "watermark", "add", "-mode", "{mode}", "--", "{mark}", "{options}", "{input}", "{output}"}
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+
+	var overlay image.Image
+	if len(config.Image) > 0 {
+		var err error
+		if overlay, _, err = image.Decode(bytes.NewReader(config.Image)); err != nil {
+			return nil, fmt.Errorf("filesystem: decode watermark image: %w", err)
+		}
+	} else {
+		overlay = textOverlay(config.Text)
+	}
+	return &Watermarker{config: config, overlay: overlay}, nil
+}
+
+// Supports reports whether a file is a PNG or JPEG image or a PDF
+func (w *Watermarker) Supports(filename, contentType string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png", ".jpg", ".jpeg", ".pdf":
+		return true
+	}
+	return contentType == "image/png" || contentType == "image/jpeg" || contentType == "application/pdf"
+}
+
+// Watermark returns a file with the watermark, in the format of the file
+func (w *Watermarker) Watermark(ctx context.Context, src io.Reader, filename string) ([]byte, error) {
+	if strings.ToLower(filepath.Ext(filename)) == ".pdf" {
+		return w.stampPDF(ctx, src)
+	}
+
+	img, format, err := image.Decode(src)
+	if err != nil {
+		return nil, fmt.Errorf("filesystem: decode %s: %w", filename, err)
+	}
+	marked := w.Draw(img)
+
+	out := &bytes.Buffer{}
+	if format == "jpeg" {
+		err = jpeg.Encode(out, marked, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(out, marked)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Draw returns a copy of an image with the watermark drawn on it
+func (w *Watermarker) Draw(img image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+
+	// Scale the watermark to its share of the width of the image
+	src := w.overlay.Bounds()
+	width := max(1, int(float64(bounds.Dx())*w.config.Width))
+	height := max(1, src.Dy()*width/max(1, src.Dx()))
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), w.overlay, src, xdraw.Src, nil)
+
+	margin := min(bounds.Dx(), bounds.Dy()) / 50
+	x := bounds.Min.X + (bounds.Dx()-width)/2
+	y := bounds.Min.Y + (bounds.Dy()-height)/2
+	switch w.config.Position {
+	case WatermarkTopLeft:
+		x, y = bounds.Min.X+margin, bounds.Min.Y+margin
+	case WatermarkTopRight:
+		x, y = bounds.Max.X-width-margin, bounds.Min.Y+margin
+	case WatermarkBottomLeft:
+		x, y = bounds.Min.X+margin, bounds.Max.Y-height-margin
+	case WatermarkBottomRight:
+		x, y = bounds.Max.X-width-margin, bounds.Max.Y-height-margin
+	}
+
+	mask := image.NewUniform(color.Alpha{A: uint8(w.config.Opacity * 255)})
+	draw.DrawMask(dst, image.Rect(x, y, x+width, y+height), scaled, image.Point{}, mask, image.Point{}, draw.Over)
+	return dst
+}
+
+// textOverlay renders a text in white with a dark outline, so it shows on
+// light and dark images
+func textOverlay(text string) image.Image {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, text).Ceil() + 2
+	height := face.Metrics().Height.Ceil() + 2
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	drawer := &font.Drawer{Dst: img, Face: face}
+	baseline := face.Metrics().Ascent.Ceil() + 1
+	drawer.Src = image.NewUniform(color.RGBA{A: 0xFF})
+	for _, offset := range []image.Point{{0, 1}, {2, 1}, {1, 0}, {1, 2}} {
+		drawer.Dot = fixed.P(offset.X, baseline+offset.Y-1)
+		drawer.DrawString(text)
+	}
+	drawer.Src = image.White
+	drawer.Dot = fixed.P(1, baseline)
+	drawer.DrawString(text)
+	return img
+}
+
+// stampPDF runs the PDF command on a temporary copy of a PDF
+func (w *Watermarker) stampPDF(ctx context.Context, src io.Reader) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.config.Timeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "gokit-watermark-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	output := filepath.Join(dir, "output.pdf")
+	if err := writeFile(input, src); err != nil {
+		return nil, err
+	}
+
+	mode, mark := "text", w.config.Text
+	if len(w.config.Image) > 0 {
+		ext := ".png"
+		if http.DetectContentType(w.config.Image) == "image/jpeg" {
+			ext = ".jpg"
+		}
+		mode, mark = "image", filepath.Join(dir, "watermark"+ext)
+		if err := os.WriteFile(mark, w.config.Image, 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	options := fmt.Sprintf("pos:%s, op:%s, sc:%s rel",
+		pdfcpuPositions[w.config.Position],
+		strconv.FormatFloat(w.config.Opacity, 'f', -1, 64),
+		strconv.FormatFloat(w.config.Width, 'f', -1, 64))
+	if w.config.Position != WatermarkCenter {
+		// Only the centered watermark runs diagonally
+		options += ", rot:0"
+	}
+
+	replacer := strings.NewReplacer("{input}", input, "{output}", output, "{mode}", mode, "{mark}", mark, "{options}", options)
+	args := make([]string, len(w.config.PDFCommand))
+	for i, arg := range w.config.PDFCommand {
+		args[i] = replacer.Replace(arg)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("filesystem: %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return os.ReadFile(output)
+}
+
+// WatermarkProcessor is a processor replacing images and PDFs with their
+// watermarked copies, recorded in the "watermark" metadata. Re-encoding
+// drops the EXIF data of photos, so run an ExifProcessor before it. Its
+// name is "watermark".
+type WatermarkProcessor struct {
+	watermarker *Watermarker
+}
+
+// NewWatermarkProcessor creates a processor watermarking files
+func NewWatermarkProcessor(watermarker *Watermarker) *WatermarkProcessor {
+	return &WatermarkProcessor{watermarker: watermarker}
+}
+
+// Name returns "watermark"
+func (p *WatermarkProcessor) Name() string {
+	return "watermark"
+}
+
+// Process watermarks a file and replaces it
+func (p *WatermarkProcessor) Process(ctx context.Context, file *ProcessFile) error {
+	if !p.watermarker.Supports(file.Path, file.Info.ContentType) {
+		return nil
+	}
+
+	src, err := file.Open(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := p.watermarker.Watermark(ctx, src, file.Path)
+	src.Close()
+	if err != nil {
+		return err
+	}
+	if err := file.Replace(ctx, data); err != nil {
+		return err
+	}
+	file.Metadata["watermark"] = "true"
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package flags evaluates feature flags from static, environment, and file
+// providers, with percentage rollouts and targeting of users and tenants
+package flags
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// Flag configures a feature. A disabled flag is off for everyone. An enabled
+// flag is on for everyone unless it lists users, tenants, or a rollout, in
+// which case it is on for the listed users and tenants and the rollout
+// percentage of the others.
+type Flag struct {
+	Enabled bool `json:"enabled"`
+
+	// Rollout is the percentage of users, from 0 to 100, the flag is on for.
+	// Users are picked by a hash of their ID, so each keeps the same result
+	// and raising the percentage only adds users.
+	Rollout float64 `json:"rollout,omitempty"`
+
+	// RolloutBy is "user" (default) to roll out by user, or "tenant" to turn
+	// the flag on for whole tenants
+	RolloutBy string `json:"rolloutBy,omitempty"`
+
+	// Users and Tenants are IDs the flag is always on for
+	Users   []string `json:"users,omitempty"`
+	Tenants []string `json:"tenants,omitempty"`
+}
+
+// Provider looks up flags
+type Provider interface {
+	// Flag returns the flag of a name, and false when the provider does not
+	// define it
+	Flag(ctx context.Context, name string) (Flag, bool, error)
+}
+
+// Evaluator decides whether flags are on, looking them up in its providers
+// in order. Flags no provider defines are off.
+type Evaluator struct {
+	providers []Provider
+}
+
+// New creates an evaluator. Earlier providers take precedence, so an
+// environment provider first overrides the flags of a file:
+//
+//	flags.New(flags.NewEnv(), flags.NewFile("flags.json"))
+func New(providers ...Provider) *Evaluator {
+	return &Evaluator{providers: providers}
+}
+
+// Lookup returns the flag of a name from the first provider defining it
+func (e *Evaluator) Lookup(ctx context.Context, name string) (Flag, bool, error) {
+	for _, provider := range e.providers {
+		flag, ok, err := provider.Flag(ctx, name)
+		if err != nil {
+			return Flag{}, false, err
+		}
+		if ok {
+			return flag, true, nil
+		}
+	}
+	return Flag{}, false, nil
+}
+
+// Enabled reports whether a flag is on for the target of ctx, see
+// TargetFromContext. Flags that fail to load are off.
+func (e *Evaluator) Enabled(ctx context.Context, name string) bool {
+	flag, ok, err := e.Lookup(ctx, name)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to load flag %q: %v", name, err)
+		return false
+	}
+	return ok && flag.EnabledFor(name, TargetFromContext(ctx))
+}
+
+// EnabledFor reports whether the flag of a name is on for a target
+func (f Flag) EnabledFor(name string, target Target) bool {
+	if !f.Enabled {
+		return false
+	}
+	if len(f.Users) == 0 && len(f.Tenants) == 0 && f.Rollout <= 0 {
+		return true
+	}
+	if target.UserID != "" && contains(f.Users, target.UserID) {
+		return true
+	}
+	if target.TenantID != "" && contains(f.Tenants, target.TenantID) {
+		return true
+	}
+	if f.Rollout >= 100 {
+		return true
+	}
+
+	key := target.UserID
+	if f.RolloutBy == "tenant" {
+		key = target.TenantID
+	}
+	if f.Rollout <= 0 || key == "" {
+		return false
+	}
+	return bucket(name, key) < f.Rollout*100
+}
+
+// bucket places a key in one of 10000 buckets, differently for each flag so
+// the same users are not always the first to get new features
+func bucket(name, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{':'})
+	h.Write([]byte(key))
+	return float64(h.Sum32() % 10000)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultEvaluator = New(NewEnv())
+)
+
+// Default returns the default evaluator, which reads the FLAG_* environment
+// variables until SetDefault replaces it
+func Default() *Evaluator {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultEvaluator
+}
+
+// SetDefault replaces the default evaluator
+func SetDefault(e *Evaluator) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultEvaluator = e
+}
+
+// EnabledCtx reports whether a flag of the default evaluator is on for the
+// target of ctx
+func EnabledCtx(ctx context.Context, name string) bool {
+	return Default().Enabled(ctx, name)
+}
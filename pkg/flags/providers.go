@@ -0,0 +1,147 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// Static is a provider of flags defined in code
+type Static map[string]Flag
+
+// Flag returns the flag of a name
+func (s Static) Flag(_ context.Context, name string) (Flag, bool, error) {
+	flag, ok := s[name]
+	return flag, ok, nil
+}
+
+// Env reads flags from environment variables named after them, so
+// "new-upload-flow" is FLAG_NEW_UPLOAD_FLOW. Values are "true" or "false",
+// or a rollout percentage such as "25%".
+type Env struct {
+	prefix string
+}
+
+// NewEnv creates an environment provider for variables with a prefix,
+// defaulting to "FLAG_"
+func NewEnv(prefix ...string) *Env {
+	p := "FLAG_"
+	if len(prefix) > 0 {
+		p = prefix[0]
+	}
+	return &Env{prefix: p}
+}
+
+// Flag returns the flag of a name when its variable is set
+func (e *Env) Flag(_ context.Context, name string) (Flag, bool, error) {
+	variable := e.prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	value, ok := os.LookupEnv(variable)
+	if !ok || value == "" {
+		return Flag{}, false, nil
+	}
+
+	if percent, isPercent := strings.CutSuffix(value, "%"); isPercent {
+		rollout, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil || rollout < 0 || rollout > 100 {
+			return Flag{}, false, fmt.Errorf("flags: invalid rollout %q in %s", value, variable)
+		}
+		return Flag{Enabled: rollout > 0, Rollout: rollout}, true, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return Flag{}, false, fmt.Errorf("flags: invalid %s %q, expected true, false, or a percentage", variable, value)
+	}
+	return Flag{Enabled: enabled}, true, nil
+}
+
+// File reads flags from a JSON file mapping names to flags, reloading it when
+// it changes:
+//
+//	{
+//		"new-upload-flow": {"enabled": true, "rollout": 25, "tenants": ["acme"]},
+//		"dark-mode": {"enabled": true}
+//	}
+type File struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	flags   map[string]Flag
+	modTime time.Time
+	checked time.Time
+}
+
+// NewFile creates a provider of the flags in a JSON file, checking it for
+// changes at most once per interval, defaulting to 5 seconds. A missing
+// file defines no flags.
+func NewFile(path string, interval ...time.Duration) *File {
+	f := &File{path: path, interval: 5 * time.Second}
+	if len(interval) > 0 && interval[0] > 0 {
+		f.interval = interval[0]
+	}
+	return f
+}
+
+// Flag returns the flag of a name
+func (f *File) Flag(_ context.Context, name string) (Flag, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.flags == nil || time.Since(f.checked) >= f.interval {
+		if err := f.load(); err != nil {
+			if f.flags == nil {
+				return Flag{}, false, err
+			}
+			// Keep serving the flags read before until the file is fixed
+			logger.Default().Warnf("Failed to reload flags: %v", err)
+		}
+	}
+	flag, ok := f.flags[name]
+	return flag, ok, nil
+}
+
+// Reload reads the file again
+func (f *File) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.modTime = time.Time{}
+	return f.load()
+}
+
+// load reads the file when it changed since it was last read
+func (f *File) load() error {
+	f.checked = time.Now()
+	info, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
+		f.flags = map[string]Flag{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+	if f.flags != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+	var flags map[string]Flag
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return fmt.Errorf("flags: parse %s: %w", f.path, err)
+	}
+	if flags == nil {
+		flags = map[string]Flag{}
+	}
+	f.flags = flags
+	f.modTime = info.ModTime()
+	return nil
+}
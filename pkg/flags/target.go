@@ -0,0 +1,67 @@
+package flags
+
+import (
+	"context"
+
+	"github.com/anaknegeri/gokit/pkg/auth"
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/anaknegeri/gokit/pkg/tenant"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Target is who a flag is evaluated for
+type Target struct {
+	UserID   string
+	TenantID string
+}
+
+type targetKey struct{}
+
+// WithTarget returns a context evaluating flags for a target
+func WithTarget(ctx context.Context, target Target) context.Context {
+	return context.WithValue(ctx, targetKey{}, target)
+}
+
+// TargetFromContext returns the target of ctx: the one set with WithTarget,
+// or else the subject and "tenant" claim of the token verified by the auth
+// middleware, and the tenant resolved by the tenant middleware
+func TargetFromContext(ctx context.Context) Target {
+	if ctx == nil {
+		return Target{}
+	}
+	if target, ok := ctx.Value(targetKey{}).(Target); ok {
+		return target
+	}
+	if claims, ok := auth.ClaimsFromContext(ctx); ok {
+		target := Target{UserID: claims.Subject}
+		target.TenantID, _ = claims.Extra["tenant"].(string)
+		if target.TenantID == "" {
+			target.TenantID, _ = tenant.FromContext(ctx)
+		}
+		return target
+	}
+	id, _ := tenant.FromContext(ctx)
+	return Target{TenantID: id}
+}
+
+// Enabled reports whether a flag of the default evaluator is on for the user
+// of a request:
+//
+//	if flags.Enabled(c, "new-upload-flow") {
+//		return uploadResumable(c)
+//	}
+func Enabled(c *fiber.Ctx, name string) bool {
+	return Default().Enabled(c.UserContext(), name)
+}
+
+// Require returns a middleware responding 404 NOT_FOUND to requests a flag
+// is off for, hiding routes that are not released yet
+func Require(name string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !Enabled(c, name) {
+			return response.Error(c, errors.NotFoundError(""))
+		}
+		return c.Next()
+	}
+}
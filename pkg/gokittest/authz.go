@@ -0,0 +1,63 @@
+package gokittest
+
+import (
+	"context"
+
+	"github.com/anaknegeri/gokit/pkg/authz"
+)
+
+// Roles is an authz.Store of the roles and permissions set in the test
+type Roles struct {
+	recorder
+	permissions map[string][]string
+	users       map[string][]string
+}
+
+var _ authz.Store = (*Roles)(nil)
+
+// NewRoles creates a store of the permissions of roles, by role name
+func NewRoles(permissions map[string][]string) *Roles {
+	r := &Roles{permissions: map[string][]string{}, users: map[string][]string{}}
+	for role, perms := range permissions {
+		r.permissions[role] = append([]string(nil), perms...)
+	}
+	return r
+}
+
+// Grant sets the permissions of a role
+func (r *Roles) Grant(role string, permissions ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.permissions[role] = append([]string(nil), permissions...)
+}
+
+// Assign sets the roles of a user
+func (r *Roles) Assign(userID string, roles ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID] = append([]string(nil), roles...)
+}
+
+// RolePermissions returns the permissions of roles, by role name
+func (r *Roles) RolePermissions(_ context.Context, roles []string) ([]string, error) {
+	if err := r.record("RolePermissions", roles); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, r.permissions[role]...)
+	}
+	return permissions, nil
+}
+
+// UserRoles returns the role names of a user
+func (r *Roles) UserRoles(_ context.Context, userID string) ([]string, error) {
+	if err := r.record("UserRoles", userID); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.users[userID]...), nil
+}
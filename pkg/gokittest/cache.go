@@ -0,0 +1,79 @@
+package gokittest
+
+import (
+	"context"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+)
+
+// Cache is a cache.Cache keeping values in memory like cache.Memory, and
+// recording its calls
+type Cache struct {
+	recorder
+	memory *cache.Memory
+}
+
+var (
+	_ cache.Cache = (*Cache)(nil)
+	_ cache.Adder = (*Cache)(nil)
+)
+
+// NewCache creates an empty cache
+func NewCache() *Cache {
+	return &Cache{memory: cache.NewMemory(0)}
+}
+
+// Get returns the value of a key
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := c.record("Get", key); err != nil {
+		return nil, err
+	}
+	return c.memory.Get(ctx, key)
+}
+
+// Set stores a value
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if err := c.record("Set", key, value, ttl, tags); err != nil {
+		return err
+	}
+	return c.memory.Set(ctx, key, value, ttl, tags...)
+}
+
+// Add stores a value unless the key holds an unexpired value, reporting
+// whether it was stored
+func (c *Cache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if err := c.record("Add", key, value, ttl); err != nil {
+		return false, err
+	}
+	return c.memory.Add(ctx, key, value, ttl)
+}
+
+// Delete removes keys
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if err := c.record("Delete", keys); err != nil {
+		return err
+	}
+	return c.memory.Delete(ctx, keys...)
+}
+
+// DeleteTags removes the keys stored with any of the tags
+func (c *Cache) DeleteTags(ctx context.Context, tags ...string) error {
+	if err := c.record("DeleteTags", tags); err != nil {
+		return err
+	}
+	return c.memory.DeleteTags(ctx, tags...)
+}
+
+// GetOrSet returns the value of a key, or loads it with fn and stores it
+func (c *Cache) GetOrSet(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) ([]byte, error), tags ...string) ([]byte, error) {
+	if err := c.record("GetOrSet", key, ttl, tags); err != nil {
+		return nil, err
+	}
+	return c.memory.GetOrSet(ctx, key, ttl, fn, tags...)
+}
+
+// Len returns the number of values stored
+func (c *Cache) Len() int {
+	return c.memory.Len()
+}
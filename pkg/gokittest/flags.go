@@ -0,0 +1,54 @@
+package gokittest
+
+import (
+	"context"
+
+	"github.com/anaknegeri/gokit/pkg/flags"
+)
+
+// Flags is a flags.Provider of flags set in the test
+type Flags struct {
+	recorder
+	flags map[string]flags.Flag
+}
+
+var _ flags.Provider = (*Flags)(nil)
+
+// NewFlags creates a provider of flags, by name
+func NewFlags(defined map[string]flags.Flag) *Flags {
+	f := &Flags{flags: map[string]flags.Flag{}}
+	for name, flag := range defined {
+		f.flags[name] = flag
+	}
+	return f
+}
+
+// Set defines a flag
+func (f *Flags) Set(name string, flag flags.Flag) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags[name] = flag
+}
+
+// Enable defines a flag on for everyone, or off
+func (f *Flags) Enable(name string, enabled bool) {
+	f.Set(name, flags.Flag{Enabled: enabled})
+}
+
+// Unset removes a flag
+func (f *Flags) Unset(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.flags, name)
+}
+
+// Flag returns the flag of a name
+func (f *Flags) Flag(_ context.Context, name string) (flags.Flag, bool, error) {
+	if err := f.record("Flag", name); err != nil {
+		return flags.Flag{}, false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	flag, ok := f.flags[name]
+	return flag, ok, nil
+}
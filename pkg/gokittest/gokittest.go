@@ -0,0 +1,87 @@
+// Package gokittest provides fakes of the interfaces of gokit and helpers
+// for the tests of applications using it:
+//
+//	func TestAvatar(t *testing.T) {
+//		storage := gokittest.NewStorage()
+//		mail := gokittest.NewMailer()
+//		svc := NewAvatars(filesystem.NewProvider(storage), mail)
+//
+//		storage.Fail("Upload", errors.New("disk full"))
+//		if err := svc.Set(ctx, user, gokittest.NewFileHeader(t, "me.png", png)); err == nil {
+//			t.Fatal("expected an error")
+//		}
+//		if len(mail.Messages()) != 0 {
+//			t.Fatal("expected no mail")
+//		}
+//	}
+//
+// Fakes work in memory, record their calls, and fail on demand with Fail.
+// Job queues and event buses already have memory backends, jobs.NewMemory
+// and events.NewMemory.
+package gokittest
+
+import (
+	"sync"
+)
+
+// Call is a recorded call of a fake
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// recorder records the calls of a fake and the errors its methods fail with
+type recorder struct {
+	mu    sync.Mutex
+	calls []Call
+	errs  map[string]error
+}
+
+// Fail makes the calls of a method return err, until it is called again
+// with a nil error
+func (r *recorder) Fail(method string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.errs == nil {
+		r.errs = map[string]error{}
+	}
+	if err == nil {
+		delete(r.errs, method)
+		return
+	}
+	r.errs[method] = err
+}
+
+// Calls returns the calls made so far, only those of methods when given
+func (r *recorder) Calls(methods ...string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(methods) == 0 {
+		return append([]Call(nil), r.calls...)
+	}
+	var calls []Call
+	for _, call := range r.calls {
+		for _, method := range methods {
+			if call.Method == method {
+				calls = append(calls, call)
+				break
+			}
+		}
+	}
+	return calls
+}
+
+// ResetCalls forgets the calls made so far
+func (r *recorder) ResetCalls() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// record records a call, returning the error set with Fail for its method
+func (r *recorder) record(method string, args ...interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+	return r.errs[method]
+}
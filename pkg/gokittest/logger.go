@@ -0,0 +1,113 @@
+package gokittest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// Logs records the entries of a logger
+type Logs struct {
+	mu      sync.Mutex
+	entries []logger.Entry
+}
+
+// NewLogger creates a logger writing its entries to the test log and
+// recording them
+func NewLogger(t testing.TB) (*logger.Logger, *Logs) {
+	t.Helper()
+	logs := &Logs{}
+	l := logger.NewLogger()
+	l.SetOutput(newTestWriter(t))
+	l.AddHook(func(e logger.Entry) error {
+		logs.mu.Lock()
+		defer logs.mu.Unlock()
+		logs.entries = append(logs.entries, e)
+		return nil
+	})
+	return l, logs
+}
+
+// Entries returns the entries logged so far, only those of levels when
+// given
+func (l *Logs) Entries(levels ...logger.LogLevel) []logger.Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []logger.Entry
+	for _, e := range l.entries {
+		if len(levels) == 0 || containsLevel(levels, e.Level) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Contains reports whether an entry of a level was logged with a message
+// containing substr
+func (l *Logs) Contains(level logger.LogLevel, substr string) bool {
+	for _, e := range l.Entries(level) {
+		if strings.Contains(e.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Field returns the value of a field of an entry, and false when the entry
+// does not have it
+func Field(e logger.Entry, key string) (interface{}, bool) {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return e.Fields[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Reset forgets the entries logged so far
+func (l *Logs) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// containsLevel reports whether levels contains level
+func containsLevel(levels []logger.LogLevel, level logger.LogLevel) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// testWriter writes to the log of a test until it ends, as writing to it
+// afterwards, e.g. from goroutines still running, panics
+type testWriter struct {
+	t    testing.TB
+	mu   sync.Mutex
+	done bool
+}
+
+// newTestWriter creates a writer to the log of a test
+func newTestWriter(t testing.TB) *testWriter {
+	w := &testWriter{t: t}
+	t.Cleanup(func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.done = true
+	})
+	return w
+}
+
+// Write logs p without its trailing newline
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
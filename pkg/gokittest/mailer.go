@@ -0,0 +1,49 @@
+package gokittest
+
+import (
+	"context"
+
+	"github.com/anaknegeri/gokit/pkg/mailer"
+)
+
+// Mailer is a mailer.Mailer keeping the messages it sends, like
+// mailer.Memory, and recording its calls
+type Mailer struct {
+	recorder
+	memory *mailer.Memory
+}
+
+var _ mailer.Mailer = (*Mailer)(nil)
+
+// NewMailer creates a mailer
+func NewMailer() *Mailer {
+	return &Mailer{memory: mailer.NewMemory()}
+}
+
+// Send records a message
+func (m *Mailer) Send(ctx context.Context, msg *mailer.Message) error {
+	if err := m.record("Send", msg); err != nil {
+		return err
+	}
+	return m.memory.Send(ctx, msg)
+}
+
+// Messages returns the messages sent so far
+func (m *Mailer) Messages() []mailer.Message {
+	return m.memory.Messages()
+}
+
+// Last returns the last message sent, and false when none was
+func (m *Mailer) Last() (mailer.Message, bool) {
+	messages := m.memory.Messages()
+	if len(messages) == 0 {
+		return mailer.Message{}, false
+	}
+	return messages[len(messages)-1], true
+}
+
+// Reset forgets the messages sent and the calls made so far
+func (m *Mailer) Reset() {
+	m.memory.Reset()
+	m.ResetCalls()
+}
@@ -0,0 +1,47 @@
+package gokittest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anaknegeri/gokit/pkg/secrets"
+)
+
+// Secrets is a secrets.Secrets of values set in the test
+type Secrets struct {
+	recorder
+	values map[string]string
+}
+
+var _ secrets.Secrets = (*Secrets)(nil)
+
+// NewSecrets creates secrets holding values, by name
+func NewSecrets(values map[string]string) *Secrets {
+	s := &Secrets{values: map[string]string{}}
+	for name, value := range values {
+		s.values[name] = value
+	}
+	return s
+}
+
+// Set sets the value of a secret, e.g. to rotate it
+func (s *Secrets) Set(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[name] = value
+}
+
+// Get returns the value of a secret, or an error wrapping
+// secrets.ErrNotFound
+func (s *Secrets) Get(_ context.Context, name string) (string, error) {
+	if err := s.record("Get", name); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", secrets.ErrNotFound, name)
+	}
+	return value, nil
+}
@@ -0,0 +1,291 @@
+package gokittest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+	fserrors "github.com/anaknegeri/gokit/pkg/filesystem/errors"
+)
+
+// DefaultPageSize is the page size of Storage.ListPage without a limit
+const DefaultPageSize = 1000
+
+// storedFile is a file of a Storage
+type storedFile struct {
+	data []byte
+	info filesystem.FileInfo
+}
+
+// Storage is a filesystem.Storage and filesystem.PagedLister keeping files
+// in memory. Its errors are those of the local storage, such as
+// fserrors.FileNotFoundError for missing files.
+type Storage struct {
+	recorder
+	files map[string]*storedFile
+}
+
+var (
+	_ filesystem.Storage     = (*Storage)(nil)
+	_ filesystem.PagedLister = (*Storage)(nil)
+)
+
+// NewStorage creates an empty storage
+func NewStorage() *Storage {
+	return &Storage{files: map[string]*storedFile{}}
+}
+
+// Put stores a file without recording a call, replacing any file at the
+// path, to set up a test
+func (s *Storage) Put(filePath string, data []byte) *filesystem.FileInfo {
+	key := filesystem.NormalizeKey(filePath)
+	file := &storedFile{
+		data: append([]byte(nil), data...),
+		info: filesystem.FileInfo{
+			Name:         path.Base(key),
+			Size:         int64(len(data)),
+			LastModified: time.Now(),
+			URL:          key,
+			ContentType:  contentType(key, data),
+		},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[key] = file
+	info := file.info
+	return &info
+}
+
+// Data returns the content of a file, and false when it does not exist
+func (s *Storage) Data(filePath string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[filesystem.NormalizeKey(filePath)]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), file.data...), true
+}
+
+// Paths returns the paths of the stored files, sorted
+func (s *Storage) Paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := make([]string, 0, len(s.files))
+	for key := range s.files {
+		paths = append(paths, key)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Upload stores a file, failing with a conflict when one exists at the path
+func (s *Storage) Upload(_ context.Context, file *multipart.FileHeader, filePath string) (*filesystem.FileInfo, error) {
+	if err := s.record("Upload", file, filePath); err != nil {
+		return nil, err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fserrors.WrapError(err, http.StatusInternalServerError, "Failed to open uploaded file")
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fserrors.WrapError(err, http.StatusInternalServerError, "Failed to copy file contents")
+	}
+
+	if _, exists := s.Data(filePath); exists {
+		return nil, fserrors.NewCustomError(
+			http.StatusConflict,
+			fserrors.ErrCodeFileAlreadyExists,
+			fmt.Sprintf("File already exists: %s", filePath),
+		)
+	}
+	return s.Put(filePath, data), nil
+}
+
+// Get returns the content of a file
+func (s *Storage) Get(_ context.Context, filePath string) (io.ReadCloser, *filesystem.FileInfo, error) {
+	if err := s.record("Get", filePath); err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[filesystem.NormalizeKey(filePath)]
+	if !ok {
+		return nil, nil, fserrors.FileNotFoundError(filePath)
+	}
+	info := file.info
+	return io.NopCloser(bytes.NewReader(file.data)), &info, nil
+}
+
+// Delete removes a file
+func (s *Storage) Delete(_ context.Context, filePath string) error {
+	if err := s.record("Delete", filePath); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := filesystem.NormalizeKey(filePath)
+	if _, ok := s.files[key]; !ok {
+		return fserrors.FileNotFoundError(filePath)
+	}
+	delete(s.files, key)
+	return nil
+}
+
+// Exists reports whether a file exists
+func (s *Storage) Exists(_ context.Context, filePath string) (bool, error) {
+	if err := s.record("Exists", filePath); err != nil {
+		return false, err
+	}
+	_, ok := s.Data(filePath)
+	return ok, nil
+}
+
+// List returns the files and directories of a directory, sorted by name, or
+// the file at the path
+func (s *Storage) List(_ context.Context, dir string) ([]filesystem.FileInfo, error) {
+	if err := s.record("List", dir); err != nil {
+		return nil, err
+	}
+	return s.list(dir)
+}
+
+// ListPage returns a page of the entries of List, continuing after the name
+// in the token
+func (s *Storage) ListPage(_ context.Context, dir string, opts filesystem.ListOptions) (*filesystem.ListPage, error) {
+	if err := s.record("ListPage", dir, opts); err != nil {
+		return nil, err
+	}
+	files, err := s.list(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	start := sort.Search(len(files), func(i int) bool { return files[i].Name > opts.Token })
+	page := &filesystem.ListPage{Files: files[start:]}
+	if len(page.Files) > limit {
+		page.Files = page.Files[:limit]
+		page.NextToken = page.Files[limit-1].Name
+	}
+	return page, nil
+}
+
+// GetInfo returns the information of a file
+func (s *Storage) GetInfo(_ context.Context, filePath string) (*filesystem.FileInfo, error) {
+	if err := s.record("GetInfo", filePath); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[filesystem.NormalizeKey(filePath)]
+	if !ok {
+		return nil, fserrors.FileNotFoundError(filePath)
+	}
+	info := file.info
+	return &info, nil
+}
+
+// list returns the entries of a directory, or the file at the path
+func (s *Storage) list(dir string) ([]filesystem.FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := filesystem.NormalizeKey(dir)
+	if file, ok := s.files[key]; ok {
+		return []filesystem.FileInfo{file.info}, nil
+	}
+
+	prefix := ""
+	if key != "" {
+		prefix = key + "/"
+	}
+	entries := map[string]filesystem.FileInfo{}
+	for fileKey, file := range s.files {
+		rest, ok := strings.CutPrefix(fileKey, prefix)
+		if !ok {
+			continue
+		}
+		name, _, nested := strings.Cut(rest, "/")
+		if !nested {
+			entries[name] = file.info
+			continue
+		}
+		entry, seen := entries[name]
+		if !seen {
+			entry = filesystem.FileInfo{Name: name, URL: prefix + name, IsDirectory: true}
+		}
+		if file.info.LastModified.After(entry.LastModified) {
+			entry.LastModified = file.info.LastModified
+		}
+		entries[name] = entry
+	}
+	if len(entries) == 0 && key != "" {
+		return nil, fserrors.FileNotFoundError(dir)
+	}
+
+	files := make([]filesystem.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, entry)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// contentType returns the content type of a file by its extension, or by
+// its content
+func contentType(key string, data []byte) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data)
+}
+
+// NewTempLocalStorage creates a local storage in a directory removed when
+// the test ends
+func NewTempLocalStorage(t testing.TB) *filesystem.LocalStorage {
+	t.Helper()
+	storage, err := filesystem.NewLocalStorage(filesystem.LocalStorageConfig{
+		BasePath:          t.TempDir(),
+		CreateDirectories: true,
+	})
+	if err != nil {
+		t.Fatalf("gokittest: creating local storage: %v", err)
+	}
+	return storage
+}
+
+// NewTempProvider creates a filesystem provider over a local storage in a
+// directory removed when the test ends
+func NewTempProvider(t testing.TB, config ...filesystem.ProviderConfig) *filesystem.Provider {
+	t.Helper()
+	return filesystem.NewProvider(NewTempLocalStorage(t), config...)
+}
+
+// NewFileHeader wraps data in a multipart file header, the form uploads
+// take
+func NewFileHeader(t testing.TB, filename string, data []byte) *multipart.FileHeader {
+	t.Helper()
+	file, err := filesystem.NewFileHeader(filename, data)
+	if err != nil {
+		t.Fatalf("gokittest: creating file header: %v", err)
+	}
+	return file
+}
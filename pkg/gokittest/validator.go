@@ -0,0 +1,48 @@
+package gokittest
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/anaknegeri/gokit/pkg/validator"
+	playground "github.com/go-playground/validator/v10"
+)
+
+// Validator is a validator.Validator accepting every struct, unless failed
+// with Fail("Struct", err) or Fail("StructCtx", err), and recording the
+// structs it validates
+type Validator struct {
+	recorder
+}
+
+var _ validator.Validator = (*Validator)(nil)
+
+// NewValidator creates a validator
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// Struct records a validated struct
+func (v *Validator) Struct(s interface{}) error {
+	return v.record("Struct", s)
+}
+
+// StructCtx records a validated struct
+func (v *Validator) StructCtx(ctx context.Context, s interface{}) error {
+	return v.record("StructCtx", ctx, s)
+}
+
+// RegisterValidation records a registered validation
+func (v *Validator) RegisterValidation(tag string, fn interface{}) error {
+	return v.record("RegisterValidation", tag, fn)
+}
+
+// RegisterValidationCtx records a registered validation
+func (v *Validator) RegisterValidationCtx(tag string, fn playground.FuncCtx) error {
+	return v.record("RegisterValidationCtx", tag, fn)
+}
+
+// RegisterTagNameFunc records a registered tag name function
+func (v *Validator) RegisterTagNameFunc(fn func(fld reflect.StructField) string) {
+	_ = v.record("RegisterTagNameFunc", fn)
+}
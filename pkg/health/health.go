@@ -0,0 +1,163 @@
+// Package health runs the health checks of a service, such as pinging its
+// database, and serves their report
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultTimeout limits each check
+const DefaultTimeout = 5 * time.Second
+
+// Statuses of checks and reports
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check reports whether a dependency is healthy
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a check
+type Result struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Report is the outcome of every check, up when all of them are
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry holds named checks
+type Registry struct {
+	mu      sync.RWMutex
+	checks  map[string]Check
+	timeout time.Duration
+}
+
+// NewRegistry creates a registry limiting each check to timeout, defaulting
+// to DefaultTimeout
+func NewRegistry(timeout ...time.Duration) *Registry {
+	r := &Registry{checks: make(map[string]Check), timeout: DefaultTimeout}
+	if len(timeout) > 0 && timeout[0] > 0 {
+		r.timeout = timeout[0]
+	}
+	return r
+}
+
+// Register adds a check, replacing any check with the same name
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = check
+}
+
+// Unregister removes a check
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// Names returns the names of the checks in order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check runs every check concurrently
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := r.run(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// run runs a check with the timeout, recovering from panics
+func (r *Registry) run(ctx context.Context, check Check) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			result.Status = StatusDown
+			result.Error = fmt.Sprintf("panic: %v", rec)
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	if err := check(ctx); err != nil {
+		return Result{Status: StatusDown, Error: err.Error()}
+	}
+	return Result{Status: StatusUp}
+}
+
+// Handler serves the report, with status 200 when every check is up and
+// 503 otherwise
+func (r *Registry) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report := r.Check(c.UserContext())
+		if report.Status != StatusUp {
+			err := errors.ServiceUnavailableError("")
+			err.Details = report
+			return response.Error(c, err)
+		}
+		return response.Success(c, "Service is healthy", report)
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default returns the registry used by Register and Handler
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register adds a check to the default registry
+func Register(name string, check Check) {
+	defaultRegistry.Register(name, check)
+}
+
+// Handler serves the report of the default registry
+func Handler() fiber.Handler {
+	return defaultRegistry.Handler()
+}
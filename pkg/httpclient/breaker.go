@@ -0,0 +1,103 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the cause of the error returned for requests to a host
+// whose circuit is open, matched with errors.Is
+var ErrCircuitOpen = errors.New("httpclient: circuit open")
+
+// BreakerConfig configures the circuit breaker of each host: after
+// Threshold failures in a row, requests fail at once for the cooldown, then
+// one trial request decides whether the circuit closes again
+type BreakerConfig struct {
+	// Threshold is how many network errors or 5xx responses in a row open
+	// the circuit, defaulting to 5. A negative threshold disables the breaker.
+	Threshold int
+
+	// Cooldown is how long the circuit stays open, defaulting to 30 seconds
+	Cooldown time.Duration
+}
+
+// Circuit states
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// breaker is the circuit breaker of a host
+type breaker struct {
+	config BreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool
+	now      func() time.Time
+}
+
+// newBreaker creates a closed breaker
+func newBreaker(config BreakerConfig) *breaker {
+	return &breaker{config: config, state: StateClosed, now: time.Now}
+}
+
+// allow reports whether a request may be sent, letting one trial request
+// through once the cooldown has passed
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.config.Cooldown {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.trial = true
+		return true
+	case StateHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record records the outcome of a request and returns the state it leads to
+func (b *breaker) record(success bool) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = StateClosed
+		b.failures = 0
+		b.trial = false
+		return b.state
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.config.Threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+		b.trial = false
+	}
+	return b.state
+}
+
+// current returns the state of the breaker
+func (b *breaker) current() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		return StateHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,387 @@
+// Package httpclient calls HTTP services with retries, backoff, a circuit
+// breaker, and per-attempt timeouts, decoding the response envelopes of
+// gokit services
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/response"
+)
+
+// Config configures a client
+type Config struct {
+	// BaseURL is prepended to request paths, e.g. "http://users:8080/api/v1"
+	BaseURL string
+
+	// Timeout limits each attempt, including reading the response body,
+	// defaulting to 30 seconds. Bound the whole call with the context.
+	Timeout time.Duration
+
+	// Retries is how many times a failed request is sent again, defaulting
+	// to 2. Only requests with idempotent methods or an Idempotency-Key
+	// header are retried, after network errors and 429, 502, 503, or 504.
+	// A negative value disables retries.
+	Retries int
+
+	// Backoff returns the delay before a retry, starting at 1, defaulting to
+	// ExponentialBackoff. A Retry-After header takes precedence.
+	Backoff func(retry int) time.Duration
+
+	// MaxRetryWait is the longest Retry-After honored, defaulting to 30
+	// seconds; longer waits are not retried
+	MaxRetryWait time.Duration
+
+	// Breaker configures the circuit breaker of each host
+	Breaker BreakerConfig
+
+	// Headers are sent with every request
+	Headers map[string]string
+
+	// Transport sends the requests, defaulting to http.DefaultTransport
+	Transport http.RoundTripper
+
+	// Fields are the envelope field names of the called services, which
+	// default to those of the response package
+	Fields response.Fields
+
+	// Logger logs requests at debug level and failures at warn level,
+	// defaulting to logger.Named("httpclient")
+	Logger *logger.Logger
+}
+
+// Client calls HTTP services
+type Client struct {
+	config    Config
+	transport *transport
+	http      *http.Client
+}
+
+// New creates a client
+func New(config ...Config) *Client {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = 2
+	} else if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = ExponentialBackoff
+	}
+	if cfg.MaxRetryWait <= 0 {
+		cfg.MaxRetryWait = 30 * time.Second
+	}
+	if cfg.Breaker.Threshold == 0 {
+		cfg.Breaker.Threshold = 5
+	}
+	if cfg.Breaker.Cooldown <= 0 {
+		cfg.Breaker.Cooldown = 30 * time.Second
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("httpclient")
+	}
+	cfg.Fields = fieldDefaults(cfg.Fields)
+
+	t := &transport{base: cfg.Transport, config: cfg, breakers: make(map[string]*breaker)}
+	return &Client{
+		config:    cfg,
+		transport: t,
+		http:      &http.Client{Transport: t},
+	}
+}
+
+// HTTPClient returns an http.Client with the retries, timeouts, circuit
+// breaker, and logging of the client, for code that needs a plain client
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// CircuitState returns the state of the circuit of a host: StateClosed,
+// StateOpen, or StateHalfOpen
+func (c *Client) CircuitState(host string) string {
+	if c.config.Breaker.Threshold < 0 {
+		return StateClosed
+	}
+	return c.transport.breaker(host).current()
+}
+
+// Request is a request sent with Do
+type Request struct {
+	Method string
+
+	// Path is appended to the base URL, or used as is when it is a full URL
+	Path   string
+	Query  url.Values
+	Header http.Header
+
+	// Body is sent as is when it is []byte, string, or io.Reader, and as
+	// JSON otherwise
+	Body interface{}
+}
+
+// Response is the response to a successful request
+type Response struct {
+	StatusCode int
+	Header     http.Header
+
+	// Body is the raw body
+	Body []byte
+
+	// Message, Meta, and Links are read from the envelope, if any
+	Message string
+	Meta    json.RawMessage
+	Links   json.RawMessage
+}
+
+// Get sends a GET request and decodes the response into out
+func (c *Client) Get(ctx context.Context, path string, out interface{}) error {
+	_, err := c.Do(ctx, Request{Method: http.MethodGet, Path: path}, out)
+	return err
+}
+
+// Post sends a POST request with a JSON body and decodes the response into out
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	_, err := c.Do(ctx, Request{Method: http.MethodPost, Path: path, Body: body}, out)
+	return err
+}
+
+// Put sends a PUT request with a JSON body and decodes the response into out
+func (c *Client) Put(ctx context.Context, path string, body, out interface{}) error {
+	_, err := c.Do(ctx, Request{Method: http.MethodPut, Path: path, Body: body}, out)
+	return err
+}
+
+// Patch sends a PATCH request with a JSON body and decodes the response into out
+func (c *Client) Patch(ctx context.Context, path string, body, out interface{}) error {
+	_, err := c.Do(ctx, Request{Method: http.MethodPatch, Path: path, Body: body}, out)
+	return err
+}
+
+// Delete sends a DELETE request and decodes the response into out
+func (c *Client) Delete(ctx context.Context, path string, out interface{}) error {
+	_, err := c.Do(ctx, Request{Method: http.MethodDelete, Path: path}, out)
+	return err
+}
+
+// Do sends a request and decodes the data of the response envelope, or the
+// whole body when there is none, into out unless it is nil. Error responses
+// are returned as *errors.AppError with the code, message, and details of
+// the error envelope, so errors.Is matches them against the sentinels of
+// the errors package.
+func (c *Client) Do(ctx context.Context, r Request, out interface{}) (*Response, error) {
+	req, err := c.newRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var appErr *errors.AppError
+		if errors.As(err, &appErr) {
+			return nil, appErr
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, unavailable(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+
+	result := &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+	if resp.StatusCode >= 400 {
+		return result, c.decodeError(req, resp.StatusCode, body)
+	}
+	if err := c.decode(result, out); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// newRequest builds the HTTP request of a request
+func (c *Client) newRequest(ctx context.Context, r Request) (*http.Request, error) {
+	target := r.Path
+	if !strings.Contains(target, "://") && c.config.BaseURL != "" {
+		target = strings.TrimRight(c.config.BaseURL, "/") + "/" + strings.TrimLeft(target, "/")
+	}
+	if len(r.Query) > 0 {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + r.Query.Encode()
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch b := r.Body.(type) {
+	case nil:
+	case []byte:
+		body = bytes.NewReader(b)
+	case string:
+		body = strings.NewReader(b)
+	case io.Reader:
+		body = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("httpclient: encode request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("httpclient: %w", err)
+	}
+	for name, values := range r.Header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	return req, nil
+}
+
+// decode reads the envelope of a successful response and decodes its data
+// into out
+func (c *Client) decode(result *Response, out interface{}) error {
+	fields := c.config.Fields
+	data := json.RawMessage(result.Body)
+
+	if envelope, ok := parseEnvelope(result.Body, fields); ok {
+		if raw, ok := envelope[fields.Message]; ok {
+			_ = json.Unmarshal(raw, &result.Message)
+		}
+		result.Meta = envelope[fields.Meta]
+		result.Links = envelope[fields.Links]
+		data = envelope[fields.Data]
+	}
+
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.WrapError(fmt.Errorf("httpclient: decode response: %w", err), http.StatusBadGateway, "Invalid response from upstream service")
+	}
+	return nil
+}
+
+// decodeError converts an error response into an AppError, keeping the
+// code, message, and details of an error envelope
+func (c *Client) decodeError(req *http.Request, status int, body []byte) error {
+	fields := c.config.Fields
+	upstream := fmt.Errorf("httpclient: %s %s responded %d", req.Method, req.URL.Redacted(), status)
+
+	if envelope, ok := parseEnvelope(body, fields); ok {
+		var code, message string
+		_ = json.Unmarshal(envelope[fields.Error], &code)
+		_ = json.Unmarshal(envelope[fields.ErrorMessage], &message)
+		if code != "" {
+			var details interface{}
+			if raw, ok := envelope[fields.Details]; ok {
+				_ = json.Unmarshal(raw, &details)
+			}
+			if message == "" {
+				message = http.StatusText(status)
+			}
+			return &errors.AppError{
+				Code:     code,
+				Message:  message,
+				Details:  details,
+				HTTPCode: status,
+				Internal: upstream,
+			}
+		}
+	}
+
+	err := errors.NewError(status, http.StatusText(status))
+	if text := strings.TrimSpace(string(body)); text != "" {
+		if len(text) > 512 {
+			text = text[:512]
+		}
+		upstream = fmt.Errorf("%w: %s", upstream, text)
+	}
+	err.Internal = upstream
+	return err
+}
+
+// unavailable is the error for a request that got no response
+func unavailable(err error) error {
+	appErr := errors.ServiceUnavailableError("")
+	appErr.Internal = err
+	return appErr
+}
+
+// parseEnvelope parses a body as a JSON object with a success or data
+// field, the shape of gokit responses
+func parseEnvelope(body []byte, fields response.Fields) (map[string]json.RawMessage, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &envelope); err != nil {
+		return nil, false
+	}
+	_, hasSuccess := envelope[fields.Success]
+	_, hasData := envelope[fields.Data]
+	_, hasError := envelope[fields.Error]
+	return envelope, hasSuccess || hasData || hasError
+}
+
+// fieldDefaults fills in the default envelope field names
+func fieldDefaults(f response.Fields) response.Fields {
+	for _, field := range []struct {
+		name  *string
+		value string
+	}{
+		{&f.Success, "success"},
+		{&f.Code, "code"},
+		{&f.Message, "message"},
+		{&f.Data, "data"},
+		{&f.Meta, "meta"},
+		{&f.Links, "links"},
+		{&f.Error, "error"},
+		{&f.Details, "details"},
+	} {
+		if *field.name == "" {
+			*field.name = field.value
+		}
+	}
+	if f.ErrorMessage == "" {
+		f.ErrorMessage = f.Message
+	}
+	return f
+}
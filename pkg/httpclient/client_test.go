@@ -0,0 +1,320 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	apperrors "github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// roundTripperFunc is a RoundTripper calling a function
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// upstream is a server answering with the next of its statuses, then with
+// 200, recording the bodies it receives
+type upstream struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	bodies   []string
+}
+
+func newUpstream(t *testing.T, statuses ...int) *upstream {
+	t.Helper()
+	u := &upstream{statuses: statuses, header: make(http.Header)}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		u.mu.Lock()
+		u.bodies = append(u.bodies, string(body))
+		status := http.StatusOK
+		if len(u.statuses) > 0 {
+			status, u.statuses = u.statuses[0], u.statuses[1:]
+		}
+		for name, values := range u.header {
+			w.Header()[name] = values
+		}
+		u.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status < 400 {
+			io.WriteString(w, `{"success":true,"data":{"id":7}}`)
+		} else {
+			io.WriteString(w, `{"success":false,"error":"UPSTREAM","message":"Try again"}`)
+		}
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// calls returns the number of requests the server received
+func (u *upstream) calls() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.bodies)
+}
+
+// host returns the host of the server
+func (u *upstream) host() string {
+	parsed, _ := url.Parse(u.URL)
+	return parsed.Host
+}
+
+// clock is a settable clock for the circuit breaker
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) get() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestClientRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		header     http.Header
+		statuses   []int
+		retryAfter string
+		retries    int
+		calls      int
+		status     int
+	}{
+		{"succeeds first", http.MethodGet, nil, nil, "", 0, 1, http.StatusOK},
+		{"succeeds on retry", http.MethodGet, nil, []int{503, 502}, "", 0, 3, http.StatusOK},
+		{"out of retries", http.MethodGet, nil, []int{503, 503, 503, 503}, "", 0, 3, http.StatusServiceUnavailable},
+		{"more retries", http.MethodGet, nil, []int{429, 504, 503}, "", 3, 4, http.StatusOK},
+		{"retries disabled", http.MethodGet, nil, []int{503}, "", -1, 1, http.StatusServiceUnavailable},
+		{"not retried status", http.MethodGet, nil, []int{500}, "", 0, 1, http.StatusInternalServerError},
+		{"client error", http.MethodPut, nil, []int{404}, "", 0, 1, http.StatusNotFound},
+		{"post", http.MethodPost, nil, []int{503}, "", 0, 1, http.StatusServiceUnavailable},
+		{"post with idempotency key", http.MethodPost, http.Header{"Idempotency-Key": {"k1"}}, []int{503}, "", 0, 2, http.StatusOK},
+		{"short retry after", http.MethodGet, nil, []int{503}, "0", 0, 2, http.StatusOK},
+		{"long retry after", http.MethodGet, nil, []int{503}, "3600", 0, 1, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newUpstream(t, tt.statuses...)
+			if tt.retryAfter != "" {
+				server.header.Set("Retry-After", tt.retryAfter)
+			}
+			var mu sync.Mutex
+			var backoffs []int
+			client := New(Config{
+				BaseURL: server.URL,
+				Retries: tt.retries,
+				Backoff: func(retry int) time.Duration {
+					mu.Lock()
+					defer mu.Unlock()
+					backoffs = append(backoffs, retry)
+					return 0
+				},
+				Breaker: BreakerConfig{Threshold: -1},
+			})
+
+			var out struct{ ID int }
+			resp, err := client.Do(context.Background(), Request{Method: tt.method, Path: "/users/7", Header: tt.header, Body: map[string]int{"id": 7}}, &out)
+			if server.calls() != tt.calls {
+				t.Errorf("Expected %d calls, got %d", tt.calls, server.calls())
+			}
+			if tt.status < 400 {
+				if err != nil || resp.StatusCode != tt.status || out.ID != 7 {
+					t.Errorf("Expected status %d with the data, got %+v, %+v, and %v", tt.status, resp, out, err)
+				}
+			} else {
+				var appErr *apperrors.AppError
+				if !errors.As(err, &appErr) || appErr.HTTPCode != tt.status || appErr.Code != "UPSTREAM" {
+					t.Errorf("Expected an UPSTREAM error with status %d, got %v", tt.status, err)
+				}
+			}
+
+			// Every attempt sends the whole body
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			for i, body := range server.bodies {
+				if body != `{"id":7}` && tt.method != http.MethodGet {
+					t.Errorf("Expected attempt %d to send the body, got %q", i+1, body)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.retryAfter == "" && len(backoffs) != tt.calls-1 {
+				t.Errorf("Expected %d backoffs, got %v", tt.calls-1, backoffs)
+			}
+			for i, retry := range backoffs {
+				if retry != i+1 {
+					t.Errorf("Expected backoff %d for retry %d, got %d", i+1, i+1, retry)
+				}
+			}
+		})
+	}
+}
+
+func TestClientNetworkError(t *testing.T) {
+	calls := 0
+	client := New(Config{
+		BaseURL: "http://users",
+		Backoff: func(int) time.Duration { return 0 },
+		Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			calls++
+			return nil, errors.New("connection refused")
+		}),
+	})
+
+	err := client.Get(context.Background(), "/users/7", nil)
+	if !apperrors.Is(err, apperrors.ErrServiceUnavailable) {
+		t.Errorf("Expected a service unavailable error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestClientRetryCanceled(t *testing.T) {
+	server := newUpstream(t, 503, 503, 503)
+	client := New(Config{BaseURL: server.URL, Backoff: func(int) time.Duration { return time.Hour }})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := client.Get(ctx, "/users/7", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the backoff to end with the context, took %s", elapsed)
+	}
+	if server.calls() != 1 {
+		t.Errorf("Expected 1 call, got %d", server.calls())
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	server := newUpstream(t, 500, 503, 500, 500)
+	client := New(Config{BaseURL: server.URL, Retries: -1, Breaker: BreakerConfig{Threshold: 2, Cooldown: time.Minute}})
+	clock := &clock{now: time.Now()}
+	client.transport.breaker(server.host()).now = clock.get
+	ctx := context.Background()
+
+	expect := func(state string, calls int) {
+		t.Helper()
+		if got := client.CircuitState(server.host()); got != state {
+			t.Errorf("Expected the circuit %s, got %s", state, got)
+		}
+		if server.calls() != calls {
+			t.Errorf("Expected %d calls, got %d", calls, server.calls())
+		}
+	}
+
+	// Two failures in a row open the circuit
+	client.Get(ctx, "/", nil)
+	expect(StateClosed, 1)
+	client.Get(ctx, "/", nil)
+	expect(StateOpen, 2)
+
+	// Requests fail at once while the circuit is open
+	err := client.Get(ctx, "/", nil)
+	if !errors.Is(err, ErrCircuitOpen) || !apperrors.Is(err, apperrors.ErrServiceUnavailable) {
+		t.Errorf("Expected a circuit open error, got %v", err)
+	}
+	expect(StateOpen, 2)
+
+	// After the cooldown a failed trial opens the circuit again
+	clock.advance(time.Minute)
+	expect(StateHalfOpen, 2)
+	client.Get(ctx, "/", nil)
+	expect(StateOpen, 3)
+	if err := client.Get(ctx, "/", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected a circuit open error, got %v", err)
+	}
+
+	// Each failed trial waits another cooldown, and a successful one closes
+	// the circuit
+	clock.advance(time.Minute)
+	if err := client.Get(ctx, "/", nil); err == nil {
+		t.Fatal("Expected the trial to fail")
+	}
+	expect(StateOpen, 4)
+	clock.advance(time.Minute)
+	if err := client.Get(ctx, "/", nil); err != nil {
+		t.Fatalf("Expected the trial to succeed, got %v", err)
+	}
+	expect(StateClosed, 5)
+}
+
+func TestCircuitBreakerClientErrors(t *testing.T) {
+	server := newUpstream(t, 404, 404, 404)
+	client := New(Config{BaseURL: server.URL, Breaker: BreakerConfig{Threshold: 2}})
+	for i := 0; i < 3; i++ {
+		var appErr *apperrors.AppError
+		if err := client.Get(context.Background(), "/", nil); !errors.As(err, &appErr) || appErr.HTTPCode != http.StatusNotFound {
+			t.Errorf("Expected a not found error, got %v", err)
+		}
+	}
+	if got := client.CircuitState(server.host()); got != StateClosed {
+		t.Errorf("Expected 4xx responses to leave the circuit closed, got %s", got)
+	}
+}
+
+func TestBreakerTrial(t *testing.T) {
+	clock := &clock{now: time.Now()}
+	b := newBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Second})
+	b.now = clock.get
+
+	b.record(false)
+	if b.allow() {
+		t.Error("Expected the open circuit to refuse requests")
+	}
+	clock.advance(time.Second)
+	if !b.allow() {
+		t.Error("Expected the trial request to be allowed")
+	}
+	if b.allow() {
+		t.Error("Expected one trial request at a time")
+	}
+	b.record(true)
+	if !b.allow() || !b.allow() {
+		t.Error("Expected the closed circuit to allow requests")
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		retry int
+		base  time.Duration
+	}{
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{4, 1600 * time.Millisecond},
+		{6, 5 * time.Second},
+		{50, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := ExponentialBackoff(tt.retry); got < tt.base-tt.base/10 || got > tt.base+tt.base/10 {
+				t.Errorf("ExponentialBackoff(%d) = %s, want %s ±10%%", tt.retry, got, tt.base)
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// transport sends requests with timeouts, retries, and a circuit breaker
+// per host, logging each attempt
+type transport struct {
+	base   http.RoundTripper
+	config Config
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// RoundTrip sends a request, retrying it when that is safe
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	req = req.Clone(ctx)
+	for name, value := range t.config.Headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	if req.Header.Get("X-Request-ID") == "" {
+		if id := logger.RequestIDFromContext(ctx); id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+	}
+
+	log := t.config.Logger.WithFields(logger.Fields{
+		"method": req.Method,
+		"url":    req.URL.Redacted(),
+	})
+	b := t.breaker(req.URL.Host)
+
+	attempts := 1
+	if retryable(req) {
+		attempts += t.config.Retries
+	}
+	for attempt := 1; ; attempt++ {
+		if b != nil && !b.allow() {
+			return nil, circuitOpenError(req.URL.Host)
+		}
+
+		resp, err := t.send(req, attempt)
+		if b != nil {
+			failed := err != nil || resp.StatusCode >= 500
+			if state := b.record(!failed); state == StateOpen && failed {
+				log.Warnf("Circuit for %s is open", req.URL.Host)
+			}
+		}
+
+		var delay time.Duration
+		retry := attempt < attempts && ctx.Err() == nil
+		if retry {
+			delay, retry = t.retryDelay(resp, err, attempt)
+		}
+		if !retry {
+			if err != nil {
+				log.Warnf("Request failed after %d attempts: %v", attempt, err)
+			}
+			return resp, err
+		}
+
+		if err != nil {
+			log.Warnf("Request failed, retrying in %s: %v", delay, err)
+		} else {
+			log.Warnf("Request responded %d, retrying in %s", resp.StatusCode, delay)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send sends one attempt of a request with the attempt timeout, which lasts
+// until the response body is closed
+func (t *transport) send(req *http.Request, attempt int) (*http.Response, error) {
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.config.Timeout)
+	ctx, span := tracing.Tracer().Start(ctx, req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.Redacted()),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.HTTPRequestResendCount(attempt-1),
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	duration := time.Since(start)
+
+	if err != nil {
+		tracing.End(span, err)
+		cancel()
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, "")
+	}
+	span.End()
+	t.config.Logger.WithFields(logger.Fields{
+		"method":      req.Method,
+		"url":         req.URL.Redacted(),
+		"status":      resp.StatusCode,
+		"duration_ms": duration.Milliseconds(),
+		"attempt":     attempt,
+	}).Debugf("%s %s %d in %s", req.Method, req.URL.Redacted(), resp.StatusCode, duration)
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryDelay returns how long to wait before retrying a failed attempt, and
+// whether to retry: after network errors, 429, 502, 503, and 504, honoring
+// Retry-After up to MaxRetryWait
+func (t *transport) retryDelay(resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		return t.config.Backoff(attempt), true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, false
+	}
+
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		var wait time.Duration
+		if seconds, err := strconv.Atoi(value); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(value); err == nil {
+			wait = time.Until(at)
+		}
+		if wait > t.config.MaxRetryWait {
+			return 0, false
+		}
+		if wait > 0 {
+			return wait, true
+		}
+	}
+	return t.config.Backoff(attempt), true
+}
+
+// breaker returns the circuit breaker of a host, or nil when disabled
+func (t *transport) breaker(host string) *breaker {
+	if t.config.Breaker.Threshold < 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[host]
+	if !ok {
+		b = newBreaker(t.config.Breaker)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// retryable reports whether a request can be sent again: idempotent methods
+// and requests with an Idempotency-Key, whose body can be read again
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// circuitOpenError is the error for a request to a host whose circuit is open
+func circuitOpenError(host string) error {
+	err := errors.ServiceUnavailableError("")
+	err.Internal = fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+	return err
+}
+
+// cancelBody cancels the attempt context when the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// ExponentialBackoff waits 200ms before the first retry and doubles the
+// delay for each further one up to 5 seconds, varying it by up to 20%
+func ExponentialBackoff(retry int) time.Duration {
+	delay := time.Duration(math.Min(200*math.Pow(2, float64(retry-1)), 5000)) * time.Millisecond
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay - delay/10 + jitter
+}
@@ -0,0 +1,317 @@
+// Package i18n provides translatable messages: catalogs loaded from JSON
+// files, plural forms, locale negotiation, and the T helper. It holds the
+// only message catalogs of gokit: pkg/errors adds its error and validation
+// messages to them, so catalogs can translate those messages or add locales.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LocaleEnglish is the locale messages fall back to last
+const LocaleEnglish = "en"
+
+// Args are the values of the {name} placeholders of a message. The "count"
+// argument selects the plural form of messages that have them.
+type Args map[string]interface{}
+
+// Messages are the messages of a locale by key. Values are strings, plural
+// forms such as {"one": "{count} file", "other": "{count} files"}, or nested
+// messages whose keys are joined with dots.
+type Messages map[string]interface{}
+
+// message is a message template, with its plural forms if it has them
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+// localsKey is the Fiber local holding the locale of a request. Fiber stores
+// locals on the fasthttp request context, which exposes them through Value.
+const localsKey = "locale"
+
+type localeContextKey struct{}
+
+var (
+	mu            sync.RWMutex
+	defaultLocale = LocaleEnglish
+	catalogs      = map[string]map[string]message{}
+)
+
+// Add adds or replaces the messages of a locale:
+//
+//	i18n.Add("id", i18n.Messages{
+//	    "welcome": "Selamat datang, {name}",
+//	    "files":   map[string]string{"other": "{count} berkas"},
+//	    "validation.required": "{field} wajib diisi",
+//	})
+func Add(locale string, messages Messages) error {
+	flat := make(map[string]message, len(messages))
+	if err := flatten(flat, "", messages); err != nil {
+		return err
+	}
+
+	locale = normalizeLocale(locale)
+	mu.Lock()
+	defer mu.Unlock()
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog = make(map[string]message, len(flat))
+		catalogs[locale] = catalog
+	}
+	for key, m := range flat {
+		catalog[key] = m
+	}
+	return nil
+}
+
+// SetDefaultLocale sets the locale used when no locale is requested
+func SetDefaultLocale(locale string) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLocale = normalizeLocale(locale)
+}
+
+// DefaultLocale returns the locale used when no locale is requested
+func DefaultLocale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLocale
+}
+
+// Locales returns the locales that have messages, including locales added
+// with no messages, such as the validator locales of pkg/errors
+func Locales() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// T renders the message for key in the locale of ctx, set by Middleware or
+// WithLocale. Like Translate, it falls back to the default locale and English,
+// and returns the key when no catalog has it.
+func T(ctx context.Context, key string, args ...Args) string {
+	return Translate(Locale(ctx), key, args...)
+}
+
+// Translate renders the message for key in a locale. It falls back to the
+// base language of regional locales, the default locale, and English, and
+// returns the key when no catalog has it. Messages of pkg/errors, such as
+// "record_not_found", are rendered too.
+func Translate(locale string, key string, args ...Args) string {
+	params := toParams(args)
+	for _, l := range Fallbacks(locale) {
+		if text, ok := Message(l, key, params); ok {
+			return text
+		}
+	}
+	return key
+}
+
+// Message renders the message for key in a single locale, choosing the
+// plural form by the "count" parameter
+func Message(locale, key string, params map[string]string) (string, bool) {
+	mu.RLock()
+	m, ok := catalogs[normalizeLocale(locale)][key]
+	mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	text := m.text
+	if m.plural != nil {
+		if count, ok := params["count"]; ok {
+			if form, ok := m.plural[pluralForm(locale, count)]; ok {
+				text = form
+			}
+		}
+	}
+	return render(text, params), true
+}
+
+// Fallbacks returns the locales messages are looked up in, in order: the
+// locale, its base language for regional tags such as "pt-br", the default
+// locale, and English
+func Fallbacks(locale string) []string {
+	locale = normalizeLocale(locale)
+	locales := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		locales = append(locales, base)
+	}
+	return append(locales, DefaultLocale(), LocaleEnglish)
+}
+
+// MatchLocale picks the best locale with messages from an Accept-Language
+// header value, falling back from regional tags to their base language. It
+// returns the default locale when nothing matches.
+func MatchLocale(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		tag, q := part, 1.0
+		if idx := strings.Index(part, ";"); idx >= 0 {
+			tag = strings.TrimSpace(part[:idx])
+			for _, param := range strings.Split(part[idx+1:], ";") {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = v
+					}
+				}
+			}
+		}
+		if tag == "*" || q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{locale: normalizeLocale(tag), q: q})
+	}
+
+	// Stable sort keeps header order for equal weights
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, c := range candidates {
+		if locale := match(c.locale); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale()
+}
+
+// WithLocale returns a copy of ctx that carries a locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, normalizeLocale(locale))
+}
+
+// LocaleFromContext returns the locale carried by ctx, or "" when there is none
+func LocaleFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if locale, ok := ctx.Value(localeContextKey{}).(string); ok {
+		return locale
+	}
+	if locale, ok := ctx.Value(localsKey).(string); ok {
+		return locale
+	}
+	return ""
+}
+
+// Locale returns the locale carried by ctx, or the default locale
+func Locale(ctx context.Context) string {
+	if locale := LocaleFromContext(ctx); locale != "" {
+		return locale
+	}
+	return DefaultLocale()
+}
+
+// flatten adds messages to flat, prefixing their keys
+func flatten(flat map[string]message, prefix string, messages map[string]interface{}) error {
+	for key, value := range messages {
+		key = prefix + key
+		switch v := value.(type) {
+		case string:
+			flat[key] = message{text: v}
+		case map[string]string:
+			nested := make(map[string]interface{}, len(v))
+			for k, text := range v {
+				nested[k] = text
+			}
+			if err := flattenMap(flat, key, nested); err != nil {
+				return err
+			}
+		case Messages:
+			if err := flattenMap(flat, key, v); err != nil {
+				return err
+			}
+		case map[string]interface{}:
+			if err := flattenMap(flat, key, v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("i18n: message %q is a %T, not a string or an object", key, value)
+		}
+	}
+	return nil
+}
+
+// flattenMap adds an object, which is either the plural forms of key or
+// messages nested below it
+func flattenMap(flat map[string]message, key string, value map[string]interface{}) error {
+	if isPlural(value) {
+		forms := make(map[string]string, len(value))
+		for form, text := range value {
+			s, ok := text.(string)
+			if !ok {
+				return fmt.Errorf("i18n: plural form %q of %q is a %T, not a string", form, key, text)
+			}
+			forms[form] = s
+		}
+		flat[key] = message{text: forms["other"], plural: forms}
+		return nil
+	}
+	return flatten(flat, key+".", value)
+}
+
+// isPlural reports whether an object holds plural forms: its keys are all
+// plural forms, one of them "other"
+func isPlural(value map[string]interface{}) bool {
+	if _, ok := value["other"]; !ok {
+		return false
+	}
+	for form := range value {
+		if !pluralForms[form] {
+			return false
+		}
+	}
+	return true
+}
+
+// toParams converts the arguments of a message to its parameters
+func toParams(args []Args) map[string]string {
+	if len(args) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(args[0]))
+	for name, value := range args[0] {
+		params[name] = fmt.Sprint(value)
+	}
+	return params
+}
+
+// render replaces {name} placeholders in a template
+func render(template string, params map[string]string) string {
+	if len(params) == 0 {
+		return template
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// normalizeLocale lowercases a language tag and uses "-" as separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
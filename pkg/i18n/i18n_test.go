@@ -0,0 +1,307 @@
+package i18n
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-playground/locales/ru"
+	"github.com/gofiber/fiber/v2"
+)
+
+// useCatalogs replaces the catalogs for a test, restoring them afterwards
+func useCatalogs(t *testing.T, locales map[string]Messages) {
+	t.Helper()
+	mu.Lock()
+	saved, savedDefault := catalogs, defaultLocale
+	catalogs, defaultLocale = map[string]map[string]message{}, LocaleEnglish
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		catalogs, defaultLocale = saved, savedDefault
+		mu.Unlock()
+	})
+
+	for locale, messages := range locales {
+		if err := Add(locale, messages); err != nil {
+			t.Fatalf("Failed to add %s messages: %v", locale, err)
+		}
+	}
+}
+
+// testCatalogs are English and Indonesian messages
+var testCatalogs = map[string]Messages{
+	"en": {
+		"welcome": "Welcome, {name}",
+		"files":   map[string]string{"one": "{count} file", "other": "{count} files"},
+		"errors":  Messages{"required": "{field} is required"},
+		"only_en": "English only",
+	},
+	"id": {
+		"welcome": "Selamat datang, {name}",
+		"files":   map[string]string{"other": "{count} berkas"},
+		"errors":  Messages{"required": "{field} wajib diisi"},
+	},
+}
+
+func TestPluralForm(t *testing.T) {
+	RegisterPluralRules(ru.New())
+	t.Cleanup(func() {
+		pluralMu.Lock()
+		delete(pluralRules, "ru")
+		pluralMu.Unlock()
+	})
+
+	tests := []struct {
+		locale string
+		count  string
+		want   string
+	}{
+		{"en", "1", "one"},
+		{"en", "0", "other"},
+		{"en", "2", "other"},
+		{"en", "1.0", "other"},
+		{"en", "1.5", "other"},
+		{"en", "-1", "one"},
+		{"en", "many", "other"},
+		{"en-GB", "1", "one"},
+		{"en_US", "1", "one"},
+		{"id", "1", "other"},
+		{"id", "0", "other"},
+		{"id", "100", "other"},
+		{"id-ID", "1", "other"},
+		{"ru", "1", "one"},
+		{"ru", "3", "few"},
+		{"ru", "5", "many"},
+		{"ru", "21", "one"},
+		{"ru", "1.5", "other"},
+		{"fr", "1", "one"}, // English rules when a locale has none
+		{"fr", "0", "other"},
+	}
+	for _, tt := range tests {
+		if got := pluralForm(tt.locale, tt.count); got != tt.want {
+			t.Errorf("pluralForm(%q, %q) = %q, want %q", tt.locale, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	useCatalogs(t, testCatalogs)
+
+	tests := []struct {
+		name   string
+		locale string
+		key    string
+		args   []Args
+		want   string
+	}{
+		{"english", "en", "welcome", []Args{{"name": "Ana"}}, "Welcome, Ana"},
+		{"indonesian", "id", "welcome", []Args{{"name": "Ana"}}, "Selamat datang, Ana"},
+		{"nested key", "id", "errors.required", []Args{{"field": "Nama"}}, "Nama wajib diisi"},
+		{"english one", "en", "files", []Args{{"count": 1}}, "1 file"},
+		{"english other", "en", "files", []Args{{"count": 3}}, "3 files"},
+		{"english zero", "en", "files", []Args{{"count": 0}}, "0 files"},
+		{"indonesian one", "id", "files", []Args{{"count": 1}}, "1 berkas"},
+		{"indonesian other", "id", "files", []Args{{"count": 3}}, "3 berkas"},
+		{"plural without count", "en", "files", nil, "{count} files"},
+		{"regional to base", "id-ID", "welcome", []Args{{"name": "Ana"}}, "Selamat datang, Ana"},
+		{"missing in locale", "id", "only_en", nil, "English only"},
+		{"unknown locale", "fr", "welcome", []Args{{"name": "Ana"}}, "Welcome, Ana"},
+		{"unknown key", "id", "missing", nil, "missing"},
+		{"unused argument", "en", "only_en", []Args{{"name": "Ana"}}, "English only"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.locale, tt.key, tt.args...); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// The default locale comes before English
+	SetDefaultLocale("id")
+	if got := T(context.Background(), "welcome", Args{"name": "Ana"}); got != "Selamat datang, Ana" {
+		t.Errorf("Expected the default locale, got %q", got)
+	}
+	if got := Translate("fr", "only_en"); got != "English only" {
+		t.Errorf("Expected English after the default locale, got %q", got)
+	}
+}
+
+func TestMatchLocale(t *testing.T) {
+	useCatalogs(t, map[string]Messages{
+		"en":    testCatalogs["en"],
+		"id":    testCatalogs["id"],
+		"pt-br": {"welcome": "Bem-vindo, {name}"},
+	})
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"id", "id"},
+		{"ID", "id"},
+		{"id-ID", "id"},
+		{"id_ID", "id"},
+		{"en-US,en;q=0.9", "en"},
+		{"fr-FR,fr;q=0.9,id;q=0.8,en;q=0.7", "id"},
+		{"en;q=0.5, id;q=0.8", "id"},
+		{"en, id", "en"},
+		{"id;q=0, en;q=0.1", "en"},
+		{"pt-BR", "pt-br"},
+		{"pt", ""}, // a base language does not match a regional catalog
+		{"pt-PT", ""},
+		{"*", "en"},
+		{"fr, de", "en"},
+		{"id;q=abc", "id"},
+		{" ; , id ", "id"},
+	}
+	for _, tt := range tests {
+		want := tt.want
+		if want == "" {
+			want = DefaultLocale()
+		}
+		if got := MatchLocale(tt.header); got != want {
+			t.Errorf("MatchLocale(%q) = %q, want %q", tt.header, got, want)
+		}
+	}
+
+	SetDefaultLocale("id")
+	if got := MatchLocale("fr"); got != "id" {
+		t.Errorf("Expected the default locale when nothing matches, got %q", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	useCatalogs(t, testCatalogs)
+
+	app := fiber.New()
+	app.Use(Middleware(MiddlewareConfig{QueryParam: "lang", Cookie: "locale"}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(T(c.UserContext(), "welcome", Args{"name": "Ana"}))
+	})
+
+	tests := []struct {
+		name     string
+		target   string
+		cookie   string
+		header   string
+		locale   string
+		body     string
+		variesBy bool
+	}{
+		{"default", "/", "", "", "en", "Welcome, Ana", true},
+		{"header", "/", "", "id-ID,id;q=0.9,en;q=0.8", "id", "Selamat datang, Ana", true},
+		{"cookie over header", "/", "id", "en", "id", "Selamat datang, Ana", false},
+		{"query over cookie", "/?lang=en", "id", "id", "en", "Welcome, Ana", false},
+		{"regional query", "/?lang=id_ID", "", "", "id", "Selamat datang, Ana", false},
+		{"unsupported query", "/?lang=fr", "", "id", "id", "Selamat datang, Ana", true},
+		{"unsupported cookie", "/", "fr", "id", "id", "Selamat datang, Ana", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "locale", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("Expected %q, got %q", tt.body, body)
+			}
+			if got := resp.Header.Get("Content-Language"); got != tt.locale {
+				t.Errorf("Expected Content-Language %s, got %s", tt.locale, got)
+			}
+			if varies := strings.Contains(resp.Header.Get("Vary"), "Accept-Language"); varies != tt.variesBy {
+				t.Errorf("Expected Vary on Accept-Language to be %v, got %q", tt.variesBy, resp.Header.Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		check   map[string]string // locale and key: message
+		wantErr string
+	}{
+		{
+			name: "catalogs",
+			files: fstest.MapFS{
+				"locales/en.json":        {Data: []byte(`{"welcome": "Welcome, {name}", "files": {"one": "{count} file", "other": "{count} files"}}`)},
+				"locales/id.json":        {Data: []byte(`{"welcome": "Selamat datang, {name}", "files": {"other": "{count} berkas"}}`)},
+				"locales/errors.id.json": {Data: []byte(`{"validation": {"required": "{field} wajib diisi"}}`)},
+				"locales/pt-BR.json":     {Data: []byte(`{"welcome": "Bem-vindo, {name}"}`)},
+				"locales/README.md":      {Data: []byte("not a catalog")},
+				"locales/old/fr.json":    {Data: []byte(`{"welcome": "Bienvenue"}`)},
+			},
+			check: map[string]string{
+				"en:welcome":             "Welcome, {name}",
+				"en:files":               "{count} files",
+				"id:welcome":             "Selamat datang, {name}",
+				"id:validation.required": "{field} wajib diisi",
+				"pt-br:welcome":          "Bem-vindo, {name}",
+				"fr:welcome":             "",
+			},
+		},
+		{
+			name:    "invalid JSON",
+			files:   fstest.MapFS{"locales/en.json": {Data: []byte(`{"welcome": `)}},
+			wantErr: "parse en.json",
+		},
+		{
+			name:    "invalid message",
+			files:   fstest.MapFS{"locales/id.json": {Data: []byte(`{"count": 3}`)}},
+			wantErr: `message "count" is a float64`,
+		},
+		{
+			name:    "invalid plural form",
+			files:   fstest.MapFS{"locales/id.json": {Data: []byte(`{"files": {"one": 1, "other": "{count} berkas"}}`)}},
+			wantErr: `plural form "one" of "files"`,
+		},
+		{
+			name:    "missing directory",
+			files:   fstest.MapFS{},
+			wantErr: "read locales",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCatalogs(t, nil)
+			err := LoadFS(tt.files, "locales")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load catalogs: %v", err)
+			}
+			for localeKey, want := range tt.check {
+				locale, key, _ := strings.Cut(localeKey, ":")
+				got, ok := Message(locale, key, nil)
+				if got != want || ok != (want != "") {
+					t.Errorf("Expected %s to be %q, got %q", localeKey, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// LoadFS adds the JSON catalogs of a directory of a file system, e.g. an
+// embed.FS. Each file holds the Messages of the locale in its name, such as
+// "en.json", "pt-BR.json", or "errors.id.json":
+//
+//	{
+//	    "welcome": "Welcome, {name}",
+//	    "files": {"one": "{count} file", "other": "{count} files"},
+//	    "validation": {"required": "{field} is required"}
+//	}
+func LoadFS(fsys fs.FS, dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("i18n: read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || path.Ext(name) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("i18n: read %s: %w", name, err)
+		}
+
+		var messages Messages
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("i18n: parse %s: %w", name, err)
+		}
+		if err := Add(fileLocale(name), messages); err != nil {
+			return fmt.Errorf("%w in %s", err, name)
+		}
+	}
+	return nil
+}
+
+// LoadDir adds the JSON catalogs of a directory, see LoadFS
+func LoadDir(dir string) error {
+	return LoadFS(os.DirFS(dir), ".")
+}
+
+// fileLocale returns the locale of a catalog file, the last part of its name
+// before the extension
+func fileLocale(name string) string {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,73 @@
+package i18n
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MiddlewareConfig configures how Middleware picks the locale of a request
+type MiddlewareConfig struct {
+	// QueryParam, when set, is a query parameter choosing the locale, e.g.
+	// "lang" for ?lang=id, which takes precedence over the cookie and header
+	QueryParam string
+
+	// Cookie, when set, is a cookie holding the locale the user chose, which
+	// takes precedence over the Accept-Language header
+	Cookie string
+}
+
+// Middleware negotiates the locale of each request from the query parameter
+// and cookie of the config, if any, then the Accept-Language header, among
+// the locales of the catalogs. Unsupported choices fall
+// through to the next source, and the default locale is used last.
+//
+// The locale is carried by the user context of the request for T, and error
+// responses of pkg/response are rendered in it. The response gets a
+// Content-Language header.
+func Middleware(config ...MiddlewareConfig) fiber.Handler {
+	cfg := MiddlewareConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	return func(c *fiber.Ctx) error {
+		locale := ""
+		if cfg.QueryParam != "" {
+			locale = match(c.Query(cfg.QueryParam))
+		}
+		if locale == "" && cfg.Cookie != "" {
+			locale = match(c.Cookies(cfg.Cookie))
+		}
+		if locale == "" {
+			locale = MatchLocale(c.Get(fiber.HeaderAcceptLanguage))
+			c.Vary(fiber.HeaderAcceptLanguage)
+		}
+
+		c.Locals(localsKey, locale)
+		c.SetUserContext(WithLocale(c.UserContext(), locale))
+		c.Set(fiber.HeaderContentLanguage, locale)
+		return c.Next()
+	}
+}
+
+// match returns the supported locale of a language tag, falling back from a
+// regional tag to its base language, or "" when it is not supported
+func match(tag string) string {
+	tag = normalizeLocale(tag)
+	if tag == "" {
+		return ""
+	}
+	base, _, _ := strings.Cut(tag, "-")
+
+	found := ""
+	for _, locale := range Locales() {
+		if locale == tag {
+			return tag
+		}
+		if locale == base {
+			found = base
+		}
+	}
+	return found
+}
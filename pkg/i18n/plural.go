@@ -0,0 +1,73 @@
+package i18n
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+)
+
+// pluralForms are the CLDR plural categories messages can have forms for
+var pluralForms = map[string]bool{
+	"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true,
+}
+
+var (
+	pluralMu    sync.RWMutex
+	pluralRules = map[string]locales.Translator{}
+)
+
+func init() {
+	RegisterPluralRules(en.New())
+	RegisterPluralRules(id.New())
+}
+
+// RegisterPluralRules sets how a locale chooses plural forms, from its
+// github.com/go-playground/locales package:
+//
+//	i18n.RegisterPluralRules(ru.New())
+//
+// English and Indonesian are registered by default. Locales without rules
+// use those of their base language, or the English ones.
+func RegisterPluralRules(translator locales.Translator) {
+	pluralMu.Lock()
+	defer pluralMu.Unlock()
+	pluralRules[normalizeLocale(translator.Locale())] = translator
+}
+
+// pluralForm returns the plural form of a count in a locale, "other" when the
+// count is not a number
+func pluralForm(locale, count string) string {
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil {
+		return "other"
+	}
+	var digits uint64
+	if _, fraction, ok := strings.Cut(count, "."); ok {
+		digits = uint64(len(fraction))
+	}
+
+	rule := pluralRule(normalizeLocale(locale)).CardinalPluralRule(n, digits)
+	if rule == locales.PluralRuleUnknown {
+		return "other"
+	}
+	return strings.ToLower(rule.String())
+}
+
+// pluralRule returns the plural rules of a locale, its base language, or English
+func pluralRule(locale string) locales.Translator {
+	pluralMu.RLock()
+	defer pluralMu.RUnlock()
+	if t, ok := pluralRules[locale]; ok {
+		return t
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if t, ok := pluralRules[base]; ok {
+			return t
+		}
+	}
+	return pluralRules["en"]
+}
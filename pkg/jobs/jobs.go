@@ -0,0 +1,134 @@
+// Package jobs runs background jobs from a queue kept in memory or in Redis,
+// retrying failed jobs with backoff
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// DefaultQueue is the queue of jobs enqueued without one
+const DefaultQueue = "default"
+
+// Job is a unit of background work
+type Job struct {
+	ID          string          `json:"id"`
+	Queue       string          `json:"queue"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	RunAt       time.Time       `json:"runAt"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	LastError   string          `json:"lastError,omitempty"`
+
+	// Trace holds the trace context of the code that enqueued the job, so
+	// its runs continue that trace
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// Decode decodes the payload of the job into v
+func (j *Job) Decode(v interface{}) error {
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Handler runs a job. Returned errors retry the job until it runs out of
+// attempts, unless they are permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// Handle adapts a function taking a decoded payload to a Handler:
+//
+//	queue.Register("thumbnail", jobs.Handle(func(ctx context.Context, p ThumbnailPayload) error {
+//		return thumbnails.Create(ctx, p.Path)
+//	}))
+func Handle[T any](fn func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, job *Job) error {
+		var payload T
+		if err := job.Decode(&payload); err != nil {
+			return Permanent(err)
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Backend stores jobs, e.g. NewMemory or NewRedis
+type Backend interface {
+	// Enqueue adds a job to run at its RunAt time
+	Enqueue(ctx context.Context, job *Job) error
+
+	// Dequeue claims the next job of a queue that is due, or returns nil when
+	// there is none
+	Dequeue(ctx context.Context, queue string) (*Job, error)
+
+	// Complete removes a finished job
+	Complete(ctx context.Context, job *Job) error
+
+	// Retry puts a failed job back to run at a time
+	Retry(ctx context.Context, job *Job, at time.Time) error
+
+	// Fail moves a job out of attempts to the dead jobs
+	Fail(ctx context.Context, job *Job) error
+}
+
+// Option sets an option of an enqueued job
+type Option func(job *Job)
+
+// OnQueue enqueues a job on a queue instead of DefaultQueue
+func OnQueue(queue string) Option {
+	return func(job *Job) { job.Queue = queue }
+}
+
+// Delay runs a job after a delay
+func Delay(d time.Duration) Option {
+	return func(job *Job) { job.RunAt = job.RunAt.Add(d) }
+}
+
+// At runs a job at a time
+func At(t time.Time) Option {
+	return func(job *Job) { job.RunAt = t }
+}
+
+// MaxAttempts sets how many times a job runs before it is moved to the dead
+// jobs
+func MaxAttempts(n int) Option {
+	return func(job *Job) { job.MaxAttempts = n }
+}
+
+// permanentError is an error that is not retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error so the job fails without further attempts, e.g.
+// when the payload is invalid
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// isPermanent reports whether an error should not be retried: errors marked
+// with Permanent and AppErrors for bad input such as VALIDATION_ERROR, which
+// fail the same way every time
+func isPermanent(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return true
+	}
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.HTTPCode == http.StatusBadRequest || appErr.HTTPCode == http.StatusUnprocessableEntity
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps jobs in process, for tests and single-instance apps. Jobs are
+// lost when the process exits.
+type Memory struct {
+	mu     sync.Mutex
+	queues map[string][]*Job
+	dead   []*Job
+}
+
+// NewMemory creates an empty memory backend
+func NewMemory() *Memory {
+	return &Memory{queues: make(map[string][]*Job)}
+}
+
+// Enqueue adds a job to run at its RunAt time
+func (m *Memory) Enqueue(_ context.Context, job *Job) error {
+	m.push(job)
+	return nil
+}
+
+// Dequeue claims the next job of a queue that is due, or returns nil when
+// there is none
+func (m *Memory) Dequeue(_ context.Context, queue string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	jobs := m.queues[queue]
+	next := -1
+	for i, job := range jobs {
+		if !job.RunAt.After(now) && (next < 0 || job.RunAt.Before(jobs[next].RunAt)) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil, nil
+	}
+
+	job := jobs[next]
+	m.queues[queue] = append(jobs[:next], jobs[next+1:]...)
+	return job, nil
+}
+
+// Complete removes a finished job
+func (m *Memory) Complete(_ context.Context, _ *Job) error {
+	return nil
+}
+
+// Retry puts a failed job back to run at a time
+func (m *Memory) Retry(_ context.Context, job *Job, at time.Time) error {
+	job.RunAt = at
+	m.push(job)
+	return nil
+}
+
+// Fail moves a job out of attempts to the dead jobs
+func (m *Memory) Fail(_ context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *job
+	m.dead = append(m.dead, &copied)
+	return nil
+}
+
+// Len returns the number of jobs waiting in a queue, including delayed ones
+func (m *Memory) Len(queue string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queues[queue])
+}
+
+// Dead returns the jobs that ran out of attempts
+func (m *Memory) Dead() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dead := make([]Job, len(m.dead))
+	for i, job := range m.dead {
+		dead[i] = *job
+	}
+	return dead
+}
+
+// push adds a copy of a job to its queue
+func (m *Memory) push(job *Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *job
+	m.queues[job.Queue] = append(m.queues[job.Queue], &copied)
+}
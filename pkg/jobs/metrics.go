@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/metrics"
+)
+
+// MetricsBackend records the jobs going through a backend:
+//
+//	jobs_enqueued_total{queue, type}              jobs enqueued
+//	jobs_processed_total{queue, type, result}     attempts, with result "completed", "retried", or "failed"
+//	jobs_duration_seconds{queue, type}            time from taking a job to its outcome
+//	jobs_wait_seconds{queue, type}                time jobs wait past their RunAt to be taken
+type MetricsBackend struct {
+	backend   Backend
+	enqueued  *metrics.Counter
+	processed *metrics.Counter
+	duration  *metrics.Timer
+	wait      *metrics.Timer
+
+	mu      sync.Mutex
+	started map[string]time.Time
+}
+
+// NewMetricsBackend wraps a backend to record its jobs in a registry,
+// defaulting to metrics.Default():
+//
+//	queue := jobs.New(jobs.NewMetricsBackend(jobs.NewRedis(redis, "jobs"), nil))
+func NewMetricsBackend(backend Backend, r *metrics.Registry) *MetricsBackend {
+	if r == nil {
+		r = metrics.Default()
+	}
+	return &MetricsBackend{
+		backend:   backend,
+		enqueued:  r.Counter("jobs_enqueued_total", "Jobs enqueued", "queue", "type"),
+		processed: r.Counter("jobs_processed_total", "Job attempts by result", "queue", "type", "result"),
+		duration:  r.Timer("jobs_duration_seconds", "Job run time in seconds", "queue", "type"),
+		wait:      r.Timer("jobs_wait_seconds", "Time jobs wait to be taken after they are due, in seconds", "queue", "type"),
+		started:   make(map[string]time.Time),
+	}
+}
+
+// Enqueue adds a job to run at its RunAt time
+func (b *MetricsBackend) Enqueue(ctx context.Context, job *Job) error {
+	if err := b.backend.Enqueue(ctx, job); err != nil {
+		return err
+	}
+	b.enqueued.Inc(job.Queue, job.Type)
+	return nil
+}
+
+// Dequeue claims the next job of a queue that is due
+func (b *MetricsBackend) Dequeue(ctx context.Context, queue string) (*Job, error) {
+	job, err := b.backend.Dequeue(ctx, queue)
+	if err != nil || job == nil {
+		return job, err
+	}
+
+	now := time.Now()
+	wait := now.Sub(job.RunAt)
+	if wait < 0 {
+		wait = 0
+	}
+	b.wait.Observe(wait, job.Queue, job.Type)
+	b.mu.Lock()
+	b.started[job.ID] = now
+	b.mu.Unlock()
+	return job, nil
+}
+
+// Complete removes a finished job
+func (b *MetricsBackend) Complete(ctx context.Context, job *Job) error {
+	b.done(job, "completed")
+	return b.backend.Complete(ctx, job)
+}
+
+// Retry puts a failed job back to run at a time
+func (b *MetricsBackend) Retry(ctx context.Context, job *Job, at time.Time) error {
+	b.done(job, "retried")
+	return b.backend.Retry(ctx, job, at)
+}
+
+// Fail moves a job out of attempts to the dead jobs
+func (b *MetricsBackend) Fail(ctx context.Context, job *Job) error {
+	b.done(job, "failed")
+	return b.backend.Fail(ctx, job)
+}
+
+// done records the outcome of an attempt of a job
+func (b *MetricsBackend) done(job *Job, result string) {
+	b.processed.Inc(job.Queue, job.Type, result)
+
+	b.mu.Lock()
+	start, ok := b.started[job.ID]
+	delete(b.started, job.ID)
+	b.mu.Unlock()
+	if ok {
+		b.duration.Since(start, job.Queue, job.Type)
+	}
+}
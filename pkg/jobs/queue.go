@@ -0,0 +1,358 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	mathrand "math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/lifecycle"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultConcurrency is how many jobs run at once by default
+	DefaultConcurrency = 10
+
+	// DefaultPollInterval is how often idle workers check for due jobs
+	DefaultPollInterval = time.Second
+
+	// DefaultTimeout limits how long a job runs by default
+	DefaultTimeout = 5 * time.Minute
+
+	// DefaultMaxAttempts is how many times a job runs by default
+	DefaultMaxAttempts = 5
+)
+
+// Config configures a queue
+type Config struct {
+	// Queues are the queues workers take jobs from, in order of priority,
+	// defaulting to DefaultQueue
+	Queues []string
+
+	// Concurrency is how many jobs run at once, defaulting to DefaultConcurrency
+	Concurrency int
+
+	// PollInterval is how often idle workers check for due jobs, defaulting
+	// to DefaultPollInterval
+	PollInterval time.Duration
+
+	// Timeout limits how long a job runs, defaulting to DefaultTimeout
+	Timeout time.Duration
+
+	// MaxAttempts is how many times a job runs unless it was enqueued with
+	// MaxAttempts, defaulting to DefaultMaxAttempts
+	MaxAttempts int
+
+	// Backoff returns the delay before a job runs again after failing its
+	// nth attempt, defaulting to ExponentialBackoff
+	Backoff func(attempt int) time.Duration
+
+	// Logger logs failed jobs, defaulting to the logger named "jobs"
+	Logger *logger.Logger
+}
+
+// Queue enqueues jobs and runs them with a pool of workers
+type Queue struct {
+	backend Backend
+	config  Config
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	wake       chan struct{}
+	stop       chan struct{}
+	cancel     context.CancelFunc
+	workers    sync.WaitGroup
+	started    bool
+	registered bool
+}
+
+// New creates a queue storing jobs in a backend
+func New(backend Backend, config ...Config) *Queue {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if len(cfg.Queues) == 0 {
+		cfg.Queues = []string{DefaultQueue}
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = ExponentialBackoff
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("jobs")
+	}
+
+	return &Queue{
+		backend:  backend,
+		config:   cfg,
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler of a job type
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Enqueue adds a job of a type with a payload encoded as JSON. It runs as
+// soon as a worker is free unless delayed with Delay or At.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts ...Option) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: encode payload: %w", err)
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        newID(),
+		Queue:     DefaultQueue,
+		Type:      jobType,
+		Payload:   data,
+		RunAt:     now,
+		CreatedAt: now,
+		Trace:     tracing.Inject(ctx),
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = q.config.MaxAttempts
+	}
+
+	if err := q.backend.Enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+	q.notify()
+	return job, nil
+}
+
+// Start starts the workers, which run until Shutdown is called. The queue
+// registers with the default lifecycle manager, which shuts it down with the
+// app, after the HTTP server.
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	if !q.registered {
+		q.registered = true
+		lifecycle.Register("jobs", q)
+	}
+	q.started = true
+	q.stop = make(chan struct{})
+
+	var ctx context.Context
+	ctx, q.cancel = context.WithCancel(context.Background())
+	for i := 0; i < q.config.Concurrency; i++ {
+		q.workers.Add(1)
+		go q.work(ctx)
+	}
+}
+
+// Shutdown stops taking jobs and waits for the running ones to finish. When
+// ctx is done first, the running jobs are cancelled and put back to run
+// again.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return nil
+	}
+	q.started = false
+	close(q.stop)
+	cancel := q.cancel
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		cancel()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// notify wakes an idle worker
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work takes and runs jobs until the queue stops
+func (q *Queue) work(ctx context.Context) {
+	defer q.workers.Done()
+
+	timer := time.NewTimer(q.config.PollInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		if q.next(ctx) {
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(q.config.PollInterval)
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// next runs the next due job, reporting whether there was one
+func (q *Queue) next(ctx context.Context) bool {
+	for _, queue := range q.config.Queues {
+		job, err := q.backend.Dequeue(ctx, queue)
+		if err != nil {
+			if ctx.Err() == nil {
+				q.config.Logger.Errorf("Failed to take a job from queue %s: %v", queue, err)
+			}
+			return false
+		}
+		if job != nil {
+			q.process(ctx, job)
+			return true
+		}
+	}
+	return false
+}
+
+// process runs a job in a span and completes, retries, or fails it
+func (q *Queue) process(ctx context.Context, job *Job) {
+	job.Attempts++
+	spanCtx, span := tracing.Tracer().Start(tracing.Extract(ctx, job.Trace), "job "+job.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.id", job.ID),
+			attribute.String("job.type", job.Type),
+			attribute.String("job.queue", job.Queue),
+			attribute.Int("job.attempt", job.Attempts),
+		),
+	)
+	log := q.config.Logger.Ctx(spanCtx).WithFields(logger.Fields{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"queue":    job.Queue,
+		"attempt":  job.Attempts,
+	})
+
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	var err error
+	if ok {
+		err = q.run(logger.WithContext(spanCtx, log), job, handler)
+	} else {
+		err = Permanent(fmt.Errorf("jobs: no handler for job type %q", job.Type))
+	}
+	tracing.End(span, err)
+
+	// Persist the outcome even while shutting down
+	bg := context.Background()
+	switch {
+	case err == nil:
+		err = q.backend.Complete(bg, job)
+
+	case ctx.Err() != nil:
+		// Cancelled by Shutdown: run again without counting the attempt
+		job.Attempts--
+		err = q.backend.Retry(bg, job, time.Now())
+
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		job.LastError = err.Error()
+		log.Errorf("Job failed after %d attempts: %v", job.Attempts, err)
+		err = q.backend.Fail(bg, job)
+
+	default:
+		job.LastError = err.Error()
+		delay := q.config.Backoff(job.Attempts)
+		log.Warnf("Job failed, retrying in %s: %v", delay, err)
+		err = q.backend.Retry(bg, job, time.Now().Add(delay))
+	}
+	if err != nil {
+		log.Errorf("Failed to update job: %v", err)
+	}
+}
+
+// run calls a handler with the job timeout, turning panics into errors
+func (q *Queue) run(ctx context.Context, job *Job, handler Handler) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, q.config.Timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Errorf("Job panicked: %v\n%s", r, debug.Stack())
+			appErr := errors.InternalServerError("")
+			appErr.Internal = fmt.Errorf("panic: %v", r)
+			err = appErr
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// ExponentialBackoff waits 1s after the first failed attempt and doubles the
+// delay after each further one up to an hour, varying it by up to 20% so
+// failed jobs do not all run again at once
+func ExponentialBackoff(attempt int) time.Duration {
+	delay := time.Duration(math.Min(math.Pow(2, float64(attempt-1)), 3600)) * time.Second
+	jitter := time.Duration(mathrand.Int63n(int64(delay)/5 + 1))
+	return delay - delay/10 + jitter
+}
+
+// newID returns a random job ID
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
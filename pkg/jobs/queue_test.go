@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apperrors "github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// startQueue starts a queue with one worker polling often, shut down when
+// the test ends
+func startQueue(t *testing.T, backend Backend, config Config) *Queue {
+	t.Helper()
+	config.Concurrency = 1
+	config.PollInterval = 5 * time.Millisecond
+	queue := New(backend, config)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		queue.Shutdown(ctx)
+	})
+	queue.Start()
+	return queue
+}
+
+// waitFor waits up to a second for a condition
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	var mu sync.Mutex
+	var backoffs []int
+	var attempts []int
+	done := make(chan struct{})
+
+	queue := startQueue(t, NewMemory(), Config{Backoff: func(attempt int) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		backoffs = append(backoffs, attempt)
+		return 0
+	}})
+	queue.Register("flaky", func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, job.Attempts)
+		if job.Attempts < 3 {
+			return errors.New("unavailable")
+		}
+		if job.LastError != "unavailable" {
+			t.Errorf("Expected the last error to be kept, got %q", job.LastError)
+		}
+		close(done)
+		return nil
+	})
+	if _, err := queue.Enqueue(context.Background(), "flaky", nil); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the job to succeed")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 || attempts[2] != 3 {
+		t.Errorf("Expected three attempts, got %v", attempts)
+	}
+	if len(backoffs) != 2 || backoffs[0] != 1 || backoffs[1] != 2 {
+		t.Errorf("Expected a backoff after attempts 1 and 2, got %v", backoffs)
+	}
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	tests := []struct {
+		name        string
+		jobType     string
+		maxAttempts int
+		err         error
+		attempts    int
+		lastError   string
+	}{
+		{"out of attempts", "failing", 3, errors.New("unavailable"), 3, "unavailable"},
+		{"permanent error", "failing", 3, Permanent(errors.New("bad payload")), 1, "bad payload"},
+		{"bad request", "failing", 3, apperrors.BadRequestError("missing field"), 1, "missing field"},
+		{"panic", "panicking", 2, nil, 2, "panic: boom"},
+		{"no handler", "unknown", 3, nil, 1, `jobs: no handler for job type "unknown"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NewMemory()
+			queue := startQueue(t, backend, Config{Backoff: func(int) time.Duration { return 0 }})
+			queue.Register("failing", func(context.Context, *Job) error { return tt.err })
+			queue.Register("panicking", func(context.Context, *Job) error { panic("boom") })
+
+			job, err := queue.Enqueue(context.Background(), tt.jobType, map[string]int{"id": 1}, MaxAttempts(tt.maxAttempts))
+			if err != nil {
+				t.Fatalf("Enqueue failed: %v", err)
+			}
+			waitFor(t, "the dead job", func() bool { return len(backend.Dead()) == 1 })
+
+			dead := backend.Dead()[0]
+			if dead.ID != job.ID || dead.Attempts != tt.attempts || !strings.Contains(dead.LastError, tt.lastError) {
+				t.Errorf("Expected job %s dead after %d attempts with %q, got %+v", job.ID, tt.attempts, tt.lastError, dead)
+			}
+			if backend.Len(DefaultQueue) != 0 {
+				t.Errorf("Expected the dead job to leave the queue, got %d jobs", backend.Len(DefaultQueue))
+			}
+		})
+	}
+}
+
+func TestQueueDelay(t *testing.T) {
+	backend := NewMemory()
+	queue := New(backend)
+	ctx := context.Background()
+	if _, err := queue.Enqueue(ctx, "later", nil, Delay(time.Hour), OnQueue("mail")); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if job, _ := backend.Dequeue(ctx, "mail"); job != nil {
+		t.Errorf("Expected the delayed job not to be due, got %+v", job)
+	}
+	if backend.Len("mail") != 1 {
+		t.Errorf("Expected the delayed job to wait in its queue, got %d jobs", backend.Len("mail"))
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{13, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := ExponentialBackoff(tt.attempt); got < tt.base-tt.base/10 || got > tt.base+tt.base/10 {
+				t.Errorf("ExponentialBackoff(%d) = %s, want %s ±10%%", tt.attempt, got, tt.base)
+			}
+		}
+	}
+}
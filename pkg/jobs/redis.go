@@ -0,0 +1,218 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+)
+
+// DefaultVisibilityTimeout is how long a job taken by a worker stays hidden
+// from other workers by default
+const DefaultVisibilityTimeout = 30 * time.Minute
+
+// Redis keeps jobs in Redis, shared by every instance of an app. Each queue
+// is a sorted set of job IDs by run time. Jobs taken by a worker that stops
+// without finishing them, e.g. because its process crashed, run again after
+// the visibility timeout.
+type Redis struct {
+	client     cache.RedisClient
+	prefix     string
+	visibility time.Duration
+}
+
+// NewRedis creates a Redis backend whose keys start with prefix, e.g.
+// "myapp:", using a client such as cache.DialRedis
+func NewRedis(client cache.RedisClient, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix, visibility: DefaultVisibilityTimeout}
+}
+
+// SetVisibilityTimeout sets how long a taken job stays hidden from other
+// workers. It must be longer than the job timeout of the queue.
+func (r *Redis) SetVisibilityTimeout(d time.Duration) *Redis {
+	r.visibility = d
+	return r
+}
+
+// Enqueue adds a job to run at its RunAt time
+func (r *Redis) Enqueue(ctx context.Context, job *Job) error {
+	if err := r.save(ctx, job); err != nil {
+		return err
+	}
+	_, err := r.client.Do(ctx, "ZADD", r.queueKey(job.Queue), millis(job.RunAt), job.ID)
+	return err
+}
+
+// Dequeue claims the next job of a queue that is due, or returns nil when
+// there is none
+func (r *Redis) Dequeue(ctx context.Context, queue string) (*Job, error) {
+	if err := r.requeueExpired(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reply, err := r.client.Do(ctx, "ZRANGEBYSCORE", r.queueKey(queue), "-inf", millis(now), "LIMIT", 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	ids := replyStrings(reply)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	id := ids[0]
+
+	// Removing the ID claims the job; another worker may have been first
+	reply, err = r.client.Do(ctx, "ZREM", r.queueKey(queue), id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := reply.(int64); n == 0 {
+		return nil, nil
+	}
+	if _, err := r.client.Do(ctx, "ZADD", r.key("processing"), millis(now.Add(r.visibility)), id); err != nil {
+		return nil, err
+	}
+
+	job, err := r.load(ctx, id)
+	if err != nil || job == nil {
+		_, _ = r.client.Do(ctx, "ZREM", r.key("processing"), id)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Complete removes a finished job
+func (r *Redis) Complete(ctx context.Context, job *Job) error {
+	if _, err := r.client.Do(ctx, "ZREM", r.key("processing"), job.ID); err != nil {
+		return err
+	}
+	_, err := r.client.Do(ctx, "DEL", r.jobKey(job.ID))
+	return err
+}
+
+// Retry puts a failed job back to run at a time
+func (r *Redis) Retry(ctx context.Context, job *Job, at time.Time) error {
+	job.RunAt = at
+	if err := r.save(ctx, job); err != nil {
+		return err
+	}
+	if _, err := r.client.Do(ctx, "ZADD", r.queueKey(job.Queue), millis(at), job.ID); err != nil {
+		return err
+	}
+	_, err := r.client.Do(ctx, "ZREM", r.key("processing"), job.ID)
+	return err
+}
+
+// Fail moves a job out of attempts to the dead jobs, a sorted set of job IDs
+// by failure time
+func (r *Redis) Fail(ctx context.Context, job *Job) error {
+	if err := r.save(ctx, job); err != nil {
+		return err
+	}
+	if _, err := r.client.Do(ctx, "ZADD", r.key("dead"), millis(time.Now()), job.ID); err != nil {
+		return err
+	}
+	_, err := r.client.Do(ctx, "ZREM", r.key("processing"), job.ID)
+	return err
+}
+
+// requeueExpired puts back the jobs whose worker did not finish them in time
+func (r *Redis) requeueExpired(ctx context.Context) error {
+	reply, err := r.client.Do(ctx, "ZRANGEBYSCORE", r.key("processing"), "-inf", millis(time.Now()), "LIMIT", 0, 100)
+	if err != nil {
+		return err
+	}
+	for _, id := range replyStrings(reply) {
+		reply, err := r.client.Do(ctx, "ZREM", r.key("processing"), id)
+		if err != nil {
+			return err
+		}
+		if n, _ := reply.(int64); n == 0 {
+			continue
+		}
+		job, err := r.load(ctx, id)
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			continue
+		}
+		if _, err := r.client.Do(ctx, "ZADD", r.queueKey(job.Queue), millis(time.Now()), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save stores the data of a job
+func (r *Redis) save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.Do(ctx, "SET", r.jobKey(job.ID), data)
+	return err
+}
+
+// load returns the data of a job, or nil when it is missing
+func (r *Redis) load(ctx context.Context, id string) (*Job, error) {
+	reply, err := r.client.Do(ctx, "GET", r.jobKey(id))
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch v := reply.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, fmt.Errorf("jobs: unexpected reply %T to GET", reply)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("jobs: decode job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+// key returns a key of the backend
+func (r *Redis) key(name string) string {
+	return r.prefix + "jobs:" + name
+}
+
+// queueKey returns the key of the sorted set of a queue
+func (r *Redis) queueKey(queue string) string {
+	return r.key("queue:" + queue)
+}
+
+// jobKey returns the key of the data of a job
+func (r *Redis) jobKey(id string) string {
+	return r.key("job:" + id)
+}
+
+// millis returns a time in milliseconds since the epoch, used as scores
+func millis(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// replyStrings converts an array reply of bulk strings
+func replyStrings(reply interface{}) []string {
+	items, _ := reply.([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case []byte:
+			values = append(values, string(v))
+		case string:
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis runs the commands of the Redis backend on maps, one at a time as
+// a Redis server does
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	zsets  map[string]map[string]float64
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string][]byte), zsets: make(map[string]map[string]float64)}
+}
+
+func (f *fakeRedis) Do(_ context.Context, args ...interface{}) (interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := make([]string, len(args))
+	for i, arg := range args {
+		if b, ok := arg.([]byte); ok {
+			arg = string(b)
+		}
+		s[i] = fmt.Sprint(arg)
+	}
+
+	switch s[0] {
+	case "GET":
+		if value, ok := f.values[s[1]]; ok {
+			return value, nil
+		}
+		return nil, nil
+	case "SET":
+		f.values[s[1]] = []byte(s[2])
+		return "OK", nil
+	case "DEL":
+		delete(f.values, s[1])
+		return int64(1), nil
+	case "ZADD":
+		score, _ := strconv.ParseFloat(s[2], 64)
+		if f.zsets[s[1]] == nil {
+			f.zsets[s[1]] = make(map[string]float64)
+		}
+		f.zsets[s[1]][s[3]] = score
+		return int64(1), nil
+	case "ZREM":
+		if _, ok := f.zsets[s[1]][s[2]]; !ok {
+			return int64(0), nil
+		}
+		delete(f.zsets[s[1]], s[2])
+		return int64(1), nil
+	case "ZRANGEBYSCORE":
+		// ZRANGEBYSCORE key -inf max LIMIT offset count
+		max, _ := strconv.ParseFloat(s[3], 64)
+		count, _ := strconv.Atoi(s[6])
+		var members []string
+		for member, score := range f.zsets[s[1]] {
+			if score <= max {
+				members = append(members, member)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool {
+			a, b := f.zsets[s[1]][members[i]], f.zsets[s[1]][members[j]]
+			return a < b || a == b && members[i] < members[j]
+		})
+		reply := []interface{}{}
+		for i := 0; i < len(members) && i < count; i++ {
+			reply = append(reply, []byte(members[i]))
+		}
+		return reply, nil
+	}
+	return nil, errors.New("unknown command " + s[0])
+}
+
+// members returns the members of a sorted set
+func (f *fakeRedis) members(key string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var members []string
+	for member := range f.zsets[key] {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func TestRedisBackend(t *testing.T) {
+	fake := newFakeRedis()
+	backend := NewRedis(fake, "app:")
+	ctx := context.Background()
+	now := time.Now()
+
+	due := &Job{ID: "due", Queue: "mail", Type: "send", RunAt: now.Add(-time.Second), MaxAttempts: 3}
+	later := &Job{ID: "later", Queue: "mail", Type: "send", RunAt: now.Add(time.Hour), MaxAttempts: 3}
+	for _, job := range []*Job{later, due} {
+		if err := backend.Enqueue(ctx, job); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	job, err := backend.Dequeue(ctx, "mail")
+	if err != nil || job == nil || job.ID != "due" || job.Type != "send" {
+		t.Fatalf("Expected the due job, got %+v and %v", job, err)
+	}
+	if job, err := backend.Dequeue(ctx, "mail"); err != nil || job != nil {
+		t.Fatalf("Expected no due job, got %+v and %v", job, err)
+	}
+	if got := fake.members("app:jobs:processing"); len(got) != 1 || got[0] != "due" {
+		t.Errorf("Expected the taken job to be processing, got %v", got)
+	}
+
+	// A retried job keeps its attempts and error
+	job.Attempts, job.LastError = 1, "unavailable"
+	if err := backend.Retry(ctx, job, now.Add(-time.Millisecond)); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	job, err = backend.Dequeue(ctx, "mail")
+	if err != nil || job == nil || job.Attempts != 1 || job.LastError != "unavailable" {
+		t.Fatalf("Expected the retried job, got %+v and %v", job, err)
+	}
+
+	if err := backend.Fail(ctx, job); err != nil {
+		t.Fatalf("Fail failed: %v", err)
+	}
+	if got := fake.members("app:jobs:dead"); len(got) != 1 || got[0] != "due" {
+		t.Errorf("Expected the failed job to be dead, got %v", got)
+	}
+	if got := fake.members("app:jobs:processing"); len(got) != 0 {
+		t.Errorf("Expected the failed job to leave processing, got %v", got)
+	}
+
+	if err := backend.Complete(ctx, later); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if _, ok := fake.values["app:jobs:job:later"]; ok {
+		t.Error("Expected the completed job to be deleted")
+	}
+}
+
+func TestRedisBackendVisibilityTimeout(t *testing.T) {
+	fake := newFakeRedis()
+	backend := NewRedis(fake, "app:").SetVisibilityTimeout(-time.Second)
+	ctx := context.Background()
+	if err := backend.Enqueue(ctx, &Job{ID: "crashed", Queue: DefaultQueue, RunAt: time.Now()}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	// The worker taking the job never finishes it, so the job is hidden
+	// until its visibility timeout, already over here, and then taken again
+	for i := 0; i < 2; i++ {
+		job, err := backend.Dequeue(ctx, DefaultQueue)
+		if err != nil || job == nil || job.ID != "crashed" {
+			t.Fatalf("Expected take %d to return the job, got %+v and %v", i+1, job, err)
+		}
+	}
+}
+
+func TestQueueWithRedisBackend(t *testing.T) {
+	fake := newFakeRedis()
+	queue := startQueue(t, NewRedis(fake, "app:"), Config{Backoff: func(int) time.Duration { return 0 }})
+	queue.Register("failing", func(context.Context, *Job) error { return errors.New("unavailable") })
+
+	job, err := queue.Enqueue(context.Background(), "failing", nil, MaxAttempts(2))
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	waitFor(t, "the dead job", func() bool { return len(fake.members("app:jobs:dead")) == 1 })
+
+	dead, err := NewRedis(fake, "app:").load(context.Background(), job.ID)
+	if err != nil || dead == nil || dead.Attempts != 2 || dead.LastError != "unavailable" {
+		t.Errorf("Expected the job dead after 2 attempts, got %+v and %v", dead, err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package lifecycle shuts down the components of a service in order when it
+// is asked to stop, so requests, uploads, and jobs in flight finish first
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// DefaultTimeout limits the whole shutdown by default
+const DefaultTimeout = 30 * time.Second
+
+// Shutdowner is a component that stops gracefully, such as an app, a job
+// queue, a scheduler, a WebSocket hub, or a storage provider. It returns
+// ctx.Err() when ctx is done before it has stopped.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Func adapts a function to a Shutdowner
+type Func func(ctx context.Context) error
+
+// Shutdown calls f
+func (f Func) Shutdown(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Closer adapts an io.Closer, such as a *sql.DB, to a Shutdowner
+func Closer(c io.Closer) Shutdowner {
+	return Func(func(context.Context) error {
+		return c.Close()
+	})
+}
+
+// Config configures a manager
+type Config struct {
+	// Timeout limits the whole shutdown, defaulting to DefaultTimeout
+	Timeout time.Duration
+
+	// Logger defaults to the logger named "lifecycle"
+	Logger *logger.Logger
+}
+
+// Manager shuts down registered components in reverse order of
+// registration, so components started last, such as the HTTP server, stop
+// before those they depend on, such as storage
+type Manager struct {
+	config Config
+
+	mu         sync.Mutex
+	components []component
+
+	stopOnce sync.Once
+	stopping chan struct{}
+	done     chan struct{}
+	err      error
+}
+
+type component struct {
+	name     string
+	closer   Shutdowner
+	timeout  time.Duration
+	shutdown bool
+}
+
+// New creates a manager
+func New(config ...Config) *Manager {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("lifecycle")
+	}
+	return &Manager{
+		config:   cfg,
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Register adds a component to shut down, limited to timeout when given,
+// and otherwise to what is left of the manager timeout. Components
+// registered during shutdown are shut down at its end.
+//
+//	m.Register("database", lifecycle.Closer(sqlDB))
+//	m.Register("jobs", queue, 20*time.Second)
+//	m.Register("http", app)
+func (m *Manager) Register(name string, closer Shutdowner, timeout ...time.Duration) {
+	c := component{name: name, closer: closer}
+	if len(timeout) > 0 {
+		c.timeout = timeout[0]
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, c)
+}
+
+// OnSignal shuts down when the process receives one of the signals,
+// defaulting to SIGINT and SIGTERM. A second signal stops the process at
+// once. Wait returns once the shutdown is done.
+func (m *Manager) OnSignal(signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+
+	go func() {
+		select {
+		case sig := <-received:
+			signal.Stop(received)
+			m.config.Logger.Infof("Received %s, shutting down", sig)
+			_ = m.Shutdown(context.Background())
+		case <-m.stopping:
+			signal.Stop(received)
+		}
+	}()
+}
+
+// Stopping returns a channel closed when the shutdown starts, e.g. for
+// loops to stop taking work
+func (m *Manager) Stopping() <-chan struct{} {
+	return m.stopping
+}
+
+// Wait waits for the shutdown to finish and returns its error
+func (m *Manager) Wait() error {
+	<-m.done
+	return m.err
+}
+
+// Shutdown shuts down the components in reverse order of registration,
+// within the manager timeout and until ctx is done. Every component is
+// asked to stop even when an earlier one fails or runs out of time. Later
+// calls wait for the first one and return its error.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	first := false
+	m.stopOnce.Do(func() {
+		first = true
+		close(m.stopping)
+	})
+	if !first {
+		select {
+		case <-m.done:
+			return m.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	var errs []error
+	for {
+		c, ok := m.next()
+		if !ok {
+			break
+		}
+		if err := m.stop(ctx, c); err != nil {
+			errs = append(errs, fmt.Errorf("lifecycle: %s: %w", c.name, err))
+		}
+	}
+	m.err = errors.Join(errs...)
+	close(m.done)
+	return m.err
+}
+
+// next returns the last registered component not yet shut down
+func (m *Manager) next() (component, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.components) - 1; i >= 0; i-- {
+		if !m.components[i].shutdown {
+			m.components[i].shutdown = true
+			return m.components[i], true
+		}
+	}
+	return component{}, false
+}
+
+// stop shuts down a component within its timeout
+func (m *Manager) stop(ctx context.Context, c component) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	m.config.Logger.Debugf("Stopping %s", c.name)
+	if err := c.closer.Shutdown(ctx); err != nil {
+		m.config.Logger.Errorf("Failed to stop %s after %s: %v", c.name, time.Since(start), err)
+		return err
+	}
+	m.config.Logger.Infof("Stopped %s in %s", c.name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+var defaultManager = New()
+
+// Default returns the manager job queues, schedulers, and storage providers
+// register with, and apps shut down
+func Default() *Manager {
+	return defaultManager
+}
+
+// Register adds a component to the default manager
+func Register(name string, closer Shutdowner, timeout ...time.Duration) {
+	defaultManager.Register(name, closer, timeout...)
+}
+
+// OnSignal shuts down the default manager when the process receives one of
+// the signals, defaulting to SIGINT and SIGTERM
+func OnSignal(signals ...os.Signal) {
+	defaultManager.OnSignal(signals...)
+}
+
+// Shutdown shuts down the components of the default manager
+func Shutdown(ctx context.Context) error {
+	return defaultManager.Shutdown(ctx)
+}
+
+// Wait waits for the default manager to shut down
+func Wait() error {
+	return defaultManager.Wait()
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultAsyncBufferSize is the queue size used when AsyncConfig.BufferSize is not set
+const DefaultAsyncBufferSize = 1024
+
+// AsyncConfig configures asynchronous logging
+type AsyncConfig struct {
+	// BufferSize is the number of entries queued for the background writer
+	BufferSize int
+
+	// DropWhenFull drops entries instead of blocking the caller when the
+	// queue is full. Dropped entries are counted by Logger.Dropped.
+	DropWhenFull bool
+}
+
+// asyncWriter writes queued entries from a background goroutine
+type asyncWriter struct {
+	mu           sync.RWMutex
+	queue        chan asyncItem
+	closed       bool
+	done         chan struct{}
+	dropWhenFull bool
+	dropped      atomic.Uint64
+}
+
+// asyncItem is a queued entry, or a flush marker when flushed is set
+type asyncItem struct {
+	logger  *Logger
+	entry   *Entry
+	flushed chan struct{}
+}
+
+// newAsyncWriter starts a background writer
+func newAsyncWriter(config AsyncConfig) *asyncWriter {
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultAsyncBufferSize
+	}
+
+	w := &asyncWriter{
+		queue:        make(chan asyncItem, config.BufferSize),
+		done:         make(chan struct{}),
+		dropWhenFull: config.DropWhenFull,
+	}
+	go w.run()
+	return w
+}
+
+// run writes entries until the queue is closed
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	for item := range w.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		item.logger.dispatchSync(item.entry)
+		releaseEntry(item.entry)
+	}
+}
+
+// enqueue queues an item, reporting false when the writer is closed
+func (w *asyncWriter) enqueue(item asyncItem) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return false
+	}
+
+	if w.dropWhenFull && item.flushed == nil {
+		select {
+		case w.queue <- item:
+		default:
+			w.dropped.Add(1)
+			releaseEntry(item.entry)
+		}
+		return true
+	}
+
+	w.queue <- item
+	return true
+}
+
+// flush waits until every entry queued before the call is written
+func (w *asyncWriter) flush() {
+	flushed := make(chan struct{})
+	if w.enqueue(asyncItem{flushed: flushed}) {
+		<-flushed
+	}
+}
+
+// close writes the remaining entries and stops the background writer
+func (w *asyncWriter) close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+}
+
+// EnableAsync writes entries from a background goroutine so logging calls do
+// not wait for the outputs. Child loggers created afterwards share the queue.
+// Field values are formatted after the call returns, so they must not be
+// modified once logged. Call Close before the program exits.
+func (l *Logger) EnableAsync(config AsyncConfig) {
+	l.mu.Lock()
+	previous := l.async
+	l.async = newAsyncWriter(config)
+	l.mu.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+}
+
+// Flush waits until queued entries are written. It is a no-op for synchronous loggers.
+func (l *Logger) Flush() {
+	if async := l.asyncWriter(); async != nil {
+		async.flush()
+	}
+}
+
+// Close writes the queued entries and stops the background writer. The
+// logger keeps working synchronously afterwards.
+func (l *Logger) Close() error {
+	if async := l.asyncWriter(); async != nil {
+		async.close()
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the async queue was full
+func (l *Logger) Dropped() uint64 {
+	async := l.asyncWriter()
+	if async == nil {
+		return 0
+	}
+	return async.dropped.Load()
+}
+
+// asyncWriter returns the background writer, if async logging is enabled
+func (l *Logger) asyncWriter() *asyncWriter {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.async
+}
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names added from the context
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+	FieldSpanID    = "span_id"
+)
+
+// fiberRequestIDKey is the key used by Fiber's requestid middleware. Fiber stores
+// locals on the fasthttp request context, which exposes them through Value.
+const fiberRequestIDKey = "requestid"
+
+// ContextExtractor returns fields to attach to entries logged with a context
+type ContextExtractor func(ctx context.Context) Fields
+
+type loggerContextKey struct{}
+type requestIDContextKey struct{}
+
+var (
+	defaultLogger   = NewLogger()
+	defaultLoggerMu sync.RWMutex
+
+	extractorsMu sync.RWMutex
+	extractors   []ContextExtractor
+)
+
+// Default returns the package default logger used by FromContext
+func Default() *Logger {
+	defaultLoggerMu.RLock()
+	defer defaultLoggerMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the package default logger
+func SetDefault(l *Logger) {
+	defaultLoggerMu.Lock()
+	defer defaultLoggerMu.Unlock()
+	defaultLogger = l
+}
+
+// WithContext returns a copy of ctx that carries the logger
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger, enriched
+// with the request ID and trace fields found in ctx
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(loggerContextKey{}).(*Logger)
+	if !ok || l == nil {
+		l = Default()
+	}
+	return l.Ctx(ctx)
+}
+
+// ContextWithRequestID returns a copy of ctx that carries the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDContextKey{}).(string); ok && id != "" {
+		return id
+	}
+	if id, ok := ctx.Value(fiberRequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// AddContextExtractor registers a function that adds fields from the context,
+// e.g. a tenant or user ID, to entries logged with a context
+func AddContextExtractor(extractor ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = append(extractors, extractor)
+}
+
+// Ctx returns a child logger with the request ID, trace and span IDs, and any
+// extractor fields found in ctx. It returns the logger itself when ctx has none.
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	added := contextFields(ctx)
+	if len(added) == 0 {
+		return l
+	}
+	return l.withFields(added)
+}
+
+// contextFields collects the request ID, trace fields, and extractor fields from ctx
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+
+	var fields []Field
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		fields = append(fields, Field{Key: FieldRequestID, Value: requestID})
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields,
+			Field{Key: FieldTraceID, Value: spanContext.TraceID().String()},
+			Field{Key: FieldSpanID, Value: spanContext.SpanID().String()},
+		)
+	}
+
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	for _, extractor := range extractors {
+		if extracted := extractor(ctx); len(extracted) > 0 {
+			fields = append(fields, mapToFields(extracted)...)
+		}
+	}
+
+	return fields
+}
+
+// DebugCtx logs a debug message with format and the fields from ctx
+func (l *Logger) DebugCtx(ctx context.Context, format string, args ...interface{}) {
+	l.Ctx(ctx).log(DEBUG, fmt.Sprintf(format, args...))
+}
+
+// InfoCtx logs an info message with format and the fields from ctx
+func (l *Logger) InfoCtx(ctx context.Context, format string, args ...interface{}) {
+	l.Ctx(ctx).log(INFO, fmt.Sprintf(format, args...))
+}
+
+// WarnCtx logs a warning message with format and the fields from ctx
+func (l *Logger) WarnCtx(ctx context.Context, format string, args ...interface{}) {
+	l.Ctx(ctx).log(WARN, fmt.Sprintf(format, args...))
+}
+
+// ErrorCtx logs an error message with format and the fields from ctx
+func (l *Logger) ErrorCtx(ctx context.Context, format string, args ...interface{}) {
+	l.Ctx(ctx).log(ERROR, fmt.Sprintf(format, args...))
+}
+
+// FatalCtx logs a fatal message with format and the fields from ctx
+func (l *Logger) FatalCtx(ctx context.Context, format string, args ...interface{}) {
+	l.Ctx(ctx).log(FATAL, fmt.Sprintf(format, args...))
+}
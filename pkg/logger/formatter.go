@@ -0,0 +1,352 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimestampFormat is the timestamp layout used by the text formatter
+const DefaultTimestampFormat = "2006-01-02 15:04:05.000"
+
+// Entry is a single log entry passed to a Formatter
+type Entry struct {
+	Time    time.Time
+	Level   LogLevel
+	File    string
+	Line    int
+	Prefix  string
+	Message string
+	Fields  []Field
+
+	// Structured is set for entries logged with the *j methods, which carry
+	// their content in Fields instead of Message
+	Structured bool
+
+	// pc is the program counter of the logging call site
+	pc uintptr
+}
+
+// Formatter renders log entries. The returned bytes include the trailing
+// newline. Entries are reused once written, so formatters must not keep them.
+type Formatter interface {
+	Format(e *Entry) ([]byte, error)
+}
+
+// FormatterByName returns the built-in formatter for a LOG_FORMAT value:
+// "text", "json", "logfmt", or "console"
+func FormatterByName(name string) (Formatter, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "text":
+		return &TextFormatter{}, true
+	case "json":
+		return &JSONFormatter{}, true
+	case "logfmt":
+		return &LogfmtFormatter{}, true
+	case "console", "color", "pretty":
+		return &ConsoleFormatter{DisableColors: os.Getenv("NO_COLOR") != ""}, true
+	default:
+		return nil, false
+	}
+}
+
+// TextFormatter renders entries in the default pipe-separated line format.
+// Entries logged with the *j methods are written as JSON objects, as before.
+type TextFormatter struct {
+	// TimestampFormat defaults to DefaultTimestampFormat
+	TimestampFormat string
+}
+
+// Format renders an entry as a text line
+func (f *TextFormatter) Format(e *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.formatTo(&buf, e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatTo renders an entry as a text line into buf
+func (f *TextFormatter) formatTo(buf *bytes.Buffer, e *Entry) error {
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = DefaultTimestampFormat
+	}
+
+	if e.Structured {
+		b, err := formatLegacyJSON(e, timestampFormat)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	}
+
+	buf.Write(e.Time.AppendFormat(buf.AvailableBuffer(), timestampFormat))
+	buf.WriteString(" | ")
+	buf.WriteString(e.Level.String())
+	buf.WriteString(" | ")
+	buf.WriteString(e.File)
+	buf.WriteByte(':')
+	buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(e.Line), 10))
+	buf.WriteString(" | ")
+	buf.WriteString(e.Prefix)
+	buf.WriteString(e.Message)
+	writeFields(buf, e.Fields)
+	buf.WriteByte('\n')
+	return nil
+}
+
+// formatLegacyJSON renders a *j entry as a JSON object with the metadata keys
+// merged into the caller's fields
+func formatLegacyJSON(e *Entry, timestampFormat string) ([]byte, error) {
+	j := make(map[string]interface{}, len(e.Fields)+5)
+	for _, field := range e.Fields {
+		if err, ok := field.Value.(error); ok {
+			j[field.Key] = err.Error()
+		} else {
+			j[field.Key] = field.Value
+		}
+	}
+
+	// Add metadata to JSON
+	j["timestamp"] = e.Time.Format(timestampFormat)
+	j["level"] = e.Level.String()
+	j["file"] = e.File
+	j["line"] = e.Line
+	if e.Prefix != "" {
+		j["prefix"] = e.Prefix
+	}
+
+	bytes, err := json.Marshal(j)
+	if err != nil {
+		return nil, err
+	}
+	return append(bytes, '\n'), nil
+}
+
+// JSONFormatter renders every entry as a single-line JSON object with the
+// same schema: timestamp, level, message, file, line, prefix (when set),
+// followed by the fields. Fields that clash with those keys are written
+// under "fields.<key>".
+type JSONFormatter struct {
+	// TimestampFormat defaults to time.RFC3339Nano
+	TimestampFormat string
+}
+
+// jsonReservedKeys are the keys written by JSONFormatter for every entry
+var jsonReservedKeys = map[string]bool{
+	"timestamp": true,
+	"level":     true,
+	"message":   true,
+	"file":      true,
+	"line":      true,
+	"prefix":    true,
+}
+
+// Format renders an entry as a JSON line
+func (f *JSONFormatter) Format(e *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.formatTo(&buf, e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatTo renders an entry as a JSON line into buf
+func (f *JSONFormatter) formatTo(buf *bytes.Buffer, e *Entry) error {
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = time.RFC3339Nano
+	}
+
+	buf.WriteByte('{')
+	writeJSONPair(buf, "timestamp", e.Time.Format(timestampFormat), true)
+	writeJSONPair(buf, "level", e.Level.String(), false)
+	writeJSONPair(buf, "message", e.Message, false)
+	writeJSONPair(buf, "file", e.File, false)
+	writeJSONPair(buf, "line", e.Line, false)
+	if e.Prefix != "" {
+		writeJSONPair(buf, "prefix", e.Prefix, false)
+	}
+
+	for _, field := range dedupeFields(e.Fields) {
+		key := field.Key
+		if jsonReservedKeys[key] {
+			key = "fields." + key
+		}
+		writeJSONPair(buf, key, field.Value, false)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// writeJSONPair writes a "key":value pair, falling back to the value's string
+// form when it cannot be marshaled
+func writeJSONPair(buf *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		buf.WriteByte(',')
+	}
+
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(v)
+}
+
+// dedupeFields keeps the last value for each key, in first-seen order
+func dedupeFields(fields []Field) []Field {
+	index := make(map[string]int, len(fields))
+	deduped := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		if i, exists := index[field.Key]; exists {
+			deduped[i].Value = field.Value
+			continue
+		}
+		index[field.Key] = len(deduped)
+		deduped = append(deduped, field)
+	}
+	return deduped
+}
+
+// LogfmtFormatter renders entries as logfmt key=value lines:
+//
+//	time=2024-01-02T15:04:05Z level=info caller=main.go:12 msg="started" port=8080
+type LogfmtFormatter struct {
+	// TimestampFormat defaults to time.RFC3339Nano
+	TimestampFormat string
+}
+
+// Format renders an entry as a logfmt line
+func (f *LogfmtFormatter) Format(e *Entry) ([]byte, error) {
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = time.RFC3339Nano
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("time=")
+	buf.WriteString(e.Time.Format(timestampFormat))
+	buf.WriteString(" level=")
+	buf.WriteString(strings.ToLower(e.Level.String()))
+	buf.WriteString(" caller=")
+	buf.WriteString(formatFieldValue(e.File + ":" + strconv.Itoa(e.Line)))
+	if e.Prefix != "" {
+		buf.WriteString(" prefix=")
+		buf.WriteString(formatFieldValue(e.Prefix))
+	}
+	if !e.Structured || e.Message != "" {
+		buf.WriteString(" msg=")
+		buf.WriteString(strconv.Quote(e.Message))
+	}
+
+	for _, field := range e.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(logfmtKey(field.Key))
+		buf.WriteByte('=')
+		buf.WriteString(formatFieldValue(field.Value))
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// logfmtKey replaces characters that are not allowed in logfmt keys
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// ANSI color codes used by ConsoleFormatter
+const (
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"
+	colorYellow  = "\033[33m"
+	colorMagenta = "\033[35m"
+	colorCyan    = "\033[36m"
+	colorGray    = "\033[90m"
+)
+
+// ConsoleFormatter renders entries as colored, human-readable lines for
+// local development
+type ConsoleFormatter struct {
+	// TimestampFormat defaults to "15:04:05.000"
+	TimestampFormat string
+
+	// DisableColors writes plain text, e.g. when the output is not a terminal
+	DisableColors bool
+}
+
+// Format renders an entry as a console line
+func (f *ConsoleFormatter) Format(e *Entry) ([]byte, error) {
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = "15:04:05.000"
+	}
+
+	var buf bytes.Buffer
+	f.colorize(&buf, colorGray, e.Time.Format(timestampFormat))
+	buf.WriteByte(' ')
+	f.colorize(&buf, levelColor(e.Level), fmt.Sprintf("%-5s", e.Level.String()))
+	buf.WriteByte(' ')
+	f.colorize(&buf, colorGray, fmt.Sprintf("%s:%d", e.File, e.Line))
+	if message := e.Prefix + e.Message; message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(message)
+	}
+
+	for _, field := range e.Fields {
+		buf.WriteByte(' ')
+		f.colorize(&buf, colorCyan, field.Key+"=")
+		buf.WriteString(formatFieldValue(field.Value))
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// colorize writes s wrapped in the color unless colors are disabled
+func (f *ConsoleFormatter) colorize(buf *bytes.Buffer, color, s string) {
+	if f.DisableColors {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(s)
+	buf.WriteString(colorReset)
+}
+
+// levelColor returns the console color for a level
+func levelColor(level LogLevel) string {
+	switch level {
+	case DEBUG:
+		return colorGray
+	case INFO:
+		return colorCyan
+	case WARN:
+		return colorYellow
+	case ERROR:
+		return colorRed
+	default:
+		return colorMagenta
+	}
+}
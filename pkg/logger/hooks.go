@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Hook is called with every entry the logger writes, e.g. to mirror errors to
+// Sentry or Slack. A returned error is reported on stderr and does not stop
+// the entry from being written.
+type Hook func(Entry) error
+
+// hookEntry is a registered hook with the levels it fires for
+type hookEntry struct {
+	hook   Hook
+	levels []LogLevel
+}
+
+// Output is an additional destination for log entries
+type Output struct {
+	// Writer receives the formatted entries
+	Writer io.Writer
+
+	// Level is the minimum level written to this output. Entries below the
+	// logger level are never written, whatever the output level.
+	Level LogLevel
+
+	// Formatter renders entries for this output, defaulting to the logger formatter
+	Formatter Formatter
+}
+
+// AddOutput writes entries to an additional output alongside the primary one
+// set with SetOutput, e.g. stdout plus a file that only receives errors.
+// Writes are serialized as for SetOutput.
+//
+//	l.AddOutput(logger.Output{Writer: file, Level: logger.ERROR, Formatter: &logger.JSONFormatter{}})
+func (l *Logger) AddOutput(output Output) {
+	if output.Writer == nil {
+		return
+	}
+	output.Writer = lockWriter(output.Writer)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.outputs = append(l.outputs[:len(l.outputs):len(l.outputs)], output)
+}
+
+// AddHook registers a hook for the given levels, or for every level when none are given
+func (l *Logger) AddHook(hook Hook, levels ...LogLevel) {
+	if hook == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks[:len(l.hooks):len(l.hooks)], hookEntry{hook: hook, levels: levels})
+}
+
+// dispatch queues an entry for the async writer, or writes it directly. The
+// entry is returned to the pool once written.
+func (l *Logger) dispatch(e *Entry) {
+	if async := l.asyncWriter(); async != nil && async.enqueue(asyncItem{logger: l, entry: e}) {
+		return
+	}
+	l.dispatchSync(e)
+	releaseEntry(e)
+}
+
+// dispatchSync writes an entry to the primary output or slog handler, the
+// additional outputs, and the hooks
+func (l *Logger) dispatchSync(e *Entry) {
+	l.mu.RLock()
+	handler, primary, defaultFormatter := l.handler, l.output, l.formatter
+	outputs, hooks := l.outputs, l.hooks
+	l.mu.RUnlock()
+
+	if handler != nil {
+		forward(handler, e)
+	} else if primary != nil {
+		l.write(primary, defaultFormatter, e)
+	}
+
+	for _, output := range outputs {
+		if e.Level < output.Level {
+			continue
+		}
+		formatter := output.Formatter
+		if formatter == nil {
+			formatter = defaultFormatter
+		}
+		l.write(output.Writer, formatter, e)
+	}
+
+	fireHooks(hooks, e)
+}
+
+// fireHooks calls the hooks registered for the entry level
+func fireHooks(hooks []hookEntry, e *Entry) {
+	for _, h := range hooks {
+		if !h.firesFor(e.Level) {
+			continue
+		}
+		if err := h.hook(*e); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to fire log hook: %v\n", err)
+		}
+	}
+}
+
+// firesFor reports whether the hook is registered for the level
+func (h hookEntry) firesFor(level LogLevel) bool {
+	if len(h.levels) == 0 {
+		return true
+	}
+	for _, l := range h.levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
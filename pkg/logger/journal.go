@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultJournalSocket is the systemd journal native protocol socket
+const DefaultJournalSocket = "/run/systemd/journal/socket"
+
+// JournalFormatter renders entries in the systemd journal native protocol.
+// Entry fields become journal fields with upper-case names, so user_id is
+// stored as USER_ID and can be queried with journalctl USER_ID=7.
+type JournalFormatter struct {
+	// Identifier is the SYSLOG_IDENTIFIER, defaulting to the executable name
+	Identifier string
+}
+
+// Format renders an entry as a journal message
+func (f *JournalFormatter) Format(e *Entry) ([]byte, error) {
+	identifier := f.Identifier
+	if identifier == "" {
+		identifier = defaultAppName()
+	}
+
+	message := e.Prefix + e.Message
+	if message == "" {
+		message = strings.TrimSpace(formatFields(e.Fields))
+	}
+
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", message)
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(e.Level)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", identifier)
+	writeJournalField(&buf, "CODE_FILE", e.File)
+	writeJournalField(&buf, "CODE_LINE", strconv.Itoa(e.Line))
+	for _, field := range e.Fields {
+		writeJournalField(&buf, journalFieldName(field.Key), fieldString(field.Value))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeJournalField writes a field, using the binary form for values with newlines
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts a field key to a valid journal field name:
+// upper-case letters, digits, and underscores, not starting with an underscore
+// or digit, at most 64 characters
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// JournalWriter sends each write as one datagram to the systemd journal.
+// Entries larger than the socket's datagram limit are rejected by the journal.
+type JournalWriter struct {
+	path string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewJournalWriter creates a writer for the journal socket at path, or the
+// default socket when path is empty
+func NewJournalWriter(path string) *JournalWriter {
+	if path == "" {
+		path = DefaultJournalSocket
+	}
+	return &JournalWriter{path: path}
+}
+
+// Write sends p to the journal
+func (w *JournalWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		conn, err := net.Dial("unixgram", w.path)
+		if err != nil {
+			return 0, err
+		}
+		w.conn = conn
+	}
+
+	if _, err := w.conn.Write(p); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the journal socket
+func (w *JournalWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// NewJournalOutput creates an output that sends entries to the systemd journal
+func NewJournalOutput(formatter JournalFormatter) Output {
+	return Output{
+		Writer:    NewJournalWriter(""),
+		Formatter: &formatter,
+	}
+}
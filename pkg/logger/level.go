@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// AtomicLevel is a log level that can be changed while other goroutines log.
+// A level with a parent follows the parent until it is set explicitly.
+type AtomicLevel struct {
+	v      atomic.Int32
+	parent *AtomicLevel
+}
+
+// levelUnset marks an AtomicLevel that follows its parent
+const levelUnset = -1
+
+// NewAtomicLevel creates an atomic level set to level
+func NewAtomicLevel(level LogLevel) *AtomicLevel {
+	a := &AtomicLevel{}
+	a.SetLevel(level)
+	return a
+}
+
+// newInheritedLevel creates a level that follows parent until it is set
+func newInheritedLevel(parent *AtomicLevel) *AtomicLevel {
+	a := &AtomicLevel{parent: parent}
+	a.v.Store(levelUnset)
+	return a
+}
+
+// Level returns the current level
+func (a *AtomicLevel) Level() LogLevel {
+	v := a.v.Load()
+	if v != levelUnset {
+		return LogLevel(v)
+	}
+	if a.parent == nil {
+		return DEBUG
+	}
+	return a.parent.Level()
+}
+
+// SetLevel changes the level
+func (a *AtomicLevel) SetLevel(level LogLevel) {
+	a.v.Store(int32(level))
+}
+
+// Inherit clears an explicit level so the level follows its parent again
+func (a *AtomicLevel) Inherit() {
+	if a.parent != nil {
+		a.v.Store(levelUnset)
+	}
+}
+
+// AtomicLevel returns the level shared by the logger and its child loggers
+func (l *Logger) AtomicLevel() *AtomicLevel {
+	return l.level
+}
+
+// enabled reports whether entries at the level are written
+func (l *Logger) enabled(level LogLevel) bool {
+	return l.level == nil || level >= l.level.Level()
+}
+
+// LevelLoader returns the level to apply when a reload is requested
+type LevelLoader func() (LogLevel, error)
+
+// LevelFromFile returns a loader that reads a level name such as "debug" from a file
+func LevelFromFile(path string) LevelLoader {
+	return func() (LogLevel, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return DEBUG, err
+		}
+		level, ok := ParseLevel(string(data))
+		if !ok {
+			return DEBUG, fmt.Errorf("invalid log level %q in %s", strings.TrimSpace(string(data)), path)
+		}
+		return level, nil
+	}
+}
+
+// ReloadLevelOnSignal changes the level each time the process receives one of
+// the signals, SIGHUP by default, until ctx is done. A nil loader toggles
+// between DEBUG and the level set when the watch started.
+func (l *Logger) ReloadLevelOnSignal(ctx context.Context, load LevelLoader, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	if load == nil {
+		load = toggleDebug(l.level.Level())
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				level, err := load()
+				if err != nil {
+					l.Errorf("Failed to reload log level: %v", err)
+					continue
+				}
+				l.level.SetLevel(level)
+				l.Infof("Log level set to %s", level)
+			}
+		}
+	}()
+}
+
+// toggleDebug returns a loader that alternates between DEBUG and the base level
+func toggleDebug(base LogLevel) LevelLoader {
+	debug := false
+	return func() (LogLevel, error) {
+		debug = !debug
+		if debug {
+			return DEBUG, nil
+		}
+		return base, nil
+	}
+}
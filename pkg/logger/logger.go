@@ -2,13 +2,17 @@
 package logger
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -42,19 +46,43 @@ func (l LogLevel) String() string {
 	}
 }
 
+// Fields is a set of key-value pairs attached to log entries
+type Fields map[string]interface{}
+
+// Field is a single key-value pair attached to a log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
 // Logger is a custom logger implementation
 type Logger struct {
-	logLevel LogLevel
-	output   io.Writer
-	prefix   string
+	// mu guards the settings below; fields are immutable after creation
+	mu sync.RWMutex
+
+	level     *AtomicLevel
+	name      string
+	output    *lockedWriter
+	prefix    string
+	fields    []Field
+	formatter Formatter
+	outputs   []Output
+	hooks     []hookEntry
+	sampler   *sampler
+	async     *asyncWriter
+
+	// handler receives entries instead of output when the logger wraps a slog.Logger
+	handler slog.Handler
 }
 
 // NewLogger creates a new logger instance
 func NewLogger() *Logger {
 	return &Logger{
-		logLevel: DEBUG,
-		output:   os.Stdout,
-		prefix:   "",
+		level:     NewAtomicLevel(DEBUG),
+		output:    stdoutWriter,
+		prefix:    "",
+		formatter: &TextFormatter{},
+		sampler:   &sampler{},
 	}
 }
 
@@ -63,46 +91,64 @@ func InitLogger() *Logger {
 	logger := NewLogger()
 
 	// Set log level from environment variable
-	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
-		switch strings.ToLower(logLevel) {
-		case "debug":
-			logger.SetLevel(uint8(DEBUG))
-		case "info":
-			logger.SetLevel(uint8(INFO))
-		case "warn", "warning":
-			logger.SetLevel(uint8(WARN))
-		case "error":
-			logger.SetLevel(uint8(ERROR))
-		case "fatal":
-			logger.SetLevel(uint8(FATAL))
-		}
+	if level, ok := ParseLevel(os.Getenv("LOG_LEVEL")); ok {
+		logger.SetLevel(uint8(level))
 	}
 
-	// Set output from environment variable
+	// Reload the level from LOG_LEVEL_FILE when the process receives SIGHUP
+	if levelFile := os.Getenv("LOG_LEVEL_FILE"); levelFile != "" {
+		logger.ReloadLevelOnSignal(context.Background(), LevelFromFile(levelFile))
+	}
+
+	// Set outputs from environment variable, e.g. LOG_OUTPUT=stdout,file
 	if logOutput := os.Getenv("LOG_OUTPUT"); logOutput != "" {
-		switch strings.ToLower(logOutput) {
-		case "stdout":
-			logger.SetOutput(os.Stdout)
-		case "stderr":
-			logger.SetOutput(os.Stderr)
-		case "file":
-			// Open log file if LOG_FILE_PATH is set
-			if logFilePath := os.Getenv("LOG_FILE_PATH"); logFilePath != "" {
-				// Ensure directory exists
-				dir := filepath.Dir(logFilePath)
-				if err := os.MkdirAll(dir, 0755); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to create log directory: %v\n", err)
-				} else {
-					// Open file for logging
-					file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
-					} else {
-						logger.SetOutput(file)
-					}
-				}
+		primary := false
+		for _, name := range strings.Split(logOutput, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			output, ok := openOutput(name)
+			if !ok {
+				continue
+			}
+
+			// The first output without its own format becomes the primary output
+			if !primary && output.Formatter == nil {
+				logger.SetOutput(output.Writer)
+				primary = true
+				continue
+			}
+
+			if name == "file" {
+				output.Level, _ = ParseLevel(os.Getenv("LOG_FILE_LEVEL"))
 			}
+			logger.AddOutput(output)
 		}
+
+		// Only syslog or journal outputs were requested
+		if !primary && len(logger.outputs) > 0 {
+			logger.SetOutput(nil)
+		}
+	}
+
+	// Set format from environment variable
+	if logFormat := os.Getenv("LOG_FORMAT"); logFormat != "" {
+		if formatter, ok := FormatterByName(logFormat); ok {
+			logger.SetFormatter(formatter)
+		} else {
+			fmt.Fprintf(os.Stderr, "Unknown log format %q, using text\n", logFormat)
+		}
+	}
+
+	// Set sampling from environment variable, e.g. LOG_SAMPLING=debug=100,info=10
+	if logSampling := os.Getenv("LOG_SAMPLING"); logSampling != "" {
+		for level, n := range parseSampling(logSampling) {
+			logger.SetSampling(level, n)
+		}
+	}
+
+	// Enable async writing from environment variable
+	if logAsync, _ := strconv.ParseBool(os.Getenv("LOG_ASYNC")); logAsync {
+		bufferSize, _ := strconv.Atoi(os.Getenv("LOG_ASYNC_BUFFER"))
+		logger.EnableAsync(AsyncConfig{BufferSize: bufferSize})
 	}
 
 	// Set prefix from environment variable
@@ -114,23 +160,103 @@ func InitLogger() *Logger {
 	return logger
 }
 
-// SetLevel sets the logger level
+// ParseLevel parses a level name such as "info" or "warning"
+func ParseLevel(name string) (LogLevel, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return DEBUG, true
+	case "info":
+		return INFO, true
+	case "warn", "warning":
+		return WARN, true
+	case "error":
+		return ERROR, true
+	case "fatal":
+		return FATAL, true
+	default:
+		return DEBUG, false
+	}
+}
+
+// openOutput opens a LOG_OUTPUT destination: stdout, stderr, the file at
+// LOG_FILE_PATH, syslog at LOG_SYSLOG_ADDR, or the systemd journal
+func openOutput(name string) (Output, bool) {
+	switch name {
+	case "stdout":
+		return Output{Writer: os.Stdout}, true
+	case "stderr":
+		return Output{Writer: os.Stderr}, true
+	case "file":
+		// Open log file if LOG_FILE_PATH is set
+		logFilePath := os.Getenv("LOG_FILE_PATH")
+		if logFilePath == "" {
+			return Output{}, false
+		}
+
+		// Ensure directory exists
+		dir := filepath.Dir(logFilePath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create log directory: %v\n", err)
+			return Output{}, false
+		}
+
+		// Open file for logging
+		file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open log file: %v\n", err)
+			return Output{}, false
+		}
+		return Output{Writer: file}, true
+	case "syslog":
+		network, addr, err := ParseSyslogAddress(os.Getenv("LOG_SYSLOG_ADDR"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to configure syslog output: %v\n", err)
+			return Output{}, false
+		}
+		return NewSyslogOutput(network, addr, SyslogFormatter{AppName: os.Getenv("LOG_APP_NAME")}), true
+	case "journal":
+		return NewJournalOutput(JournalFormatter{Identifier: os.Getenv("LOG_APP_NAME")}), true
+	default:
+		return Output{}, false
+	}
+}
+
+// SetLevel sets the logger level. It is safe to call while logging, and the
+// level is shared with child loggers created by With and WithFields.
 func (l *Logger) SetLevel(v uint8) {
 	if v <= uint8(FATAL) {
-		l.logLevel = LogLevel(v)
+		l.level.SetLevel(LogLevel(v))
 	}
 }
 
-// SetOutput sets the logger output
+// SetOutput sets the logger output. A nil writer disables the primary output,
+// e.g. when entries only go to outputs added with AddOutput. Writes are
+// serialized, so w need not be safe for concurrent use.
 func (l *Logger) SetOutput(w io.Writer) {
-	l.output = w
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.output = lockWriter(w)
 }
 
 // SetPrefix sets the logger prefix
 func (l *Logger) SetPrefix(p string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.prefix = p
 }
 
+// SetFormatter sets the formatter used to render entries. A nil formatter
+// restores the default text format.
+func (l *Logger) SetFormatter(f Formatter) {
+	if f == nil {
+		f = &TextFormatter{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.formatter = f
+}
+
 // SetHeader is a no-op for compatibility
 func (l *Logger) SetHeader(h string) {
 	// No-op for compatibility
@@ -138,104 +264,251 @@ func (l *Logger) SetHeader(h string) {
 
 // Output returns the logger output
 func (l *Logger) Output() io.Writer {
-	return l.output
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.output.writer()
 }
 
 // Prefix returns the logger prefix
 func (l *Logger) Prefix() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.prefix
 }
 
+// Formatter returns the logger formatter
+func (l *Logger) Formatter() Formatter {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.formatter
+}
+
 // Level returns the logger level
 func (l *Logger) Level() uint8 {
-	return uint8(l.logLevel)
+	return uint8(l.level.Level())
+}
+
+// With returns a child logger that includes the key-value pair in every entry
+func (l *Logger) With(key string, value interface{}) *Logger {
+	return l.withFields([]Field{{Key: key, Value: value}})
+}
+
+// WithFields returns a child logger that includes the fields in every entry
+func (l *Logger) WithFields(fields Fields) *Logger {
+	// Sort keys so the output order is stable
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	added := make([]Field, 0, len(keys))
+	for _, key := range keys {
+		added = append(added, Field{Key: key, Value: fields[key]})
+	}
+	return l.withFields(added)
+}
+
+// Fields returns a copy of the fields attached to the logger
+func (l *Logger) Fields() Fields {
+	fields := make(Fields, len(l.fields))
+	for _, f := range l.fields {
+		fields[f.Key] = f.Value
+	}
+	return fields
+}
+
+// withFields creates a child logger with the added fields, replacing existing keys
+func (l *Logger) withFields(added []Field) *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return &Logger{
+		level:     l.level,
+		name:      l.name,
+		output:    l.output,
+		prefix:    l.prefix,
+		fields:    mergeFields(l.fields, added),
+		formatter: l.formatter,
+		outputs:   l.outputs,
+		hooks:     l.hooks,
+		sampler:   l.sampler,
+		async:     l.async,
+		handler:   l.handler,
+	}
+}
+
+// mergeFields appends added to base, dropping base fields whose keys are added
+func mergeFields(base, added []Field) []Field {
+	if len(added) == 0 {
+		return base
+	}
+
+	fields := make([]Field, 0, len(base)+len(added))
+	for _, f := range base {
+		replaced := false
+		for _, a := range added {
+			if a.Key == f.Key {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			fields = append(fields, f)
+		}
+	}
+	return append(fields, added...)
 }
 
 // Logf logs a message with specified level and format
 func (l *Logger) Logf(level LogLevel, format string, args ...interface{}) {
-	l.log(level, format, args...)
+	l.log(level, fmt.Sprintf(format, args...))
 }
 
 // log logs a message at the specified level
-func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
-	if level < l.logLevel {
+func (l *Logger) log(level LogLevel, message string) {
+	if !l.enabled(level) || !l.sampler.allow(level) {
 		return
 	}
 
 	// Get caller information
-	_, file, line, ok := runtime.Caller(2)
-	if !ok {
-		file = "???"
-		line = 0
-	}
-	file = filepath.Base(file)
-
-	// Format message
-	message := format
-	if format == "" {
-		message = fmt.Sprint(args...)
-	} else {
-		message = fmt.Sprintf(format, args...)
-	}
+	pc := callerPC(3)
 
-	// Log to output
-	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
-	fmt.Fprintf(l.output, "%s | %s | %s:%d | %s%s\n",
-		timestamp, level.String(), file, line, l.prefix, message)
+	l.dispatch(l.newEntry(time.Now(), level, pc, message, nil))
 
-	// If FATAL, exit
+	// If FATAL, write queued entries and exit
 	if level == FATAL {
+		l.Flush()
 		os.Exit(1)
 	}
 }
 
 // logJSON logs a JSON object at the specified level
 func (l *Logger) logJSON(level LogLevel, j map[string]interface{}) {
-	if level < l.logLevel {
+	if !l.enabled(level) || !l.sampler.allow(level) {
 		return
 	}
 
 	// Get caller information
-	_, file, line, ok := runtime.Caller(2)
-	if !ok {
-		file = "???"
-		line = 0
+	pc := callerPC(3)
+
+	entry := l.newEntry(time.Now(), level, pc, "", mapToFields(j))
+	entry.Structured = true
+	l.dispatch(entry)
+
+	// If FATAL, write queued entries and exit
+	if level == FATAL {
+		l.Flush()
+		os.Exit(1)
+	}
+}
+
+// newEntry builds an entry with the logger prefix and fields. Extra fields
+// replace logger fields with the same key.
+func (l *Logger) newEntry(t time.Time, level LogLevel, pc uintptr, message string, extra []Field) *Entry {
+	file, line := callerFrame(pc)
+
+	l.mu.RLock()
+	prefix := l.prefix
+	l.mu.RUnlock()
+
+	e := getEntry()
+	e.Time = t
+	e.Level = level
+	e.File = file
+	e.Line = line
+	e.Prefix = prefix
+	e.Message = message
+	e.Fields = mergeFields(l.fields, extra)
+	e.pc = pc
+	return e
+}
+
+// write renders an entry with the formatter and writes it to w in a single
+// call. The built-in formatters render into a pooled buffer.
+func (l *Logger) write(w io.Writer, formatter Formatter, e *Entry) {
+	if formatter == nil {
+		formatter = &TextFormatter{}
 	}
-	file = filepath.Base(file)
 
-	// Add metadata to JSON
-	j["timestamp"] = time.Now().Format("2006-01-02 15:04:05.000")
-	j["level"] = level.String()
-	j["file"] = file
-	j["line"] = line
-	if l.prefix != "" {
-		j["prefix"] = l.prefix
+	if f, ok := formatter.(bufferFormatter); ok {
+		buf := getBuffer()
+		defer releaseBuffer(buf)
+		if err := f.formatTo(buf, e); err != nil {
+			fmt.Fprintf(w, "ERROR FORMATTING LOG ENTRY: %v\n", err)
+			return
+		}
+		w.Write(buf.Bytes())
+		return
 	}
 
-	// Convert to JSON
-	bytes, err := json.Marshal(j)
+	b, err := formatter.Format(e)
 	if err != nil {
-		fmt.Fprintf(l.output, "ERROR MARSHALING JSON: %v\n", err)
+		fmt.Fprintf(w, "ERROR FORMATTING LOG ENTRY: %v\n", err)
 		return
 	}
+	w.Write(b)
+}
 
-	// Log to output
-	fmt.Fprintln(l.output, string(bytes))
+// callerPC returns the program counter of the caller skip frames up the stack
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
 
-	// If FATAL, exit
-	if level == FATAL {
-		os.Exit(1)
+// callerFrame resolves a program counter to a file base name and line
+func callerFrame(pc uintptr) (string, int) {
+	if pc == 0 {
+		return "???", 0
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return "???", 0
+	}
+	return filepath.Base(frame.File), frame.Line
+}
+
+// formatFields renders fields as " key=value" pairs
+func formatFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	writeFields(&b, fields)
+	return b.String()
+}
+
+// writeFields writes fields as " key=value" pairs
+func writeFields(w io.StringWriter, fields []Field) {
+	for _, f := range fields {
+		w.WriteString(" ")
+		w.WriteString(f.Key)
+		w.WriteString("=")
+		w.WriteString(formatFieldValue(f.Value))
+	}
+}
+
+// formatFieldValue renders a field value, quoting strings that contain spaces
+func formatFieldValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
 	}
+	return s
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(i ...interface{}) {
-	l.log(DEBUG, "", i...)
+	l.log(DEBUG, fmt.Sprint(i...))
 }
 
 // Debugf logs a debug message with format
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	l.log(DEBUG, format, args...)
+	l.log(DEBUG, fmt.Sprintf(format, args...))
 }
 
 // Debugj logs a debug message as JSON
@@ -245,12 +518,12 @@ func (l *Logger) Debugj(j map[string]interface{}) {
 
 // Info logs an info message
 func (l *Logger) Info(i ...interface{}) {
-	l.log(INFO, "", i...)
+	l.log(INFO, fmt.Sprint(i...))
 }
 
 // Infof logs an info message with format
 func (l *Logger) Infof(format string, args ...interface{}) {
-	l.log(INFO, format, args...)
+	l.log(INFO, fmt.Sprintf(format, args...))
 }
 
 // Infoj logs an info message as JSON
@@ -260,12 +533,12 @@ func (l *Logger) Infoj(j map[string]interface{}) {
 
 // Warn logs a warning message
 func (l *Logger) Warn(i ...interface{}) {
-	l.log(WARN, "", i...)
+	l.log(WARN, fmt.Sprint(i...))
 }
 
 // Warnf logs a warning message with format
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	l.log(WARN, format, args...)
+	l.log(WARN, fmt.Sprintf(format, args...))
 }
 
 // Warnj logs a warning message as JSON
@@ -275,12 +548,12 @@ func (l *Logger) Warnj(j map[string]interface{}) {
 
 // Error logs an error message
 func (l *Logger) Error(i ...interface{}) {
-	l.log(ERROR, "", i...)
+	l.log(ERROR, fmt.Sprint(i...))
 }
 
 // Errorf logs an error message with format
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	l.log(ERROR, format, args...)
+	l.log(ERROR, fmt.Sprintf(format, args...))
 }
 
 // Errorj logs an error message as JSON
@@ -290,12 +563,12 @@ func (l *Logger) Errorj(j map[string]interface{}) {
 
 // Fatal logs a fatal message
 func (l *Logger) Fatal(i ...interface{}) {
-	l.log(FATAL, "", i...)
+	l.log(FATAL, fmt.Sprint(i...))
 }
 
 // Fatalf logs a fatal message with format
 func (l *Logger) Fatalf(format string, args ...interface{}) {
-	l.log(FATAL, format, args...)
+	l.log(FATAL, fmt.Sprintf(format, args...))
 }
 
 // Fatalj logs a fatal message as JSON
@@ -305,12 +578,12 @@ func (l *Logger) Fatalj(j map[string]interface{}) {
 
 // Print logs a message
 func (l *Logger) Print(i ...interface{}) {
-	l.log(INFO, "", i...)
+	l.log(INFO, fmt.Sprint(i...))
 }
 
 // Printf logs a message with format
 func (l *Logger) Printf(format string, args ...interface{}) {
-	l.log(INFO, format, args...)
+	l.log(INFO, fmt.Sprintf(format, args...))
 }
 
 // Printj logs a message as JSON
@@ -320,13 +593,13 @@ func (l *Logger) Printj(j map[string]interface{}) {
 
 // Panic logs a panic message
 func (l *Logger) Panic(i ...interface{}) {
-	l.log(FATAL, "", i...)
+	l.log(FATAL, fmt.Sprint(i...))
 	panic(fmt.Sprint(i...))
 }
 
 // Panicf logs a panic message with format
 func (l *Logger) Panicf(format string, args ...interface{}) {
-	l.log(FATAL, format, args...)
+	l.log(FATAL, fmt.Sprintf(format, args...))
 	panic(fmt.Sprintf(format, args...))
 }
 
@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// serialWriter is a writer that is not safe for concurrent use. It fails the
+// test when two writes overlap and keeps every write as one line.
+type serialWriter struct {
+	t       *testing.T
+	writing atomic.Bool
+	buf     bytes.Buffer
+	writes  []string
+}
+
+func (w *serialWriter) Write(p []byte) (int, error) {
+	if !w.writing.CompareAndSwap(false, true) {
+		w.t.Error("concurrent writes")
+		return len(p), nil
+	}
+	defer w.writing.Store(false)
+	w.buf.Write(p)
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+// checkLines checks that every write is a single, whole text or JSON line
+func checkLines(t *testing.T, writes []string) {
+	t.Helper()
+	for _, line := range writes {
+		if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+			t.Fatalf("write is not a single line: %q", line)
+		}
+		if strings.HasPrefix(line, "{") {
+			var j map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &j); err != nil {
+				t.Fatalf("invalid JSON line %q: %v", line, err)
+			}
+			continue
+		}
+		if parts := strings.SplitN(line, " | ", 4); len(parts) != 4 || !strings.Contains(parts[3], "message ") {
+			t.Fatalf("invalid text line %q", line)
+		}
+	}
+}
+
+func TestConcurrentLogging(t *testing.T) {
+	const goroutines, entries = 8, 200
+
+	w := &serialWriter{t: t}
+	l := NewLogger()
+	l.SetOutput(w)
+
+	var hooked atomic.Int64
+	l.AddHook(func(e Entry) error {
+		hooked.Add(1)
+		return nil
+	}, INFO)
+
+	stop := make(chan struct{})
+	var reconfigured sync.WaitGroup
+	reconfigured.Add(1)
+	go func() {
+		defer reconfigured.Done()
+		formatters := []Formatter{&TextFormatter{}, &JSONFormatter{}}
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			l.SetLevel(uint8(DEBUG))
+			l.SetPrefix(fmt.Sprintf("p%d ", i%3))
+			l.SetFormatter(formatters[i%2])
+			l.SetOutput(w)
+			_ = l.Output()
+			_ = l.Prefix()
+			_ = l.Formatter()
+		}
+	}()
+
+	var logging sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		logging.Add(1)
+		go func(g int) {
+			defer logging.Done()
+			child := l.With("goroutine", g)
+			for i := 0; i < entries; i++ {
+				if i%2 == 0 {
+					l.Infof("message %d", i)
+				} else {
+					child.WithFields(Fields{"i": i}).Debugf("message %d", i)
+				}
+			}
+		}(g)
+	}
+	logging.Wait()
+	close(stop)
+	reconfigured.Wait()
+
+	if len(w.writes) != goroutines*entries {
+		t.Fatalf("got %d lines, want %d", len(w.writes), goroutines*entries)
+	}
+	if hooked.Load() != goroutines*entries/2 {
+		t.Errorf("hook fired %d times, want %d", hooked.Load(), goroutines*entries/2)
+	}
+	checkLines(t, w.writes)
+}
+
+func TestConcurrentLoggingAsync(t *testing.T) {
+	const goroutines, entries = 8, 200
+
+	w := &serialWriter{t: t}
+	l := NewLogger()
+	l.SetOutput(w)
+	l.EnableAsync(AsyncConfig{BufferSize: 16})
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			child := l.With("goroutine", g)
+			for i := 0; i < entries; i++ {
+				child.Infof("message %d", i)
+				if i%50 == 0 {
+					l.SetPrefix("async ")
+					l.Flush()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	l.Close()
+
+	if len(w.writes) != goroutines*entries {
+		t.Fatalf("got %d lines, want %d", len(w.writes), goroutines*entries)
+	}
+	checkLines(t, w.writes)
+}
+
+func TestSharedWriterAcrossLoggers(t *testing.T) {
+	w := &serialWriter{t: t}
+	a, b := NewLogger(), NewLogger()
+	a.SetOutput(w)
+	b.SetOutput(a.Output())
+	b.AddOutput(Output{Writer: w, Formatter: &JSONFormatter{}})
+
+	var wg sync.WaitGroup
+	for _, l := range []*Logger{a, b, a.With("child", true), b.With("child", true)} {
+		wg.Add(1)
+		go func(l *Logger) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				l.Infof("message %d", i)
+			}
+		}(l)
+	}
+	wg.Wait()
+
+	if got, want := len(w.writes), 600; got != want {
+		t.Fatalf("got %d lines, want %d", got, want)
+	}
+	checkLines(t, w.writes)
+}
+
+func TestTextFormatterPooled(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger()
+	l.SetOutput(&buf)
+
+	l.With("user", "a b").Infof("message %d", 1)
+	l.Info("message 2")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %q", len(lines), buf.String())
+	}
+	for i, want := range []string{`message 1 user="a b"`, "message 2"} {
+		if !strings.Contains(lines[i], " | INFO | logger_test.go:") || !strings.HasSuffix(lines[i], " | "+want) {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want)
+		}
+	}
+}
+
+func BenchmarkTextLogger(b *testing.B) {
+	benchmarkLogger(b, &TextFormatter{})
+}
+
+func BenchmarkJSONLogger(b *testing.B) {
+	benchmarkLogger(b, &JSONFormatter{})
+}
+
+func benchmarkLogger(b *testing.B, formatter Formatter) {
+	l := NewLogger()
+	l.SetOutput(io.Discard)
+	l.SetFormatter(formatter)
+	child := l.WithFields(Fields{"request_id": "abc123", "user_id": 42})
+
+	b.Run("serial", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			child.Info("request handled")
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				child.Info("request handled")
+			}
+		})
+	})
+}
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// FieldLogger is the field that holds the name of a named logger
+const FieldLogger = "logger"
+
+// namedRegistry holds the named loggers and their levels
+type namedRegistry struct {
+	mu         sync.Mutex
+	loggers    map[string]*Logger
+	levels     map[string]*AtomicLevel
+	configured map[string]LogLevel
+	root       *AtomicLevel
+}
+
+var (
+	registry     = &namedRegistry{}
+	registryOnce sync.Once
+)
+
+// Named returns the logger for a subsystem, e.g. "filesystem.s3". Names are
+// hierarchical: a logger follows the level configured for its own name, then
+// for each parent name ("filesystem"), then the default logger's level.
+// Levels can be set with SetNamedLevel or LOG_LEVELS=filesystem=debug,http=warn.
+//
+// Named loggers are created from the default logger on first use, so call
+// SetDefault before requesting them.
+func Named(name string) *Logger {
+	registryOnce.Do(registry.loadEnv)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if l, ok := registry.loggers[name]; ok {
+		return l
+	}
+
+	base := Default()
+	if registry.root == nil {
+		registry.root = base.level
+	}
+
+	l := base.withFields([]Field{{Key: FieldLogger, Value: name}})
+	l.name = name
+	l.level = registry.levelFor(name)
+
+	if registry.loggers == nil {
+		registry.loggers = make(map[string]*Logger)
+	}
+	registry.loggers[name] = l
+	return l
+}
+
+// Name returns the name of a named logger
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// SetNamedLevel sets the level for a name and every name below it that has
+// no level of its own
+func SetNamedLevel(name string, level LogLevel) {
+	registryOnce.Do(registry.loadEnv)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.setLevel(name, level)
+}
+
+// ResetNamedLevel removes the level set for a name, so it follows its parent again
+func ResetNamedLevel(name string) {
+	registryOnce.Do(registry.loadEnv)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	delete(registry.configured, name)
+	if level, ok := registry.levels[name]; ok {
+		level.Inherit()
+	}
+}
+
+// NamedLevels returns the levels set for names
+func NamedLevels() map[string]LogLevel {
+	registryOnce.Do(registry.loadEnv)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	levels := make(map[string]LogLevel, len(registry.configured))
+	for name, level := range registry.configured {
+		levels[name] = level
+	}
+	return levels
+}
+
+// ParseLevels parses a LOG_LEVELS value such as "filesystem=debug,http=warn"
+func ParseLevels(value string) map[string]LogLevel {
+	levels := make(map[string]LogLevel)
+	for _, pair := range strings.Split(value, ",") {
+		name, levelName, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		level, ok := ParseLevel(levelName)
+		if name == "" || !ok {
+			continue
+		}
+		levels[name] = level
+	}
+	return levels
+}
+
+// loadEnv applies the levels from LOG_LEVELS
+func (r *namedRegistry) loadEnv() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, level := range ParseLevels(os.Getenv("LOG_LEVELS")) {
+		r.setLevel(name, level)
+	}
+}
+
+// setLevel records the level for a name and applies it to an existing logger
+func (r *namedRegistry) setLevel(name string, level LogLevel) {
+	if r.configured == nil {
+		r.configured = make(map[string]LogLevel)
+	}
+	r.configured[name] = level
+
+	if atomicLevel, ok := r.levels[name]; ok {
+		atomicLevel.SetLevel(level)
+	}
+}
+
+// levelFor returns the level for a name, creating it and its parents as needed
+func (r *namedRegistry) levelFor(name string) *AtomicLevel {
+	if level, ok := r.levels[name]; ok {
+		return level
+	}
+
+	parent := r.root
+	if i := strings.LastIndex(name, "."); i > 0 {
+		parent = r.levelFor(name[:i])
+	}
+
+	level := newInheritedLevel(parent)
+	if configured, ok := r.configured[name]; ok {
+		level.SetLevel(configured)
+	}
+
+	if r.levels == nil {
+		r.levels = make(map[string]*AtomicLevel)
+	}
+	r.levels[name] = level
+	return level
+}
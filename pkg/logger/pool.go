@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxPooledBufferSize keeps unusually large entries from pinning their buffers in the pool
+const maxPooledBufferSize = 64 << 10
+
+var (
+	entryPool  = sync.Pool{New: func() interface{} { return &Entry{} }}
+	bufferPool = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+)
+
+// getEntry returns an empty entry from the pool
+func getEntry() *Entry {
+	return entryPool.Get().(*Entry)
+}
+
+// releaseEntry returns an entry to the pool once it has been written. The
+// fields slice may be shared with the logger, so it is dropped, not reused.
+func releaseEntry(e *Entry) {
+	*e = Entry{}
+	entryPool.Put(e)
+}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// releaseBuffer returns a buffer to the pool
+func releaseBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// bufferFormatter is implemented by the built-in formatters to render into
+// a pooled buffer instead of allocating the line
+type bufferFormatter interface {
+	formatTo(buf *bytes.Buffer, e *Entry) error
+}
+
+// lockedWriter serializes writes so concurrent entries never interleave,
+// also for writers that are not safe for concurrent use
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Standard streams share one lock across loggers
+var (
+	stdoutWriter = &lockedWriter{w: os.Stdout}
+	stderrWriter = &lockedWriter{w: os.Stderr}
+)
+
+// lockWriter wraps a writer in a lockedWriter. Loggers writing to stdout or
+// stderr share a lock; child loggers share the lock of their parent.
+func lockWriter(w io.Writer) *lockedWriter {
+	switch w := w.(type) {
+	case nil:
+		return nil
+	case *lockedWriter:
+		return w
+	case *os.File:
+		if w == stdoutWriter.w {
+			return stdoutWriter
+		}
+		if w == stderrWriter.w {
+			return stderrWriter
+		}
+	}
+	return &lockedWriter{w: w}
+}
+
+// Write writes p to the underlying writer while holding the lock
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// writer returns the wrapped writer, or nil
+func (w *lockedWriter) writer() io.Writer {
+	if w == nil {
+		return nil
+	}
+	return w.w
+}
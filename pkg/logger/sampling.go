@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// sampler keeps 1 in N entries per level
+type sampler struct {
+	rates    [FATAL]atomic.Uint64
+	counters [FATAL]atomic.Uint64
+}
+
+// allow reports whether the next entry at the level is kept. FATAL entries are never sampled.
+func (s *sampler) allow(level LogLevel) bool {
+	if s == nil || level < DEBUG || level >= FATAL {
+		return true
+	}
+
+	n := s.rates[level].Load()
+	if n <= 1 {
+		return true
+	}
+	return (s.counters[level].Add(1)-1)%n == 0
+}
+
+// SetSampling keeps only 1 in every n entries at the level, e.g. to cut debug
+// output in hot request paths. An n of 0 or 1 disables sampling for the level.
+// Child loggers share the sampling settings of their parent.
+func (l *Logger) SetSampling(level LogLevel, n int) {
+	if level < DEBUG || level >= FATAL || l.sampler == nil {
+		return
+	}
+	if n < 0 {
+		n = 0
+	}
+	l.sampler.rates[level].Store(uint64(n))
+	l.sampler.counters[level].Store(0)
+}
+
+// parseSampling parses a LOG_SAMPLING value such as "debug=100,info=10"
+func parseSampling(value string) map[LogLevel]int {
+	rates := make(map[LogLevel]int)
+	for _, pair := range strings.Split(value, ",") {
+		name, rate, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		level, ok := ParseLevel(name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(rate))
+		if err != nil {
+			continue
+		}
+		rates[level] = n
+	}
+	return rates
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// LevelFatal is the slog level used for FATAL entries
+const LevelFatal = slog.LevelError + 4
+
+// SlogLevel converts a log level to the equivalent slog level
+func (l LogLevel) SlogLevel() slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case INFO:
+		return slog.LevelInfo
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+// LevelFromSlog converts a slog level to the closest log level
+func LevelFromSlog(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	case level < LevelFatal:
+		return ERROR
+	default:
+		return FATAL
+	}
+}
+
+// Handler is a slog.Handler that writes records through a gokit Logger,
+// keeping the logger's formatter, level, prefix, and fields
+type Handler struct {
+	logger *Logger
+	attrs  []Field
+	group  string
+}
+
+// NewHandler creates a slog.Handler that writes through the logger
+func NewHandler(l *Logger) *Handler {
+	return &Handler{logger: l}
+}
+
+// Enabled reports whether the logger's level allows the record level
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.enabled(LevelFromSlog(level))
+}
+
+// Handle writes the record through the logger
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	level := LevelFromSlog(r.Level)
+	if !h.logger.sampler.allow(level) {
+		return nil
+	}
+
+	fields := make([]Field, 0, len(h.attrs)+r.NumAttrs())
+	fields = append(fields, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+	fields = append(fields, contextFields(ctx)...)
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	h.logger.dispatch(h.logger.newEntry(t, level, r.PC, r.Message, fields))
+	return nil
+}
+
+// WithAttrs returns a handler that includes the attributes in every record
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.attrs = make([]Field, 0, len(h.attrs)+len(attrs))
+	child.attrs = append(child.attrs, h.attrs...)
+	for _, a := range attrs {
+		child.attrs = appendAttr(child.attrs, h.group, a)
+	}
+	return &child
+}
+
+// WithGroup returns a handler that qualifies subsequent attribute keys with the group name
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.group = h.group + name + "."
+	return &child
+}
+
+// Slog returns a *slog.Logger that writes through this logger
+func (l *Logger) Slog() *slog.Logger {
+	l.mu.RLock()
+	handler := l.handler
+	l.mu.RUnlock()
+
+	if handler != nil {
+		return slog.New(handler.WithAttrs(fieldsToAttrs(l.fields)))
+	}
+	return slog.New(NewHandler(l))
+}
+
+// NewLoggerFromSlog creates a logger that sends every entry to the slog logger's
+// handler, so gokit code can log into an existing slog setup
+func NewLoggerFromSlog(sl *slog.Logger) *Logger {
+	l := NewLogger()
+	l.handler = sl.Handler()
+	return l
+}
+
+// forward sends an entry to the slog handler backing a logger
+func forward(handler slog.Handler, e *Entry) {
+	ctx := context.Background()
+	slogLevel := e.Level.SlogLevel()
+	if !handler.Enabled(ctx, slogLevel) {
+		return
+	}
+
+	r := slog.NewRecord(e.Time, slogLevel, e.Prefix+e.Message, e.pc)
+	r.AddAttrs(fieldsToAttrs(e.Fields)...)
+	_ = handler.Handle(ctx, r)
+}
+
+// appendAttr flattens a slog attribute into fields, qualifying keys with the group
+func appendAttr(fields []Field, group string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		prefix := group
+		if a.Key != "" {
+			prefix = group + a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+
+	return append(fields, Field{Key: group + a.Key, Value: a.Value.Any()})
+}
+
+// fieldsToAttrs converts fields to slog attributes
+func fieldsToAttrs(fields []Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	return attrs
+}
+
+// mapToFields converts a JSON entry to fields sorted by key
+func mapToFields(j map[string]interface{}) []Field {
+	keys := make([]string, 0, len(j))
+	for key := range j {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, Field{Key: key, Value: j[key]})
+	}
+	return fields
+}
@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogFacility is a syslog facility code
+type SyslogFacility int
+
+// Syslog facilities for application logs
+const (
+	FacilityUser   SyslogFacility = 1
+	FacilityDaemon SyslogFacility = 3
+	FacilityLocal0 SyslogFacility = 16
+	FacilityLocal1 SyslogFacility = 17
+	FacilityLocal2 SyslogFacility = 18
+	FacilityLocal3 SyslogFacility = 19
+	FacilityLocal4 SyslogFacility = 20
+	FacilityLocal5 SyslogFacility = 21
+	FacilityLocal6 SyslogFacility = 22
+	FacilityLocal7 SyslogFacility = 23
+)
+
+// DefaultSyslogSDID is the structured data ID used for entry fields
+const DefaultSyslogSDID = "fields@32473"
+
+// syslogSeverity returns the syslog severity for a level
+func syslogSeverity(level LogLevel) int {
+	switch level {
+	case DEBUG:
+		return 7
+	case INFO:
+		return 6
+	case WARN:
+		return 4
+	case ERROR:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// SyslogFormatter renders entries as RFC 5424 syslog messages. The caller and
+// the entry fields are written as structured data:
+//
+//	<14>1 2024-01-02T15:04:05.000000Z host app 42 - [fields@32473 caller="main.go:12" user_id="7"] Signed in
+type SyslogFormatter struct {
+	// Facility defaults to FacilityUser
+	Facility SyslogFacility
+
+	// Hostname defaults to os.Hostname
+	Hostname string
+
+	// AppName defaults to the executable name
+	AppName string
+
+	// SDID is the structured data ID, defaulting to DefaultSyslogSDID
+	SDID string
+}
+
+// Format renders an entry as a syslog message
+func (f *SyslogFormatter) Format(e *Entry) ([]byte, error) {
+	facility := f.Facility
+	if facility == 0 {
+		facility = FacilityUser
+	}
+	sdID := f.SDID
+	if sdID == "" {
+		sdID = DefaultSyslogSDID
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d - ",
+		int(facility)*8+syslogSeverity(e.Level),
+		e.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderValue(f.Hostname, defaultHostname(), 255),
+		syslogHeaderValue(f.AppName, defaultAppName(), 48),
+		os.Getpid())
+
+	// Structured data
+	buf.WriteByte('[')
+	buf.WriteString(sdID)
+	buf.WriteString(` caller="`)
+	buf.WriteString(escapeSDValue(e.File + ":" + strconv.Itoa(e.Line)))
+	buf.WriteByte('"')
+	for _, field := range e.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(sdParamName(field.Key))
+		buf.WriteString(`="`)
+		buf.WriteString(escapeSDValue(fieldString(field.Value)))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte(']')
+
+	if message := e.Prefix + e.Message; message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(message)
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// syslogHeaderValue returns a header field without spaces, or "-" when empty
+func syslogHeaderValue(value, fallback string, maxLen int) string {
+	if value == "" {
+		value = fallback
+	}
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, value)
+	if value == "" {
+		return "-"
+	}
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	return value
+}
+
+// sdParamName replaces characters that are not allowed in structured data names
+func sdParamName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "_"
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// escapeSDValue escapes the characters that are special in structured data values
+func escapeSDValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// fieldString renders a field value as plain text
+func fieldString(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(v)
+}
+
+// defaultHostname returns the host name, or "-" when it is unknown
+func defaultHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "-"
+	}
+	return hostname
+}
+
+// defaultAppName returns the executable name
+func defaultAppName() string {
+	if len(os.Args) == 0 {
+		return "-"
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// SyslogWriter sends each write as one syslog message over UDP, TCP, or a unix
+// socket. Stream connections use octet-counting framing (RFC 6587). The
+// connection is dialed on first use and re-dialed after a failed write.
+type SyslogWriter struct {
+	network string
+	addr    string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogWriter creates a writer for a network ("udp", "tcp", "unix", or
+// "unixgram") and address
+func NewSyslogWriter(network, addr string) *SyslogWriter {
+	return &SyslogWriter{network: network, addr: addr}
+}
+
+// ParseSyslogAddress parses a LOG_SYSLOG_ADDR value such as "udp://host:514",
+// "tcp://host:601", or "unixgram:///dev/log". An empty value is the local
+// syslog socket.
+func ParseSyslogAddress(value string) (network, addr string, err error) {
+	if value == "" {
+		return "unixgram", "/dev/log", nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid syslog address %q: %w", value, err)
+	}
+
+	switch u.Scheme {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("invalid syslog address %q: missing host", value)
+		}
+		return u.Scheme, u.Host, nil
+	case "unix", "unixgram":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("invalid syslog address %q: missing socket path", value)
+		}
+		return u.Scheme, u.Path, nil
+	default:
+		return "", "", fmt.Errorf("invalid syslog address %q: unsupported scheme %q", value, u.Scheme)
+	}
+}
+
+// Write sends p as a single syslog message
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	message := w.frame(bytes.TrimRight(p, "\n"))
+
+	// Retry once on a fresh connection, e.g. after the server restarted
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			w.conn, err = net.DialTimeout(w.network, w.addr, 5*time.Second)
+			if err != nil {
+				continue
+			}
+		}
+		if _, err = w.conn.Write(message); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+// frame adds octet-counting framing for stream connections
+func (w *SyslogWriter) frame(message []byte) []byte {
+	switch w.network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		framed := make([]byte, 0, len(message)+8)
+		framed = strconv.AppendInt(framed, int64(len(message)), 10)
+		framed = append(framed, ' ')
+		return append(framed, message...)
+	default:
+		return message
+	}
+}
+
+// Close closes the connection
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// NewSyslogOutput creates an output that sends entries to a syslog server, e.g.
+//
+//	l.AddOutput(logger.NewSyslogOutput("udp", "logs.internal:514", logger.SyslogFormatter{AppName: "api"}))
+func NewSyslogOutput(network, addr string, formatter SyslogFormatter) Output {
+	return Output{
+		Writer:    NewSyslogWriter(network, addr),
+		Formatter: &formatter,
+	}
+}
@@ -0,0 +1,243 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// APIError is an error response of an email API
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mailer: %s responded %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// Temporary reports whether sending again may succeed: rate limits and
+// server errors, but not rejected requests
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// defaultHTTPClient is used by the API mailers without a client
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// SendGridConfig configures the SendGrid API
+type SendGridConfig struct {
+	APIKey string
+
+	// BaseURL defaults to https://api.sendgrid.com
+	BaseURL string
+
+	// HTTPClient defaults to a client with a 30 second timeout
+	HTTPClient *http.Client
+}
+
+// SendGrid sends messages with the SendGrid v3 API
+type SendGrid struct {
+	config SendGridConfig
+}
+
+// NewSendGrid creates a SendGrid mailer
+func NewSendGrid(config SendGridConfig) *SendGrid {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.sendgrid.com"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = defaultHTTPClient
+	}
+	return &SendGrid{config: config}
+}
+
+// sendGridAddress is an address in a SendGrid request
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Send sends a message
+func (s *SendGrid) Send(ctx context.Context, msg *Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	personalization := map[string][]sendGridAddress{"to": sendGridAddresses(msg.To)}
+	if len(msg.Cc) > 0 {
+		personalization["cc"] = sendGridAddresses(msg.Cc)
+	}
+	if len(msg.Bcc) > 0 {
+		personalization["bcc"] = sendGridAddresses(msg.Bcc)
+	}
+
+	// SendGrid requires text/plain before text/html
+	var content []map[string]string
+	if msg.Text != "" {
+		content = append(content, map[string]string{"type": "text/plain", "value": msg.Text})
+	}
+	if msg.HTML != "" {
+		content = append(content, map[string]string{"type": "text/html", "value": msg.HTML})
+	}
+
+	body := map[string]interface{}{
+		"personalizations": []interface{}{personalization},
+		"from":             sendGridAddresses([]string{msg.From})[0],
+		"subject":          msg.Subject,
+		"content":          content,
+	}
+	if msg.ReplyTo != "" {
+		body["reply_to"] = sendGridAddresses([]string{msg.ReplyTo})[0]
+	}
+	if len(msg.Headers) > 0 {
+		body["headers"] = msg.Headers
+	}
+	if len(msg.Attachments) > 0 {
+		attachments := make([]map[string]string, len(msg.Attachments))
+		for i, a := range msg.Attachments {
+			attachment := map[string]string{
+				"content":     base64.StdEncoding.EncodeToString(a.Data),
+				"filename":    a.Filename,
+				"type":        attachmentType(a),
+				"disposition": "attachment",
+			}
+			if a.Inline {
+				attachment["disposition"] = "inline"
+				attachment["content_id"] = a.Filename
+			}
+			attachments[i] = attachment
+		}
+		body["attachments"] = attachments
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.BaseURL+"/v3/mail/send", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	return doAPIRequest(s.config.HTTPClient, req, "sendgrid")
+}
+
+// sendGridAddresses converts addresses, which Validate has checked
+func sendGridAddresses(values []string) []sendGridAddress {
+	addresses := make([]sendGridAddress, 0, len(values))
+	for _, value := range values {
+		if addr, err := mail.ParseAddress(value); err == nil {
+			addresses = append(addresses, sendGridAddress{Email: addr.Address, Name: addr.Name})
+		}
+	}
+	return addresses
+}
+
+// MailgunConfig configures the Mailgun API
+type MailgunConfig struct {
+	// Domain is the sending domain, e.g. "mg.example.com"
+	Domain string
+	APIKey string
+
+	// BaseURL defaults to https://api.mailgun.net; use https://api.eu.mailgun.net
+	// for domains in the EU region
+	BaseURL string
+
+	// HTTPClient defaults to a client with a 30 second timeout
+	HTTPClient *http.Client
+}
+
+// Mailgun sends messages with the Mailgun API
+type Mailgun struct {
+	config MailgunConfig
+}
+
+// NewMailgun creates a Mailgun mailer
+func NewMailgun(config MailgunConfig) *Mailgun {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.mailgun.net"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = defaultHTTPClient
+	}
+	return &Mailgun{config: config}
+}
+
+// Send sends a message
+func (m *Mailgun) Send(ctx context.Context, msg *Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	field := func(name, value string) {
+		if value != "" {
+			form.WriteField(name, value)
+		}
+	}
+	field("from", msg.From)
+	field("to", strings.Join(msg.To, ","))
+	field("cc", strings.Join(msg.Cc, ","))
+	field("bcc", strings.Join(msg.Bcc, ","))
+	field("subject", msg.Subject)
+	field("text", msg.Text)
+	field("html", msg.HTML)
+	field("h:Reply-To", msg.ReplyTo)
+	for name, value := range msg.Headers {
+		field("h:"+name, value)
+	}
+	for _, a := range msg.Attachments {
+		name := "attachment"
+		if a.Inline {
+			name = "inline"
+		}
+		w, err := form.CreateFormFile(name, a.Filename)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(a.Data); err != nil {
+			return err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v3/%s/messages", m.config.BaseURL, m.config.Domain)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &buf)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", m.config.APIKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return doAPIRequest(m.config.HTTPClient, req, "mailgun")
+}
+
+// doAPIRequest sends a request, returning an APIError for responses other
+// than 2xx
+func doAPIRequest(client *http.Client, req *http.Request, provider string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mailer: %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &APIError{Provider: provider, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+}
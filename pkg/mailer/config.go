@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// NewFromEnv creates a mailer from environment variables: MAIL_DRIVER is
+// "smtp" (default), "sendgrid", "mailgun", or "memory". SMTP reads
+// SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, and SMTP_ENCRYPTION;
+// SendGrid reads SENDGRID_API_KEY; Mailgun reads MAILGUN_DOMAIN,
+// MAILGUN_API_KEY, and MAILGUN_BASE_URL.
+func NewFromEnv() (Mailer, error) {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp", "":
+		config := SMTPConfig{
+			Host:       os.Getenv("SMTP_HOST"),
+			Username:   os.Getenv("SMTP_USERNAME"),
+			Password:   os.Getenv("SMTP_PASSWORD"),
+			Encryption: os.Getenv("SMTP_ENCRYPTION"),
+		}
+		if config.Host == "" {
+			return nil, fmt.Errorf("mailer: SMTP_HOST is required")
+		}
+		if port := os.Getenv("SMTP_PORT"); port != "" {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, fmt.Errorf("mailer: invalid SMTP_PORT: %w", err)
+			}
+			config.Port = p
+		}
+		return NewSMTP(config), nil
+
+	case "sendgrid":
+		return NewSendGrid(SendGridConfig{APIKey: os.Getenv("SENDGRID_API_KEY")}), nil
+
+	case "mailgun":
+		return NewMailgun(MailgunConfig{
+			Domain:  os.Getenv("MAILGUN_DOMAIN"),
+			APIKey:  os.Getenv("MAILGUN_API_KEY"),
+			BaseURL: os.Getenv("MAILGUN_BASE_URL"),
+		}), nil
+
+	case "memory":
+		return NewMemory(), nil
+
+	default:
+		return nil, fmt.Errorf("mailer: unsupported MAIL_DRIVER %q", driver)
+	}
+}
@@ -0,0 +1,236 @@
+// Package mailer sends email through SMTP or an email API, with templates,
+// attachments from a filesystem provider, and sending in the background
+// through a job queue
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"sync"
+
+	apperrors "github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/filesystem"
+	"github.com/anaknegeri/gokit/pkg/jobs"
+)
+
+// DefaultJobType is the job type of messages sent in the background
+const DefaultJobType = "mailer.send"
+
+// Mailer sends messages, e.g. NewSMTP, NewSendGrid, or NewMailgun
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Config configures a service
+type Config struct {
+	// From is the sender of messages without one, e.g.
+	// "Example <no-reply@example.com>"
+	From string
+
+	// Files holds the attachments added with a Path
+	Files *filesystem.Provider
+
+	// Templates renders the messages sent with SendTemplate
+	Templates *Templates
+
+	// Queue sends the messages passed to Enqueue in the background. The
+	// service registers its job handler on it.
+	Queue *jobs.Queue
+
+	// JobType defaults to DefaultJobType
+	JobType string
+}
+
+// Service sends messages with a mailer, filling in the sender, templates,
+// and attachments. It is a Mailer itself.
+type Service struct {
+	mailer Mailer
+	config Config
+}
+
+// New creates a service sending messages with a mailer
+func New(mailer Mailer, config ...Config) *Service {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.JobType == "" {
+		cfg.JobType = DefaultJobType
+	}
+
+	s := &Service{mailer: mailer, config: cfg}
+	if cfg.Queue != nil {
+		cfg.Queue.Register(cfg.JobType, jobs.Handle(s.sendJob))
+	}
+	return s
+}
+
+// Send sends a message, loading attachments with a Path from the filesystem
+// provider
+func (s *Service) Send(ctx context.Context, msg *Message) error {
+	prepared, err := s.prepare(ctx, msg)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, prepared)
+}
+
+// SendTemplate renders a template with data into a message and sends it
+func (s *Service) SendTemplate(ctx context.Context, msg *Message, name string, data interface{}) error {
+	if err := s.Render(msg, name, data); err != nil {
+		return err
+	}
+	return s.Send(ctx, msg)
+}
+
+// Render renders a template with data into a message
+func (s *Service) Render(msg *Message, name string, data interface{}) error {
+	if s.config.Templates == nil {
+		return errors.New("mailer: no templates configured")
+	}
+	return s.config.Templates.Apply(msg, name, data)
+}
+
+// Enqueue sends a message in the background through the job queue. Invalid
+// messages are rejected at once; attachments with a Path are read when the
+// message is sent.
+func (s *Service) Enqueue(ctx context.Context, msg *Message, opts ...jobs.Option) (*jobs.Job, error) {
+	if s.config.Queue == nil {
+		return nil, errors.New("mailer: no job queue configured")
+	}
+	queued := *msg
+	if queued.From == "" {
+		queued.From = s.config.From
+	}
+	if err := validateAddresses(&queued); err != nil {
+		return nil, err
+	}
+	return s.config.Queue.Enqueue(ctx, s.config.JobType, &queued, opts...)
+}
+
+// sendJob sends a queued message, failing at once when retrying cannot help
+func (s *Service) sendJob(ctx context.Context, msg Message) error {
+	err := s.Send(ctx, &msg)
+	if err != nil && permanent(err) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// prepare returns a copy of a message with the default sender and the
+// attachment data loaded
+func (s *Service) prepare(ctx context.Context, msg *Message) (*Message, error) {
+	prepared := *msg
+	if prepared.From == "" {
+		prepared.From = s.config.From
+	}
+
+	prepared.Attachments = make([]Attachment, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		if a.Path != "" && a.Data == nil {
+			loaded, err := s.load(ctx, a)
+			if err != nil {
+				return nil, err
+			}
+			a = loaded
+		}
+		prepared.Attachments[i] = a
+	}
+
+	if err := prepared.Validate(); err != nil {
+		return nil, err
+	}
+	return &prepared, nil
+}
+
+// load reads an attachment from the filesystem provider
+func (s *Service) load(ctx context.Context, a Attachment) (Attachment, error) {
+	if s.config.Files == nil {
+		return a, fmt.Errorf("mailer: no filesystem to read attachment %s from", a.Path)
+	}
+	reader, info, err := s.config.Files.Get(ctx, a.Path)
+	if err != nil {
+		return a, err
+	}
+	defer reader.Close()
+
+	a.Data, err = io.ReadAll(reader)
+	if err != nil {
+		return a, fmt.Errorf("mailer: read attachment %s: %w", a.Path, err)
+	}
+	if a.ContentType == "" && info != nil {
+		a.ContentType = info.ContentType
+	}
+	return a, nil
+}
+
+// validateAddresses validates a message except for its attachments, which
+// are loaded later
+func validateAddresses(msg *Message) error {
+	check := *msg
+	check.Attachments = nil
+	return check.Validate()
+}
+
+// permanent reports whether sending a message failed for good: invalid
+// messages, missing attachments, SMTP replies 5xx, and rejected API requests
+func permanent(err error) bool {
+	var invalid *InvalidMessageError
+	if errors.As(err, &invalid) {
+		return true
+	}
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) && appErr.HTTPCode == http.StatusNotFound {
+		return true
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 500
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return !apiErr.Temporary()
+	}
+	return false
+}
+
+// Memory keeps sent messages instead of sending them, for tests and
+// development
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemory creates a memory mailer
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send records a message
+func (m *Memory) Send(_ context.Context, msg *Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns the messages sent so far
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Reset forgets the messages sent so far
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
@@ -0,0 +1,318 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is an email
+type Message struct {
+	// From defaults to the From of the service
+	From    string   `json:"from,omitempty"`
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Bcc     []string `json:"bcc,omitempty"`
+	ReplyTo string   `json:"replyTo,omitempty"`
+	Subject string   `json:"subject"`
+
+	// Text and HTML are the bodies; clients show HTML when both are set
+	Text string `json:"text,omitempty"`
+	HTML string `json:"html,omitempty"`
+
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+}
+
+// Attachment is a file attached to a message, with its contents in Data or
+// at Path in the filesystem provider of the service
+type Attachment struct {
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Data        []byte `json:"data,omitempty"`
+
+	// Path is read from the filesystem provider when the message is sent,
+	// so queued messages stay small
+	Path string `json:"path,omitempty"`
+
+	// Inline attachments are shown in the HTML body, referenced as
+	// "cid:<filename>", e.g. <img src="cid:logo.png">
+	Inline bool `json:"inline,omitempty"`
+}
+
+// Attach attaches data as a file
+func (m *Message) Attach(filename string, data []byte) *Message {
+	m.Attachments = append(m.Attachments, Attachment{Filename: filename, Data: data})
+	return m
+}
+
+// AttachFile attaches the file at a path of the filesystem provider
+func (m *Message) AttachFile(filePath string) *Message {
+	m.Attachments = append(m.Attachments, Attachment{Filename: path.Base(filePath), Path: filePath})
+	return m
+}
+
+// Recipients returns the addresses of every To, Cc, and Bcc recipient
+func (m *Message) Recipients() ([]string, error) {
+	var recipients []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, value := range list {
+			addr, err := mail.ParseAddress(value)
+			if err != nil {
+				return nil, fmt.Errorf("mailer: invalid recipient %q: %w", value, err)
+			}
+			recipients = append(recipients, addr.Address)
+		}
+	}
+	return recipients, nil
+}
+
+// Validate checks that the message has a sender, a recipient, and valid
+// addresses
+func (m *Message) Validate() error {
+	if m.From == "" {
+		return &InvalidMessageError{Reason: "no sender"}
+	}
+	if _, err := mail.ParseAddress(m.From); err != nil {
+		return &InvalidMessageError{Reason: fmt.Sprintf("invalid sender %q", m.From)}
+	}
+	if m.ReplyTo != "" {
+		if _, err := mail.ParseAddress(m.ReplyTo); err != nil {
+			return &InvalidMessageError{Reason: fmt.Sprintf("invalid reply-to %q", m.ReplyTo)}
+		}
+	}
+	recipients, err := m.Recipients()
+	if err != nil {
+		return &InvalidMessageError{Reason: strings.TrimPrefix(err.Error(), "mailer: ")}
+	}
+	if len(recipients) == 0 {
+		return &InvalidMessageError{Reason: "no recipients"}
+	}
+	for _, a := range m.Attachments {
+		if a.Path != "" && a.Data == nil {
+			return &InvalidMessageError{Reason: fmt.Sprintf("attachment %s was not loaded", a.Path)}
+		}
+	}
+	return nil
+}
+
+// InvalidMessageError is returned for messages that cannot be sent
+type InvalidMessageError struct {
+	Reason string
+}
+
+func (e *InvalidMessageError) Error() string { return "mailer: " + e.Reason }
+
+// Bytes encodes the message in the Internet Message Format (RFC 5322) with
+// MIME parts, leaving out Bcc recipients. Attachments must have their Data.
+func (m *Message) Bytes() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+
+	from, _ := mail.ParseAddress(m.From)
+	writeHeader("From", from.String())
+	if to := formatAddresses(m.To); to != "" {
+		writeHeader("To", to)
+	}
+	if cc := formatAddresses(m.Cc); cc != "" {
+		writeHeader("Cc", cc)
+	}
+	if m.ReplyTo != "" {
+		replyTo, _ := mail.ParseAddress(m.ReplyTo)
+		writeHeader("Reply-To", replyTo.String())
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID(from.Address))
+	writeHeader("MIME-Version", "1.0")
+
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(textproto.CanonicalMIMEHeaderKey(name), mime.QEncoding.Encode("utf-8", m.Headers[name]))
+	}
+
+	body := m.entity()
+	for name, values := range body.header {
+		writeHeader(name, values[0])
+	}
+	buf.WriteString("\r\n")
+	if err := body.writeBody(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// entity is a MIME entity: a body or a multipart of entities
+type entity struct {
+	header textproto.MIMEHeader
+
+	// body writes the encoded body of a leaf
+	body func(w io.Writer) error
+
+	// parts and boundary are set for a multipart
+	parts    []*entity
+	boundary string
+}
+
+// entity builds the MIME structure of the message: the bodies as
+// multipart/alternative, with inline attachments as multipart/related, and
+// other attachments as multipart/mixed
+func (m *Message) entity() *entity {
+	var bodies []*entity
+	if m.Text != "" || m.HTML == "" {
+		bodies = append(bodies, textEntity("text/plain", m.Text))
+	}
+	if m.HTML != "" {
+		bodies = append(bodies, textEntity("text/html", m.HTML))
+	}
+	body := multipartEntity("alternative", bodies)
+
+	var inline, attached []*entity
+	for _, a := range m.Attachments {
+		if a.Inline {
+			inline = append(inline, attachmentEntity(a))
+		} else {
+			attached = append(attached, attachmentEntity(a))
+		}
+	}
+	if len(inline) > 0 {
+		body = multipartEntity("related", append([]*entity{body}, inline...))
+	}
+	if len(attached) > 0 {
+		body = multipartEntity("mixed", append([]*entity{body}, attached...))
+	}
+	return body
+}
+
+// textEntity is a UTF-8 text body encoded as quoted-printable
+func textEntity(contentType, text string) *entity {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return &entity{header: header, body: func(w io.Writer) error {
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(text)); err != nil {
+			return err
+		}
+		return qp.Close()
+	}}
+}
+
+// attachmentEntity is an attachment encoded as base64
+func attachmentEntity(a Attachment) *entity {
+	filename := mime.QEncoding.Encode("utf-8", a.Filename)
+	disposition := "attachment"
+	header := textproto.MIMEHeader{}
+	if a.Inline {
+		disposition = "inline"
+		// Set directly, since canonicalizing the key would give "Content-Id"
+		header["Content-ID"] = []string{"<" + a.Filename + ">"}
+	}
+	header.Set("Content-Type", fmt.Sprintf("%s; name=%q", attachmentType(a), filename))
+	header.Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, filename))
+	header.Set("Content-Transfer-Encoding", "base64")
+	return &entity{header: header, body: func(w io.Writer) error {
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+				return err
+			}
+			encoded = encoded[76:]
+		}
+		_, err := io.WriteString(w, encoded+"\r\n")
+		return err
+	}}
+}
+
+// multipartEntity combines entities, or returns the only one
+func multipartEntity(subtype string, parts []*entity) *entity {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	boundary := randomHex(16)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", fmt.Sprintf("multipart/%s; boundary=%q", subtype, boundary))
+	return &entity{header: header, parts: parts, boundary: boundary}
+}
+
+// writeBody writes the body of an entity, and the parts of a multipart
+func (e *entity) writeBody(w io.Writer) error {
+	if e.parts == nil {
+		return e.body(w)
+	}
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(e.boundary); err != nil {
+		return err
+	}
+	for _, part := range e.parts {
+		pw, err := mw.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+		if err := part.writeBody(pw); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// attachmentType returns the content type of an attachment, from its
+// filename when not set
+func attachmentType(a Attachment) string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+	if t := mime.TypeByExtension(path.Ext(a.Filename)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// formatAddresses formats a list of addresses for a header
+func formatAddresses(values []string) string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		if addr, err := mail.ParseAddress(value); err == nil {
+			formatted = append(formatted, addr.String())
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// messageID returns a unique Message-ID at the domain of the sender
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), randomHex(8), domain)
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,168 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP encryption modes
+const (
+	// EncryptionAuto uses implicit TLS on port 465 and STARTTLS elsewhere when
+	// the server offers it
+	EncryptionAuto = ""
+
+	// EncryptionSTARTTLS requires upgrading the connection with STARTTLS
+	EncryptionSTARTTLS = "starttls"
+
+	// EncryptionTLS connects with TLS from the start, usually on port 465
+	EncryptionTLS = "tls"
+
+	// EncryptionNone never encrypts, e.g. for a local relay
+	EncryptionNone = "none"
+)
+
+// SMTPConfig configures an SMTP server
+type SMTPConfig struct {
+	Host string
+
+	// Port defaults to 587
+	Port int
+
+	// Username and Password authenticate with PLAIN, which needs an
+	// encrypted connection except to localhost
+	Username string
+	Password string
+
+	// Encryption is one of the Encryption modes, defaulting to EncryptionAuto
+	Encryption string
+
+	// Timeout limits sending a message unless the context has a deadline,
+	// defaulting to 30 seconds
+	Timeout time.Duration
+
+	// LocalName is the host name sent in HELO, defaulting to "localhost"
+	LocalName string
+}
+
+// SMTP sends messages through an SMTP server
+type SMTP struct {
+	config SMTPConfig
+}
+
+// NewSMTP creates an SMTP mailer
+func NewSMTP(config SMTPConfig) *SMTP {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &SMTP{config: config}
+}
+
+// Send sends a message over a new connection
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	from, _ := mail.ParseAddress(msg.From)
+	recipients, _ := msg.Recipients()
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("mailer: authenticate: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial connects to the server, applying the deadline of ctx to the whole
+// conversation, and sets up encryption
+func (s *SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	implicit := s.config.Encryption == EncryptionTLS ||
+		(s.config.Encryption == EncryptionAuto && s.config.Port == 465)
+	if implicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	fail := func(err error) (*smtp.Client, error) {
+		client.Close()
+		return nil, err
+	}
+
+	localName := s.config.LocalName
+	if localName == "" {
+		localName = "localhost"
+	}
+	if err := client.Hello(localName); err != nil {
+		return fail(err)
+	}
+
+	if !implicit && s.config.Encryption != EncryptionNone {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fail(fmt.Errorf("mailer: starttls: %w", err))
+			}
+		} else if s.config.Encryption == EncryptionSTARTTLS {
+			return fail(fmt.Errorf("mailer: %s does not support STARTTLS", addr))
+		}
+	}
+	return client, nil
+}
@@ -0,0 +1,159 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates renders messages from HTML and text templates in a file system:
+// "welcome.html" and "welcome.txt" are the bodies of the template
+// "welcome", and either can set the subject with {{define "subject"}}.
+// Files starting with "_", e.g. "_layout.html", are shared by every
+// template of their kind.
+type Templates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// Content is a rendered message
+type Content struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// NewTemplates parses the .html and .txt files of a file system, e.g. an
+// embed.FS, with optional template functions
+func NewTemplates(fsys fs.FS, funcs ...htmltemplate.FuncMap) (*Templates, error) {
+	var htmlFiles, textFiles, htmlShared, textShared []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		shared := strings.HasPrefix(path.Base(name), "_")
+		switch path.Ext(name) {
+		case ".html":
+			if shared {
+				htmlShared = append(htmlShared, name)
+			} else {
+				htmlFiles = append(htmlFiles, name)
+			}
+		case ".txt":
+			if shared {
+				textShared = append(textShared, name)
+			} else {
+				textFiles = append(textFiles, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mailer: read templates: %w", err)
+	}
+
+	t := &Templates{
+		html: make(map[string]*htmltemplate.Template),
+		text: make(map[string]*texttemplate.Template),
+	}
+
+	htmlBase := htmltemplate.New("")
+	textBase := texttemplate.New("")
+	for _, f := range funcs {
+		htmlBase.Funcs(f)
+		textBase.Funcs(texttemplate.FuncMap(f))
+	}
+	if len(htmlShared) > 0 {
+		if _, err := htmlBase.ParseFS(fsys, htmlShared...); err != nil {
+			return nil, fmt.Errorf("mailer: parse templates: %w", err)
+		}
+	}
+	if len(textShared) > 0 {
+		if _, err := textBase.ParseFS(fsys, textShared...); err != nil {
+			return nil, fmt.Errorf("mailer: parse templates: %w", err)
+		}
+	}
+
+	for _, file := range htmlFiles {
+		tmpl, err := htmlBase.Clone()
+		if err == nil {
+			tmpl, err = tmpl.ParseFS(fsys, file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mailer: parse template %s: %w", file, err)
+		}
+		t.html[strings.TrimSuffix(file, ".html")] = tmpl.Lookup(path.Base(file))
+	}
+	for _, file := range textFiles {
+		tmpl, err := textBase.Clone()
+		if err == nil {
+			tmpl, err = tmpl.ParseFS(fsys, file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mailer: parse template %s: %w", file, err)
+		}
+		t.text[strings.TrimSuffix(file, ".txt")] = tmpl.Lookup(path.Base(file))
+	}
+	return t, nil
+}
+
+// Render renders a template with data
+func (t *Templates) Render(name string, data interface{}) (*Content, error) {
+	htmlTmpl, hasHTML := t.html[name]
+	textTmpl, hasText := t.text[name]
+	if !hasHTML && !hasText {
+		return nil, fmt.Errorf("mailer: no template %q", name)
+	}
+
+	content := &Content{}
+	var buf bytes.Buffer
+	if hasText {
+		if err := textTmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("mailer: render template %s: %w", name, err)
+		}
+		content.Text = buf.String()
+
+		if subject := textTmpl.Lookup("subject"); subject != nil {
+			buf.Reset()
+			if err := subject.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("mailer: render subject of %s: %w", name, err)
+			}
+			content.Subject = strings.TrimSpace(buf.String())
+		}
+	}
+	if hasHTML {
+		buf.Reset()
+		if err := htmlTmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("mailer: render template %s: %w", name, err)
+		}
+		content.HTML = buf.String()
+
+		if subject := htmlTmpl.Lookup("subject"); subject != nil && content.Subject == "" {
+			buf.Reset()
+			if err := subject.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("mailer: render subject of %s: %w", name, err)
+			}
+			content.Subject = html.UnescapeString(strings.TrimSpace(buf.String()))
+		}
+	}
+	return content, nil
+}
+
+// Apply renders a template into a message, keeping a subject already set
+func (t *Templates) Apply(msg *Message, name string, data interface{}) error {
+	content, err := t.Render(name, data)
+	if err != nil {
+		return err
+	}
+	if msg.Subject == "" {
+		msg.Subject = content.Subject
+	}
+	msg.HTML = content.HTML
+	msg.Text = content.Text
+	return nil
+}
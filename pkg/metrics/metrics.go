@@ -0,0 +1,294 @@
+// Package metrics records counters, gauges, histograms, and timers, and
+// exports them in the Prometheus text format
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the histogram buckets of durations in seconds, from 5ms
+// to 10s
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// SizeBuckets are the histogram buckets of sizes in bytes, from 100B to 100MB
+var SizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7, 1e8}
+
+// Metric types
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// validName matches metric and label names
+var validName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Registry holds metrics by name. Asking for a metric that exists returns
+// it, so decorators and handlers created more than once share their metrics.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default returns the registry of the package-level functions, the
+// middleware, and the decorators of other packages
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Counter returns a counter, a value that only goes up, such as the number
+// of requests. Label values are given in the order of labelNames when the
+// counter is updated.
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	return &Counter{r.family(name, help, typeCounter, labelNames, nil)}
+}
+
+// Gauge returns a gauge, a value that goes up and down, such as the number
+// of open connections
+func (r *Registry) Gauge(name, help string, labelNames ...string) *Gauge {
+	return &Gauge{r.family(name, help, typeGauge, labelNames, nil)}
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at each export,
+// such as the length of a queue
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	f := r.family(name, help, typeGauge, nil, nil)
+	f.mu.Lock()
+	f.fn = fn
+	f.mu.Unlock()
+}
+
+// Histogram returns a histogram, which counts observations such as request
+// sizes in buckets. Buckets are the upper bounds, defaulting to
+// DefaultBuckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{r.family(name, help, typeHistogram, labelNames, buckets)}
+}
+
+// Timer returns a histogram of durations in seconds with DefaultBuckets. Its
+// name should end with "_seconds".
+func (r *Registry) Timer(name, help string, labelNames ...string) *Timer {
+	return &Timer{r.Histogram(name, help, DefaultBuckets, labelNames...)}
+}
+
+// family returns the metric of a name, creating it when it does not exist.
+// It panics when the name is invalid or used by a metric of another type or
+// with other labels.
+func (r *Registry) family(name, help, kind string, labelNames []string, buckets []float64) *family {
+	if !validName.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, label := range labelNames {
+		if !validName.MatchString(label) || strings.HasPrefix(label, "__") || label == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q of %s", label, name))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || strings.Join(f.labelNames, ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metrics: %s is already registered as a %s with labels %v", name, f.kind, f.labelNames))
+		}
+		return f
+	}
+
+	f := &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: append([]string(nil), labelNames...),
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// Counter is a value that only goes up
+type Counter struct {
+	f *family
+}
+
+// Inc adds 1 to the counter of the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the counter of the label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s cannot decrease", c.f.name))
+	}
+	c.f.with(labelValues).add(v)
+}
+
+// Gauge is a value that goes up and down
+type Gauge struct {
+	f *family
+}
+
+// Set sets the gauge of the label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.with(labelValues).set(v)
+}
+
+// Add adds v, which may be negative, to the gauge of the label values
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.with(labelValues).add(v)
+}
+
+// Inc adds 1 to the gauge of the label values
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts 1 from the gauge of the label values
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	f *family
+}
+
+// Observe records a value in the histogram of the label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.with(labelValues).observe(h.f.buckets, v)
+}
+
+// Timer is a histogram of durations in seconds
+type Timer struct {
+	h *Histogram
+}
+
+// Observe records a duration in the timer of the label values
+func (t *Timer) Observe(d time.Duration, labelValues ...string) {
+	t.h.Observe(d.Seconds(), labelValues...)
+}
+
+// Since records the time elapsed since start:
+//
+//	start := time.Now()
+//	defer timer.Since(start, "upload")
+func (t *Timer) Since(start time.Time, labelValues ...string) {
+	t.Observe(time.Since(start), labelValues...)
+}
+
+// family is a metric with its series by label values
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.RWMutex
+	fn     func() float64
+	series map[string]*series
+}
+
+// with returns the series of the label values, creating it on first use. It
+// panics when the number of values does not match the labels.
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values %v, got %d", f.name, len(f.labelNames), f.labelNames, len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	// Clone the values, which may point to buffers Fiber reuses for the next request
+	values := make([]string, len(labelValues))
+	for i, v := range labelValues {
+		values[i] = strings.Clone(v)
+	}
+	s = &series{labelValues: values}
+	if f.buckets != nil {
+		s.counts = make([]uint64, len(f.buckets))
+	}
+	f.series[key] = s
+	return s
+}
+
+// series is the value of a metric for some label values
+type series struct {
+	labelValues []string
+
+	mu     sync.Mutex
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (s *series) add(v float64) {
+	s.mu.Lock()
+	s.value += v
+	s.mu.Unlock()
+}
+
+func (s *series) set(v float64) {
+	s.mu.Lock()
+	s.value = v
+	s.mu.Unlock()
+}
+
+func (s *series) observe(buckets []float64, v float64) {
+	i := sort.SearchFloat64s(buckets, v)
+	s.mu.Lock()
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+// NewCounter returns a counter of the default registry
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	return defaultRegistry.Counter(name, help, labelNames...)
+}
+
+// NewGauge returns a gauge of the default registry
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	return defaultRegistry.Gauge(name, help, labelNames...)
+}
+
+// NewHistogram returns a histogram of the default registry
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return defaultRegistry.Histogram(name, help, buckets, labelNames...)
+}
+
+// NewTimer returns a timer of the default registry
+func NewTimer(name, help string, labelNames ...string) *Timer {
+	return defaultRegistry.Timer(name, help, labelNames...)
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UnmatchedRoute is the route label of requests that matched no route, so
+// scans of random paths do not create a series each
+const UnmatchedRoute = "unmatched"
+
+// MiddlewareConfig configures the HTTP metrics middleware
+type MiddlewareConfig struct {
+	// Registry defaults to Default()
+	Registry *Registry
+
+	// SkipPaths are request paths that are not recorded, e.g. "/metrics"
+	SkipPaths []string
+
+	// Skip reports whether a request should not be recorded
+	Skip func(c *fiber.Ctx) bool
+}
+
+// Middleware returns a middleware recording, by method, route, and status:
+//
+//	http_requests_total             requests
+//	http_request_duration_seconds   latency
+//	http_request_size_bytes         request body sizes
+//	http_response_size_bytes        response body sizes
+//	http_requests_in_flight         requests being served, by method only
+//
+// The route is the path pattern, such as "/users/:id", so IDs do not create
+// a series each. Errors returned by later handlers are passed to the app
+// error handler so the recorded status matches the response.
+func Middleware(config ...MiddlewareConfig) fiber.Handler {
+	cfg := MiddlewareConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	r := cfg.Registry
+	if r == nil {
+		r = Default()
+	}
+
+	skipPaths := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skipPaths[path] = true
+	}
+
+	requests := r.Counter("http_requests_total", "HTTP requests by method, route, and status", "method", "route", "status")
+	duration := r.Timer("http_request_duration_seconds", "HTTP request latency in seconds", "method", "route", "status")
+	requestSize := r.Histogram("http_request_size_bytes", "HTTP request body sizes in bytes", SizeBuckets, "method", "route", "status")
+	responseSize := r.Histogram("http_response_size_bytes", "HTTP response body sizes in bytes", SizeBuckets, "method", "route", "status")
+	inFlight := r.Gauge("http_requests_in_flight", "HTTP requests being served", "method")
+
+	return func(c *fiber.Ctx) error {
+		if skipPaths[c.Path()] || (cfg.Skip != nil && cfg.Skip(c)) {
+			return c.Next()
+		}
+
+		method := c.Method()
+		start := time.Now()
+		inFlight.Inc(method)
+		defer inFlight.Dec(method)
+
+		chainErr := c.Next()
+
+		// Let the error handler write the response so the recorded status is the one sent
+		if chainErr != nil {
+			if err := c.App().ErrorHandler(c, chainErr); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		route := c.Route().Path
+		if matchedNoRoute(c, chainErr) {
+			route = UnmatchedRoute
+		}
+		labels := []string{method, route, strconv.Itoa(status)}
+
+		requests.Inc(labels...)
+		duration.Since(start, labels...)
+		requestSize.Observe(float64(len(c.Request().Body())), labels...)
+		responseSize.Observe(float64(len(c.Response().Body())), labels...)
+		return nil
+	}
+}
+
+// matchedNoRoute reports whether an error is one Fiber returns for requests
+// that fell through every route: 404 "Cannot GET /nope", or 405 when the
+// path has routes for other methods only
+func matchedNoRoute(c *fiber.Ctx, err error) bool {
+	if err == fiber.ErrMethodNotAllowed {
+		return true
+	}
+	var fiberErr *fiber.Error
+	return errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusNotFound &&
+		strings.HasPrefix(fiberErr.Message, "Cannot "+c.Method()+" ")
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteText writes every metric in the Prometheus text format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns a Fiber handler serving the metrics to Prometheus:
+//
+//	app.Get("/metrics", metrics.Default().Handler())
+func (r *Registry) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var buf bytes.Buffer
+		if err := r.WriteText(&buf); err != nil {
+			return err
+		}
+		c.Set(fiber.HeaderContentType, ContentType)
+		return c.Send(buf.Bytes())
+	}
+}
+
+// ServeHTTP serves the metrics to Prometheus from a net/http server
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set(fiber.HeaderContentType, ContentType)
+	_ = r.WriteText(w)
+}
+
+// Handler returns a Fiber handler serving the metrics of the default registry
+func Handler() fiber.Handler {
+	return defaultRegistry.Handler()
+}
+
+// write writes the metric and its series, sorted by label values
+func (f *family) write(w *bufio.Writer) {
+	f.mu.RLock()
+	fn := f.fn
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	f.mu.RUnlock()
+	if fn == nil && len(all) == 0 {
+		return
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labelValues, "\xff") < strings.Join(all[j].labelValues, "\xff")
+	})
+
+	w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	w.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+	if fn != nil {
+		writeSample(w, f.name, nil, nil, fn())
+	}
+
+	for _, s := range all {
+		s.mu.Lock()
+		value, sum, count := s.value, s.sum, s.count
+		counts := append([]uint64(nil), s.counts...)
+		s.mu.Unlock()
+
+		if f.kind != typeHistogram {
+			writeSample(w, f.name, f.labelNames, s.labelValues, value)
+			continue
+		}
+
+		names := append(append([]string(nil), f.labelNames...), "le")
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += counts[i]
+			values := append(append([]string(nil), s.labelValues...), formatFloat(bound))
+			writeSample(w, f.name+"_bucket", names, values, float64(cumulative))
+		}
+		values := append(append([]string(nil), s.labelValues...), "+Inf")
+		writeSample(w, f.name+"_bucket", names, values, float64(count))
+		writeSample(w, f.name+"_sum", f.labelNames, s.labelValues, sum)
+		writeSample(w, f.name+"_count", f.labelNames, s.labelValues, float64(count))
+	}
+}
+
+// writeSample writes a line such as `name{label="value"} 1`
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 {
+		w.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabel(labelValues[i]) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatFloat formats a value as Prometheus expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,101 @@
+// Package middleware provides Fiber middleware built on the gokit packages
+package middleware
+
+import (
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccessLogConfig configures the access log middleware
+type AccessLogConfig struct {
+	// SkipPaths are request paths that are not logged, e.g. "/health"
+	SkipPaths []string
+
+	// Skip reports whether a request should not be logged
+	Skip func(c *fiber.Ctx) bool
+
+	// SlowThreshold logs requests that take longer at WARN level. Zero disables it.
+	SlowThreshold time.Duration
+}
+
+// AccessLog returns a middleware that logs one structured entry per request
+// with the method, path, status, latency, response size, request ID, client
+// IP, and user agent. Server errors are logged at ERROR level, slow requests
+// at WARN level, and everything else at INFO level. Errors returned by later
+// handlers are passed to the app error handler so the logged status matches
+// the response.
+func AccessLog(l *logger.Logger, config ...AccessLogConfig) fiber.Handler {
+	cfg := AccessLogConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if l == nil {
+		l = logger.Default()
+	}
+
+	skipPaths := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skipPaths[path] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		if skipPaths[c.Path()] || (cfg.Skip != nil && cfg.Skip(c)) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		chainErr := c.Next()
+
+		// Let the error handler write the response so the logged status is the one sent
+		if chainErr != nil {
+			if err := c.App().ErrorHandler(c, chainErr); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		latency := time.Since(start)
+		status := c.Response().StatusCode()
+
+		fields := logger.Fields{
+			"method":     c.Method(),
+			"path":       c.Path(),
+			"status":     status,
+			"latency_ms": float64(latency.Microseconds()) / 1000,
+			"bytes":      len(c.Response().Body()),
+			"ip":         c.IP(),
+			"user_agent": c.Get(fiber.HeaderUserAgent),
+		}
+		if requestID := requestID(c); requestID != "" {
+			fields[logger.FieldRequestID] = requestID
+		}
+		if chainErr != nil {
+			fields["error"] = chainErr.Error()
+		}
+
+		// Pick the level from the outcome
+		level := logger.INFO
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = logger.ERROR
+		case cfg.SlowThreshold > 0 && latency > cfg.SlowThreshold:
+			level = logger.WARN
+			fields["slow"] = true
+		}
+
+		l.WithFields(fields).Logf(level, "%s %s %d %s", c.Method(), c.Path(), status, latency)
+		return nil
+	}
+}
+
+// requestID returns the request ID set by the requestid middleware or sent by the client
+func requestID(c *fiber.Ctx) string {
+	if id := logger.RequestIDFromContext(c.Context()); id != "" {
+		return id
+	}
+	if id := c.GetRespHeader(fiber.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Get(fiber.HeaderXRequestID)
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyGuardConfig configures the body guard middleware
+type BodyGuardConfig struct {
+	// MaxSize is the largest request body in bytes. Zero leaves the size
+	// unchecked.
+	MaxSize int64
+
+	// ContentTypes are the media types request bodies may have, such as
+	// "multipart/form-data" or "image/*". Empty allows any.
+	ContentTypes []string
+
+	// Skip reports whether a request should not be checked
+	Skip func(c *fiber.Ctx) bool
+}
+
+// BodyGuard returns a middleware rejecting request bodies larger than
+// MaxSize with 400 FILE_TOO_LARGE, and bodies of other content types than
+// ContentTypes with 415 UNSUPPORTED_MEDIA_TYPE, for a route or a group:
+//
+//	app.Post("/avatars", middleware.BodyGuard(middleware.BodyGuardConfig{
+//		MaxSize:      5 << 20,
+//		ContentTypes: []string{"multipart/form-data"},
+//	}), uploadAvatar)
+//
+// The size is checked from the Content-Length header. Fiber reads the body
+// before calling handlers unless the app streams request bodies, so for
+// huge bodies to be rejected before they are read, set StreamRequestBody
+// and DisablePreParseMultipartForm in the Fiber config. BodyLimit is then
+// how much is read before handlers run, and bodies of unknown length are
+// read up to MaxSize by the guard.
+func BodyGuard(config BodyGuardConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if config.Skip != nil && config.Skip(c) {
+			return c.Next()
+		}
+
+		req := c.Request()
+		length := req.Header.ContentLength()
+		if length == 0 {
+			return c.Next()
+		}
+
+		if len(config.ContentTypes) > 0 {
+			contentType := mediaType(c.Get(fiber.HeaderContentType))
+			if !matchMediaType(contentType, config.ContentTypes) {
+				return reject(c, errors.UnsupportedMediaTypeError(contentType))
+			}
+		}
+
+		if config.MaxSize <= 0 {
+			return c.Next()
+		}
+		if int64(length) > config.MaxSize {
+			return reject(c, errors.FileTooLargeError(int64(length), config.MaxSize))
+		}
+		if length < 0 {
+			// Chunked bodies of unknown length
+			size, err := limitBody(c, config.MaxSize)
+			if err != nil {
+				return reject(c, err)
+			}
+			if size > config.MaxSize {
+				return reject(c, errors.FileTooLargeError(size, config.MaxSize))
+			}
+		}
+		return c.Next()
+	}
+}
+
+// limitBody returns the size of a body of unknown length, reading a
+// streamed body up to max bytes and buffering what was read. It returns
+// max+1 for bodies larger than max.
+func limitBody(c *fiber.Ctx, max int64) (int64, error) {
+	req := c.Request()
+	stream := req.BodyStream()
+	if stream == nil {
+		return int64(len(req.Body())), nil
+	}
+	var buf bytes.Buffer
+	size, err := io.Copy(&buf, io.LimitReader(stream, max+1))
+	if err != nil {
+		return 0, errors.InvalidRequestError("The request body could not be read")
+	}
+	if size <= max {
+		req.SetBody(buf.Bytes())
+	}
+	return size, nil
+}
+
+// reject sends the error response of a rejected body and closes the
+// connection, so the rest of the body is not read as the next request
+func reject(c *fiber.Ctx, err error) error {
+	c.Set(fiber.HeaderConnection, "close")
+	return response.Error(c, err)
+}
+
+// mediaType returns the media type of a Content-Type header, in lower case
+// and without parameters
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// matchMediaType reports whether a media type is one of the allowed ones,
+// which may end with "/*"
+func matchMediaType(contentType string, allowed []string) bool {
+	if contentType == "" {
+		return false
+	}
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == contentType || pattern == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
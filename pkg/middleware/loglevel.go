@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultLogLevelPath is the conventional route for LogLevelHandler
+const DefaultLogLevelPath = "/internal/loglevel"
+
+// logLevelRequest is the body accepted by LogLevelHandler
+type logLevelRequest struct {
+	Name  string `json:"name" form:"name"`
+	Level string `json:"level" form:"level"`
+}
+
+// LogLevelHandler returns a handler that reports the logger level on GET and
+// changes it on PUT, with the level in a JSON body ({"level":"debug"}) or the
+// level query parameter. A name ({"name":"filesystem","level":"debug"} or the
+// name query parameter) targets a named logger instead. Mount it on an
+// internal or authenticated route:
+//
+//	admin.All(middleware.DefaultLogLevelPath, middleware.LogLevelHandler(log))
+func LogLevelHandler(l *logger.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet:
+			target := l
+			if name := c.Query("name"); name != "" {
+				target = logger.Named(name)
+			}
+			return response.Success(c, "Current log level", fiber.Map{
+				"level":  levelName(logger.LogLevel(target.Level())),
+				"levels": namedLevelNames(),
+			})
+
+		case fiber.MethodPut:
+			req := logLevelRequest{Name: c.Query("name"), Level: c.Query("level")}
+			if req.Level == "" && len(c.Body()) > 0 {
+				if err := c.BodyParser(&req); err != nil {
+					return response.BadRequest(c, "Invalid request body", err.Error())
+				}
+			}
+
+			level, ok := logger.ParseLevel(req.Level)
+			if !ok {
+				return response.BadRequest(c, "Invalid log level", fiber.Map{
+					"level":   req.Level,
+					"allowed": []string{"debug", "info", "warn", "error", "fatal"},
+				})
+			}
+
+			if req.Name != "" {
+				previous := logger.LogLevel(logger.Named(req.Name).Level())
+				logger.SetNamedLevel(req.Name, level)
+				l.Infof("Log level of %s changed from %s to %s", req.Name, previous, level)
+
+				return response.Success(c, "Log level updated", fiber.Map{
+					"name":     req.Name,
+					"level":    levelName(level),
+					"previous": levelName(previous),
+				})
+			}
+
+			previous := logger.LogLevel(l.Level())
+			l.SetLevel(uint8(level))
+			l.Infof("Log level changed from %s to %s", previous, level)
+
+			return response.Success(c, "Log level updated", fiber.Map{
+				"level":    levelName(level),
+				"previous": levelName(previous),
+			})
+
+		default:
+			return response.MethodNotAllowed(c, "Use GET or PUT")
+		}
+	}
+}
+
+// namedLevelNames returns the levels set for named loggers by name
+func namedLevelNames() map[string]string {
+	levels := make(map[string]string)
+	for name, level := range logger.NamedLevels() {
+		levels[name] = levelName(level)
+	}
+	return levels
+}
+
+// levelName returns the lowercase name of a level
+func levelName(level logger.LogLevel) string {
+	return strings.ToLower(level.String())
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecoverConfig configures the recovery middleware
+type RecoverConfig struct {
+	// DisableStackTrace leaves the stack out of the logged entry
+	DisableStackTrace bool
+
+	// OnPanic is called with every recovered panic after it is logged, e.g.
+	// to report it to an error tracker
+	OnPanic func(c *fiber.Ctx, err *PanicError)
+}
+
+// PanicError is a recovered panic, the Internal error of the AppError sent
+// for it. It unwraps to the panic value when that is an error.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error returns the panic value
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Recover returns a middleware that turns panics of later handlers into 500
+// INTERNAL_ERROR responses, in place of Fiber's recover middleware. The
+// panic and its stack are logged at ERROR level with the request fields,
+// and the error response carries the request ID in its details:
+//
+//	{"success":false,"code":500,"error":"INTERNAL_ERROR","message":"Internal server error","details":{"requestId":"..."}}
+//
+// Register it after the request ID and access log middleware so panicking
+// requests are logged with their status.
+func Recover(l *logger.Logger, config ...RecoverConfig) fiber.Handler {
+	cfg := RecoverConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if l == nil {
+		l = logger.Default()
+	}
+
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			panicErr := &PanicError{Value: r, Stack: debug.Stack()}
+			id := requestID(c)
+
+			fields := logger.Fields{
+				"method": c.Method(),
+				"path":   c.Path(),
+			}
+			if id != "" {
+				fields[logger.FieldRequestID] = id
+			}
+			if !cfg.DisableStackTrace {
+				fields["stack"] = string(panicErr.Stack)
+			}
+			l.Ctx(c.UserContext()).WithFields(fields).Errorf("Handler panicked: %v", r)
+
+			if cfg.OnPanic != nil {
+				cfg.OnPanic(c, panicErr)
+			}
+
+			appErr := errors.InternalServerError("")
+			appErr.Internal = panicErr
+			if id != "" {
+				appErr = errors.WithDetails(appErr, map[string]interface{}{"requestId": id})
+			}
+			err = response.Error(c, appErr)
+		}()
+
+		return c.Next()
+	}
+}
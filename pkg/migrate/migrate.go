@@ -0,0 +1,333 @@
+// Package migrate applies and reverts versioned SQL migrations, recording the
+// version in a schema_migrations table compatible with golang-migrate
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultTable is the table recording the schema version
+const DefaultTable = "schema_migrations"
+
+// Config configures a migrator
+type Config struct {
+	// Dir is the directory of the migrations in the file system, defaulting
+	// to its root
+	Dir string
+
+	// Table defaults to DefaultTable
+	Table string
+
+	// Logger defaults to logger.Named("migrate")
+	Logger *logger.Logger
+}
+
+// Migrator applies the migrations of a file system to a database. Postgres
+// and MySQL are locked while migrating, so service instances starting
+// together apply each migration once.
+//
+// Each file is executed as a whole, so MySQL needs multiStatements=true in
+// its DSN for files with several statements.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	config     Config
+}
+
+// Status is the state of a migration
+type Status struct {
+	Version uint64
+	Name    string
+	Applied bool
+
+	// Current is set for the migration of the schema version, and Dirty when
+	// it failed halfway
+	Current bool
+	Dirty   bool
+}
+
+// DirtyError is returned when the last migration failed halfway; fix the
+// schema by hand, then set the version with Force
+type DirtyError struct {
+	Version uint64
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("migrate: database is dirty at version %d, fix the schema and force a version", e.Version)
+}
+
+// New creates a migrator for the migrations of a file system, e.g. an
+// embed.FS or os.DirFS("migrations")
+func New(db *gorm.DB, fsys fs.FS, config ...Config) (*Migrator, error) {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Table == "" {
+		cfg.Table = DefaultTable
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("migrate")
+	}
+
+	migrations, err := Load(fsys, cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, config: cfg}, nil
+}
+
+// Migrations returns the migrations in version order
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Version returns the schema version, 0 when no migration has been applied,
+// and whether the last migration failed halfway
+func (m *Migrator) Version(ctx context.Context) (version uint64, dirty bool, err error) {
+	err = m.run(ctx, func(conn *gorm.DB) error {
+		version, dirty, err = m.version(conn)
+		return err
+	})
+	return version, dirty, err
+}
+
+// Status returns the state of every migration
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: migration.Version <= version && !(dirty && migration.Version == version),
+			Current: migration.Version == version,
+		}
+		statuses[i].Dirty = statuses[i].Current && dirty
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.UpTo(ctx, 0)
+}
+
+// UpTo applies the pending migrations up to a version, or all of them when
+// the version is 0, and returns how many were applied
+func (m *Migrator) UpTo(ctx context.Context, target uint64) (int, error) {
+	applied := 0
+	err := m.run(ctx, func(conn *gorm.DB) error {
+		version, dirty, err := m.version(conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return &DirtyError{Version: version}
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			if target > 0 && migration.Version > target {
+				break
+			}
+			if err := m.apply(conn, migration, migration.Up, version, migration.Version); err != nil {
+				return err
+			}
+			version = migration.Version
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, or all of them when steps
+// is 0 or less, and returns how many were reverted
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.run(ctx, func(conn *gorm.DB) error {
+		version, dirty, err := m.version(conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return &DirtyError{Version: version}
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			if steps > 0 && reverted >= steps {
+				break
+			}
+			if migration.Version != version {
+				return fmt.Errorf("migrate: applied version %d has no migration file", version)
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migrate: migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			var previous uint64
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := m.apply(conn, migration, migration.Down, version, previous); err != nil {
+				return err
+			}
+			version = previous
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force sets the schema version without running migrations and clears the
+// dirty flag, after fixing a failed migration by hand. Version 0 means no
+// migration is applied.
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	return m.run(ctx, func(conn *gorm.DB) error {
+		return m.setVersion(conn, version, false)
+	})
+}
+
+// apply runs the SQL of a migration, moving the schema from one version to
+// another. The database is marked dirty while it runs; when the migration
+// fails on a database with transactional DDL, the rollback undoes it and
+// the previous version is restored.
+func (m *Migrator) apply(conn *gorm.DB, migration Migration, sql string, from, to uint64) error {
+	direction := "Applied"
+	if to < from {
+		direction = "Reverted"
+	}
+	start := time.Now()
+
+	dirtyVersion := to
+	if to < from {
+		dirtyVersion = from
+	}
+	if err := m.setVersion(conn, dirtyVersion, true); err != nil {
+		return err
+	}
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+		return m.setVersion(tx, to, false)
+	})
+	if err != nil {
+		if transactionalDDL(conn) {
+			if restoreErr := m.setVersion(conn, from, false); restoreErr != nil {
+				m.config.Logger.Errorf("Failed to restore version %d: %v", from, restoreErr)
+			}
+		}
+		return fmt.Errorf("migrate: migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+
+	m.config.Logger.Infof("%s migration %d_%s in %s", direction, migration.Version, migration.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// run runs fn on a single connection holding the migration lock
+func (m *Migrator) run(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		unlock, err := m.lock(conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		err = conn.Exec("CREATE TABLE IF NOT EXISTS ? (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)",
+			clause.Table{Name: m.config.Table}).Error
+		if err != nil {
+			return fmt.Errorf("migrate: create %s: %w", m.config.Table, err)
+		}
+		return fn(conn)
+	})
+}
+
+// lock takes the migration lock of Postgres and MySQL, waiting for other
+// migrators to finish
+func (m *Migrator) lock(conn *gorm.DB) (unlock func(), err error) {
+	id := int64(crc32.ChecksumIEEE([]byte("gokit:migrate:" + m.config.Table)))
+	name := fmt.Sprintf("gokit_migrate_%d", id)
+
+	switch conn.Dialector.Name() {
+	case "postgres":
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", id).Error; err != nil {
+			return nil, fmt.Errorf("migrate: lock: %w", err)
+		}
+		return func() { conn.Exec("SELECT pg_advisory_unlock(?)", id) }, nil
+
+	case "mysql":
+		var locked int
+		if err := conn.Raw("SELECT GET_LOCK(?, 600)", name).Scan(&locked).Error; err != nil {
+			return nil, fmt.Errorf("migrate: lock: %w", err)
+		}
+		if locked != 1 {
+			return nil, fmt.Errorf("migrate: timed out waiting for the migration lock")
+		}
+		return func() { conn.Exec("SELECT RELEASE_LOCK(?)", name) }, nil
+
+	default:
+		return func() {}, nil
+	}
+}
+
+// version reads the schema version
+func (m *Migrator) version(conn *gorm.DB) (uint64, bool, error) {
+	var rows []struct {
+		Version uint64
+		Dirty   bool
+	}
+	err := conn.Raw("SELECT version, dirty FROM ? LIMIT 1", clause.Table{Name: m.config.Table}).Scan(&rows).Error
+	if err != nil {
+		return 0, false, fmt.Errorf("migrate: read version: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, false, nil
+	}
+	return rows[0].Version, rows[0].Dirty, nil
+}
+
+// setVersion records the schema version, leaving the table empty for 0
+func (m *Migrator) setVersion(conn *gorm.DB, version uint64, dirty bool) error {
+	table := clause.Table{Name: m.config.Table}
+	if err := conn.Exec("DELETE FROM ?", table).Error; err != nil {
+		return fmt.Errorf("migrate: set version: %w", err)
+	}
+	if version == 0 && !dirty {
+		return nil
+	}
+	if err := conn.Exec("INSERT INTO ? (version, dirty) VALUES (?, ?)", table, version, dirty).Error; err != nil {
+		return fmt.Errorf("migrate: set version: %w", err)
+	}
+	return nil
+}
+
+// transactionalDDL reports whether schema changes of a database are rolled
+// back with their transaction
+func transactionalDDL(conn *gorm.DB) bool {
+	switch conn.Dialector.Name() {
+	case "postgres", "sqlite", "sqlserver":
+		return true
+	default:
+		return false
+	}
+}
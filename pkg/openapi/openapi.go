@@ -0,0 +1,393 @@
+// Package openapi builds an OpenAPI 3 document of the routes of an app, for
+// documentation and client generation. Routes are described with the DTOs
+// they bind, whose json, form, query, and validate tags become the schemas
+// of their bodies and parameters:
+//
+//	spec := openapi.New(openapi.Info{Title: "Users", Version: "1.0.0"})
+//	spec.Handle(api, fiber.MethodPost, "/users", openapi.Route{
+//		Summary:  "Create a user",
+//		Request:  CreateUserRequest{},
+//		Response: User{},
+//		Status:   fiber.StatusCreated,
+//	}, createUser)
+//	app.Mount(spec) // GET /openapi.json
+//
+// The CRUD routes of crud.Register and the file routes of a filesystem
+// provider describe themselves when given the spec.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/anaknegeri/gokit/pkg/pagination"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Version is the OpenAPI version of the documents
+const Version = "3.0.3"
+
+// DefaultPath serves the document in Routes
+const DefaultPath = "/openapi.json"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	Tags       []Tag               `json:"tags,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a URL the API is served at
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lowercase method
+type PathItem map[string]*Operation
+
+// Operation is an operation of a path
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	OperationID string               `json:"operationId"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+// Parameter is a path, query, or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request by content type
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType is the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Components holds the schemas referenced by the operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is a JSON schema, in the OpenAPI 3.0 dialect
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+}
+
+// Route describes an operation by the DTOs of its handler
+type Route struct {
+	Summary     string
+	Description string
+	Tags        []string
+
+	// OperationID names the operation in generated clients, defaulting to
+	// the method and path, e.g. "getUsersById"
+	OperationID string
+
+	// Params is bound with binding.BindParams, typing the path parameters
+	// by their params tags. Parameters it lacks are strings.
+	Params interface{}
+
+	// Query is bound with binding.BindQuery, its query tags are the query
+	// parameters
+	Query interface{}
+
+	// Request is the body bound with binding.BindAndValidate: JSON, or a
+	// multipart form by its form tags when it has *multipart.FileHeader
+	// fields
+	Request interface{}
+
+	// Response is the data of the response envelope, or the type of the
+	// items of a paginated response
+	Response interface{}
+
+	// Paginated responses are sent with response.SuccessWithPagination, with
+	// the page parameters of PageParams, defaulting to ?page=2&pageSize=20,
+	// and a sort parameter
+	Paginated  bool
+	PageParams *pagination.ParamConfig
+
+	// Body is the whole success body, for handlers that do not send the
+	// response envelope. It is sent as JSON unless Produces is set.
+	Body interface{}
+
+	// Produces is the content type of the success body, e.g.
+	// "application/octet-stream" for downloads, whose body is not described
+	Produces string
+
+	// Status is the status of the success response, defaulting to 200
+	Status int
+
+	// Errors are the statuses of the error responses besides the default,
+	// 400 and 422 being added for routes that bind a body or query
+	Errors []int
+
+	Deprecated bool
+}
+
+// operation is a described route
+type operation struct {
+	method string
+	path   string
+	route  Route
+}
+
+// Spec collects the routes of an app and builds its document
+type Spec struct {
+	mu         sync.Mutex
+	info       Info
+	servers    []Server
+	operations []operation
+	document   []byte
+}
+
+// New creates a spec of an API
+func New(info Info, servers ...Server) *Spec {
+	return &Spec{info: info, servers: servers}
+}
+
+// Describe adds a route mounted on a router to the document. The path uses
+// the Fiber syntax and is relative to the prefix of the router, which may be
+// nil for full paths.
+func (s *Spec) Describe(router fiber.Router, method, path string, route Route) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operations = append(s.operations, operation{
+		method: strings.ToUpper(method),
+		path:   prefix(router) + path,
+		route:  route,
+	})
+	s.document = nil
+}
+
+// Handle mounts the handlers of a route on a router and describes it
+func (s *Spec) Handle(router fiber.Router, method, path string, route Route, handlers ...fiber.Handler) fiber.Router {
+	s.Describe(router, method, path, route)
+	return router.Add(strings.ToUpper(method), path, handlers...)
+}
+
+// Document builds the document of the described routes
+func (s *Spec) Document() *Document {
+	s.mu.Lock()
+	operations := append([]operation(nil), s.operations...)
+	s.mu.Unlock()
+
+	b := newBuilder(response.CurrentConfig())
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.info,
+		Servers: s.servers,
+		Paths:   map[string]PathItem{},
+	}
+	ids := map[string]int{}
+	tags := map[string]bool{}
+	for _, op := range operations {
+		path, params := openAPIPath(op.path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		built := b.operation(op.method, path, params, op.route)
+		if ids[built.OperationID]++; ids[built.OperationID] > 1 {
+			built.OperationID += strconv.Itoa(ids[built.OperationID])
+		}
+		item[strings.ToLower(op.method)] = built
+		for _, tag := range built.Tags {
+			if !tags[tag] {
+				tags[tag] = true
+				doc.Tags = append(doc.Tags, Tag{Name: tag})
+			}
+		}
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = b.components
+	return doc
+}
+
+// Handler returns a handler serving the document as JSON
+func (s *Spec) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		s.mu.Lock()
+		cached := s.document
+		s.mu.Unlock()
+
+		if cached == nil {
+			body, err := c.App().Config().JSONEncoder(s.Document())
+			if err != nil {
+				return err
+			}
+			s.mu.Lock()
+			s.document, cached = body, body
+			s.mu.Unlock()
+		}
+
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(cached)
+	}
+}
+
+// Routes serves the document at DefaultPath, so the spec can be mounted on
+// a server app
+func (s *Spec) Routes(router fiber.Router) {
+	router.Get(DefaultPath, s.Handler())
+}
+
+// prefix returns the path prefix of a router
+func prefix(router fiber.Router) string {
+	if group, ok := router.(*fiber.Group); ok {
+		return strings.TrimSuffix(group.Prefix, "/")
+	}
+	return ""
+}
+
+// pathParam is a parameter of a path
+type pathParam struct {
+	name     string
+	wildcard bool
+}
+
+// openAPIPath converts a Fiber path to an OpenAPI path, returning its
+// parameters. Wildcards become a "path" parameter.
+func openAPIPath(path string) (string, []pathParam) {
+	var params []pathParam
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name := strings.TrimRight(segment[1:], "?")
+			params = append(params, pathParam{name: name})
+			segments[i] = "{" + name + "}"
+		case segment == "*" || segment == "+" || strings.HasPrefix(segment, "*") && isDigits(segment[1:]):
+			name := "path"
+			if len(segment) > 1 {
+				name += segment[1:]
+			}
+			params = append(params, pathParam{name: name, wildcard: true})
+			segments[i] = "{" + name + "}"
+		}
+	}
+	path = strings.Join(segments, "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path, params
+}
+
+// isDigits reports whether s is a non-empty string of digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// operationID names an operation by its method and path, e.g.
+// "getUsersById" for GET /users/{id}
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			id.WriteString("By")
+			segment = strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+		}) {
+			id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return id.String()
+}
+
+// statusKey returns the key of a response status, e.g. "200"
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
+
+// statusDescription describes a response status
+func statusDescription(status int) string {
+	if text := http.StatusText(status); text != "" {
+		return text
+	}
+	return fmt.Sprintf("Status %d", status)
+}
+
+// dereference returns the type of a value, without pointers
+func dereference(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
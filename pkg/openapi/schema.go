@@ -0,0 +1,599 @@
+package openapi
+
+import (
+	"database/sql"
+	"encoding"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/anaknegeri/gokit/pkg/pagination"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// errorSchema names the schema of error responses
+const errorSchema = "ErrorResponse"
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	nullTimeType    = reflect.TypeOf(sql.NullTime{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+	uuidType        = reflect.TypeOf(uuid.UUID{})
+	fileType        = reflect.TypeOf(multipart.FileHeader{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage(nil))
+	jsonMarshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	formatsByRule   = map[string]string{"email": "email", "url": "uri", "uri": "uri", "http_url": "uri", "uuid": "uuid", "uuid4": "uuid", "uuid_rfc4122": "uuid", "uuid4_rfc4122": "uuid", "ipv4": "ipv4", "ipv6": "ipv6", "hostname": "hostname", "fqdn": "hostname"}
+	patternsByRule  = map[string]string{"alpha": "^[a-zA-Z]+$", "alphanum": "^[a-zA-Z0-9]+$", "numeric": `^[-+]?[0-9]+(?:\.[0-9]+)?$`, "number": "^[0-9]+$", "hexadecimal": "^(0[xX])?[0-9a-fA-F]+$", "e164": `^\+[1-9]?[0-9]{7,14}$`}
+	typeArgSplitter = regexp.MustCompile(`[\[\],]`)
+)
+
+// schemaKey identifies the schema of a type, which differs between
+// requests and responses when responses convert their keys
+type schemaKey struct {
+	t        reflect.Type
+	response bool
+}
+
+// builder builds the operations and the schemas they reference
+type builder struct {
+	config     response.Config
+	convert    func(string) string
+	converts   bool
+	components map[string]*Schema
+	names      map[schemaKey]string
+	owners     map[string]schemaKey
+}
+
+// newBuilder creates a builder of the bodies written with a response
+// configuration
+func newBuilder(config response.Config) *builder {
+	return &builder{
+		config:     config,
+		convert:    config.ConvertKey,
+		converts:   config.KeyCase != response.KeyCasePassThrough,
+		components: map[string]*Schema{},
+		names:      map[schemaKey]string{},
+		owners:     map[string]schemaKey{},
+	}
+}
+
+// key returns the key of a response body, written in the configured case
+func (b *builder) key(name string, response bool) string {
+	if response {
+		return b.convert(name)
+	}
+	return name
+}
+
+// operation builds the operation of a route
+func (b *builder) operation(method, path string, params []pathParam, route Route) *Operation {
+	op := &Operation{
+		Tags:        route.Tags,
+		Summary:     route.Summary,
+		Description: route.Description,
+		OperationID: route.OperationID,
+		Deprecated:  route.Deprecated,
+		Responses:   map[string]*Response{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(method, path)
+	}
+
+	// Path parameters, typed by the params DTO
+	paramFields := map[string]structField{}
+	if t := dereference(route.Params); t != nil && t.Kind() == reflect.Struct {
+		for _, field := range b.fields(t, "params") {
+			paramFields[field.name] = field
+		}
+	}
+	for _, param := range params {
+		p := Parameter{Name: param.name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		if field, ok := paramFields[param.name]; ok {
+			p.Schema, _ = b.fieldSchema(field, false)
+		}
+		if param.wildcard {
+			p.Description = "Path of the file, which may contain slashes"
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+
+	// Query parameters
+	if t := dereference(route.Query); t != nil && t.Kind() == reflect.Struct {
+		for _, field := range b.fields(t, "query") {
+			schema, required := b.fieldSchema(field, false)
+			op.Parameters = append(op.Parameters, Parameter{Name: field.name, In: "query", Required: required, Schema: schema})
+		}
+	}
+	if route.Paginated {
+		op.Parameters = append(op.Parameters, pageParameters(route.PageParams)...)
+	}
+
+	// Request body
+	if t := dereference(route.Request); t != nil {
+		body := &RequestBody{Required: true, Content: map[string]MediaType{}}
+		if t.Kind() == reflect.Struct && hasFiles(t) {
+			body.Content[fiber.MIMEMultipartForm] = MediaType{Schema: b.object(t, "form", false)}
+		} else {
+			body.Content[fiber.MIMEApplicationJSON] = MediaType{Schema: b.schema(t, false)}
+		}
+		op.RequestBody = body
+	}
+
+	// Success response
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: statusDescription(status)}
+	switch {
+	case status == http.StatusNoContent:
+	case route.Produces != "":
+		schema := &Schema{Type: "string", Format: "binary"}
+		if t := dereference(route.Body); t != nil {
+			schema = b.schema(t, true)
+		}
+		success.Content = map[string]MediaType{route.Produces: {Schema: schema}}
+	case route.Body != nil:
+		success.Content = map[string]MediaType{fiber.MIMEApplicationJSON: {Schema: b.schema(dereference(route.Body), true)}}
+	default:
+		success.Content = map[string]MediaType{fiber.MIMEApplicationJSON: {Schema: b.envelope(route)}}
+	}
+	op.Responses[statusKey(status)] = success
+
+	// Error responses
+	errors := append([]int(nil), route.Errors...)
+	if route.Request != nil || route.Query != nil {
+		errors = append(errors, http.StatusBadRequest, http.StatusUnprocessableEntity)
+	}
+	slices.Sort(errors)
+	for _, code := range slices.Compact(errors) {
+		op.Responses[statusKey(code)] = b.errorResponse(statusDescription(code))
+	}
+	op.Responses["default"] = b.errorResponse("Error")
+	return op
+}
+
+// pageParameters are the query parameters of paginated routes
+func pageParameters(config *pagination.ParamConfig) []Parameter {
+	cfg := pagination.ParamConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.PageParam == "" {
+		cfg.PageParam = "page"
+	}
+	if len(cfg.PageSizeParams) == 0 {
+		cfg.PageSizeParams = []string{"pageSize"}
+	}
+
+	one := 1.0
+	params := []Parameter{}
+	if cfg.OffsetParam != "" {
+		zero := 0.0
+		params = append(params, Parameter{Name: cfg.OffsetParam, In: "query", Description: "Zero-based offset of the page", Schema: &Schema{Type: "integer", Minimum: &zero}})
+	} else {
+		params = append(params, Parameter{Name: cfg.PageParam, In: "query", Description: "One-based page number", Schema: &Schema{Type: "integer", Minimum: &one}})
+	}
+	params = append(params,
+		Parameter{Name: cfg.PageSizeParams[0], In: "query", Description: "Number of items per page", Schema: &Schema{Type: "integer", Minimum: &one}},
+		Parameter{Name: "sort", In: "query", Description: `Fields to sort by, descending with a leading "-", e.g. -createdAt,name`, Schema: &Schema{Type: "string"}},
+	)
+	return params
+}
+
+// envelope returns the schema of the response envelope of a route
+func (b *builder) envelope(route Route) *Schema {
+	fields := b.config.Fields
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	add := func(name string, property *Schema, required bool) {
+		if name == "-" || property == nil {
+			return
+		}
+		name = b.key(name, true)
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	add(fields.Success, &Schema{Type: "boolean"}, true)
+	add(fields.Code, &Schema{Type: "integer"}, true)
+	add(fields.Message, &Schema{Type: "string"}, true)
+	if t := dereference(route.Response); t != nil {
+		data := b.schema(t, true)
+		if route.Paginated {
+			data = &Schema{Type: "array", Items: data}
+		}
+		add(fields.Data, data, false)
+	}
+	if route.Paginated {
+		add(fields.Meta, b.schema(reflect.TypeOf(pagination.PaginationMeta{}), true), true)
+		add(fields.Links, b.schema(reflect.TypeOf(pagination.Links{}), true), false)
+	}
+	for _, property := range b.metadata() {
+		add(property.name, property.schema, false)
+	}
+	return schema
+}
+
+// namedSchema is a property of an envelope
+type namedSchema struct {
+	name   string
+	schema *Schema
+}
+
+// metadata returns the metadata fields enabled in the response configuration
+func (b *builder) metadata() []namedSchema {
+	meta, fields := b.config.Metadata, b.config.Fields
+	var properties []namedSchema
+	if meta.APIVersion != "" {
+		properties = append(properties, namedSchema{fields.APIVersion, &Schema{Type: "string", Enum: []interface{}{meta.APIVersion}}})
+	}
+	if meta.ServerTime {
+		properties = append(properties, namedSchema{fields.ServerTime, &Schema{Type: "string", Format: "date-time"}})
+	}
+	if meta.Duration {
+		properties = append(properties, namedSchema{fields.Duration, &Schema{Type: "number", Description: "Time spent on the request in milliseconds"}})
+	}
+	if meta.RequestID {
+		properties = append(properties, namedSchema{fields.RequestID, &Schema{Type: "string"}})
+	}
+	return properties
+}
+
+// errorResponse returns a response with the error envelope, added to the
+// components on first use
+func (b *builder) errorResponse(description string) *Response {
+	if _, ok := b.components[errorSchema]; !ok {
+		fields := b.config.Fields
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		required := []namedSchema{
+			{fields.Success, &Schema{Type: "boolean"}},
+			{fields.Code, &Schema{Type: "integer"}},
+			{fields.Error, &Schema{Type: "string", Description: "Error code, e.g. NOT_FOUND"}},
+			{fields.ErrorMessage, &Schema{Type: "string"}},
+		}
+		optional := append([]namedSchema{
+			{fields.Details, &Schema{Description: "Details of the error, e.g. the failed validations"}},
+		}, b.metadata()...)
+		for i, property := range append(required, optional...) {
+			if property.name == "-" {
+				continue
+			}
+			name := b.key(property.name, true)
+			schema.Properties[name] = property.schema
+			if i < len(required) {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		b.components[errorSchema] = schema
+	}
+	return &Response{
+		Description: description,
+		Content: map[string]MediaType{
+			fiber.MIMEApplicationJSON: {Schema: &Schema{Ref: "#/components/schemas/" + errorSchema}},
+		},
+	}
+}
+
+// schema returns the schema of a type, a reference for exported named
+// structs, which are added to the components
+func (b *builder) schema(t reflect.Type, response bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "Duration in nanoseconds"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	if t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler) {
+		// Such as gorm.DeletedAt
+		if t.ConvertibleTo(nullTimeType) {
+			return &Schema{Type: "string", Format: "date-time", Nullable: true}
+		}
+		if t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+			return &Schema{Type: "string"}
+		}
+		return &Schema{}
+	}
+	if t.Implements(textMarshaler) || reflect.PointerTo(t).Implements(textMarshaler) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem(), response)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem(), response)}
+	case reflect.Struct:
+		if t.Name() == "" || !unicode.IsUpper([]rune(t.Name())[0]) {
+			return b.object(t, "json", response)
+		}
+		return &Schema{Ref: "#/components/schemas/" + b.component(t, response)}
+	default:
+		return &Schema{}
+	}
+}
+
+// component adds the schema of a named struct to the components, returning
+// its name
+func (b *builder) component(t reflect.Type, response bool) string {
+	// Both directions share a schema unless responses convert their keys
+	key := schemaKey{t: t, response: response && b.converts}
+	if name, ok := b.names[key]; ok {
+		return name
+	}
+
+	name := componentName(t)
+	if b.converts && !key.response {
+		name += "Input"
+	}
+	if owner, ok := b.owners[name]; ok && owner != key {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		for i := 2; ; i++ {
+			if owner, ok := b.owners[name]; !ok || owner == key {
+				break
+			}
+			name = strings.TrimRight(name, "0123456789") + strconv.Itoa(i)
+		}
+	}
+
+	// Registered before the fields for recursive types
+	b.names[key] = name
+	b.owners[name] = key
+	b.components[name] = b.object(t, "json", response)
+	return name
+}
+
+// componentName names the schema of a type, e.g. "ResultUser" for
+// pagination.Result[models.User]
+func componentName(t reflect.Type) string {
+	parts := typeArgSplitter.Split(t.Name(), -1)
+	var name strings.Builder
+	for _, part := range parts {
+		part = part[strings.LastIndex(part, ".")+1:]
+		part = part[strings.LastIndex(part, "/")+1:]
+		part = strings.TrimLeft(part, "*")
+		if part != "" {
+			name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return name.String()
+}
+
+// object returns the inline schema of the fields of a struct by their tag
+func (b *builder) object(t reflect.Type, tag string, response bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, field := range b.fields(t, tag) {
+		property, required := b.fieldSchema(field, response)
+		name := field.name
+		if tag == "json" {
+			name = b.key(name, response)
+		}
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// structField is a field of a struct as its tag names it
+type structField struct {
+	name  string
+	field reflect.StructField
+}
+
+// fields returns the fields of a struct by the names of their tag, with the
+// fields of embedded structs without a name promoted like encoding/json does
+func (b *builder) fields(t reflect.Type, tag string) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, b.fields(embedded, tag)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{name: name, field: field})
+	}
+	return fields
+}
+
+// fieldSchema returns the schema of a field, constrained by its validate
+// tag, and whether the field is required
+func (b *builder) fieldSchema(field structField, response bool) (*Schema, bool) {
+	t := field.field.Type
+	schema := b.schema(t, response)
+	if t.Kind() == reflect.Pointer && schema.Ref == "" && t.Elem() != fileType {
+		schema.Nullable = true
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	required := false
+	target := schema
+	for _, rule := range strings.Split(field.field.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch {
+		case name == "required" && target == schema:
+			required = true
+		case name == "dive":
+			// Later rules apply to the items
+			if target.Items == nil {
+				return schema, required
+			}
+			target = target.Items
+			for t = t.Elem(); t.Kind() == reflect.Pointer; t = t.Elem() {
+			}
+		case target.Ref == "" && !strings.Contains(rule, "|"):
+			applyRule(target, t.Kind(), name, param)
+		}
+	}
+	return schema, required
+}
+
+// applyRule constrains a schema with a validation rule of its value
+func applyRule(schema *Schema, kind reflect.Kind, rule, param string) {
+	if format, ok := formatsByRule[rule]; ok {
+		schema.Format = format
+		return
+	}
+	if pattern, ok := patternsByRule[rule]; ok {
+		schema.Pattern = pattern
+		return
+	}
+
+	n, err := strconv.ParseFloat(param, 64)
+	numeric := err == nil
+	var lower, upper **int
+	switch kind {
+	case reflect.String:
+		lower, upper = &schema.MinLength, &schema.MaxLength
+	case reflect.Slice, reflect.Array, reflect.Map:
+		lower, upper = &schema.MinItems, &schema.MaxItems
+	}
+	count := func(delta int) *int {
+		v := int(n) + delta
+		return &v
+	}
+
+	switch rule {
+	case "min", "gte", "gt":
+		if !numeric {
+			return
+		}
+		if lower == nil {
+			schema.Minimum = &n
+			schema.ExclusiveMinimum = rule == "gt"
+		} else if rule == "gt" {
+			*lower = count(1)
+		} else {
+			*lower = count(0)
+		}
+	case "max", "lte", "lt":
+		if !numeric {
+			return
+		}
+		if upper == nil {
+			schema.Maximum = &n
+			schema.ExclusiveMaximum = rule == "lt"
+		} else if rule == "lt" {
+			*upper = count(-1)
+		} else {
+			*upper = count(0)
+		}
+	case "len":
+		if numeric && lower != nil {
+			*lower, *upper = count(0), count(0)
+		}
+	case "oneof":
+		schema.Enum = nil
+		for _, value := range oneOfValues(param) {
+			if f, err := strconv.ParseFloat(value, 64); err == nil && schema.Type != "string" {
+				schema.Enum = append(schema.Enum, f)
+			} else {
+				schema.Enum = append(schema.Enum, value)
+			}
+		}
+	case "datetime":
+		if param == time.DateOnly {
+			schema.Format = "date"
+		} else if param == time.RFC3339 {
+			schema.Format = "date-time"
+		}
+	case "startswith":
+		schema.Pattern = "^" + regexp.QuoteMeta(param)
+	case "endswith":
+		schema.Pattern = regexp.QuoteMeta(param) + "$"
+	}
+}
+
+// oneOfValues splits the values of a oneof rule, which may be quoted to
+// hold spaces, e.g. "red 'dark blue'"
+func oneOfValues(param string) []string {
+	var values []string
+	for param = strings.TrimSpace(param); param != ""; param = strings.TrimSpace(param) {
+		if param[0] == '\'' {
+			if end := strings.IndexByte(param[1:], '\''); end >= 0 {
+				values = append(values, param[1:end+1])
+				param = param[end+2:]
+				continue
+			}
+		}
+		value, rest, _ := strings.Cut(param, " ")
+		values = append(values, value)
+		param = rest
+	}
+	return values
+}
+
+// hasFiles reports whether a struct has uploaded file fields, making it a
+// multipart form
+func hasFiles(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i).Type
+		if ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Pointer && ft.Elem() == fileType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package pagination
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/cache"
+	"gorm.io/gorm"
+)
+
+// CountMode is how the paginator counts the total number of records
+type CountMode string
+
+// Count modes
+const (
+	// CountExact runs COUNT(*) for every page
+	CountExact CountMode = "exact"
+
+	// CountEstimated uses the PostgreSQL planner's row estimate, which is
+	// fast on large tables but approximate. Other databases count exactly.
+	CountEstimated CountMode = "estimated"
+
+	// CountCached runs COUNT(*) and reuses the result for the same query
+	// until the cache TTL expires
+	CountCached CountMode = "cached"
+
+	// CountNone skips counting. Total and TotalPages are -1 and HasNext tells
+	// whether there is another page.
+	CountNone CountMode = "none"
+)
+
+// DefaultCountCacheTTL is how long CountCached reuses a count by default
+const DefaultCountCacheTTL = time.Minute
+
+// DefaultCountCacheSize is how many counts the default count cache holds
+const DefaultCountCacheSize = 1000
+
+// SetCountMode sets how the total number of records is counted. Counting
+// every page with COUNT(*) dominates list latency on tables with millions of
+// rows, where an estimate, a cached count, or no count at all is often enough.
+func (p *Paginator) SetCountMode(mode CountMode) *Paginator {
+	p.countMode = mode
+	return p
+}
+
+// SetCountCache sets the cache of CountCached, e.g. a Redis cache shared by
+// every instance of the app. It defaults to an in-memory cache of
+// DefaultCountCacheSize counts per paginator.
+func (p *Paginator) SetCountCache(c cache.Cache) *Paginator {
+	p.countCacheMu.Lock()
+	defer p.countCacheMu.Unlock()
+	p.countCache = c
+	return p
+}
+
+// SetCountCacheTTL sets how long CountCached reuses a count
+func (p *Paginator) SetCountCacheTTL(ttl time.Duration) *Paginator {
+	p.countCacheTTL = ttl
+	return p
+}
+
+// mode returns the configured count mode, defaulting to CountExact
+func (p *Paginator) mode() CountMode {
+	if p.countMode == "" {
+		return CountExact
+	}
+	return p.countMode
+}
+
+// count counts the records of the query in the configured mode, returning
+// the mode that was used, which is CountExact when no estimate is available
+func (p *Paginator) count(query *gorm.DB) (int64, CountMode, error) {
+	switch p.mode() {
+	case CountEstimated:
+		total, estimated, err := estimateCount(query)
+		if err != nil || estimated {
+			return total, CountEstimated, err
+		}
+		total, err = exactCount(query)
+		return total, CountExact, err
+	case CountCached:
+		total, err := p.cachedCount(query)
+		return total, CountCached, err
+	default:
+		total, err := exactCount(query)
+		return total, CountExact, err
+	}
+}
+
+// exactCount counts the records with COUNT(*)
+func exactCount(query *gorm.DB) (int64, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// cachedCount returns the cached count for the query, counting it when the
+// cached count is missing or expired
+func (p *Paginator) cachedCount(query *gorm.DB) (int64, error) {
+	sql := query.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var total int64
+		return tx.Count(&total)
+	})
+	sum := sha256.Sum256([]byte(sql))
+	key := "pagination:count:" + hex.EncodeToString(sum[:])
+
+	ttl := p.countCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultCountCacheTTL
+	}
+
+	ctx := query.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	value, err := p.getCountCache().GetOrSet(ctx, key, ttl, func(context.Context) ([]byte, error) {
+		total, err := exactCount(query)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatInt(total, 10)), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+// getCountCache returns the count cache, creating an in-memory cache on
+// first use
+func (p *Paginator) getCountCache() cache.Cache {
+	p.countCacheMu.Lock()
+	defer p.countCacheMu.Unlock()
+	if p.countCache == nil {
+		p.countCache = cache.NewMemory(DefaultCountCacheSize)
+	}
+	return p.countCache
+}
+
+// estimateCount estimates the records from the PostgreSQL statistics: the
+// table's reltuples in pg_class for queries without conditions or joins, and
+// the planner's row estimate from EXPLAIN otherwise. It counts exactly on
+// other databases and on tables that have not been analyzed yet, which is
+// reported by estimated being false.
+func estimateCount(query *gorm.DB) (total int64, estimated bool, err error) {
+	if query.Dialector.Name() != "postgres" {
+		return 0, false, nil
+	}
+
+	stmt := query.Statement
+	if stmt.Table == "" && stmt.Model != nil {
+		if err := stmt.Parse(stmt.Model); err != nil {
+			return 0, false, err
+		}
+	}
+
+	_, hasWhere := stmt.Clauses["WHERE"]
+	if !hasWhere && len(stmt.Joins) == 0 && stmt.Table != "" {
+		var estimate float64
+		err := query.Session(&gorm.Session{NewDB: true}).
+			Raw("SELECT reltuples FROM pg_class WHERE oid = to_regclass(?)", stmt.Table).
+			Scan(&estimate).Error
+		if err != nil {
+			return 0, false, err
+		}
+		return int64(estimate), estimate >= 0, nil
+	}
+
+	// Ask the planner how many rows the query returns
+	dry := query.Session(&gorm.Session{DryRun: true}).Find(map[string]interface{}{})
+	if dry.Error != nil {
+		return 0, false, dry.Error
+	}
+
+	var plan string
+	err = query.Session(&gorm.Session{NewDB: true}).
+		Raw("EXPLAIN (FORMAT JSON) "+dry.Statement.SQL.String(), dry.Statement.Vars...).
+		Scan(&plan).Error
+	if err != nil {
+		return 0, false, err
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &plans); err != nil || len(plans) == 0 {
+		return 0, false, nil
+	}
+	return int64(plans[0].Plan.Rows), true, nil
+}
@@ -0,0 +1,242 @@
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// CursorParams represents keyset pagination parameters
+type CursorParams struct {
+	// Cursor is the nextCursor or prevCursor from a previous page, or empty
+	// for the first page
+	Cursor string `json:"cursor,omitempty" query:"cursor"`
+
+	// Limit is the number of items per page
+	Limit int `json:"limit" query:"limit"`
+
+	// Filter is applied in the same way as for offset pagination
+	Filter FilterParams `json:"filter,omitempty" query:"-"`
+}
+
+// CursorMeta contains metadata about keyset pagination results
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	HasNext    bool   `json:"hasNext"`
+	HasPrev    bool   `json:"hasPrev"`
+}
+
+// CursorResult represents keyset paginated results with data and metadata
+type CursorResult struct {
+	Data interface{} `json:"data"`
+	Meta CursorMeta  `json:"meta"`
+}
+
+// cursor is the decoded form of an opaque cursor
+type cursor struct {
+	// Values are the cursor column values of the boundary row
+	Values []json.RawMessage `json:"v"`
+
+	// Prev is set for cursors that page backwards
+	Prev bool `json:"p,omitempty"`
+}
+
+// SetCursorColumns sets the columns used for keyset pagination, e.g.
+// SortField{Field: "created_at", Direction: SortDesc}, SortField{Field: "id"}.
+// Together the columns must be unique and not null. Defaults to "id" ascending.
+func (p *Paginator) SetCursorColumns(columns ...SortField) *Paginator {
+	p.cursorColumns = columns
+	return p
+}
+
+// PaginateCursor performs keyset pagination on a database query. Instead of
+// skipping rows with OFFSET, each page continues from the cursor column values
+// of the previous page, so large tables can be paged through in constant time
+// per page when the cursor columns are indexed.
+func (p *Paginator) PaginateCursor(params CursorParams, result interface{}, scopes ...Scope) (*CursorResult, error) {
+	return p.PaginateCursorQuery(p.db, params, result, scopes...)
+}
+
+// PaginateCursorQuery performs keyset pagination on a query built for this call
+func (p *Paginator) PaginateCursorQuery(query *gorm.DB, params CursorParams, result interface{}, scopes ...Scope) (*CursorResult, error) {
+	// Default to 10 items per page if limit is invalid
+	if params.Limit <= 0 {
+		params.Limit = 10
+	}
+	params.Limit = p.limitPageSize(params.Limit)
+
+	columns := p.cursorColumns
+	if len(columns) == 0 {
+		columns = []SortField{{Field: "id", Direction: SortAsc}}
+	}
+
+	// Resolve the fields of the cursor columns on the result model
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(result); err != nil {
+		return nil, err
+	}
+	fields := make([]*cursorField, len(columns))
+	for i, column := range columns {
+		field := stmt.Schema.LookUpField(column.Field)
+		if field == nil {
+			return nil, fmt.Errorf("pagination: cursor column %q is not a field of %s", column.Field, stmt.Schema.Name)
+		}
+		fields[i] = &cursorField{SortField: column, field: field}
+	}
+
+	// Validate and apply the filters
+	query, err := p.applyFilters(p.baseQuery(query, result, scopes), params.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the cursor and continue after or before its row
+	var current cursor
+	if params.Cursor != "" {
+		if current, err = decodeCursor(params.Cursor, len(fields)); err != nil {
+			return nil, err
+		}
+		condition, err := keysetCondition(fields, current)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition)
+	}
+
+	// Order by the cursor columns, reversed when paging backwards
+	for _, field := range fields {
+		query = query.Order(clause.OrderByColumn{
+			Column: field.column(),
+			Desc:   (field.Direction == SortDesc) != current.Prev,
+		})
+	}
+
+	// Fetch one extra row to know whether there is another page
+	if err := p.applyLoading(query.Limit(params.Limit + 1)).Find(result).Error; err != nil {
+		return nil, err
+	}
+
+	rows := reflect.ValueOf(result).Elem()
+	hasMore := rows.Len() > params.Limit
+	if hasMore {
+		rows.Set(rows.Slice(0, params.Limit))
+	}
+
+	meta := CursorMeta{Limit: params.Limit}
+	if current.Prev {
+		// Restore the requested order after paging backwards
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+		meta.HasPrev = hasMore
+		meta.HasNext = true
+	} else {
+		meta.HasNext = hasMore
+		meta.HasPrev = params.Cursor != ""
+	}
+
+	// Encode the cursors from the first and last rows
+	if rows.Len() > 0 {
+		if meta.HasNext {
+			if meta.NextCursor, err = encodeCursor(fields, rows.Index(rows.Len()-1), false); err != nil {
+				return nil, err
+			}
+		}
+		if meta.HasPrev {
+			if meta.PrevCursor, err = encodeCursor(fields, rows.Index(0), true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &CursorResult{
+		Data: result,
+		Meta: meta,
+	}, nil
+}
+
+// cursorField is a cursor column resolved on the result model
+type cursorField struct {
+	SortField
+	field *schema.Field
+}
+
+// column returns the column qualified with the model's table, so it stays
+// unambiguous when the query has joins
+func (f *cursorField) column() clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: f.field.DBName}
+}
+
+// keysetCondition builds the condition for the rows after the cursor, or
+// before it for backward cursors:
+//
+//	a > ? OR (a = ? AND b > ?) OR (a = ? AND b = ? AND c > ?)
+func keysetCondition(fields []*cursorField, c cursor) (clause.Expression, error) {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value := reflect.New(field.field.FieldType)
+		if err := json.Unmarshal(c.Values[i], value.Interface()); err != nil {
+			return nil, errors.InvalidCursorError()
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	var branches []clause.Expression
+	for i, field := range fields {
+		var conditions []clause.Expression
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, clause.Eq{Column: fields[j].column(), Value: values[j]})
+		}
+
+		column := field.column()
+		if (field.Direction == SortDesc) != c.Prev {
+			conditions = append(conditions, clause.Lt{Column: column, Value: values[i]})
+		} else {
+			conditions = append(conditions, clause.Gt{Column: column, Value: values[i]})
+		}
+		branches = append(branches, clause.And(conditions...))
+	}
+	return clause.Or(branches...), nil
+}
+
+// encodeCursor encodes the cursor column values of a row as an opaque cursor
+func encodeCursor(fields []*cursorField, row reflect.Value, prev bool) (string, error) {
+	c := cursor{Values: make([]json.RawMessage, len(fields)), Prev: prev}
+	for i, field := range fields {
+		value, _ := field.field.ValueOf(context.Background(), reflect.Indirect(row))
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		c.Values[i] = raw
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes an opaque cursor with the expected number of values
+func decodeCursor(value string, columns int) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, errors.InvalidCursorError()
+	}
+	if err := json.Unmarshal(data, &c); err != nil || len(c.Values) != columns {
+		return c, errors.InvalidCursorError()
+	}
+	return c, nil
+}
@@ -0,0 +1,300 @@
+package pagination
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FilterOperator is a comparison applied by a filter
+type FilterOperator string
+
+// Filter operators
+const (
+	OpEq   FilterOperator = "eq"
+	OpNe   FilterOperator = "ne"
+	OpGt   FilterOperator = "gt"
+	OpGte  FilterOperator = "gte"
+	OpLt   FilterOperator = "lt"
+	OpLte  FilterOperator = "lte"
+	OpIn   FilterOperator = "in"
+	OpLike FilterOperator = "like"
+	OpNull FilterOperator = "null"
+)
+
+// FilterType is the type filter values are converted to
+type FilterType string
+
+// Filter types
+const (
+	FilterString FilterType = "string"
+	FilterInt    FilterType = "int"
+	FilterFloat  FilterType = "float"
+	FilterBool   FilterType = "bool"
+	FilterTime   FilterType = "time"
+)
+
+// FilterField describes a field clients may filter by
+type FilterField struct {
+	// Column is the database column
+	Column string
+
+	// Type is the type values are converted to, defaulting to FilterString
+	Type FilterType
+
+	// Operators are the allowed operators. When empty, every operator that
+	// makes sense for the type is allowed.
+	Operators []FilterOperator
+}
+
+// FilterSchema is the allowlist of filterable fields for a model
+type FilterSchema struct {
+	// Fields maps field names used in the query string to their columns
+	Fields map[string]FilterField
+
+	// SearchColumns are matched against the q parameter with a
+	// case-insensitive contains match
+	SearchColumns []string
+}
+
+// Filter is a single condition from the query string
+type Filter struct {
+	Field    string         `json:"field"`
+	Operator FilterOperator `json:"operator"`
+	Value    string         `json:"value"`
+}
+
+// FilterParams are the filters and search term of a request
+type FilterParams struct {
+	Filters []Filter `json:"filters,omitempty"`
+	Search  string   `json:"q,omitempty"`
+}
+
+// IsEmpty reports whether there are no filters and no search term
+func (f FilterParams) IsEmpty() bool {
+	return len(f.Filters) == 0 && f.Search == ""
+}
+
+// filterKeyPattern matches filter[field] and filter[field][operator]
+var filterKeyPattern = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// ParseFilters parses filters from query parameters such as
+// filter[status]=active, filter[age][gte]=18, filter[role][in]=admin,editor,
+// and q=smith
+func ParseFilters(query map[string]string) FilterParams {
+	var params FilterParams
+	for key, value := range query {
+		if key == "q" {
+			params.Search = strings.TrimSpace(value)
+			continue
+		}
+
+		match := filterKeyPattern.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+
+		operator := OpEq
+		if match[2] != "" {
+			operator = FilterOperator(strings.ToLower(match[2]))
+		}
+		params.Filters = append(params.Filters, Filter{
+			Field:    match[1],
+			Operator: operator,
+			Value:    value,
+		})
+	}
+
+	// Sort filters so the generated SQL is stable
+	sort.Slice(params.Filters, func(i, j int) bool {
+		if params.Filters[i].Field != params.Filters[j].Field {
+			return params.Filters[i].Field < params.Filters[j].Field
+		}
+		return params.Filters[i].Operator < params.Filters[j].Operator
+	})
+
+	return params
+}
+
+// Apply adds the where clauses for the filters and search term to the query.
+// Filters on fields or with operators outside the schema are rejected.
+func (s FilterSchema) Apply(query *gorm.DB, params FilterParams) (*gorm.DB, error) {
+	for _, filter := range params.Filters {
+		field, ok := s.Fields[filter.Field]
+		if !ok {
+			return nil, errors.InvalidFilterError(filter.Field, "field is not filterable")
+		}
+
+		expr, err := field.expression(filter)
+		if err != nil {
+			return nil, errors.InvalidFilterError(filter.Field, err.Error())
+		}
+		query = query.Where(expr)
+	}
+
+	if params.Search != "" && len(s.SearchColumns) > 0 {
+		pattern := "%" + escapeLike(strings.ToLower(params.Search)) + "%"
+		conditions := make([]clause.Expression, 0, len(s.SearchColumns))
+		for _, column := range s.SearchColumns {
+			conditions = append(conditions, clause.Expr{
+				SQL:  "LOWER(?) LIKE ? ESCAPE '!'",
+				Vars: []interface{}{clause.Column{Name: column}, pattern},
+			})
+		}
+		query = query.Where(clause.Or(conditions...))
+	}
+
+	return query, nil
+}
+
+// expression builds the clause for a filter on the field
+func (f FilterField) expression(filter Filter) (clause.Expression, error) {
+	if !f.allows(filter.Operator) {
+		return nil, fmt.Errorf("operator %q is not allowed", filter.Operator)
+	}
+	column := clause.Column{Name: f.Column}
+
+	switch filter.Operator {
+	case OpNull:
+		isNull, err := strconv.ParseBool(filter.Value)
+		if err != nil {
+			return nil, fmt.Errorf("value %q must be true or false", filter.Value)
+		}
+		if isNull {
+			return clause.Eq{Column: column, Value: nil}, nil
+		}
+		return clause.Neq{Column: column, Value: nil}, nil
+
+	case OpIn:
+		var values []interface{}
+		for _, part := range strings.Split(filter.Value, ",") {
+			value, err := f.convert(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return clause.IN{Column: column, Values: values}, nil
+
+	case OpLike:
+		return clause.Expr{
+			SQL:  "LOWER(?) LIKE ? ESCAPE '!'",
+			Vars: []interface{}{column, "%" + escapeLike(strings.ToLower(filter.Value)) + "%"},
+		}, nil
+	}
+
+	value, err := f.convert(filter.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filter.Operator {
+	case OpEq:
+		return clause.Eq{Column: column, Value: value}, nil
+	case OpNe:
+		return clause.Neq{Column: column, Value: value}, nil
+	case OpGt:
+		return clause.Gt{Column: column, Value: value}, nil
+	case OpGte:
+		return clause.Gte{Column: column, Value: value}, nil
+	case OpLt:
+		return clause.Lt{Column: column, Value: value}, nil
+	case OpLte:
+		return clause.Lte{Column: column, Value: value}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", filter.Operator)
+	}
+}
+
+// allows reports whether the operator may be used on the field
+func (f FilterField) allows(operator FilterOperator) bool {
+	operators := f.Operators
+	if len(operators) == 0 {
+		operators = defaultOperators(f.Type)
+	}
+	for _, allowed := range operators {
+		if allowed == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultOperators returns the operators allowed for a type when none are configured
+func defaultOperators(t FilterType) []FilterOperator {
+	switch t {
+	case FilterInt, FilterFloat, FilterTime:
+		return []FilterOperator{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNull}
+	case FilterBool:
+		return []FilterOperator{OpEq, OpNe, OpNull}
+	default:
+		return []FilterOperator{OpEq, OpNe, OpIn, OpLike, OpNull}
+	}
+}
+
+// convert converts a query string value to the field type
+func (f FilterField) convert(value string) (interface{}, error) {
+	switch f.Type {
+	case FilterInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q must be an integer", value)
+		}
+		return n, nil
+	case FilterFloat:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q must be a number", value)
+		}
+		return n, nil
+	case FilterBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("value %q must be true or false", value)
+		}
+		return b, nil
+	case FilterTime:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("value %q must be a date (2006-01-02) or RFC 3339 time", value)
+	default:
+		return value, nil
+	}
+}
+
+// escapeLike escapes LIKE wildcards using "!" as the escape character, which
+// behaves the same on every supported database
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// SetFilterSchema sets the fields clients may filter and search by. Filters
+// are rejected when no schema is set.
+func (p *Paginator) SetFilterSchema(schema FilterSchema) *Paginator {
+	p.filterSchema = &schema
+	return p
+}
+
+// applyFilters adds the filter clauses to the query
+func (p *Paginator) applyFilters(query *gorm.DB, params FilterParams) (*gorm.DB, error) {
+	if params.IsEmpty() {
+		return query, nil
+	}
+	if p.filterSchema == nil {
+		if len(params.Filters) > 0 {
+			return nil, errors.InvalidFilterError(params.Filters[0].Field, "field is not filterable")
+		}
+		return query, nil
+	}
+	return p.filterSchema.Apply(query, params)
+}
@@ -0,0 +1,147 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type filterUser struct {
+	ID     int
+	Name   string
+	Status string
+	Age    int
+}
+
+// newFilterDB returns an in-memory database with a few users
+func newFilterDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&filterUser{}); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	users := []filterUser{
+		{ID: 1, Name: "Alice", Status: "active", Age: 30},
+		{ID: 2, Name: "Bob", Status: "inactive", Age: 17},
+		{ID: 3, Name: "100% Carol", Status: "active", Age: 45},
+		{ID: 4, Name: "dan_smith", Status: "active", Age: 25},
+		{ID: 5, Name: "danXsmith", Status: "active", Age: 52},
+		{ID: 6, Name: "Robert'); DROP TABLE filter_users;--", Status: "active", Age: 60},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("Failed to insert users: %v", err)
+	}
+	return db
+}
+
+func TestFilterSchemaApply(t *testing.T) {
+	db := newFilterDB(t)
+	schema := FilterSchema{
+		Fields: map[string]FilterField{
+			"name":   {Column: "name"},
+			"status": {Column: "status", Operators: []FilterOperator{OpEq, OpIn}},
+			"age":    {Column: "age", Type: FilterInt},
+		},
+		SearchColumns: []string{"name"},
+	}
+
+	tests := []struct {
+		name    string
+		query   map[string]string
+		ids     []int
+		invalid bool
+	}{
+		{"equal", map[string]string{"filter[status]": "inactive"}, []int{2}, false},
+		{"in", map[string]string{"filter[status][in]": "inactive, unknown"}, []int{2}, false},
+		{"range", map[string]string{"filter[age][gte]": "25", "filter[age][lt]": "50"}, []int{1, 3, 4}, false},
+		{"like", map[string]string{"filter[name][like]": "ALI"}, []int{1}, false},
+		{"search", map[string]string{"q": " bob "}, []int{2}, false},
+		{"null", map[string]string{"filter[name][null]": "false", "filter[age][lte]": "17"}, []int{2}, false},
+
+		// LIKE wildcards in values match themselves
+		{"percent", map[string]string{"filter[name][like]": "100%"}, []int{3}, false},
+		{"underscore", map[string]string{"filter[name][like]": "dan_"}, []int{4}, false},
+		{"search percent", map[string]string{"q": "%"}, []int{3}, false},
+		{"escape character", map[string]string{"q": "!"}, nil, false},
+
+		// Injection-shaped values are compared as values
+		{"quote in value", map[string]string{"filter[name]": "Robert'); DROP TABLE filter_users;--"}, []int{6}, false},
+		{"tautology", map[string]string{"filter[name]": "' OR '1'='1"}, nil, false},
+		{"tautology like", map[string]string{"filter[name][like]": "' OR 1=1 --"}, nil, false},
+		{"tautology int", map[string]string{"filter[age]": "1 OR 1=1"}, nil, true},
+
+		// Fields and operators outside the schema are refused
+		{"unknown field", map[string]string{"filter[password]": "x"}, nil, true},
+		{"column name as field", map[string]string{"filter[id]": "1"}, nil, true},
+		{"injection in field", map[string]string{"filter[name = name OR 1]": "1"}, nil, true},
+		{"operator not allowed", map[string]string{"filter[status][like]": "act"}, nil, true},
+		{"operator for another type", map[string]string{"filter[age][like]": "3"}, nil, true},
+		{"unknown operator", map[string]string{"filter[name][regex]": ".*"}, nil, true},
+		{"bad int", map[string]string{"filter[age][gt]": "old"}, nil, true},
+		{"bad null", map[string]string{"filter[name][null]": "maybe"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := schema.Apply(db.Model(&filterUser{}), ParseFilters(tt.query))
+			if tt.invalid {
+				if !errors.Is(err, errors.ErrInvalidFilter) {
+					t.Errorf("Expected ErrInvalidFilter, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var ids []int
+			if err := query.Order("id").Pluck("id", &ids).Error; err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("Expected users %v, got %v", tt.ids, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("Expected users %v, got %v", tt.ids, ids)
+				}
+			}
+		})
+	}
+
+	var count int64
+	if err := db.Model(&filterUser{}).Count(&count).Error; err != nil || count != 6 {
+		t.Errorf("Expected the table to be intact, got %d rows and %v", count, err)
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	params := ParseFilters(map[string]string{
+		"filter[age][GTE]": "18",
+		"filter[status]":   "active",
+		"filter[a][b][c]":  "ignored",
+		"filter[]":         "ignored",
+		"page":             "2",
+		"q":                "  smith ",
+	})
+	want := []Filter{
+		{Field: "age", Operator: OpGte, Value: "18"},
+		{Field: "status", Operator: OpEq, Value: "active"},
+	}
+	if len(params.Filters) != len(want) || params.Search != "smith" {
+		t.Fatalf("Unexpected filters: %+v", params)
+	}
+	for i := range want {
+		if params.Filters[i] != want[i] {
+			t.Errorf("Expected filter %+v, got %+v", want[i], params.Filters[i])
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pagination
+
+import "gorm.io/gorm"
+
+// Result represents paginated results of type T with metadata
+type Result[T any] struct {
+	Data []T            `json:"data"`
+	Meta PaginationMeta `json:"meta"`
+}
+
+// Paginate performs pagination on a database query and returns the page as a
+// []T. The model defaults to T when the query has none, and scopes narrow
+// the query:
+//
+//	result, err := pagination.Paginate[User](db, params, func(q *gorm.DB) *gorm.DB {
+//		return q.Where("age > ?", 18)
+//	})
+func Paginate[T any](db *gorm.DB, params PaginationParams, scopes ...Scope) (*Result[T], error) {
+	return PaginateWith[T](NewPaginator(db), params, scopes...)
+}
+
+// PaginateWith performs pagination with a configured paginator, e.g. one with
+// sortable fields or a filter schema, and returns the page as a []T
+func PaginateWith[T any](p *Paginator, params PaginationParams, scopes ...Scope) (*Result[T], error) {
+	// Start from an empty slice so an empty page is encoded as [] rather than null
+	items := make([]T, 0)
+	result, err := p.Paginate(params, &items, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result[T]{
+		Data: items,
+		Meta: result.Meta,
+	}, nil
+}
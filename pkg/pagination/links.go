@@ -0,0 +1,95 @@
+package pagination
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Links are the URLs of the pages around the current page
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// BuildLinks builds the page links for an offset paginated request. The
+// request's other query parameters, such as sort and filters, are kept. Last
+// is omitted when the total is unknown.
+func BuildLinks(requestURL *url.URL, meta PaginationMeta) Links {
+	page := func(n int) string {
+		return withQuery(requestURL, map[string]string{
+			"page":     strconv.Itoa(n),
+			"pageSize": strconv.Itoa(meta.PageSize),
+		})
+	}
+
+	links := Links{
+		Self:  requestURL.String(),
+		First: page(1),
+	}
+	if meta.Page > 1 {
+		links.Prev = page(meta.Page - 1)
+	}
+	if meta.HasNext {
+		links.Next = page(meta.Page + 1)
+	}
+	if meta.TotalPages > 0 {
+		links.Last = page(meta.TotalPages)
+	}
+	return links
+}
+
+// BuildCursorLinks builds the page links for a keyset paginated request.
+// First drops the cursor; there is no last page link.
+func BuildCursorLinks(requestURL *url.URL, meta CursorMeta) Links {
+	limit := strconv.Itoa(meta.Limit)
+	links := Links{
+		Self:  requestURL.String(),
+		First: withQuery(requestURL, map[string]string{"cursor": "", "limit": limit}),
+	}
+	if meta.PrevCursor != "" {
+		links.Prev = withQuery(requestURL, map[string]string{"cursor": meta.PrevCursor, "limit": limit})
+	}
+	if meta.NextCursor != "" {
+		links.Next = withQuery(requestURL, map[string]string{"cursor": meta.NextCursor, "limit": limit})
+	}
+	return links
+}
+
+// Header returns the links as an RFC 8288 Link header value, e.g.
+//
+//	<https://api.example.com/users?page=3&pageSize=10>; rel="next"
+func (l Links) Header() string {
+	var parts []string
+	for _, link := range []struct{ rel, url string }{
+		{"first", l.First},
+		{"prev", l.Prev},
+		{"next", l.Next},
+		{"last", l.Last},
+	} {
+		if link.url != "" {
+			parts = append(parts, "<"+link.url+`>; rel="`+link.rel+`"`)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// withQuery returns the URL with query parameters replaced, removing the
+// parameters with empty values
+func withQuery(u *url.URL, params map[string]string) string {
+	query := u.Query()
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+	}
+
+	copied := *u
+	copied.RawQuery = query.Encode()
+	return copied.String()
+}
@@ -3,14 +3,20 @@ package pagination
 
 import (
 	"math"
+	"reflect"
+	"sync"
+	"time"
 
+	"github.com/anaknegeri/gokit/pkg/cache"
 	"gorm.io/gorm"
 )
 
 // PaginationParams represents pagination parameters
 type PaginationParams struct {
-	Page     int `json:"page" query:"page"`
-	PageSize int `json:"pageSize" query:"pageSize"`
+	Page     int          `json:"page" query:"page"`
+	PageSize int          `json:"pageSize" query:"pageSize"`
+	Sort     []SortField  `json:"sort,omitempty" query:"-"`
+	Filter   FilterParams `json:"filter,omitempty" query:"-"`
 }
 
 // PaginationMeta contains metadata about pagination results
@@ -19,6 +25,16 @@ type PaginationMeta struct {
 	Page       int   `json:"page"`
 	PageSize   int   `json:"pageSize"`
 	TotalPages int   `json:"totalPages"`
+
+	// HasNext reports whether there is a page after this one
+	HasNext bool `json:"hasNext"`
+
+	// CountMode is how Total was counted. Total is approximate for
+	// CountEstimated and -1, like TotalPages, for CountNone.
+	CountMode CountMode `json:"countMode,omitempty"`
+
+	// Sort is the order applied to the results
+	Sort []SortField `json:"sort,omitempty"`
 }
 
 // PaginationResult represents paginated results with data and metadata
@@ -27,20 +43,50 @@ type PaginationResult struct {
 	Meta PaginationMeta `json:"meta"`
 }
 
+// Scope narrows a paginated query, e.g. with conditions or joins
+type Scope func(*gorm.DB) *gorm.DB
+
 // Paginator handles paginating database queries
 type Paginator struct {
-	db *gorm.DB
+	db           *gorm.DB
+	sortable     map[string]string
+	defaultSort  []SortField
+	filterSchema *FilterSchema
+
+	cursorColumns []SortField
+
+	preloads []association
+	joins    []join
+	selects  []string
+
+	countMode     CountMode
+	countCacheTTL time.Duration
+	countCache    cache.Cache
+	countCacheMu  sync.Mutex
+
+	maxPageSize int
 }
 
 // NewPaginator creates a new paginator with the provided database connection
 func NewPaginator(db *gorm.DB) *Paginator {
 	return &Paginator{
-		db: db,
+		db:          db,
+		maxPageSize: DefaultMaxPageSize,
 	}
 }
 
-// Paginate performs pagination on a database query
-func (p *Paginator) Paginate(params PaginationParams, result interface{}) (*PaginationResult, error) {
+// Paginate performs pagination on the paginator's database query, narrowed by
+// the optional scopes
+func (p *Paginator) Paginate(params PaginationParams, result interface{}, scopes ...Scope) (*PaginationResult, error) {
+	return p.PaginateQuery(p.db, params, result, scopes...)
+}
+
+// PaginateQuery performs pagination on a query built for this call, e.g.
+//
+//	paginator.PaginateQuery(db.Model(&User{}).Where("age > ?", 18), params, &users)
+//
+// The paginator's sortable fields and filter schema still apply.
+func (p *Paginator) PaginateQuery(query *gorm.DB, params PaginationParams, result interface{}, scopes ...Scope) (*PaginationResult, error) {
 	// Default to page 1 if page is invalid
 	if params.Page <= 0 {
 		params.Page = 1
@@ -50,13 +96,62 @@ func (p *Paginator) Paginate(params PaginationParams, result interface{}) (*Pagi
 	if params.PageSize <= 0 {
 		params.PageSize = 10
 	}
+	params.PageSize = p.limitPageSize(params.PageSize)
 
 	// Calculate offset for the query
-	offset := (params.Page - 1) * params.PageSize
+	offset, err := pageOffset("page", params.Page, params.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate and apply the filters, which both the count and the page query use
+	filtered, err := p.applyFilters(p.baseQuery(query, result, scopes), params.Filter)
+	if err != nil {
+		return nil, err
+	}
+	filtered = filtered.Session(&gorm.Session{})
+
+	// Validate and apply the sort order
+	sortFields := params.Sort
+	if len(sortFields) == 0 {
+		sortFields = p.defaultSort
+	}
+	sorted, err := p.applySort(filtered, sortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := p.mode()
+	if mode == CountNone {
+		// Fetch one extra row to know whether there is another page
+		page := p.applyLoading(sorted.Limit(params.PageSize + 1).Offset(offset))
+		if err := page.Find(result).Error; err != nil {
+			return nil, err
+		}
+
+		rows := reflect.ValueOf(result).Elem()
+		hasNext := rows.Len() > params.PageSize
+		if hasNext {
+			rows.Set(rows.Slice(0, params.PageSize))
+		}
+
+		return &PaginationResult{
+			Data: result,
+			Meta: PaginationMeta{
+				Total:      -1,
+				Page:       params.Page,
+				PageSize:   params.PageSize,
+				TotalPages: -1,
+				HasNext:    hasNext,
+				CountMode:  mode,
+				Sort:       sortFields,
+			},
+		}, nil
+	}
 
 	// Get total count of records
-	var total int64
-	if err := p.db.Count(&total).Error; err != nil {
+	total, mode, err := p.count(filtered)
+	if err != nil {
 		return nil, err
 	}
 
@@ -64,7 +159,8 @@ func (p *Paginator) Paginate(params PaginationParams, result interface{}) (*Pagi
 	totalPages := int(math.Ceil(float64(total) / float64(params.PageSize)))
 
 	// Execute the query with pagination
-	if err := p.db.Limit(params.PageSize).Offset(offset).Find(result).Error; err != nil {
+	page := p.applyLoading(sorted.Limit(params.PageSize).Offset(offset))
+	if err := page.Find(result).Error; err != nil {
 		return nil, err
 	}
 
@@ -76,16 +172,75 @@ func (p *Paginator) Paginate(params PaginationParams, result interface{}) (*Pagi
 			Page:       params.Page,
 			PageSize:   params.PageSize,
 			TotalPages: totalPages,
+			HasNext:    params.Page < totalPages,
+			CountMode:  mode,
+			Sort:       sortFields,
 		},
 	}, nil
 }
 
-// GetParams extracts pagination parameters from a request context
+// Query returns the paginator's query with the filters, sort order, joins,
+// and loading of params applied but without a limit, e.g. to export every
+// matching row. result is the slice the rows are loaded into, which sets the
+// model when the query has none.
+func (p *Paginator) Query(params PaginationParams, result interface{}, scopes ...Scope) (*gorm.DB, error) {
+	filtered, err := p.applyFilters(p.baseQuery(p.db, result, scopes), params.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sortFields := params.Sort
+	if len(sortFields) == 0 {
+		sortFields = p.defaultSort
+	}
+	sorted, err := p.applySort(filtered, sortFields)
+	if err != nil {
+		return nil, err
+	}
+	return p.applyLoading(sorted), nil
+}
+
+// baseQuery returns a new session of the query with the joins and scopes
+// applied, so conditions never leak into a shared handle. The model defaults
+// to the result when the query has no model or table, which Count needs.
+func (p *Paginator) baseQuery(query *gorm.DB, result interface{}, scopes []Scope) *gorm.DB {
+	query = query.Session(&gorm.Session{})
+	if query.Statement.Model == nil && query.Statement.Table == "" {
+		query = query.Model(result)
+	}
+	query = p.applyJoins(query)
+	for _, scope := range scopes {
+		query = scope(query)
+	}
+	return query
+}
+
+// GetParams extracts pagination parameters from a request context, including
+// the sort order from ?sort=-createdAt,name and the filters from
+// ?filter[status]=active&filter[age][gte]=18&q=smith
 func GetParams(c interface {
 	QueryInt(string, ...int) int
+	Query(string, ...string) string
+	Queries() map[string]string
 }) PaginationParams {
 	return PaginationParams{
 		Page:     c.QueryInt("page", 1),
 		PageSize: c.QueryInt("pageSize", 10),
+		Sort:     ParseSort(c.Query("sort")),
+		Filter:   ParseFilters(c.Queries()),
+	}
+}
+
+// GetCursorParams extracts keyset pagination parameters from a request
+// context, using ?cursor=...&limit=20 and the same filters as GetParams
+func GetCursorParams(c interface {
+	QueryInt(string, ...int) int
+	Query(string, ...string) string
+	Queries() map[string]string
+}) CursorParams {
+	return CursorParams{
+		Cursor: c.Query("cursor"),
+		Limit:  c.QueryInt("limit", 10),
+		Filter: ParseFilters(c.Queries()),
 	}
 }
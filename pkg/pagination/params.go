@@ -0,0 +1,164 @@
+package pagination
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// Default page sizes
+const (
+	DefaultPageSize    = 10
+	DefaultMaxPageSize = 100
+)
+
+// ParamConfig configures the query parameters GetPaginationFromRequest reads
+type ParamConfig struct {
+	// PageParam is the one-based page number parameter, defaulting to "page"
+	PageParam string
+
+	// PageSizeParams are the page size parameters in order of preference,
+	// defaulting to "pageSize". Use e.g. {"per_page"} or {"limit"}.
+	PageSizeParams []string
+
+	// OffsetParam, when set, is a zero-based row offset that selects the page
+	// instead of PageParam, e.g. "offset" with PageSizeParams {"limit"}. It
+	// must be a multiple of the page size.
+	OffsetParam string
+
+	// DefaultPageSize is used when no page size is given, defaulting to 10
+	DefaultPageSize int
+
+	// MaxPageSize is the largest page size clients may request. Zero means
+	// DefaultMaxPageSize, and negative values disable the limit.
+	MaxPageSize int
+}
+
+// Common parameter configurations
+var (
+	// PageParams reads ?page=2&pageSize=20
+	PageParams = ParamConfig{PageParam: "page", PageSizeParams: []string{"pageSize"}}
+
+	// PerPageParams reads ?page=2&per_page=20
+	PerPageParams = ParamConfig{PageParam: "page", PageSizeParams: []string{"per_page"}}
+
+	// LimitOffsetParams reads ?limit=20&offset=40
+	LimitOffsetParams = ParamConfig{PageSizeParams: []string{"limit"}, OffsetParam: "offset"}
+)
+
+// requestQuery is the request context the parameters are read from
+type requestQuery interface {
+	Query(string, ...string) string
+	Queries() map[string]string
+}
+
+// GetPaginationFromRequest extracts and validates pagination parameters from
+// a request context, including the sort order and filters. Page numbers and
+// sizes that are not positive numbers, or sizes above MaxPageSize, are
+// rejected with errors.ErrInvalidPage.
+func GetPaginationFromRequest(c requestQuery, config ...ParamConfig) (PaginationParams, error) {
+	cfg := PageParams
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.PageParam == "" && cfg.OffsetParam == "" {
+		cfg.PageParam = "page"
+	}
+	if len(cfg.PageSizeParams) == 0 {
+		cfg.PageSizeParams = []string{"pageSize"}
+	}
+	if cfg.DefaultPageSize <= 0 {
+		cfg.DefaultPageSize = DefaultPageSize
+	}
+	if cfg.MaxPageSize == 0 {
+		cfg.MaxPageSize = DefaultMaxPageSize
+	}
+
+	params := PaginationParams{
+		Page:     1,
+		PageSize: cfg.DefaultPageSize,
+		Sort:     ParseSort(c.Query("sort")),
+		Filter:   ParseFilters(c.Queries()),
+	}
+
+	// Read the page size from the first parameter that is present
+	for _, name := range cfg.PageSizeParams {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		size, err := parsePositive(name, value)
+		if err != nil {
+			return params, err
+		}
+		if cfg.MaxPageSize > 0 && size > cfg.MaxPageSize {
+			return params, errors.InvalidPageError(name, fmt.Sprintf("must be at most %d", cfg.MaxPageSize))
+		}
+		params.PageSize = size
+		break
+	}
+
+	// Read the page from the offset or the page number
+	if cfg.OffsetParam != "" {
+		if value := c.Query(cfg.OffsetParam); value != "" {
+			offset, err := strconv.Atoi(value)
+			if err != nil || offset < 0 {
+				return params, errors.InvalidPageError(cfg.OffsetParam, "must be a number of at least 0")
+			}
+			if offset%params.PageSize != 0 {
+				return params, errors.InvalidPageError(cfg.OffsetParam, fmt.Sprintf("must be a multiple of %d", params.PageSize))
+			}
+			params.Page = offset/params.PageSize + 1
+		}
+	} else if value := c.Query(cfg.PageParam); value != "" {
+		page, err := parsePositive(cfg.PageParam, value)
+		if err != nil {
+			return params, err
+		}
+		if _, err := pageOffset(cfg.PageParam, page, params.PageSize); err != nil {
+			return params, err
+		}
+		params.Page = page
+	}
+
+	return params, nil
+}
+
+// pageOffset returns the row offset of a page, rejecting pages whose offset
+// does not fit in an int with errors.ErrInvalidPage
+func pageOffset(name string, page, pageSize int) (int, error) {
+	if maxPage := math.MaxInt/pageSize + 1; page > maxPage {
+		return 0, errors.InvalidPageError(name, fmt.Sprintf("must be at most %d", maxPage))
+	}
+	return (page - 1) * pageSize, nil
+}
+
+// parsePositive parses a parameter that must be a number of at least 1
+func parsePositive(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.InvalidPageError(name, "must be a number of at least 1")
+	}
+	return n, nil
+}
+
+// SetMaxPageSize sets the largest page size Paginate and PaginateCursor
+// return; larger requests are reduced to it. Zero means DefaultMaxPageSize,
+// and negative values disable the limit.
+func (p *Paginator) SetMaxPageSize(size int) *Paginator {
+	if size == 0 {
+		size = DefaultMaxPageSize
+	}
+	p.maxPageSize = size
+	return p
+}
+
+// limitPageSize reduces a page size to the maximum page size
+func (p *Paginator) limitPageSize(size int) int {
+	if p.maxPageSize > 0 && size > p.maxPageSize {
+		return p.maxPageSize
+	}
+	return size
+}
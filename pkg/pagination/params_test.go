@@ -0,0 +1,88 @@
+package pagination
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// fakeQuery is a request with query parameters
+type fakeQuery map[string]string
+
+func (q fakeQuery) Query(key string, defaultValue ...string) string {
+	if value, ok := q[key]; ok {
+		return value
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+func (q fakeQuery) Queries() map[string]string {
+	return q
+}
+
+func TestGetPaginationFromRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    fakeQuery
+		config   []ParamConfig
+		page     int
+		pageSize int
+		invalid  bool
+	}{
+		{"defaults", fakeQuery{}, nil, 1, DefaultPageSize, false},
+		{"page and size", fakeQuery{"page": "3", "pageSize": "20"}, nil, 3, 20, false},
+		{"size above the cap", fakeQuery{"pageSize": "101"}, nil, 0, 0, true},
+		{"raised cap", fakeQuery{"pageSize": "500"}, []ParamConfig{{MaxPageSize: 500}}, 1, 500, false},
+		{"no cap", fakeQuery{"pageSize": "100000"}, []ParamConfig{{MaxPageSize: -1}}, 1, 100000, false},
+		{"zero page", fakeQuery{"page": "0"}, nil, 0, 0, true},
+		{"not a number", fakeQuery{"page": "two"}, nil, 0, 0, true},
+		{"offset overflow", fakeQuery{"page": strconv.Itoa(math.MaxInt)}, nil, 0, 0, true},
+		{"last page", fakeQuery{"page": strconv.Itoa(math.MaxInt/DefaultPageSize + 1)}, nil, math.MaxInt/DefaultPageSize + 1, DefaultPageSize, false},
+		{"offset", fakeQuery{"limit": "20", "offset": "40"}, []ParamConfig{LimitOffsetParams}, 3, 20, false},
+		{"offset not a multiple", fakeQuery{"limit": "20", "offset": "30"}, []ParamConfig{LimitOffsetParams}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := GetPaginationFromRequest(tt.query, tt.config...)
+			if tt.invalid {
+				if !errors.Is(err, errors.ErrInvalidPage) {
+					t.Errorf("Expected ErrInvalidPage, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if params.Page != tt.page || params.PageSize != tt.pageSize {
+				t.Errorf("Expected page %d of %d, got page %d of %d", tt.page, tt.pageSize, params.Page, params.PageSize)
+			}
+		})
+	}
+}
+
+func TestSetMaxPageSize(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+		size int
+		want int
+	}{
+		{"default", 0, 1000, DefaultMaxPageSize},
+		{"raised", 500, 1000, 500},
+		{"below the cap", 500, 20, 20},
+		{"disabled", -1, 1000, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPaginator(nil).SetMaxPageSize(tt.max)
+			if got := p.limitPageSize(tt.size); got != tt.want {
+				t.Errorf("Expected page size %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package pagination
+
+import "gorm.io/gorm"
+
+// association is an association to eager-load with its conditions
+type association struct {
+	name string
+	args []interface{}
+}
+
+// join is a join clause with its arguments
+type join struct {
+	query string
+	args  []interface{}
+}
+
+// Preload eager-loads an association for every page, e.g. Preload("Roles") or
+// Preload("Roles.Permissions"). Conditions are passed to gorm's Preload, e.g.
+// Preload("Orders", "state = ?", "paid"). Associations are loaded with one
+// query per page instead of one per row.
+func (p *Paginator) Preload(name string, args ...interface{}) *Paginator {
+	p.preloads = append(p.preloads, association{name: name, args: args})
+	return p
+}
+
+// Joins adds a join to both the count and the page query, e.g. Joins("Company")
+// for a belongs-to association or Joins("JOIN teams ON teams.id = users.team_id").
+// Qualify columns in sortable fields and filter schemas when they are ambiguous.
+func (p *Paginator) Joins(query string, args ...interface{}) *Paginator {
+	p.joins = append(p.joins, join{query: query, args: args})
+	return p
+}
+
+// Select limits the columns loaded for each page. The count query is not
+// affected. Include the primary key when preloading associations, and the
+// cursor columns when using PaginateCursor.
+func (p *Paginator) Select(columns ...string) *Paginator {
+	p.selects = columns
+	return p
+}
+
+// applyJoins adds the joins to the query
+func (p *Paginator) applyJoins(query *gorm.DB) *gorm.DB {
+	for _, j := range p.joins {
+		query = query.Joins(j.query, j.args...)
+	}
+	return query
+}
+
+// applyLoading adds the selected columns and preloads to the page query
+func (p *Paginator) applyLoading(query *gorm.DB) *gorm.DB {
+	if len(p.selects) > 0 {
+		query = query.Select(p.selects)
+	}
+	for _, a := range p.preloads {
+		query = query.Preload(a.name, a.args...)
+	}
+	return query
+}
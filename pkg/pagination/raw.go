@@ -0,0 +1,268 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Queryer runs queries with database/sql. It is satisfied by *sql.DB,
+// *sql.Tx, *sql.Conn, and the sqlx DB and Tx types.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// selector scans query results into a slice, as sqlx does with its own
+// column mapping
+type selector interface {
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// RawConfig configures PaginateRaw
+type RawConfig struct {
+	// MaxPageSize is the largest page size returned; larger requests are
+	// reduced to it. Zero means DefaultMaxPageSize, and negative values
+	// disable the limit.
+	MaxPageSize int
+}
+
+// PaginateRaw paginates a raw SQL query for code that does not use GORM:
+//
+//	var users []User
+//	result, err := pagination.PaginateRaw(ctx, db,
+//		"SELECT id, name FROM users WHERE age > $1 ORDER BY id", "",
+//		[]interface{}{18}, params, &users)
+//
+// LIMIT and OFFSET are appended to querySQL as integer literals, so the
+// query must not have its own LIMIT. Page sizes above RawConfig.MaxPageSize
+// are reduced to it, and pages past the largest offset fail with
+// errors.ErrInvalidPage. When countSQL is empty the total is counted by
+// wrapping querySQL in SELECT COUNT(*). Both queries receive args.
+//
+// Rows are scanned with SelectContext when db provides it, as sqlx does.
+// Otherwise columns are matched to struct fields by their db tag, their name,
+// or their name in snake_case; dest may also be a *[]map[string]interface{}.
+func PaginateRaw(ctx context.Context, db Queryer, querySQL, countSQL string, args []interface{}, params PaginationParams, dest interface{}, config ...RawConfig) (*PaginationResult, error) {
+	cfg := RawConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxPageSize == 0 {
+		cfg.MaxPageSize = DefaultMaxPageSize
+	}
+
+	// Default to page 1 if page is invalid
+	if params.Page <= 0 {
+		params.Page = 1
+	}
+
+	// Default to 10 items per page if page size is invalid, and reduce
+	// larger sizes to the maximum page size
+	if params.PageSize <= 0 {
+		params.PageSize = 10
+	}
+	if cfg.MaxPageSize > 0 && params.PageSize > cfg.MaxPageSize {
+		params.PageSize = cfg.MaxPageSize
+	}
+
+	// Calculate offset for the query
+	offset, err := pageOffset("page", params.Page, params.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	querySQL = strings.TrimRight(strings.TrimSpace(querySQL), ";")
+	if countSQL == "" {
+		countSQL = "SELECT COUNT(*) FROM (" + querySQL + ") AS count_query"
+	}
+
+	// Get total count of records
+	var total int64
+	rows, err := db.QueryContext(ctx, countSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	if rows.Next() {
+		err = rows.Scan(&total)
+	}
+	if closeErr := rows.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(params.PageSize)))
+
+	// Execute the query with pagination; the limit and offset are integers
+	// formatted here, never caller input, so they cannot inject SQL
+	pageSQL := querySQL + " LIMIT " + strconv.Itoa(params.PageSize) + " OFFSET " + strconv.Itoa(offset)
+	if s, ok := db.(selector); ok {
+		err = s.SelectContext(ctx, dest, pageSQL, args...)
+	} else {
+		err = selectRows(ctx, db, dest, pageSQL, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Create and return the pagination result
+	return &PaginationResult{
+		Data: dest,
+		Meta: PaginationMeta{
+			Total:      total,
+			Page:       params.Page,
+			PageSize:   params.PageSize,
+			TotalPages: totalPages,
+			HasNext:    params.Page < totalPages,
+			CountMode:  CountExact,
+		},
+	}, nil
+}
+
+// selectRows runs a query and scans every row into the slice dest points to
+func selectRows(ctx context.Context, db Queryer, dest interface{}, query string, args []interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("pagination: dest must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	// Start from an empty slice so an empty page is encoded as [] rather than null
+	slice.Set(reflect.MakeSlice(slice.Type(), 0, 0))
+	for rows.Next() {
+		elem := reflect.New(elemType).Elem()
+		if err := scanRow(rows, columns, elem); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return rows.Err()
+}
+
+// scanRow scans the current row into a struct, a pointer to a struct, or a map
+func scanRow(rows *sql.Rows, columns []string, elem reflect.Value) error {
+	target := elem
+	if target.Kind() == reflect.Ptr {
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+
+	values := make([]interface{}, len(columns))
+	switch target.Kind() {
+	case reflect.Map:
+		if target.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("pagination: cannot scan into %s", target.Type())
+		}
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+		target.Set(reflect.MakeMapWithSize(target.Type(), len(columns)))
+		for i, column := range columns {
+			value := *(values[i].(*interface{}))
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			target.SetMapIndex(reflect.ValueOf(column), reflect.ValueOf(&value).Elem())
+		}
+		return nil
+
+	case reflect.Struct:
+		fields := structColumns(target.Type())
+		for i, column := range columns {
+			index, ok := fields[strings.ToLower(column)]
+			if !ok {
+				// Skip columns without a field
+				values[i] = new(interface{})
+				continue
+			}
+			values[i] = target.FieldByIndex(index).Addr().Interface()
+		}
+		return rows.Scan(values...)
+
+	default:
+		if len(columns) != 1 {
+			return fmt.Errorf("pagination: cannot scan %d columns into %s", len(columns), target.Type())
+		}
+		return rows.Scan(target.Addr().Interface())
+	}
+}
+
+// structColumns maps lower-case column names to the fields of a struct,
+// including the fields of embedded structs
+func structColumns(t reflect.Type) map[string][]int {
+	columns := make(map[string][]int)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldIndex := append(append([]int{}, index...), i)
+
+			tag := field.Tag.Get("db")
+			if tag == "-" {
+				continue
+			}
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+				walk(field.Type, fieldIndex)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+
+			if name, _, _ := strings.Cut(tag, ","); name != "" {
+				columns[strings.ToLower(name)] = fieldIndex
+				continue
+			}
+			for _, name := range []string{field.Name, snakeCase(field.Name)} {
+				if _, exists := columns[strings.ToLower(name)]; !exists {
+					columns[strings.ToLower(name)] = fieldIndex
+				}
+			}
+		}
+	}
+	walk(t, nil)
+	return columns
+}
+
+// snakeCase converts a field name such as CreatedAt or UserID to created_at or user_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word before an upper-case letter that follows a
+			// lower-case one, or that starts a word after an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
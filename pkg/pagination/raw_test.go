@@ -0,0 +1,125 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type rawUser struct {
+	ID   int
+	Name string
+}
+
+// newRawDB returns an in-memory database with users 1 to n
+func newRawDB(t *testing.T, n int) *sql.DB {
+	t.Helper()
+	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db, err := gormDB.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i := 1; i <= n; i++ {
+		if _, err := db.Exec("INSERT INTO users (id, name) VALUES (?, ?)", i, "user"); err != nil {
+			t.Fatalf("Failed to insert user: %v", err)
+		}
+	}
+	return db
+}
+
+func TestPaginateRaw(t *testing.T) {
+	db := newRawDB(t, 250)
+
+	tests := []struct {
+		name     string
+		params   PaginationParams
+		page     int
+		pageSize int
+		firstID  int
+		rows     int
+		hasNext  bool
+	}{
+		{"defaults", PaginationParams{}, 1, 10, 1, 10, true},
+		{"second page", PaginationParams{Page: 2, PageSize: 20}, 2, 20, 21, 20, true},
+		{"last page", PaginationParams{Page: 3, PageSize: 100}, 3, 100, 201, 50, false},
+		{"capped size", PaginationParams{Page: 1, PageSize: 1000}, 1, DefaultMaxPageSize, 1, DefaultMaxPageSize, true},
+		{"capped size second page", PaginationParams{Page: 2, PageSize: math.MaxInt}, 2, DefaultMaxPageSize, 101, DefaultMaxPageSize, true},
+		{"past the end", PaginationParams{Page: 30, PageSize: 10}, 30, 10, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []rawUser
+			result, err := PaginateRaw(context.Background(), db, "SELECT id, name FROM users ORDER BY id", "", nil, tt.params, &users)
+			if err != nil {
+				t.Fatalf("PaginateRaw failed: %v", err)
+			}
+			meta := result.Meta
+			if meta.Page != tt.page || meta.PageSize != tt.pageSize || meta.Total != 250 || meta.HasNext != tt.hasNext {
+				t.Errorf("Unexpected meta: %+v", meta)
+			}
+			if len(users) != tt.rows || (tt.rows > 0 && users[0].ID != tt.firstID) {
+				t.Errorf("Expected %d rows from %d, got %d", tt.rows, tt.firstID, len(users))
+			}
+		})
+	}
+}
+
+func TestPaginateRawMaxPageSize(t *testing.T) {
+	db := newRawDB(t, 250)
+	tests := []struct {
+		name        string
+		maxPageSize int
+		pageSize    int
+	}{
+		{"default", 0, DefaultMaxPageSize},
+		{"smaller", 20, 20},
+		{"larger", 200, 200},
+		{"disabled", -1, 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []rawUser
+			result, err := PaginateRaw(context.Background(), db, "SELECT id, name FROM users ORDER BY id", "", nil,
+				PaginationParams{Page: 1, PageSize: 250}, &users, RawConfig{MaxPageSize: tt.maxPageSize})
+			if err != nil {
+				t.Fatalf("PaginateRaw failed: %v", err)
+			}
+			if result.Meta.PageSize != tt.pageSize || len(users) != tt.pageSize {
+				t.Errorf("Expected pages of %d, got %d with %d rows", tt.pageSize, result.Meta.PageSize, len(users))
+			}
+		})
+	}
+}
+
+func TestPaginateRawOffsetOverflow(t *testing.T) {
+	db := newRawDB(t, 1)
+	for _, page := range []int{math.MaxInt, math.MaxInt/DefaultMaxPageSize + 2} {
+		var users []rawUser
+		_, err := PaginateRaw(context.Background(), db, "SELECT id, name FROM users ORDER BY id", "", nil,
+			PaginationParams{Page: page, PageSize: DefaultMaxPageSize}, &users)
+		if !errors.Is(err, errors.ErrInvalidPage) {
+			t.Errorf("Expected ErrInvalidPage for page %d, got %v", page, err)
+		}
+	}
+
+	// The largest page whose offset fits is still queried
+	var users []rawUser
+	if _, err := PaginateRaw(context.Background(), db, "SELECT id, name FROM users ORDER BY id", "", nil,
+		PaginationParams{Page: math.MaxInt/DefaultMaxPageSize + 1, PageSize: DefaultMaxPageSize}, &users); err != nil {
+		t.Errorf("Expected the last page to be queried, got %v", err)
+	}
+}
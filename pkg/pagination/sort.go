@@ -0,0 +1,109 @@
+package pagination
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SortDirection is the order of a sort field
+type SortDirection string
+
+// Sort directions
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// SortField is a field to order results by
+type SortField struct {
+	Field     string        `json:"field"`
+	Direction SortDirection `json:"direction"`
+}
+
+// ParseSort parses a sort query value such as "-createdAt,name", where a
+// leading "-" sorts the field in descending order
+func ParseSort(value string) []SortField {
+	var fields []SortField
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		direction := SortAsc
+		switch part[0] {
+		case '-':
+			direction = SortDesc
+			part = part[1:]
+		case '+':
+			part = part[1:]
+		}
+
+		if part != "" {
+			fields = append(fields, SortField{Field: part, Direction: direction})
+		}
+	}
+	return fields
+}
+
+// String returns the field in the sort query format, e.g. "-createdAt"
+func (s SortField) String() string {
+	if s.Direction == SortDesc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// SetSortableFields sets the fields clients may sort by, mapping each field
+// name to its database column, e.g. {"createdAt": "created_at"}. Sorting is
+// rejected for every field when none are set.
+func (p *Paginator) SetSortableFields(fields map[string]string) *Paginator {
+	p.sortable = make(map[string]string, len(fields))
+	for field, column := range fields {
+		p.sortable[field] = column
+	}
+	return p
+}
+
+// SetDefaultSort sets the order used when the params have no sort fields
+func (p *Paginator) SetDefaultSort(fields ...SortField) *Paginator {
+	p.defaultSort = fields
+	return p
+}
+
+// applySort validates the sort fields against the sortable fields and adds the
+// order clauses to the query
+func (p *Paginator) applySort(query *gorm.DB, fields []SortField) (*gorm.DB, error) {
+	for _, field := range fields {
+		column, ok := p.sortable[field.Field]
+		if !ok {
+			return nil, errors.InvalidSortError(field.Field, p.sortableFieldNames())
+		}
+
+		switch field.Direction {
+		case SortAsc, SortDesc, "":
+		default:
+			return nil, errors.InvalidSortError(field.String(), p.sortableFieldNames())
+		}
+
+		query = query.Order(clause.OrderByColumn{
+			Column: clause.Column{Name: column},
+			Desc:   field.Direction == SortDesc,
+		})
+	}
+	return query, nil
+}
+
+// sortableFieldNames returns the sortable field names in a stable order
+func (p *Paginator) sortableFieldNames() []string {
+	names := make([]string, 0, len(p.sortable))
+	for field := range p.sortable {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	return names
+}
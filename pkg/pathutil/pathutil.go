@@ -0,0 +1,141 @@
+// Package pathutil turns untrusted paths and file names into storage keys,
+// file names, and local paths that cannot leave their base:
+//
+//	key := pathutil.JoinKey("uploads", userDir, pathutil.SanitizeFilename(upload.Filename))
+//	local := pathutil.Join("/var/data", key) // always under /var/data
+//
+// Keys use "/" separators, like S3 keys, whatever the operating system.
+package pathutil
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxKeyLength is the maximum length of a key in bytes, that of S3
+	MaxKeyLength = 1024
+
+	// MaxFilenameLength is the maximum length of a file name in bytes, that
+	// of most filesystems
+	MaxFilenameLength = 255
+)
+
+// ErrInvalidKey is wrapped by the errors of ValidKey
+var ErrInvalidKey = errors.New("pathutil: invalid key")
+
+// unsafeFilenameChars are replaced in file names, as they are reserved on
+// Windows or in URLs
+const unsafeFilenameChars = `:*?"<>|%`
+
+// NormalizeKey returns the storage key of a path: "/" separators, also for
+// Windows paths, cleaned of "." and ".." elements, without leading or
+// trailing slashes. Keys never leave the root, so "../a" is "a", and the
+// root is "". Control characters are removed and invalid UTF-8 is replaced
+// with "_", so keys are never longer than their path.
+func NormalizeKey(p string) string {
+	p = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\\':
+			return '/'
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(p, "_"))
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// JoinKey joins path elements into a key, normalizing each on its own so
+// no element leaves the one before it: JoinKey("uploads", "../a") is
+// "uploads/a"
+func JoinKey(elem ...string) string {
+	keys := make([]string, 0, len(elem))
+	for _, e := range elem {
+		if key := NormalizeKey(e); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return strings.Join(keys, "/")
+}
+
+// RelativeKey returns a key relative to a base, or false when it is not
+// under the base
+func RelativeKey(base, key string) (string, bool) {
+	base, key = NormalizeKey(base), NormalizeKey(key)
+	if base == "" {
+		return key, true
+	}
+	rel, ok := strings.CutPrefix(key, base+"/")
+	return rel, ok
+}
+
+// ValidKey checks that a key is normalized, not the root, and at most
+// MaxKeyLength bytes long
+func ValidKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("%w: empty", ErrInvalidKey)
+	case len(key) > MaxKeyLength:
+		return fmt.Errorf("%w: longer than %d bytes", ErrInvalidKey, MaxKeyLength)
+	case NormalizeKey(key) != key:
+		return fmt.Errorf("%w: %q is not normalized", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// Join returns the local path of a key under a base directory. The key is
+// normalized first, so the path is the base or under it whatever the key.
+func Join(base, key string) string {
+	return filepath.Join(base, filepath.FromSlash(NormalizeKey(key)))
+}
+
+// SanitizeFilename returns the last element of a path as a file name safe
+// to store and serve: without control characters or invalid UTF-8, with
+// characters reserved on Windows or in URLs replaced with "_", without
+// surrounding spaces, and at most MaxFilenameLength bytes long, keeping its
+// extension. Names of nothing but dots, or nothing at all, are "".
+func SanitizeFilename(filename string) string {
+	filename = strings.TrimRight(filename, `/\`)
+	if i := strings.LastIndexAny(filename, `/\`); i >= 0 {
+		filename = filename[i+1:]
+	}
+
+	filename = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(unsafeFilenameChars, r):
+			return '_'
+		}
+		return r
+	}, strings.ToValidUTF8(filename, "_"))
+
+	filename = strings.TrimSpace(truncate(filename, MaxFilenameLength))
+	if strings.Trim(filename, ".") == "" {
+		return ""
+	}
+	return filename
+}
+
+// truncate shortens a file name to max bytes, keeping its extension when
+// short enough and never splitting a character
+func truncate(filename string, max int) string {
+	if len(filename) <= max {
+		return filename
+	}
+	ext := path.Ext(filename)
+	if len(ext) > max/4 {
+		ext = ""
+	}
+	stem := filename[:max-len(ext)]
+	for len(stem) > 0 && !utf8.ValidString(stem) {
+		stem = stem[:len(stem)-1]
+	}
+	return stem + ext
+}
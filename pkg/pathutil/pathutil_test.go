@@ -0,0 +1,182 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// paths seeds the fuzz targets with traversal attempts and awkward names
+var paths = []string{
+	"",
+	"/",
+	".",
+	"..",
+	"a.txt",
+	"docs/2024/a.txt",
+	"../../etc/passwd",
+	"docs/../../a.txt",
+	`..\..\windows\system32`,
+	`C:\Users\me\a.txt`,
+	"//server/share/a.txt",
+	"a/./b/../../../c",
+	"a\x00/../../b",
+	".\x00./.\x00./etc",
+	"résumé 履歴書 📄.txt",
+	"\xff\xfe/../a",
+	"a\u0085b/c\u200bd",
+	"name:with*reserved?chars\"<>|%.txt",
+	"   .   ",
+	"...",
+	"trailing/",
+	strings.Repeat("a", 300) + ".txt",
+	strings.Repeat("é", 200) + ".jpeg",
+}
+
+func FuzzNormalizeKey(f *testing.F) {
+	for _, p := range paths {
+		f.Add(p)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		key := NormalizeKey(p)
+		checkKey(t, p, key)
+		if again := NormalizeKey(key); again != key {
+			t.Errorf("NormalizeKey(%q) = %q, normalized again %q", p, key, again)
+		}
+		if len(key) > len(p) {
+			t.Errorf("NormalizeKey(%q) = %q is longer than the path", p, key)
+		}
+		if key != "" && ValidKey(key) != nil && len(key) <= MaxKeyLength {
+			t.Errorf("ValidKey(%q) = %v", key, ValidKey(key))
+		}
+	})
+}
+
+func FuzzJoinKey(f *testing.F) {
+	for _, p := range paths {
+		f.Add("uploads", p)
+		f.Add(p, "a.txt")
+	}
+	f.Fuzz(func(t *testing.T, base, p string) {
+		key := JoinKey(base, p)
+		checkKey(t, base+" + "+p, key)
+
+		baseKey, pKey := NormalizeKey(base), NormalizeKey(p)
+		switch {
+		case baseKey == "":
+			if key != pKey {
+				t.Errorf("JoinKey(%q, %q) = %q, want %q", base, p, key, pKey)
+			}
+		case pKey == "":
+			if key != baseKey {
+				t.Errorf("JoinKey(%q, %q) = %q, want %q", base, p, key, baseKey)
+			}
+		default:
+			rel, ok := RelativeKey(base, key)
+			if !ok || rel != pKey {
+				t.Errorf("JoinKey(%q, %q) = %q, not %q under %q", base, p, key, pKey, baseKey)
+			}
+		}
+	})
+}
+
+func FuzzJoin(f *testing.F) {
+	for _, p := range paths {
+		f.Add(p)
+	}
+	base := filepath.Join(f.TempDir(), "base")
+	f.Fuzz(func(t *testing.T, p string) {
+		local := Join(base, p)
+		rel, err := filepath.Rel(base, local)
+		if err != nil {
+			t.Fatalf("Join(%q, %q) = %q, not relative to the base: %v", base, p, local, err)
+		}
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+			t.Errorf("Join(%q, %q) = %q leaves the base", base, p, local)
+		}
+	})
+}
+
+func FuzzSanitizeFilename(f *testing.F) {
+	for _, p := range paths {
+		f.Add(p)
+	}
+	f.Fuzz(func(t *testing.T, filename string) {
+		name := SanitizeFilename(filename)
+		if again := SanitizeFilename(name); again != name {
+			t.Errorf("SanitizeFilename(%q) = %q, sanitized again %q", filename, name, again)
+		}
+		if name == "" {
+			return
+		}
+		if !utf8.ValidString(name) {
+			t.Errorf("SanitizeFilename(%q) = %q is not valid UTF-8", filename, name)
+		}
+		if len(name) > MaxFilenameLength {
+			t.Errorf("SanitizeFilename(%q) is %d bytes long", filename, len(name))
+		}
+		if strings.Trim(name, ".") == "" || name != strings.TrimSpace(name) {
+			t.Errorf("SanitizeFilename(%q) = %q", filename, name)
+		}
+		if i := strings.IndexFunc(name, func(r rune) bool {
+			return r == '/' || r == '\\' || unicode.IsControl(r) || strings.ContainsRune(unsafeFilenameChars, r)
+		}); i >= 0 {
+			t.Errorf("SanitizeFilename(%q) = %q has an unsafe character at %d", filename, name, i)
+		}
+		if key := JoinKey("uploads", name); key != "uploads/"+name {
+			t.Errorf("JoinKey(%q, %q) = %q, not a single element", "uploads", name, key)
+		}
+	})
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"photo.png", "photo.png"},
+		{"résumé.pdf", "résumé.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\report.docx`, "report.docx"},
+		{"docs/", "docs"},
+		{"a:b*c?.txt", "a_b_c_.txt"},
+		{"100%.txt", "100_.txt"},
+		{"bad\x00name\n.txt", "badname.txt"},
+		{"\xffname.txt", "_name.txt"},
+		{"  spaced.txt  ", "spaced.txt"},
+		{"", ""},
+		{".", ""},
+		{"..", ""},
+		{"/", ""},
+		{strings.Repeat("a", 300) + ".txt", strings.Repeat("a", MaxFilenameLength-4) + ".txt"},
+		{strings.Repeat("é", 200) + ".txt", strings.Repeat("é", 125) + ".txt"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.filename); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+// checkKey checks that a key is normalized: valid UTF-8 without control
+// characters, backslashes, or empty, "." or ".." elements
+func checkKey(t *testing.T, input, key string) {
+	t.Helper()
+	if key == "" {
+		return
+	}
+	if !utf8.ValidString(key) {
+		t.Errorf("key of %q = %q is not valid UTF-8", input, key)
+	}
+	if strings.ContainsFunc(key, func(r rune) bool { return r == '\\' || unicode.IsControl(r) }) {
+		t.Errorf("key of %q = %q has a backslash or control character", input, key)
+	}
+	for _, elem := range strings.Split(key, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			t.Errorf("key of %q = %q has the element %q", input, key, elem)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Package repository provides a generic GORM repository for a model, with
+// pagination, filtering, soft deletes, and errors ready for API responses
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/anaknegeri/gokit/pkg/database"
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Config configures a repository
+type Config struct {
+	// Entity names the model in error messages, defaulting to its type
+	// name, e.g. "User with ID 5 not found"
+	Entity string
+
+	// Preload are the associations loaded with each record
+	Preload []string
+
+	// SortableFields maps the sort fields clients may use to their columns
+	SortableFields map[string]string
+
+	// DefaultSort is the order of lists without a sort parameter
+	DefaultSort []pagination.SortField
+
+	// Filters are the fields clients may filter and search lists by
+	Filters *pagination.FilterSchema
+
+	// MaxPageSize is the largest page size of lists. Zero means
+	// pagination.DefaultMaxPageSize, and negative values disable the limit.
+	MaxPageSize int
+
+	// CountMode is how lists count their total, defaulting to an exact count
+	CountMode pagination.CountMode
+}
+
+// Repository reads and writes the records of the model T. Its methods run
+// in the transaction of their context, if any, see database.WithTx.
+//
+// Models with a gorm.DeletedAt field are soft deleted: Delete sets the
+// field, and soft-deleted records are left out unless a query uses
+// WithTrashed or OnlyTrashed.
+type Repository[T any] struct {
+	db     *gorm.DB
+	config Config
+}
+
+// New creates a repository for the model T
+func New[T any](db *gorm.DB, config ...Config) *Repository[T] {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Entity == "" {
+		t := reflect.TypeOf((*T)(nil)).Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		cfg.Entity = t.Name()
+	}
+	return &Repository[T]{db: db, config: cfg}
+}
+
+// Entity returns the name of the model in messages
+func (r *Repository[T]) Entity() string {
+	return r.config.Entity
+}
+
+// DB returns a query on the model in the transaction of ctx, if any, for
+// queries the repository does not cover
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return database.Conn(ctx, r.db).Model(new(T))
+}
+
+// Create inserts a record, filling in its primary key and defaults
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	if err := database.Conn(ctx, r.db).Create(entity).Error; err != nil {
+		return r.error(err, nil)
+	}
+	return nil
+}
+
+// CreateMany inserts records in batches of 100
+func (r *Repository[T]) CreateMany(ctx context.Context, entities []T) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	if err := database.Conn(ctx, r.db).CreateInBatches(entities, 100).Error; err != nil {
+		return r.error(err, nil)
+	}
+	return nil
+}
+
+// GetByID returns the record with a primary key, or a RECORD_NOT_FOUND error
+func (r *Repository[T]) GetByID(ctx context.Context, id interface{}, scopes ...pagination.Scope) (*T, error) {
+	entity := new(T)
+	query := r.query(ctx, scopes).Where(primaryKey(id))
+	if err := query.First(entity).Error; err != nil {
+		return nil, r.error(err, id)
+	}
+	return entity, nil
+}
+
+// First returns the first record matching the scopes, or a RECORD_NOT_FOUND
+// error
+func (r *Repository[T]) First(ctx context.Context, scopes ...pagination.Scope) (*T, error) {
+	entity := new(T)
+	if err := r.query(ctx, scopes).First(entity).Error; err != nil {
+		return nil, r.error(err, nil)
+	}
+	return entity, nil
+}
+
+// Find returns every record matching the scopes
+func (r *Repository[T]) Find(ctx context.Context, scopes ...pagination.Scope) ([]T, error) {
+	entities := make([]T, 0)
+	if err := r.query(ctx, scopes).Find(&entities).Error; err != nil {
+		return nil, r.error(err, nil)
+	}
+	return entities, nil
+}
+
+// Count returns how many records match the scopes
+func (r *Repository[T]) Count(ctx context.Context, scopes ...pagination.Scope) (int64, error) {
+	var count int64
+	query := database.Conn(ctx, r.db).Model(new(T))
+	for _, scope := range scopes {
+		query = scope(query)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return 0, r.error(err, nil)
+	}
+	return count, nil
+}
+
+// List returns a page of records with the sort order and filters of params,
+// checked against the sortable fields and filter schema of the repository
+func (r *Repository[T]) List(ctx context.Context, params pagination.PaginationParams, scopes ...pagination.Scope) (*pagination.Result[T], error) {
+	p := pagination.NewPaginator(database.Conn(ctx, r.db))
+	if r.config.SortableFields != nil {
+		p.SetSortableFields(r.config.SortableFields)
+	}
+	if len(r.config.DefaultSort) > 0 {
+		p.SetDefaultSort(r.config.DefaultSort...)
+	}
+	if r.config.Filters != nil {
+		p.SetFilterSchema(*r.config.Filters)
+	}
+	p.SetMaxPageSize(r.config.MaxPageSize)
+	if r.config.CountMode != "" {
+		p.SetCountMode(r.config.CountMode)
+	}
+	for _, name := range r.config.Preload {
+		p.Preload(name)
+	}
+
+	result, err := pagination.PaginateWith[T](p, params, scopes...)
+	if err != nil {
+		return nil, r.error(err, nil)
+	}
+	return result, nil
+}
+
+// Update saves every field of a record, including zero values, except its
+// primary key and creation time, or returns a RECORD_NOT_FOUND error
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	conn := database.Conn(ctx, r.db)
+	result := conn.Model(entity).Select("*").Omit(clause.Associations, "CreatedAt").Updates(entity)
+	if result.Error != nil {
+		return r.error(result.Error, nil)
+	}
+	if result.RowsAffected == 0 {
+		return r.notFoundUnlessExists(conn, entity)
+	}
+	return nil
+}
+
+// UpdateFields updates some fields of the record with a primary key, given
+// as a map or a struct whose zero fields are skipped, and returns the
+// updated record
+func (r *Repository[T]) UpdateFields(ctx context.Context, id interface{}, values interface{}) (*T, error) {
+	conn := database.Conn(ctx, r.db)
+	result := conn.Model(new(T)).Where(primaryKey(id)).Updates(values)
+	if result.Error != nil {
+		return nil, r.error(result.Error, id)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Delete deletes the record with a primary key, soft deleting models with a
+// gorm.DeletedAt field, or returns a RECORD_NOT_FOUND error
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	return r.delete(database.Conn(ctx, r.db), id)
+}
+
+// ForceDelete deletes the record with a primary key for good, even when it
+// is soft deleted
+func (r *Repository[T]) ForceDelete(ctx context.Context, id interface{}) error {
+	return r.delete(database.Conn(ctx, r.db).Unscoped(), id)
+}
+
+// delete deletes a record with a query
+func (r *Repository[T]) delete(query *gorm.DB, id interface{}) error {
+	result := query.Where(primaryKey(id)).Delete(new(T))
+	if result.Error != nil {
+		return r.error(result.Error, id)
+	}
+	if result.RowsAffected == 0 {
+		return errors.RecordNotFoundError(r.config.Entity, id)
+	}
+	return nil
+}
+
+// Restore undeletes the soft-deleted record with a primary key, or returns
+// a RECORD_NOT_FOUND error when there is no such record
+func (r *Repository[T]) Restore(ctx context.Context, id interface{}) (*T, error) {
+	column, err := r.deletedAtColumn()
+	if err != nil {
+		return nil, err
+	}
+	result := database.Conn(ctx, r.db).Unscoped().Model(new(T)).
+		Where(primaryKey(id)).
+		Where(clause.Neq{Column: clause.Column{Name: column}, Value: nil}).
+		Update(column, nil)
+	if result.Error != nil {
+		return nil, r.error(result.Error, id)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.RecordNotFoundError(r.config.Entity, id)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// WithTrashed is a scope including soft-deleted records
+func (r *Repository[T]) WithTrashed() pagination.Scope {
+	return func(q *gorm.DB) *gorm.DB {
+		return q.Unscoped()
+	}
+}
+
+// OnlyTrashed is a scope selecting only soft-deleted records
+func (r *Repository[T]) OnlyTrashed() pagination.Scope {
+	return func(q *gorm.DB) *gorm.DB {
+		column, err := r.deletedAtColumn()
+		if err != nil {
+			q.AddError(err)
+			return q
+		}
+		return q.Unscoped().Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: nil})
+	}
+}
+
+// query returns a query on the model with the preloads and scopes applied
+func (r *Repository[T]) query(ctx context.Context, scopes []pagination.Scope) *gorm.DB {
+	query := database.Conn(ctx, r.db).Model(new(T))
+	for _, name := range r.config.Preload {
+		query = query.Preload(name)
+	}
+	for _, scope := range scopes {
+		query = scope(query)
+	}
+	return query
+}
+
+// notFoundUnlessExists returns a RECORD_NOT_FOUND error when an updated
+// record does not exist. MySQL reports updates that change nothing as
+// affecting no rows, so the record is looked up before failing.
+func (r *Repository[T]) notFoundUnlessExists(conn *gorm.DB, entity *T) error {
+	s, err := r.Schema()
+	if err != nil {
+		return err
+	}
+	var id interface{}
+	if s.PrioritizedPrimaryField != nil {
+		id, _ = s.PrioritizedPrimaryField.ValueOf(conn.Statement.Context, reflect.ValueOf(entity).Elem())
+	}
+
+	var count int64
+	if err := conn.Model(new(T)).Where(primaryKey(id)).Count(&count).Error; err != nil {
+		return r.error(err, id)
+	}
+	if count == 0 {
+		return errors.RecordNotFoundError(r.config.Entity, id)
+	}
+	return nil
+}
+
+// deletedAtColumn returns the column of the gorm.DeletedAt field of the model
+func (r *Repository[T]) deletedAtColumn() (string, error) {
+	s, err := r.Schema()
+	if err != nil {
+		return "", err
+	}
+	deletedAt := reflect.TypeOf(gorm.DeletedAt{})
+	for _, field := range s.Fields {
+		if field.FieldType == deletedAt && field.DBName != "" {
+			return field.DBName, nil
+		}
+	}
+	appErr := errors.InternalServerError("")
+	appErr.Internal = fmt.Errorf("repository: %s has no gorm.DeletedAt field", r.config.Entity)
+	return "", appErr
+}
+
+// Schema returns the parsed GORM schema of the model
+func (r *Repository[T]) Schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, errors.DatabaseError(err)
+	}
+	return stmt.Schema, nil
+}
+
+// error converts a GORM error into an AppError naming the entity
+func (r *Repository[T]) error(err error, id interface{}) error {
+	return errors.FromGormErrorFor(err, r.config.Entity, id)
+}
+
+// primaryKey is the condition selecting a record by primary key
+func primaryKey(id interface{}) clause.Expression {
+	return clause.Eq{Column: clause.PrimaryColumn, Value: id}
+}
@@ -0,0 +1,125 @@
+package response
+
+import "sync"
+
+// KeyCase is how the keys of response bodies are written
+type KeyCase int
+
+// Key cases
+const (
+	// KeyCasePassThrough keeps keys as the JSON tags define them
+	KeyCasePassThrough KeyCase = iota
+
+	// KeyCaseSnake writes keys in snake_case, e.g. first_name
+	KeyCaseSnake
+
+	// KeyCaseCamel writes keys in camelCase, e.g. firstName
+	KeyCaseCamel
+)
+
+// Fields are the names of the envelope fields. Empty names use the default
+// and "-" leaves a field out.
+type Fields struct {
+	Success string // defaults to "success"
+	Code    string // defaults to "code", the HTTP status
+	Message string // defaults to "message"
+	Data    string // defaults to "data"
+	Meta    string // defaults to "meta"
+	Links   string // defaults to "links"
+	Error   string // defaults to "error", the error code such as NOT_FOUND
+	Details string // defaults to "details"
+
+	// ErrorMessage is the field of the message in error responses, defaulting
+	// to Message. Set Error to "code" and ErrorMessage to "error" for bodies
+	// such as {"code": "NOT_FOUND", "error": "User not found"}.
+	ErrorMessage string
+
+	// Fields of the Metadata, written when enabled
+	APIVersion string // defaults to "api_version"
+	ServerTime string // defaults to "server_time"
+	Duration   string // defaults to "duration_ms"
+	RequestID  string // defaults to "request_id"
+}
+
+// Config configures how responses are written
+type Config struct {
+	// KeyCase converts every key of the body, including those of the data and
+	// the envelope, which keeps casing consistent across structs and maps
+	KeyCase KeyCase
+
+	// Fields renames or leaves out envelope fields
+	Fields Fields
+
+	// Metadata adds the API version, server time, duration, or request ID
+	// to every response
+	Metadata Metadata
+}
+
+var (
+	configMu sync.RWMutex
+	config   = withDefaults(Config{})
+)
+
+// Configure sets how responses are written, once when the app starts:
+//
+//	response.Configure(response.Config{
+//		KeyCase:  response.KeyCaseSnake,
+//		Fields:   response.Fields{Code: "-", Data: "result"},
+//		Metadata: response.Metadata{APIVersion: "v2", Duration: true, RequestID: true},
+//	})
+func Configure(cfg Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config = withDefaults(cfg)
+}
+
+// currentConfig returns the response configuration
+func currentConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config
+}
+
+// CurrentConfig returns the configuration set by Configure, with the
+// default field names filled in
+func CurrentConfig() Config {
+	return currentConfig()
+}
+
+// ConvertKey writes a key in the key case of the configuration
+func (c Config) ConvertKey(key string) string {
+	if convert := keyConverter(c.KeyCase); convert != nil {
+		return convert(key)
+	}
+	return key
+}
+
+// withDefaults fills in the default envelope field names
+func withDefaults(cfg Config) Config {
+	f := &cfg.Fields
+	for _, field := range []struct {
+		name  *string
+		value string
+	}{
+		{&f.Success, "success"},
+		{&f.Code, "code"},
+		{&f.Message, "message"},
+		{&f.Data, "data"},
+		{&f.Meta, "meta"},
+		{&f.Links, "links"},
+		{&f.Error, "error"},
+		{&f.Details, "details"},
+		{&f.APIVersion, "api_version"},
+		{&f.ServerTime, "server_time"},
+		{&f.Duration, "duration_ms"},
+		{&f.RequestID, "request_id"},
+	} {
+		if *field.name == "" {
+			*field.name = field.value
+		}
+	}
+	if f.ErrorMessage == "" {
+		f.ErrorMessage = f.Message
+	}
+	return cfg
+}
@@ -0,0 +1,113 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultDeprecationMessage is the Warning text of a Deprecation without a message
+const DefaultDeprecationMessage = "This endpoint is deprecated"
+
+// Deprecation describes the deprecation of an endpoint, signaled to clients
+// with the Deprecation (RFC 9745), Sunset (RFC 8594), Link, and Warning
+// headers:
+//
+//	Deprecation: @1717200000
+//	Sunset: Tue, 31 Dec 2024 23:59:59 GMT
+//	Link: <https://api.example.com/v2/users>; rel="successor-version", <https://docs.example.com/migrate>; rel="deprecation"; type="text/html"
+//	Warning: 299 - "Use /v2/users instead"
+type Deprecation struct {
+	// Since is when the endpoint was deprecated. Zero sends "Deprecation: true".
+	Since time.Time
+
+	// Sunset is when the endpoint stops working, if known
+	Sunset time.Time
+
+	// Link is the URL of the documentation of the deprecation, such as a
+	// migration guide. Paths are made absolute with the request's base URL.
+	Link string
+
+	// Successor is the URL of the endpoint replacing this one
+	Successor string
+
+	// Message is the text of the Warning header, defaulting to
+	// DefaultDeprecationMessage
+	Message string
+}
+
+// Header returns the headers signaling the deprecation
+func (d Deprecation) Header() http.Header {
+	header := http.Header{}
+	if d.Since.IsZero() {
+		header.Set("Deprecation", "true")
+	} else {
+		header.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	}
+	if !d.Sunset.IsZero() {
+		header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		header.Add(fiber.HeaderLink, "<"+d.Successor+`>; rel="successor-version"`)
+	}
+	if d.Link != "" {
+		header.Add(fiber.HeaderLink, "<"+d.Link+`>; rel="deprecation"; type="text/html"`)
+	}
+
+	message := d.Message
+	if message == "" {
+		message = DefaultDeprecationMessage
+	}
+	header.Set("Warning", "299 - "+strconv.Quote(message))
+	return header
+}
+
+// Deprecated adds the deprecation headers to the response, before it is sent:
+//
+//	response.Deprecated(c, response.Deprecation{Successor: "/v2/users/" + id})
+//	return response.Success(c, "User found", user)
+func Deprecated(c *fiber.Ctx, d Deprecation) {
+	setDeprecation(fiberExchange{c}, d)
+}
+
+// DeprecatedRoute returns a middleware adding the deprecation headers to
+// every response of a route or a group:
+//
+//	v1 := app.Group("/v1", response.DeprecatedRoute(response.Deprecation{
+//		Since:  time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+//		Sunset: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+//		Link:   "https://docs.example.com/migrate-to-v2",
+//	}))
+func DeprecatedRoute(d Deprecation) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		Deprecated(c, d)
+		return c.Next()
+	}
+}
+
+// setDeprecation adds the deprecation headers, with the link paths made absolute
+func setDeprecation(x exchange, d Deprecation) {
+	d.Link = absoluteURL(x, d.Link)
+	d.Successor = absoluteURL(x, d.Successor)
+	for name, values := range d.Header() {
+		for _, value := range values {
+			if name == fiber.HeaderLink {
+				x.addHeader(name, value)
+			} else {
+				x.setHeader(name, value)
+			}
+		}
+	}
+}
+
+// absoluteURL returns a path such as /v2/users made absolute with the base
+// URL of the request, or the URL unchanged
+func absoluteURL(x exchange, href string) string {
+	if strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//") {
+		return x.baseURL() + href
+	}
+	return href
+}
@@ -0,0 +1,354 @@
+package response
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Content types of the built-in encoders
+const (
+	MIMEApplicationXML     = fiber.MIMEApplicationXML
+	MIMETextXML            = fiber.MIMETextXML
+	MIMEApplicationMsgPack = "application/msgpack"
+)
+
+// Encoder encodes a response body for a content type. The body is the JSON
+// document of the response, after key case conversion, so every format has
+// the same fields:
+//
+//	response.RegisterEncoder("application/cbor", func(body []byte) ([]byte, error) {
+//		var v interface{}
+//		if err := json.Unmarshal(body, &v); err != nil {
+//			return nil, err
+//		}
+//		return cbor.Marshal(v)
+//	})
+type Encoder func(body []byte) ([]byte, error)
+
+// encoderEntry is a registered encoder
+type encoderEntry struct {
+	contentType string
+	encode      Encoder
+}
+
+var (
+	encodersMu sync.RWMutex
+
+	// encoders are offered in order, so JSON is sent when the client accepts anything
+	encoders = []encoderEntry{
+		{fiber.MIMEApplicationJSON, func(body []byte) ([]byte, error) { return body, nil }},
+		{MIMEApplicationXML, EncodeXML},
+		{MIMETextXML, EncodeXML},
+		{MIMEApplicationMsgPack, EncodeMsgPack},
+		{"application/x-msgpack", EncodeMsgPack},
+		{"application/vnd.msgpack", EncodeMsgPack},
+	}
+)
+
+// RegisterEncoder adds or replaces the encoder of a content type, which
+// responses use when the Accept header of the request prefers it
+func RegisterEncoder(contentType string, enc Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i := range encoders {
+		if encoders[i].contentType == contentType {
+			encoders[i].encode = enc
+			return
+		}
+	}
+	encoders = append(encoders, encoderEntry{contentType: contentType, encode: enc})
+}
+
+// negotiate returns the content type and encoder an Accept header prefers,
+// falling back to JSON
+func negotiate(accept string) (string, Encoder) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	best, bestQuality := 0, 0.0
+	for i, e := range encoders {
+		// Earlier encoders win ties, so JSON is sent for */*
+		if q := acceptQuality(accept, e.contentType); q > bestQuality {
+			best, bestQuality = i, q
+		}
+	}
+	return encoders[best].contentType, encoders[best].encode
+}
+
+// acceptQuality returns the quality an Accept header gives a content type,
+// from its most specific matching media range, or 0 when it is not accepted.
+// Every type is accepted without an Accept header.
+func acceptQuality(accept, contentType string) float64 {
+	if strings.TrimSpace(accept) == "" {
+		return 1
+	}
+
+	quality, specificity := 0.0, -1
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+
+		var s int
+		switch {
+		case name == contentType:
+			s = 2
+		case strings.HasSuffix(name, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(name, "*")):
+			s = 1
+		case name == "*/*" || name == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		quality, specificity = q, s
+	}
+	return quality
+}
+
+// EncodeXML converts a JSON document to XML under a response element. Object
+// keys become elements, and array values are written as item elements:
+//
+//	<response><success>true</success><data><item><id>1</id></item></data></response>
+func EncodeXML(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := writeXML(dec, &buf, "response"); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXML writes the next JSON value of the decoder as an element
+func writeXML(dec *json.Decoder, buf *bytes.Buffer, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString("<" + name + ">")
+	switch t := tok.(type) {
+	case json.Delim:
+		for dec.More() {
+			child := "item"
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = xmlName(key.(string))
+			}
+			if err := writeXML(dec, buf, child); err != nil {
+				return err
+			}
+		}
+		// Read the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case string:
+		if err := xml.EscapeText(buf, []byte(t)); err != nil {
+			return err
+		}
+	case json.Number:
+		buf.WriteString(t.String())
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	}
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+// xmlName turns a key into a valid element name, replacing invalid characters
+// with underscores
+func xmlName(key string) string {
+	runes := []rune(key)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			runes[i] = '_'
+		}
+	}
+	if len(runes) == 0 || !(unicode.IsLetter(runes[0]) || runes[0] == '_') {
+		runes = append([]rune{'_'}, runes...)
+	}
+	return string(runes)
+}
+
+// EncodeMsgPack converts a JSON document to MessagePack
+func EncodeMsgPack(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	if err := writeMsgPack(dec, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMsgPack writes the next JSON value of the decoder as MessagePack
+func writeMsgPack(dec *json.Decoder, buf *bytes.Buffer) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		// Containers start with their length, so encode the elements first
+		var (
+			elements bytes.Buffer
+			n        int
+		)
+		for ; dec.More(); n++ {
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				writeMsgPackString(&elements, key.(string))
+			}
+			if err := writeMsgPack(dec, &elements); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+
+		if t == '{' {
+			writeMsgPackHeader(buf, n, 0x80, 0xde, 0xdf)
+		} else {
+			writeMsgPackHeader(buf, n, 0x90, 0xdc, 0xdd)
+		}
+		buf.Write(elements.Bytes())
+	case string:
+		writeMsgPackString(buf, t)
+	case json.Number:
+		return writeMsgPackNumber(buf, t)
+	case bool:
+		if t {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case nil:
+		buf.WriteByte(0xc0)
+	}
+	return nil
+}
+
+// writeMsgPackHeader writes the header of an array or map of n elements
+func writeMsgPackHeader(buf *bytes.Buffer, n int, fix, code16, code32 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(code32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+// writeMsgPackString writes a string
+func writeMsgPackString(buf *bytes.Buffer, s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(0xdb)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	buf.WriteString(s)
+}
+
+// writeMsgPackNumber writes a number in the smallest integer format that holds
+// it, or as a 64-bit float
+func writeMsgPackNumber(buf *bytes.Buffer, num json.Number) error {
+	if n, err := strconv.ParseInt(num.String(), 10, 64); err == nil {
+		switch {
+		case n >= 0 && n <= math.MaxInt8:
+			buf.WriteByte(byte(n))
+		case n >= 0:
+			writeMsgPackUint(buf, uint64(n))
+		case n >= -32:
+			buf.WriteByte(byte(int8(n)))
+		case n >= math.MinInt8:
+			buf.WriteByte(0xd0)
+			buf.WriteByte(byte(int8(n)))
+		case n >= math.MinInt16:
+			buf.WriteByte(0xd1)
+			buf.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(n))))
+		case n >= math.MinInt32:
+			buf.WriteByte(0xd2)
+			buf.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(n))))
+		default:
+			buf.WriteByte(0xd3)
+			buf.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+		}
+		return nil
+	}
+	if n, err := strconv.ParseUint(num.String(), 10, 64); err == nil {
+		writeMsgPackUint(buf, n)
+		return nil
+	}
+
+	f, err := num.Float64()
+	if err != nil {
+		return fmt.Errorf("response: invalid number %q", num)
+	}
+	buf.WriteByte(0xcb)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+	return nil
+}
+
+// writeMsgPackUint writes an unsigned integer above the positive fixint range
+func writeMsgPackUint(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(0xcf)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
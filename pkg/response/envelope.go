@@ -0,0 +1,230 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// field is a field of a response envelope
+type field struct {
+	name  string
+	value interface{}
+}
+
+// envelope is a response body whose fields are written in order
+type envelope []field
+
+// add appends a field, unless its name is "-"
+func (e envelope) add(name string, value interface{}) envelope {
+	if name == "-" {
+		return e
+	}
+	return append(e, field{name: name, value: value})
+}
+
+// MarshalJSON writes the fields as a JSON object in order
+func (e envelope) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range e {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// successBody builds the envelope of a successful response
+func successBody(fields Fields, code int, message string) envelope {
+	return envelope{}.
+		add(fields.Success, true).
+		add(fields.Code, code).
+		add(fields.Message, message)
+}
+
+// errorBody builds the envelope of an error response
+func errorBody(fields Fields, code int, errCode, message string, details interface{}) envelope {
+	body := envelope{}.
+		add(fields.Success, false).
+		add(fields.Code, code).
+		add(fields.Error, errCode).
+		add(fields.ErrorMessage, message)
+	if details != nil {
+		body = body.add(fields.Details, details)
+	}
+	return body
+}
+
+// send writes a response body with the configured metadata and key case, in
+// the format the Accept header of the request prefers
+func send(x exchange, status int, body envelope) error {
+	cfg := currentConfig()
+	out, err := x.marshal(addMetadata(x, body, cfg))
+	if err != nil {
+		return err
+	}
+
+	if convert := keyConverter(cfg.KeyCase); convert != nil {
+		if out, err = convertKeys(out, convert); err != nil {
+			return err
+		}
+	}
+
+	contentType, encode := negotiate(x.header(fiber.HeaderAccept))
+	if out, err = encode(out); err != nil {
+		return err
+	}
+
+	x.vary(fiber.HeaderAccept)
+	return x.send(status, contentType, out)
+}
+
+// keyConverter returns the function converting keys to a key case, or nil to
+// keep them as they are
+func keyConverter(keyCase KeyCase) func(string) string {
+	switch keyCase {
+	case KeyCaseSnake:
+		return snakeCase
+	case KeyCaseCamel:
+		return camelCase
+	default:
+		return nil
+	}
+}
+
+// convertKeys rewrites the object keys of a JSON document, keeping the order
+// of the keys and leaving the values as they are
+func convertKeys(data []byte, convert func(string) string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// Containers being written, with the number of tokens written in each
+	type container struct {
+		object bool
+		tokens int
+	}
+	var (
+		buf   bytes.Buffer
+		stack []container
+	)
+
+	// separate writes the separator before a token and reports whether the
+	// token is an object key
+	separate := func() bool {
+		if len(stack) == 0 {
+			return false
+		}
+		top := &stack[len(stack)-1]
+		isKey := top.object && top.tokens%2 == 0
+		switch {
+		case top.object && !isKey:
+			buf.WriteByte(':')
+		case top.tokens > 0:
+			buf.WriteByte(',')
+		}
+		top.tokens++
+		return isKey
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			if t == '{' || t == '[' {
+				separate()
+				stack = append(stack, container{object: t == '{'})
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+			buf.WriteRune(rune(t))
+		case string:
+			if separate() {
+				t = convert(t)
+			}
+			s, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(s)
+		case json.Number:
+			separate()
+			buf.WriteString(t.String())
+		case bool:
+			separate()
+			if t {
+				buf.WriteString("true")
+			} else {
+				buf.WriteString("false")
+			}
+		case nil:
+			separate()
+			buf.WriteString("null")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// snakeCase converts a key such as createdAt, UserID, or first-name to
+// created_at, user_id, or first_name
+func snakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			r = '_'
+		case unicode.IsUpper(r):
+			// Start a new word before an upper-case letter that follows a
+			// lower-case one, or that starts a word after an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// camelCase converts a key such as created_at, UserID, or first-name to
+// createdAt, userId, or firstName
+func camelCase(key string) string {
+	var b strings.Builder
+	for i, word := range strings.Split(snakeCase(key), "_") {
+		if word == "" {
+			continue
+		}
+		if i > 0 && b.Len() > 0 {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			word = string(runes)
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+)
+
+// exchange is a request and its response, so the helpers work with both Fiber
+// and net/http
+type exchange interface {
+	// context returns the context of the request
+	context() context.Context
+
+	// header returns a request header
+	header(name string) string
+
+	// query returns a query parameter
+	query(name string) string
+
+	// baseURL returns the scheme and host of the request
+	baseURL() string
+
+	// requestURL returns the full URL of the request
+	requestURL() string
+
+	// setHeader sets a response header
+	setHeader(name, value string)
+
+	// addHeader adds a value to a response header
+	addHeader(name, value string)
+
+	// vary adds a request header to the Vary header of the response
+	vary(name string)
+
+	// marshal encodes a value as JSON
+	marshal(v interface{}) ([]byte, error)
+
+	// send writes the status and the body of the response
+	send(status int, contentType string, body []byte) error
+
+	// started returns the time the request started, or the zero time
+	started() time.Time
+
+	// requestID returns the ID of the request, if any
+	requestID() string
+}
+
+// fiberExchange is a Fiber request
+type fiberExchange struct {
+	c *fiber.Ctx
+}
+
+func (x fiberExchange) context() context.Context     { return x.c.UserContext() }
+func (x fiberExchange) header(name string) string    { return x.c.Get(name) }
+func (x fiberExchange) query(name string) string     { return x.c.Query(name) }
+func (x fiberExchange) baseURL() string              { return x.c.BaseURL() }
+func (x fiberExchange) requestURL() string           { return x.c.BaseURL() + x.c.OriginalURL() }
+func (x fiberExchange) setHeader(name, value string) { x.c.Set(name, value) }
+func (x fiberExchange) addHeader(name, value string) { x.c.Append(name, value) }
+func (x fiberExchange) vary(name string)             { x.c.Vary(name) }
+
+func (x fiberExchange) marshal(v interface{}) ([]byte, error) {
+	return x.c.App().Config().JSONEncoder(v)
+}
+
+func (x fiberExchange) send(status int, contentType string, body []byte) error {
+	x.c.Set(fiber.HeaderContentType, contentType)
+	return x.c.Status(status).Send(body)
+}
+
+func (x fiberExchange) started() time.Time {
+	return x.c.Context().Time()
+}
+
+func (x fiberExchange) requestID() string {
+	if id := logger.RequestIDFromContext(x.c.Context()); id != "" {
+		return id
+	}
+	if id := x.c.GetRespHeader(fiber.HeaderXRequestID); id != "" {
+		return id
+	}
+	return x.c.Get(fiber.HeaderXRequestID)
+}
+
+// httpExchange is a net/http request
+type httpExchange struct {
+	w http.ResponseWriter
+	r *http.Request
+}
+
+func (x httpExchange) context() context.Context     { return x.r.Context() }
+func (x httpExchange) header(name string) string    { return x.r.Header.Get(name) }
+func (x httpExchange) query(name string) string     { return x.r.URL.Query().Get(name) }
+func (x httpExchange) requestURL() string           { return x.baseURL() + x.r.URL.RequestURI() }
+func (x httpExchange) setHeader(name, value string) { x.w.Header().Set(name, value) }
+func (x httpExchange) addHeader(name, value string) { x.w.Header().Add(name, value) }
+func (x httpExchange) vary(name string)             { x.w.Header().Add(fiber.HeaderVary, name) }
+
+func (x httpExchange) baseURL() string {
+	scheme := "http"
+	if x.r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + x.r.Host
+}
+
+func (x httpExchange) marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (x httpExchange) send(status int, contentType string, body []byte) error {
+	x.w.Header().Set(fiber.HeaderContentType, contentType)
+	x.w.WriteHeader(status)
+	_, err := x.w.Write(body)
+	return err
+}
+
+func (x httpExchange) started() time.Time {
+	return startTimeFromContext(x.r.Context())
+}
+
+func (x httpExchange) requestID() string {
+	if id := logger.RequestIDFromContext(x.r.Context()); id != "" {
+		return id
+	}
+	if id := x.w.Header().Get(fiber.HeaderXRequestID); id != "" {
+		return id
+	}
+	return x.r.Header.Get(fiber.HeaderXRequestID)
+}
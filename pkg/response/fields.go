@@ -0,0 +1,146 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// FieldsQuery is the query parameter selecting the fields of the response
+// data, such as ?fields=id,name,author.name
+const FieldsQuery = "fields"
+
+// fieldMask is a set of requested fields; a nil mask for a field keeps all of
+// its nested fields
+type fieldMask map[string]fieldMask
+
+// parseFieldMask parses a comma-separated list of fields, where nested fields
+// are separated by dots, or returns nil when no fields are listed
+func parseFieldMask(s string) fieldMask {
+	var mask fieldMask
+	for _, path := range strings.Split(s, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if mask == nil {
+			mask = fieldMask{}
+		}
+
+		m := mask
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			sub, ok := m[name]
+			if i == len(names)-1 {
+				// The whole field is requested
+				m[name] = nil
+				break
+			}
+			if ok && sub == nil {
+				// The whole field is already requested
+				break
+			}
+			if !ok {
+				sub = fieldMask{}
+				m[name] = sub
+			}
+			m = sub
+		}
+	}
+	return mask
+}
+
+// selectFields projects data to the fields requested with the fields query
+// parameter. Fields are matched by their names in the response, after key
+// case conversion. Data without requested fields is returned unchanged.
+func selectFields(x exchange, data interface{}) (interface{}, error) {
+	mask := parseFieldMask(x.query(FieldsQuery))
+	if mask == nil || data == nil {
+		return data, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	convert := keyConverter(currentConfig().KeyCase)
+	if convert == nil {
+		convert = func(key string) string { return key }
+	}
+	selected, err := mask.apply(raw, convert)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(selected), nil
+}
+
+// apply keeps the requested fields of a JSON object, or of each object of a
+// JSON array; other values are returned unchanged
+func (m fieldMask) apply(data []byte, convert func(string) string) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return data, nil
+	}
+
+	switch trimmed[0] {
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			selected, err := m.apply(item, convert)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = selected
+		}
+		return json.Marshal(items)
+
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := tok.(string)
+
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, err
+			}
+			sub, ok := m[convert(key)]
+			if !ok {
+				continue
+			}
+			if sub != nil {
+				if value, err = sub.apply(value, convert); err != nil {
+					return nil, err
+				}
+			}
+
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			name, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+
+	default:
+		return data, nil
+	}
+}
@@ -0,0 +1,98 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// HTTP sends the same responses as the Fiber helpers with net/http, for
+// services that do not use Fiber:
+//
+//	func getUser(w http.ResponseWriter, r *http.Request) {
+//		user, err := users.Find(r.Context(), r.PathValue("id"))
+//		if err != nil {
+//			response.HTTP.Error(w, r, err)
+//			return
+//		}
+//		response.HTTP.Success(w, r, "User found", user)
+//	}
+//
+// Responses use the configuration set with Configure, the encoders of
+// RegisterEncoder, and the fields query parameter, like the Fiber helpers.
+var HTTP HTTPResponder
+
+// HTTPResponder sends responses with net/http; use the HTTP variable
+type HTTPResponder struct{}
+
+// Success sends a successful response with the provided data
+func (HTTPResponder) Success(w http.ResponseWriter, r *http.Request, message string, data interface{}, statusCode ...int) error {
+	return success(httpExchange{w, r}, message, data, statusCode)
+}
+
+// SuccessWithPagination sends a successful paginated response with page links
+func (HTTPResponder) SuccessWithPagination(w http.ResponseWriter, r *http.Request, message string, paginationResult interface{}, statusCode ...int) error {
+	return successWithPagination(httpExchange{w, r}, message, paginationResult, statusCode)
+}
+
+// SuccessWithLinks sends a successful response with links to related resources
+func (HTTPResponder) SuccessWithLinks(w http.ResponseWriter, r *http.Request, message string, data interface{}, links Links, statusCode ...int) error {
+	return successWithLinks(httpExchange{w, r}, message, data, links, statusCode)
+}
+
+// Deprecated adds the deprecation headers to the response, before it is sent
+func (HTTPResponder) Deprecated(w http.ResponseWriter, r *http.Request, d Deprecation) {
+	setDeprecation(httpExchange{w, r}, d)
+}
+
+// Created sends a successful created response
+func (HTTPResponder) Created(w http.ResponseWriter, r *http.Request, message string, data interface{}) error {
+	return success(httpExchange{w, r}, message, data, []int{http.StatusCreated})
+}
+
+// Error sends an error response, with catalog messages in the request locale
+func (HTTPResponder) Error(w http.ResponseWriter, r *http.Request, err error) error {
+	return sendErr(httpExchange{w, r}, err)
+}
+
+// ValidationError sends the 422 response for validator errors in the request locale
+func (HTTPResponder) ValidationError(w http.ResponseWriter, r *http.Request, err error) error {
+	return sendValidationError(httpExchange{w, r}, err)
+}
+
+// BadRequest sends a bad request error response
+func (HTTPResponder) BadRequest(w http.ResponseWriter, r *http.Request, message string, details interface{}) error {
+	return sendError(httpExchange{w, r}, http.StatusBadRequest, errors.ErrCodeBadRequest, message, details)
+}
+
+// NotFound sends a not found error response
+func (HTTPResponder) NotFound(w http.ResponseWriter, r *http.Request, message string) error {
+	return sendError(httpExchange{w, r}, http.StatusNotFound, errors.ErrCodeNotFound, message, nil)
+}
+
+// MethodNotAllowed sends a method not allowed error response
+func (HTTPResponder) MethodNotAllowed(w http.ResponseWriter, r *http.Request, message string) error {
+	return sendError(httpExchange{w, r}, http.StatusMethodNotAllowed, errors.ErrCodeMethodNotAllowed, message, nil)
+}
+
+// Unauthorized sends an unauthorized error response
+func (HTTPResponder) Unauthorized(w http.ResponseWriter, r *http.Request, message string) error {
+	return sendError(httpExchange{w, r}, http.StatusUnauthorized, errors.ErrCodeUnauthorized, message, nil)
+}
+
+// Forbidden sends a forbidden error response
+func (HTTPResponder) Forbidden(w http.ResponseWriter, r *http.Request, message string) error {
+	return sendError(httpExchange{w, r}, http.StatusForbidden, errors.ErrCodeForbidden, message, nil)
+}
+
+// InternalServerError sends an internal server error response
+func (HTTPResponder) InternalServerError(w http.ResponseWriter, r *http.Request, message string) error {
+	return sendError(httpExchange{w, r}, http.StatusInternalServerError, errors.ErrCodeInternalError, message, nil)
+}
+
+// Locale returns the best supported locale for the request based on its
+// Accept-Language header, falling back to the default locale. A locale set
+// with i18n.WithLocale on the request context takes precedence.
+func (HTTPResponder) Locale(r *http.Request) string {
+	return locale(httpExchange{r: r})
+}
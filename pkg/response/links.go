@@ -0,0 +1,78 @@
+package response
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Links are the URLs of related resources and actions by relation, written
+// like the page links of paginated responses:
+//
+//	response.Links{
+//		"orders": "/users/42/orders",
+//		"avatar": "https://cdn.example.com/avatars/42.png",
+//	}
+type Links map[string]string
+
+// Header returns the links as an RFC 8288 Link header value, without the
+// self link
+func (l Links) Header() string {
+	rels := make([]string, 0, len(l))
+	for rel := range l {
+		if rel != "self" && l[rel] != "" {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+
+	parts := make([]string, len(rels))
+	for i, rel := range rels {
+		parts[i] = "<" + l[rel] + `>; rel="` + rel + `"`
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resolve returns the links with paths such as /users/42 made absolute with
+// the base URL of the request, and a self link to the request when it has none
+func (l Links) resolve(x exchange) Links {
+	resolved := Links{"self": x.requestURL()}
+	for rel, href := range l {
+		resolved[rel] = absoluteURL(x, href)
+	}
+	return resolved
+}
+
+// SuccessWithLinks sends a successful response with links to related
+// resources. Paths are made absolute, a self link to the request is added
+// unless links has one, and the links are also sent in the Link header:
+//
+//	return response.SuccessWithLinks(c, "User found", user, response.Links{
+//		"orders": "/users/" + id + "/orders",
+//	})
+func SuccessWithLinks(c *fiber.Ctx, message string, data interface{}, links Links, statusCode ...int) error {
+	return successWithLinks(fiberExchange{c}, message, data, links, statusCode)
+}
+
+// successWithLinks sends a successful response with links
+func successWithLinks(x exchange, message string, data interface{}, links Links, statusCode []int) error {
+	code := statusOr(statusCode, fiber.StatusOK)
+
+	data, err := selectFields(x, data)
+	if err != nil {
+		return err
+	}
+
+	links = links.resolve(x)
+	if header := links.Header(); header != "" {
+		x.addHeader(fiber.HeaderLink, header)
+	}
+
+	fields := currentConfig().Fields
+	body := successBody(fields, code, message)
+	if data != nil {
+		body = body.add(fields.Data, data)
+	}
+	return send(x, code, body.add(fields.Links, links))
+}
@@ -0,0 +1,45 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+)
+
+// SuccessJSON returns the JSON body Success writes, with the configured
+// field names and key case, for transports other than HTTP such as
+// WebSockets. Field selection with ?fields= does not apply.
+func SuccessJSON(message string, data interface{}, statusCode ...int) ([]byte, error) {
+	code := statusOr(statusCode, http.StatusOK)
+	fields := currentConfig().Fields
+	body := successBody(fields, code, message)
+	if data != nil {
+		body = body.add(fields.Data, data)
+	}
+	return encodeJSON(body)
+}
+
+// ErrorJSON returns the JSON body Error writes for an error, with messages
+// from the standard error catalog rendered in a locale
+func ErrorJSON(err error, locale string) ([]byte, error) {
+	fields := currentConfig().Fields
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) {
+		appErr = appErr.Localize(locale)
+		return encodeJSON(errorBody(fields, appErr.HTTPCode, appErr.Code, appErr.Message, appErr.Details))
+	}
+	return encodeJSON(errorBody(fields, http.StatusInternalServerError, errors.ErrCodeInternalError, err.Error(), nil))
+}
+
+// encodeJSON encodes a body as JSON with the configured key case
+func encodeJSON(body envelope) ([]byte, error) {
+	out, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	if convert := keyConverter(currentConfig().KeyCase); convert != nil {
+		return convertKeys(out, convert)
+	}
+	return out, nil
+}
@@ -0,0 +1,70 @@
+package response
+
+import (
+	"context"
+	"time"
+)
+
+// Metadata selects the fields added to every response so clients and
+// support teams can tell the API version and the latency of a request from
+// its body alone:
+//
+//	{"success":true,...,"api_version":"2024-06-01","server_time":"2024-06-01T12:00:00.123Z","duration_ms":12.5,"request_id":"..."}
+type Metadata struct {
+	// APIVersion is written in every response when set
+	APIVersion string
+
+	// ServerTime adds the time the response is written, in RFC 3339 UTC
+	ServerTime bool
+
+	// Duration adds the milliseconds since the request started. Requests
+	// served with net/http need a start time, see WithStartTime.
+	Duration bool
+
+	// RequestID adds the ID set by the requestid middleware or sent in the
+	// X-Request-ID header, when the request has one
+	RequestID bool
+}
+
+// startTimeKey is the context key of the start time of a request
+type startTimeKey struct{}
+
+// WithStartTime returns a copy of ctx carrying the time a request started,
+// for the duration of net/http responses. Fiber requests know their start.
+//
+//	func timing(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			next.ServeHTTP(w, r.WithContext(response.WithStartTime(r.Context(), time.Now())))
+//		})
+//	}
+func WithStartTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, startTimeKey{}, t)
+}
+
+// startTimeFromContext returns the start time stored by WithStartTime
+func startTimeFromContext(ctx context.Context) time.Time {
+	t, _ := ctx.Value(startTimeKey{}).(time.Time)
+	return t
+}
+
+// addMetadata appends the metadata fields of the configuration to a body
+func addMetadata(x exchange, body envelope, cfg Config) envelope {
+	meta, fields := cfg.Metadata, cfg.Fields
+	if meta.APIVersion != "" {
+		body = body.add(fields.APIVersion, meta.APIVersion)
+	}
+	if meta.ServerTime {
+		body = body.add(fields.ServerTime, time.Now().UTC().Format(time.RFC3339Nano))
+	}
+	if meta.Duration {
+		if started := x.started(); !started.IsZero() {
+			body = body.add(fields.Duration, float64(time.Since(started).Microseconds())/1000)
+		}
+	}
+	if meta.RequestID {
+		if id := x.requestID(); id != "" {
+			body = body.add(fields.RequestID, id)
+		}
+	}
+	return body
+}
@@ -2,10 +2,13 @@
 package response
 
 import (
+	"net/url"
 	"reflect"
 
 	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/i18n"
 	"github.com/anaknegeri/gokit/pkg/pagination"
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -17,27 +20,47 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// Success sends a successful response with the provided data
+// Success sends a successful response with the provided data. Clients can
+// request some fields of the data with ?fields=id,name.
 func Success(c *fiber.Ctx, message string, data interface{}, statusCode ...int) error {
-	code := fiber.StatusOK
-	if len(statusCode) > 0 {
-		code = statusCode[0]
+	return success(fiberExchange{c}, message, data, statusCode)
+}
+
+// success sends a successful response
+func success(x exchange, message string, data interface{}, statusCode []int) error {
+	code := statusOr(statusCode, fiber.StatusOK)
+
+	data, err := selectFields(x, data)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(code).JSON(Response{
-		Success: true,
-		Code:    code,
-		Message: message,
-		Data:    data,
-	})
+	fields := currentConfig().Fields
+	body := successBody(fields, code, message)
+	if data != nil {
+		body = body.add(fields.Data, data)
+	}
+	return send(x, code, body)
 }
 
-// SuccessWithPagination sends a successful paginated response
-func SuccessWithPagination(c *fiber.Ctx, message string, paginationResult interface{}, statusCode ...int) error {
-	code := fiber.StatusOK
+// statusOr returns the optional status code, or the default
+func statusOr(statusCode []int, defaultCode int) int {
 	if len(statusCode) > 0 {
-		code = statusCode[0]
+		return statusCode[0]
 	}
+	return defaultCode
+}
+
+// SuccessWithPagination sends a successful paginated response. Links to the
+// first, previous, next, and last pages are added to the envelope and to the
+// Link header. Clients can request some fields of the items with ?fields=id,name.
+func SuccessWithPagination(c *fiber.Ctx, message string, paginationResult interface{}, statusCode ...int) error {
+	return successWithPagination(fiberExchange{c}, message, paginationResult, statusCode)
+}
+
+// successWithPagination sends a successful paginated response
+func successWithPagination(x exchange, message string, paginationResult interface{}, statusCode []int) error {
+	code := statusOr(statusCode, fiber.StatusOK)
 
 	// Extract data and metadata from pagination result if it's from our pagination package
 	var data interface{}
@@ -48,8 +71,9 @@ func SuccessWithPagination(c *fiber.Ctx, message string, paginationResult interf
 		data = pr.Data
 		meta = pr.Meta
 	} else {
-		// Otherwise, assume it's a custom structure with data and meta fields
-		v := reflect.ValueOf(paginationResult)
+		// Otherwise, assume it's a custom structure with data and meta fields,
+		// such as pagination.Result[T] or pagination.CursorResult
+		v := reflect.Indirect(reflect.ValueOf(paginationResult))
 		if v.Kind() == reflect.Struct {
 			dataField := v.FieldByName("Data")
 			metaField := v.FieldByName("Meta")
@@ -69,39 +93,106 @@ func SuccessWithPagination(c *fiber.Ctx, message string, paginationResult interf
 		}
 	}
 
-	return c.Status(code).JSON(struct {
-		Success bool        `json:"success"`
-		Code    int         `json:"code"`
-		Message string      `json:"message"`
-		Data    interface{} `json:"data"`
-		Meta    interface{} `json:"meta,omitempty"`
-	}{
-		Success: true,
-		Code:    code,
-		Message: message,
-		Data:    data,
-		Meta:    meta,
-	})
+	data, err := selectFields(x, data)
+	if err != nil {
+		return err
+	}
+
+	// Add navigation links for our pagination metadata
+	links := paginationLinks(x.requestURL(), meta)
+	if links != nil {
+		if header := links.Header(); header != "" {
+			x.addHeader(fiber.HeaderLink, header)
+		}
+	}
+
+	fields := currentConfig().Fields
+	body := successBody(fields, code, message).add(fields.Data, data)
+	if meta != nil {
+		body = body.add(fields.Meta, meta)
+	}
+	if links != nil {
+		body = body.add(fields.Links, links)
+	}
+	return send(x, code, body)
+}
+
+// PaginationLinks builds the page links for the request from pagination or
+// cursor metadata, or returns nil for other metadata
+func PaginationLinks(c *fiber.Ctx, meta interface{}) *pagination.Links {
+	return paginationLinks(fiberExchange{c}.requestURL(), meta)
+}
+
+// paginationLinks builds the page links for a request URL
+func paginationLinks(rawURL string, meta interface{}) *pagination.Links {
+	requestURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	var links pagination.Links
+	switch m := meta.(type) {
+	case pagination.PaginationMeta:
+		links = pagination.BuildLinks(requestURL, m)
+	case pagination.CursorMeta:
+		links = pagination.BuildCursorLinks(requestURL, m)
+	default:
+		return nil
+	}
+	return &links
+}
+
+// Locale returns the locale of the request: the one chosen by
+// i18n.Middleware, or else the best supported locale for its Accept-Language
+// header, falling back to the default locale
+func Locale(c *fiber.Ctx) string {
+	return locale(fiberExchange{c})
+}
+
+// locale returns the locale of a request
+func locale(x exchange) string {
+	if l := i18n.LocaleFromContext(x.context()); l != "" {
+		return l
+	}
+	return i18n.MatchLocale(x.header(fiber.HeaderAcceptLanguage))
 }
 
 // Error sends an error response
+// Messages from the standard error catalog are rendered in the request locale
 func Error(c *fiber.Ctx, err error) error {
-	if appErr, ok := err.(*errors.AppError); ok {
-		return c.Status(appErr.HTTPCode).JSON(errors.ErrorResponse{
-			Success: false,
-			Code:    appErr.HTTPCode,
-			Error:   appErr.Code,
-			Message: appErr.Message,
-			Details: appErr.Details,
-		})
+	return sendErr(fiberExchange{c}, err)
+}
+
+// sendErr sends the error response of an error
+func sendErr(x exchange, err error) error {
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) {
+		appErr = appErr.Localize(locale(x))
+		return sendError(x, appErr.HTTPCode, appErr.Code, appErr.Message, appErr.Details)
 	}
 
-	return c.Status(fiber.StatusInternalServerError).JSON(errors.ErrorResponse{
-		Success: false,
-		Code:    fiber.StatusInternalServerError,
-		Error:   errors.ErrCodeInternalError,
-		Message: err.Error(),
-	})
+	return sendError(x, fiber.StatusInternalServerError, errors.ErrCodeInternalError, err.Error(), nil)
+}
+
+// sendError sends an error response envelope
+func sendError(x exchange, code int, errCode, message string, details interface{}) error {
+	return send(x, code, errorBody(currentConfig().Fields, code, errCode, message, details))
+}
+
+// ValidationError sends the 422 response for validator errors with messages
+// in the request locale, see Locale
+func ValidationError(c *fiber.Ctx, err error) error {
+	return sendValidationError(fiberExchange{c}, err)
+}
+
+// sendValidationError sends the response of validator errors
+func sendValidationError(x exchange, err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		// Other errors, such as validating a nil pointer, are not the client's fault
+		return sendErr(x, err)
+	}
+	return sendErr(x, errors.ValidatorErrorLocalized(err, locale(x)))
 }
 
 // Created sends a successful created response
@@ -111,61 +202,30 @@ func Created(c *fiber.Ctx, message string, data interface{}) error {
 
 // BadRequest sends a bad request error response
 func BadRequest(c *fiber.Ctx, message string, details interface{}) error {
-	return c.Status(fiber.StatusBadRequest).JSON(errors.ErrorResponse{
-		Success: false,
-		Code:    fiber.StatusBadRequest,
-		Error:   errors.ErrCodeBadRequest,
-		Message: message,
-		Details: details,
-	})
+	return sendError(fiberExchange{c}, fiber.StatusBadRequest, errors.ErrCodeBadRequest, message, details)
 }
 
 // NotFound sends a not found error response
 func NotFound(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusNotFound).JSON(errors.ErrorResponse{
-		Success: false,
-		Code:    fiber.StatusNotFound,
-		Error:   errors.ErrCodeNotFound,
-		Message: message,
-	})
+	return sendError(fiberExchange{c}, fiber.StatusNotFound, errors.ErrCodeNotFound, message, nil)
 }
 
 // MethodNotAllowed sends a method not allowed error response
 func MethodNotAllowed(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusMethodNotAllowed).JSON(errors.ErrorResponse{
-		Success: false,
-		Code:    fiber.StatusMethodNotAllowed,
-		Error:   errors.ErrCodeMethodNotAllowed,
-		Message: message,
-	})
+	return sendError(fiberExchange{c}, fiber.StatusMethodNotAllowed, errors.ErrCodeMethodNotAllowed, message, nil)
 }
 
 // Unauthorized sends an unauthorized error response
 func Unauthorized(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusUnauthorized).JSON(errors.ErrorResponse{
-		Success: false,
-		Code:    fiber.StatusUnauthorized,
-		Error:   errors.ErrCodeUnauthorized,
-		Message: message,
-	})
+	return sendError(fiberExchange{c}, fiber.StatusUnauthorized, errors.ErrCodeUnauthorized, message, nil)
 }
 
 // Forbidden sends a forbidden error response
 func Forbidden(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusForbidden).JSON(errors.ErrorResponse{
-		Success: false,
-		Code:    fiber.StatusForbidden,
-		Error:   errors.ErrCodeForbidden,
-		Message: message,
-	})
+	return sendError(fiberExchange{c}, fiber.StatusForbidden, errors.ErrCodeForbidden, message, nil)
 }
 
 // InternalServerError sends an internal server error response
 func InternalServerError(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusInternalServerError).JSON(errors.ErrorResponse{
-		Success: false,
-		Code:    fiber.StatusInternalServerError,
-		Error:   errors.ErrCodeInternalError,
-		Message: message,
-	})
+	return sendError(fiberExchange{c}, fiber.StatusInternalServerError, errors.ErrCodeInternalError, message, nil)
 }
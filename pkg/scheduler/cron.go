@@ -0,0 +1,239 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns when a task runs next
+type Schedule interface {
+	// Next returns the first run time after t, or the zero time when there
+	// is none
+	Next(t time.Time) time.Time
+}
+
+// Every returns a schedule running at a fixed interval, at least a second
+func Every(d time.Duration) Schedule {
+	if d < time.Second {
+		d = time.Second
+	}
+	return interval(d)
+}
+
+// interval is a schedule with a fixed interval
+type interval time.Duration
+
+// Next returns t plus the interval
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// cron is a schedule parsed from a cron expression, with a bit set per field
+type cron struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar record a day of month or week field starting with
+	// "*", since a day matches either field when both are restricted
+	domStar, dowStar bool
+}
+
+// cronField describes a field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a schedule: a cron expression with the five fields minute,
+// hour, day of month, month, and day of week, e.g. "*/15 * * * *" or
+// "30 2 * * mon-fri"; a descriptor such as "@daily" or "@hourly"; or an
+// interval such as "@every 90s"
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("scheduler: invalid interval %q", rest)
+		}
+		return Every(d), nil
+	}
+	if expr, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("scheduler: cron expression %q needs %d fields, got %d", spec, len(cronFields), len(fields))
+	}
+
+	var c cron
+	sets := []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, field := range fields {
+		set, err := parseField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("scheduler: cron expression %q: %w", spec, err)
+		}
+		*sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	// As in Vixie cron, a field starting with "*", such as "*/2", counts as
+	// unrestricted for the day rule
+	c.domStar = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[2], "?")
+	c.dowStar = strings.HasPrefix(fields[4], "*") || strings.HasPrefix(fields[4], "?")
+	return &c, nil
+}
+
+// parseField parses a comma-separated list of values, ranges, and steps
+func parseField(expr string, field cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepExpr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepExpr, field.name)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+			lo, hi = field.min, field.max
+		case strings.Contains(rangeExpr, "-"):
+			from, to, _ := strings.Cut(rangeExpr, "-")
+			var err error
+			if lo, err = parseValue(from, field); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(to, field); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s", rangeExpr, field.name)
+			}
+		default:
+			var err error
+			if lo, err = parseValue(rangeExpr, field); err != nil {
+				return 0, err
+			}
+			// "5/10" runs from 5 to the end of the range
+			hi = lo
+			if hasStep {
+				hi = field.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a number or name of a field
+func parseValue(expr string, field cronField) (int, error) {
+	if v, ok := field.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil || v < field.min || v > field.max {
+		return 0, fmt.Errorf("invalid value %q in %s", expr, field.name)
+	}
+	return v, nil
+}
+
+// Next returns the first minute after t matching the expression, in the
+// location of t
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Any expression matches within 5 years, including February 29
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = dateAfter(t, t.Year(), t.Month()+1, 1, 0)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = dateAfter(t, t.Year(), t.Month(), t.Day()+1, 0)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = dateAfter(t, t.Year(), t.Month(), t.Day(), t.Hour()+1)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			next := nextBit(c.minute, t.Minute())
+			if next < 0 {
+				t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			} else {
+				t = t.Add(time.Duration(next-t.Minute()) * time.Minute)
+			}
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dateAfter returns the start of an hour in the location of t, which must
+// be after t. A wall clock time skipped when clocks go forward is moved on
+// by the hours skipped, since time.Date may return a time before it.
+func dateAfter(t time.Time, year int, month time.Month, day, hour int) time.Time {
+	next := time.Date(year, month, day, hour, 0, 0, 0, t.Location())
+	for !next.After(t) {
+		next = next.Add(time.Hour)
+	}
+	return next
+}
+
+// matchDay reports whether the day of t matches the day of month and day of
+// week fields
+func (c *cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// nextBit returns the lowest set bit of a set above from, or -1
+func nextBit(set uint64, from int) int {
+	rest := set >> uint(from+1)
+	if rest == 0 {
+		return -1
+	}
+	return from + 1 + bits.TrailingZeros64(rest)
+}
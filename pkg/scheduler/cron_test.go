@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	in := func(loc *time.Location, value string) time.Time {
+		t.Helper()
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+		if err != nil {
+			t.Fatalf("Invalid time %q: %v", value, err)
+		}
+		return parsed
+	}
+	utc := func(value string) time.Time { return in(time.UTC, value) }
+	local := func(value string) time.Time { return in(newYork, value) }
+
+	// 2024-01-01 is a Monday
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time // Zero for none
+	}{
+		// Day of month and day of week
+		{"both restricted match either", "0 0 13 * fri", utc("2024-01-01 00:00"), utc("2024-01-05 00:00")},
+		{"both restricted match the day of month", "0 0 2 * fri", utc("2024-01-01 00:00"), utc("2024-01-02 00:00")},
+		{"day of week only", "0 0 * * mon", utc("2024-01-01 00:00"), utc("2024-01-08 00:00")},
+		{"day of month only", "0 0 15 * *", utc("2024-01-01 00:00"), utc("2024-01-15 00:00")},
+		{"stepped day of month matches both", "0 0 */2 * mon", utc("2024-01-01 00:00"), utc("2024-01-15 00:00")},
+		{"stepped day of week matches both", "0 0 1 * */2", utc("2024-01-01 00:00"), utc("2024-02-01 00:00")},
+		{"question mark", "0 0 ? * sun", utc("2024-01-01 00:00"), utc("2024-01-07 00:00")},
+		{"sunday as 7", "0 0 * * 7", utc("2024-01-01 00:00"), utc("2024-01-07 00:00")},
+
+		// Rollovers
+		{"next hour", "30 * * * *", utc("2024-01-31 23:45"), utc("2024-02-01 00:30")},
+		{"next year", "59 23 31 12 *", utc("2024-12-31 23:59"), utc("2025-12-31 23:59")},
+		{"months without the day", "0 0 31 * *", utc("2024-01-31 00:00"), utc("2024-03-31 00:00")},
+		{"leap day", "0 0 29 2 *", utc("2024-03-01 00:00"), utc("2028-02-29 00:00")},
+		{"month list", "0 0 1 mar,nov *", utc("2024-03-15 00:00"), utc("2024-11-01 00:00")},
+		{"no such day", "0 0 30 2 *", utc("2024-01-01 00:00"), time.Time{}},
+
+		// Daylight saving time in New York: clocks skip 02:00-03:00 on
+		// 2024-03-10 and repeat 01:00-02:00 on 2024-11-03. In Santiago they
+		// skip 00:00-01:00 on 2024-09-08.
+		{"skipped hour", "30 2 * * *", local("2024-03-09 03:00"), local("2024-03-11 02:30")},
+		{"midnight before spring forward", "0 0 * * *", local("2024-03-09 12:00"), local("2024-03-10 00:00")},
+		{"hour after spring forward", "0 * * * *", local("2024-03-10 01:30"), local("2024-03-10 03:00")},
+		{"midnight after fall back", "0 0 * * *", local("2024-11-03 00:00"), local("2024-11-04 00:00")},
+		{"repeated hour", "0 * * * *", local("2024-11-03 00:30"), local("2024-11-03 01:00")},
+		{"skipped midnight", "0 0 * * *", in(santiago, "2024-09-07 23:30"), in(santiago, "2024-09-09 00:00")},
+		{"hour after skipped midnight", "0 * * * *", in(santiago, "2024-09-07 23:30"), in(santiago, "2024-09-08 01:00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.spec, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextRepeatedHour(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	schedule, err := Parse("0 * * * *")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	// Hourly runs stay an hour apart while clocks go back
+	next := time.Date(2024, 11, 3, 0, 0, 0, 0, newYork)
+	for i := 0; i < 4; i++ {
+		previous := next
+		next = schedule.Next(previous)
+		if next.Sub(previous) != time.Hour {
+			t.Errorf("Expected a run an hour after %v, got %v", previous, next)
+		}
+	}
+	if next.Hour() != 3 {
+		t.Errorf("Expected four runs to reach 03:00, got %v", next)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@every -1s",
+		"@every soon",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Expected %q to be refused", spec)
+		}
+	}
+}
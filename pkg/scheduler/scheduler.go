@@ -0,0 +1,366 @@
+// Package scheduler runs recurring tasks on cron expressions or fixed
+// intervals, e.g. emptying the trash every night
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/lifecycle"
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// Func is the work of a task. It should return when ctx is done, which
+// happens when the task times out or the scheduler shuts down.
+type Func func(ctx context.Context) error
+
+// Config configures a scheduler
+type Config struct {
+	// Location is the time zone of cron expressions, defaulting to time.Local
+	Location *time.Location
+
+	// Timeout limits how long tasks run unless set per task, with no limit
+	// by default
+	Timeout time.Duration
+
+	// Logger logs task runs, defaulting to the logger named "scheduler"
+	Logger *logger.Logger
+
+	// OnRun is called after each run or skipped run of a task, e.g. to
+	// record metrics
+	OnRun func(run Run)
+}
+
+// TaskConfig configures a task
+type TaskConfig struct {
+	// Timeout limits how long the task runs, overriding the scheduler timeout
+	Timeout time.Duration
+
+	// AllowOverlap starts a run even when the previous one has not finished.
+	// By default such runs are skipped.
+	AllowOverlap bool
+}
+
+// Run describes a finished or skipped run of a task
+type Run struct {
+	Task     string
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+
+	// Skipped is set when the run did not start because the previous one was
+	// still running
+	Skipped bool
+}
+
+// TaskInfo describes a task and its runs so far
+type TaskInfo struct {
+	Name      string
+	Spec      string
+	Running   int
+	Runs      int
+	Failures  int
+	Skips     int
+	NextRun   time.Time
+	LastRun   time.Time
+	LastError string
+}
+
+// task is a scheduled task
+type task struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       Func
+	config   TaskConfig
+
+	mu   sync.Mutex
+	info TaskInfo
+}
+
+// Scheduler runs tasks on their schedules
+type Scheduler struct {
+	config Config
+
+	mu         sync.Mutex
+	tasks      map[string]*task
+	started    bool
+	registered bool
+	stop       context.CancelFunc // stops scheduling runs
+	cancel     context.CancelFunc // cancels running tasks
+	loopCtx    context.Context
+	runCtx     context.Context
+	loops      sync.WaitGroup
+	runs       sync.WaitGroup
+}
+
+// New creates a scheduler
+func New(config ...Config) *Scheduler {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("scheduler")
+	}
+	return &Scheduler{config: cfg, tasks: make(map[string]*task)}
+}
+
+// Add schedules a task with a spec accepted by Parse, e.g. "0 3 * * *" or
+// "@every 10m". Task names must be unique.
+func (s *Scheduler) Add(name, spec string, fn Func, config ...TaskConfig) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return s.add(name, spec, schedule, fn, config)
+}
+
+// AddSchedule schedules a task with a schedule such as Every(time.Minute)
+func (s *Scheduler) AddSchedule(name string, schedule Schedule, fn Func, config ...TaskConfig) error {
+	spec := ""
+	if i, ok := schedule.(interval); ok {
+		spec = "@every " + time.Duration(i).String()
+	}
+	return s.add(name, spec, schedule, fn, config)
+}
+
+// add schedules a task, starting its loop when the scheduler is running
+func (s *Scheduler) add(name, spec string, schedule Schedule, fn Func, config []TaskConfig) error {
+	t := &task{name: name, spec: spec, schedule: schedule, fn: fn}
+	if len(config) > 0 {
+		t.config = config[0]
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = s.config.Timeout
+	}
+	t.info = TaskInfo{Name: name, Spec: spec}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.tasks[name]; exists {
+		return fmt.Errorf("scheduler: task %q already exists", name)
+	}
+	s.tasks[name] = t
+	if s.started {
+		s.loops.Add(1)
+		go s.loop(s.loopCtx, t)
+	}
+	return nil
+}
+
+// Remove unschedules a task, letting a running run finish
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, name)
+}
+
+// Start starts running tasks on their schedules until Shutdown is called.
+// The scheduler registers with the default lifecycle manager, which shuts it
+// down with the app.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	if !s.registered {
+		s.registered = true
+		lifecycle.Register("scheduler", s)
+	}
+	s.started = true
+	s.loopCtx, s.stop = context.WithCancel(context.Background())
+	s.runCtx, s.cancel = context.WithCancel(context.Background())
+	for _, t := range s.tasks {
+		s.loops.Add(1)
+		go s.loop(s.loopCtx, t)
+	}
+}
+
+// Shutdown stops starting runs and waits for the running ones to finish. When
+// ctx is done first, the running tasks are cancelled.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.started = false
+	stop, cancel := s.stop, s.cancel
+	s.mu.Unlock()
+
+	stop()
+	s.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		cancel()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// RunNow runs a task once outside its schedule and waits for it, e.g. from
+// an admin endpoint. It returns the error of the task.
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	t, ok := s.tasks[name]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("scheduler: no task %q", name)
+	}
+	if !s.begin(t) {
+		return fmt.Errorf("scheduler: task %q is already running", name)
+	}
+	return s.run(ctx, t)
+}
+
+// Tasks describes the scheduled tasks, sorted by name
+func (s *Scheduler) Tasks() []TaskInfo {
+	s.mu.Lock()
+	tasks := make([]*task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, t)
+	}
+	s.mu.Unlock()
+
+	infos := make([]TaskInfo, len(tasks))
+	for i, t := range tasks {
+		t.mu.Lock()
+		infos[i] = t.info
+		t.mu.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// loop starts the runs of a task on its schedule until ctx is done or the
+// task is removed
+func (s *Scheduler) loop(ctx context.Context, t *task) {
+	defer s.loops.Done()
+
+	for {
+		next := t.schedule.Next(time.Now().In(s.config.Location))
+		if next.IsZero() {
+			return
+		}
+		t.mu.Lock()
+		t.info.NextRun = next
+		t.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		current := s.tasks[t.name] == t
+		runCtx := s.runCtx
+		s.mu.Unlock()
+		if !current {
+			return
+		}
+
+		if !s.begin(t) {
+			t.mu.Lock()
+			t.info.Skips++
+			t.mu.Unlock()
+			s.config.Logger.WithFields(logger.Fields{"task": t.name}).
+				Warnf("Skipped task %s: the previous run has not finished", t.name)
+			s.report(Run{Task: t.name, Start: time.Now(), Skipped: true})
+			continue
+		}
+		s.runs.Add(1)
+		go func() {
+			defer s.runs.Done()
+			_ = s.run(runCtx, t)
+		}()
+	}
+}
+
+// begin marks a run of a task as started, reporting false when it would
+// overlap a run that is not allowed to
+func (s *Scheduler) begin(t *task) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.info.Running > 0 && !t.config.AllowOverlap {
+		return false
+	}
+	t.info.Running++
+	return true
+}
+
+// run runs a task started with begin, recording and logging the outcome
+func (s *Scheduler) run(ctx context.Context, t *task) error {
+	log := s.config.Logger.WithFields(logger.Fields{"task": t.name})
+	start := time.Now()
+
+	err := s.call(logger.WithContext(ctx, log), t)
+	duration := time.Since(start)
+
+	t.mu.Lock()
+	t.info.Running--
+	t.info.Runs++
+	t.info.LastRun = start
+	t.info.LastError = ""
+	if err != nil {
+		t.info.Failures++
+		t.info.LastError = err.Error()
+	}
+	t.mu.Unlock()
+
+	if err != nil {
+		log.Errorf("Task %s failed after %s: %v", t.name, duration, err)
+	} else {
+		log.Debugf("Task %s finished in %s", t.name, duration)
+	}
+	s.report(Run{Task: t.name, Start: start, Duration: duration, Err: err})
+	return err
+}
+
+// call calls the task function with its timeout, turning panics into errors
+func (s *Scheduler) call(ctx context.Context, t *task) (err error) {
+	if t.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Errorf("Task %s panicked: %v\n%s", t.name, r, debug.Stack())
+			appErr := errors.InternalServerError("")
+			appErr.Internal = fmt.Errorf("panic: %v", r)
+			err = appErr
+		}
+	}()
+	return t.fn(ctx)
+}
+
+// report passes a run to the OnRun callback
+func (s *Scheduler) report(run Run) {
+	if s.config.OnRun != nil {
+		s.config.OnRun(run)
+	}
+}
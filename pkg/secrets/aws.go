@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// AWSConfig configures AWS Secrets Manager
+type AWSConfig struct {
+	// AWSConfig is used when its region is set; otherwise the default
+	// configuration of the environment is loaded
+	AWSConfig aws.Config
+
+	// Region overrides the region of the default configuration
+	Region string
+
+	// Prefix is prepended to secret names, e.g. "prod/api/"
+	Prefix string
+}
+
+// AWS reads secrets from AWS Secrets Manager. "name#key" reads a key of a
+// secret stored as JSON key/value pairs, as the console creates them.
+type AWS struct {
+	client *secretsmanager.Client
+	prefix string
+}
+
+// NewAWS creates Secrets reading from AWS Secrets Manager
+func NewAWS(ctx context.Context, cfg AWSConfig) (*AWS, error) {
+	awsCfg := cfg.AWSConfig
+	if awsCfg.Region == "" {
+		var options []func(*config.LoadOptions) error
+		if cfg.Region != "" {
+			options = append(options, config.WithRegion(cfg.Region))
+		}
+		loaded, err := config.LoadDefaultConfig(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("secrets: load AWS config: %w", err)
+		}
+		awsCfg = loaded
+	}
+	return &AWS{client: secretsmanager.NewFromConfig(awsCfg), prefix: cfg.Prefix}, nil
+}
+
+// Get returns the current value of a secret
+func (a *AWS) Get(ctx context.Context, name string) (string, error) {
+	name, key := splitKey(name)
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(a.prefix + name),
+	})
+	if err != nil {
+		var missing *types.ResourceNotFoundException
+		if errors.As(err, &missing) {
+			return "", notFound(name)
+		}
+		return "", fmt.Errorf("secrets: get %s: %w", name, err)
+	}
+
+	value := string(out.SecretBinary)
+	if out.SecretString != nil {
+		value = *out.SecretString
+	}
+	if key != "" {
+		return field(name, value, key)
+	}
+	return value, nil
+}
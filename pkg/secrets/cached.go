@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+)
+
+// DefaultTTL is how long Cached keeps secrets by default
+const DefaultTTL = 5 * time.Minute
+
+// Cached keeps the secrets read from Secrets for a time, and calls the
+// callbacks registered with OnRotate when a secret read again has changed
+type Cached struct {
+	secrets Secrets
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]cachedSecret
+	callbacks map[string][]func(value string)
+}
+
+type cachedSecret struct {
+	value   string
+	fetched time.Time
+}
+
+// NewCached wraps Secrets to keep secrets for ttl, defaulting to DefaultTTL:
+//
+//	s := secrets.NewCached(vault, time.Minute)
+//	s.OnRotate("api/db#password", func(password string) { pool.Reconnect(password) })
+//	go s.Watch(ctx, time.Minute)
+func NewCached(secrets Secrets, ttl time.Duration) *Cached {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cached{
+		secrets:   secrets,
+		ttl:       ttl,
+		entries:   make(map[string]cachedSecret),
+		callbacks: make(map[string][]func(value string)),
+	}
+}
+
+// Get returns a secret, reading it again when it is older than the TTL. When
+// that fails, the value read before is returned while the store is
+// unreachable, but not once the secret is deleted.
+func (c *Cached) Get(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetched) < c.ttl {
+		return entry.value, nil
+	}
+
+	value, err := c.fetch(ctx, name)
+	if err != nil && ok && !errors.Is(err, ErrNotFound) {
+		logger.FromContext(ctx).Warnf("Failed to refresh secret %q, using the cached value: %v", name, err)
+		return entry.value, nil
+	}
+	return value, err
+}
+
+// OnRotate registers a callback called with the new value of a secret when
+// it changes. Changes are noticed when the secret is read after its TTL, or
+// by Refresh and Watch.
+func (c *Cached) OnRotate(name string, fn func(value string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks[name] = append(c.callbacks[name], fn)
+}
+
+// Invalidate drops secrets from the cache, or every secret when no name is
+// given, so they are read again
+func (c *Cached) Invalidate(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(names) == 0 {
+		c.entries = make(map[string]cachedSecret)
+		return
+	}
+	for _, name := range names {
+		delete(c.entries, name)
+	}
+}
+
+// Refresh reads again every cached secret and every secret with a rotation
+// callback, calling the callbacks of those that changed
+func (c *Cached) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	names := make(map[string]bool, len(c.entries)+len(c.callbacks))
+	for name := range c.entries {
+		names[name] = true
+	}
+	for name := range c.callbacks {
+		names[name] = true
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for name := range names {
+		if _, err := c.fetch(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Watch calls Refresh every interval until ctx is done, logging failures
+func (c *Cached) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+				logger.FromContext(ctx).Warnf("Failed to refresh secrets: %v", err)
+			}
+		}
+	}
+}
+
+// fetch reads a secret, caches it, and calls the rotation callbacks when it
+// differs from the cached value. Deleted secrets are dropped.
+func (c *Cached) fetch(ctx context.Context, name string) (string, error) {
+	value, err := c.secrets.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.Invalidate(name)
+		}
+		return "", err
+	}
+
+	c.mu.Lock()
+	previous, known := c.entries[name]
+	c.entries[name] = cachedSecret{value: value, fetched: time.Now()}
+	var callbacks []func(value string)
+	if known && previous.value != value {
+		callbacks = append(callbacks, c.callbacks[name]...)
+	}
+	c.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// credentialsTTL is how long AWS credentials read from secrets are used
+// before they are read again, so rotated keys are picked up
+const credentialsTTL = 5 * time.Minute
+
+// AWSCredentials returns an AWS credentials provider reading an access key
+// and secret key from Secrets, and again every 5 minutes:
+//
+//	cfg.S3Credentials = secrets.AWSCredentials(vault, "api/s3#access_key", "api/s3#secret_key")
+func AWSCredentials(s Secrets, accessKeyName, secretKeyName string) aws.CredentialsProvider {
+	return aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		accessKey, err := s.Get(ctx, accessKeyName)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+		secretKey, err := s.Get(ctx, secretKeyName)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+		return aws.Credentials{
+			AccessKeyID:     accessKey,
+			SecretAccessKey: secretKey,
+			Source:          "gokit/secrets",
+			CanExpire:       true,
+			Expires:         time.Now().Add(credentialsTTL),
+		}, nil
+	}))
+}
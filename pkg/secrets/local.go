@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Env reads secrets from environment variables, named after the secret in
+// upper case with dashes, dots, and slashes replaced by underscores, so
+// "s3/secret-key" is S3_SECRET_KEY
+type Env struct {
+	prefix string
+}
+
+// NewEnv creates Secrets reading environment variables with an optional
+// prefix, e.g. "APP_"
+func NewEnv(prefix ...string) *Env {
+	e := &Env{}
+	if len(prefix) > 0 {
+		e.prefix = prefix[0]
+	}
+	return e
+}
+
+// Get returns the value of the variable of a secret
+func (e *Env) Get(_ context.Context, name string) (string, error) {
+	variable := e.prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", "/", "_").Replace(name))
+	value, ok := os.LookupEnv(variable)
+	if !ok {
+		return "", notFound(name)
+	}
+	return value, nil
+}
+
+// File reads secrets from files in a directory, such as the /run/secrets of
+// Docker and Kubernetes secret volumes. A trailing newline is removed, and
+// "name#key" reads a key of a file holding a JSON object.
+type File struct {
+	dir string
+}
+
+// NewFile creates Secrets reading the files of a directory, defaulting to
+// /run/secrets
+func NewFile(dir ...string) *File {
+	f := &File{dir: "/run/secrets"}
+	if len(dir) > 0 && dir[0] != "" {
+		f.dir = dir[0]
+	}
+	return f
+}
+
+// Get returns the contents of the file of a secret
+func (f *File) Get(_ context.Context, name string) (string, error) {
+	name, key := splitKey(name)
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("secrets: invalid secret name %q", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(f.dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return "", notFound(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("secrets: read %s: %w", name, err)
+	}
+
+	value := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if key != "" {
+		return field(name, value, key)
+	}
+	return value, nil
+}
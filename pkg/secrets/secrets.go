@@ -0,0 +1,113 @@
+// Package secrets reads secrets from the environment, files, AWS Secrets
+// Manager, or Vault, with caching and callbacks for rotated secrets
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is wrapped by the errors of secrets that do not exist
+var ErrNotFound = errors.New("secrets: not found")
+
+// Secrets reads secrets by name. Stores holding JSON documents, such as AWS
+// Secrets Manager, take "name#key" to read one key of a document.
+type Secrets interface {
+	// Get returns the value of a secret, or an error wrapping ErrNotFound
+	// when it does not exist
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Func adapts a function to Secrets
+type Func func(ctx context.Context, name string) (string, error)
+
+// Get calls f
+func (f Func) Get(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// Chain returns Secrets trying each of secrets in order, so secrets set in
+// the environment can override those of a store:
+//
+//	secrets.Chain(secrets.NewEnv(), vault)
+func Chain(secrets ...Secrets) Secrets {
+	return Func(func(ctx context.Context, name string) (string, error) {
+		for _, s := range secrets {
+			value, err := s.Get(ctx, name)
+			if err == nil || !errors.Is(err, ErrNotFound) {
+				return value, err
+			}
+		}
+		return "", notFound(name)
+	})
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultSecrets Secrets = NewEnv()
+)
+
+// Default returns the default secrets, read from the environment until
+// SetDefault replaces them
+func Default() Secrets {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultSecrets
+}
+
+// SetDefault replaces the default secrets, which gokit packages such as
+// filesystem read their credentials from
+func SetDefault(s Secrets) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultSecrets = s
+}
+
+// Get returns the value of a secret of the default secrets
+func Get(ctx context.Context, name string) (string, error) {
+	return Default().Get(ctx, name)
+}
+
+// notFound returns the error of a missing secret
+func notFound(name string) error {
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// splitKey splits "name#key" into the name of a secret and a key of its
+// JSON document
+func splitKey(name string) (string, string) {
+	if i := strings.LastIndexByte(name, '#'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// field returns a key of a secret holding a JSON object
+func field(name, document, key string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(document), &fields); err != nil {
+		return "", fmt.Errorf("secrets: %s is not a JSON object: %w", name, err)
+	}
+	return fieldValue(name, fields, key)
+}
+
+// fieldValue returns a key of a decoded JSON object as a string, encoding
+// values other than strings as JSON
+func fieldValue(name string, fields map[string]interface{}, key string) (string, error) {
+	value, ok := fields[key]
+	if !ok {
+		return "", notFound(name + "#" + key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("secrets: encode %s#%s: %w", name, key, err)
+	}
+	return string(data), nil
+}
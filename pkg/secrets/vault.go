@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures a HashiCorp Vault KV version 2 secrets engine.
+// Empty fields are read from VAULT_ADDR, VAULT_TOKEN, and VAULT_NAMESPACE.
+type VaultConfig struct {
+	// Address is the URL of Vault, e.g. "https://vault:8200"
+	Address string
+
+	// Token authenticates requests
+	Token string
+
+	// Namespace is the Vault Enterprise namespace, if any
+	Namespace string
+
+	// Mount is the path the KV engine is mounted at, defaulting to "secret"
+	Mount string
+
+	// HTTPClient defaults to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// Vault reads secrets from the KV version 2 engine of Vault. Secrets are
+// "path#key", such as "api/s3#secret_key", reading key "value" when no key
+// is given.
+type Vault struct {
+	config VaultConfig
+}
+
+// NewVault creates Secrets reading from Vault
+func NewVault(config ...VaultConfig) (*Vault, error) {
+	var cfg VaultConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("secrets: Vault address is required, set VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("secrets: Vault token is required, set VAULT_TOKEN")
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	cfg.Mount = strings.Trim(cfg.Mount, "/")
+	return &Vault{config: cfg}, nil
+}
+
+// Get returns a key of the latest version of a secret
+func (v *Vault) Get(ctx context.Context, name string) (string, error) {
+	path, key := splitKey(name)
+	if key == "" {
+		key = "value"
+	}
+
+	endpoint := v.config.Address + "/v1/" + v.config.Mount + "/data/" + escapePath(strings.Trim(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.config.Token)
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	resp, err := v.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets: get %s from Vault: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", notFound(path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return "", fmt.Errorf("secrets: get %s from Vault: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("secrets: decode %s from Vault: %w", path, err)
+	}
+	// Deleted versions have no data
+	if secret.Data.Data == nil {
+		return "", notFound(path)
+	}
+	return fieldValue(path, secret.Data.Data, key)
+}
+
+// escapePath escapes the segments of a secret path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/lifecycle"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultAddr is the address apps listen on by default
+	DefaultAddr = ":3000"
+
+	// DefaultShutdownTimeout is how long apps wait for requests and shutdown
+	// hooks to finish by default
+	DefaultShutdownTimeout = 15 * time.Second
+
+	// DefaultHealthPath serves the report of the health checks by default
+	DefaultHealthPath = "/health"
+
+	// DefaultMetricsPath serves the metrics to Prometheus by default
+	DefaultMetricsPath = "/metrics"
+)
+
+// Config configures an app
+type Config struct {
+	// Name is the name of the service, used as the Fiber app name
+	Name string
+
+	// Addr is the address to listen on, defaulting to DefaultAddr
+	Addr string
+
+	// BodyLimit is the largest request body in bytes, defaulting to Fiber's
+	// 4MB
+	BodyLimit int
+
+	// ShutdownTimeout limits waiting for requests in flight and the other
+	// components of the lifecycle on shutdown
+	ShutdownTimeout time.Duration
+
+	// Lifecycle shuts down the app with the components registered with it,
+	// defaulting to lifecycle.Default(), which job queues, schedulers, and
+	// storage providers register with
+	Lifecycle *lifecycle.Manager
+
+	// HealthPath serves the report of the default health registry, with
+	// HealthPath+"/live" answering while the process is up. "-" leaves the
+	// routes out.
+	HealthPath string
+
+	// MetricsPath serves the default metrics registry. "-" leaves the route
+	// and the metrics middleware out.
+	MetricsPath string
+
+	// AccessLog configures the access log. Requests to the health and
+	// metrics routes are not logged.
+	AccessLog middleware.AccessLogConfig
+
+	// Logger defaults to one configured by the LOG_* environment variables,
+	// see logger.InitLogger. It becomes the default logger.
+	Logger *logger.Logger
+
+	// Fiber is the base configuration of the Fiber app. The app sets the
+	// error handler, name, and body limit when they are not set.
+	Fiber fiber.Config
+}
+
+// Option changes the configuration of an app
+type Option func(cfg *Config)
+
+// ConfigFromEnv reads the configuration of an app from environment
+// variables:
+//
+//	APP_NAME           name of the service
+//	APP_ADDR           address to listen on, or
+//	PORT               port to listen on, e.g. 8080
+//	APP_BODY_LIMIT     largest request body in MB
+//	SHUTDOWN_TIMEOUT   e.g. 30s
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Name: os.Getenv("APP_NAME"),
+		Addr: os.Getenv("APP_ADDR"),
+	}
+	if config.Addr == "" {
+		if port := os.Getenv("PORT"); port != "" {
+			config.Addr = ":" + port
+		}
+	}
+
+	if value := os.Getenv("APP_BODY_LIMIT"); value != "" {
+		mb, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("server: invalid APP_BODY_LIMIT: %w", err)
+		}
+		config.BodyLimit = mb * 1024 * 1024
+	}
+
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("server: invalid SHUTDOWN_TIMEOUT: %w", err)
+		}
+		config.ShutdownTimeout = d
+	}
+	return config, nil
+}
+
+// WithName sets the name of the service
+func WithName(name string) Option {
+	return func(cfg *Config) {
+		cfg.Name = name
+	}
+}
+
+// WithAddr sets the address to listen on
+func WithAddr(addr string) Option {
+	return func(cfg *Config) {
+		cfg.Addr = addr
+	}
+}
+
+// WithLogger sets the logger of the app
+func WithLogger(l *logger.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = l
+	}
+}
+
+// WithBodyLimit sets the largest request body in bytes
+func WithBodyLimit(limit int) Option {
+	return func(cfg *Config) {
+		cfg.BodyLimit = limit
+	}
+}
+
+// WithShutdownTimeout sets how long shutdown waits for requests in flight
+// and the other components of the lifecycle
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.ShutdownTimeout = timeout
+	}
+}
+
+// WithLifecycle sets the lifecycle manager shutting down the app
+func WithLifecycle(m *lifecycle.Manager) Option {
+	return func(cfg *Config) {
+		cfg.Lifecycle = m
+	}
+}
+
+// WithHealthPath sets the path of the health routes, or leaves them out
+// with "-"
+func WithHealthPath(path string) Option {
+	return func(cfg *Config) {
+		cfg.HealthPath = path
+	}
+}
+
+// WithMetricsPath sets the path of the metrics route, or leaves metrics out
+// with "-"
+func WithMetricsPath(path string) Option {
+	return func(cfg *Config) {
+		cfg.MetricsPath = path
+	}
+}
+
+// WithAccessLog configures the access log
+func WithAccessLog(config middleware.AccessLogConfig) Option {
+	return func(cfg *Config) {
+		cfg.AccessLog = config
+	}
+}
+
+// WithFiberConfig sets the base configuration of the Fiber app
+func WithFiberConfig(config fiber.Config) Option {
+	return func(cfg *Config) {
+		cfg.Fiber = config
+	}
+}
+
+// setDefaults fills the unset fields of the configuration
+func (c *Config) setDefaults() {
+	if c.Addr == "" {
+		c.Addr = DefaultAddr
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if c.HealthPath == "" {
+		c.HealthPath = DefaultHealthPath
+	}
+	if c.MetricsPath == "" {
+		c.MetricsPath = DefaultMetricsPath
+	}
+	if c.Logger == nil {
+		c.Logger = logger.InitLogger()
+	}
+	if c.Lifecycle == nil {
+		c.Lifecycle = lifecycle.Default()
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrorHandler is a Fiber error handler sending errors as error responses.
+// Fiber errors, such as 404 for unknown routes, get the standard error codes
+// and messages, and other errors that are not AppErrors are sent as 500
+// without their message, which is only logged.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var appErr *errors.AppError
+	if errors.As(err, &appErr) {
+		return response.Error(c, appErr)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return response.Error(c, fromFiberError(fiberErr))
+	}
+
+	appErr = errors.InternalServerError("")
+	appErr.Internal = err
+	return response.Error(c, appErr)
+}
+
+// fromFiberError converts a Fiber error, using the catalog message for the
+// statuses that have one
+func fromFiberError(err *fiber.Error) *errors.AppError {
+	switch err.Code {
+	case fiber.StatusNotFound:
+		return errors.NotFoundError("")
+	case fiber.StatusMethodNotAllowed:
+		return errors.MethodNotAllowedError("")
+	case fiber.StatusInternalServerError:
+		return errors.InternalServerError("")
+	case fiber.StatusServiceUnavailable:
+		return errors.ServiceUnavailableError("")
+	}
+	return errors.NewError(err.Code, err.Message)
+}
@@ -0,0 +1,210 @@
+// Package server builds Fiber apps wired with the gokit logger, error
+// responses, request IDs, metrics, health routes, and graceful shutdown
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/health"
+	"github.com/anaknegeri/gokit/pkg/lifecycle"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/metrics"
+	"github.com/anaknegeri/gokit/pkg/middleware"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// Module registers routes, e.g. the file routes of a filesystem provider
+type Module interface {
+	Routes(router fiber.Router)
+}
+
+// ModuleFunc adapts a function to a Module
+type ModuleFunc func(router fiber.Router)
+
+// Routes calls f
+func (f ModuleFunc) Routes(router fiber.Router) {
+	f(router)
+}
+
+// App is a Fiber app with the gokit middleware and routes, run until the
+// process is asked to stop
+type App struct {
+	config    Config
+	err       error
+	fiber     *fiber.App
+	log       *logger.Logger
+	lifecycle *lifecycle.Manager
+}
+
+// New creates an app configured by the environment, see ConfigFromEnv, and
+// the options. Requests go through, in order:
+//
+//	request ID   X-Request-ID, generated when the client sends none
+//	metrics      unless MetricsPath is "-"
+//	access log
+//	recover      panics become 500 responses with the request ID, see middleware.Recover
+//
+// Errors are sent as error responses, see ErrorHandler. The health report is
+// served at /health and the metrics at /metrics.
+//
+//	app := server.New(server.WithName("uploads"))
+//	app.Mount(fs)
+//	app.Group("/api").Get("/users/:id", getUser)
+//	if err := app.Run(); err != nil {
+//		log.Fatal(err)
+//	}
+func New(opts ...Option) *App {
+	config, err := ConfigFromEnv()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	config.setDefaults()
+	logger.SetDefault(config.Logger)
+
+	fiberConfig := config.Fiber
+	if fiberConfig.AppName == "" {
+		fiberConfig.AppName = config.Name
+	}
+	if fiberConfig.BodyLimit == 0 {
+		fiberConfig.BodyLimit = config.BodyLimit
+	}
+	if fiberConfig.ErrorHandler == nil {
+		fiberConfig.ErrorHandler = ErrorHandler
+	}
+	fiberConfig.DisableStartupMessage = true
+
+	a := &App{
+		config:    config,
+		err:       err,
+		fiber:     fiber.New(fiberConfig),
+		log:       config.Logger,
+		lifecycle: config.Lifecycle,
+	}
+	a.setup()
+	return a
+}
+
+// setup adds the middleware and the health and metrics routes
+func (a *App) setup() {
+	var skipPaths []string
+	if path := a.config.HealthPath; path != "-" {
+		skipPaths = append(skipPaths, path, path+"/live")
+	}
+	if path := a.config.MetricsPath; path != "-" {
+		skipPaths = append(skipPaths, path)
+	}
+
+	a.fiber.Use(requestid.New())
+	if a.config.MetricsPath != "-" {
+		a.fiber.Use(metrics.Middleware(metrics.MiddlewareConfig{SkipPaths: skipPaths}))
+	}
+	accessLog := a.config.AccessLog
+	accessLog.SkipPaths = append(accessLog.SkipPaths, skipPaths...)
+	a.fiber.Use(middleware.AccessLog(a.log, accessLog))
+	a.fiber.Use(middleware.Recover(a.log))
+
+	if path := a.config.HealthPath; path != "-" {
+		a.fiber.Get(path, health.Handler())
+		a.fiber.Get(path+"/live", func(c *fiber.Ctx) error {
+			return response.Success(c, "Service is alive", nil)
+		})
+	}
+	if path := a.config.MetricsPath; path != "-" {
+		a.fiber.Get(path, metrics.Handler())
+	}
+}
+
+// Fiber returns the Fiber app, e.g. to add routes
+func (a *App) Fiber() *fiber.App {
+	return a.fiber
+}
+
+// Logger returns the logger of the app
+func (a *App) Logger() *logger.Logger {
+	return a.log
+}
+
+// Use adds middleware to the app, see fiber.App.Use
+func (a *App) Use(args ...interface{}) fiber.Router {
+	return a.fiber.Use(args...)
+}
+
+// Group returns a router for routes with a common prefix and middleware
+func (a *App) Group(prefix string, handlers ...fiber.Handler) fiber.Router {
+	return a.fiber.Group(prefix, handlers...)
+}
+
+// Mount registers the routes of modules on the app
+func (a *App) Mount(modules ...Module) *App {
+	for _, module := range modules {
+		module.Routes(a.fiber)
+	}
+	return a
+}
+
+// Register adds a component to shut down with the app, after the HTTP
+// server, see lifecycle.Manager.Register
+func (a *App) Register(name string, closer lifecycle.Shutdowner, timeout ...time.Duration) *App {
+	a.lifecycle.Register(name, closer, timeout...)
+	return a
+}
+
+// OnShutdown registers a function called on shutdown once the requests in
+// flight are done. Like other components, functions are called in reverse
+// order of registration, so one closing the database is registered before
+// the job queue using it starts.
+func (a *App) OnShutdown(fn func(ctx context.Context) error) *App {
+	return a.Register("shutdown hook", lifecycle.Func(fn))
+}
+
+// Run listens on the configured address until the process receives SIGINT
+// or SIGTERM, then shuts down within ShutdownTimeout: the HTTP server stops
+// accepting requests and waits for those in flight, and then the other
+// components of the lifecycle stop in reverse order of registration. It
+// returns nil after a clean shutdown.
+func (a *App) Run() error {
+	if a.err != nil {
+		return a.err
+	}
+
+	// Registered last, the server stops first
+	a.lifecycle.Register("http server", lifecycle.Func(a.fiber.ShutdownWithContext))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- a.fiber.Listen(a.config.Addr)
+	}()
+	a.log.Infof("Listening on %s", a.config.Addr)
+
+	var runErr error
+	select {
+	case err := <-listenErr:
+		runErr = fmt.Errorf("server: listen on %s: %w", a.config.Addr, err)
+	case <-ctx.Done():
+		a.log.Infof("Shutting down")
+	case <-a.lifecycle.Stopping():
+	}
+	// A second signal stops the process at once
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+	return errors.Join(runErr, a.Shutdown(shutdownCtx))
+}
+
+// Shutdown shuts down the lifecycle of the app, stopping the HTTP server
+// and then the other components, until ctx is done
+func (a *App) Shutdown(ctx context.Context) error {
+	return a.lifecycle.Shutdown(ctx)
+}
@@ -0,0 +1,194 @@
+// Package tenant resolves the tenant of requests, from a header, the
+// subdomain, or a token claim, and carries it in their context, e.g. to
+// scope storage to the tenant
+package tenant
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/auth"
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultHeader is the header the tenant is read from by Header
+	DefaultHeader = "X-Tenant-ID"
+
+	// DefaultClaim is the token claim holding the tenant
+	DefaultClaim = "tenant"
+)
+
+// localKey is the local holding the tenant. Handlers that use c.Context()
+// as their context see locals with string keys, but not the user context.
+const localKey = "gokit.tenant"
+
+// validID matches tenant IDs, which are used as path segments
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx that carries a tenant ID
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant ID stored in ctx by WithTenant or the
+// middleware, if any
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	if id, ok := ctx.Value(tenantKey{}).(string); ok && id != "" {
+		return id, true
+	}
+	if id, ok := ctx.Value(localKey).(string); ok && id != "" {
+		return id, true
+	}
+	return "", false
+}
+
+// Current returns the tenant ID of a request resolved by the middleware
+func Current(c *fiber.Ctx) (string, bool) {
+	id, ok := c.Locals(localKey).(string)
+	return id, ok && id != ""
+}
+
+// ValidID reports whether a tenant ID is 1 to 64 letters, digits, dashes,
+// and underscores, starting with a letter or digit
+func ValidID(id string) bool {
+	return validID.MatchString(id)
+}
+
+// Resolver returns the tenant ID of a request, or "" when the request names
+// none
+type Resolver func(c *fiber.Ctx) string
+
+// Header resolves the tenant from a header, defaulting to DefaultHeader
+func Header(name ...string) Resolver {
+	header := DefaultHeader
+	if len(name) > 0 && name[0] != "" {
+		header = name[0]
+	}
+	return func(c *fiber.Ctx) string {
+		return strings.TrimSpace(c.Get(header))
+	}
+}
+
+// Subdomain resolves the tenant from the subdomain of a domain, e.g. "acme"
+// for acme.example.com with the domain "example.com"
+func Subdomain(domain string) Resolver {
+	suffix := "." + strings.ToLower(strings.TrimPrefix(domain, "."))
+	return func(c *fiber.Ctx) string {
+		host := strings.ToLower(c.Hostname())
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		sub, ok := strings.CutSuffix(host, suffix)
+		if !ok || strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// Claim resolves the tenant from a claim of the token verified by the auth
+// middleware, defaulting to DefaultClaim
+func Claim(name ...string) Resolver {
+	claim := DefaultClaim
+	if len(name) > 0 && name[0] != "" {
+		claim = name[0]
+	}
+	return func(c *fiber.Ctx) string {
+		claims, ok := auth.CurrentClaims(c)
+		if !ok {
+			return ""
+		}
+		id, _ := claims.Extra[claim].(string)
+		return id
+	}
+}
+
+// First resolves the tenant with the first resolver that finds one
+func First(resolvers ...Resolver) Resolver {
+	return func(c *fiber.Ctx) string {
+		for _, resolve := range resolvers {
+			if id := resolve(c); id != "" {
+				return id
+			}
+		}
+		return ""
+	}
+}
+
+// Config configures the tenant middleware
+type Config struct {
+	// Resolver finds the tenant of a request, defaulting to the token claim,
+	// and then the X-Tenant-ID header when TrustHeader is set
+	Resolver Resolver
+
+	// TrustHeader lets the default resolver read the tenant from the
+	// X-Tenant-ID header of requests whose token has no tenant claim,
+	// including requests without a token. Clients choose the header, so set
+	// it only when a gateway in front of the service sets or strips it.
+	TrustHeader bool
+
+	// Claim is the token claim that, when present, the resolved tenant must
+	// match, so users cannot reach other tenants by changing the header or
+	// subdomain. Defaults to DefaultClaim.
+	Claim string
+
+	// Optional lets requests without a tenant through
+	Optional bool
+}
+
+// Middleware resolves the tenant of each request and stores it in the
+// request context, for Current, FromContext, and TenantStorage. Requests
+// without a tenant are answered with 400, unless Optional is set, and
+// requests for another tenant than the one of their token with 403.
+//
+//	app.Use(auth.Middleware(), tenant.Middleware(tenant.Config{
+//		Resolver: tenant.Subdomain("example.com"),
+//	}))
+func Middleware(config ...Config) fiber.Handler {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Claim == "" {
+		cfg.Claim = DefaultClaim
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = Claim(cfg.Claim)
+		if cfg.TrustHeader {
+			cfg.Resolver = First(Claim(cfg.Claim), Header())
+		}
+	}
+	claim := Claim(cfg.Claim)
+
+	return func(c *fiber.Ctx) error {
+		id := cfg.Resolver(c)
+		if id == "" {
+			if cfg.Optional {
+				return c.Next()
+			}
+			return response.Error(c, errors.BadRequestError("Tenant is required"))
+		}
+		if !ValidID(id) {
+			return response.Error(c, errors.BadRequestError("Invalid tenant"))
+		}
+		if own := claim(c); own != "" && own != id {
+			return response.Error(c, errors.ForbiddenError(""))
+		}
+
+		// Keep an immutable copy, since Fiber reuses request memory
+		id = strings.Clone(id)
+		c.Locals(localKey, id)
+		c.SetUserContext(WithTenant(c.UserContext(), id))
+		return c.Next()
+	}
+}
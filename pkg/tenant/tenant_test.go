@@ -0,0 +1,93 @@
+package tenant
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anaknegeri/gokit/pkg/auth"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMiddleware(t *testing.T) {
+	tokens, err := auth.New(auth.Config{Secret: []byte("0123456789abcdef0123456789abcdef")})
+	if err != nil {
+		t.Fatalf("Failed to create token manager: %v", err)
+	}
+	issue := func(tenant string) string {
+		t.Helper()
+		claims := auth.Claims{Subject: "42"}
+		if tenant != "" {
+			claims.Set(DefaultClaim, tenant)
+		}
+		token, err := tokens.Issue(claims)
+		if err != nil {
+			t.Fatalf("Failed to issue token: %v", err)
+		}
+		return token
+	}
+	acme, noTenant := issue("acme"), issue("")
+
+	newApp := func(config Config) *fiber.App {
+		app := fiber.New()
+		app.Use(tokens.Middleware(auth.MiddlewareConfig{Optional: true}), Middleware(config))
+		app.Get("/", func(c *fiber.Ctx) error {
+			id, _ := FromContext(c.UserContext())
+			return c.SendString(id)
+		})
+		return app
+	}
+	defaults := newApp(Config{})
+	trusted := newApp(Config{TrustHeader: true})
+	optional := newApp(Config{Optional: true})
+	headers := newApp(Config{Resolver: Header()})
+
+	tests := []struct {
+		name   string
+		app    *fiber.App
+		token  string
+		header string
+		status int
+		tenant string
+	}{
+		{"claim", defaults, acme, "", http.StatusOK, "acme"},
+		{"claim and same header", defaults, acme, "acme", http.StatusOK, "acme"},
+		{"header without a token", defaults, "", "globex", http.StatusBadRequest, ""},
+		{"header without a claim", defaults, noTenant, "globex", http.StatusBadRequest, ""},
+		{"no tenant", defaults, "", "", http.StatusBadRequest, ""},
+		{"trusted header without a token", trusted, "", "globex", http.StatusOK, "globex"},
+		{"trusted header without a claim", trusted, noTenant, "globex", http.StatusOK, "globex"},
+		{"claim over trusted header", trusted, acme, "globex", http.StatusOK, "acme"},
+		{"header resolver for another tenant", headers, acme, "globex", http.StatusForbidden, ""},
+		{"header resolver for the same tenant", headers, acme, "acme", http.StatusOK, "acme"},
+		{"invalid trusted header", trusted, "", "../globex", http.StatusBadRequest, ""},
+		{"optional", optional, "", "globex", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.header != "" {
+				req.Header.Set(DefaultHeader, tt.header)
+			}
+			resp, err := tt.app.Test(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if got := string(body); got != tt.tenant {
+				t.Errorf("Expected tenant %q, got %q", tt.tenant, got)
+			}
+		})
+	}
+}
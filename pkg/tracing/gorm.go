@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "gokit:tracing_span"
+
+// GormPlugin returns a GORM plugin starting a client span for each query,
+// named after the operation and table, such as "SELECT users", with the SQL
+// and its placeholders but not the values. database.Open installs it; add it
+// to connections opened otherwise with db.Use(tracing.GormPlugin()). Queries
+// are children of the span in the context passed with db.WithContext.
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+// Name returns the name of the plugin
+func (gormPlugin) Name() string {
+	return "gokit:tracing"
+}
+
+// Initialize registers callbacks starting a span before each operation and
+// ending it after
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	processors := []struct {
+		name      string
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", "INSERT", callback.Create().Before("gorm:create").Register, callback.Create().After("gorm:create").Register},
+		{"query", "SELECT", callback.Query().Before("gorm:query").Register, callback.Query().After("gorm:query").Register},
+		{"update", "UPDATE", callback.Update().Before("gorm:update").Register, callback.Update().After("gorm:update").Register},
+		{"delete", "DELETE", callback.Delete().Before("gorm:delete").Register, callback.Delete().After("gorm:delete").Register},
+		{"row", "", callback.Row().Before("gorm:row").Register, callback.Row().After("gorm:row").Register},
+		{"raw", "", callback.Raw().Before("gorm:raw").Register, callback.Raw().After("gorm:raw").Register},
+	}
+	for _, p := range processors {
+		if err := p.before("gokit:tracing_before_"+p.name, startQuerySpan(p.operation)); err != nil {
+			return err
+		}
+		if err := p.after("gokit:tracing_after_"+p.name, endQuerySpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startQuerySpan returns a callback starting the span of an operation. Row
+// and raw queries, whose operation is not known, are named "SQL".
+func startQuerySpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		name := operation
+		if name == "" {
+			name = "SQL"
+		}
+		if table := db.Statement.Table; table != "" {
+			name += " " + table
+		}
+
+		ctx, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemKey.String(db.Dialector.Name())),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// endQuerySpan ends the span of an operation with its SQL and outcome
+func endQuerySpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	if span.IsRecording() {
+		span.SetAttributes(
+			semconv.DBQueryText(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		if table := db.Statement.Table; table != "" {
+			span.SetAttributes(semconv.DBCollectionName(table))
+		}
+	}
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MiddlewareConfig configures the tracing middleware
+type MiddlewareConfig struct {
+	// SkipPaths are request paths that are not traced, e.g. "/health"
+	SkipPaths []string
+
+	// Skip reports whether a request should not be traced
+	Skip func(c *fiber.Ctx) bool
+}
+
+// Middleware returns a middleware starting a server span for each request,
+// continuing the trace of the caller from the traceparent header. The span
+// is named after the method and route, such as "GET /users/:id", and is
+// stored in the user context so the spans of handlers, queries, and jobs
+// enqueued by them are its children and logs carry its trace ID. Errors
+// returned by later handlers are passed to the app error handler so the
+// recorded status matches the response; 5xx responses mark the span as
+// failed.
+func Middleware(config ...MiddlewareConfig) fiber.Handler {
+	cfg := MiddlewareConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	skipPaths := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skipPaths[path] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		if skipPaths[c.Path()] || (cfg.Skip != nil && cfg.Skip(c)) {
+			return c.Next()
+		}
+
+		// Fiber strings are reused after the request, so attributes keep copies
+		method := strings.Clone(c.Method())
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		ctx, span := Tracer().Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(strings.Clone(c.Path())),
+				semconv.URLScheme(strings.Clone(c.Protocol())),
+				semconv.ServerAddress(strings.Clone(c.Hostname())),
+				semconv.ClientAddress(strings.Clone(c.IP())),
+				semconv.UserAgentOriginal(strings.Clone(c.Get(fiber.HeaderUserAgent))),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		chainErr := c.Next()
+
+		// Let the error handler write the response so the recorded status is the one sent
+		if chainErr != nil {
+			if err := c.App().ErrorHandler(c, chainErr); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		if !matchedNoRoute(c, chainErr) {
+			route := c.Route().Path
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			if chainErr != nil {
+				span.RecordError(chainErr)
+			}
+			span.SetStatus(codes.Error, "")
+		}
+		return nil
+	}
+}
+
+// matchedNoRoute reports whether an error is one Fiber returns for requests
+// that fell through every route, whose route is then that of a middleware
+func matchedNoRoute(c *fiber.Ctx, err error) bool {
+	if err == fiber.ErrMethodNotAllowed {
+		return true
+	}
+	var fiberErr *fiber.Error
+	return errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusNotFound &&
+		strings.HasPrefix(fiberErr.Message, "Cannot "+c.Method()+" ")
+}
+
+// headerCarrier reads the trace context from the request headers
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, h.c.Request().Header.Len())
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
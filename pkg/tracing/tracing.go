@@ -0,0 +1,229 @@
+// Package tracing sets up OpenTelemetry tracing from configuration or the
+// environment, with a Fiber middleware creating server spans and helpers to
+// start child spans around storage, database, and job operations
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporters
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// Config configures tracing
+type Config struct {
+	// ServiceName names the service in traces. It defaults to the
+	// service.name resource attribute, or "unknown_service:<executable>".
+	ServiceName string
+
+	// ServiceVersion is recorded as service.version when set
+	ServiceVersion string
+
+	// Environment is recorded as deployment.environment when set, e.g.
+	// "production"
+	Environment string
+
+	// Exporter is "otlp" (default) to send spans to a collector over
+	// OTLP/HTTP, "stdout" to print them, or "none" to only propagate trace
+	// context
+	Exporter string
+
+	// Endpoint is the URL of the OTLP collector, e.g. "http://collector:4318".
+	// It defaults to the OTEL_EXPORTER_OTLP_* variables read by the exporter,
+	// or http://localhost:4318.
+	Endpoint string
+
+	// Headers are sent with every export, e.g. an API key of a hosted backend
+	Headers map[string]string
+
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1.
+	// Requests continuing a trace follow the sampling decision of the
+	// caller. Zero uses the OTEL_TRACES_SAMPLER variables, recording every
+	// trace by default.
+	SampleRatio float64
+
+	// Attributes are added to the resource of every span. The
+	// OTEL_RESOURCE_ATTRIBUTES variable overrides them.
+	Attributes map[string]string
+
+	// Writer is where the stdout exporter prints, defaulting to os.Stdout
+	Writer io.Writer
+}
+
+// ConfigFromEnv loads configuration from the standard OpenTelemetry
+// environment variables: OTEL_SERVICE_NAME, OTEL_TRACES_EXPORTER ("otlp",
+// "console", or "none"), OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, and
+// OTEL_TRACES_SAMPLER_ARG when OTEL_TRACES_SAMPLER is a ratio sampler.
+// OTEL_SDK_DISABLED=true turns exporting off. The exporter and resource read
+// the remaining variables, such as OTEL_EXPORTER_OTLP_HEADERS and
+// OTEL_RESOURCE_ATTRIBUTES, themselves.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+	}
+	if config.Exporter == "console" {
+		config.Exporter = ExporterStdout
+	}
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		config.Exporter = ExporterNone
+	}
+
+	switch os.Getenv("OTEL_TRACES_SAMPLER") {
+	case "traceidratio", "parentbased_traceidratio":
+		if value := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); value != "" {
+			ratio, err := strconv.ParseFloat(value, 64)
+			if err != nil || ratio < 0 || ratio > 1 {
+				return config, fmt.Errorf("tracing: invalid OTEL_TRACES_SAMPLER_ARG %q", value)
+			}
+			config.SampleRatio = ratio
+		}
+	}
+	return config, nil
+}
+
+// Init sets up the global tracer provider and the W3C trace context and
+// baggage propagators, loading the configuration with ConfigFromEnv when
+// none is given. Call the returned function on shutdown to flush the spans
+// not yet exported:
+//
+//	shutdown, err := tracing.Init(ctx, tracing.Config{ServiceName: "api"})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer shutdown(context.Background())
+func Init(ctx context.Context, config ...Config) (func(context.Context) error, error) {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	} else {
+		var err error
+		if cfg, err = ConfigFromEnv(); err != nil {
+			return nil, err
+		}
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Default().Warnf("Tracing: %v", err)
+	}))
+
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if cfg.SampleRatio > 0 {
+		options = append(options, sdktrace.WithSampler(
+			sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio)),
+		))
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the exporter of a config, or nil for "none"
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(cfg.Exporter) {
+	case "", ExporterOTLP:
+		var options []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			u, err := url.Parse(cfg.Endpoint)
+			if err != nil || u.Host == "" {
+				return nil, fmt.Errorf("tracing: invalid endpoint %q, expected a URL such as http://collector:4318", cfg.Endpoint)
+			}
+			if u.Path == "" || u.Path == "/" {
+				u.Path = "/v1/traces"
+			}
+			options = append(options, otlptracehttp.WithEndpointURL(u.String()))
+		}
+		if len(cfg.Headers) > 0 {
+			options = append(options, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		exporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: create OTLP exporter: %w", err)
+		}
+		return exporter, nil
+
+	case ExporterStdout, "console":
+		w := cfg.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, fmt.Errorf("tracing: create stdout exporter: %w", err)
+		}
+		return exporter, nil
+
+	case ExporterNone:
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+}
+
+// newResource describes the service, with the environment variables taking
+// precedence over the config
+func newResource(ctx context.Context, cfg Config) (*resource.Resource, error) {
+	var attrs []attribute.KeyValue
+	if cfg.ServiceName != "" {
+		attrs = append(attrs, semconv.ServiceName(cfg.ServiceName))
+	}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.ServiceVersion))
+	}
+	if cfg.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(cfg.Environment))
+	}
+	for key, value := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create resource: %w", err)
+	}
+	return resource.Merge(resource.Default(), res)
+}
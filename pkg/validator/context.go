@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// contextKey is the type of the context keys of this package
+type contextKey int
+
+const (
+	rolesKey contextKey = iota
+	flagsKey
+)
+
+// ContextWithRoles returns a context carrying the roles of the current user,
+// which the role validations check when validating with StructCtx
+func ContextWithRoles(ctx context.Context, roles ...string) context.Context {
+	return context.WithValue(ctx, rolesKey, roles)
+}
+
+// ContextWithFlags returns a context carrying the enabled feature flags,
+// which the flag validations check when validating with StructCtx
+func ContextWithFlags(ctx context.Context, flags ...string) context.Context {
+	return context.WithValue(ctx, flagsKey, flags)
+}
+
+// HasRole reports whether the context carries one of the roles
+func HasRole(ctx context.Context, roles ...string) bool {
+	return containsAny(ctx, rolesKey, roles)
+}
+
+// HasFlag reports whether the context carries one of the feature flags
+func HasFlag(ctx context.Context, flags ...string) bool {
+	return containsAny(ctx, flagsKey, flags)
+}
+
+// containsAny reports whether the values stored under key include one of wanted
+func containsAny(ctx context.Context, key contextKey, wanted []string) bool {
+	values, _ := ctx.Value(key).([]string)
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// registerContextValidations registers the validations that depend on the
+// context passed to StructCtx. Their parameters are space-separated lists:
+//
+//   - required_if_role=admin editor: required when the user has one of the roles
+//   - excluded_unless_role=admin: must be empty unless the user has one of the roles
+//   - required_if_flag=beta: required when one of the feature flags is enabled
+//   - excluded_unless_flag=beta: must be empty unless one of the feature flags is enabled
+//
+// Without roles or flags in the context, as with Struct, fields are never
+// required and must be empty.
+func registerContextValidations(v *validator.Validate) {
+	rules := map[string]func(ctx context.Context, fl validator.FieldLevel) bool{
+		"required_if_role": func(ctx context.Context, fl validator.FieldLevel) bool {
+			return !HasRole(ctx, strings.Fields(fl.Param())...) || hasValue(fl.Field())
+		},
+		"excluded_unless_role": func(ctx context.Context, fl validator.FieldLevel) bool {
+			return HasRole(ctx, strings.Fields(fl.Param())...) || !hasValue(fl.Field())
+		},
+		"required_if_flag": func(ctx context.Context, fl validator.FieldLevel) bool {
+			return !HasFlag(ctx, strings.Fields(fl.Param())...) || hasValue(fl.Field())
+		},
+		"excluded_unless_flag": func(ctx context.Context, fl validator.FieldLevel) bool {
+			return HasFlag(ctx, strings.Fields(fl.Param())...) || !hasValue(fl.Field())
+		},
+	}
+	for tag, fn := range rules {
+		// Run the rules on nil fields too, which must fail when they are required
+		if err := v.RegisterValidationCtx(tag, fn, true); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// hasValue reports whether a field is set, as the required validation does
+func hasValue(field reflect.Value) bool {
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface, reflect.Chan, reflect.Func:
+		return !field.IsNil()
+	case reflect.Invalid:
+		return false
+	default:
+		return !field.IsZero()
+	}
+}
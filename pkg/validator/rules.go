@@ -0,0 +1,206 @@
+package validator
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// PasswordPolicy configures the strong_password validation
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters
+	MinLength int
+
+	// RequireUpper requires an upper-case letter
+	RequireUpper bool
+
+	// RequireLower requires a lower-case letter
+	RequireLower bool
+
+	// RequireDigit requires a digit
+	RequireDigit bool
+
+	// RequireSymbol requires a character that is not a letter, digit, or space
+	RequireSymbol bool
+}
+
+// DefaultPasswordPolicy requires 8 characters with upper-case and lower-case
+// letters, a digit, and a symbol
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:     8,
+	RequireUpper:  true,
+	RequireLower:  true,
+	RequireDigit:  true,
+	RequireSymbol: true,
+}
+
+// Option configures a validator created by NewValidator
+type Option func(v *validator.Validate)
+
+// WithDefaults registers the built-in validations:
+//
+//   - strong_password: a password that meets DefaultPasswordPolicy
+//   - e164_id: an Indonesian phone number in E.164 format, e.g. +6281234567890
+//   - nik: a 16-digit Indonesian national identity number (Nomor Induk Kependudukan)
+//   - npwp: an Indonesian tax number, either 15 or 16 digits or formatted as 01.234.567.8-901.234
+//   - slug: lower-case letters and digits separated by single hyphens
+//   - no_html: text without HTML tags or comments
+//   - safe_filename: a file name without paths, control or reserved characters
+func WithDefaults() Option {
+	return func(v *validator.Validate) {
+		mustRegister(v, "strong_password", stringValidation(DefaultPasswordPolicy.Check))
+		mustRegister(v, "e164_id", stringValidation(IsIndonesianPhone))
+		mustRegister(v, "nik", stringValidation(IsNIK))
+		mustRegister(v, "npwp", stringValidation(IsNPWP))
+		mustRegister(v, "slug", stringValidation(IsSlug))
+		mustRegister(v, "no_html", stringValidation(func(s string) bool { return !ContainsHTML(s) }))
+		mustRegister(v, "safe_filename", stringValidation(IsSafeFilename))
+	}
+}
+
+// WithPasswordPolicy registers strong_password with a custom policy. Pass it
+// after WithDefaults to replace the default policy.
+func WithPasswordPolicy(policy PasswordPolicy) Option {
+	return func(v *validator.Validate) {
+		mustRegister(v, "strong_password", stringValidation(policy.Check))
+	}
+}
+
+// mustRegister registers a built-in validation, which only fails for an
+// empty tag or a nil function
+func mustRegister(v *validator.Validate, tag string, fn validator.Func) {
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		panic(err)
+	}
+}
+
+// stringValidation adapts a string check to a validation that fails for
+// fields that are not strings
+func stringValidation(check func(string) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		field := fl.Field()
+		if field.Kind() != reflect.String {
+			return false
+		}
+		return check(field.String())
+	}
+}
+
+// Check reports whether a password meets the policy
+func (p PasswordPolicy) Check(password string) bool {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return false
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	return (upper || !p.RequireUpper) &&
+		(lower || !p.RequireLower) &&
+		(digit || !p.RequireDigit) &&
+		(symbol || !p.RequireSymbol)
+}
+
+var (
+	indonesianPhoneRegex = regexp.MustCompile(`^\+62[1-9][0-9]{7,11}$`)
+	nikRegex             = regexp.MustCompile(`^[1-9][0-9]{15}$`)
+	npwpRegex            = regexp.MustCompile(`^[0-9]{2}\.[0-9]{3}\.[0-9]{3}\.[0-9]-[0-9]{3}\.[0-9]{3}$`)
+	slugRegex            = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	htmlRegex            = regexp.MustCompile(`<[a-zA-Z/!?]`)
+)
+
+// IsIndonesianPhone reports whether s is an Indonesian phone number in E.164
+// format: +62 followed by 8 to 12 digits without the leading zero
+func IsIndonesianPhone(s string) bool {
+	return indonesianPhoneRegex.MatchString(s)
+}
+
+// IsNIK reports whether s is a valid NIK: 16 digits where digits 7 to 12 are
+// the date of birth as DDMMYY, with 40 added to the day for women
+func IsNIK(s string) bool {
+	if !nikRegex.MatchString(s) {
+		return false
+	}
+
+	day, _ := strconv.Atoi(s[6:8])
+	if day > 40 {
+		day -= 40
+	}
+	month, _ := strconv.Atoi(s[8:10])
+
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12 && s[12:] != "0000"
+}
+
+// IsNPWP reports whether s is a valid NPWP: 15 digits, formatted or not, or
+// the 16-digit NPWP used since 2024
+func IsNPWP(s string) bool {
+	if strings.ContainsAny(s, ".-") {
+		return npwpRegex.MatchString(s)
+	}
+	if len(s) != 15 && len(s) != 16 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSlug reports whether s is a URL slug such as "hello-world-2"
+func IsSlug(s string) bool {
+	return slugRegex.MatchString(s)
+}
+
+// ContainsHTML reports whether s contains an HTML tag, closing tag, comment,
+// or processing instruction
+func ContainsHTML(s string) bool {
+	return htmlRegex.MatchString(s)
+}
+
+// reservedFilenames are device names Windows does not allow as file names
+var reservedFilenames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// IsSafeFilename reports whether s can be used as a file name on any platform:
+// at most 255 bytes, not "." or "..", without path separators, control
+// characters, or the characters <>:"|?*, not a reserved Windows device name,
+// and not ending with a dot or space
+func IsSafeFilename(s string) bool {
+	if s == "" || s == "." || s == ".." || len(s) > 255 || !utf8.ValidString(s) {
+		return false
+	}
+	if strings.ContainsAny(s, `/\<>:"|?*`) || strings.HasSuffix(s, ".") || strings.HasSuffix(s, " ") {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	base, _, _ := strings.Cut(s, ".")
+	return !reservedFilenames[strings.ToUpper(base)]
+}
@@ -0,0 +1,245 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ModTag is the struct tag holding the modifiers applied by Sanitize
+const ModTag = "mod"
+
+// Modifier normalizes a string value; param is the text after "=" in the tag
+type Modifier func(value string, param string) string
+
+// Sanitizer normalizes struct fields with the modifiers in their mod tags,
+// applied from left to right before validation:
+//
+//	type SignupRequest struct {
+//		Name  string `json:"name" mod:"trim,collapse" validate:"required"`
+//		Email string `json:"email" mod:"email" validate:"required,email"`
+//		Bio   string `json:"bio" mod:"strip_html,trim"`
+//		Role  string `json:"role" mod:"default=member"`
+//	}
+//
+// The built-in modifiers are:
+//
+//   - trim, ltrim, rtrim: remove surrounding white space
+//   - lcase, ucase, title: change the letter case
+//   - collapse: replace runs of white space with a single space
+//   - strip_html: remove HTML tags
+//   - email: trim and lower-case an email address
+//   - default=value: set empty fields to value; also works for numbers and booleans
+//
+// Modifiers apply to string, *string, and []string fields. Nested structs,
+// pointers to structs, and slices of structs are sanitized as well.
+type Sanitizer struct {
+	mu        sync.RWMutex
+	modifiers map[string]Modifier
+}
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// NewSanitizer creates a sanitizer with the built-in modifiers
+func NewSanitizer() *Sanitizer {
+	return &Sanitizer{
+		modifiers: map[string]Modifier{
+			"trim":  func(s, _ string) string { return strings.TrimSpace(s) },
+			"ltrim": func(s, _ string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) },
+			"rtrim": func(s, _ string) string { return strings.TrimRightFunc(s, unicode.IsSpace) },
+			"lcase": func(s, _ string) string { return strings.ToLower(s) },
+			"ucase": func(s, _ string) string { return strings.ToUpper(s) },
+			"title": func(s, _ string) string { return titleCase(s) },
+			"collapse": func(s, _ string) string {
+				return strings.Join(strings.Fields(s), " ")
+			},
+			"strip_html": func(s, _ string) string { return htmlTagRegex.ReplaceAllString(s, "") },
+			"email":      func(s, _ string) string { return strings.ToLower(strings.TrimSpace(s)) },
+		},
+	}
+}
+
+// RegisterModifier adds or replaces a modifier
+func (s *Sanitizer) RegisterModifier(name string, fn Modifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modifiers[name] = fn
+}
+
+// defaultSanitizer is used by Sanitize
+var defaultSanitizer = NewSanitizer()
+
+// Sanitize applies the mod tags of a struct with the built-in modifiers and
+// those added with RegisterModifier
+func Sanitize(v interface{}) error {
+	return defaultSanitizer.Struct(v)
+}
+
+// RegisterModifier adds or replaces a modifier used by Sanitize
+func RegisterModifier(name string, fn Modifier) {
+	defaultSanitizer.RegisterModifier(name, fn)
+}
+
+// Struct applies the mod tags of the struct v points to
+func (s *Sanitizer) Struct(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("validator: cannot sanitize %T, need a pointer to a struct", v)
+	}
+	return s.sanitizeStruct(value.Elem())
+}
+
+// sanitizeStruct applies the mod tags of a struct's fields and sanitizes the
+// structs it contains
+func (s *Sanitizer) sanitizeStruct(value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldValue := value.Field(i)
+
+		if tag := field.Tag.Get(ModTag); tag != "" && tag != "-" {
+			if err := s.modify(fieldValue, tag); err != nil {
+				return fmt.Errorf("validator: field %s: %w", field.Name, err)
+			}
+		}
+		if err := s.sanitizeNested(fieldValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sanitizeNested sanitizes the structs held by a field
+func (s *Sanitizer) sanitizeNested(value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			return s.sanitizeNested(value.Elem())
+		}
+	case reflect.Struct:
+		return s.sanitizeStruct(value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := s.sanitizeNested(value.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// modify applies the modifiers of a tag to a field
+func (s *Sanitizer) modify(value reflect.Value, tag string) error {
+	for _, mod := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(mod), "=")
+		if name == "" {
+			continue
+		}
+		if name == "default" {
+			if err := setDefault(value, param); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s.mu.RLock()
+		fn, ok := s.modifiers[name]
+		s.mu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown modifier %q", name)
+		}
+		if err := modifyStrings(value, func(v string) string { return fn(v, param) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modifyStrings applies fn to a string, *string, or []string value
+func modifyStrings(value reflect.Value, fn func(string) string) error {
+	switch {
+	case value.Kind() == reflect.String:
+		value.SetString(fn(value.String()))
+	case value.Kind() == reflect.Ptr && value.Type().Elem().Kind() == reflect.String:
+		if !value.IsNil() {
+			value.Elem().SetString(fn(value.Elem().String()))
+		}
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < value.Len(); i++ {
+			value.Index(i).SetString(fn(value.Index(i).String()))
+		}
+	default:
+		return fmt.Errorf("modifiers need a string field, got %s", value.Type())
+	}
+	return nil
+}
+
+// setDefault sets an empty field to a default value parsed for its type
+func setDefault(value reflect.Value, param string) error {
+	if value.Kind() == reflect.Ptr {
+		if !value.IsNil() {
+			return nil
+		}
+		value.Set(reflect.New(value.Type().Elem()))
+		value = value.Elem()
+	} else if !value.IsZero() {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(param)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(param, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %w", param, err)
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(param, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %w", param, err)
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(param, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %w", param, err)
+		}
+		value.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(param)
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %w", param, err)
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("cannot set a default for %s", value.Type())
+	}
+	return nil
+}
+
+// titleCase upper-cases the first letter of every word and lower-cases the rest
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			start = true
+			continue
+		}
+		if start {
+			runes[i] = unicode.ToUpper(r)
+		} else {
+			runes[i] = unicode.ToLower(r)
+		}
+		start = false
+	}
+	return string(runes)
+}
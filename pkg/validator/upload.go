@@ -0,0 +1,347 @@
+package validator
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // Register the GIF decoder for image dimensions
+	_ "image/jpeg" // Register the JPEG decoder for image dimensions
+	_ "image/png"  // Register the PNG decoder for image dimensions
+	"mime/multipart"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// UploadTag is the struct tag holding the rules for uploaded files
+const UploadTag = "upload"
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// FileValidator validates uploaded files against rules in upload struct tags
+// on *multipart.FileHeader and []*multipart.FileHeader fields:
+//
+//	type AvatarForm struct {
+//		Avatar *multipart.FileHeader `form:"avatar" upload:"required,maxsize=5MB,mime=image/png image/jpeg,minwidth=200"`
+//	}
+//
+// The rules are:
+//
+//   - required: a file must be uploaded
+//   - maxsize, minsize: the file size in bytes or with a KB, MB, or GB suffix (1 KB = 1024 bytes)
+//   - mime: space-separated content types such as image/png or image/*,
+//     detected from the file content instead of the client's Content-Type
+//   - ext: space-separated file name extensions such as .pdf .docx
+//   - minwidth, maxwidth, minheight, maxheight: image dimensions in pixels
+//     for GIF, JPEG, and PNG images
+//
+// Failures are returned as validator.ValidationErrors, so they are reported
+// like other validation errors.
+type FileValidator struct{}
+
+// NewFileValidator creates a new file validator
+func NewFileValidator() *FileValidator {
+	return &FileValidator{}
+}
+
+// Struct validates the file fields of a struct with upload tags
+func (v *FileValidator) Struct(s interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(s))
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("validator: cannot validate files of %T", s)
+	}
+
+	var errs validator.ValidationErrors
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		rules, ok := field.Tag.Lookup(UploadTag)
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		name := uploadFieldName(field)
+		namespace := value.Type().Name() + "." + name
+		check := func(file *multipart.FileHeader, index string) error {
+			fe, err := checkFile(file, rules, namespace+index, name+index, field.Name+index)
+			if fe != nil {
+				errs = append(errs, fe)
+			}
+			return err
+		}
+
+		switch {
+		case field.Type == fileHeaderType:
+			file, _ := value.Field(i).Interface().(*multipart.FileHeader)
+			if err := check(file, ""); err != nil {
+				return err
+			}
+
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem() == fileHeaderType:
+			files, _ := value.Field(i).Interface().([]*multipart.FileHeader)
+			if len(files) == 0 {
+				// An empty list only fails the required rule
+				files = []*multipart.FileHeader{nil}
+			}
+			for j, file := range files {
+				index := ""
+				if file != nil {
+					index = "[" + strconv.Itoa(j) + "]"
+				}
+				if err := check(file, index); err != nil {
+					return err
+				}
+			}
+
+		default:
+			return fmt.Errorf("validator: upload tag on %s, which is not a *multipart.FileHeader", field.Name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// File validates a single uploaded file against comma-separated rules, e.g.
+// "maxsize=5MB,ext=.pdf"
+func (v *FileValidator) File(file *multipart.FileHeader, rules string) error {
+	fe, err := checkFile(file, rules, "file", "file", "file")
+	if err != nil {
+		return err
+	}
+	if fe != nil {
+		return validator.ValidationErrors{fe}
+	}
+	return nil
+}
+
+// uploadFieldName returns the name of a file field: its form tag, its JSON
+// tag, or its Go name
+func uploadFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// checkFile checks a file against the rules, returning the first rule that
+// fails, or an error when the rules are invalid or the file cannot be read
+func checkFile(file *multipart.FileHeader, rules string, namespace, name, structField string) (validator.FieldError, error) {
+	fail := func(tag, param string) validator.FieldError {
+		fe := &fileFieldError{
+			tag:         tag,
+			param:       param,
+			namespace:   namespace,
+			field:       name,
+			structField: structField,
+		}
+		if file != nil {
+			fe.value = file.Filename
+		}
+		return fe
+	}
+
+	var (
+		mimeTypes  string
+		dimensions []fileRule
+	)
+	for _, rule := range strings.Split(rules, ",") {
+		tag, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if tag == "" {
+			continue
+		}
+		if file == nil {
+			// Only the required rule applies when no file is uploaded
+			if tag == "required" {
+				return fail(tag, ""), nil
+			}
+			continue
+		}
+
+		switch tag {
+		case "required":
+		case "maxsize", "minsize":
+			limit, err := parseFileSize(param)
+			if err != nil {
+				return nil, fmt.Errorf("validator: invalid %s rule %q: %w", tag, param, err)
+			}
+			if (tag == "maxsize" && file.Size > limit) || (tag == "minsize" && file.Size < limit) {
+				return fail(tag, param), nil
+			}
+		case "ext":
+			ext := strings.ToLower(filepath.Ext(file.Filename))
+			if !containsFold(strings.Fields(param), ext) {
+				return fail(tag, param), nil
+			}
+		case "mime":
+			mimeTypes = param
+		case "minwidth", "maxwidth", "minheight", "maxheight":
+			limit, err := strconv.Atoi(param)
+			if err != nil {
+				return nil, fmt.Errorf("validator: invalid %s rule %q: %w", tag, param, err)
+			}
+			dimensions = append(dimensions, fileRule{tag: tag, param: param, limit: limit})
+		default:
+			return nil, fmt.Errorf("validator: unknown upload rule %q", tag)
+		}
+	}
+
+	// Rules that read the file content run after the cheaper checks
+	if mimeTypes != "" {
+		ok, err := matchesMIME(file, strings.Fields(mimeTypes))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return fail("mime", mimeTypes), nil
+		}
+	}
+	if len(dimensions) > 0 {
+		config, err := imageConfig(file)
+		if err != nil {
+			return fail("image", ""), nil
+		}
+		for _, rule := range dimensions {
+			if !rule.check(config) {
+				return fail(rule.tag, rule.param), nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// fileRule is an image dimension rule
+type fileRule struct {
+	tag   string
+	param string
+	limit int
+}
+
+// check reports whether an image meets the rule
+func (r fileRule) check(config image.Config) bool {
+	switch r.tag {
+	case "minwidth":
+		return config.Width >= r.limit
+	case "maxwidth":
+		return config.Width <= r.limit
+	case "minheight":
+		return config.Height >= r.limit
+	default:
+		return config.Height <= r.limit
+	}
+}
+
+// parseFileSize parses a size such as 1024, 500KB, or 5MB into bytes
+func parseFileSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("not a file size")
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// matchesMIME reports whether the content type detected from the file
+// content, or one of its parent types, is one of the allowed types
+func matchesMIME(file *multipart.FileHeader, allowed []string) (bool, error) {
+	f, err := file.Open()
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	detected, err := mimetype.DetectReader(f)
+	if err != nil {
+		return false, err
+	}
+
+	for m := detected; m != nil; m = m.Parent() {
+		for _, a := range allowed {
+			if prefix, ok := strings.CutSuffix(a, "/*"); ok {
+				if strings.HasPrefix(m.String(), prefix+"/") {
+					return true, nil
+				}
+			} else if m.Is(a) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// imageConfig decodes the dimensions of an uploaded image
+func imageConfig(file *multipart.FileHeader) (image.Config, error) {
+	f, err := file.Open()
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	return config, err
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileFieldError is a validator.FieldError for an uploaded file
+type fileFieldError struct {
+	tag         string
+	param       string
+	namespace   string
+	field       string
+	structField string
+	value       interface{}
+}
+
+func (e *fileFieldError) Tag() string             { return e.tag }
+func (e *fileFieldError) ActualTag() string       { return e.tag }
+func (e *fileFieldError) Namespace() string       { return e.namespace }
+func (e *fileFieldError) StructNamespace() string { return e.namespace }
+func (e *fileFieldError) Field() string           { return e.field }
+func (e *fileFieldError) StructField() string     { return e.structField }
+func (e *fileFieldError) Value() interface{}      { return e.value }
+func (e *fileFieldError) Param() string           { return e.param }
+func (e *fileFieldError) Kind() reflect.Kind      { return reflect.Ptr }
+func (e *fileFieldError) Type() reflect.Type      { return fileHeaderType }
+
+// Translate returns the untranslated error, as the validator's translations
+// do not cover upload rules
+func (e *fileFieldError) Translate(_ ut.Translator) string { return e.Error() }
+
+// Error returns the error in the format of the validator's field errors
+func (e *fileFieldError) Error() string {
+	return fmt.Sprintf("Key: '%s' Error:Field validation for '%s' failed on the '%s' tag", e.namespace, e.field, e.tag)
+}
@@ -2,9 +2,11 @@
 package validator
 
 import (
+	"context"
 	"reflect"
 	"strings"
 
+	"github.com/anaknegeri/gokit/pkg/errors"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -13,9 +15,17 @@ type Validator interface {
 	// Struct validates a struct and returns an error if validation fails
 	Struct(s interface{}) error
 
+	// StructCtx validates a struct with a context, which is passed to
+	// validations registered with RegisterValidationCtx
+	StructCtx(ctx context.Context, s interface{}) error
+
 	// RegisterValidation registers a custom validation function
 	RegisterValidation(tag string, fn interface{}) error
 
+	// RegisterValidationCtx registers a custom validation function that
+	// receives the context passed to StructCtx
+	RegisterValidationCtx(tag string, fn validator.FuncCtx) error
+
 	// RegisterTagNameFunc sets a function to get the field name from a struct tag
 	RegisterTagNameFunc(fn func(fld reflect.StructField) string)
 }
@@ -25,8 +35,9 @@ type validatorImpl struct {
 	validate *validator.Validate
 }
 
-// NewValidator creates a new validator instance
-func NewValidator() Validator {
+// NewValidator creates a new validator instance, e.g.
+// NewValidator(WithDefaults()) to register the built-in validations
+func NewValidator(opts ...Option) Validator {
 	v := validator.New()
 
 	// By default, use JSON tag names in validation errors
@@ -38,6 +49,17 @@ func NewValidator() Validator {
 		return name
 	})
 
+	// Register the validations that check roles and feature flags in the context
+	registerContextValidations(v)
+
+	// Register the validator's own messages for tags without a catalog
+	// message; they fall back to a generic message if registration fails
+	_ = errors.RegisterValidatorTranslations(v)
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
 	return &validatorImpl{
 		validate: v,
 	}
@@ -48,6 +70,16 @@ func (v *validatorImpl) Struct(s interface{}) error {
 	return v.validate.Struct(s)
 }
 
+// StructCtx validates a struct with a context
+func (v *validatorImpl) StructCtx(ctx context.Context, s interface{}) error {
+	return v.validate.StructCtx(ctx, s)
+}
+
+// RegisterValidationCtx registers a custom validation function that receives the context
+func (v *validatorImpl) RegisterValidationCtx(tag string, fn validator.FuncCtx) error {
+	return v.validate.RegisterValidationCtx(tag, fn)
+}
+
 // RegisterValidation registers a custom validation function
 func (v *validatorImpl) RegisterValidation(tag string, fn interface{}) error {
 	validatorFunc, ok := fn.(validator.Func)
@@ -0,0 +1,347 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/auth"
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/i18n"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/contrib/websocket"
+)
+
+// contextLocal is the local holding the user context of the upgraded request
+const contextLocal = "gokit:ws_context"
+
+var (
+	// ErrClosed is returned when sending to a closed connection
+	ErrClosed = errors.New("ws: connection closed")
+
+	// ErrQueueFull is returned when sending to a connection whose send queue
+	// is full. The connection is closed.
+	ErrQueueFull = errors.New("ws: send queue full")
+)
+
+// Message is a message sent by a client:
+//
+//	{"type": "subscribe", "data": {"upload": "42"}}
+type Message struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Decode decodes the data of the message into v
+func (m Message) Decode(v interface{}) error {
+	if len(m.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(m.Data, v)
+}
+
+// Conn is a WebSocket connection of a hub
+type Conn struct {
+	// ID identifies the connection
+	ID string
+
+	// UserID is the subject of the token of the upgraded request, if any
+	UserID string
+
+	hub  *Hub
+	ws   *websocket.Conn
+	ctx  context.Context
+	log  *logger.Logger
+	send chan []byte
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+
+	mu    sync.Mutex
+	rooms map[string]struct{}
+}
+
+// Context returns the context of the upgraded request, holding its user,
+// locale, and trace, without its cancellation
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Locals returns a local of the upgraded request, such as one set by an
+// earlier middleware
+func (c *Conn) Locals(key string) interface{} {
+	return c.ws.Locals(key)
+}
+
+// Query returns a query parameter of the upgraded request
+func (c *Conn) Query(key string) string {
+	return c.ws.Query(key)
+}
+
+// Params returns a route parameter of the upgraded request
+func (c *Conn) Params(key string) string {
+	return c.ws.Params(key)
+}
+
+// Send queues a message for the connection, as an envelope with the event
+// as its type:
+//
+//	{"type": "upload.progress", "success": true, "code": 200, "message": "", "data": {...}}
+func (c *Conn) Send(event string, data interface{}) error {
+	msg, err := encode(event, data)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(msg)
+}
+
+// SendError queues an error envelope of type "error", with the message in
+// the locale of the connection
+func (c *Conn) SendError(err error) error {
+	body, encodeErr := response.ErrorJSON(err, i18n.Locale(c.ctx))
+	if encodeErr != nil {
+		return encodeErr
+	}
+	return c.enqueue(withType("error", body))
+}
+
+// Join adds the connection to rooms, to receive the messages emitted to them
+func (c *Conn) Join(rooms ...string) {
+	c.hub.join(c, rooms)
+}
+
+// Leave takes the connection out of rooms
+func (c *Conn) Leave(rooms ...string) {
+	c.hub.leave(c, rooms)
+}
+
+// Rooms returns the rooms the connection is in
+func (c *Conn) Rooms() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// Close closes the connection normally after the queued messages are sent
+func (c *Conn) Close() {
+	c.closeWith(websocket.CloseNormalClosure, "")
+}
+
+// closeWith closes the connection with a status code
+func (c *Conn) closeWith(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+// enqueue queues an encoded message, closing connections that cannot keep up
+func (c *Conn) enqueue(msg []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.send <- msg:
+		return nil
+	default:
+		c.log.Warnf("Closing connection whose send queue is full")
+		c.closeWith(websocket.ClosePolicyViolation, "too slow")
+		return ErrQueueFull
+	}
+}
+
+// serve runs a connection upgraded by Handler until it closes
+func (h *Hub) serve(ws *websocket.Conn) {
+	ctx, _ := ws.Locals(contextLocal).(context.Context)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	conn := &Conn{
+		ID:    newID(),
+		hub:   h,
+		ws:    ws,
+		ctx:   ctx,
+		send:  make(chan []byte, h.config.SendQueue),
+		done:  make(chan struct{}),
+		rooms: make(map[string]struct{}),
+	}
+	if claims, ok := auth.ClaimsFromContext(ctx); ok {
+		conn.UserID = claims.Subject
+	}
+	conn.log = h.config.Logger.Ctx(ctx).WithFields(logger.Fields{"conn_id": conn.ID})
+
+	if !h.add(conn) {
+		_ = ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(h.config.WriteTimeout))
+		return
+	}
+	defer h.remove(conn)
+	if conn.UserID != "" {
+		conn.Join(UserRoom(conn.UserID))
+	}
+
+	// The writer owns writes to the socket and closes it
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		conn.write()
+	}()
+	defer func() { <-written }()
+	defer conn.Close()
+
+	if h.config.OnConnect != nil {
+		if err := h.config.OnConnect(conn); err != nil {
+			_ = conn.SendError(err)
+			return
+		}
+	}
+	if h.config.OnDisconnect != nil {
+		defer h.config.OnDisconnect(conn)
+	}
+	conn.read()
+}
+
+// read handles the messages of the client until the connection closes
+func (c *Conn) read() {
+	cfg := c.hub.config
+	c.ws.SetReadLimit(cfg.MaxMessageSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				c.log.Debugf("Connection closed: %v", err)
+			}
+			return
+		}
+		_ = c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			_ = c.SendError(errors.BadRequestError(`Messages are JSON objects with a "type"`))
+			continue
+		}
+		if cfg.OnMessage == nil {
+			continue
+		}
+		if err := c.handle(msg); err != nil {
+			_ = c.SendError(err)
+		}
+	}
+}
+
+// handle calls OnMessage, turning panics into errors
+func (c *Conn) handle(msg Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.log.Errorf("Message handler panicked: %v\n%s", r, debug.Stack())
+			appErr := errors.InternalServerError("")
+			appErr.Internal = fmt.Errorf("panic: %v", r)
+			err = appErr
+		}
+	}()
+	return c.hub.config.OnMessage(c, msg)
+}
+
+// write sends the queued messages and pings until the connection closes,
+// then sends the remaining messages and a close frame
+func (c *Conn) write() {
+	cfg := c.hub.config
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer ticker.Stop()
+	defer c.ws.Close()
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.writeMessage(msg); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(cfg.WriteTimeout)); err != nil {
+				c.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+
+		case <-c.done:
+			c.drain()
+			_ = c.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, c.closeText),
+				time.Now().Add(cfg.WriteTimeout))
+			return
+		}
+	}
+}
+
+// drain writes the messages left in the queue
+func (c *Conn) drain() {
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.writeMessage(msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// writeMessage writes a text message
+func (c *Conn) writeMessage(msg []byte) error {
+	_ = c.ws.SetWriteDeadline(time.Now().Add(c.hub.config.WriteTimeout))
+	return c.ws.WriteMessage(websocket.TextMessage, msg)
+}
+
+// encode encodes the envelope of an event
+func encode(event string, data interface{}) ([]byte, error) {
+	body, err := response.SuccessJSON("", data)
+	if err != nil {
+		return nil, fmt.Errorf("ws: encode %s: %w", event, err)
+	}
+	return withType(event, body), nil
+}
+
+// withType adds the type field to the start of a JSON envelope
+func withType(event string, body []byte) []byte {
+	name, _ := json.Marshal(event)
+	msg := make([]byte, 0, len(body)+len(name)+10)
+	msg = append(msg, `{"type":`...)
+	msg = append(msg, name...)
+	if len(body) > 2 {
+		msg = append(msg, ',')
+	}
+	return append(msg, body[1:]...)
+}
+
+// newID returns a random connection ID
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
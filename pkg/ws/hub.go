@@ -0,0 +1,320 @@
+// Package ws serves WebSocket connections through a hub with rooms,
+// broadcasts, per-connection send queues, and keepalive pings, sending
+// messages in the envelope of the response package
+package ws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/anaknegeri/gokit/pkg/errors"
+	"github.com/anaknegeri/gokit/pkg/logger"
+	"github.com/anaknegeri/gokit/pkg/response"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultSendQueue is how many messages wait to be written to a
+	// connection by default
+	DefaultSendQueue = 64
+
+	// DefaultPingInterval is how often connections are pinged by default
+	DefaultPingInterval = 30 * time.Second
+
+	// DefaultPongTimeout is how long a connection may stay silent, including
+	// answers to pings, by default
+	DefaultPongTimeout = 60 * time.Second
+
+	// DefaultWriteTimeout limits writing a message by default
+	DefaultWriteTimeout = 10 * time.Second
+
+	// DefaultMaxMessageSize is the largest message clients may send by
+	// default
+	DefaultMaxMessageSize = 64 << 10
+)
+
+// Config configures a hub
+type Config struct {
+	// SendQueue is how many messages wait to be written to a connection.
+	// Connections too slow to keep up are closed rather than holding up
+	// broadcasts.
+	SendQueue int
+
+	// PingInterval is how often connections are pinged
+	PingInterval time.Duration
+
+	// PongTimeout closes connections that send nothing, not even an answer
+	// to a ping, for this long. It must be longer than PingInterval.
+	PongTimeout time.Duration
+
+	// WriteTimeout limits writing a message
+	WriteTimeout time.Duration
+
+	// MaxMessageSize is the largest message clients may send
+	MaxMessageSize int64
+
+	// Origins are the origins allowed to connect, defaulting to all
+	Origins []string
+
+	// OnConnect is called when a connection opens, before it receives
+	// messages, e.g. to join rooms. Returning an error sends it to the client
+	// and closes the connection.
+	OnConnect func(conn *Conn) error
+
+	// OnMessage is called with each message a client sends. Returning an
+	// error sends it to the client as an error message.
+	OnMessage func(conn *Conn, msg Message) error
+
+	// OnDisconnect is called when a connection closes
+	OnDisconnect func(conn *Conn)
+
+	// Logger defaults to the logger named "ws"
+	Logger *logger.Logger
+}
+
+// Hub tracks the open connections and the rooms they joined
+type Hub struct {
+	config Config
+
+	mu      sync.RWMutex
+	conns   map[*Conn]struct{}
+	rooms   map[string]map[*Conn]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+// NewHub creates a hub. Serve it with Handler, and send to its connections
+// with Broadcast, Emit, and EmitToUser:
+//
+//	hub := ws.NewHub(ws.Config{
+//		OnMessage: func(conn *ws.Conn, msg ws.Message) error { ... },
+//	})
+//	app.Get("/ws", auth.Middleware(), hub.Handler())
+//
+//	hub.EmitToUser(userID, "upload.progress", progress)
+func NewHub(config ...Config) *Hub {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.SendQueue <= 0 {
+		cfg.SendQueue = DefaultSendQueue
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = DefaultPingInterval
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = DefaultPongTimeout
+	}
+	if cfg.PongTimeout <= cfg.PingInterval {
+		cfg.PongTimeout = cfg.PingInterval * 2
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logger.Named("ws")
+	}
+	return &Hub{
+		config: cfg,
+		conns:  make(map[*Conn]struct{}),
+		rooms:  make(map[string]map[*Conn]struct{}),
+	}
+}
+
+// Handler returns a handler upgrading requests to WebSocket connections of
+// the hub. Connections of requests authenticated by the auth middleware
+// join the room of their user, see EmitToUser. Other requests are answered
+// with 426 UPGRADE_REQUIRED, and requests during Shutdown with 503.
+func (h *Hub) Handler() fiber.Handler {
+	upgrade := websocket.New(h.serve, websocket.Config{Origins: h.config.Origins})
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return response.Error(c, errors.NewError(fiber.StatusUpgradeRequired, "WebSocket upgrade required"))
+		}
+		h.mu.RLock()
+		closing := h.closing
+		h.mu.RUnlock()
+		if closing {
+			return response.Error(c, errors.ServiceUnavailableError(""))
+		}
+
+		// The connection outlives the request, so it keeps its values, such as
+		// the user and locale, but not its cancellation
+		c.Locals(contextLocal, context.WithoutCancel(c.UserContext()))
+		return upgrade(c)
+	}
+}
+
+// Broadcast sends a message to every connection
+func (h *Hub) Broadcast(event string, data interface{}) error {
+	msg, err := encode(event, data)
+	if err != nil {
+		return err
+	}
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range conns {
+		conn.enqueue(msg)
+	}
+	return nil
+}
+
+// Emit sends a message to the connections in a room
+func (h *Hub) Emit(room, event string, data interface{}) error {
+	msg, err := encode(event, data)
+	if err != nil {
+		return err
+	}
+	for _, conn := range h.members(room) {
+		conn.enqueue(msg)
+	}
+	return nil
+}
+
+// EmitToUser sends a message to the connections of a user, in every tab
+// and device they are connected from
+func (h *Hub) EmitToUser(userID, event string, data interface{}) error {
+	return h.Emit(UserRoom(userID), event, data)
+}
+
+// UserRoom returns the name of the room the connections of a user join
+func UserRoom(userID string) string {
+	return "user:" + userID
+}
+
+// Count returns the number of open connections
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// RoomCount returns the number of connections in a room
+func (h *Hub) RoomCount(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Shutdown stops accepting connections, sends the messages already queued,
+// and closes every connection with status 1001 "going away", so clients
+// reconnect to another instance. It waits for the connections to close
+// until ctx is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.closeWith(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// add registers a connection, unless the hub is shutting down
+func (h *Hub) add(conn *Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return false
+	}
+	h.conns[conn] = struct{}{}
+	h.wg.Add(1)
+	return true
+}
+
+// remove unregisters a connection and takes it out of its rooms
+func (h *Hub) remove(conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, conn)
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	for room := range conn.rooms {
+		h.removeMember(room, conn)
+	}
+	conn.rooms = nil
+	h.wg.Done()
+}
+
+// join adds an open connection to rooms. The hub lock is always taken
+// before the lock of a connection.
+func (h *Hub) join(conn *Conn, rooms []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.conns[conn]; !ok {
+		return
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	for _, room := range rooms {
+		members, ok := h.rooms[room]
+		if !ok {
+			members = make(map[*Conn]struct{})
+			h.rooms[room] = members
+		}
+		members[conn] = struct{}{}
+		conn.rooms[room] = struct{}{}
+	}
+}
+
+// leave takes a connection out of rooms
+func (h *Hub) leave(conn *Conn, rooms []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	for _, room := range rooms {
+		h.removeMember(room, conn)
+		delete(conn.rooms, room)
+	}
+}
+
+// removeMember removes a connection from a room, dropping empty rooms. The
+// caller holds the lock.
+func (h *Hub) removeMember(room string, conn *Conn) {
+	members := h.rooms[room]
+	delete(members, conn)
+	if len(members) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// members returns the connections in a room
+func (h *Hub) members(room string) []*Conn {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	conns := make([]*Conn, 0, len(h.rooms[room]))
+	for conn := range h.rooms[room] {
+		conns = append(conns, conn)
+	}
+	return conns
+}